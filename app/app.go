@@ -98,10 +98,16 @@ type Context struct {
 	//
 	// ActionArgs is the list of arguments for this action.
 	//
-	// Both of these fields will only be set for the Execute call.
+	// Both of these fields will only be set for the Build and Deploy
+	// calls.
 	Action     string
 	ActionArgs []string
 
+	// BuildVars are the free-form variables of a build, such as "tag" for
+	// the tag of an image, given with `otto build -var`. These are only
+	// set for the Build and BuildArtifacts calls.
+	BuildVars map[string]string
+
 	// Dir is the directory that the compilation is allowed to write to
 	// for persistant storage of data that is available during task
 	// execution. For tasks, this will be the directory that compilation
@@ -143,15 +149,84 @@ type Context struct {
 	// DevDepFragments will be populated with the list of dev dep
 	// Vagrantfile fragment paths. This will only be available in the Compile
 	// call.
+	//
+	// The fragments are in dependency order: the fragment of every
+	// dependency comes after the fragments of the dependencies it depends
+	// on. Otherwise they're ordered by the name of the dependency.
 	DevDepFragments []string
 
+	// DevDepAddresses are the dev IP addresses of the dependencies by
+	// dependency name, and DevDepPorts are the dev ports of the
+	// dependencies that have any. The dev environment can use these to
+	// reach the dev environments of the dependencies.
+	//
+	// These are only set for the root application in the Dev call.
+	DevDepAddresses map[string]string
+	DevDepPorts     map[string][]int
+
 	// DevIPAddress is a local IP address in the private address space
 	// that can be used for a development environment. Otto core
 	// does its best to ensure this is unused.
 	//
-	// This is only available if this app is the root application being
-	// developed (dependencies don't get an IP).
+	// Every app, including each dependency, gets its own address.
 	DevIPAddress string
+
+	// DevPorts are host ports allocated for the development environment
+	// of this app, such as for forwarded ports. The number of ports is
+	// requested with CompileResult.DevPorts. Otto core ensures these
+	// don't conflict with the ports of other apps, and the same app
+	// gets the same ports every time.
+	DevPorts []int
+
+	// DevLayers are the directories of the layers the app asked for with
+	// CompileResult.DevLayers, in the same order. Each directory has a
+	// copy of the provisioning fragment of the layer. The layer itself is
+	// built and stored in the directory by the app, which should reuse it
+	// if it was already built for another project.
+	//
+	// These are only set for the root application in the Dev call.
+	DevLayers []string
+
+	// Environment is the environment that builds and deploys are for,
+	// such as "staging". This is directory.DefaultEnvironment unless
+	// another one is configured, and should be set as the Environment of
+	// the lookups of builds and deploys in the directory.
+	Environment string
+
+	// DeployInfo is the information of the last deploy of the app, and
+	// InfraOutputs are the outputs of the infrastructure it was deployed
+	// to. These are only set for the Health, Logs, and Console calls and
+	// for the smoke tests.
+	DeployInfo   map[string]string
+	InfraOutputs map[string]string
+
+	// DepOutputs are the deploy information of the deployed dependencies
+	// of the app in the environment, keyed by the application name of the
+	// dependency, such as the endpoint of a database. These are only set
+	// for the Deploy and Plan calls.
+	DepOutputs map[string]map[string]string
+
+	// Artifacts are the files of the last build of the app, keyed by the
+	// names in the BuildResult, if the app implements ArtifactBuilder.
+	// These are only set for the Deploy and Plan calls.
+	Artifacts map[string]*ArtifactFile
+}
+
+// DevLayer describes a layer of a development environment that is
+// shared by all the projects that use it, such as a base image with the
+// language runtime installed.
+type DevLayer struct {
+	// ID identifies the layer, such as "go-runtime". This must only
+	// contain letters, digits, '-', '_', and '.'.
+	ID string `json:"id"`
+
+	// Version is the version of the layer. Changing the version makes a
+	// new layer; projects using the old version keep using it.
+	Version string `json:"version"`
+
+	// FragmentPath is the path to the provisioning fragment that builds
+	// the layer, which is copied into the layer directory.
+	FragmentPath string `json:"fragment_path"`
 }
 
 // RouteName implements the router.Context interface so we can use Router
@@ -179,13 +254,41 @@ type CompileResult struct {
 	// elements of Otto.
 	FoundationConfig foundation.Config `json:"foundation_config"`
 
+	// FoundationPhases are the phases that the foundations compile files
+	// for this app, such as foundation.AppPhaseBuild. Only the foundation
+	// subdirectories of these phases are created. If this is empty, the
+	// app participates in every phase in foundation.AppPhases, and Otto
+	// core sets this to them.
+	FoundationPhases []string `json:"foundation_phases,omitempty"`
+
 	// DevDepFragmentPath is the path to the Vagrantfile fragment that
 	// should be added to other Vagrantfiles when this application is
 	// used as a dependency.
 	DevDepFragmentPath string `json:"dev_dep_fragment_path"`
 
+	// DevPorts is the number of host ports the development environment
+	// needs. The ports are allocated by Otto core and available as
+	// Context.DevPorts.
+	DevPorts int `json:"dev_ports,omitempty"`
+
+	// DevLayers are the layers that the development environment is based
+	// on, from the bottom layer up. Layers are shared by all projects
+	// that use the same layer, so they should only have what is common to
+	// the app type, like the language runtime. The layer directories are
+	// available as Context.DevLayers.
+	DevLayers []*DevLayer `json:"dev_layers,omitempty"`
+
 	// FoundationResults are the compilation results of the foundations.
 	//
 	// This is populated by Otto core and any set value here will be ignored.
 	FoundationResults map[string]*foundation.CompileResult `json:"foundation_results"`
+
+	// Plugin is the tuple of the app that compiled this, and PluginVersion
+	// is its version if it reports one. These tell if the app that uses
+	// the compiled files later is the one that compiled them.
+	//
+	// These are populated by Otto core and any set value here will be
+	// ignored.
+	Plugin        string `json:"plugin,omitempty"`
+	PluginVersion string `json:"plugin_version,omitempty"`
 }
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"errors"
+)
+
+// ErrArtifactsNotSupported is returned by BuildArtifacts when the app
+// doesn't return the files it builds. This is mostly for apps that are
+// served over RPC, which always look like they implement ArtifactBuilder.
+var ErrArtifactsNotSupported = errors.New("build artifacts are not supported")
+
+// ArtifactBuilder is an optional interface that an App can implement if
+// its builds produce files, such as tarballs or container images saved
+// to disk. Otto calls BuildArtifacts instead of Build, and uploads the
+// files to the artifact store so that they can be deployed from another
+// machine.
+type ArtifactBuilder interface {
+	// BuildArtifacts builds like Build and returns the files it built.
+	BuildArtifacts(ctx *Context) (*BuildResult, error)
+}
+
+// BuildResult is the result of BuildArtifacts.
+type BuildResult struct {
+	// Files are the local paths of the files that were built, keyed by a
+	// name of the app's choosing, such as "image".
+	Files map[string]string
+}
+
+// ArtifactFile is a file of a build that is stored in the artifact store.
+// Use artifact.Fetch in helper/artifact to download it.
+type ArtifactFile struct {
+	// URL is where the file is stored, such as "s3://bucket/key" or a
+	// "file://" URL if no artifact store is configured.
+	URL string
+
+	// Checksum is the hex-encoded SHA-256 checksum of the file.
+	Checksum string
+}
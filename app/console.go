@@ -0,0 +1,36 @@
+package app
+
+import (
+	"errors"
+)
+
+// ErrConsoleNotSupported is returned by Console when the app can't open
+// a console. This is mostly for apps that are served over RPC, which
+// always look like they implement ConsoleProvider.
+var ErrConsoleNotSupported = errors.New("consoles are not supported")
+
+// ConsoleProvider is an optional interface that an App can implement to
+// open an interactive shell into the development environment or onto the
+// deployed application, such as with SSH.
+type ConsoleProvider interface {
+	// Console hands the user's terminal to the shell of the target and
+	// returns once the shell exits. Otto only calls this when the
+	// standard input and output are a terminal.
+	//
+	// For a deployed target, the context has the information of the last
+	// deploy in DeployInfo, the outputs of the infrastructure in
+	// InfraOutputs, and the infrastructure credentials.
+	Console(ctx *Context, target ConsoleTarget) error
+}
+
+// ConsoleTarget is where a console is opened.
+type ConsoleTarget struct {
+	// Dev, if true, opens the console into the development environment.
+	// Otherwise, it is opened onto the deployed application.
+	Dev bool
+
+	// Instance is a hint for which instance to connect to if there are
+	// many, such as an ID or address. If this is empty, the app picks
+	// one.
+	Instance string
+}
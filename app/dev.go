@@ -1,12 +1,18 @@
 package app
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // DevDep has information about an upstream dependency that should be
@@ -46,7 +52,100 @@ func (d *DevDep) RelFiles(dir string) error {
 	return nil
 }
 
-// ReadDevDep reads a marshalled DevDep from disk.
+// ValidateFiles verifies that all the Files values are inside the
+// given directory, which should be the CacheDir. Paths must be relative
+// (see RelFiles), can't reference a parent directory even with Windows
+// separators, and can't resolve outside of the directory through a
+// symlink. Every file must exist.
+func (d *DevDep) ValidateFiles(dir string) error {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range d.Files {
+		if err := validateDevDepFile(root, f); err != nil {
+			return fmt.Errorf("invalid file %q: %s", f, err)
+		}
+	}
+
+	return nil
+}
+
+func validateDevDepFile(root, f string) error {
+	if f == "" {
+		return fmt.Errorf("path is empty")
+	}
+
+	// Check with both kinds of separators so that paths meant for another
+	// OS are caught no matter which OS we're running on.
+	slashed := strings.Replace(f, "\\", "/", -1)
+	if filepath.IsAbs(f) || filepath.VolumeName(f) != "" ||
+		strings.HasPrefix(slashed, "/") ||
+		(len(slashed) >= 2 && slashed[1] == ':') {
+		return fmt.Errorf("absolute paths aren't allowed")
+	}
+	for _, part := range strings.Split(slashed, "/") {
+		if part == ".." {
+			return fmt.Errorf("parent directory references aren't allowed")
+		}
+	}
+
+	resolved, err := filepath.EvalSymlinks(filepath.Join(root, f))
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil {
+		return err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("resolves outside of the cache directory: %s", resolved)
+	}
+
+	return nil
+}
+
+// DevDepCompressSize is the size in bytes above which WriteDevDep
+// compresses the DevDep on disk. Smaller files are left uncompressed so
+// they can be easily inspected.
+const DevDepCompressSize = 64 * 1024
+
+// devDepVersion is the version of the file format written by WriteDevDep.
+// Files without a version are the original format: the DevDep itself
+// with no checksum.
+const devDepVersion = 1
+
+// devDepFile is the format written to disk by WriteDevDep.
+type devDepFile struct {
+	Version  int     `json:"version"`
+	Checksum string  `json:"checksum"`
+	DevDep   *DevDep `json:"dev_dep"`
+}
+
+// CorruptDevDepError is returned by ReadDevDep when the file exists but
+// is corrupt, such as when a write was interrupted. The DevDep should be
+// built again, as if it were never cached.
+type CorruptDevDepError struct {
+	Path string
+	Err  error
+}
+
+func (e *CorruptDevDepError) Error() string {
+	return fmt.Sprintf("dev dependency cache is corrupt: %s: %s", e.Path, e.Err)
+}
+
+// IsCorruptDevDep returns true if err is a CorruptDevDepError.
+func IsCorruptDevDep(err error) bool {
+	_, ok := err.(*CorruptDevDepError)
+	return ok
+}
+
+// ReadDevDep reads a marshalled DevDep from disk. This reads both files
+// written by WriteDevDep and files in the original uncompressed format
+// without a checksum.
+//
+// If the file is corrupt, a *CorruptDevDepError is returned.
 func ReadDevDep(path string) (*DevDep, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -54,30 +153,134 @@ func ReadDevDep(path string) (*DevDep, error) {
 	}
 	defer f.Close()
 
-	var result DevDep
-	dec := json.NewDecoder(f)
-	if err := dec.Decode(&result); err != nil {
+	// Compressed files are detected by the gzip header
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	header, err := br.Peek(2)
+	if err == nil && header[0] == 0x1f && header[1] == 0x8b {
+		gzipR, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, &CorruptDevDepError{Path: path, Err: err}
+		}
+		defer gzipR.Close()
+
+		r = gzipR
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, &CorruptDevDepError{Path: path, Err: err}
+	}
+
+	var file devDepFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, &CorruptDevDepError{Path: path, Err: err}
+	}
+
+	// The original format is just the DevDep
+	if file.Version == 0 {
+		var result DevDep
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, &CorruptDevDepError{Path: path, Err: err}
+		}
+
+		return &result, nil
+	}
+
+	if file.Version > devDepVersion {
+		return nil, fmt.Errorf(
+			"dev dependency cache %s was written by a newer version of Otto", path)
+	}
+	if file.DevDep == nil {
+		return nil, &CorruptDevDepError{
+			Path: path, Err: fmt.Errorf("no dev dependency")}
+	}
+	checksum, err := devDepChecksum(file.DevDep)
+	if err != nil {
 		return nil, err
 	}
+	if checksum != file.Checksum {
+		return nil, &CorruptDevDepError{
+			Path: path, Err: fmt.Errorf("checksum mismatch")}
+	}
 
-	return &result, nil
+	return file.DevDep, nil
 }
 
-// WriteDevDep writes a DevDep out to disk.
+// WriteDevDep writes a DevDep out to disk. The file is replaced
+// atomically, so it is never left partially written. DevDeps larger than
+// DevDepCompressSize are compressed.
 func WriteDevDep(path string, dep *DevDep) error {
+	checksum, err := devDepChecksum(dep)
+	if err != nil {
+		return err
+	}
+
 	// Pretty-print the JSON data so that it can be more easily inspected
-	data, err := json.MarshalIndent(dep, "", "    ")
+	data, err := json.MarshalIndent(&devDepFile{
+		Version:  devDepVersion,
+		Checksum: checksum,
+		DevDep:   dep,
+	}, "", "    ")
 	if err != nil {
 		return err
 	}
 
-	// Write it out
-	f, err := os.Create(path)
+	if len(data) > DevDepCompressSize {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+
+		data = buf.Bytes()
+	}
+
+	// Write it out to a temporary file next to the real one so that the
+	// rename below replaces it in a single step.
+	f, err := ioutil.TempFile(filepath.Dir(path), ".dev-dep-")
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	tempPath := f.Name()
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		os.Remove(tempPath)
+		return err
+	}
+	if _, err := io.Copy(f, bytes.NewReader(data)); err != nil {
+		f.Close()
+		os.Remove(tempPath)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tempPath)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	return nil
+}
+
+// devDepChecksum returns the checksum of a DevDep that is stored with it.
+func devDepChecksum(dep *DevDep) (string, error) {
+	data, err := json.Marshal(dep)
+	if err != nil {
+		return "", err
+	}
 
-	_, err = io.Copy(f, bytes.NewReader(data))
-	return err
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
 }
@@ -0,0 +1,20 @@
+package app
+
+import (
+	"errors"
+)
+
+// ErrDevDepRefreshNotSupported is returned by DevDepRefresh when the app
+// can't refresh dev dependencies. This is mostly for apps that are served
+// over RPC, which always look like they implement DevDepRefresher.
+var ErrDevDepRefreshNotSupported = errors.New("refreshing dev dependencies is not supported")
+
+// DevDepRefresher is an optional interface that an App can implement to
+// apply a rebuilt dev dependency to its running dev environment, without
+// the dev environment being created again.
+type DevDepRefresher interface {
+	// DevDepRefresh is called on the root app after the dependency with
+	// the context src was rebuilt. The contexts are the same as for
+	// DevDep, and the rebuilt files are in the CacheDir of src.
+	DevDepRefresh(dst *Context, src *Context) error
+}
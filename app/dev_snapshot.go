@@ -0,0 +1,44 @@
+package app
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrDevSnapshotNotSupported is returned by the DevSnapshotter functions
+// when the app doesn't support snapshots. This is mostly for apps that
+// are served over RPC, which always look like they implement
+// DevSnapshotter.
+var ErrDevSnapshotNotSupported = errors.New("dev snapshots are not supported")
+
+// DevSnapshotter is an optional interface that an App can implement to
+// save and restore the state of its development environment, such as the
+// data loaded into a database, so that it survives the environment being
+// destroyed and created again.
+//
+// Snapshot names are validated by Otto before these are called. They
+// contain only letters, numbers, '.', '_', and '-' so they are safe to
+// use as file names.
+type DevSnapshotter interface {
+	// DevSnapshot saves the state of the dev environment as the snapshot
+	// with the given name, replacing any snapshot with the same name.
+	DevSnapshot(ctx *Context, name string) error
+
+	// DevRestore restores the dev environment to the snapshot with the
+	// given name.
+	DevRestore(ctx *Context, name string) error
+
+	// DevSnapshots returns all the snapshots of the dev environment.
+	DevSnapshots(ctx *Context) ([]SnapshotInfo, error)
+}
+
+// SnapshotInfo is information about a single snapshot of a dev
+// environment.
+type SnapshotInfo struct {
+	Name string    `json:"name"`
+	Time time.Time `json:"time"`
+
+	// Size is the size of the snapshot in bytes, or zero if it isn't
+	// known.
+	Size int64 `json:"size,omitempty"`
+}
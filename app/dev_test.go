@@ -0,0 +1,196 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteDevDep(t *testing.T) {
+	td, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "dev-dep.json")
+	dep := &DevDep{Files: []string{"foo", "bar"}}
+	if err := WriteDevDep(path, dep); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ReadDevDep(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, dep) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// No temporary files are left behind
+	infos, err := ioutil.ReadDir(td)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("bad: %d", len(infos))
+	}
+}
+
+func TestWriteDevDep_compressed(t *testing.T) {
+	td, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	dep := new(DevDep)
+	for i := 0; i < DevDepCompressSize/8; i++ {
+		dep.Files = append(dep.Files, "file")
+	}
+
+	path := filepath.Join(td, "dev-dep.json")
+	if err := WriteDevDep(path, dep); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if info.Size() > DevDepCompressSize {
+		t.Fatalf("should be compressed: %d", info.Size())
+	}
+
+	actual, err := ReadDevDep(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, dep) {
+		t.Fatal("bad")
+	}
+}
+
+func TestReadDevDep_original(t *testing.T) {
+	td, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "dev-dep.json")
+	data := []byte(`{"files": ["foo"]}`)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ReadDevDep(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, &DevDep{Files: []string{"foo"}}) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestReadDevDep_corrupt(t *testing.T) {
+	td, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "dev-dep.json")
+	if err := WriteDevDep(path, &DevDep{Files: []string{"foo"}}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := map[string][]byte{
+		"truncated": data[:len(data)/2],
+		"modified":  []byte(strings.Replace(string(data), "foo", "bar", 1)),
+		"gzip":      []byte{0x1f, 0x8b, 0x00},
+	}
+	for name, data := range cases {
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		_, err := ReadDevDep(path)
+		if !IsCorruptDevDep(err) {
+			t.Fatalf("%s: bad: %#v", name, err)
+		}
+	}
+
+	// A missing file isn't corrupt
+	os.Remove(path)
+	if _, err := ReadDevDep(path); !os.IsNotExist(err) {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
+func TestDevDepValidateFiles(t *testing.T) {
+	td, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	dir := filepath.Join(td, "cache")
+	outside := filepath.Join(td, "outside")
+	for _, d := range []string{filepath.Join(dir, "sub"), outside} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	for _, f := range []string{
+		filepath.Join(dir, "sub", "file"),
+		filepath.Join(outside, "file"),
+	} {
+		if err := ioutil.WriteFile(f, []byte("foo"), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "escape")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "sub"), filepath.Join(dir, "inside")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := []struct {
+		File string
+		Err  string
+	}{
+		{"sub/file", ""},
+		{"sub", ""},
+		{"inside/file", ""},
+		{"", "empty"},
+		{"missing", "no such file"},
+		{filepath.Join(outside, "file"), "absolute"},
+		{"/etc/passwd", "absolute"},
+		{`\Windows\System32`, "absolute"},
+		{`C:\Windows`, "absolute"},
+		{"../outside/file", "parent"},
+		{"sub/../../outside/file", "parent"},
+		{`sub\..\..\outside\file`, "parent"},
+		{"escape/file", "outside of the cache directory"},
+	}
+
+	for _, tc := range cases {
+		dep := &DevDep{Files: []string{tc.File}}
+		err := dep.ValidateFiles(dir)
+		if (err != nil) != (tc.Err != "") {
+			t.Fatalf("%q: bad: %v", tc.File, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), tc.Err) {
+			t.Fatalf("%q: bad: %s", tc.File, err)
+		}
+	}
+}
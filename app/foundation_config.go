@@ -0,0 +1,31 @@
+package app
+
+import (
+	"errors"
+
+	"github.com/hashicorp/otto/foundation"
+)
+
+// ErrFoundationConfigNotSupported is returned by FoundationConfig when the
+// app can't tell its foundation configuration before it's compiled. This
+// is mostly for apps that are served over RPC, which always look like
+// they implement FoundationConfigurer.
+var ErrFoundationConfigNotSupported = errors.New(
+	"foundation configuration before compilation is not supported")
+
+// FoundationConfigurer is an optional interface that an App can implement
+// to return its foundation configuration without compiling.
+//
+// Foundations are compiled for every app twice: once before the app is
+// compiled, and once after it with the FoundationConfig of its
+// CompileResult. Otto calls FoundationConfig before the first pass so the
+// foundations have the configuration of the app in both. For apps that
+// don't implement this, the first pass gets the configuration from the
+// previous compilation of the app instead, which there is none of the
+// first time the app is compiled.
+//
+// The configuration should be the same as the one in the CompileResult,
+// which is what the second pass gets if they differ.
+type FoundationConfigurer interface {
+	FoundationConfig(ctx *Context) (*foundation.Config, error)
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrHealthNotSupported is returned by Health when the app can't check
+// the health of its deploy. This is mostly for apps that are served over
+// RPC, which always look like they implement Healthchecker.
+var ErrHealthNotSupported = errors.New("health checks are not supported")
+
+// Healthchecker is an optional interface that an App can implement to
+// check whether its deploy is actually serving, such as by requesting
+// its endpoints.
+type Healthchecker interface {
+	// Health checks the deployed application. The context has the
+	// information of the last deploy in DeployInfo and the outputs of the
+	// infrastructure in InfraOutputs.
+	//
+	// An error means the health couldn't be checked at all. An app that
+	// was checked and isn't serving is a report with HealthUnhealthy.
+	Health(ctx *Context) (*HealthReport, error)
+}
+
+// HealthStatus is the overall result of a health check.
+type HealthStatus string
+
+const (
+	HealthHealthy   HealthStatus = "healthy"
+	HealthUnhealthy HealthStatus = "unhealthy"
+	HealthUnknown   HealthStatus = "unknown"
+)
+
+// HealthReport is the result of a health check of a deployed application.
+type HealthReport struct {
+	// Status is the overall status of the application.
+	Status HealthStatus `json:"status"`
+
+	// Checks are the individual endpoints that were checked.
+	Checks []*HealthCheck `json:"checks,omitempty"`
+
+	// CheckedAt is when the check finished. This is populated by Otto
+	// core and any set value here will be ignored.
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// HealthCheck is the check of a single endpoint in a HealthReport.
+type HealthCheck struct {
+	// Endpoint is what was checked, such as a URL.
+	Endpoint string `json:"endpoint"`
+
+	// Status is the status of this endpoint, and Message describes it,
+	// such as the error if the endpoint couldn't be reached.
+	Status  HealthStatus `json:"status"`
+	Message string       `json:"message,omitempty"`
+
+	// Latency is how long the endpoint took to respond.
+	Latency time.Duration `json:"latency"`
+}
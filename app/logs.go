@@ -0,0 +1,44 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrLogsNotSupported is returned by the LogViewer functions when the app
+// can't fetch the logs. This is mostly for apps that are served over RPC,
+// which always look like they implement LogViewer.
+var ErrLogsNotSupported = errors.New("logs are not supported")
+
+// LogViewer is an optional interface that an App can implement to stream
+// the logs of the application.
+type LogViewer interface {
+	// Logs writes the logs of the deployed application to w. The context
+	// has the information of the last deploy in DeployInfo, the outputs
+	// of the infrastructure in InfraOutputs, and the infrastructure
+	// credentials.
+	Logs(ctx *Context, opts LogOptions, w io.Writer) error
+
+	// DevLogs writes the logs of the application in the development
+	// environment to w.
+	DevLogs(ctx *Context, opts LogOptions, w io.Writer) error
+}
+
+// LogOptions are the options for the LogViewer functions.
+type LogOptions struct {
+	// Follow, if true, keeps writing new logs as they come until
+	// ShutdownCh is closed. Otherwise, the logs are written once.
+	Follow bool
+
+	// Tail is the number of the most recent lines to write first. If this
+	// is zero, all the lines are written.
+	Tail int
+
+	// Since, if set, only writes the logs after this time.
+	Since time.Time
+
+	// ShutdownCh stops streaming the logs when it is closed. This can be
+	// nil, in which case following the logs never stops on its own.
+	ShutdownCh <-chan struct{}
+}
@@ -1,7 +1,11 @@
 package app
 
 import (
+	"io"
+	"sync"
+
 	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/foundation"
 )
 
 // Mock is a mock implementation of the App interface.
@@ -40,27 +44,51 @@ type Mock struct {
 	DevDepContextSrc *Context
 	DevDepResult     *DevDep
 	DevDepErr        error
+
+	// Calls are the names of the methods that were called, in the order
+	// they were called. This is safe to read once the calls are done.
+	Calls []string
+
+	lock sync.Mutex
+}
+
+// record appends name to Calls and calls f to record the arguments, both
+// under the lock. Otto may call the mock concurrently when it walks apps
+// in parallel.
+func (m *Mock) record(name string, f func()) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.Calls = append(m.Calls, name)
+	f()
 }
 
 func (m *Mock) Meta() (*Meta, error) {
-	m.MetaCalled = true
+	m.record("Meta", func() {
+		m.MetaCalled = true
+	})
 	return m.MetaResult, m.MetaErr
 }
 
 func (m *Mock) Close() error {
-	m.CloseCalled = true
+	m.record("Close", func() {
+		m.CloseCalled = true
+	})
 	return nil
 }
 
 func (m *Mock) Implicit(ctx *Context) (*appfile.File, error) {
-	m.ImplicitCalled = true
-	m.ImplicitContext = ctx
+	m.record("Implicit", func() {
+		m.ImplicitCalled = true
+		m.ImplicitContext = ctx
+	})
 	return m.ImplicitResult, m.ImplicitErr
 }
 
 func (m *Mock) Compile(ctx *Context) (*CompileResult, error) {
-	m.CompileCalled = true
-	m.CompileContext = ctx
+	m.record("Compile", func() {
+		m.CompileCalled = true
+		m.CompileContext = ctx
+	})
 	if m.CompileFunc != nil {
 		return m.CompileFunc(ctx)
 	}
@@ -68,26 +96,305 @@ func (m *Mock) Compile(ctx *Context) (*CompileResult, error) {
 }
 
 func (m *Mock) Build(ctx *Context) error {
-	m.BuildCalled = true
-	m.BuildContext = ctx
+	m.record("Build", func() {
+		m.BuildCalled = true
+		m.BuildContext = ctx
+	})
 	return m.BuildErr
 }
 
 func (m *Mock) Deploy(ctx *Context) error {
-	m.DeployCalled = true
-	m.DeployContext = ctx
+	m.record("Deploy", func() {
+		m.DeployCalled = true
+		m.DeployContext = ctx
+	})
 	return m.DeployErr
 }
 
 func (m *Mock) Dev(ctx *Context) error {
-	m.DevCalled = true
-	m.DevContext = ctx
+	m.record("Dev", func() {
+		m.DevCalled = true
+		m.DevContext = ctx
+	})
 	return m.DevErr
 }
 
 func (m *Mock) DevDep(dst, src *Context) (*DevDep, error) {
-	m.DevDepCalled = true
-	m.DevDepContextDst = dst
-	m.DevDepContextSrc = src
+	m.record("DevDep", func() {
+		m.DevDepCalled = true
+		m.DevDepContextDst = dst
+		m.DevDepContextSrc = src
+	})
 	return m.DevDepResult, m.DevDepErr
 }
+
+// MockDevSnapshotter is a mock implementation of an App that also
+// implements DevSnapshotter.
+type MockDevSnapshotter struct {
+	Mock
+
+	DevSnapshotCalled bool
+	DevSnapshotName   string
+	DevSnapshotErr    error
+
+	DevRestoreCalled bool
+	DevRestoreName   string
+	DevRestoreErr    error
+
+	DevSnapshotsCalled bool
+	DevSnapshotsResult []SnapshotInfo
+	DevSnapshotsErr    error
+}
+
+func (m *MockDevSnapshotter) DevSnapshot(ctx *Context, name string) error {
+	m.record("DevSnapshot", func() {
+		m.DevSnapshotCalled = true
+		m.DevSnapshotName = name
+	})
+	return m.DevSnapshotErr
+}
+
+func (m *MockDevSnapshotter) DevRestore(ctx *Context, name string) error {
+	m.record("DevRestore", func() {
+		m.DevRestoreCalled = true
+		m.DevRestoreName = name
+	})
+	return m.DevRestoreErr
+}
+
+func (m *MockDevSnapshotter) DevSnapshots(ctx *Context) ([]SnapshotInfo, error) {
+	m.record("DevSnapshots", func() {
+		m.DevSnapshotsCalled = true
+	})
+	return m.DevSnapshotsResult, m.DevSnapshotsErr
+}
+
+// MockDevDepRefresher is a mock implementation of an App that also
+// implements DevDepRefresher.
+type MockDevDepRefresher struct {
+	Mock
+
+	DevDepRefreshCalled     bool
+	DevDepRefreshContextDst *Context
+	DevDepRefreshContextSrc *Context
+	DevDepRefreshErr        error
+}
+
+func (m *MockDevDepRefresher) DevDepRefresh(dst, src *Context) error {
+	m.record("DevDepRefresh", func() {
+		m.DevDepRefreshCalled = true
+		m.DevDepRefreshContextDst = dst
+		m.DevDepRefreshContextSrc = src
+	})
+	return m.DevDepRefreshErr
+}
+
+// MockValidator is a mock implementation of an App that also implements
+// Validator.
+type MockValidator struct {
+	Mock
+
+	ValidateCalled  bool
+	ValidateContext *Context
+	ValidateErr     error
+}
+
+func (m *MockValidator) Validate(ctx *Context) error {
+	m.record("Validate", func() {
+		m.ValidateCalled = true
+		m.ValidateContext = ctx
+	})
+	return m.ValidateErr
+}
+
+// MockHealthchecker is a mock implementation of an App that also
+// implements Healthchecker.
+type MockHealthchecker struct {
+	Mock
+
+	HealthCalled  bool
+	HealthContext *Context
+	HealthResult  *HealthReport
+	HealthErr     error
+	HealthFunc    func(*Context) (*HealthReport, error)
+}
+
+func (m *MockHealthchecker) Health(ctx *Context) (*HealthReport, error) {
+	m.record("Health", func() {
+		m.HealthCalled = true
+		m.HealthContext = ctx
+	})
+	if m.HealthFunc != nil {
+		return m.HealthFunc(ctx)
+	}
+
+	return m.HealthResult, m.HealthErr
+}
+
+// MockFoundationConfigurer is a mock implementation of an App that also
+// implements FoundationConfigurer.
+type MockFoundationConfigurer struct {
+	Mock
+
+	FoundationConfigCalled  bool
+	FoundationConfigContext *Context
+	FoundationConfigResult  *foundation.Config
+	FoundationConfigErr     error
+}
+
+func (m *MockFoundationConfigurer) FoundationConfig(ctx *Context) (*foundation.Config, error) {
+	m.record("FoundationConfig", func() {
+		m.FoundationConfigCalled = true
+		m.FoundationConfigContext = ctx
+	})
+	return m.FoundationConfigResult, m.FoundationConfigErr
+}
+
+// MockLogViewer is a mock implementation of an App that also implements
+// LogViewer. The output is written to the writer.
+type MockLogViewer struct {
+	Mock
+
+	LogsCalled  bool
+	LogsContext *Context
+	LogsOpts    LogOptions
+	LogsOutput  string
+	LogsErr     error
+
+	DevLogsCalled bool
+	DevLogsOpts   LogOptions
+	DevLogsOutput string
+	DevLogsErr    error
+}
+
+func (m *MockLogViewer) Logs(ctx *Context, opts LogOptions, w io.Writer) error {
+	m.record("Logs", func() {
+		m.LogsCalled = true
+		m.LogsContext = ctx
+		m.LogsOpts = opts
+	})
+	if _, err := io.WriteString(w, m.LogsOutput); err != nil {
+		return err
+	}
+
+	return m.LogsErr
+}
+
+func (m *MockLogViewer) DevLogs(ctx *Context, opts LogOptions, w io.Writer) error {
+	m.record("DevLogs", func() {
+		m.DevLogsCalled = true
+		m.DevLogsOpts = opts
+	})
+	if _, err := io.WriteString(w, m.DevLogsOutput); err != nil {
+		return err
+	}
+
+	return m.DevLogsErr
+}
+
+// MockConsoleProvider is a mock implementation of an App that also
+// implements ConsoleProvider.
+type MockConsoleProvider struct {
+	Mock
+
+	ConsoleCalled  bool
+	ConsoleContext *Context
+	ConsoleTarget  ConsoleTarget
+	ConsoleErr     error
+}
+
+func (m *MockConsoleProvider) Console(ctx *Context, target ConsoleTarget) error {
+	m.record("Console", func() {
+		m.ConsoleCalled = true
+		m.ConsoleContext = ctx
+		m.ConsoleTarget = target
+	})
+	return m.ConsoleErr
+}
+
+// MockPlanner is a mock implementation of an App that also implements
+// Planner.
+type MockPlanner struct {
+	Mock
+
+	PlanCalled  bool
+	PlanContext *Context
+	PlanResult  string
+	PlanErr     error
+}
+
+func (m *MockPlanner) Plan(ctx *Context) (string, error) {
+	m.record("Plan", func() {
+		m.PlanCalled = true
+		m.PlanContext = ctx
+	})
+	return m.PlanResult, m.PlanErr
+}
+
+// MockArtifactBuilder is a mock implementation of an App that also
+// implements ArtifactBuilder.
+type MockArtifactBuilder struct {
+	Mock
+
+	BuildArtifactsCalled  bool
+	BuildArtifactsContext *Context
+	BuildArtifactsResult  *BuildResult
+	BuildArtifactsErr     error
+}
+
+func (m *MockArtifactBuilder) BuildArtifacts(ctx *Context) (*BuildResult, error) {
+	m.record("BuildArtifacts", func() {
+		m.BuildArtifactsCalled = true
+		m.BuildArtifactsContext = ctx
+	})
+	return m.BuildArtifactsResult, m.BuildArtifactsErr
+}
+
+// MockRollout is a mock implementation of an App that also implements
+// all the rollout interfaces. The weights that are set are appended to
+// TrafficWeights.
+type MockRollout struct {
+	Mock
+
+	DeployInactiveCalled bool
+	DeployInactiveErr    error
+
+	SwitchTrafficCalled bool
+	SwitchTrafficErr    error
+
+	SetTrafficWeightCalled bool
+	TrafficWeights         []int
+	SetTrafficWeightErr    error
+
+	TeardownInactiveCalled bool
+	TeardownInactiveErr    error
+}
+
+func (m *MockRollout) DeployInactive(ctx *Context) error {
+	m.record("DeployInactive", func() {
+		m.DeployInactiveCalled = true
+	})
+	return m.DeployInactiveErr
+}
+
+func (m *MockRollout) SwitchTraffic(ctx *Context) error {
+	m.record("SwitchTraffic", func() {
+		m.SwitchTrafficCalled = true
+	})
+	return m.SwitchTrafficErr
+}
+
+func (m *MockRollout) SetTrafficWeight(ctx *Context, percent int) error {
+	m.record("SetTrafficWeight", func() {
+		m.SetTrafficWeightCalled = true
+	})
+	m.TrafficWeights = append(m.TrafficWeights, percent)
+	return m.SetTrafficWeightErr
+}
+
+func (m *MockRollout) TeardownInactive(ctx *Context) error {
+	m.record("TeardownInactive", func() {
+		m.TeardownInactiveCalled = true
+	})
+	return m.TeardownInactiveErr
+}
@@ -9,3 +9,56 @@ func TestMock_impl(t *testing.T) {
 	var _ App = new(Mock)
 	var _ io.Closer = new(Mock)
 }
+
+func TestMockDevSnapshotter_impl(t *testing.T) {
+	var _ App = new(MockDevSnapshotter)
+	var _ DevSnapshotter = new(MockDevSnapshotter)
+}
+
+func TestMockDevDepRefresher_impl(t *testing.T) {
+	var _ App = new(MockDevDepRefresher)
+	var _ DevDepRefresher = new(MockDevDepRefresher)
+}
+
+func TestMockValidator_impl(t *testing.T) {
+	var _ App = new(MockValidator)
+	var _ Validator = new(MockValidator)
+}
+
+func TestMockHealthchecker_impl(t *testing.T) {
+	var _ App = new(MockHealthchecker)
+	var _ Healthchecker = new(MockHealthchecker)
+}
+
+func TestMockFoundationConfigurer_impl(t *testing.T) {
+	var _ App = new(MockFoundationConfigurer)
+	var _ FoundationConfigurer = new(MockFoundationConfigurer)
+}
+
+func TestMockLogViewer_impl(t *testing.T) {
+	var _ App = new(MockLogViewer)
+	var _ LogViewer = new(MockLogViewer)
+}
+
+func TestMockConsoleProvider_impl(t *testing.T) {
+	var _ App = new(MockConsoleProvider)
+	var _ ConsoleProvider = new(MockConsoleProvider)
+}
+
+func TestMockRollout_impl(t *testing.T) {
+	var _ App = new(MockRollout)
+	var _ InactiveDeployer = new(MockRollout)
+	var _ TrafficSwitcher = new(MockRollout)
+	var _ TrafficWeighter = new(MockRollout)
+	var _ InactiveTeardowner = new(MockRollout)
+}
+
+func TestMockPlanner_impl(t *testing.T) {
+	var _ App = new(MockPlanner)
+	var _ Planner = new(MockPlanner)
+}
+
+func TestMockArtifactBuilder_impl(t *testing.T) {
+	var _ App = new(MockArtifactBuilder)
+	var _ ArtifactBuilder = new(MockArtifactBuilder)
+}
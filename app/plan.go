@@ -0,0 +1,22 @@
+package app
+
+import (
+	"errors"
+)
+
+// ErrPlanNotSupported is returned by Plan when the app can't describe a
+// deploy before it is done. This is mostly for apps that are served over
+// RPC, which always look like they implement Planner.
+var ErrPlanNotSupported = errors.New("plans are not supported")
+
+// Planner is an optional interface that an App can implement to describe
+// what a deploy would change, such as the resources that would be added,
+// changed or removed, without changing anything. Otto shows the plan when
+// it asks the user to confirm a deploy.
+type Planner interface {
+	// Plan returns the description of the changes that Deploy would make
+	// with the same context, ready to show to the user. The context has
+	// the infrastructure credentials and the DepOutputs of the deployed
+	// dependencies, like for Deploy.
+	Plan(ctx *Context) (string, error)
+}
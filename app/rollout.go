@@ -0,0 +1,70 @@
+package app
+
+import (
+	"errors"
+)
+
+// ErrRolloutNotSupported is returned by the rollout functions when the app
+// doesn't support them. This is mostly for apps that are served over RPC,
+// which always look like they implement the rollout interfaces.
+var ErrRolloutNotSupported = errors.New("the rollout step is not supported")
+
+// The rollout interfaces are optional interfaces that an App can implement
+// so that Otto can roll out a new deploy gradually, such as blue/green or
+// canary deploys. Otto sequences the steps and checks the health between
+// them; the app only does each step.
+//
+// During a rollout there are two deployments of the app: the live one
+// that gets the traffic, and the new one next to it.
+
+// InactiveDeployer deploys the new version next to the live one without
+// sending it any traffic.
+type InactiveDeployer interface {
+	DeployInactive(ctx *Context) error
+}
+
+// TrafficSwitcher sends all the traffic to the deployment that doesn't
+// get it, which makes it the live one. Calling it twice switches back.
+type TrafficSwitcher interface {
+	SwitchTraffic(ctx *Context) error
+}
+
+// TrafficWeighter sends the given percentage of the traffic, from 0 to
+// 100, to the new deployment, and the rest to the live one.
+type TrafficWeighter interface {
+	SetTrafficWeight(ctx *Context, percent int) error
+}
+
+// InactiveTeardowner removes the deployment that gets no traffic.
+type InactiveTeardowner interface {
+	TeardownInactive(ctx *Context) error
+}
+
+// RolloutSupporter is an optional interface for apps that only support
+// some of the rollout interfaces they implement, such as apps served over
+// RPC. RolloutSupport returns the names of the methods that are supported,
+// such as "DeployInactive", and Otto uses it instead of the type of the
+// app to check what a rollout strategy requires.
+type RolloutSupporter interface {
+	RolloutSupport() ([]string, error)
+}
+
+// RolloutMethods returns the names of the rollout methods that the app
+// implements, in the order they're used by a rollout.
+func RolloutMethods(a App) []string {
+	var result []string
+	if _, ok := a.(InactiveDeployer); ok {
+		result = append(result, "DeployInactive")
+	}
+	if _, ok := a.(TrafficWeighter); ok {
+		result = append(result, "SetTrafficWeight")
+	}
+	if _, ok := a.(TrafficSwitcher); ok {
+		result = append(result, "SwitchTraffic")
+	}
+	if _, ok := a.(InactiveTeardowner); ok {
+		result = append(result, "TeardownInactive")
+	}
+
+	return result
+}
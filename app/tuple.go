@@ -2,6 +2,7 @@ package app
 
 import (
 	"fmt"
+	"strings"
 )
 
 // Tuple is the tupled used for looking up the App implementation
@@ -13,10 +14,56 @@ type Tuple struct {
 	InfraFlavor string // InfraFlavor is the flavor, i.e. "vpc-public-private"
 }
 
+// String returns the canonical form of the Tuple, such as
+// "rails/aws/vpc-public-private", which ParseTuple parses. A "/" or "\" in
+// a field is escaped with a "\".
 func (t Tuple) String() string {
-	return fmt.Sprintf("(%q, %q, %q)", t.App, t.Infra, t.InfraFlavor)
+	return strings.Join([]string{
+		tupleEscaper.Replace(t.App),
+		tupleEscaper.Replace(t.Infra),
+		tupleEscaper.Replace(t.InfraFlavor),
+	}, "/")
 }
 
+// ParseTuple parses a Tuple in the canonical form of Tuple.String, such
+// as "rails/aws/vpc-public-private". Any field can be the wildcard "*".
+func ParseTuple(v string) (Tuple, error) {
+	var fields []string
+	var field []byte
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case '\\':
+			i++
+			if i == len(v) || (v[i] != '\\' && v[i] != '/') {
+				return Tuple{}, fmt.Errorf(
+					"invalid app tuple %q: invalid escape", v)
+			}
+
+			field = append(field, v[i])
+		case '/':
+			fields = append(fields, string(field))
+			field = nil
+		default:
+			field = append(field, v[i])
+		}
+	}
+	fields = append(fields, string(field))
+
+	if len(fields) != 3 {
+		return Tuple{}, fmt.Errorf(
+			"invalid app tuple %q: expected app/infra/flavor", v)
+	}
+
+	return Tuple{
+		App:         fields[0],
+		Infra:       fields[1],
+		InfraFlavor: fields[2],
+	}, nil
+}
+
+// tupleEscaper escapes the fields of a Tuple for Tuple.String.
+var tupleEscaper = strings.NewReplacer(`\`, `\\`, "/", `\/`)
+
 // TupleSlice is an alias of []Tuple that implements sort.Interface for
 // sorting tuples. See the tests in tuple_test.go to see the sorting order.
 type TupleSlice []Tuple
@@ -53,33 +100,81 @@ func (s TupleSlice) Map(f Factory) TupleMap {
 
 // TupleMap is an alias of map[Tuple]Factory that adds additional helper
 // methods on top to help work with app tuples.
+//
+// Any field of a Tuple in the map can be the wildcard "*", which matches
+// any value. When more than one Tuple matches, the most specific one wins:
+// an exact match, then a Tuple with a single wildcard, then two, then
+// three. Between Tuples with the same number of wildcards, the fields
+// have priority in order: App, then Infra, then InfraFlavor. So "x/y/*"
+// wins over "x/*/z", which wins over "*/y/z".
+//
+// Since the fields have priority, two different Tuples that match the
+// same Tuple never rank the same, so every lookup is unambiguous.
 type TupleMap map[Tuple]Factory
 
 // Lookup looks up a Tuple. This should be used instead of direct [] access
-// since it respects wildcards ('*') within the Tuple.
+// since it respects wildcards ('*') within the Tuple. The result doesn't
+// depend on the order of the map, see TupleMap.
 func (m TupleMap) Lookup(t Tuple) Factory {
 	// If it just exists, return it
 	if f, ok := m[t]; ok {
 		return f
 	}
 
-	// Eh, this isn't terrible complexity, but we should probably look
-	// to do better than this at some point.
+	var result Factory
+	best := -1
 	for h, f := range m {
-		if h.App != "*" && h.App != t.App {
-			continue
-		}
-		if h.Infra != "*" && h.Infra != t.Infra {
+		if !h.match(t) {
 			continue
 		}
-		if h.InfraFlavor != "*" && h.InfraFlavor != t.InfraFlavor {
-			continue
+
+		if s := h.specificity(); s > best {
+			best = s
+			result = f
 		}
+	}
 
-		return f
+	return result
+}
+
+// match returns true if other, which has no wildcards, matches t.
+func (t Tuple) match(other Tuple) bool {
+	return tupleFieldMatch(t.App, other.App) &&
+		tupleFieldMatch(t.Infra, other.Infra) &&
+		tupleFieldMatch(t.InfraFlavor, other.InfraFlavor)
+}
+
+// specificity ranks how specific the Tuple is for Lookup. Fewer wildcards
+// always rank higher, and then the fields with a higher priority.
+func (t Tuple) specificity() int {
+	result := (3 - t.wildcards()) * 8
+	if t.App != "*" {
+		result += 4
 	}
+	if t.Infra != "*" {
+		result += 2
+	}
+	if t.InfraFlavor != "*" {
+		result += 1
+	}
+
+	return result
+}
+
+// wildcards returns the number of fields of the Tuple that are "*".
+func (t Tuple) wildcards() int {
+	result := 0
+	for _, v := range []string{t.App, t.Infra, t.InfraFlavor} {
+		if v == "*" {
+			result++
+		}
+	}
+
+	return result
+}
 
-	return nil
+func tupleFieldMatch(pattern, v string) bool {
+	return pattern == "*" || pattern == v
 }
 
 // Add is a helper to add another map to this one.
@@ -0,0 +1,13 @@
+package app
+
+// Validator is an optional interface that an App can implement to check
+// for misconfiguration that is cheap to detect, such as a missing
+// customization or an unsupported runtime version. Otto calls Validate
+// for every app before it compiles it, and from Core.Validate.
+//
+// An error fails the compilation before anything is compiled. Warnings
+// that shouldn't fail the compilation can be output with ui.Warn on the
+// Ui of the context.
+type Validator interface {
+	Validate(ctx *Context) error
+}
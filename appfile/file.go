@@ -35,6 +35,10 @@ type File struct {
 	Infrastructure []*Infrastructure
 	Customization  *CustomizationSet
 
+	// SmokeTests are the checks that are run against the application
+	// after every successful deploy.
+	SmokeTests []*SmokeTest
+
 	// Imports is the list of imports that this File made. The imports
 	// are realized during compilation, but this list won't be cleared
 	// in case it wants to be inspected later.
@@ -66,6 +70,30 @@ type Dependency struct {
 type Project struct {
 	Name           string
 	Infrastructure string
+
+	// ArtifactName and RecordName are the naming templates of the files
+	// that builds upload to the artifact store and of the build and
+	// deploy records. See NameVars for the variables of the templates.
+	ArtifactName string `mapstructure:"artifact_name"`
+	RecordName   string `mapstructure:"record_name"`
+
+	// Env are the environment variables of the project, from its env
+	// blocks, which Otto gives to the apps, the infrastructure, and the
+	// foundations of the project.
+	Env []*EnvVar
+}
+
+// EnvVar is an environment variable of a project.
+type EnvVar struct {
+	Name string
+
+	// Value is the value of the variable. It can reference the process
+	// environment of Otto, such as "${HTTP_PROXY}", and "$$" is a "$".
+	Value string
+
+	// Secret is true if the value must not be output, so it's redacted
+	// from the output and the debug bundles of Otto.
+	Secret bool
 }
 
 // Infrastructure is the structure of defining the infrastructure
@@ -85,6 +113,30 @@ type Foundation struct {
 	Config map[string]interface{}
 }
 
+// SmokeTest is a check that is run against the deployed application
+// after every successful deploy, in the order they're defined.
+type SmokeTest struct {
+	Name string
+
+	// Type is "http" to request Target, a URL, and check its status
+	// code, or "command" to run Target with the shell and check its exit
+	// status. Expect is the expected status code or exit status, which
+	// defaults to 200 or 0.
+	//
+	// Target can reference the deploy information and the outputs of the
+	// infrastructure, such as "${deploy.url}" and "${infra.region}".
+	Type   string
+	Target string
+	Expect string
+
+	// Timeout is how long the check can take, such as "30s".
+	Timeout string
+
+	// OnFailure is "fail" to fail the deploy when the check fails, which
+	// is the default, or "warn" to only output a warning.
+	OnFailure string `mapstructure:"on_failure"`
+}
+
 // Import is an import request of another Appfile into this one
 type Import struct {
 	Source string
@@ -143,6 +195,11 @@ func (f *File) Merge(other *File) error {
 	// TODO: customizations
 	f.Customization = other.Customization
 
+	// Smoke tests
+	if len(other.SmokeTests) > 0 {
+		f.SmokeTests = other.SmokeTests
+	}
+
 	return nil
 }
 
@@ -244,10 +301,18 @@ func (v *Infrastructure) GoString() string {
 	return fmt.Sprintf("*%#v", *v)
 }
 
+func (v *SmokeTest) GoString() string {
+	return fmt.Sprintf("*%#v", *v)
+}
+
 func (v *Project) GoString() string {
 	return fmt.Sprintf("*%#v", *v)
 }
 
+func (v *EnvVar) GoString() string {
+	return fmt.Sprintf("*%#v", *v)
+}
+
 const idFileTemplate = `
 %s
 
@@ -117,6 +117,28 @@ func TestFileMerge(t *testing.T) {
 			},
 		},
 
+		"SmokeTests": {
+			One: &File{
+				SmokeTests: []*SmokeTest{&SmokeTest{Name: "foo"}},
+			},
+			Two: &File{
+				SmokeTests: []*SmokeTest{&SmokeTest{Name: "bar"}},
+			},
+			Three: &File{
+				SmokeTests: []*SmokeTest{&SmokeTest{Name: "bar"}},
+			},
+		},
+
+		"SmokeTests (no merge)": {
+			One: &File{
+				SmokeTests: []*SmokeTest{&SmokeTest{Name: "foo"}},
+			},
+			Two: &File{},
+			Three: &File{
+				SmokeTests: []*SmokeTest{&SmokeTest{Name: "foo"}},
+			},
+		},
+
 		"Infra (no merge)": {
 			One: &File{
 				Infrastructure: []*Infrastructure{
@@ -0,0 +1,75 @@
+package appfile
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"text/template"
+)
+
+// NameVars are the variables of the naming templates of a project, which
+// are Go templates such as "{{.Project}}-{{.App}}-{{.Version}}".
+type NameVars struct {
+	App         string // Name of the application
+	AppID       string // ID of the Appfile of the application
+	Project     string // Name of the project
+	Environment string // Environment of the build or deploy
+	Infra       string // Type of the active infrastructure
+	Fingerprint string // Fingerprint of the source of the application
+	Version     string // Version given to the build, if any
+	Timestamp   string // Time of the build, as 20060102150405 in UTC
+}
+
+// nameRegexp matches the names that templates can produce. Names are used
+// as keys in artifact stores, so they're limited to the characters that
+// are safe in paths and URLs.
+var nameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+
+// NameTemplate is a parsed naming template.
+type NameTemplate struct {
+	tpl *template.Template
+}
+
+// ParseNameTemplate parses the naming template s. The template is checked
+// with sample variables so that references to unknown variables and
+// invalid names are found before the template is used.
+func ParseNameTemplate(s string) (*NameTemplate, error) {
+	tpl, err := template.New("name").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &NameTemplate{tpl: tpl}
+	_, err = result.Execute(&NameVars{
+		App:         "app",
+		AppID:       "00000000-0000-0000-0000-000000000000",
+		Project:     "project",
+		Environment: "default",
+		Infra:       "aws",
+		Fingerprint: "0123456789ab",
+		Version:     "1.0.0",
+		Timestamp:   "20160102150405",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Execute returns the name for the variables vars.
+func (t *NameTemplate) Execute(vars *NameVars) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tpl.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+
+	name := buf.String()
+	if !nameRegexp.MatchString(name) {
+		return "", fmt.Errorf(
+			"invalid name %q: names must start with a letter or digit and\n"+
+				"only have letters, digits, '.', '_', '-', and '/'", name)
+	}
+
+	return name, nil
+}
@@ -0,0 +1,57 @@
+package appfile
+
+import (
+	"testing"
+)
+
+func TestParseNameTemplate(t *testing.T) {
+	cases := []struct {
+		Input string
+		Err   bool
+	}{
+		{"{{.Project}}-{{.App}}-{{.Timestamp}}", false},
+		{"builds/{{.Environment}}/{{.Version}}", false},
+		{"{{.Project", true},
+		{"{{.Sha}}", true},
+		{"-{{.App}}", true},
+		{"{{.App}} {{.Version}}", true},
+	}
+
+	for _, tc := range cases {
+		_, err := ParseNameTemplate(tc.Input)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s: %v", tc.Input, err)
+		}
+	}
+}
+
+func TestNameTemplate(t *testing.T) {
+	tpl, err := ParseNameTemplate("{{.Project}}-{{.App}}-{{.Version}}")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	name, err := tpl.Execute(&NameVars{Project: "acme", App: "web", Version: "1.2"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if name != "acme-web-1.2" {
+		t.Fatalf("bad: %s", name)
+	}
+
+	// The version is given by the user, so it can make an invalid name
+	if _, err := tpl.Execute(&NameVars{Project: "acme", App: "web", Version: "a b"}); err == nil {
+		t.Fatal("should error")
+	}
+
+	tpl, err = ParseNameTemplate("{{.App}}-{{.Fingerprint}}")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	name, err = tpl.Execute(&NameVars{App: "web", Fingerprint: "0123456789ab"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if name != "web-0123456789ab" {
+		t.Fatalf("bad: %s", name)
+	}
+}
@@ -45,6 +45,7 @@ func Parse(r io.Reader) (*File, error) {
 		"import",
 		"infrastructure",
 		"project",
+		"smoke_test",
 	}
 	if err := checkHCLKeys(list, valid); err != nil {
 		return nil, err
@@ -87,6 +88,13 @@ func Parse(r io.Reader) (*File, error) {
 		}
 	}
 
+	// Parse the smoke tests
+	if o := list.Filter("smoke_test"); len(o.Items) > 0 {
+		if err := parseSmokeTests(&result, o); err != nil {
+			return nil, fmt.Errorf("error parsing 'smoke_test': %s", err)
+		}
+	}
+
 	return &result, nil
 }
 
@@ -293,6 +301,50 @@ func parseFoundations(result *Infrastructure, list *ast.ObjectList) error {
 	return nil
 }
 
+func parseSmokeTests(result *File, list *ast.ObjectList) error {
+	list = list.Children()
+	if len(list.Items) == 0 {
+		return nil
+	}
+
+	// Go through each object and turn it into an actual result.
+	collection := make([]*SmokeTest, 0, len(list.Items))
+	seen := make(map[string]struct{})
+	for _, item := range list.Items {
+		n := item.Keys[0].Token.Value().(string)
+
+		// Make sure we haven't already found this
+		if _, ok := seen[n]; ok {
+			return fmt.Errorf("smoke_test '%s' defined more than once", n)
+		}
+		seen[n] = struct{}{}
+
+		// Check for invalid keys
+		valid := []string{"type", "target", "expect", "timeout", "on_failure"}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf(
+				"smoke_test '%s':", n))
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, item.Val); err != nil {
+			return err
+		}
+
+		var t SmokeTest
+		if err := mapstructure.WeakDecode(m, &t); err != nil {
+			return fmt.Errorf(
+				"error parsing smoke_test '%s': %s", n, err)
+		}
+		t.Name = n
+
+		collection = append(collection, &t)
+	}
+
+	result.SmokeTests = collection
+	return nil
+}
+
 func parseProject(result *File, list *ast.ObjectList) error {
 	if len(list.Items) > 1 {
 		return fmt.Errorf("only one 'project' block allowed")
@@ -302,15 +354,23 @@ func parseProject(result *File, list *ast.ObjectList) error {
 	item := list.Items[0]
 
 	// Check for invalid keys
-	valid := []string{"name", "infrastructure"}
+	valid := []string{"name", "infrastructure", "artifact_name", "record_name", "env"}
 	if err := checkHCLKeys(item.Val, valid); err != nil {
 		return multierror.Prefix(err, "project:")
 	}
 
+	var listVal *ast.ObjectList
+	if ot, ok := item.Val.(*ast.ObjectType); ok {
+		listVal = ot.List
+	} else {
+		return fmt.Errorf("project: should be an object")
+	}
+
 	var m map[string]interface{}
 	if err := hcl.DecodeObject(&m, item.Val); err != nil {
 		return err
 	}
+	delete(m, "env")
 
 	// Parse the project
 	var proj Project
@@ -319,6 +379,55 @@ func parseProject(result *File, list *ast.ObjectList) error {
 		return err
 	}
 
+	// Parse the environment variables if we have any
+	if o := listVal.Filter("env"); len(o.Items) > 0 {
+		if err := parseEnv(&proj, o); err != nil {
+			return fmt.Errorf("error parsing 'env': %s", err)
+		}
+	}
+
+	return nil
+}
+
+func parseEnv(result *Project, list *ast.ObjectList) error {
+	list = list.Children()
+	if len(list.Items) == 0 {
+		return nil
+	}
+
+	// Go through each object and turn it into an actual result.
+	collection := make([]*EnvVar, 0, len(list.Items))
+	seen := make(map[string]struct{})
+	for _, item := range list.Items {
+		n := item.Keys[0].Token.Value().(string)
+
+		// Make sure we haven't already found this
+		if _, ok := seen[n]; ok {
+			return fmt.Errorf("env '%s' defined more than once", n)
+		}
+		seen[n] = struct{}{}
+
+		// Check for invalid keys
+		valid := []string{"value", "secret"}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("env '%s':", n))
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, item.Val); err != nil {
+			return err
+		}
+
+		var v EnvVar
+		if err := mapstructure.WeakDecode(m, &v); err != nil {
+			return fmt.Errorf("error parsing env '%s': %s", n, err)
+		}
+		v.Name = n
+
+		collection = append(collection, &v)
+	}
+
+	result.Env = collection
 	return nil
 }
 
@@ -151,6 +151,66 @@ func TestParse(t *testing.T) {
 			true,
 		},
 
+		{
+			"smoke-test.hcl",
+			&File{
+				Application: &Application{
+					Name:   "foo",
+					Detect: true,
+				},
+				SmokeTests: []*SmokeTest{
+					&SmokeTest{
+						Name:    "home",
+						Type:    "http",
+						Target:  "${deploy.url}/health",
+						Expect:  "200",
+						Timeout: "10s",
+					},
+					&SmokeTest{
+						Name:      "migrations",
+						Type:      "command",
+						Target:    "./check-migrations ${infra.db_address}",
+						OnFailure: "warn",
+					},
+				},
+			},
+			false,
+		},
+
+		{
+			"smoke-test-dup.hcl",
+			nil,
+			true,
+		},
+
+		{
+			"project-env.hcl",
+			&File{
+				Project: &Project{
+					Name:           "foo",
+					Infrastructure: "aws",
+					Env: []*EnvVar{
+						&EnvVar{
+							Name:  "HTTP_PROXY",
+							Value: "http://proxy:3128",
+						},
+						&EnvVar{
+							Name:   "API_TOKEN",
+							Value:  "${API_TOKEN}",
+							Secret: true,
+						},
+					},
+				},
+			},
+			false,
+		},
+
+		{
+			"project-env-dup.hcl",
+			nil,
+			true,
+		},
+
 		// Imports
 
 		{
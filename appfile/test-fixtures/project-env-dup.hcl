@@ -0,0 +1,12 @@
+project {
+    name = "foo"
+    infrastructure = "aws"
+
+    env "FOO" {
+        value = "1"
+    }
+
+    env "FOO" {
+        value = "2"
+    }
+}
@@ -0,0 +1,13 @@
+project {
+    name = "foo"
+    infrastructure = "aws"
+
+    env "HTTP_PROXY" {
+        value = "http://proxy:3128"
+    }
+
+    env "API_TOKEN" {
+        value = "${API_TOKEN}"
+        secret = true
+    }
+}
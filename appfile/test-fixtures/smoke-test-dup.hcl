@@ -0,0 +1,13 @@
+application {
+    name = "foo"
+}
+
+smoke_test "home" {
+    type = "http"
+    target = "${deploy.url}"
+}
+
+smoke_test "home" {
+    type = "http"
+    target = "${deploy.url}/health"
+}
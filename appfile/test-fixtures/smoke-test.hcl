@@ -0,0 +1,16 @@
+application {
+    name = "foo"
+}
+
+smoke_test "home" {
+    type = "http"
+    target = "${deploy.url}/health"
+    expect = 200
+    timeout = "10s"
+}
+
+smoke_test "migrations" {
+    type = "command"
+    target = "./check-migrations ${infra.db_address}"
+    on_failure = "warn"
+}
@@ -2,6 +2,9 @@ package appfile
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 )
@@ -59,6 +62,60 @@ func (f *File) Validate() error {
 					f.Project.Infrastructure))
 			}
 		}
+
+		names := []struct{ Key, Template string }{
+			{"artifact_name", f.Project.ArtifactName},
+			{"record_name", f.Project.RecordName},
+		}
+		for _, n := range names {
+			if n.Template == "" {
+				continue
+			}
+			if _, err := ParseNameTemplate(n.Template); err != nil {
+				result = multierror.Append(result, fmt.Errorf(
+					"project: %s: %s", n.Key, err))
+			}
+		}
+
+		for _, v := range f.Project.Env {
+			if v.Name == "" || strings.ContainsAny(v.Name, "=\x00") {
+				result = multierror.Append(result, fmt.Errorf(
+					"project: env '%s': name must be non-empty without '=' or NUL",
+					v.Name))
+			}
+		}
+	}
+
+	// Validate the smoke tests
+	for _, t := range f.SmokeTests {
+		switch t.Type {
+		case "http", "command":
+		default:
+			result = multierror.Append(result, fmt.Errorf(
+				"smoke_test '%s': type must be 'http' or 'command'", t.Name))
+		}
+		if t.Target == "" {
+			result = multierror.Append(result, fmt.Errorf(
+				"smoke_test '%s': target is required", t.Name))
+		}
+		if t.Expect != "" {
+			if _, err := strconv.Atoi(t.Expect); err != nil {
+				result = multierror.Append(result, fmt.Errorf(
+					"smoke_test '%s': expect must be a number", t.Name))
+			}
+		}
+		if t.Timeout != "" {
+			if _, err := time.ParseDuration(t.Timeout); err != nil {
+				result = multierror.Append(result, fmt.Errorf(
+					"smoke_test '%s': invalid timeout: %s", t.Name, err))
+			}
+		}
+		switch t.OnFailure {
+		case "", "fail", "warn":
+		default:
+			result = multierror.Append(result, fmt.Errorf(
+				"smoke_test '%s': on_failure must be 'fail' or 'warn'", t.Name))
+		}
 	}
 
 	return result
@@ -54,6 +54,31 @@ func TestFileValidate(t *testing.T) {
 			"validate-project-unknown-infra",
 			true,
 		},
+
+		{
+			"validate-smoke-test",
+			false,
+		},
+
+		{
+			"validate-smoke-test-bad",
+			true,
+		},
+
+		{
+			"validate-naming",
+			false,
+		},
+
+		{
+			"validate-naming-bad",
+			true,
+		},
+
+		{
+			"validate-env-bad",
+			true,
+		},
 	}
 
 	for _, tc := range cases {
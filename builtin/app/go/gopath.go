@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/ui"
 )
 
 // DetectImportPath will try to automatically determine the import path
@@ -17,13 +18,13 @@ import (
 func DetectImportPath(ctx *app.Context) (string, error) {
 	gopath := os.Getenv("GOPATH")
 	if gopath == "" {
-		ctx.Ui.Message(
-			"Warning! GOPATH not set. Otto will be unable to automatically\n" +
-				"setup your application GOPATH for development and builds. While Otto\n" +
-				"sets up a development for you, your folder structure outside of Otto\n" +
-				"should still represent a proper Go environment. If you do this, then\n" +
-				"the development and build process function a lot smoother.\n\n" +
-				"For simple Go applications, this may not be necessary.\n\n" +
+		ui.Warn(ctx.Ui,
+			"GOPATH not set. Otto will be unable to automatically\n"+
+				"setup your application GOPATH for development and builds. While Otto\n"+
+				"sets up a development for you, your folder structure outside of Otto\n"+
+				"should still represent a proper Go environment. If you do this, then\n"+
+				"the development and build process function a lot smoother.\n\n"+
+				"For simple Go applications, this may not be necessary.\n\n"+
 				"This is just an informational message. This is not a bug.")
 		return "", nil
 	}
@@ -59,11 +60,11 @@ func DetectImportPath(ctx *app.Context) (string, error) {
 	// The directory has to be prefixed with the gopath
 	gopath = filepath.Join(gopath, "src")
 	if !strings.HasPrefix(dir, gopath) {
-		ctx.Ui.Message(
-			"Warning! It looks like your application is not within your set\n" +
-				"GOPATH. Otto will be unable to automatically setup the proper\n" +
-				"GOPATH structure within your development and build environments.\n\n" +
-				"To fix this, please put your application into the proper GOPATH\n" +
+		ui.Warn(ctx.Ui,
+			"It looks like your application is not within your set\n"+
+				"GOPATH. Otto will be unable to automatically setup the proper\n"+
+				"GOPATH structure within your development and build environments.\n\n"+
+				"To fix this, please put your application into the proper GOPATH\n"+
 				"location as according to standard Go development practices.")
 		return "", nil
 	}
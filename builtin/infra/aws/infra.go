@@ -45,6 +45,7 @@ func creds(ctx *infrastructure.Context) (map[string]string, error) {
 			Query:       "AWS Secret Key",
 			Description: "AWS secret key used for API calls.",
 			EnvVars:     []string{"AWS_SECRET_ACCESS_KEY"},
+			Hide:        true,
 		},
 		&ui.InputOpts{
 			Id:          "ssh_public_key_path",
@@ -3,6 +3,9 @@ package command
 import (
 	"fmt"
 	"strings"
+
+	"github.com/hashicorp/otto/helper/flag"
+	"github.com/hashicorp/otto/otto"
 )
 
 // BuildCommand is the command that builds a deployable artifact
@@ -12,18 +15,24 @@ type BuildCommand struct {
 }
 
 func (c *BuildCommand) Run(args []string) int {
+	var opts otto.BuildOpts
+	var vars flag.KV
 	fs := c.FlagSet("build", FlagSetNone)
 	fs.Usage = func() { c.Ui.Error(c.Help()) }
-
+	fs.StringVar(&opts.Version, "version", "", "version")
+	fs.Var(&vars, "var", "var")
+	args, execArgs, posArgs := flag.FilterArgs(fs, args)
 	if err := fs.Parse(args); err != nil {
 		return 1
 	}
 
-	// Show usage if build command was passed any arguments.
-	if len(args) != 0 {
-		fs.Usage()
-		return 1
+	// Get the remaining args to determine if we have an action.
+	if len(posArgs) > 0 {
+		opts.Action = posArgs[0]
+		execArgs = append(execArgs, posArgs[1:]...)
 	}
+	opts.Args = execArgs
+	opts.Vars = vars
 
 	// Load the appfile
 	app, err := c.Appfile()
@@ -39,11 +48,12 @@ func (c *BuildCommand) Run(args []string) int {
 			"Error loading core: %s", err))
 		return 1
 	}
+	defer core.Close()
 
 	// Build the artifact
-	if err := core.Build(); err != nil {
-		c.Ui.Error(fmt.Sprintf(
-			"Error building app: %s", err))
+	if err := core.BuildWithOpts(&opts); err != nil {
+		c.ErrorHint(fmt.Sprintf(
+			"Error building app: %s", err), err)
 		return 1
 	}
 
@@ -56,7 +66,7 @@ func (c *BuildCommand) Synopsis() string {
 
 func (c *BuildCommand) Help() string {
 	helpText := `
-Usage: otto build [options]
+Usage: otto build [options] [ACTION] [ARGS...]
 
   Builds the deployable artifact for the app on the target
   infrastructure specified during compilation of the Appfile.
@@ -64,6 +74,22 @@ Usage: otto build [options]
   This will build and inventory the artifact that is deployable
   for the app represented by this Appfile.
 
+  The ACTION and ARGS, if given, are passed to the app type, which
+  decides what they do. Run "otto build help" to see the actions of
+  the app type, if it has any.
+
+Options:
+
+  -var 'key=value'     A variable for the build, such as the tag of an
+                       image. This can be given more than once. The
+                       variables are passed to the app type and recorded
+                       with the build.
+
+  -version=VERSION     The version of the build for the naming templates
+                       of the project, such as a release number or a
+                       commit. The builds are named with the record_name
+                       template of the project block of the Appfile.
+
 `
 
 	return strings.TrimSpace(helpText)
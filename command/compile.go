@@ -153,6 +153,7 @@ func (c *CompileCommand) Run(args []string) int {
 			"Error loading core: %s", err))
 		return 1
 	}
+	defer core.Close()
 
 	// Get the active infrastructure just for UI reasons
 	infra := app.ActiveInfrastructure()
@@ -172,16 +173,16 @@ func (c *CompileCommand) Run(args []string) int {
 
 	// Compile!
 	if err := core.Compile(); err != nil {
-		c.Ui.Error(fmt.Sprintf(
-			"Error compiling: %s", err))
+		c.ErrorHint(fmt.Sprintf(
+			"Error compiling: %s", err), err)
 		return 1
 	}
 
 	// Store the used plugins so later calls don't have to load everything
 	usedPath, err := c.AppfilePluginsPath(capp)
 	if err != nil {
-		c.Ui.Error(fmt.Sprintf(
-			"Error compiling: %s", err))
+		c.ErrorHint(fmt.Sprintf(
+			"Error compiling: %s", err), err)
 		return 1
 	}
 	if err := pluginMgr.StoreUsed(usedPath); err != nil {
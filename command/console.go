@@ -0,0 +1,77 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/otto/app"
+)
+
+// ConsoleCommand is the command that opens an interactive shell into the
+// development environment or onto the deployed application.
+type ConsoleCommand struct {
+	Meta
+}
+
+func (c *ConsoleCommand) Run(args []string) int {
+	var target app.ConsoleTarget
+	fs := c.FlagSet("console", FlagSetNone)
+	fs.Usage = func() { c.Ui.Error(c.Help()) }
+	fs.BoolVar(&target.Dev, "dev", false, "")
+	fs.StringVar(&target.Instance, "instance", "", "")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	// Load the appfile
+	app, err := c.Appfile()
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	// Get a core
+	core, err := c.Core(app)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error loading core: %s", err))
+		return 1
+	}
+	defer core.Close()
+
+	// Open the console
+	if err := core.Console(target); err != nil {
+		c.ErrorHint(err.Error(), err)
+		return 1
+	}
+
+	return 0
+}
+
+func (c *ConsoleCommand) Synopsis() string {
+	return "Open a shell into the application"
+}
+
+func (c *ConsoleCommand) Help() string {
+	helpText := `
+Usage: otto console [options]
+
+  Opens an interactive shell onto the deployed application, or into the
+  development environment with -dev.
+
+  The application type must support consoles. This must be run from a
+  terminal.
+
+Options:
+
+  -dev                   Open the shell into the development environment
+                         instead of the deploy.
+
+  -instance=ID           The instance to connect to if the application
+                         runs on many. By default, the application type
+                         picks one.
+
+`
+
+	return strings.TrimSpace(helpText)
+}
@@ -2,9 +2,12 @@ package command
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/otto/helper/flag"
+	"github.com/hashicorp/otto/otto"
 )
 
 // DeployCommand is the command that deploys the app once it is built.
@@ -13,13 +16,42 @@ type DeployCommand struct {
 }
 
 func (c *DeployCommand) Run(args []string) int {
+	var opts otto.DeployOpts
+	var strategy, canarySteps string
+	var rollback bool
+	var dep string
+	var all, redeploy bool
 	fs := c.FlagSet("deploy", FlagSetNone)
 	fs.Usage = func() { c.Ui.Error(c.Help()) }
+	fs.BoolVar(&opts.Health, "health", false, "")
+	fs.DurationVar(&opts.HealthGrace, "health-grace", time.Minute, "")
+	fs.StringVar(&strategy, "strategy", "", "")
+	fs.StringVar(&canarySteps, "canary-steps", "", "")
+	fs.BoolVar(&opts.Resume, "resume", false, "")
+	fs.BoolVar(&rollback, "rollback", false, "")
+	fs.BoolVar(&opts.Confirm, "confirm", false, "")
+	fs.BoolVar(&opts.AutoApprove, "auto-approve", false, "")
+	fs.StringVar(&dep, "dep", "", "")
+	fs.BoolVar(&all, "all", false, "")
+	fs.BoolVar(&redeploy, "redeploy", false, "")
 	args, execArgs, posArgs := flag.FilterArgs(fs, args)
 	if err := fs.Parse(args); err != nil {
 		return 1
 	}
 
+	opts.Strategy = otto.DeployStrategy(strategy)
+	if canarySteps != "" {
+		for _, v := range strings.Split(canarySteps, ",") {
+			w, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				c.Ui.Error(fmt.Sprintf(
+					"Invalid canary step %q: must be a percentage", v))
+				return 1
+			}
+			opts.CanarySteps = append(opts.CanarySteps, w)
+		}
+	}
+
 	// Get the remaining args to determine if we have an action.
 	var action string
 	if len(posArgs) > 0 {
@@ -41,20 +73,72 @@ func (c *DeployCommand) Run(args []string) int {
 			"Error loading core: %s", err))
 		return 1
 	}
+	defer core.Close()
+
+	// Deploy the dependencies and the application in order
+	if all {
+		if action != "" || dep != "" || strategy != "" || opts.Resume || rollback {
+			c.Ui.Error("The -all flag can't be used with a subcommand, -dep,\n" +
+				"-strategy, -resume or -rollback.")
+			return 1
+		}
+
+		err := core.DeployAll(&otto.DeployAllOpts{
+			Force:       redeploy,
+			Confirm:     opts.Confirm,
+			AutoApprove: opts.AutoApprove,
+		})
+		if err == otto.ErrDeployDeclined {
+			c.Ui.Error(err.Error())
+			return 2
+		}
+		if err != nil {
+			c.ErrorHint(err.Error(), err)
+			return 1
+		}
+
+		return 0
+	}
+
+	// Deploy only the dependency, which doesn't support the options of
+	// the root application
+	if dep != "" {
+		if strategy != "" || opts.Resume || rollback || opts.Confirm {
+			c.Ui.Error("The -dep flag can't be used with -strategy, -resume,\n" +
+				"-rollback or -confirm.")
+			return 1
+		}
+
+		if err := core.DeployDep(dep, action, execArgs); err != nil {
+			c.ErrorHint(err.Error(), err)
+			return 1
+		}
+
+		return 0
+	}
 
-	// Destroy action gets an extra double-check
-	if action == "destroy" {
-		msg := "Otto will delete all resources associated with the deploy."
-		if !c.confirmDestroy(msg, execArgs) {
+	// Roll back the unfinished rollout
+	if rollback {
+		if err := core.RolloutRollback(); err != nil {
+			c.ErrorHint(err.Error(), err)
 			return 1
 		}
+
+		return 0
 	}
 
 	// Deploy the artifact
-	if err := core.Deploy(action, execArgs); err != nil {
+	if err := core.DeployWithOpts(action, execArgs, &opts); err != nil {
+		// Declining the deploy has its own exit status so scripts can
+		// tell it apart from a failure.
+		if err == otto.ErrDeployDeclined {
+			c.Ui.Error(err.Error())
+			return 2
+		}
+
 		// Display errors without prefix, we expect them to be formatted in a way
 		// that's suitable for UI.
-		c.Ui.Error(err.Error())
+		c.ErrorHint(err.Error(), err)
 		return 1
 	}
 
@@ -78,6 +162,45 @@ Usage: otto deploy [options]
   build artifact. Deploy can be called multiple times with the same
   artifact to redeploy an application.
 
+Options:
+
+  -health              Check the health of the application after it is
+                       deployed, if the application type supports it.
+
+  -health-grace=1m     How long to wait for the application to become
+                       healthy before the deploy is considered unhealthy.
+
+  -strategy=standard   How to roll out the new version: "standard",
+                       "blue-green" or "canary". The strategies other than
+                       the standard one must be supported by the
+                       application type.
+
+  -canary-steps=10,50  The percentages of the traffic that the canary
+                       strategy sends to the new version before it
+                       switches all of it.
+
+  -resume              Resume the unfinished rollout.
+
+  -rollback            Roll back the unfinished rollout to the old version.
+
+  -dep=NAME            Deploy the dependency with the application name or
+                       ID NAME instead of this application. Its own
+                       dependencies must already be deployed.
+
+  -all                 Deploy the dependencies of this application, and
+                       their dependencies, before this application. The
+                       applications that are already deployed are skipped.
+
+  -redeploy            With -all, deploy the applications that are already
+                       deployed again instead of skipping them.
+
+  -confirm             Show what will be deployed and ask for confirmation
+                       before deploying. If the deploy isn't confirmed, the
+                       exit status is 2.
+
+  -auto-approve        Deploy without asking for confirmation with -confirm.
+                       This is required to use -confirm non-interactively.
+
 `
 
 	return strings.TrimSpace(helpText)
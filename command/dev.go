@@ -43,6 +43,7 @@ func (c *DevCommand) Run(args []string) int {
 			"Error loading core: %s", err))
 		return 1
 	}
+	defer core.Close()
 
 	// If we have an action, then we use Execute(). Otherwise, we're
 	// building the dev environment with Dev().
@@ -64,7 +65,7 @@ func (c *DevCommand) Run(args []string) int {
 		Args:   execArgs,
 	})
 	if err != nil {
-		c.Ui.Error(err.Error())
+		c.ErrorHint(err.Error(), err)
 		return 1
 	}
 
@@ -0,0 +1,63 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HealthCommand is the command that checks whether the deployed
+// application is serving.
+type HealthCommand struct {
+	Meta
+}
+
+func (c *HealthCommand) Run(args []string) int {
+	fs := c.FlagSet("health", FlagSetNone)
+	fs.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	// Load the appfile
+	app, err := c.Appfile()
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	// Get a core
+	core, err := c.Core(app)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error loading core: %s", err))
+		return 1
+	}
+	defer core.Close()
+
+	// Check the health
+	if err := core.Health(); err != nil {
+		c.ErrorHint(err.Error(), err)
+		return 1
+	}
+
+	return 0
+}
+
+func (c *HealthCommand) Synopsis() string {
+	return "Check the health of the deployed application"
+}
+
+func (c *HealthCommand) Help() string {
+	helpText := `
+Usage: otto health
+
+  Checks whether the deployed application is serving.
+
+  The checks depend on the application type, which must support health
+  checks. The result is shown by "otto status". This exits with a non-zero
+  status if the application isn't healthy.
+
+`
+
+	return strings.TrimSpace(helpText)
+}
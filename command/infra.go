@@ -42,20 +42,13 @@ func (c *InfraCommand) Run(args []string) int {
 			"Error loading core: %s", err))
 		return 1
 	}
-
-	// Destroy action gets an extra double-check
-	if action == "destroy" {
-		msg := "Otto will delete all your managed infrastructure."
-		if !c.confirmDestroy(msg, execArgs) {
-			return 1
-		}
-	}
+	defer core.Close()
 
 	// Execute the task
 	err = core.Infra(action, execArgs)
 	if err != nil {
-		c.Ui.Error(fmt.Sprintf(
-			"Error occurred: %s", err))
+		c.ErrorHint(fmt.Sprintf(
+			"Error occurred: %s", err), err)
 		return 1
 	}
 
@@ -10,6 +10,7 @@ import (
 
 	"github.com/hashicorp/otto/appfile"
 	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/helper/artifact"
 	"github.com/hashicorp/otto/otto"
 	"github.com/hashicorp/otto/plugin"
 	"github.com/hashicorp/otto/ui"
@@ -36,6 +37,11 @@ const (
 	// DefaultDataDir is the default directory for the directory
 	// data if a directory in the Appfile isn't specified.
 	DefaultDataDir = "otto-data"
+
+	// EnvArtifactStore is the environment variable with the address of
+	// the artifact store, such as "s3://bucket/prefix". See
+	// artifact.NewStore.
+	EnvArtifactStore = "OTTO_ARTIFACT_STORE"
 )
 
 var (
@@ -59,6 +65,10 @@ type Meta struct {
 	PluginMap  plugin.ServeMuxMap
 
 	pluginManager *PluginManager
+	verbose       bool
+	quiet         bool
+	noColor       bool
+	environment   string
 }
 
 // Appfile loads the compiled Appfile. If the Appfile isn't compiled yet,
@@ -133,12 +143,33 @@ func (m *Meta) Core(f *appfile.Compiled) (*otto.Core, error) {
 	config.CompileDir = filepath.Join(
 		rootDir, DefaultOutputDir, DefaultOutputDirCompiledData)
 	config.Ui = m.OttoUi()
+	if m.verbose {
+		config.Verbosity = ui.LevelDebug
+	}
+	config.Quiet = m.quiet
+	config.DisableColor = m.noColor
+	if m.environment != "" {
+		config.Environment = m.environment
+	}
+
+	// The plugin manager already discovered the plugins, including the
+	// ones in the data directory, and only loads the ones that are used.
+	config.DisablePluginDiscovery = true
 
 	config.Directory, err = m.Directory(&config)
 	if err != nil {
 		return nil, err
 	}
 
+	// The files of builds are uploaded to the artifact store if one is
+	// configured with the environment.
+	if v := os.Getenv(EnvArtifactStore); v != "" && config.ArtifactStore == nil {
+		config.ArtifactStore, err = artifact.NewStore(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return otto.NewCore(&config)
 }
 
@@ -210,6 +241,15 @@ func (m *Meta) Directory(config *otto.CoreConfig) (directory.Backend, error) {
 func (m *Meta) FlagSet(n string, fs FlagSetFlags) *flag.FlagSet {
 	f := flag.NewFlagSet(n, flag.ContinueOnError)
 
+	// -v enables debug output from Otto itself, -q hides everything
+	// except errors and the final result.
+	f.BoolVar(&m.verbose, "v", false, "verbose")
+	f.BoolVar(&m.quiet, "q", false, "quiet")
+	f.BoolVar(&m.noColor, "no-color", false, "no-color")
+
+	// -env selects the environment that builds and deploys are for.
+	f.StringVar(&m.environment, "env", "", "env")
+
 	// Create an io.Writer that writes to our Ui properly for errors.
 	// This is kind of a hack, but it does the job. Basically: create
 	// a pipe, use a scanner to break it into lines, and output each line
@@ -264,39 +304,19 @@ func (m *Meta) PluginManager() (*PluginManager, error) {
 	return m.pluginManager, nil
 }
 
-// OttoUi returns the ui.Ui object.
-func (m *Meta) OttoUi() ui.Ui {
-	return NewUi(m.Ui)
-}
-
-// confirmDestroy is a little helper that will ask the user to confirm a
-// destroy action using the provided msg, unless -force is included in args it
-// returns true if the destroy should be considered confirmed, and false if
-// the destroy should be aborted.
-func (m *Meta) confirmDestroy(msg string, args []string) bool {
-	destroyForce := false
-	for _, arg := range args {
-		if arg == "-force" {
-			destroyForce = true
-		}
+// ErrorHint outputs the error msg, which describes err, followed by the
+// remediation hint of err on its own line if it has one. See
+// otto.HintedError.
+func (m *Meta) ErrorHint(msg string, err error) {
+	u := m.OttoUi()
+	if m.noColor {
+		u = ui.DisableColor(u)
 	}
 
-	if !destroyForce {
-		v, err := m.OttoUi().Input(&ui.InputOpts{
-			Id:    "destroy",
-			Query: "Do you really want to destroy?",
-			Description: fmt.Sprintf("%s\n"+
-				"There is no undo. Only 'yes' will be accepted to confirm.", msg),
-		})
-		if err != nil {
-			m.Ui.Error(fmt.Sprintf("Error asking for confirmation: %s", err))
-			return false
-		}
-		if v != "yes" {
-			m.Ui.Output("Destroy cancelled.")
-			return false
-		}
-	}
+	ui.ErrorHint(u, msg, otto.ErrorHint(err))
+}
 
-	return true
+// OttoUi returns the ui.Ui object.
+func (m *Meta) OttoUi() ui.Ui {
+	return NewUi(m.Ui)
 }
@@ -12,14 +12,17 @@ import (
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/foundation"
 	"github.com/hashicorp/otto/helper/semaphore"
+	"github.com/hashicorp/otto/infrastructure"
 	"github.com/hashicorp/otto/otto"
 	"github.com/hashicorp/otto/plugin"
+	pluginrpc "github.com/hashicorp/otto/rpc"
 	"github.com/kardianos/osext"
 )
 
 // PluginGlob is the glob pattern used to find plugins.
-const PluginGlob = "otto-plugin-*"
+const PluginGlob = otto.PluginGlob
 
 // PluginManager is responsible for discovering and starting plugins.
 //
@@ -51,11 +54,21 @@ type Plugin struct {
 	Builtin bool `json:"builtin"`
 
 	// The fields below are loaded as part of the Load() call and should
-	// not be set manually, but can be accessed after Load.
+	// not be set manually, but can be accessed after Load. Each is only
+	// set if the plugin serves that kind of implementation.
 	App     app.Factory `json:"-"`
 	AppMeta *app.Meta   `json:"-"`
 
-	used bool
+	// Infra implements the infrastructure types in Infras
+	Infra  infrastructure.Factory `json:"-"`
+	Infras []string               `json:"-"`
+
+	// Foundation implements the tuples in Foundations
+	Foundation  foundation.Factory    `json:"-"`
+	Foundations foundation.TupleSlice `json:"-"`
+
+	client *plugin.Client
+	used   bool
 }
 
 // Load loads the plugin specified by the Path and instantiates the
@@ -75,36 +88,71 @@ func (p *Plugin) Load() error {
 		SyncStderr: os.Stderr,
 	})
 
+	p.client = pluginClient
+
 	// Request the client
 	client, err := pluginClient.Client()
 	if err != nil {
 		return err
 	}
 
-	// Get the app implementation
-	appImpl, err := client.App()
-	if err != nil {
-		return err
-	}
-	if c, ok := appImpl.(io.Closer); ok {
-		defer c.Close()
-	}
-
-	p.AppMeta, err = appImpl.Meta()
+	// Find out what the plugin serves. Plugins built for older versions
+	// of Otto only serve an app and can't tell us.
+	meta, err := client.Meta()
 	if err != nil {
-		return err
+		log.Printf("[DEBUG] plugin %s has no metadata, assuming an app: %s", p, err)
+		meta = &pluginrpc.ServerMeta{App: true}
 	}
 
-	// Create a custom factory that when called marks the plugin as used
+	// Create custom factories that when called mark the plugin as used
 	p.used = false
-	p.App = func() (app.App, error) {
-		p.used = true
-		return client.App()
+	if meta.App {
+		// Get the app implementation
+		appImpl, err := client.App()
+		if err != nil {
+			return err
+		}
+		if c, ok := appImpl.(io.Closer); ok {
+			defer c.Close()
+		}
+
+		p.AppMeta, err = appImpl.Meta()
+		if err != nil {
+			return err
+		}
+
+		p.App = func() (app.App, error) {
+			p.used = true
+			return client.App()
+		}
+	}
+	if len(meta.Infras) > 0 {
+		p.Infras = meta.Infras
+		p.Infra = func() (infrastructure.Infrastructure, error) {
+			p.used = true
+			return client.Infra()
+		}
+	}
+	if len(meta.Foundations) > 0 {
+		p.Foundations = meta.Foundations
+		p.Foundation = func() (foundation.Foundation, error) {
+			p.used = true
+			return client.Foundation()
+		}
 	}
 
 	return nil
 }
 
+// Close ends the plugin process if it was loaded.
+func (p *Plugin) Close() error {
+	if p.client == nil {
+		return nil
+	}
+
+	return p.client.Close()
+}
+
 // Used tracks whether or not this plugin was used or not. You can call
 // this after compilation on each plugin to determine what plugin
 // was used.
@@ -127,10 +175,41 @@ func (m *PluginManager) ConfigureCore(core *otto.CoreConfig) error {
 	if core.Apps == nil {
 		core.Apps = make(map[app.Tuple]app.Factory)
 	}
+	if core.Infrastructures == nil {
+		core.Infrastructures = make(map[string]infrastructure.Factory)
+	}
+	if core.Foundations == nil {
+		core.Foundations = make(map[foundation.Tuple]foundation.Factory)
+	}
+	if core.PluginOrigins == nil {
+		core.PluginOrigins = make(map[interface{}]string)
+	}
 
 	for _, p := range m.Plugins() {
-		for _, tuple := range p.AppMeta.Tuples {
-			core.Apps[tuple] = p.App
+		// Built-in plugins are still plugin processes, so they have an
+		// origin and their API version was checked when they started.
+		origin := otto.PluginOriginBuiltin
+		if !p.Builtin {
+			origin = p.Path
+		}
+
+		if p.AppMeta != nil {
+			for _, tuple := range p.AppMeta.Tuples {
+				core.Apps[tuple] = p.App
+				core.PluginOrigins[tuple] = origin
+			}
+		}
+		for _, name := range p.Infras {
+			core.Infrastructures[name] = p.Infra
+			core.PluginOrigins[name] = origin
+		}
+		for _, tuple := range p.Foundations {
+			core.Foundations[tuple] = p.Foundation
+			core.PluginOrigins[tuple] = origin
+		}
+
+		if p.client != nil {
+			core.Plugins = append(core.Plugins, p)
 		}
 	}
 
@@ -9,6 +9,9 @@ import (
 	"testing"
 
 	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/foundation"
+	"github.com/hashicorp/otto/infrastructure"
+	"github.com/hashicorp/otto/otto"
 	"github.com/hashicorp/otto/plugin"
 )
 
@@ -44,6 +47,77 @@ func TestPluginLoad_used(t *testing.T) {
 	}
 }
 
+func TestPluginLoad_infra(t *testing.T) {
+	plugin := testPlugin(t, "infra")
+	if err := plugin.Load(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer plugin.Close()
+
+	if plugin.App != nil || plugin.AppMeta != nil {
+		t.Fatalf("bad: %#v", plugin)
+	}
+	if !reflect.DeepEqual(plugin.Infras, []string{"test"}) {
+		t.Fatalf("bad: %#v", plugin.Infras)
+	}
+	if !reflect.DeepEqual(plugin.Foundations, testPluginFoundations) {
+		t.Fatalf("bad: %#v", plugin.Foundations)
+	}
+
+	infra, err := plugin.Infra()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !plugin.Used() {
+		t.Fatal("should be used")
+	}
+	if v := infra.Flavors(); !reflect.DeepEqual(v, []string{"simple"}) {
+		t.Fatalf("bad: %#v", v)
+	}
+	if _, err := plugin.Foundation(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestPluginManager_configureCore(t *testing.T) {
+	mock := testPlugin(t, "mock")
+	infra := testPlugin(t, "infra")
+	mgr := &PluginManager{plugins: []*Plugin{mock, infra}}
+	if err := mgr.LoadAll(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var config otto.CoreConfig
+	if err := mgr.ConfigureCore(&config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if config.Apps[testPluginMockMeta.Tuples[0]] == nil {
+		t.Fatalf("bad: %#v", config.Apps)
+	}
+	if config.Infrastructures["test"] == nil {
+		t.Fatalf("bad: %#v", config.Infrastructures)
+	}
+	if config.Foundations[testPluginFoundations[0]] == nil {
+		t.Fatalf("bad: %#v", config.Foundations)
+	}
+	if len(config.Plugins) != 2 {
+		t.Fatalf("bad: %#v", config.Plugins)
+	}
+	if v := config.PluginOrigins["test"]; v != os.Args[0] {
+		t.Fatalf("bad: %#v", config.PluginOrigins)
+	}
+
+	// Closing the core ends the plugin processes
+	for _, p := range config.Plugins {
+		if err := p.Close(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if !infra.client.Exited() {
+		t.Fatal("plugin should exit")
+	}
+}
+
 func TestPluginManager_saveLoad(t *testing.T) {
 	mock := testPlugin(t, "mock")
 	mgr := &PluginManager{
@@ -141,6 +215,15 @@ func TestPluginProcess(*testing.T) {
 		opts.AppFunc = func() app.App {
 			return appImpl
 		}
+	case "infra":
+		opts.InfraFunc = func() infrastructure.Infrastructure {
+			return &infrastructure.Mock{FlavorsResult: []string{"simple"}}
+		}
+		opts.Infras = []string{"test"}
+		opts.FoundationFunc = func() foundation.Foundation {
+			return new(foundation.Mock)
+		}
+		opts.Foundations = testPluginFoundations
 	default:
 		fmt.Fprintf(os.Stderr, "Invalid plugin: %s\n", args[1])
 		os.Exit(2)
@@ -155,3 +238,7 @@ var testPluginMockMeta = &app.Meta{
 		app.Tuple{"test", "test", "test"},
 	},
 }
+
+var testPluginFoundations = foundation.TupleSlice{
+	foundation.Tuple{"test", "test", "test"},
+}
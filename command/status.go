@@ -1,8 +1,13 @@
 package command
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"os/signal"
 	"strings"
+
+	"github.com/hashicorp/otto/otto"
 )
 
 // StatusCommand is the command that shows the status of the various
@@ -12,8 +17,13 @@ type StatusCommand struct {
 }
 
 func (c *StatusCommand) Run(args []string) int {
+	var jsonOutput bool
+	var opts otto.StatusOpts
 	fs := c.FlagSet("status", FlagSetNone)
 	fs.Usage = func() { c.Ui.Error(c.Help()) }
+	fs.BoolVar(&jsonOutput, "json", false, "json")
+	fs.BoolVar(&opts.Refresh, "refresh", false, "refresh")
+	fs.DurationVar(&opts.Timeout, "timeout", 0, "timeout")
 	if err := fs.Parse(args); err != nil {
 		return 1
 	}
@@ -32,12 +42,48 @@ func (c *StatusCommand) Run(args []string) int {
 			"Error loading core: %s", err))
 		return 1
 	}
+	defer core.Close()
+
+	// The structured status is for scripts
+	if jsonOutput {
+		result, err := core.StatusResult()
+		if err != nil {
+			c.ErrorHint(fmt.Sprintf(
+				"Error occurred: %s", err), err)
+			return 1
+		}
+
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error encoding the status: %s", err))
+			return 1
+		}
+
+		c.Ui.Output(string(data))
+		return 0
+	}
+
+	// Interrupting stops waiting for the status
+	shutdownCh := make(chan struct{})
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+	go func() {
+		select {
+		case <-sigCh:
+			close(shutdownCh)
+		case <-doneCh:
+		}
+	}()
+	opts.ShutdownCh = shutdownCh
 
 	// Execute the task
-	err = core.Status()
+	err = core.StatusWithOpts(&opts)
 	if err != nil {
-		c.Ui.Error(fmt.Sprintf(
-			"Error occurred: %s", err))
+		c.ErrorHint(fmt.Sprintf(
+			"Error occurred: %s", err), err)
 		return 1
 	}
 
@@ -50,7 +96,7 @@ func (c *StatusCommand) Synopsis() string {
 
 func (c *StatusCommand) Help() string {
 	helpText := `
-Usage: otto status
+Usage: otto status [options]
 
   Shows the status of this application.
 
@@ -63,6 +109,22 @@ Usage: otto status
   Otto. For a true status, the actual command to manage each thing must
   be run. For example, for development "otto dev" should be run.
 
+  The status also shows if the dev environment, the build, or the deploy
+  is outdated because the Appfile or the source changed since.
+
+Options:
+
+  -json      Output the status as JSON, such as to fail a CI job if the
+             deploy is outdated ("drifted"), instead of as text.
+
+  -refresh   Check that the infrastructure still exists and that the
+             deploy is healthy, instead of only reading the records of
+             the directory. This needs the infrastructure credentials.
+
+  -timeout   How long to wait for the directory, such as "30s". The
+             components that didn't load in time are shown as unknown.
+             Defaults to 2 minutes.
+
 `
 
 	return strings.TrimSpace(helpText)
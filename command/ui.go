@@ -23,11 +23,9 @@ var defaultInputWriter io.Writer
 // NewUi returns a new otto Ui implementation for use around
 // the given CLI Ui implementation.
 func NewUi(raw cli.Ui) ui.Ui {
-	return &ui.Styled{
-		Ui: &cliUi{
-			CliUi: raw,
-		},
-	}
+	u := &cliUi{CliUi: raw}
+	u.progress = &ui.ProgressBar{Ui: u}
+	return &ui.Styled{Ui: u}
 }
 
 // cliUi is a wrapper around a cli.Ui that implements the otto.Ui
@@ -42,14 +40,67 @@ type cliUi struct {
 
 	interrupted bool
 	l           sync.Mutex
+
+	progress *ui.ProgressBar
+	noColor  bool
 }
 
 func (u *cliUi) Header(msg string) {
-	u.CliUi.Output(ui.Colorize(msg))
+	u.progress.Clear()
+	defer u.progress.Redraw()
+	u.CliUi.Output(u.colorize(msg))
 }
 
 func (u *cliUi) Message(msg string) {
-	u.CliUi.Output(ui.Colorize(msg))
+	u.progress.Clear()
+	defer u.progress.Redraw()
+	u.CliUi.Output(u.colorize(msg))
+}
+
+// SetColor implements ui.ColorUi.
+func (u *cliUi) SetColor(enabled bool) {
+	u.noColor = !enabled
+}
+
+// ErrorHint implements ui.HintUi. Errors are output to stderr, and color
+// is only used for the hint since msg is usually the text of an error.
+func (u *cliUi) ErrorHint(msg, hint string) {
+	u.progress.Clear()
+	defer u.progress.Redraw()
+	u.CliUi.Error(msg)
+	if hint == "" {
+		return
+	}
+
+	line := ui.HintLine(hint)
+	if u.noColor || !ui.IsTerminal(os.Stderr) {
+		line = ui.StripColors(line)
+	} else {
+		line = ui.Colorize(line)
+	}
+
+	u.CliUi.Error(line)
+}
+
+// colorize renders the color markup in msg. Color is only output to
+// terminals, otherwise the markup is stripped.
+func (u *cliUi) colorize(msg string) string {
+	if u.noColor || !ui.IsTerminal(os.Stdout) {
+		return ui.StripColors(msg)
+	}
+
+	return ui.Colorize(msg)
+}
+
+// Progress implements ui.ProgressUi. If we're outputting to a terminal
+// then progress is rendered in-place, otherwise it is output periodically
+// as messages.
+func (u *cliUi) Progress(name string) ui.ProgressHandle {
+	if u.progress == nil || !ui.IsTerminal(os.Stdout) {
+		return ui.NewLineProgress(u, name)
+	}
+
+	return u.progress.Progress(name)
 }
 
 func (u *cliUi) Raw(msg string) {
@@ -59,6 +110,11 @@ func (u *cliUi) Raw(msg string) {
 func (i *cliUi) Input(opts *ui.InputOpts) (string, error) {
 	// If any of the configured EnvVars are set, we don't ask for input.
 	if value := opts.EnvVarValue(); value != "" {
+		if err := opts.ValidateValue(value); err != nil {
+			return "", fmt.Errorf(
+				"Invalid value for '%s' from environment: %s", opts.Id, err)
+		}
+
 		return value, nil
 	}
 
@@ -77,11 +133,42 @@ func (i *cliUi) Input(opts *ui.InputOpts) (string, error) {
 		w = os.Stdout
 	}
 
+	// We can only re-prompt for invalid values and hide the input
+	// if we're reading from a terminal. Otherwise, the input is likely
+	// piped in for automation.
+	f, interactive := r.(*os.File)
+	interactive = interactive && ui.IsTerminal(f)
+
 	// Make sure we only ask for input once at a time. Terraform
 	// should enforce this, but it doesn't hurt to verify.
 	i.l.Lock()
 	defer i.l.Unlock()
 
+	for {
+		line, err := i.ask(r, w, opts, interactive)
+		if err != nil {
+			return "", err
+		}
+
+		if err := opts.ValidateValue(line); err != nil {
+			if !interactive {
+				return "", fmt.Errorf(
+					"Invalid value for '%s': %s", opts.Id, err)
+			}
+
+			fmt.Fprint(w, i.colorize(fmt.Sprintf(
+				"  [red]Invalid value: %s[reset]\n\n", err)))
+			continue
+		}
+
+		return line, nil
+	}
+}
+
+// ask asks for a single input value. The lock must be held.
+func (i *cliUi) ask(
+	r io.Reader, w io.Writer,
+	opts *ui.InputOpts, interactive bool) (string, error) {
 	// If we're interrupted, then don't ask for input
 	if i.interrupted {
 		return "", errors.New("interrupted")
@@ -111,25 +198,32 @@ func (i *cliUi) Input(opts *ui.InputOpts) (string, error) {
 	buf.WriteString("  [bold]Enter a value:[reset] ")
 
 	// Ask the user for their input
-	if _, err := fmt.Fprint(w, ui.Colorize(buf.String())); err != nil {
+	if _, err := fmt.Fprint(w, i.colorize(buf.String())); err != nil {
 		return "", err
 	}
 
 	// Listen for the input in a goroutine. This will allow us to
 	// interrupt this if we are interrupted (SIGINT)
 	result := make(chan string, 1)
-	if opts.Hide {
-		f, ok := r.(*os.File)
-		if !ok {
-			return "", fmt.Errorf("reader must be a file")
-		}
-
-		line, err := password.Read(f)
+	if opts.Hide && interactive {
+		// Masked input is only possible on a terminal
+		line, err := password.Read(r.(*os.File))
 		if err != nil {
 			return "", err
 		}
 
 		result <- line
+	} else if opts.Hide {
+		// Secrets can contain spaces, so read the full line. Piped input
+		// isn't echoed so there is nothing to mask.
+		go func() {
+			line, err := readLine(r)
+			if err != nil {
+				log.Printf("[ERR] UIInput read err: %s", err)
+			}
+
+			result <- line
+		}()
 	} else {
 		go func() {
 			var line string
@@ -161,3 +255,29 @@ func (i *cliUi) Input(opts *ui.InputOpts) (string, error) {
 		return "", errors.New("interrupted")
 	}
 }
+
+// readLine reads a single line from r without reading past the newline,
+// so that later reads from r still see the remaining input.
+func readLine(r io.Reader) (string, error) {
+	var buf bytes.Buffer
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				break
+			}
+
+			buf.WriteByte(b[0])
+		}
+		if err != nil {
+			if err == io.EOF && buf.Len() > 0 {
+				break
+			}
+
+			return "", err
+		}
+	}
+
+	return strings.TrimRight(buf.String(), "\r"), nil
+}
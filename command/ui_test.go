@@ -26,3 +26,31 @@ func TestCliUiInput(t *testing.T) {
 		t.Fatalf("bad: %#v", v)
 	}
 }
+
+func TestCliUiInput_hidePiped(t *testing.T) {
+	i := &cliUi{
+		Reader: bytes.NewBufferString("foo bar\nbaz\n"),
+		Writer: bytes.NewBuffer(nil),
+	}
+
+	v, err := i.Input(&ui.InputOpts{Hide: true})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if v != "foo bar" {
+		t.Fatalf("bad: %#v", v)
+	}
+}
+
+func TestCliUiInput_invalidPiped(t *testing.T) {
+	i := &cliUi{
+		Reader: bytes.NewBufferString("foo\n"),
+		Writer: bytes.NewBuffer(nil),
+	}
+
+	_, err := i.Input(&ui.InputOpts{Id: "foo", Pattern: `^[0-9]+$`})
+	if err == nil {
+		t.Fatal("should error")
+	}
+}
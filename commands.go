@@ -50,6 +50,7 @@ func init() {
 			Infrastructures: map[string]infrastructure.Factory{
 				"aws": infraAws.Infra,
 			},
+			LogFiles: 10,
 		},
 		Ui:        Ui,
 		PluginMap: pluginmap.Map,
@@ -58,8 +59,10 @@ func init() {
 	CommandsInclude = []string{
 		"compile",
 		"build",
+		"console",
 		"deploy",
 		"dev",
+		"health",
 		"infra",
 		"status",
 		"version",
@@ -78,6 +81,12 @@ func init() {
 			}, nil
 		},
 
+		"console": func() (cli.Command, error) {
+			return &command.ConsoleCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"deploy": func() (cli.Command, error) {
 			return &command.DeployCommand{
 				Meta: meta,
@@ -90,6 +99,12 @@ func init() {
 			}, nil
 		},
 
+		"health": func() (cli.Command, error) {
+			return &command.HealthCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"infra": func() (cli.Command, error) {
 			return &command.InfraCommand{
 				Meta: meta,
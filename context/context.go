@@ -1,8 +1,13 @@
 package context
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
 	"github.com/hashicorp/otto/appfile"
 	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/helper/logger"
 	"github.com/hashicorp/otto/ui"
 )
 
@@ -20,6 +25,10 @@ type Shared struct {
 	// Ui is the Ui object that can be used to communicate with the user.
 	Ui ui.Ui
 
+	// Logger is the logger for debugging information that isn't meant
+	// for the user. This is never nil.
+	Logger logger.Logger
+
 	// Directory is the directory service. This is available during
 	// both execution and compilation and can be used to view the
 	// global data prior to doing anything.
@@ -30,6 +39,30 @@ type Shared struct {
 	// unlikely. And you're responsible to clean up anything in here.
 	InstallDir string
 
+	// ScratchDir is the temporary directory of the operation that
+	// TempDir creates directories in. This is set by Otto core and
+	// shouldn't be used directly.
+	ScratchDir string
+
+	// Implementation names the implementation that this context is for:
+	// "app:" followed by the app tuple, "infra:" followed by the
+	// infrastructure type, or "foundation:" followed by the foundation
+	// tuple. Installers record it with the binaries they install in
+	// InstallDir, so that Otto knows which of them are still required.
+	Implementation string
+
+	// Env are the environment variables to set for the tools that the
+	// implementation runs, such as proxy settings. Use these rather than
+	// conventions based on the environment of the process.
+	//
+	// For apps, these are merged from, in increasing precedence: the env
+	// blocks of the project, the env of the app customizations, and the
+	// ExtraEnv of the Otto configuration. The infrastructure and the
+	// foundations get the project env and the ExtraEnv. The values are
+	// already interpolated. Secret values are redacted from the output of
+	// the Ui, but not from what the tools output on their own.
+	Env map[string]string
+
 	// Appfile is the full appfile
 	Appfile *appfile.File
 
@@ -41,3 +74,27 @@ type Shared struct {
 	// called.
 	FoundationDirs []string
 }
+
+// TempDir creates a new temporary directory and returns its path. Otto
+// removes it at the end of the operation, whether or not the operation
+// succeeded, so it doesn't have to be removed.
+//
+// This is only scratch space: nothing that must outlive the operation,
+// such as the compiled files, may be stored in it.
+func (s *Shared) TempDir() (string, error) {
+	if s.ScratchDir == "" {
+		return "", fmt.Errorf("no temporary directory is available")
+	}
+	if err := os.MkdirAll(s.ScratchDir, 0700); err != nil {
+		return "", err
+	}
+
+	return ioutil.TempDir(s.ScratchDir, "")
+}
+
+// Progress returns a handle for reporting the progress of a long-running
+// operation, such as a download, to the user. Done must be called on
+// the handle when the operation is complete.
+func (s *Shared) Progress(name string) ui.ProgressHandle {
+	return ui.Progress(s.Ui, name)
+}
@@ -50,7 +50,8 @@ type Backend interface {
 // Build represents a build of an App.
 type Build struct {
 	// Lookup information for the Build. AppID, Infra, and InfraFlavor
-	// are required.
+	// are required. Environment is optional and defaults to
+	// DefaultEnvironment.
 	Lookup
 
 	// Resulting artifact from the build
@@ -42,6 +42,11 @@ type BoltBackend struct {
 	Dir string
 }
 
+// String returns the description of the backend for messages.
+func (b *BoltBackend) String() string {
+	return fmt.Sprintf("local, in %s", b.Dir)
+}
+
 func (b *BoltBackend) GetBlob(k string) (*BlobData, error) {
 	db, err := b.db()
 	if err != nil {
@@ -248,8 +253,7 @@ func (b *BoltBackend) GetBuild(build *Build) (*Build, error) {
 		}
 
 		// Get the infra bucket
-		bucket = bucket.Bucket([]byte(fmt.Sprintf(
-			"%s-%s", build.Lookup.Infra, build.Lookup.InfraFlavor)))
+		bucket = bucket.Bucket([]byte(b.envBucket(&build.Lookup)))
 		if bucket == nil {
 			return nil
 		}
@@ -292,8 +296,7 @@ func (b *BoltBackend) PutBuild(build *Build) error {
 		}
 
 		// Get the infra bucket
-		bucket, err = bucket.CreateBucketIfNotExists([]byte(fmt.Sprintf(
-			"%s-%s", build.Lookup.Infra, build.Lookup.InfraFlavor)))
+		bucket, err = bucket.CreateBucketIfNotExists([]byte(b.envBucket(&build.Lookup)))
 		if err != nil {
 			return err
 		}
@@ -319,8 +322,7 @@ func (b *BoltBackend) GetDeploy(deploy *Deploy) (*Deploy, error) {
 		}
 
 		// Get the infra bucket
-		bucket = bucket.Bucket([]byte(fmt.Sprintf(
-			"%s-%s", deploy.Lookup.Infra, deploy.Lookup.InfraFlavor)))
+		bucket = bucket.Bucket([]byte(b.envBucket(&deploy.Lookup)))
 		if bucket == nil {
 			return nil
 		}
@@ -367,8 +369,7 @@ func (b *BoltBackend) PutDeploy(deploy *Deploy) error {
 		}
 
 		// Get the infra bucket
-		bucket, err = bucket.CreateBucketIfNotExists([]byte(fmt.Sprintf(
-			"%s-%s", deploy.Lookup.Infra, deploy.Lookup.InfraFlavor)))
+		bucket, err = bucket.CreateBucketIfNotExists([]byte(b.envBucket(&deploy.Lookup)))
 		if err != nil {
 			return err
 		}
@@ -377,6 +378,13 @@ func (b *BoltBackend) PutDeploy(deploy *Deploy) error {
 	})
 }
 
+// envBucket returns the name of the bucket of an app for the
+// infrastructure and environment of the lookup.
+func (b *BoltBackend) envBucket(l *Lookup) string {
+	return EnvironmentKey(l.Environment, fmt.Sprintf(
+		"%s-%s", l.Infra, l.InfraFlavor))
+}
+
 func (b *BoltBackend) infraKey(infra *Infra) string {
 	key := "root"
 	if infra.Lookup.Foundation != "" {
@@ -0,0 +1,308 @@
+package directory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// InMemBackend is a Directory backend that stores data in memory. The
+// data is gone once the backend is garbage collected.
+//
+// The primary use cases for the InMemBackend are tests and throwaway
+// runs, such as in CI: it is fast, it doesn't touch the disk, and a new
+// one has no state. Faults can be injected with SetFaults to test how
+// callers handle a slow or failing directory.
+//
+// This backend is safe for concurrent use.
+type InMemBackend struct {
+	lock   sync.Mutex
+	blobs  map[string][]byte
+	data   map[string][]byte
+	faults *InMemFaults
+	rand   *rand.Rand
+}
+
+// NewInMemBackend returns a new InMemBackend with no data.
+func NewInMemBackend() *InMemBackend {
+	return &InMemBackend{
+		blobs: make(map[string][]byte),
+		data:  make(map[string][]byte),
+	}
+}
+
+// InMemFaults are the faults that an InMemBackend injects into its
+// operations. The keys of the maps are the names of the operations, such
+// as "GetInfra", or "*" for operations without their own key.
+type InMemFaults struct {
+	// Delay is how long an operation sleeps before it runs.
+	Delay map[string]time.Duration
+
+	// FailureRate is the fraction of the calls of an operation, from 0 to
+	// 1, that return an *InjectedError instead of running.
+	FailureRate map[string]float64
+
+	// Seed seeds the choice of the calls that fail, so that the same
+	// calls fail every time.
+	Seed int64
+}
+
+// InjectedError is the error returned by an operation of an InMemBackend
+// that failed because of its InMemFaults.
+type InjectedError struct {
+	Op string
+}
+
+func (e *InjectedError) Error() string {
+	return fmt.Sprintf("injected failure of the directory operation %s", e.Op)
+}
+
+// String returns the description of the backend for messages.
+func (b *InMemBackend) String() string {
+	return "in-memory"
+}
+
+// SetFaults sets the faults to inject into the operations that start from
+// now on. A nil f stops injecting faults.
+func (b *InMemBackend) SetFaults(f *InMemFaults) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.faults = f
+	b.rand = nil
+	if f != nil {
+		b.rand = rand.New(rand.NewSource(f.Seed))
+	}
+}
+
+func (b *InMemBackend) GetBlob(k string) (*BlobData, error) {
+	if err := b.inject("GetBlob"); err != nil {
+		return nil, err
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	data, ok := b.blobs[k]
+	if !ok {
+		return nil, nil
+	}
+
+	return &BlobData{
+		Key:  k,
+		Data: bytes.NewReader(data),
+	}, nil
+}
+
+func (b *InMemBackend) PutBlob(k string, d *BlobData) error {
+	if err := b.inject("PutBlob"); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, d.Data); err != nil {
+		return err
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.blobs == nil {
+		b.blobs = make(map[string][]byte)
+	}
+	b.blobs[k] = buf.Bytes()
+	return nil
+}
+
+func (b *InMemBackend) GetInfra(infra *Infra) (*Infra, error) {
+	if err := b.inject("GetInfra"); err != nil {
+		return nil, err
+	}
+
+	var result *Infra
+	ok, err := b.get(b.infraKey(infra), &result)
+	if !ok {
+		return nil, err
+	}
+
+	return result, err
+}
+
+func (b *InMemBackend) PutInfra(infra *Infra) error {
+	if err := b.inject("PutInfra"); err != nil {
+		return err
+	}
+
+	if infra.ID == "" {
+		infra.setId()
+	}
+
+	return b.put(b.infraKey(infra), infra)
+}
+
+func (b *InMemBackend) GetDev(dev *Dev) (*Dev, error) {
+	if err := b.inject("GetDev"); err != nil {
+		return nil, err
+	}
+
+	var result *Dev
+	ok, err := b.get(b.devKey(dev), &result)
+	if !ok {
+		return nil, err
+	}
+
+	return result, err
+}
+
+func (b *InMemBackend) PutDev(dev *Dev) error {
+	if err := b.inject("PutDev"); err != nil {
+		return err
+	}
+
+	if dev.ID == "" {
+		dev.setId()
+	}
+
+	return b.put(b.devKey(dev), dev)
+}
+
+func (b *InMemBackend) DeleteDev(dev *Dev) error {
+	if err := b.inject("DeleteDev"); err != nil {
+		return err
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.data, b.devKey(dev))
+	return nil
+}
+
+func (b *InMemBackend) GetBuild(build *Build) (*Build, error) {
+	if err := b.inject("GetBuild"); err != nil {
+		return nil, err
+	}
+
+	var result *Build
+	ok, err := b.get(b.appKey(&build.Lookup, "build"), &result)
+	if !ok {
+		return nil, err
+	}
+
+	return result, err
+}
+
+func (b *InMemBackend) PutBuild(build *Build) error {
+	if err := b.inject("PutBuild"); err != nil {
+		return err
+	}
+
+	return b.put(b.appKey(&build.Lookup, "build"), build)
+}
+
+func (b *InMemBackend) GetDeploy(deploy *Deploy) (*Deploy, error) {
+	if err := b.inject("GetDeploy"); err != nil {
+		return nil, err
+	}
+
+	var result *Deploy
+	ok, err := b.get(b.appKey(&deploy.Lookup, "deploy"), &result)
+	if !ok {
+		return nil, err
+	}
+
+	return result, err
+}
+
+func (b *InMemBackend) PutDeploy(deploy *Deploy) error {
+	if err := b.inject("PutDeploy"); err != nil {
+		return err
+	}
+
+	if deploy.ID == "" {
+		deploy.setId()
+	}
+
+	return b.put(b.appKey(&deploy.Lookup, "deploy"), deploy)
+}
+
+// inject injects the faults of the operation op. This returns the error
+// that the operation should return, if any.
+func (b *InMemBackend) inject(op string) error {
+	b.lock.Lock()
+	f := b.faults
+	if f == nil {
+		b.lock.Unlock()
+		return nil
+	}
+	delay, ok := f.Delay[op]
+	if !ok {
+		delay = f.Delay["*"]
+	}
+	rate, ok := f.FailureRate[op]
+	if !ok {
+		rate = f.FailureRate["*"]
+	}
+	fail := rate > 0 && b.rand.Float64() < rate
+	b.lock.Unlock()
+
+	// Sleep without the lock so that other operations aren't delayed
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	if fail {
+		return &InjectedError{Op: op}
+	}
+
+	return nil
+}
+
+// The keys are laid out like the buckets of the BoltBackend so that both
+// backends look up the same fields.
+func (b *InMemBackend) infraKey(infra *Infra) string {
+	key := "root"
+	if infra.Lookup.Foundation != "" {
+		key = fmt.Sprintf("foundation-%s", infra.Lookup.Foundation)
+	}
+
+	return fmt.Sprintf("infra/%s/%s", infra.Lookup.Infra, key)
+}
+
+func (b *InMemBackend) devKey(dev *Dev) string {
+	return fmt.Sprintf("apps/%s/dev", dev.Lookup.AppID)
+}
+
+func (b *InMemBackend) appKey(l *Lookup, kind string) string {
+	infra := EnvironmentKey(l.Environment, fmt.Sprintf("%s-%s", l.Infra, l.InfraFlavor))
+	return fmt.Sprintf("apps/%s/%s/%s", l.AppID, infra, kind)
+}
+
+// get and put store the data encoded so that callers never share
+// structures with the backend, just like with a backend that isn't in
+// memory.
+func (b *InMemBackend) get(k string, d interface{}) (bool, error) {
+	b.lock.Lock()
+	raw, ok := b.data[k]
+	b.lock.Unlock()
+	if !ok {
+		return false, nil
+	}
+
+	return true, json.Unmarshal(raw, d)
+}
+
+func (b *InMemBackend) put(k string, d interface{}) error {
+	raw, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.data == nil {
+		b.data = make(map[string][]byte)
+	}
+	b.data[k] = raw
+	return nil
+}
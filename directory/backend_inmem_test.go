@@ -0,0 +1,98 @@
+package directory
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInMemBackend_impl(t *testing.T) {
+	var _ Backend = new(InMemBackend)
+}
+
+func TestInMemBackend(t *testing.T) {
+	TestBackend(t, NewInMemBackend())
+}
+
+func TestInMemBackend_zero(t *testing.T) {
+	TestBackend(t, new(InMemBackend))
+}
+
+func TestInMemBackend_copies(t *testing.T) {
+	b := NewInMemBackend()
+	build := &Build{
+		Lookup:   Lookup{AppID: "foo", Infra: "aws", InfraFlavor: "vpc"},
+		Artifact: map[string]string{"ami": "ami-123"},
+	}
+	if err := b.PutBuild(build); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	build.Artifact["ami"] = "changed"
+
+	result, err := b.GetBuild(build)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if result.Artifact["ami"] != "ami-123" {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestInMemBackend_faults(t *testing.T) {
+	b := NewInMemBackend()
+	b.SetFaults(&InMemFaults{
+		Delay:       map[string]time.Duration{"GetInfra": 20 * time.Millisecond},
+		FailureRate: map[string]float64{"*": 1, "GetInfra": 0},
+	})
+
+	// Operations without their own key use "*"
+	err := b.PutInfra(&Infra{Lookup: Lookup{Infra: "aws"}})
+	injected, ok := err.(*InjectedError)
+	if !ok || injected.Op != "PutInfra" {
+		t.Fatalf("err: %v", err)
+	}
+
+	start := time.Now()
+	if _, err := b.GetInfra(&Infra{Lookup: Lookup{Infra: "aws"}}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Fatalf("bad: %s", d)
+	}
+
+	// Removing the faults stops injecting them
+	b.SetFaults(nil)
+	if err := b.PutInfra(&Infra{Lookup: Lookup{Infra: "aws"}}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestInMemBackend_failureRateSeed(t *testing.T) {
+	failures := func() []bool {
+		b := NewInMemBackend()
+		b.SetFaults(&InMemFaults{
+			FailureRate: map[string]float64{"GetDev": 0.5},
+			Seed:        42,
+		})
+
+		var result []bool
+		for i := 0; i < 20; i++ {
+			_, err := b.GetDev(&Dev{Lookup: Lookup{AppID: "foo"}})
+			result = append(result, err != nil)
+		}
+		return result
+	}
+
+	first, second := failures(), failures()
+	var failed int
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("bad: %#v %#v", first, second)
+		}
+		if first[i] {
+			failed++
+		}
+	}
+	if failed == 0 || failed == len(first) {
+		t.Fatalf("bad: %#v", first)
+	}
+}
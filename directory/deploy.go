@@ -7,7 +7,8 @@ import (
 // Deploy represents a deploy of an App.
 type Deploy struct {
 	// Lookup information for the Deploy. AppID, Infra, and InfraFlavor
-	// are required.
+	// are required. Environment is optional and defaults to
+	// DefaultEnvironment.
 	Lookup
 
 	// These fields should be set for Put and will be populated on Get
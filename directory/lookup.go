@@ -1,5 +1,8 @@
 package directory
 
+// DefaultEnvironment is the environment of lookups that don't set one.
+const DefaultEnvironment = "default"
+
 // Lookup has fields that are used for looking up data in the directory.
 //
 // Note that not all fields are required for every lookup operation. Please
@@ -9,4 +12,26 @@ type Lookup struct {
 	Infra       string // Infra is the infra type, i.e. "aws"
 	InfraFlavor string // InfraFlavor is the flavor, i.e. "vpc-public-private"
 	Foundation  string // Foundation is the name of he foundation, i.e. "consul"
+	Environment string // Environment is the deploy environment, i.e. "staging"
+}
+
+// EnvironmentName returns the environment of the lookup, which is
+// DefaultEnvironment if it isn't set.
+func (l *Lookup) EnvironmentName() string {
+	if l.Environment == "" {
+		return DefaultEnvironment
+	}
+
+	return l.Environment
+}
+
+// EnvironmentKey returns the key k scoped to the environment env. Keys of
+// the default environment aren't scoped, so the data stored before there
+// were environments belongs to the default environment.
+func EnvironmentKey(env, k string) string {
+	if env == "" || env == DefaultEnvironment {
+		return k
+	}
+
+	return k + "@" + env
 }
@@ -181,6 +181,45 @@ func TestBackend(t *testing.T, b Backend) {
 		return
 	}
 
+	// GetDeploy (default environment) is the deploy without one
+	deployResult, err = b.GetDeploy(&Deploy{Lookup: Lookup{
+		AppID: "foo", Infra: "bar", InfraFlavor: "baz",
+		Environment: DefaultEnvironment}})
+	if err != nil {
+		t.Errorf("GetDeploy (default env) error: %s", err)
+		return
+	}
+	if deployResult == nil || deployResult.ID != deploy.ID {
+		t.Errorf("GetDeploy (default env) bad: %#v", deployResult)
+		return
+	}
+
+	// GetDeploy (other environment) is separate
+	envDeploy := &Deploy{Lookup: Lookup{
+		AppID: "foo", Infra: "bar", InfraFlavor: "baz", Environment: "staging"}}
+	deployResult, err = b.GetDeploy(envDeploy)
+	if err != nil {
+		t.Errorf("GetDeploy (other env) error: %s", err)
+		return
+	}
+	if deployResult != nil {
+		t.Error("GetDeploy (other env): result should be nil")
+		return
+	}
+	if err := b.PutDeploy(envDeploy); err != nil {
+		t.Errorf("PutDeploy (other env) err: %s", err)
+		return
+	}
+	deployResult, err = b.GetDeploy(deploy)
+	if err != nil {
+		t.Errorf("GetDeploy (exist) error: %s", err)
+		return
+	}
+	if deployResult == nil || deployResult.ID != deploy.ID {
+		t.Errorf("GetDeploy (other env) overwrote the deploy: %#v", deployResult)
+		return
+	}
+
 	//---------------------------------------------------------------
 	// Dev
 	//---------------------------------------------------------------
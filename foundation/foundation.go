@@ -43,11 +43,25 @@ type Context struct {
 	// application that we're working with. This is only available during
 	// the Compile function if we're compiling for an application.
 	//
-	// It should be expected during compilation that this might be nil.
-	// The cases where it is nil are not currently well defined, but the
+	// Foundations are compiled for an application both before and after
+	// the application is compiled. Before, this is the configuration the
+	// app reports with app.FoundationConfigurer, or the one of its
+	// previous compilation. After, it is the one of its CompileResult.
+	// The output of both passes should be the same for the same
+	// configuration, since the second one replaces the first.
+	//
+	// This is nil on the first compilation of an app that doesn't
+	// implement app.FoundationConfigurer, before it's compiled. The
 	// behavior in the nil case should be to do nothing except Deploy.
 	AppConfig *Config
 
+	// AppPhases are the app phases that the application participates
+	// in, such as AppPhaseBuild. The subdirectories of Dir for the other
+	// phases aren't kept, so foundations shouldn't expect them to exist.
+	// This is only set after the application is compiled, and nil means
+	// all phases.
+	AppPhases []string
+
 	// Dir is the directory that the compilation is allowed to write to
 	// for persistant storage of data that is available during task
 	// execution. For tasks, this will be the directory that compilation
@@ -61,6 +75,12 @@ type Context struct {
 }
 
 // CompileResult is the structure containing compilation result values.
-//
-// This is empty now but may be used in the future.
-type CompileResult struct{}
+type CompileResult struct {
+	// Plugin is the tuple of the foundation that compiled this, and
+	// PluginVersion is its version if it reports one.
+	//
+	// These are populated by Otto core and any set value here will be
+	// ignored.
+	Plugin        string `json:"plugin,omitempty"`
+	PluginVersion string `json:"plugin_version,omitempty"`
+}
@@ -1,5 +1,9 @@
 package foundation
 
+import (
+	"sync"
+)
+
 // Mock is a mock implementation of the Foundation interface.
 type Mock struct {
 	CompileCalled  bool
@@ -10,16 +14,54 @@ type Mock struct {
 	InfraCalled  bool
 	InfraContext *Context
 	InfraErr     error
+
+	// Calls are the names of the methods that were called, in the order
+	// they were called. This is safe to read once the calls are done.
+	Calls []string
+
+	lock sync.Mutex
+}
+
+// record appends name to Calls and calls f to record the arguments, both
+// under the lock. Otto may call the mock concurrently when it walks apps
+// in parallel.
+func (m *Mock) record(name string, f func()) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.Calls = append(m.Calls, name)
+	f()
 }
 
 func (m *Mock) Compile(ctx *Context) (*CompileResult, error) {
-	m.CompileCalled = true
-	m.CompileContext = ctx
+	m.record("Compile", func() {
+		m.CompileCalled = true
+		m.CompileContext = ctx
+	})
 	return m.CompileResult, m.CompileErr
 }
 
 func (m *Mock) Infra(ctx *Context) error {
-	m.InfraCalled = true
-	m.InfraContext = ctx
+	m.record("Infra", func() {
+		m.InfraCalled = true
+		m.InfraContext = ctx
+	})
 	return m.InfraErr
 }
+
+// MockValidator is a mock implementation of a Foundation that also
+// implements Validator.
+type MockValidator struct {
+	Mock
+
+	ValidateCalled  bool
+	ValidateContext *Context
+	ValidateErr     error
+}
+
+func (m *MockValidator) Validate(ctx *Context) error {
+	m.record("Validate", func() {
+		m.ValidateCalled = true
+		m.ValidateContext = ctx
+	})
+	return m.ValidateErr
+}
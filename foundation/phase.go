@@ -0,0 +1,46 @@
+package foundation
+
+import (
+	"path/filepath"
+)
+
+// The phases of an app that foundations compile files for. The files of
+// each phase are in the subdirectory of the foundation directory named
+// by AppPhaseDir.
+const (
+	AppPhaseDev    = "dev"
+	AppPhaseDevDep = "dev-dep"
+	AppPhaseBuild  = "build"
+	AppPhaseDeploy = "deploy"
+)
+
+// AppPhases are all the app phases, which is what an app that doesn't
+// declare its phases participates in.
+var AppPhases = []string{
+	AppPhaseDev,
+	AppPhaseDevDep,
+	AppPhaseBuild,
+	AppPhaseDeploy,
+}
+
+// AppPhaseDir returns the subdirectory of the foundation directory dir
+// with the files of the app phase.
+func AppPhaseDir(dir, phase string) string {
+	return filepath.Join(dir, "app-"+phase)
+}
+
+// HasAppPhase returns true if the app phase is in phases. An empty list
+// of phases has all of them.
+func HasAppPhase(phases []string, phase string) bool {
+	if len(phases) == 0 {
+		return true
+	}
+
+	for _, p := range phases {
+		if p == phase {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,9 +1,11 @@
 package foundation
 
 import (
+	"math/rand"
 	"reflect"
 	"sort"
 	"testing"
+	"testing/quick"
 )
 
 func TestTupleSlice_sort(t *testing.T) {
@@ -97,3 +99,160 @@ func TestTupleMap_Lookup(t *testing.T) {
 		}
 	}
 }
+
+func TestTupleMap_LookupPrecedence(t *testing.T) {
+	var value Tuple
+	m := make(TupleMap)
+	for _, tuple := range []Tuple{
+		{"rails", "aws", "vpc"},
+		{"rails", "aws", "*"},
+		{"rails", "*", "vpc"},
+		{"*", "aws", "vpc"},
+		{"rails", "*", "*"},
+		{"*", "aws", "*"},
+		{"*", "*", "vpc"},
+		{"*", "*", "*"},
+	} {
+		tuple := tuple
+		m[tuple] = func() (Foundation, error) {
+			value = tuple
+			return nil, nil
+		}
+	}
+
+	// Each tuple removes the winner of the previous one, so every row is
+	// looked up against the map without the rows above it.
+	cases := []struct {
+		T        Tuple
+		Expected Tuple
+	}{
+		{Tuple{"rails", "aws", "vpc"}, Tuple{"rails", "aws", "vpc"}},
+		{Tuple{"rails", "aws", "vpc"}, Tuple{"rails", "aws", "*"}},
+		{Tuple{"rails", "aws", "vpc"}, Tuple{"rails", "*", "vpc"}},
+		{Tuple{"rails", "aws", "vpc"}, Tuple{"*", "aws", "vpc"}},
+		{Tuple{"rails", "aws", "vpc"}, Tuple{"rails", "*", "*"}},
+		{Tuple{"rails", "aws", "vpc"}, Tuple{"*", "aws", "*"}},
+		{Tuple{"rails", "aws", "vpc"}, Tuple{"*", "*", "vpc"}},
+		{Tuple{"rails", "aws", "vpc"}, Tuple{"*", "*", "*"}},
+	}
+
+	for i, tc := range cases {
+		// Looking up many times makes sure map order doesn't matter
+		for j := 0; j < 20; j++ {
+			value = Tuple{}
+			f := m.Lookup(tc.T)
+			if f == nil {
+				t.Fatalf("%d: not found", i)
+			}
+			f()
+
+			if value != tc.Expected {
+				t.Fatalf("%d: bad: %s", i, value)
+			}
+		}
+
+		delete(m, tc.Expected)
+	}
+
+	if f := m.Lookup(Tuple{"rails", "aws", "vpc"}); f != nil {
+		t.Fatal("should not be found")
+	}
+}
+
+func TestTupleMap_Type(t *testing.T) {
+	f := func() (Foundation, error) { return nil, nil }
+	m := TupleMap{
+		Tuple{"consul", "aws", "*"}:    f,
+		Tuple{"consul", "aws", "vpc"}:  f,
+		Tuple{"vault", "aws", "*"}:     f,
+		Tuple{"*", "google", "simple"}: f,
+	}
+
+	actual := m.Type("consul")
+	expected := TupleSlice{
+		{"*", "google", "simple"},
+		{"consul", "aws", "*"},
+		{"consul", "aws", "vpc"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	delete(m, Tuple{"*", "google", "simple"})
+	if actual := m.Type("nomad"); len(actual) != 0 {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestTupleString(t *testing.T) {
+	cases := []struct {
+		Tuple    Tuple
+		Expected string
+	}{
+		{Tuple{"consul", "aws", "vpc-public"}, "consul/aws/vpc-public"},
+		{Tuple{"consul", "*", "*"}, "consul/*/*"},
+		{Tuple{"", "", ""}, "//"},
+		{Tuple{"a/b", `c\d`, "e"}, `a\/b/c\\d/e`},
+	}
+
+	for _, tc := range cases {
+		if actual := tc.Tuple.String(); actual != tc.Expected {
+			t.Fatalf("%#v bad: %s", tc.Tuple, actual)
+		}
+	}
+}
+
+func TestParseTuple(t *testing.T) {
+	cases := []struct {
+		Input    string
+		Expected Tuple
+		Err      bool
+	}{
+		{"consul/aws/vpc-public", Tuple{"consul", "aws", "vpc-public"}, false},
+		{"consul/*/*", Tuple{"consul", "*", "*"}, false},
+		{"//", Tuple{"", "", ""}, false},
+		{`a\/b/c\\d/e`, Tuple{"a/b", `c\d`, "e"}, false},
+		{"consul/aws", Tuple{}, true},
+		{"consul/aws/vpc/extra", Tuple{}, true},
+		{`consul/aws/vpc\`, Tuple{}, true},
+		{`consul/a\ws/vpc`, Tuple{}, true},
+	}
+
+	for _, tc := range cases {
+		actual, err := ParseTuple(tc.Input)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%q err: %s", tc.Input, err)
+		}
+		if actual != tc.Expected {
+			t.Fatalf("%q bad: %#v", tc.Input, actual)
+		}
+	}
+}
+
+func TestParseTuple_roundTrip(t *testing.T) {
+	f := func(a, b, c string) bool {
+		tuple := Tuple{a, b, c}
+		actual, err := ParseTuple(tuple.String())
+		return err == nil && actual == tuple
+	}
+
+	// Only slashes and backslashes are special, so make them likely
+	values := func(vs []reflect.Value, r *rand.Rand) {
+		const chars = `ab/\*-`
+		for i := range vs {
+			b := make([]byte, r.Intn(8))
+			for j := range b {
+				b[j] = chars[r.Intn(len(chars))]
+			}
+
+			vs[i] = reflect.ValueOf(string(b))
+		}
+	}
+
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 1000, Values: values}); err != nil {
+		t.Fatal(err)
+	}
+}
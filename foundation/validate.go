@@ -0,0 +1,8 @@
+package foundation
+
+// Validator is an optional interface that a Foundation can implement to
+// check its configuration before anything is compiled. This works like
+// app.Validator.
+type Validator interface {
+	Validate(ctx *Context) error
+}
@@ -41,7 +41,7 @@ func WriteVars(ctx *context.Shared) error {
 	// subdirs are the directories to write the vars file to. For now
 	// we just write it to one but we put this here because I can see
 	// a future where more may need it.
-	subdirs := []string{"app-build"}
+	subdirs := []string{AppPhaseBuild}
 
 	// Go through each foundation, grab its infrastructure data, and
 	// write it out to the proper path.
@@ -70,7 +70,7 @@ func WriteVars(ctx *context.Shared) error {
 				return err
 			}
 
-			path := filepath.Join(ctx.FoundationDirs[i], subdir)
+			path := AppPhaseDir(ctx.FoundationDirs[i], subdir)
 			if _, err := os.Stat(path); err != nil {
 				if os.IsNotExist(err) {
 					// Ignore directories that don't exist
@@ -0,0 +1,112 @@
+// Package artifact contains the stores for the files of builds, and the
+// helper to fetch them from the stores when deploying.
+package artifact
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Store stores the files of builds under keys. It has the same methods as
+// otto.ArtifactStore, so the stores of this package can be used for it.
+type Store interface {
+	// Put stores the data of r under key and returns the URL of the data
+	// for Fetch.
+	Put(key string, r io.Reader) (string, error)
+
+	// Get returns the data under key, which the caller must close.
+	Get(key string) (io.ReadCloser, error)
+
+	// Exists returns true if there is data under key.
+	Exists(key string) (bool, error)
+}
+
+// Sized is implemented by readers that know how many bytes they have
+// left. Stores that must know the size of the data up front, such as S3,
+// stream sized readers and buffer the others to a temporary file.
+type Sized interface {
+	Size() int64
+}
+
+// NewStore returns the store for the address addr, which is either an S3
+// URL such as "s3://bucket/prefix?region=us-west-2", or a local directory
+// as a path or "file://" URL. The S3 credentials are read from the
+// standard AWS environment variables.
+func NewStore(addr string) (Store, error) {
+	if !strings.Contains(addr, "://") {
+		return &Local{Dir: addr}, nil
+	}
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing artifact store address: %s", err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		return &Local{Dir: u.Path}, nil
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf(
+				"The artifact store address '%s' has no bucket.", addr)
+		}
+
+		s := s3FromURL(u)
+		s.Prefix = strings.Trim(u.Path, "/")
+		return s, nil
+	default:
+		return nil, fmt.Errorf(
+			"Unknown artifact store '%s'. The address must be an s3:// URL\n"+
+				"or a local directory.", addr)
+	}
+}
+
+// s3FromURL returns the S3 store for the bucket of the s3:// URL u, with
+// the region and endpoint of its query overriding the environment.
+func s3FromURL(u *url.URL) *S3 {
+	s := S3FromEnv(u.Host)
+	if v := u.Query().Get("region"); v != "" {
+		s.Region = v
+	}
+	if v := u.Query().Get("endpoint"); v != "" {
+		s.Endpoint = v
+	}
+
+	return s
+}
+
+// sizedReader is an io.Reader with a known size.
+type sizedReader struct {
+	io.Reader
+	size int64
+}
+
+func (r *sizedReader) Size() int64 { return r.size }
+
+// readerSize returns the size of r if it can be known without reading
+// it, or -1.
+func readerSize(r io.Reader) int64 {
+	switch v := r.(type) {
+	case Sized:
+		return v.Size()
+	case *os.File:
+		fi, err := v.Stat()
+		if err != nil || !fi.Mode().IsRegular() {
+			return -1
+		}
+
+		pos, err := v.Seek(0, os.SEEK_CUR)
+		if err != nil {
+			return -1
+		}
+
+		return fi.Size() - pos
+	case *strings.Reader:
+		return int64(v.Len())
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,59 @@
+package artifact
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNewStore(t *testing.T) {
+	s, err := NewStore("/tmp/artifacts")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if l, ok := s.(*Local); !ok || l.Dir != "/tmp/artifacts" {
+		t.Fatalf("bad: %#v", s)
+	}
+
+	s, err = NewStore("file:///tmp/artifacts")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if l, ok := s.(*Local); !ok || l.Dir != "/tmp/artifacts" {
+		t.Fatalf("bad: %#v", s)
+	}
+
+	s, err = NewStore("s3://bucket/builds/?region=eu-west-1&endpoint=http://localhost:9000")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	s3, ok := s.(*S3)
+	if !ok {
+		t.Fatalf("bad: %#v", s)
+	}
+	if s3.Bucket != "bucket" || s3.Prefix != "builds" ||
+		s3.Region != "eu-west-1" || s3.Endpoint != "http://localhost:9000" {
+		t.Fatalf("bad: %#v", s3)
+	}
+}
+
+func TestNewStore_invalid(t *testing.T) {
+	cases := []string{"s3:///builds", "ftp://host/builds"}
+	for _, tc := range cases {
+		if _, err := NewStore(tc); err == nil {
+			t.Fatalf("%s: should error", tc)
+		}
+	}
+}
+
+func TestReaderSize(t *testing.T) {
+	if n := readerSize(strings.NewReader("foo")); n != 3 {
+		t.Fatalf("bad: %d", n)
+	}
+	if n := readerSize(&sizedReader{size: 42}); n != 42 {
+		t.Fatalf("bad: %d", n)
+	}
+	if n := readerSize(io.MultiReader(strings.NewReader("foo"))); n != -1 {
+		t.Fatalf("bad: %d", n)
+	}
+}
@@ -0,0 +1,90 @@
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/otto/app"
+)
+
+// Fetch downloads the file f of a build to the path dst, and verifies its
+// checksum. The file is removed if the checksum doesn't match.
+//
+// The URL of the file can be a "file://", "http://", "https://" or
+// "s3://" URL. The S3 credentials are read from the standard AWS
+// environment variables, and the region and endpoint of S3 URLs from
+// their query like with NewStore.
+func Fetch(f *app.ArtifactFile, dst string) error {
+	r, err := open(f.URL)
+	if err != nil {
+		return fmt.Errorf("Error fetching artifact '%s': %s", f.URL, err)
+	}
+	defer r.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, h), r)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("Error fetching artifact '%s': %s", f.URL, err)
+	}
+
+	if f.Checksum != "" {
+		actual := hex.EncodeToString(h.Sum(nil))
+		if !strings.EqualFold(actual, f.Checksum) {
+			os.Remove(dst)
+			return fmt.Errorf(
+				"Checksum mismatch for artifact '%s'.\n\n"+
+					"Expected: %s\nActual:   %s",
+				f.URL, f.Checksum, actual)
+		}
+	}
+
+	return nil
+}
+
+// open opens the data at the URL raw.
+func open(raw string) (io.ReadCloser, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "file":
+		return os.Open(filepath.FromSlash(u.Path))
+	case "http", "https":
+		resp, err := cleanhttp.DefaultClient().Get(raw)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != 200 {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		}
+
+		return resp.Body, nil
+	case "s3":
+		s := s3FromURL(u)
+		return s.Get(strings.TrimPrefix(u.Path, "/"))
+	default:
+		return nil, fmt.Errorf("unknown URL scheme %q", u.Scheme)
+	}
+}
@@ -0,0 +1,145 @@
+package artifact
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+)
+
+// The SHA-256 checksum of "hello"
+const testChecksum = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+func TestFetch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := &Local{Dir: filepath.Join(dir, "store")}
+	u, err := s.Put("foo", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	dst := filepath.Join(dir, "out", "foo")
+	if err := Fetch(&app.ArtifactFile{URL: u, Checksum: testChecksum}, dst); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	data, err := ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(data) != "hello" {
+		t.Fatalf("bad: %s", data)
+	}
+
+	// A checksum mismatch removes the file
+	err = Fetch(&app.ArtifactFile{URL: u, Checksum: strings.Repeat("0", 64)}, dst)
+	if err == nil || !strings.Contains(err.Error(), "Checksum mismatch") {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestFetch_http(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/foo" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "foo")
+	f := &app.ArtifactFile{URL: server.URL + "/foo", Checksum: testChecksum}
+	if err := Fetch(f, dst); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	f.URL = server.URL + "/bar"
+	if err := Fetch(f, dst); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestFetch_s3(t *testing.T) {
+	var lock sync.Mutex
+	objects := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The region of the URL is used to sign the request
+		auth := r.Header.Get("Authorization")
+		if !strings.Contains(auth, "/us-west-2/s3/aws4_request") {
+			http.Error(w, "bad auth: "+auth, 403)
+			return
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		switch r.Method {
+		case "PUT":
+			data, _ := ioutil.ReadAll(r.Body)
+			objects[r.URL.Path] = string(data)
+		case "GET":
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte(data))
+		}
+	}))
+	defer server.Close()
+
+	for k, v := range map[string]string{
+		"AWS_REGION":            "eu-west-1",
+		"AWS_ACCESS_KEY_ID":     "key",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+	} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+
+	dir, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewStore("s3://bucket/builds?region=us-west-2&endpoint=" + server.URL)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	u, err := s.Put("foo", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	dst := filepath.Join(dir, "foo")
+	if err := Fetch(&app.ArtifactFile{URL: u, Checksum: testChecksum}, dst); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	data, err := ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(data) != "hello" {
+		t.Fatalf("bad: %s", data)
+	}
+}
@@ -0,0 +1,64 @@
+package artifact
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := &Local{Dir: dir}
+	ok, err := s.Exists("app/build/foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if ok {
+		t.Fatal("should not exist")
+	}
+
+	u, err := s.Put("app/build/foo", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if u != "file://"+filepath.ToSlash(filepath.Join(dir, "app", "build", "foo")) {
+		t.Fatalf("bad: %s", u)
+	}
+
+	ok, err = s.Exists("app/build/foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !ok {
+		t.Fatal("should exist")
+	}
+
+	r, err := s.Get("app/build/foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(data) != "hello" {
+		t.Fatalf("bad: %s", data)
+	}
+
+	// No temporary files are left
+	files, err := ioutil.ReadDir(filepath.Join(dir, "app", "build"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("bad: %#v", files)
+	}
+}
@@ -0,0 +1,300 @@
+package artifact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+// S3 is a Store that keeps the files in an S3 bucket, or in a service
+// with the same API set by Endpoint. The requests are signed with AWS
+// Signature Version 4.
+type S3 struct {
+	Bucket string
+	Prefix string // Prefix of the keys in the bucket
+	Region string
+
+	// Endpoint is the URL of the service, such as "http://localhost:9000".
+	// It defaults to the S3 endpoint of Region.
+	Endpoint string
+
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+
+	// Client is the HTTP client of the requests. It defaults to a
+	// cleanhttp client.
+	Client *http.Client
+
+	now func() time.Time // for tests
+}
+
+// S3FromEnv returns the S3 store for the bucket with the credentials and
+// the region of the standard AWS environment variables.
+func S3FromEnv(bucket string) *S3 {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return &S3{
+		Bucket:       bucket,
+		Region:       region,
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+func (s *S3) Put(key string, r io.Reader) (string, error) {
+	// S3 must know the length of the data, so data of unknown size is
+	// buffered to a temporary file first.
+	size := readerSize(r)
+	if size < 0 {
+		f, err := ioutil.TempFile("", "otto-artifact")
+		if err != nil {
+			return "", err
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+
+		size, err = io.Copy(f, r)
+		if err != nil {
+			return "", err
+		}
+		if _, err := f.Seek(0, 0); err != nil {
+			return "", err
+		}
+		r = f
+	}
+
+	req, err := s.request("PUT", key, ioutil.NopCloser(r))
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = nil
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return "", s.statusErr("uploading", key, resp)
+	}
+
+	return s.objectURL(key), nil
+}
+
+func (s *S3) Get(key string) (io.ReadCloser, error) {
+	req, err := s.request("GET", key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		defer resp.Body.Close()
+		return nil, s.statusErr("downloading", key, resp)
+	}
+
+	return resp.Body, nil
+}
+
+func (s *S3) Exists(key string) (bool, error) {
+	req, err := s.request("HEAD", key, nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case 200:
+		return true, nil
+	case 404:
+		return false, nil
+	default:
+		return false, s.statusErr("checking", key, resp)
+	}
+}
+
+// key returns the key in the bucket of the key of the store.
+func (s *S3) key(key string) string {
+	if s.Prefix == "" {
+		return key
+	}
+
+	return path.Join(s.Prefix, key)
+}
+
+// objectURL returns the s3:// URL of key. The region and the endpoint are
+// in the query, like the addresses of NewStore, so that Fetch reaches the
+// same service.
+func (s *S3) objectURL(key string) string {
+	q := make(url.Values)
+	if s.Region != "" {
+		q.Set("region", s.Region)
+	}
+	if s.Endpoint != "" {
+		q.Set("endpoint", s.Endpoint)
+	}
+
+	u := &url.URL{
+		Scheme:   "s3",
+		Host:     s.Bucket,
+		Path:     "/" + s.key(key),
+		RawQuery: q.Encode(),
+	}
+	return u.String()
+}
+
+// request returns the unsigned request with the method for key. The
+// requests use path-style addressing so that they work with any bucket
+// name and with other endpoints.
+func (s *S3) request(method, key string, body io.ReadCloser) (*http.Request, error) {
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3.amazonaws.com"
+		if s.Region != "" && s.Region != "us-east-1" {
+			endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", s.Region)
+		}
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing S3 endpoint: %s", err)
+	}
+	u.Path = "/" + s.Bucket + "/" + s.key(key)
+
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Body = body
+	}
+
+	return req, nil
+}
+
+// do signs and sends the request req.
+func (s *S3) do(req *http.Request) (*http.Response, error) {
+	if s.AccessKey == "" || s.SecretKey == "" {
+		return nil, fmt.Errorf(
+			"No AWS credentials for the S3 artifact store. Set the\n" +
+				"AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables.")
+	}
+
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	s.sign(req, now().UTC())
+
+	client := s.Client
+	if client == nil {
+		client = cleanhttp.DefaultClient()
+	}
+
+	return client.Do(req)
+}
+
+// sign signs the request req at time t with AWS Signature Version 4. The
+// payload isn't signed so that it can be streamed.
+func (s *S3) sign(req *http.Request, t time.Time) {
+	const payload = "UNSIGNED-PAYLOAD"
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payload)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	// The canonical headers are all the headers set above
+	names := make([]string, 0, len(req.Header))
+	for k := range req.Header {
+		names = append(names, strings.ToLower(k))
+	}
+	sort.Strings(names)
+	headers := make([]string, len(names))
+	for i, k := range names {
+		headers[i] = k + ":" + strings.TrimSpace(req.Header.Get(k)) + "\n"
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		strings.Join(headers, ""),
+		signed,
+		payload,
+	}, "\n")
+
+	scope := strings.Join([]string{date, s.Region, "s3", "aws4_request"}, "/")
+	toSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hashHex([]byte(canonical)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Del("Host")
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signed, signature))
+}
+
+// statusErr returns the error for the unexpected response resp.
+func (s *S3) statusErr(what, key string, resp *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf(
+		"Error %s 's3://%s/%s': %s\n\n%s",
+		what, s.Bucket, s.key(key), resp.Status, strings.TrimSpace(string(body)))
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -150,7 +150,7 @@ func appFoundations(opts *AppOptions) error {
 	// Go through each foundation and setup the layers
 	log.Printf("[INFO] compile: looking for foundation layers for dev")
 	for i, dir := range opts.Ctx.FoundationDirs {
-		devDir := filepath.Join(dir, "app-dev")
+		devDir := foundation.AppPhaseDir(dir, foundation.AppPhaseDev)
 		log.Printf("[DEBUG] compile: checking foundation dir: %s", devDir)
 
 		_, err := os.Stat(filepath.Join(devDir, "layer.sh"))
@@ -227,10 +227,10 @@ func (a *AppOptions) prepareBindata() error {
 		"deploy":  make([]string, len(ctx.FoundationDirs)),
 	}
 	for i, dir := range ctx.FoundationDirs {
-		foundationDirsContext["dev"][i] = filepath.Join(dir, "app-dev")
-		foundationDirsContext["dev_dep"][i] = filepath.Join(dir, "app-dev-dep")
-		foundationDirsContext["build"][i] = filepath.Join(dir, "app-build")
-		foundationDirsContext["deploy"][i] = filepath.Join(dir, "app-deploy")
+		foundationDirsContext["dev"][i] = foundation.AppPhaseDir(dir, foundation.AppPhaseDev)
+		foundationDirsContext["dev_dep"][i] = foundation.AppPhaseDir(dir, foundation.AppPhaseDevDep)
+		foundationDirsContext["build"][i] = foundation.AppPhaseDir(dir, foundation.AppPhaseBuild)
+		foundationDirsContext["deploy"][i] = foundation.AppPhaseDir(dir, foundation.AppPhaseDeploy)
 	}
 	data.Context["foundation_dirs"] = foundationDirsContext
 
@@ -0,0 +1,30 @@
+package flag
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KV is a flag.Value that parses "key=value" arguments into a map. The
+// flag can be given more than once, and later values of a key replace
+// earlier ones.
+type KV map[string]string
+
+func (v *KV) String() string {
+	return ""
+}
+
+func (v *KV) Set(raw string) error {
+	idx := strings.Index(raw, "=")
+	if idx <= 0 {
+		return fmt.Errorf("No '=' value in arg: %s", raw)
+	}
+
+	if *v == nil {
+		*v = make(map[string]string)
+	}
+
+	key, value := raw[0:idx], raw[idx+1:]
+	(*v)[key] = value
+	return nil
+}
@@ -0,0 +1,40 @@
+package flag
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestKV_impl(t *testing.T) {
+	var _ flag.Value = new(KV)
+}
+
+func TestKV(t *testing.T) {
+	cases := []struct {
+		Input  string
+		Output map[string]string
+		Error  bool
+	}{
+		{"key=value", map[string]string{"key": "value"}, false},
+		{"key=", map[string]string{"key": ""}, false},
+		{"key=foo=bar", map[string]string{"key": "foo=bar"}, false},
+		{"key", nil, true},
+		{"=value", nil, true},
+	}
+
+	for _, tc := range cases {
+		var v KV
+		err := v.Set(tc.Input)
+		if (err != nil) != tc.Error {
+			t.Fatalf("%s: %v", tc.Input, err)
+		}
+		if tc.Error {
+			continue
+		}
+
+		if !reflect.DeepEqual(map[string]string(v), tc.Output) {
+			t.Fatalf("%s: %#v", tc.Input, v)
+		}
+	}
+}
@@ -20,3 +20,13 @@ type Installer interface {
 	// or "" if it doesn't seem installed.
 	Path() string
 }
+
+// UsageRecorder is implemented by installers that keep an
+// installdir.Manifest. This is called by Project.InstallIfNeeded when the
+// project is already installed, so the manifest records that it is still
+// used.
+type UsageRecorder interface {
+	// Used records that the installed project was used. This does
+	// nothing if the project wasn't installed by this installer.
+	Used() error
+}
@@ -5,14 +5,17 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/otto/helper/installdir"
 	"github.com/hashicorp/otto/ui"
 	"github.com/mitchellh/ioprogress"
 	"gopkg.in/flosch/pongo2.v3"
@@ -32,6 +35,11 @@ type GoInstaller struct {
 	// Ui is the Otto UI for asking the user for input and outputting
 	// the status of installation.
 	Ui ui.Ui
+
+	// RequiredBy is the implementation that requires the project, which
+	// is recorded in the installdir.Manifest of the installation. This is
+	// usually the Implementation field of the shared context.
+	RequiredBy string
 }
 
 func (i *GoInstaller) InstallAsk(installed, required, latest *version.Version) (bool, error) {
@@ -148,8 +156,16 @@ func (i *GoInstaller) Install(vsn *version.Version) error {
 	}
 	defer zipR.Close()
 
-	// Clear our install directory
+	// Clear our install directory, keeping what required the previous
+	// installation in the new manifest.
 	installDir := filepath.Join(i.Dir, i.Name)
+	manifest, err := installdir.ReadManifest(installDir)
+	if err != nil {
+		log.Printf("[WARN] error reading manifest of %s: %s", i.Name, err)
+	}
+	if manifest == nil {
+		manifest = new(installdir.Manifest)
+	}
 	if err := os.RemoveAll(installDir); err != nil {
 		return err
 	}
@@ -181,10 +197,39 @@ func (i *GoInstaller) Install(vsn *version.Version) error {
 		}
 	}
 
+	now := time.Now().UTC()
+	manifest.Name = i.Name
+	manifest.Version = vsn.String()
+	manifest.InstalledAt = now
+	manifest.LastUsed = now
+	manifest.Require(i.RequiredBy)
+	if err := installdir.WriteManifest(installDir, manifest); err != nil {
+		return err
+	}
+
 	i.Ui.Header(fmt.Sprintf("[green]%s installed successfully!", i.Name))
 	return nil
 }
 
+func (i *GoInstaller) Used() error {
+	if i.Path() == "" {
+		return nil
+	}
+
+	installDir := filepath.Join(i.Dir, i.Name)
+	manifest, err := installdir.ReadManifest(installDir)
+	if err != nil {
+		return err
+	}
+	if manifest == nil {
+		// Installed by an older version of Otto, so when is unknown
+		manifest = &installdir.Manifest{Name: i.Name}
+	}
+	manifest.LastUsed = time.Now().UTC()
+	manifest.Require(i.RequiredBy)
+	return installdir.WriteManifest(installDir, manifest)
+}
+
 func (i *GoInstaller) Path() string {
 	path := filepath.Join(i.Dir, i.Name, i.Name)
 	if _, err := os.Stat(path); err == nil {
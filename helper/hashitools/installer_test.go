@@ -1,9 +1,52 @@
 package hashitools
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+
+	"github.com/hashicorp/otto/helper/installdir"
 )
 
 func TestGoInstaller_impl(t *testing.T) {
 	var _ Installer = new(GoInstaller)
+	var _ UsageRecorder = new(GoInstaller)
+}
+
+func TestGoInstallerUsed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	i := &GoInstaller{Name: "packer", Dir: dir, RequiredBy: "app:go/aws/simple"}
+
+	// Not installed by the installer, so nothing is recorded
+	if err := i.Used(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "packer")); !os.IsNotExist(err) {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Installed by an older version of Otto, without a manifest
+	if err := os.MkdirAll(filepath.Join(dir, "packer"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "packer", "packer"), nil, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := i.Used(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	m, err := installdir.ReadManifest(filepath.Join(dir, "packer"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if m == nil || m.LastUsed.IsZero() || !reflect.DeepEqual(m.RequiredBy, []string{"app:go/aws/simple"}) {
+		t.Fatalf("bad: %#v", m)
+	}
 }
@@ -87,6 +87,12 @@ func (p *Project) InstallIfNeeded() error {
 	// No install required? Exit out.
 	if !installRequired {
 		log.Printf("[DEBUG] installIfNeeded: %s no installation needed", p.Name)
+		if r, ok := p.Installer.(UsageRecorder); ok {
+			if err := r.Used(); err != nil {
+				log.Printf("[WARN] error recording use of %s: %s", p.Name, err)
+			}
+		}
+
 		return nil
 	}
 
@@ -0,0 +1,89 @@
+// Package installdir has the manifests that installers write into the
+// directories they install projects into within the InstallDir of the
+// shared context.
+package installdir
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ManifestName is the name of the file that installers write into the
+// installation directory of a project, see Manifest.
+const ManifestName = ".otto-manifest.json"
+
+// Manifest records what is installed in the installation directory of a
+// project and what required it, so that Otto can tell which installed
+// binaries are still needed by the implementations it has.
+type Manifest struct {
+	// Name and Version are the project and the version that is installed.
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+
+	// InstalledAt is when the project was installed, and LastUsed is the
+	// last time an implementation that required it found it installed.
+	InstalledAt time.Time `json:"installed_at"`
+	LastUsed    time.Time `json:"last_used"`
+
+	// RequiredBy are the implementations that required the project, as
+	// given by the Implementation field of their shared context.
+	RequiredBy []string `json:"required_by,omitempty"`
+}
+
+// Require adds name to the implementations that required the project,
+// if it isn't already one of them. An empty name is ignored.
+func (m *Manifest) Require(name string) {
+	if name == "" {
+		return
+	}
+	for _, n := range m.RequiredBy {
+		if n == name {
+			return
+		}
+	}
+
+	m.RequiredBy = append(m.RequiredBy, name)
+}
+
+// ReadManifest reads the manifest in the installation directory dir, or
+// returns nil if there isn't one, such as for projects installed by older
+// versions of Otto.
+func ReadManifest(dir string) (*Manifest, error) {
+	f, err := os.Open(filepath.Join(dir, ManifestName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+	defer f.Close()
+
+	var result Manifest
+	if err := json.NewDecoder(f).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// WriteManifest writes the manifest m into the installation directory dir.
+// The manifest is written to a temporary file first so that a manifest
+// that is being written is never read.
+func WriteManifest(dir string, m *Manifest) error {
+	path := filepath.Join(dir, ManifestName)
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(f).Encode(m)
+	f.Close()
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
@@ -0,0 +1,44 @@
+package installdir
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Nothing installed yet
+	m, err := ReadManifest(dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if m != nil {
+		t.Fatalf("bad: %#v", m)
+	}
+
+	m = &Manifest{Name: "terraform", Version: "0.6.3"}
+	m.Require("infra:aws")
+	m.Require("infra:aws")
+	m.Require("")
+	if err := WriteManifest(dir, m); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ReadManifest(dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, m) {
+		t.Fatalf("bad: %#v", actual)
+	}
+	if len(actual.RequiredBy) != 1 {
+		t.Fatalf("bad: %#v", actual.RequiredBy)
+	}
+}
@@ -26,16 +26,28 @@ var (
 	boltAddrMapKey  = []byte("addr_map")
 	boltAddrHeapKey = []byte("addr_heap")
 	boltSubnetKey   = []byte("subnet")
+
+	// boltChecksumKey is the checksum of the address map and heap. This
+	// isn't set in databases that weren't written since it was added.
+	boltChecksumKey = []byte("checksum")
 )
 
 var (
-	boltDataVersion byte = 2
+	boltDataVersion byte = 3
 )
 
+// DefaultSubnet is the subnet that addresses are allocated from if
+// a DB has no Subnet set.
+const DefaultSubnet = "100.64.0.0/10"
+
+// DefaultLockTimeout is the default time to wait for another process
+// that is using the database.
+const DefaultLockTimeout = 10 * time.Second
+
 var boltCidr *net.IPNet
 
 func init() {
-	_, cidr, err := net.ParseCIDR("100.64.0.0/10")
+	_, cidr, err := net.ParseCIDR(DefaultSubnet)
 	if err != nil {
 		panic(err)
 	}
@@ -71,10 +83,58 @@ type DB struct {
 	// exist but needs to be a writable path. The parent directory will
 	// be made.
 	Path string
+
+	// Subnet is the subnet to allocate addresses from. If this is nil,
+	// 100.64.0.0/10 is used. Use ParseSubnet to validate a subnet.
+	//
+	// The subnet is stored in the database. If it changes, any leases
+	// outside of the new subnet are released.
+	Subnet *net.IPNet
+
+	// LockTimeout is the time to wait for another process that is using
+	// the database before giving up. This defaults to DefaultLockTimeout.
+	//
+	// The database file is locked for the duration of every operation:
+	// exclusively for changes and shared for reads, so multiple Otto
+	// processes can safely use the same database.
+	LockTimeout time.Duration
+
+	// OnRecover, if set, is called when the database was corrupt and was
+	// replaced with a new, empty database. The corrupt database is moved
+	// to corruptPath. All leases are lost, so other projects may be
+	// assigned new addresses.
+	OnRecover func(corruptPath string)
+}
+
+// subnet returns the subnet that addresses are allocated from.
+func (this *DB) subnet() *net.IPNet {
+	if this.Subnet != nil {
+		return this.Subnet
+	}
+
+	return boltCidr
+}
+
+// Owner is the owner of a lease, recorded with the lease so that it can
+// be garbage collected once the owner no longer exists.
+type Owner struct {
+	// AppID is the ID of the Appfile that uses the address.
+	AppID string
+
+	// Path is the path to the project directory that uses the address.
+	// The lease is never garbage collected while this path exists
+	// unless it is forced.
+	Path string
 }
 
 // Next returns the next IP that is not allocated.
 func (this *DB) Next() (net.IP, error) {
+	return this.NextFor(nil)
+}
+
+// NextFor returns the next IP that is not allocated and records the
+// owner of the lease. The owner can be nil.
+func (this *DB) NextFor(owner *Owner) (net.IP, error) {
 	db, err := this.db()
 	if err != nil {
 		return nil, err
@@ -96,52 +156,79 @@ func (this *DB) Next() (net.IP, error) {
 		}
 
 		// Parse the subnet
-		ip, ipnet, err := net.ParseCIDR(string(data))
+		_, ipnet, err := net.ParseCIDR(string(data))
 		if err != nil {
 			return err
 		}
-		ip = ip.To4()
-
-		// Generate a random IP in our subnet and try to use it
-		found := false
-		for {
-			// Create a random address in the subnet
-			ipRaw := make([]byte, 4)
-			binary.LittleEndian.PutUint32(ipRaw, rand.Uint32())
-			for i, v := range ipRaw {
-				ip[i] = ip[i] + (v &^ ipnet.Mask[i])
-			}
-
-			// If this IP exists, then try again
-			if _, ok := addrMap[ip.String()]; ok {
-				continue
+		// Pick a free address, or reuse the oldest lease if there is
+		// none, since it's the most likely to be stale.
+		ip := freeAddr(ipnet, addrMap)
+		if ip == nil {
+			if addrQ.Len() == 0 {
+				return fmt.Errorf("subnet %s has no usable addresses", ipnet)
 			}
 
-			// We found an IP!
-			found = true
-			break
+			ip = heap.Pop(&addrQ).(*ipEntry).Value
 		}
-
-		// If we didn't find an IP, we just use the oldest one available
-		if !found {
-			result = heap.Pop(&addrQ).(*ipEntry).Value
-		}
-
-		// Set the result
 		result = ip
 
-		// Add the IP to the map
-		entry := &ipEntry{LeaseTime: time.Now().UTC(), Value: ip}
+		// Add the IP to the queue
+		now := time.Now().UTC()
+		entry := &ipEntry{LeaseTime: now, Created: now, Value: ip}
+		entry.setOwner(owner)
 		heap.Push(&addrQ, entry)
-		addrMap[ip.String()] = entry.Index
 
 		// Store the data
-		return this.putData(bucket, addrMap, addrQ)
+		return this.putData(bucket, addrQ)
 	})
 
 	return result, err
 }
 
+// freeAddrAttempts is the number of random addresses that freeAddr tries
+// before it walks the subnet for a free one.
+const freeAddrAttempts = 32
+
+// freeAddr returns an address of the subnet that isn't in addrMap, or nil
+// if every address is leased. The network and broadcast addresses of the
+// subnet are never returned.
+//
+// A random address is tried a few times first, so that projects are
+// unlikely to be given the same address as before in other databases.
+// If those are all taken, which is likely once a small subnet is mostly
+// leased, the addresses are walked from a random one instead.
+func freeAddr(ipnet *net.IPNet, addrMap map[string]int) net.IP {
+	ones, bits := ipnet.Mask.Size()
+	if bits != 32 || bits-ones < 2 {
+		return nil
+	}
+
+	network := binary.BigEndian.Uint32(ipnet.IP.To4())
+	hosts := uint32(1)<<uint(bits-ones) - 2
+	addr := func(i uint32) net.IP {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, network+1+i)
+		return ip
+	}
+
+	for i := 0; i < freeAddrAttempts; i++ {
+		ip := addr(uint32(rand.Int63n(int64(hosts))))
+		if _, ok := addrMap[ip.String()]; !ok {
+			return ip
+		}
+	}
+
+	start := uint32(rand.Int63n(int64(hosts)))
+	for i := uint32(0); i < hosts; i++ {
+		ip := addr((start + i) % hosts)
+		if _, ok := addrMap[ip.String()]; !ok {
+			return ip
+		}
+	}
+
+	return nil
+}
+
 // Release releases the given IP, removing it from the database.
 func (this *DB) Release(ip net.IP) error {
 	db, err := this.db()
@@ -166,19 +253,23 @@ func (this *DB) Release(ip net.IP) error {
 		}
 
 		// Delete and save
-		delete(addrMap, ip.String())
-		addrQ, addrQ[len(addrQ)-1] = append(addrQ[:idx], addrQ[idx+1:]...), nil
-		heap.Init(&addrQ)
-
-		return this.putData(bucket, addrMap, addrQ)
+		heap.Remove(&addrQ, idx)
+		return this.putData(bucket, addrQ)
 	})
 }
 
 // Renew updates the last used time of the given IP to right now.
 //
 // This should be called whenever a DB-given IP is used to make sure
-// it isn't chosen as the LRU if we run out of IPs.
+// it isn't chosen as the LRU if we run out of IPs, and so that it
+// isn't garbage collected.
 func (this *DB) Renew(ip net.IP) error {
+	return this.RenewFor(ip, nil)
+}
+
+// RenewFor is like Renew but also records the owner of the lease. If
+// owner is nil, the recorded owner is kept.
+func (this *DB) RenewFor(ip net.IP, owner *Owner) error {
 	db, err := this.db()
 	if err != nil {
 		return err
@@ -202,10 +293,199 @@ func (this *DB) Renew(ip net.IP) error {
 
 		entry := addrQ[idx]
 		entry.LeaseTime = time.Now().UTC()
+		entry.setOwner(owner)
 		addrQ.Update(entry)
 
+		return this.putData(bucket, addrQ)
+	})
+}
+
+// Claim renews the lease of the given IP, recording the owner, or leases
+// it if it isn't leased, such as after the database was recovered. It
+// returns false if the IP can't be used because it isn't in the subnet or
+// it is leased to another owner, such as when the database was rebuilt
+// and the IP was given to another project since.
+//
+// This should be used to keep using an IP that was leased before.
+func (this *DB) Claim(ip net.IP, owner *Owner) (bool, error) {
+	if !this.subnet().Contains(ip) {
+		return false, nil
+	}
+
+	db, err := this.db()
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	ok := true
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltLocalAddrBucket)
+
+		addrMap, addrQ, err := this.getData(bucket)
+		if err != nil {
+			return err
+		}
+
+		if idx, exists := addrMap[ip.String()]; exists {
+			entry := addrQ[idx]
+			if !entry.ownedBy(owner) {
+				log.Printf(
+					"[INFO] lease %s is owned by %s (%s), not claiming",
+					ip, entry.AppID, entry.Path)
+				ok = false
+				return nil
+			}
+
+			entry.LeaseTime = time.Now().UTC()
+			entry.setOwner(owner)
+			addrQ.Update(entry)
+		} else {
+			log.Printf("[INFO] re-registering lease: %s", ip)
+			now := time.Now().UTC()
+			entry := &ipEntry{LeaseTime: now, Created: now, Value: ip.To4()}
+			entry.setOwner(owner)
+			heap.Push(&addrQ, entry)
+		}
+
+		return this.putData(bucket, addrQ)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
+
+// GC releases all the leases that haven't been renewed within olderThan
+// and returns the released addresses.
+//
+// Leases whose owning project path still exists are kept unless force
+// is true, since the project may just not have been used in a while.
+// Leases without a recorded owner are released based on age alone.
+func (this *DB) GC(olderThan time.Duration, force bool) ([]net.IP, error) {
+	cutoff := time.Now().UTC().Add(-olderThan)
+	return this.releaseIf(func(entry *ipEntry) bool {
+		if !entry.LeaseTime.Before(cutoff) {
+			return false
+		}
+
+		if !force && entry.Path != "" {
+			if _, err := os.Stat(entry.Path); err == nil {
+				log.Printf(
+					"[DEBUG] keeping stale lease %s, project exists: %s",
+					entry.Value, entry.Path)
+				return false
+			}
+		}
+
+		log.Printf("[INFO] releasing stale lease: %s", entry.Value)
+		return true
+	})
+}
+
+// ReleaseOrphans releases all the leases whose owning project path no
+// longer exists, regardless of when they were last renewed, and returns
+// the released addresses. Leases without a recorded owner are kept.
+func (this *DB) ReleaseOrphans() ([]net.IP, error) {
+	return this.releaseIf(func(entry *ipEntry) bool {
+		if entry.Path == "" {
+			return false
+		}
+		if _, err := os.Stat(entry.Path); !os.IsNotExist(err) {
+			return false
+		}
+
+		log.Printf(
+			"[INFO] releasing lease %s, project is gone: %s",
+			entry.Value, entry.Path)
+		return true
+	})
+}
+
+// releaseIf releases all the leases for which f returns true and
+// returns the released addresses.
+func (this *DB) releaseIf(f func(*ipEntry) bool) ([]net.IP, error) {
+	db, err := this.db()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var result []net.IP
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltLocalAddrBucket)
+
+		_, addrQ, err := this.getData(bucket)
+		if err != nil {
+			return err
+		}
+
+		newQ := ipQueue(make([]*ipEntry, 0, len(addrQ)))
+		for _, entry := range addrQ {
+			if f(entry) {
+				result = append(result, entry.Value)
+			} else {
+				newQ = append(newQ, entry)
+			}
+		}
+		if len(result) == 0 {
+			return nil
+		}
+
+		newQ.init()
+		return this.putData(bucket, newQ)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Summary is a summary of the contents of a DB, suitable for diagnostics.
+type Summary struct {
+	// Subnet is the subnet that addresses are allocated from.
+	Subnet string
+
+	// Leases is the number of leased addresses and OldestLease is the
+	// last time the least recently used address was used.
+	Leases      int
+	OldestLease time.Time
+}
+
+// Summary returns a summary of the contents of the database. This only
+// reads the database, so it doesn't create or upgrade it.
+func (this *DB) Summary() (*Summary, error) {
+	if _, err := os.Stat(this.Path); os.IsNotExist(err) {
+		return &Summary{Subnet: this.subnet().String()}, nil
+	}
+
+	db, err := this.open(true)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var result Summary
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltLocalAddrBucket)
+		result.Subnet = string(bucket.Get(boltSubnetKey))
+
+		_, addrQ, err := this.getData(bucket)
+		if err != nil {
+			return err
+		}
+
+		result.Leases = len(addrQ)
+		if len(addrQ) > 0 {
+			result.OldestLease = addrQ[0].LeaseTime
+		}
+
 		return nil
 	})
+
+	return &result, err
 }
 
 // db returns the database handle, and sets up the DB if it has never
@@ -216,14 +496,44 @@ func (this *DB) db() (*bolt.DB, error) {
 		return nil, err
 	}
 
+	db, err := this.openAndSetup()
+	if isCorrupt(err) {
+		log.Printf("[WARN] IP database is corrupt, recovering: %s", err)
+		if err := this.recover(); err != nil {
+			return nil, err
+		}
+
+		db, err = this.openAndSetup()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// openAndSetup opens the database and sets it up.
+func (this *DB) openAndSetup() (*bolt.DB, error) {
 	// Create/Open the DB
-	db, err := bolt.Open(this.Path, 0644, nil)
+	db, err := this.open(false)
 	if err != nil {
 		return nil, err
 	}
 
+	// Close the DB if we fail to set it up so we don't hold the lock
+	if err := this.setup(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// setup creates the buckets of a new database, upgrades the data to
+// the latest version, and stores the configured subnet.
+func (this *DB) setup(db *bolt.DB) error {
 	// Create the buckets
-	err = db.Update(func(tx *bolt.Tx) error {
+	err := db.Update(func(tx *bolt.Tx) error {
 		for _, b := range boltBuckets {
 			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
 				return err
@@ -233,30 +543,29 @@ func (this *DB) db() (*bolt.DB, error) {
 		return nil
 	})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	// Check the DB version
+	// Check the DB version. A new DB is bootstrapped by upgrading it
+	// from the first version.
 	var version byte
-	bootstrap := false
-	err = db.Update(func(tx *bolt.Tx) error {
+	err = db.View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(boltLocalAddrBucket)
-		data := bucket.Get([]byte("version"))
+		data := bucket.Get(boltVersionKey)
 		if data == nil || len(data) == 0 {
-			version = boltDataVersion
-			bootstrap = true
-			return bucket.Put([]byte("version"), []byte{boltDataVersion})
+			version = 1
+			return nil
 		}
 
 		version = data[0]
 		return nil
 	})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	if version > boltDataVersion {
-		return nil, fmt.Errorf(
+		return fmt.Errorf(
 			"IP data version is higher than this version of Otto knows how\n"+
 				"to handle! This version of Otto can read up to version %d,\n"+
 				"but version %d data file found.\n\n"+
@@ -268,13 +577,14 @@ func (this *DB) db() (*bolt.DB, error) {
 	// Map of update functions
 	updateMap := map[byte]func(*bolt.DB) error{
 		1: this.v1_to_v2,
+		2: this.v2_to_v3,
 	}
 	for version < boltDataVersion {
 		log.Printf(
 			"[INFO] upgrading lease DB from v%d to v%d", version, version+1)
 		err := updateMap[version](db)
 		if err != nil {
-			return nil, fmt.Errorf(
+			return fmt.Errorf(
 				"Error upgrading data from v%d to v%d: %s",
 				version, version+1, err)
 		}
@@ -282,15 +592,101 @@ func (this *DB) db() (*bolt.DB, error) {
 		version++
 	}
 
-	// Bootstrap if we have to
-	if bootstrap {
-		if err := this.v1_to_v2(db); err != nil {
+	// Make sure the data is readable so that corruption is detected now
+	err = db.View(func(tx *bolt.Tx) error {
+		_, _, err := this.getData(tx.Bucket(boltLocalAddrBucket))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Make sure we're using the configured subnet
+	if err := this.updateSubnet(db); err != nil {
+		return fmt.Errorf("Error updating IP subnet: %s", err)
+	}
+
+	return nil
+}
+
+// open opens the database file, waiting for a lock on it: shared if
+// readOnly is true, exclusive otherwise.
+func (this *DB) open(readOnly bool) (*bolt.DB, error) {
+	return openBolt(this.Path, "IP", this.LockTimeout, readOnly)
+}
+
+// openBolt opens a bolt database, waiting up to timeout for a lock on it.
+// The kind of database is used in the error if this times out.
+//
+// If the file is replaced while this waits for the lock, such as by
+// Compact, the lock is on the old file, so the new file is opened instead.
+func openBolt(
+	path, kind string, timeout time.Duration, readOnly bool) (*bolt.DB, error) {
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+
+	for {
+		before, _ := os.Stat(path)
+		db, err := bolt.Open(path, 0644, &bolt.Options{
+			Timeout:  timeout,
+			ReadOnly: readOnly,
+		})
+		if err == bolt.ErrTimeout {
+			return nil, fmt.Errorf(
+				"Timed out after %s waiting for the %s database. Another Otto\n"+
+					"process is using the %s database at %s. Please wait for it\n"+
+					"to finish and try again.",
+				timeout, kind, kind, path)
+		}
+		if err != nil {
 			return nil, err
 		}
+
+		after, err := os.Stat(path)
+		if before == nil || (err == nil && os.SameFile(before, after)) {
+			return db, nil
+		}
+
+		log.Printf("[DEBUG] %s database was replaced while opening, reopening", kind)
+		db.Close()
 	}
+}
 
-	// Just call the upgrade to init
-	return db, nil
+// updateSubnet stores the configured subnet in the database if it
+// changed, releasing all the leases that are outside of it.
+func (this *DB) updateSubnet(db *bolt.DB) error {
+	subnet := this.subnet()
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltLocalAddrBucket)
+		old := string(bucket.Get(boltSubnetKey))
+		if old == subnet.String() {
+			return nil
+		}
+
+		log.Printf("[INFO] lease DB subnet changed from %s to %s", old, subnet)
+		_, addrQ, err := this.getData(bucket)
+		if err != nil {
+			return err
+		}
+
+		// Keep only the leases in the new subnet
+		newQ := ipQueue(make([]*ipEntry, 0, len(addrQ)))
+		for _, entry := range addrQ {
+			if subnet.Contains(entry.Value) {
+				newQ = append(newQ, entry)
+			} else {
+				log.Printf("[INFO] releasing lease outside of subnet: %s", entry.Value)
+			}
+		}
+		newQ.init()
+
+		if err := this.putData(bucket, newQ); err != nil {
+			return err
+		}
+
+		return bucket.Put(boltSubnetKey, []byte(subnet.String()))
+	})
 }
 
 func (this *DB) v1_to_v2(db *bolt.DB) error {
@@ -317,10 +713,40 @@ func (this *DB) v1_to_v2(db *bolt.DB) error {
 	})
 }
 
-func (this *DB) putData(
-	bucket *bolt.Bucket,
-	addrMap map[string]int,
-	addrQ ipQueue) error {
+// v2_to_v3 adds lease owners. Existing leases have no owner and their
+// lease time was never updated by Renew, so they're all renewed to give
+// them a full window to be renewed (and claimed) by their owners before
+// they can be garbage collected.
+func (this *DB) v2_to_v3(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltLocalAddrBucket)
+
+		_, addrQ, err := this.getData(bucket)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		for _, entry := range addrQ {
+			entry.LeaseTime = now
+		}
+		addrQ.init()
+		if err := this.putData(bucket, addrQ); err != nil {
+			return err
+		}
+
+		return bucket.Put(boltVersionKey, []byte{byte(3)})
+	})
+}
+
+// putData stores the queue of leases along with the map from address to
+// queue index, which is rebuilt from the queue so it's never stale.
+func (this *DB) putData(bucket *bolt.Bucket, addrQ ipQueue) error {
+	addrMap := make(map[string]int, len(addrQ))
+	for i, entry := range addrQ {
+		addrMap[entry.Value.String()] = i
+	}
+
 	var buf, buf2 bytes.Buffer
 	if err := gob.NewEncoder(&buf).Encode(addrMap); err != nil {
 		return err
@@ -336,29 +762,40 @@ func (this *DB) putData(
 		return err
 	}
 
-	return nil
+	return bucket.Put(boltChecksumKey, dataChecksum(buf2.Bytes(), buf.Bytes()))
 }
 
+// getData reads the queue of leases and the map from address to queue
+// index. If the data is corrupt, the error is a corruptError.
 func (this *DB) getData(bucket *bolt.Bucket) (map[string]int, ipQueue, error) {
-	var addrQ ipQueue
 	heapRaw := bucket.Get(boltAddrHeapKey)
+	mapRaw := bucket.Get(boltAddrMapKey)
+	if sum := bucket.Get(boltChecksumKey); sum != nil {
+		if !bytes.Equal(sum, dataChecksum(heapRaw, mapRaw)) {
+			return nil, nil, &corruptError{Err: fmt.Errorf("checksum mismatch")}
+		}
+	}
+
+	var addrQ ipQueue
 	if heapRaw == nil {
 		addrQ = ipQueue(make([]*ipEntry, 0, 1))
 	} else {
 		dec := gob.NewDecoder(bytes.NewReader(heapRaw))
 		if err := dec.Decode(&addrQ); err != nil {
-			return nil, nil, err
+			return nil, nil, &corruptError{Err: err}
+		}
+		for i, entry := range addrQ {
+			entry.Index = i
 		}
 	}
 
 	var addrMap map[string]int
-	mapRaw := bucket.Get(boltAddrMapKey)
 	if mapRaw == nil {
 		addrMap = make(map[string]int)
 	} else {
 		dec := gob.NewDecoder(bytes.NewReader(mapRaw))
 		if err := dec.Decode(&addrMap); err != nil {
-			return nil, nil, err
+			return nil, nil, &corruptError{Err: err}
 		}
 	}
 
@@ -24,16 +24,31 @@ type CachedDB struct {
 
 	// CachePath is the path to a file that will store the cached IP.
 	CachePath string
+
+	// Owner is the owner that is recorded with the lease when it is
+	// allocated or renewed. This is optional, but without it a cached IP
+	// that was leased to another project can't be detected.
+	Owner *Owner
+
+	// OnReallocate, if set, is called when the cached IP couldn't be used
+	// and a new IP was allocated. Whatever uses the old IP, such as a dev
+	// environment, needs to be updated to use the new one.
+	OnReallocate func(old, new net.IP)
 }
 
 // IP retrieves the IP address.
 //
 // If it is cached, it will renew and use that address. If it isn't cached,
 // then it will grab a new IP, cache that, and use that.
+//
+// The cached IP is only used if it is still in the subnet of the DB and
+// isn't leased to another owner. Otherwise the cache is dropped and a new
+// IP is allocated, since the address may be used by another project.
 func (db *CachedDB) IP() (net.IP, error) {
 	log.Printf("[DEBUG] reading IP, cache path: %s", db.CachePath)
 
 	// Try to read the cached version
+	var old net.IP
 	_, err := os.Stat(db.CachePath)
 	if err == nil {
 		raw, err := oneline.Read(db.CachePath)
@@ -41,11 +56,27 @@ func (db *CachedDB) IP() (net.IP, error) {
 			return nil, err
 		}
 
+		// Claim the cached IP. If the subnet changed or the IP was
+		// leased to another owner, the cached IP is no longer valid and
+		// we get a new one below. If the database was rebuilt and the IP
+		// is free, this registers the cached IP again.
 		ip := net.ParseIP(raw)
 		if ip != nil {
-			log.Printf("[DEBUG] read ip from cache: %s", ip)
-			db.DB.Renew(ip)
-			return ip, nil
+			ok, err := db.DB.Claim(ip, db.Owner)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				log.Printf("[DEBUG] read ip from cache: %s", ip)
+				return ip, nil
+			}
+
+			log.Printf("[INFO] cached ip %s can't be used, reallocating", ip)
+			old = ip
+		}
+
+		if err := os.Remove(db.CachePath); err != nil {
+			return nil, err
 		}
 	}
 
@@ -56,7 +87,7 @@ func (db *CachedDB) IP() (net.IP, error) {
 
 	// No cached version.
 	log.Printf("[DEBUG] no ip cache found, getting new IP")
-	ip, err := db.DB.Next()
+	ip, err := db.DB.NextFor(db.Owner)
 	if err != nil {
 		return nil, err
 	}
@@ -69,6 +100,10 @@ func (db *CachedDB) IP() (net.IP, error) {
 	}
 
 	log.Printf("[DEBUG] new IP cached: %s", ip)
+	if old != nil && db.OnReallocate != nil {
+		db.OnReallocate(old, ip)
+	}
+
 	return ip, nil
 }
 
@@ -2,6 +2,7 @@ package localaddr
 
 import (
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"testing"
@@ -32,3 +33,189 @@ func TestCachedDB(t *testing.T) {
 		t.Fatalf("bad: %s %s", next, ip)
 	}
 }
+
+func TestCachedDB_subnetChange(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	db := &CachedDB{
+		DB:        &DB{Path: filepath.Join(td, "addr.db")},
+		CachePath: filepath.Join(td, "cache"),
+	}
+	if _, err := db.IP(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	subnet, err := ParseSubnet("192.168.100.0/24")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	db.DB = &DB{Path: db.DB.Path, Subnet: subnet}
+	ip, err := db.IP()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !subnet.Contains(ip) {
+		t.Fatalf("not in subnet: %s", ip)
+	}
+
+	// The cache should have the new address
+	next, err := db.IP()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if ip.String() != next.String() {
+		t.Fatalf("bad: %s %s", next, ip)
+	}
+}
+
+func TestCachedDB_subnetShrunk(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	subnet, err := ParseSubnet("10.200.0.0/16")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	db := &CachedDB{
+		DB:        &DB{Path: filepath.Join(td, "addr.db"), Subnet: subnet},
+		CachePath: filepath.Join(td, "cache"),
+		Owner:     &Owner{AppID: "foo"},
+	}
+	var reallocated []net.IP
+	db.OnReallocate = func(old, new net.IP) {
+		reallocated = append(reallocated, old, new)
+	}
+
+	// Cache an address that is outside of the smaller subnet
+	small, err := ParseSubnet("10.200.0.0/24")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	ip := net.ParseIP("10.200.5.10")
+	if ok, err := db.DB.Claim(ip, db.Owner); err != nil || !ok {
+		t.Fatalf("bad: %v %s", ok, err)
+	}
+	testCacheIP(t, db.CachePath, ip)
+
+	db.DB = &DB{Path: db.DB.Path, Subnet: small}
+	next, err := db.IP()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !small.Contains(next) {
+		t.Fatalf("not in subnet: %s", next)
+	}
+	if len(reallocated) != 2 || !reallocated[0].Equal(ip) || !reallocated[1].Equal(next) {
+		t.Fatalf("bad: %#v", reallocated)
+	}
+
+	// The old lease is gone with the subnet
+	summary, err := db.DB.Summary()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if summary.Leases != 1 {
+		t.Fatalf("bad: %#v", summary)
+	}
+}
+
+func TestCachedDB_leasedToOther(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	db := &CachedDB{
+		DB:        &DB{Path: filepath.Join(td, "addr.db")},
+		CachePath: filepath.Join(td, "cache"),
+		Owner:     &Owner{AppID: "foo", Path: td},
+	}
+	var reallocated int
+	db.OnReallocate = func(old, new net.IP) { reallocated++ }
+
+	ip, err := db.IP()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The DB is rebuilt and the address is leased to another project
+	if err := os.Remove(db.DB.Path); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	other := &Owner{AppID: "bar", Path: filepath.Join(td, "bar")}
+	if ok, err := db.DB.Claim(ip, other); err != nil || !ok {
+		t.Fatalf("bad: %v %s", ok, err)
+	}
+
+	next, err := db.IP()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if next.Equal(ip) {
+		t.Fatalf("should reallocate: %s", next)
+	}
+	if reallocated != 1 {
+		t.Fatalf("bad: %d", reallocated)
+	}
+
+	// The new address is cached and the other project keeps its lease
+	cached, err := db.IP()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !cached.Equal(next) || reallocated != 1 {
+		t.Fatalf("bad: %s %s", cached, next)
+	}
+	if ok, err := db.DB.Claim(ip, other); err != nil || !ok {
+		t.Fatalf("bad: %v %s", ok, err)
+	}
+}
+
+func TestCachedDB_rebuiltFree(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	db := &CachedDB{
+		DB:        &DB{Path: filepath.Join(td, "addr.db")},
+		CachePath: filepath.Join(td, "cache"),
+		Owner:     &Owner{AppID: "foo", Path: td},
+	}
+	db.OnReallocate = func(old, new net.IP) {
+		t.Fatalf("should not reallocate: %s %s", old, new)
+	}
+
+	ip, err := db.IP()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The DB is rebuilt empty, so the cached address is leased again
+	if err := os.Remove(db.DB.Path); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	next, err := db.IP()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !next.Equal(ip) {
+		t.Fatalf("bad: %s %s", next, ip)
+	}
+}
+
+// testCacheIP writes the cache file of a CachedDB with the given IP.
+func testCacheIP(t *testing.T, path string, ip net.IP) {
+	if err := ioutil.WriteFile(path, []byte(ip.String()+"\n"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
@@ -0,0 +1,223 @@
+package localaddr
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// CompactThreshold is the fraction of the database file that must be
+// reclaimable before it is worth compacting. See DB.Reclaimable.
+const CompactThreshold = 0.5
+
+// compactMinSize is the smallest amount of reclaimable space that is
+// reported by Reclaimable. A new database is mostly free pages, so
+// anything less isn't worth compacting.
+const compactMinSize = 64 * 1024
+
+// LeaseInfo is information about a single lease, returned by DB.Dump.
+type LeaseInfo struct {
+	IP    net.IP `json:"ip"`
+	Owner Owner  `json:"owner"`
+
+	// Created is when the address was leased and Age is how long ago
+	// that was. These are zero for leases made by older versions of
+	// Otto. Renewed is when the lease was last renewed.
+	Created time.Time     `json:"created,omitempty"`
+	Age     time.Duration `json:"age,omitempty"`
+	Renewed time.Time     `json:"renewed"`
+}
+
+// Dump returns all the leases in the database, sorted by address. This
+// only reads the database, so it doesn't create or upgrade it.
+func (this *DB) Dump() ([]*LeaseInfo, error) {
+	if _, err := os.Stat(this.Path); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	db, err := this.open(true)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var result []*LeaseInfo
+	err = db.View(func(tx *bolt.Tx) error {
+		_, addrQ, err := this.getData(tx.Bucket(boltLocalAddrBucket))
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		for _, entry := range addrQ {
+			info := &LeaseInfo{
+				IP:      entry.Value,
+				Owner:   Owner{AppID: entry.AppID, Path: entry.Path},
+				Created: entry.Created,
+				Renewed: entry.LeaseTime,
+			}
+			if !entry.Created.IsZero() {
+				info.Age = now.Sub(entry.Created)
+			}
+
+			result = append(result, info)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(leaseInfosByIP(result))
+	return result, nil
+}
+
+// Reclaimable returns the fraction of the database file that Compact
+// would reclaim, which is the space of the pages that bolt freed. This
+// is zero if the database doesn't exist or there is too little to
+// reclaim to be worth it.
+func (this *DB) Reclaimable() (float64, error) {
+	info, err := os.Stat(this.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	db, err := this.open(true)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	// Bolt only keeps stats of the free pages for write transactions, so
+	// count them.
+	var free int64
+	err = db.View(func(tx *bolt.Tx) error {
+		pageSize := int64(tx.DB().Info().PageSize)
+		for id := 0; ; id++ {
+			p, err := tx.Page(id)
+			if err != nil {
+				return err
+			}
+			if p == nil {
+				return nil
+			}
+			if p.Type == "free" {
+				free += pageSize
+			}
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	if free < compactMinSize || info.Size() == 0 {
+		return 0, nil
+	}
+
+	return float64(free) / float64(info.Size()), nil
+}
+
+// Compact rewrites the database file without the space left behind by
+// released leases and old data.
+//
+// The database is locked for the duration. The data is written to a new
+// file that is synced and then renamed over the database, so the
+// database is never left incomplete if this is interrupted.
+func (this *DB) Compact() error {
+	db, err := this.db()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tempPath := this.Path + ".compact"
+	if err := os.Remove(tempPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := compactBolt(db, tempPath); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	// Replace the database while we still hold the lock on it. Other
+	// processes that are waiting for the lock reopen the new file, see
+	// openBolt.
+	if err := os.Rename(tempPath, this.Path); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	return nil
+}
+
+// compactBolt copies all the buckets of src into a new database at path.
+func compactBolt(src *bolt.DB, path string) error {
+	dst, err := bolt.Open(path, 0644, nil)
+	if err != nil {
+		return err
+	}
+
+	err = src.View(func(srcTx *bolt.Tx) error {
+		return dst.Update(func(dstTx *bolt.Tx) error {
+			return srcTx.ForEach(func(name []byte, b *bolt.Bucket) error {
+				dstB, err := dstTx.CreateBucket(name)
+				if err != nil {
+					return err
+				}
+
+				return copyBoltBucket(b, dstB)
+			})
+		})
+	})
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	// Bolt syncs every commit, but make sure that the complete file is
+	// on disk before it replaces the database.
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+func copyBoltBucket(src, dst *bolt.Bucket) error {
+	return src.ForEach(func(k, v []byte) error {
+		// A nil value is a nested bucket
+		if v == nil {
+			child, err := dst.CreateBucket(k)
+			if err != nil {
+				return err
+			}
+
+			return copyBoltBucket(src.Bucket(k), child)
+		}
+
+		return dst.Put(k, v)
+	})
+}
+
+type leaseInfosByIP []*LeaseInfo
+
+func (s leaseInfosByIP) Len() int      { return len(s) }
+func (s leaseInfosByIP) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s leaseInfosByIP) Less(i, j int) bool {
+	return bytes.Compare(s[i].IP.To16(), s[j].IP.To16()) < 0
+}
@@ -0,0 +1,173 @@
+package localaddr
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestDBDump(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	// Nothing to dump before the database exists
+	db := &DB{Path: filepath.Join(td, "addr.db")}
+	leases, err := db.Dump()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(leases) != 0 {
+		t.Fatalf("bad: %#v", leases)
+	}
+	if _, err := os.Stat(db.Path); !os.IsNotExist(err) {
+		t.Fatalf("should not create the database: %s", err)
+	}
+
+	owner := &Owner{AppID: "foo", Path: td}
+	ip1, err := db.NextFor(owner)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	ip2, err := db.Next()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	leases, err = db.Dump()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(leases) != 2 {
+		t.Fatalf("bad: %#v", leases)
+	}
+	if bytes.Compare(leases[0].IP.To16(), leases[1].IP.To16()) >= 0 {
+		t.Fatalf("should be sorted: %s %s", leases[0].IP, leases[1].IP)
+	}
+	for _, l := range leases {
+		switch {
+		case l.IP.Equal(ip1):
+			if l.Owner != *owner {
+				t.Fatalf("bad: %#v", l)
+			}
+		case l.IP.Equal(ip2):
+			if l.Owner != (Owner{}) {
+				t.Fatalf("bad: %#v", l)
+			}
+		default:
+			t.Fatalf("bad: %#v", l)
+		}
+	}
+	for _, l := range leases {
+		if l.Created.IsZero() || l.Renewed.Before(l.Created) || l.Age < 0 {
+			t.Fatalf("bad: %#v", l)
+		}
+	}
+}
+
+func TestDBCompact(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	db := &DB{Path: filepath.Join(td, "addr.db")}
+	ip, err := db.Next()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// A small database isn't worth compacting
+	frac, err := db.Reclaimable()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if frac != 0 {
+		t.Fatalf("bad: %f", frac)
+	}
+
+	// Leave a lot of free space behind
+	testBoltUpdate(t, db, func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte("scratch"))
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte("data"), make([]byte, 1024*1024))
+	})
+	testBoltUpdate(t, db, func(tx *bolt.Tx) error {
+		return tx.DeleteBucket([]byte("scratch"))
+	})
+
+	frac, err = db.Reclaimable()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if frac <= CompactThreshold {
+		t.Fatalf("bad: %f", frac)
+	}
+	before, err := os.Stat(db.Path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := db.Compact(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	after, err := os.Stat(db.Path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if after.Size() >= before.Size() {
+		t.Fatalf("bad: %d >= %d", after.Size(), before.Size())
+	}
+	if _, err := os.Stat(db.Path + ".compact"); !os.IsNotExist(err) {
+		t.Fatalf("temporary file should be removed: %s", err)
+	}
+	frac, err = db.Reclaimable()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if frac != 0 {
+		t.Fatalf("bad: %f", frac)
+	}
+
+	// The lease survived and the database still works
+	if err := db.Verify(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	leases, err := db.Dump()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(leases) != 1 || !leases[0].IP.Equal(ip) {
+		t.Fatalf("bad: %#v", leases)
+	}
+	ip2, err := db.Next()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if ip2.Equal(ip) {
+		t.Fatalf("should not reuse a leased address: %s", ip2)
+	}
+}
+
+func testBoltUpdate(t *testing.T, db *DB, f func(*bolt.Tx) error) {
+	bdb, err := db.db()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer bdb.Close()
+
+	if err := bdb.Update(f); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
@@ -0,0 +1,154 @@
+package localaddr
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+var stressProcs = flag.Bool(
+	"stress-procs", false, "run the IP database stress test with subprocesses")
+
+func TestDB_lockTimeout(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	// Hold the lock as if another process was using the DB
+	path := filepath.Join(td, "addr.db")
+	other, err := bolt.Open(path, 0644, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer other.Close()
+
+	db := &DB{Path: path, LockTimeout: 100 * time.Millisecond}
+	_, err = db.Next()
+	if err == nil || !strings.Contains(err.Error(), "Another Otto") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestDB_concurrent(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "addr.db")
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]struct{})
+	errs := make([]error, 0)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			db := &DB{Path: path}
+			for j := 0; j < 10; j++ {
+				ip, err := db.Next()
+
+				lock.Lock()
+				if err != nil {
+					errs = append(errs, err)
+				} else if _, ok := results[ip.String()]; ok {
+					errs = append(errs, fmt.Errorf("collision: %s", ip))
+				} else {
+					results[ip.String()] = struct{}{}
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		t.Fatalf("err: %s", errs[0])
+	}
+	if len(results) != 100 {
+		t.Fatalf("bad: %d", len(results))
+	}
+}
+
+func TestDB_concurrentProcs(t *testing.T) {
+	if !*stressProcs {
+		t.Skip("set -stress-procs to run")
+	}
+
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "addr.db")
+	cmds := make([]*exec.Cmd, 5)
+	outs := make([]*bytes.Buffer, len(cmds))
+	for i := range cmds {
+		outs[i] = new(bytes.Buffer)
+		cmds[i] = exec.Command(os.Args[0], "-test.run=TestHelperProcess")
+		cmds[i].Env = append(
+			[]string{"GO_WANT_HELPER_PROCESS=1", "LOCALADDR_DB=" + path},
+			os.Environ()...)
+		cmds[i].Stdout = outs[i]
+		if err := cmds[i].Start(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	results := make(map[string]struct{})
+	for i, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		scanner := bufio.NewScanner(outs[i])
+		for scanner.Scan() {
+			ip := scanner.Text()
+			if _, ok := results[ip]; ok {
+				t.Fatalf("collision: %s", ip)
+			}
+
+			results[ip] = struct{}{}
+		}
+	}
+	if len(results) != 50 {
+		t.Fatalf("bad: %d", len(results))
+	}
+}
+
+// This is not a real test. This is just a helper process kicked off by
+// tests that allocates addresses and outputs them.
+func TestHelperProcess(*testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	defer os.Exit(0)
+
+	db := &DB{Path: os.Getenv("LOCALADDR_DB")}
+	for i := 0; i < 10; i++ {
+		ip, err := db.Next()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "err: %s\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println(ip.String())
+	}
+}
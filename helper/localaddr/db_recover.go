@@ -0,0 +1,129 @@
+package localaddr
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"log"
+	"os"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// corruptError is returned when the data in the database is corrupt.
+type corruptError struct {
+	Err error
+}
+
+func (e *corruptError) Error() string {
+	return fmt.Sprintf("IP database is corrupt: %s", e.Err)
+}
+
+// isCorrupt returns true if err means that the database is corrupt, as
+// opposed to not being accessible.
+func isCorrupt(err error) bool {
+	if _, ok := err.(*corruptError); ok {
+		return true
+	}
+
+	switch err {
+	case bolt.ErrInvalid, bolt.ErrChecksum, bolt.ErrVersionMismatch:
+		return true
+	default:
+		return false
+	}
+}
+
+// dataChecksum returns the checksum stored for the encoded address heap
+// and map.
+func dataChecksum(heap, addrMap []byte) []byte {
+	h := crc32.NewIEEE()
+	h.Write(heap)
+	h.Write(addrMap)
+
+	result := make([]byte, 4)
+	binary.BigEndian.PutUint32(result, h.Sum32())
+	return result
+}
+
+// recover moves the corrupt database aside so that a new one is created
+// the next time it is opened.
+//
+// The database isn't locked while it is corrupt, so processes that find
+// it corrupt at the same time take turns with a lock on a separate file,
+// and it is only moved aside if it is still corrupt. Otherwise a process
+// could move aside the new database that another one just created.
+func (this *DB) recover() error {
+	guard, err := openBolt(
+		this.Path+".recover", "IP recovery", this.LockTimeout, false)
+	if err != nil {
+		return err
+	}
+	defer guard.Close()
+
+	if err := this.Verify(); !isCorrupt(err) {
+		return err
+	}
+
+	corruptPath := fmt.Sprintf(
+		"%s.corrupt-%s", this.Path, time.Now().UTC().Format("20060102150405"))
+	if err := os.Rename(this.Path, corruptPath); err != nil {
+		return fmt.Errorf(
+			"Error moving aside the corrupt IP database: %s", err)
+	}
+
+	log.Printf("[WARN] moved corrupt IP database to: %s", corruptPath)
+	if this.OnRecover != nil {
+		this.OnRecover(corruptPath)
+	}
+
+	return nil
+}
+
+// Verify checks the health of the database and returns an error
+// describing the problem if it is corrupt or inconsistent. This only
+// reads the database, so it doesn't recover it. A database that doesn't
+// exist is healthy.
+func (this *DB) Verify() error {
+	if _, err := os.Stat(this.Path); os.IsNotExist(err) {
+		return nil
+	}
+
+	db, err := this.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.View(func(tx *bolt.Tx) error {
+		for err := range tx.Check() {
+			return &corruptError{Err: err}
+		}
+
+		bucket := tx.Bucket(boltLocalAddrBucket)
+		if bucket == nil {
+			return &corruptError{Err: fmt.Errorf("missing bucket")}
+		}
+		if bucket.Get(boltSubnetKey) == nil {
+			return &corruptError{Err: fmt.Errorf("missing subnet")}
+		}
+
+		addrMap, addrQ, err := this.getData(bucket)
+		if err != nil {
+			return err
+		}
+		if len(addrMap) != len(addrQ) {
+			return &corruptError{Err: fmt.Errorf(
+				"%d addresses but %d leases", len(addrMap), len(addrQ))}
+		}
+		for i, entry := range addrQ {
+			if idx, ok := addrMap[entry.Value.String()]; !ok || idx != i {
+				return &corruptError{Err: fmt.Errorf(
+					"lease %s isn't indexed", entry.Value)}
+			}
+		}
+
+		return nil
+	})
+}
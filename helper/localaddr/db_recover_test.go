@@ -0,0 +1,160 @@
+package localaddr
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestDB_recoverCorrupt(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "addr.db")
+	if err := ioutil.WriteFile(path, []byte("garbage"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var recovered string
+	db := &DB{
+		Path:      path,
+		OnRecover: func(p string) { recovered = p },
+	}
+	if err := db.Verify(); err == nil {
+		t.Fatal("should be unhealthy")
+	}
+	if _, err := db.Next(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if recovered == "" {
+		t.Fatal("should recover")
+	}
+	if _, err := os.Stat(recovered); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := db.Verify(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestDB_recoverChecksum(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "addr.db")
+	db := &DB{Path: path}
+	if _, err := db.Next(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Corrupt the leases
+	boltDB, err := bolt.Open(path, 0644, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	err = boltDB.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltLocalAddrBucket).Put(boltAddrHeapKey, []byte("bad"))
+	})
+	boltDB.Close()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := db.Verify(); err == nil {
+		t.Fatal("should be unhealthy")
+	}
+
+	// Recovering loses the leases
+	summary, err := db.Summary()
+	if err == nil {
+		t.Fatalf("summary shouldn't recover: %#v", summary)
+	}
+	if _, err := db.Next(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	summary, err = db.Summary()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if summary.Leases != 1 {
+		t.Fatalf("bad: %#v", summary)
+	}
+}
+
+func TestCachedDB_recover(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	db := &CachedDB{
+		DB:        &DB{Path: filepath.Join(td, "addr.db")},
+		CachePath: filepath.Join(td, "cache"),
+	}
+	ip, err := db.IP()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Corrupt the DB
+	if err := ioutil.WriteFile(db.DB.Path, []byte("garbage"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The cached address should be kept and registered again
+	next, err := db.IP()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !ip.Equal(next) {
+		t.Fatalf("bad: %s %s", next, ip)
+	}
+
+	summary, err := db.DB.Summary()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if summary.Leases != 1 {
+		t.Fatalf("bad: %#v", summary)
+	}
+}
+
+func TestDB_recoverRecovered(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "addr.db")
+	recovered := false
+	db := &DB{
+		Path:      path,
+		OnRecover: func(string) { recovered = true },
+	}
+	if _, err := db.Next(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Another process found the database corrupt at the same time and
+	// already replaced it, so it is left alone.
+	if err := db.recover(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if recovered {
+		t.Fatal("should not recover")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
@@ -1,12 +1,19 @@
 package localaddr
 
 import (
+	"bytes"
+	"encoding/gob"
 	"io"
 	"io/ioutil"
 	"net"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
 )
 
 func TestDB_upgrade_v1(t *testing.T) {
@@ -59,6 +66,29 @@ func TestDB(t *testing.T) {
 	}
 }
 
+func TestDBSummary(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	db := &DB{Path: filepath.Join(td, "addr.db")}
+	for i := 0; i < 3; i++ {
+		if _, err := db.Next(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	summary, err := db.Summary()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if summary.Leases != 3 || summary.Subnet == "" || summary.OldestLease.IsZero() {
+		t.Fatalf("bad: %#v", summary)
+	}
+}
+
 func testCopyV1(t *testing.T) string {
 	td, err := ioutil.TempDir("", "localaddr")
 	if err != nil {
@@ -88,3 +118,277 @@ func testCopyV1(t *testing.T) string {
 
 	return path
 }
+
+func TestDB_subnet(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "addr.db")
+	db := &DB{Path: path}
+	old, err := db.Next()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Change the subnet
+	subnet, err := ParseSubnet("10.42.0.0/16")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	db = &DB{Path: path, Subnet: subnet}
+	for i := 0; i < 50; i++ {
+		ip, err := db.Next()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !subnet.Contains(ip) {
+			t.Fatalf("not in subnet: %s", ip)
+		}
+	}
+
+	// The old lease should be gone
+	summary, err := db.Summary()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if summary.Subnet != "10.42.0.0/16" {
+		t.Fatalf("bad: %#v", summary)
+	}
+	if summary.Leases != 50 {
+		t.Fatalf("old lease %s not released: %#v", old, summary)
+	}
+}
+
+func TestDB_full(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	// A /30 only has two usable addresses
+	_, subnet, err := net.ParseCIDR("10.42.0.0/30")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	db := &DB{Path: filepath.Join(td, "addr.db"), Subnet: subnet}
+
+	var leased []string
+	for i := 0; i < 2; i++ {
+		ip, err := db.Next()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		leased = append(leased, ip.String())
+	}
+	sort.Strings(leased)
+	if !reflect.DeepEqual(leased, []string{"10.42.0.1", "10.42.0.2"}) {
+		t.Fatalf("bad: %#v", leased)
+	}
+
+	// Once it's full, the oldest lease is reused
+	if err := db.Renew(net.ParseIP(leased[0])); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	ip, err := db.Next()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if ip.String() != leased[1] {
+		t.Fatalf("bad: %s", ip)
+	}
+
+	summary, err := db.Summary()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if summary.Leases != 2 {
+		t.Fatalf("bad: %#v", summary)
+	}
+}
+
+func TestDB_GC(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	db := &DB{Path: filepath.Join(td, "addr.db")}
+	existing, err := db.NextFor(&Owner{AppID: "foo", Path: td})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	gone, err := db.NextFor(&Owner{AppID: "bar", Path: filepath.Join(td, "gone")})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	unowned, err := db.Next()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Nothing is stale yet
+	released, err := db.GC(time.Hour, false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(released) != 0 {
+		t.Fatalf("bad: %#v", released)
+	}
+
+	// Everything is stale, but the existing project is kept
+	released, err = db.GC(0, false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	actual := make(map[string]bool)
+	for _, ip := range released {
+		actual[ip.String()] = true
+	}
+	expected := map[string]bool{gone.String(): true, unowned.String(): true}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Force releases the existing project
+	released, err = db.GC(0, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(released) != 1 || !released[0].Equal(existing) {
+		t.Fatalf("bad: %#v", released)
+	}
+}
+
+func TestDB_RenewFor(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	db := &DB{Path: filepath.Join(td, "addr.db")}
+	ip, err := db.Next()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Claiming the lease for an existing project keeps it from GC
+	if err := db.RenewFor(ip, &Owner{AppID: "foo", Path: td}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	released, err := db.GC(0, false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(released) != 0 {
+		t.Fatalf("bad: %#v", released)
+	}
+}
+
+func TestDB_upgrade_v2(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+	path := filepath.Join(td, "addr.db")
+
+	// Write a v2 DB with a lease that was allocated long ago. The entry
+	// type matches the v2 encoding, which had no owners.
+	type v2Entry struct {
+		LeaseTime time.Time
+		Value     net.IP
+		Index     int
+	}
+	var queue, addrMap bytes.Buffer
+	err = gob.NewEncoder(&queue).Encode([]*v2Entry{{
+		LeaseTime: time.Now().UTC().Add(-365 * 24 * time.Hour),
+		Value:     net.ParseIP("100.64.1.2").To4(),
+	}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	err = gob.NewEncoder(&addrMap).Encode(map[string]int{"100.64.1.2": 0})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	boltDB, err := bolt.Open(path, 0644, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	err = boltDB.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket(boltLocalAddrBucket)
+		if err != nil {
+			return err
+		}
+
+		for k, v := range map[string][]byte{
+			"version":   {2},
+			"subnet":    []byte("100.64.0.0/10"),
+			"addr_heap": queue.Bytes(),
+			"addr_map":  addrMap.Bytes(),
+		} {
+			if err := bucket.Put([]byte(k), v); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	boltDB.Close()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The lease should get a full window after the upgrade
+	db := &DB{Path: path}
+	released, err := db.GC(time.Hour, false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(released) != 0 {
+		t.Fatalf("bad: %#v", released)
+	}
+
+	summary, err := db.Summary()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if summary.Leases != 1 {
+		t.Fatalf("bad: %#v", summary)
+	}
+}
+
+func TestDB_ReleaseOrphans(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	db := &DB{Path: filepath.Join(td, "addr.db")}
+	if _, err := db.NextFor(&Owner{AppID: "foo", Path: td}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	gone, err := db.NextFor(&Owner{AppID: "bar", Path: filepath.Join(td, "gone")})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := db.Next(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	released, err := db.ReleaseOrphans()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(released) != 1 || !released[0].Equal(gone) {
+		t.Fatalf("bad: %#v", released)
+	}
+}
@@ -11,10 +11,44 @@ type ipEntry struct {
 	LeaseTime time.Time
 	Value     net.IP
 
+	// Created is when the address was leased. This is zero for leases
+	// made by older versions of Otto.
+	Created time.Time
+
+	// AppID and Path are the owner of the lease. These are empty for
+	// leases without an owner.
+	AppID string
+	Path  string
+
 	// This is maintained by heap.Push and heap.Pop
 	Index int
 }
 
+// setOwner sets the owner of the lease if owner is non-nil.
+func (e *ipEntry) setOwner(owner *Owner) {
+	if owner != nil {
+		e.AppID = owner.AppID
+		e.Path = owner.Path
+	}
+}
+
+// ownedBy returns true if the lease may belong to owner. Leases without
+// a recorded owner, and any lease if owner is nil, can't be told apart so
+// they're assumed to belong to owner.
+func (e *ipEntry) ownedBy(owner *Owner) bool {
+	if owner == nil {
+		return true
+	}
+	if e.AppID != "" && owner.AppID != "" && e.AppID != owner.AppID {
+		return false
+	}
+	if e.Path != "" && owner.Path != "" && e.Path != owner.Path {
+		return false
+	}
+
+	return true
+}
+
 // ipQueue is an implementation of heap.Interface and holds ipEntrys.
 type ipQueue []*ipEntry
 
@@ -45,6 +79,16 @@ func (q *ipQueue) Pop() interface{} {
 	return item
 }
 
+// init sets the indexes of all the entries and establishes the heap
+// invariants. This must be called after entries are added or removed
+// directly.
+func (q *ipQueue) init() {
+	for i, entry := range *q {
+		entry.Index = i
+	}
+	heap.Init(q)
+}
+
 // Update updates the given entry. This entry must already be in the queue.
 func (q *ipQueue) Update(item *ipEntry) {
 	heap.Fix(q, item.Index)
@@ -0,0 +1,324 @@
+package localaddr
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// DefaultPortMin and DefaultPortMax are the default range of ports that
+// are allocated by a PortDB.
+const (
+	DefaultPortMin = 20000
+	DefaultPortMax = 29999
+)
+
+var boltPortsBucket = []byte("ports")
+
+// portAvailable returns true if the port isn't in use on this machine.
+// This is a variable so tests can replace it.
+var portAvailable = func(port int) bool {
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+
+	ln.Close()
+	return true
+}
+
+// PortDB is a database of host ports allocated to applications, such as
+// for forwarded ports and debugger ports of dev environments. Ports are
+// allocated per application and are stable: the same application gets
+// the same ports every time.
+//
+// Like DB, the database file is locked for every operation and leases
+// that aren't renewed are garbage collected with GC.
+type PortDB struct {
+	// Path is the path to the port database. This file doesn't need to
+	// exist but needs to be a writable path. The parent directory will
+	// be made.
+	Path string
+
+	// LockTimeout is the time to wait for another process that is using
+	// the database before giving up. This defaults to DefaultLockTimeout.
+	LockTimeout time.Duration
+}
+
+// PortOpts are the options for allocating ports.
+type PortOpts struct {
+	// Min and Max are the range of ports to allocate from, inclusive.
+	// These default to DefaultPortMin and DefaultPortMax.
+	Min, Max int
+
+	// Path is the path to the project directory that uses the ports. The
+	// ports are never garbage collected while this path exists unless
+	// it is forced.
+	Path string
+}
+
+// portLease is the lease of ports for a single application.
+type portLease struct {
+	Ports     []int
+	LeaseTime time.Time
+	Path      string
+}
+
+// AllocatePorts returns n ports for the application with the given ID,
+// renewing the lease of the ports. The ports the application was given
+// before are reused, and new ports are only allocated if it needs more.
+// New ports are never in use by other applications or on this machine.
+func (this *PortDB) AllocatePorts(appID string, n int, opts *PortOpts) ([]int, error) {
+	if opts == nil {
+		opts = new(PortOpts)
+	}
+	min, max := opts.Min, opts.Max
+	if min <= 0 {
+		min = DefaultPortMin
+	}
+	if max <= 0 {
+		max = DefaultPortMax
+	}
+	if min > max || max > 65535 {
+		return nil, fmt.Errorf("invalid port range: %d-%d", min, max)
+	}
+
+	db, err := this.db()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var result []int
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltPortsBucket)
+		leases, err := this.getLeases(bucket)
+		if err != nil {
+			return err
+		}
+
+		// Find all the ports used by other applications
+		used := make(map[int]struct{})
+		for id, l := range leases {
+			if id == appID {
+				continue
+			}
+
+			for _, p := range l.Ports {
+				used[p] = struct{}{}
+			}
+		}
+
+		// Keep the ports we had that are in range
+		lease := &portLease{}
+		if old, ok := leases[appID]; ok {
+			for _, p := range old.Ports {
+				if p >= min && p <= max {
+					lease.Ports = append(lease.Ports, p)
+					used[p] = struct{}{}
+				}
+			}
+		}
+
+		// Allocate any more ports we need
+		for p := min; len(lease.Ports) < n && p <= max; p++ {
+			if _, ok := used[p]; ok {
+				continue
+			}
+			if !portAvailable(p) {
+				log.Printf("[DEBUG] port in use on this machine: %d", p)
+				continue
+			}
+
+			lease.Ports = append(lease.Ports, p)
+		}
+		if len(lease.Ports) < n {
+			return fmt.Errorf(
+				"no free ports left in the range %d-%d", min, max)
+		}
+
+		lease.LeaseTime = time.Now().UTC()
+		lease.Path = opts.Path
+		result = lease.Ports[:n]
+		return this.putLease(bucket, appID, lease)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Ports returns the ports allocated to the application with the given
+// ID, or nil if it has none. This doesn't renew the lease.
+func (this *PortDB) Ports(appID string) ([]int, error) {
+	if _, err := os.Stat(this.Path); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	db, err := openBolt(this.Path, "port", this.LockTimeout, true)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var result []int
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltPortsBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		leases, err := this.getLeases(bucket)
+		if err != nil {
+			return err
+		}
+		if l, ok := leases[appID]; ok {
+			result = l.Ports
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// Release releases the ports of the application with the given ID.
+func (this *PortDB) Release(appID string) error {
+	_, err := this.releaseIf(func(id string, l *portLease) bool {
+		return id == appID
+	})
+
+	return err
+}
+
+// GC releases the ports of all the applications whose leases haven't
+// been renewed within olderThan and returns the IDs of the applications.
+// Leases whose project path still exists are kept unless force is true.
+func (this *PortDB) GC(olderThan time.Duration, force bool) ([]string, error) {
+	cutoff := time.Now().UTC().Add(-olderThan)
+	return this.releaseIf(func(id string, l *portLease) bool {
+		if !l.LeaseTime.Before(cutoff) {
+			return false
+		}
+
+		if !force && l.Path != "" {
+			if _, err := os.Stat(l.Path); err == nil {
+				return false
+			}
+		}
+
+		log.Printf("[INFO] releasing stale ports for %s: %v", id, l.Ports)
+		return true
+	})
+}
+
+// ReleaseOrphans releases the ports of all the applications whose
+// project path no longer exists and returns the IDs of the applications.
+func (this *PortDB) ReleaseOrphans() ([]string, error) {
+	return this.releaseIf(func(id string, l *portLease) bool {
+		if l.Path == "" {
+			return false
+		}
+		if _, err := os.Stat(l.Path); !os.IsNotExist(err) {
+			return false
+		}
+
+		log.Printf("[INFO] releasing ports for %s, project is gone: %s", id, l.Path)
+		return true
+	})
+}
+
+// releaseIf deletes all the leases for which f returns true and returns
+// the IDs of the applications.
+func (this *PortDB) releaseIf(f func(string, *portLease) bool) ([]string, error) {
+	db, err := this.db()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var result []string
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltPortsBucket)
+		leases, err := this.getLeases(bucket)
+		if err != nil {
+			return err
+		}
+
+		for id, l := range leases {
+			if !f(id, l) {
+				continue
+			}
+
+			if err := bucket.Delete([]byte(id)); err != nil {
+				return err
+			}
+
+			result = append(result, id)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// db returns the database handle, and sets up the DB if it has never
+// been created.
+func (this *PortDB) db() (*bolt.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(this.Path), 0755); err != nil {
+		return nil, err
+	}
+
+	db, err := openBolt(this.Path, "port", this.LockTimeout, false)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltPortsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func (this *PortDB) getLeases(bucket *bolt.Bucket) (map[string]*portLease, error) {
+	result := make(map[string]*portLease)
+	err := bucket.ForEach(func(k, v []byte) error {
+		var l portLease
+		if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&l); err != nil {
+			return fmt.Errorf("Error reading ports for %s: %s", k, err)
+		}
+
+		result[string(k)] = &l
+		return nil
+	})
+
+	return result, err
+}
+
+func (this *PortDB) putLease(bucket *bolt.Bucket, appID string, l *portLease) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(l); err != nil {
+		return err
+	}
+
+	return bucket.Put([]byte(appID), buf.Bytes())
+}
@@ -0,0 +1,127 @@
+package localaddr
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPortDB(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	db := &PortDB{Path: filepath.Join(td, "ports.db")}
+	opts := &PortOpts{Min: 40000, Max: 40010}
+	foo, err := db.AllocatePorts("foo", 2, opts)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(foo) != 2 {
+		t.Fatalf("bad: %#v", foo)
+	}
+
+	// Another app gets different ports
+	bar, err := db.AllocatePorts("bar", 2, opts)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, p := range bar {
+		if p == foo[0] || p == foo[1] {
+			t.Fatalf("conflict: %#v %#v", foo, bar)
+		}
+	}
+
+	// The same app gets the same ports, plus more if it asks
+	again, err := db.AllocatePorts("foo", 3, opts)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(again[:2], foo) {
+		t.Fatalf("bad: %#v %#v", again, foo)
+	}
+
+	actual, err := db.Ports("foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, again) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Releasing frees them
+	if err := db.Release("foo"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	actual, err = db.Ports("foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual != nil {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestPortDB_full(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	db := &PortDB{Path: filepath.Join(td, "ports.db")}
+	opts := &PortOpts{Min: 40000, Max: 40001}
+	if _, err := db.AllocatePorts("foo", 2, opts); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := db.AllocatePorts("bar", 1, opts); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestPortDB_GC(t *testing.T) {
+	td, err := ioutil.TempDir("", "localaddr")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	db := &PortDB{Path: filepath.Join(td, "ports.db")}
+	_, err = db.AllocatePorts("foo", 1, &PortOpts{Path: td})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	_, err = db.AllocatePorts("bar", 1, &PortOpts{Path: filepath.Join(td, "gone")})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	released, err := db.ReleaseOrphans()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(released, []string{"bar"}) {
+		t.Fatalf("bad: %#v", released)
+	}
+
+	// The existing project is only released if forced
+	released, err = db.GC(time.Duration(0), false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(released) != 0 {
+		t.Fatalf("bad: %#v", released)
+	}
+	released, err = db.GC(time.Duration(0), true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(released, []string{"foo"}) {
+		t.Fatalf("bad: %#v", released)
+	}
+}
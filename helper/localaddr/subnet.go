@@ -25,17 +25,66 @@ func init() {
 	}
 }
 
-// UsableSubnet returns a /24 CIDR block of usable network addresses in the
-// RFC private address space that also isn't in use by any network interface
-// on this machine currently.
-func UsableSubnet() (*net.IPNet, error) {
+// minSubnetOnes is the largest prefix length allowed for a subnet for
+// allocating addresses, so that there is enough space for dev
+// environments. A /24 has 256 addresses.
+const minSubnetOnes = 24
+
+// sharedIPNet is the shared address space (RFC 6598) that is used by
+// default for allocating addresses.
+var sharedIPNet = &net.IPNet{
+	IP:   net.IPv4(100, 64, 0, 0).To4(),
+	Mask: net.CIDRMask(10, 32),
+}
+
+// ParseSubnet parses a CIDR and validates that it can be used as the
+// Subnet of a DB: it must be an IPv4 subnet within the private address
+// space (RFC 1918) or the shared address space (RFC 6598), and it must
+// be at least a /24.
+func ParseSubnet(v string) (*net.IPNet, error) {
+	_, ipnet, err := net.ParseCIDR(v)
+	if err != nil {
+		return nil, err
+	}
+
+	ones, bits := ipnet.Mask.Size()
+	if bits != 32 {
+		return nil, fmt.Errorf("subnet must be IPv4: %s", v)
+	}
+	if ones > minSubnetOnes {
+		return nil, fmt.Errorf(
+			"subnet must be at least a /%d to have enough addresses: %s",
+			minSubnetOnes, v)
+	}
+
+	// The whole subnet must be within one of the allowed spaces, which
+	// is true if the first address is and the mask is at least as long.
+	for _, allowed := range append([]*net.IPNet{sharedIPNet}, privateIPNets...) {
+		allowedOnes, _ := allowed.Mask.Size()
+		if allowed.Contains(ipnet.IP) && ones >= allowedOnes {
+			return ipnet, nil
+		}
+	}
+
+	return nil, fmt.Errorf(
+		"subnet must be within a private address space "+
+			"(10.0.0.0/8, 172.16.0.0/12, 192.168.0.0/16, or 100.64.0.0/10): %s", v)
+}
+
+// InterfaceLister is a function that returns the IPv4 networks of the
+// network interfaces on this machine. InterfaceNetworks is the default
+// implementation; tests can replace it.
+type InterfaceLister func() ([]*net.IPNet, error)
+
+// InterfaceNetworks returns the IPv4 networks of all the network
+// interfaces on this machine.
+func InterfaceNetworks() ([]*net.IPNet, error) {
 	interfaces, err := net.Interfaces()
 	if err != nil {
 		return nil, err
 	}
 
-	// First find all the taken private IP spaces
-	taken := make([]*net.IPNet, 0, 5)
+	result := make([]*net.IPNet, 0, 5)
 	for _, i := range interfaces {
 		addrs, err := i.Addrs()
 		if err != nil {
@@ -60,22 +109,67 @@ func UsableSubnet() (*net.IPNet, error) {
 				continue
 			}
 
-			// If the addr isn't even in the private IP space, then ignore it
-			private := false
-			for _, ipnet := range privateIPNets {
-				if ipnet.Contains(ip) {
-					private = true
-					break
-				}
-			}
-			if !private {
-				log.Printf("[DEBUG] ignoring non-private IP space: %s", addr)
-				continue
-			}
+			result = append(result, ipnet)
+		}
+	}
+
+	return result, nil
+}
+
+// Conflicts returns the networks in networks that overlap subnet and so
+// would make addresses allocated from subnet unreachable or break routing.
+//
+// Networks that are strictly within subnet are ignored, since these are
+// the host-only networks created for dev environments using addresses
+// from the subnet.
+func Conflicts(subnet *net.IPNet, networks []*net.IPNet) []*net.IPNet {
+	ones, _ := subnet.Mask.Size()
+
+	var result []*net.IPNet
+	for _, n := range networks {
+		if !n.Contains(subnet.IP) && !subnet.Contains(n.IP) {
+			continue
+		}
+
+		nOnes, _ := n.Mask.Size()
+		if nOnes > ones && subnet.Contains(n.IP) {
+			log.Printf("[DEBUG] ignoring network within subnet: %s", n)
+			continue
+		}
+
+		result = append(result, n)
+	}
+
+	return result
+}
+
+// UsableSubnet returns a /24 CIDR block of usable network addresses in the
+// RFC private address space that also isn't in use by any network interface
+// on this machine currently.
+func UsableSubnet() (*net.IPNet, error) {
+	networks, err := InterfaceNetworks()
+	if err != nil {
+		return nil, err
+	}
 
-			log.Printf("[DEBUG] occupied private IP space: %s", addr)
-			taken = append(taken, ipnet)
+	// First find all the taken private IP spaces
+	taken := make([]*net.IPNet, 0, 5)
+	for _, ipnet := range networks {
+		// If the addr isn't even in the private IP space, then ignore it
+		private := false
+		for _, privateNet := range privateIPNets {
+			if privateNet.Contains(ipnet.IP) {
+				private = true
+				break
+			}
+		}
+		if !private {
+			log.Printf("[DEBUG] ignoring non-private IP space: %s", ipnet)
+			continue
 		}
+
+		log.Printf("[DEBUG] occupied private IP space: %s", ipnet)
+		taken = append(taken, ipnet)
 	}
 
 	// Now go through and find a space that we can use
@@ -0,0 +1,100 @@
+package localaddr
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseSubnet(t *testing.T) {
+	cases := []struct {
+		Input string
+		Err   bool
+	}{
+		{"100.64.0.0/10", false},
+		{"10.0.0.0/8", false},
+		{"10.200.0.0/16", false},
+		{"172.16.0.0/12", false},
+		{"192.168.50.0/24", false},
+
+		// Not a CIDR
+		{"10.0.0.1", true},
+
+		// Too small
+		{"192.168.50.0/25", true},
+
+		// Not private
+		{"8.8.0.0/16", true},
+		{"172.0.0.0/8", true},
+
+		// IPv6
+		{"fd00::/64", true},
+	}
+
+	for _, tc := range cases {
+		_, err := ParseSubnet(tc.Input)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s: %s", tc.Input, err)
+		}
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	parse := func(v string) *net.IPNet {
+		_, ipnet, err := net.ParseCIDR(v)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		return ipnet
+	}
+
+	cases := []struct {
+		Subnet   string
+		Networks []string
+		Expected []string
+	}{
+		// No overlap
+		{
+			"100.64.0.0/10",
+			[]string{"127.0.0.0/8", "192.168.1.0/24"},
+			nil,
+		},
+
+		// Network within the subnet is a dev environment network
+		{
+			"100.64.0.0/10",
+			[]string{"100.64.12.0/24"},
+			nil,
+		},
+
+		// Network contains the subnet
+		{
+			"10.42.0.0/16",
+			[]string{"10.0.0.0/8"},
+			[]string{"10.0.0.0/8"},
+		},
+
+		// Same network
+		{
+			"192.168.50.0/24",
+			[]string{"192.168.50.0/24"},
+			[]string{"192.168.50.0/24"},
+		},
+	}
+
+	for _, tc := range cases {
+		networks := make([]*net.IPNet, len(tc.Networks))
+		for i, n := range tc.Networks {
+			networks[i] = parse(n)
+		}
+
+		var actual []string
+		for _, n := range Conflicts(parse(tc.Subnet), networks) {
+			actual = append(actual, n.String())
+		}
+		if !reflect.DeepEqual(actual, tc.Expected) {
+			t.Fatalf("%s: bad: %#v", tc.Subnet, actual)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package logger contains the structured logger used by Otto.
+//
+// Otto's logs are meant for debugging Otto itself. Anything meant for
+// the user should go to the Ui instead.
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+)
+
+// Logger is a structured logger. The kv arguments to each method are
+// alternating key and value pairs that are attached to the message,
+// such as ("app", "foo", "tuple", tuple).
+type Logger interface {
+	Debug(msg string, kv ...interface{})
+	Info(msg string, kv ...interface{})
+	Warn(msg string, kv ...interface{})
+	Error(msg string, kv ...interface{})
+
+	// With returns a Logger that attaches the given key and value pairs
+	// to every message in addition to any it already attaches.
+	With(kv ...interface{}) Logger
+}
+
+// Default returns a Logger that writes to the standard library logger
+// using the "[LEVEL] message" format that the rest of Otto uses.
+func Default() Logger {
+	return new(stdLogger)
+}
+
+// New returns a Logger that writes to w using the same format as
+// Default, with the date and time of every message.
+func New(w io.Writer) Logger {
+	return &stdLogger{out: log.New(w, "", log.LstdFlags)}
+}
+
+// stdLogger is a Logger that writes to the standard library logger. If
+// out is nil, the global standard library logger is used.
+type stdLogger struct {
+	out *log.Logger
+	kv  []interface{}
+}
+
+func (l *stdLogger) Debug(msg string, kv ...interface{}) { l.log("DEBUG", msg, kv) }
+func (l *stdLogger) Info(msg string, kv ...interface{})  { l.log("INFO", msg, kv) }
+func (l *stdLogger) Warn(msg string, kv ...interface{})  { l.log("WARN", msg, kv) }
+func (l *stdLogger) Error(msg string, kv ...interface{}) { l.log("ERR", msg, kv) }
+
+func (l *stdLogger) With(kv ...interface{}) Logger {
+	result := make([]interface{}, 0, len(l.kv)+len(kv))
+	result = append(result, l.kv...)
+	result = append(result, kv...)
+	return &stdLogger{out: l.out, kv: result}
+}
+
+func (l *stdLogger) log(level, msg string, kv []interface{}) {
+	printf := log.Printf
+	if l.out != nil {
+		printf = l.out.Printf
+	}
+
+	printf("[%s] %s%s%s", level, msg, formatKV(l.kv), formatKV(kv))
+}
+
+// Tee returns a Logger that sends every message to all of the given
+// loggers.
+func Tee(loggers ...Logger) Logger {
+	return teeLogger(loggers)
+}
+
+type teeLogger []Logger
+
+func (l teeLogger) Debug(msg string, kv ...interface{}) {
+	for _, v := range l {
+		v.Debug(msg, kv...)
+	}
+}
+
+func (l teeLogger) Info(msg string, kv ...interface{}) {
+	for _, v := range l {
+		v.Info(msg, kv...)
+	}
+}
+
+func (l teeLogger) Warn(msg string, kv ...interface{}) {
+	for _, v := range l {
+		v.Warn(msg, kv...)
+	}
+}
+
+func (l teeLogger) Error(msg string, kv ...interface{}) {
+	for _, v := range l {
+		v.Error(msg, kv...)
+	}
+}
+
+func (l teeLogger) With(kv ...interface{}) Logger {
+	result := make(teeLogger, len(l))
+	for i, v := range l {
+		result[i] = v.With(kv...)
+	}
+
+	return result
+}
+
+// formatKV formats the key and value pairs as " key=value" for every
+// pair. A key without a value is given the value "MISSING".
+func formatKV(kv []interface{}) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(kv); i += 2 {
+		var v interface{} = "MISSING"
+		if i+1 < len(kv) {
+			v = kv[i+1]
+		}
+
+		fmt.Fprintf(&buf, " %v=%v", kv[i], v)
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDefault_impl(t *testing.T) {
+	var _ Logger = Default()
+}
+
+func TestDefault(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	l := Default().With("app", "foo")
+	l.Info("hello", "tuple", "(a, b, c)", "odd")
+
+	actual := buf.String()
+	expected := "[INFO] hello app=foo tuple=(a, b, c) odd=MISSING\n"
+	if !strings.HasSuffix(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf).With("app", "foo")
+	l.Warn("hello")
+
+	actual := buf.String()
+	expected := "[WARN] hello app=foo\n"
+	if !strings.HasSuffix(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestTee(t *testing.T) {
+	var buf1, buf2 bytes.Buffer
+	l := Tee(New(&buf1), New(&buf2)).With("app", "foo")
+	l.Error("hello")
+
+	expected := "[ERR] hello app=foo\n"
+	for _, buf := range []*bytes.Buffer{&buf1, &buf2} {
+		if !strings.HasSuffix(buf.String(), expected) {
+			t.Fatalf("bad: %#v", buf.String())
+		}
+	}
+}
@@ -98,6 +98,7 @@ func Build(ctx *app.Context, opts *BuildOptions) error {
 			AppID:       ctx.Appfile.ID,
 			Infra:       ctx.Tuple.Infra,
 			InfraFlavor: ctx.Tuple.InfraFlavor,
+			Environment: ctx.Environment,
 		},
 
 		Artifact: make(map[string]string),
@@ -25,9 +25,10 @@ func Project(ctx *context.Shared) *hashitools.Project {
 		Name:       "packer",
 		MinVersion: packerMinVersion,
 		Installer: &hashitools.GoInstaller{
-			Name: "packer",
-			Dir:  filepath.Join(ctx.InstallDir),
-			Ui:   ctx.Ui,
+			Name:       "packer",
+			Dir:        filepath.Join(ctx.InstallDir),
+			Ui:         ctx.Ui,
+			RequiredBy: ctx.Implementation,
 		},
 	}
 }
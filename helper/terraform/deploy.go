@@ -291,6 +291,7 @@ func (opts *DeployOptions) lookupBuildVars(
 			AppID:       ctx.Appfile.ID,
 			Infra:       ctx.Tuple.Infra,
 			InfraFlavor: ctx.Tuple.InfraFlavor,
+			Environment: ctx.Environment,
 		},
 	})
 	if err != nil {
@@ -333,6 +334,7 @@ func (opts *DeployOptions) lookupDeploy(
 		AppID:       ctx.Appfile.ID,
 		Infra:       ctx.Tuple.Infra,
 		InfraFlavor: ctx.Tuple.InfraFlavor,
+		Environment: ctx.Environment,
 	}
 	deploy, err := ctx.Directory.GetDeploy(&directory.Deploy{Lookup: deployLookup})
 	if err != nil {
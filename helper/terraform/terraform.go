@@ -28,9 +28,10 @@ func Project(ctx *context.Shared) (*hashitools.Project, error) {
 		Name:       "terraform",
 		MinVersion: tfMinVersion,
 		Installer: &hashitools.GoInstaller{
-			Name: "terraform",
-			Dir:  filepath.Join(ctx.InstallDir),
-			Ui:   ctx.Ui,
+			Name:       "terraform",
+			Dir:        filepath.Join(ctx.InstallDir),
+			Ui:         ctx.Ui,
+			RequiredBy: ctx.Implementation,
 		},
 	}
 	return p, p.InstallIfNeeded()
@@ -397,7 +397,7 @@ Usage: otto dev
 `
 
 const actionDestroyHelp = `
-Usage: otto dev destroy
+Usage: otto dev destroy [-force]
 
   Destroys the development environment.
 
@@ -406,6 +406,9 @@ Usage: otto dev destroy
   except for your own project's code (the directory and any subdirectories
   where the Appfile exists).
 
+  Otto will ask for confirmation to protect against an accidental destroy.
+  You can provide the -force flag to skip this check.
+
 `
 
 const actionHaltHelp = `
@@ -47,6 +47,10 @@ type Context struct {
 	// The infrastructure configuration itself from the Appfile. This includes
 	// the flavor of the infrastructure we want to launch.
 	Infra *appfile.Infrastructure
+
+	// Outputs are the outputs of the infrastructure that was created, from
+	// the directory. This is only set for the Verify call.
+	Outputs map[string]string
 }
 
 // RouteName implements the router.Context interface so we can use Router
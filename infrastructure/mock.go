@@ -1,31 +1,114 @@
 package infrastructure
 
+import (
+	"sync"
+)
+
 // Mock is a mock implementation of the Infrastructure interface.
 type Mock struct {
+	CredsCalled  bool
+	CredsContext *Context
+	CredsResult  map[string]string
+	CredsErr     error
+	CredsFunc    func(*Context) (map[string]string, error)
+
+	VerifyCredsCalled  bool
+	VerifyCredsContext *Context
+	VerifyCredsErr     error
+
+	ExecuteCalled  bool
+	ExecuteContext *Context
+	ExecuteErr     error
+
 	CompileCalled  bool
 	CompileContext *Context
 	CompileResult  *CompileResult
 	CompileErr     error
+
+	FlavorsResult []string
+
+	// Calls are the names of the methods that were called, in the order
+	// they were called. This is safe to read once the calls are done.
+	Calls []string
+
+	lock sync.Mutex
+}
+
+// record appends name to Calls. Otto may call the mock concurrently when
+// it walks apps in parallel.
+func (m *Mock) record(name string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.Calls = append(m.Calls, name)
 }
 
 func (m *Mock) Creds(ctx *Context) (map[string]string, error) {
-	return nil, nil
+	m.record("Creds")
+	m.CredsCalled = true
+	m.CredsContext = ctx
+	if m.CredsFunc != nil {
+		return m.CredsFunc(ctx)
+	}
+
+	return m.CredsResult, m.CredsErr
 }
 
 func (m *Mock) VerifyCreds(ctx *Context) error {
-	return nil
+	m.record("VerifyCreds")
+	m.VerifyCredsCalled = true
+	m.VerifyCredsContext = ctx
+	return m.VerifyCredsErr
 }
 
 func (m *Mock) Execute(ctx *Context) error {
-	return nil
+	m.record("Execute")
+	m.ExecuteCalled = true
+	m.ExecuteContext = ctx
+	return m.ExecuteErr
 }
 
 func (m *Mock) Compile(ctx *Context) (*CompileResult, error) {
+	m.record("Compile")
 	m.CompileCalled = true
 	m.CompileContext = ctx
 	return m.CompileResult, m.CompileErr
 }
 
 func (m *Mock) Flavors() []string {
-	return nil
+	return m.FlavorsResult
+}
+
+// MockValidator is a mock implementation of an Infrastructure that also
+// implements Validator.
+type MockValidator struct {
+	Mock
+
+	ValidateCalled  bool
+	ValidateContext *Context
+	ValidateErr     error
+}
+
+func (m *MockValidator) Validate(ctx *Context) error {
+	m.record("Validate")
+	m.ValidateCalled = true
+	m.ValidateContext = ctx
+	return m.ValidateErr
+}
+
+// MockVerifier is a mock implementation of an Infrastructure that also
+// implements Verifier.
+type MockVerifier struct {
+	Mock
+
+	VerifyCalled  bool
+	VerifyContext *Context
+	VerifyResult  *VerifyResult
+	VerifyErr     error
+}
+
+func (m *MockVerifier) Verify(ctx *Context) (*VerifyResult, error) {
+	m.record("Verify")
+	m.VerifyCalled = true
+	m.VerifyContext = ctx
+	return m.VerifyResult, m.VerifyErr
 }
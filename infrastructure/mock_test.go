@@ -7,3 +7,13 @@ import (
 func TestMock_impl(t *testing.T) {
 	var _ Infrastructure = new(Mock)
 }
+
+func TestMockValidator_impl(t *testing.T) {
+	var _ Infrastructure = new(MockValidator)
+	var _ Validator = new(MockValidator)
+}
+
+func TestMockVerifier_impl(t *testing.T) {
+	var _ Infrastructure = new(MockVerifier)
+	var _ Verifier = new(MockVerifier)
+}
@@ -0,0 +1,8 @@
+package infrastructure
+
+// Validator is an optional interface that an Infrastructure can implement
+// to check its configuration before anything is compiled. This works
+// like app.Validator.
+type Validator interface {
+	Validate(ctx *Context) error
+}
@@ -0,0 +1,30 @@
+package infrastructure
+
+import (
+	"errors"
+)
+
+// ErrVerifyNotSupported is returned by Verify when the infrastructure
+// can't check its resources. This is mostly for infrastructures that are
+// served over RPC, which always look like they implement Verifier.
+var ErrVerifyNotSupported = errors.New("verifying the infrastructure is not supported")
+
+// Verifier is an optional interface that an Infrastructure can implement
+// to check that the resources it created still exist, since the records
+// of the directory can be out of date, such as when the resources were
+// deleted by hand.
+type Verifier interface {
+	// Verify checks the infrastructure. The context has the credentials
+	// and the outputs of the infrastructure that was created.
+	//
+	// An error means the infrastructure couldn't be checked at all.
+	// Resources that were checked and are gone are in the result.
+	Verify(ctx *Context) (*VerifyResult, error)
+}
+
+// VerifyResult is the result of verifying the infrastructure.
+type VerifyResult struct {
+	// Missing are the resources that no longer exist, such as the IDs of
+	// VPCs or instances. An empty list means the infrastructure exists.
+	Missing []string
+}
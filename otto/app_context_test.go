@@ -0,0 +1,220 @@
+package otto
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/ui"
+	"github.com/mitchellh/copystructure"
+)
+
+func TestCoreAppContext_cache(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	core := testCore(t, coreConfig)
+
+	done := core.cacheAppContexts()
+	defer done()
+
+	ctx1, err := core.appContext(core.appfile)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	ctx1.Action = "reload"
+	ctx1.InfraCreds = map[string]string{"foo": "bar"}
+
+	// The cached context is copied so changes don't leak
+	ctx2, err := core.appContext(core.appfile)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if ctx1 == ctx2 || ctx2.Action != "" || ctx2.InfraCreds != nil {
+		t.Fatalf("bad: %#v", ctx2)
+	}
+	if core.appContexts[core.appfile.ID] == nil {
+		t.Fatal("should be cached")
+	}
+
+	// Changing the compilation metadata invalidates the cache
+	err = core.saveCompileMetadata(&CompileMetadata{
+		App: &app.CompileResult{Version: 1, DevPorts: 1},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	ctx3, err := core.appContext(core.appfile)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if ctx3.CompileResult == nil || len(ctx3.DevPorts) != 1 {
+		t.Fatalf("bad: %#v", ctx3)
+	}
+
+	// Outside of an operation nothing is cached
+	done()
+	if core.appContexts != nil {
+		t.Fatalf("bad: %#v", core.appContexts)
+	}
+}
+
+func TestCopyAppContext(t *testing.T) {
+	ctx := &app.Context{
+		ActionArgs:      []string{"a"},
+		DevPorts:        []int{8080},
+		DevDepAddresses: map[string]string{"db": "10.0.0.1"},
+		DevDepPorts:     map[string][]int{"db": []int{5432}},
+	}
+	ctx.InfraCreds = map[string]string{"key": "secret"}
+	ctx.FoundationDirs = []string{"consul"}
+	ctx.Env = map[string]string{"FOO": "bar"}
+
+	result := copyAppContext(ctx)
+	if !reflect.DeepEqual(result, ctx) {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	result.ActionArgs[0] = "b"
+	result.DevPorts[0] = 1
+	result.DevDepAddresses["db"] = "changed"
+	result.DevDepPorts["db"][0] = 1
+	result.InfraCreds["key"] = "changed"
+	result.FoundationDirs[0] = "changed"
+	result.Env["FOO"] = "changed"
+	if ctx.ActionArgs[0] != "a" ||
+		ctx.DevPorts[0] != 8080 ||
+		ctx.DevDepAddresses["db"] != "10.0.0.1" ||
+		ctx.DevDepPorts["db"][0] != 5432 ||
+		ctx.InfraCreds["key"] != "secret" ||
+		ctx.FoundationDirs[0] != "consul" ||
+		ctx.Env["FOO"] != "bar" {
+		t.Fatalf("bad: %#v", ctx)
+	}
+}
+
+func TestCoreAppContext_customizations(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("customization-app-filter", "Appfile"))
+	core := testCore(t, coreConfig)
+	path := core.appfile.Path
+
+	ctx, err := core.appContext(core.appfile)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(ctx.Appfile.Customization.Raw) != 2 {
+		t.Fatalf("bad: %#v", ctx.Appfile.Customization.Raw)
+	}
+
+	// Nothing that is changed through the context changes the Appfile
+	for _, c := range ctx.Appfile.Customization.Raw {
+		c.Type = "changed"
+		c.Config["changed"] = true
+	}
+	ctx.Appfile.Customization.Raw = nil
+	ctx.Appfile.Path = "changed"
+
+	if core.appfile.Path != path {
+		t.Fatalf("bad: %s", core.appfile.Path)
+	}
+	raw := core.appfile.Customization.Raw
+	if len(raw) != 3 {
+		t.Fatalf("bad: %#v", raw)
+	}
+	for _, c := range raw {
+		if c.Type == "changed" || c.Config["changed"] != nil {
+			t.Fatalf("bad: %#v", c)
+		}
+	}
+}
+
+func TestCoreAppContext_customizationCopyError(t *testing.T) {
+	copystructure.Copiers[reflect.TypeOf(testUncopyable{})] = func(interface{}) (interface{}, error) {
+		return nil, errors.New("can't copy this")
+	}
+	defer delete(copystructure.Copiers, reflect.TypeOf(testUncopyable{}))
+
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("customization-app-filter", "Appfile"))
+	coreConfig.Ui = uiMock
+	core := testCore(t, coreConfig)
+	for _, c := range core.appfile.Customization.Filter("app") {
+		c.Config["uncopyable"] = testUncopyable{}
+	}
+
+	ctx, err := core.appContext(core.appfile)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, "can't copy this")
+	uiMock.AssertMessageContains(t, core.appfile.Path)
+
+	// The top-level settings are still copied
+	raw := ctx.Appfile.Customization.Raw
+	if len(raw) != 2 {
+		t.Fatalf("bad: %#v", raw)
+	}
+	raw[0].Config["changed"] = true
+	for _, c := range core.appfile.Customization.Raw {
+		if c.Config["changed"] != nil {
+			t.Fatalf("bad: %#v", c)
+		}
+	}
+}
+
+// testUncopyable is a value that copystructure fails to copy in tests.
+type testUncopyable struct{}
+
+// BenchmarkCoreAppContext_customizations measures building the context of
+// an app with customizations, which copies them.
+func BenchmarkCoreAppContext_customizations(b *testing.B) {
+	coreConfig := TestCoreConfig(b)
+	coreConfig.Appfile = TestAppfile(b, testPath("customization-app-filter", "Appfile"))
+	core, err := NewCore(coreConfig)
+	if err != nil {
+		b.Fatalf("err: %s", err)
+	}
+	defer core.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := core.newAppContext(core.appfile); err != nil {
+			b.Fatalf("err: %s", err)
+		}
+	}
+}
+
+// BenchmarkCoreDev_manyDeps measures Dev with 20 dependencies, which
+// builds the context of every app several times.
+func BenchmarkCoreDev_manyDeps(b *testing.B) {
+	td, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		b.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+	path := TestAppfileDeps(b, td, 20)
+
+	coreConfig := TestCoreConfig(b)
+	coreConfig.Appfile = TestAppfile(b, path)
+	TestApp(b, TestAppTuple, coreConfig)
+	core, err := NewCore(coreConfig)
+	if err != nil {
+		b.Fatalf("err: %s", err)
+	}
+	defer core.Close()
+	if err := core.Compile(); err != nil {
+		b.Fatalf("err: %s", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := core.Dev(); err != nil {
+			b.Fatalf("err: %s", err)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package otto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+// ArtifactStore stores the files that the builds of apps produce, so that
+// they can be deployed from another machine than the one that built
+// them. The stores of helper/artifact implement this.
+type ArtifactStore interface {
+	// Put stores the data of r under key and returns the URL where it is
+	// stored. If r has a Size() int64 method, it returns the number of
+	// bytes of r.
+	Put(key string, r io.Reader) (url string, err error)
+
+	// Get returns the data under key, which the caller must close.
+	Get(key string) (io.ReadCloser, error)
+
+	// Exists returns true if there is data under key.
+	Exists(key string) (bool, error)
+}
+
+// The keys of the files of a build in the artifact of the build record are
+// "file.NAME.url" and "file.NAME.sha256".
+const (
+	artifactFilePrefix   = "file."
+	artifactURLSuffix    = ".url"
+	artifactSHA256Suffix = ".sha256"
+
+	// buildVarPrefix is the prefix of the keys of the variables of the
+	// build in the artifact of the build record.
+	buildVarPrefix = "var."
+)
+
+// build builds the app impl of the context ctx. The build record of the
+// app is labeled with the names and the variables of the build, and if the
+// app returns the files it built, they're uploaded to the artifact store
+// and recorded in the build record too. Nothing is stored if names is
+// nil, such as for the actions of builds.
+func (c *Core) build(impl app.App, ctx *app.Context, names *buildNames) error {
+	var files map[string]string
+	if builder, ok := impl.(app.ArtifactBuilder); ok {
+		result, err := builder.BuildArtifacts(ctx)
+		switch {
+		case err == app.ErrArtifactsNotSupported:
+			if err := impl.Build(ctx); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case result != nil:
+			files = result.Files
+		}
+	} else if err := impl.Build(ctx); err != nil {
+		return err
+	}
+	if names == nil {
+		return nil
+	}
+
+	return c.storeBuild(ctx, names, files)
+}
+
+// storeBuild labels the build record of the app of the context ctx with
+// the names and the variables of the build, and uploads the files of the build to the
+// artifact store and records their URLs and checksums. Without an
+// artifact store the local paths are recorded, so the build can only be
+// deployed from this machine.
+func (c *Core) storeBuild(ctx *app.Context, names *buildNames, files map[string]string) error {
+	record, err := c.dir.GetBuild(&directory.Build{
+		Lookup: c.deployLookup(ctx.Appfile.ID)})
+	if err != nil {
+		return errwrap.Wrapf(
+			"Error loading the build: {{err}}", directoryErr(err))
+	}
+	if record == nil {
+		// The app didn't store a build, so there is nothing to label
+		if len(files) == 0 {
+			return nil
+		}
+
+		record = &directory.Build{Lookup: c.deployLookup(ctx.Appfile.ID)}
+	}
+	if record.Artifact == nil {
+		record.Artifact = make(map[string]string)
+	}
+	record.Artifact[buildNameKey] = names.Record
+	record.Artifact[driftFingerprintKey] = names.Fingerprint
+	if md, err := c.compileMetadata(); err == nil && md != nil {
+		record.Artifact[driftCompileHashKey] = compileMetadataHash(md)
+	}
+	for k, v := range ctx.BuildVars {
+		record.Artifact[buildVarPrefix+k] = v
+	}
+
+	if len(files) > 0 && c.artifactStore == nil {
+		ui.Warn(c.ui,
+			"No artifact store is configured, so the files of the build are only\n"+
+				"on this machine and can't be deployed from anywhere else. Set\n"+
+				"OTTO_ARTIFACT_STORE to an S3 URL or a shared directory to upload them.")
+	}
+
+	keys := make([]string, 0, len(files))
+	for name := range files {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+
+	for _, name := range keys {
+		f, err := c.storeArtifact(path.Join(names.Artifact, name), files[name])
+		if err != nil {
+			return fmt.Errorf(
+				"Error storing the build file '%s': %s", name, err)
+		}
+
+		record.Artifact[artifactFilePrefix+name+artifactURLSuffix] = f.URL
+		record.Artifact[artifactFilePrefix+name+artifactSHA256Suffix] = f.Checksum
+	}
+	if len(files) > 0 {
+		record.Artifact[buildArtifactKey] = names.Artifact
+	}
+
+	if err := c.dir.PutBuild(record); err != nil {
+		return errwrap.Wrapf(
+			"Error storing the build: {{err}}", directoryErr(err))
+	}
+
+	return nil
+}
+
+// storeArtifact uploads the local file at path p under a key with the
+// prefix prefix, and the checksum of the file next to it. The upload
+// reports its progress to the UI.
+func (c *Core) storeArtifact(prefix, p string) (*app.ArtifactFile, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	base := filepath.Base(p)
+	progress := ui.Progress(c.ui, fmt.Sprintf("Uploading %s", base))
+	defer progress.Done()
+	r := &artifactReader{
+		Reader:   f,
+		Hash:     sha256.New(),
+		Progress: progress,
+		Total:    fi.Size(),
+	}
+
+	var result app.ArtifactFile
+	if c.artifactStore == nil {
+		if _, err := io.Copy(ioutil.Discard, r); err != nil {
+			return nil, err
+		}
+
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		u := &url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}
+		result.URL = u.String()
+	} else {
+		key := path.Join(prefix, base)
+		result.URL, err = c.artifactStore.Put(key, r)
+		if err != nil {
+			return nil, err
+		}
+		if r.Current != r.Total {
+			return nil, fmt.Errorf(
+				"only %d of %d bytes were uploaded", r.Current, r.Total)
+		}
+
+		// The checksum is stored in the format of sha256sum, so that the
+		// file can be verified without Otto.
+		sum := hex.EncodeToString(r.Hash.Sum(nil))
+		_, err = c.artifactStore.Put(
+			key+".sha256", strings.NewReader(sum+"  "+base+"\n"))
+		if err != nil {
+			return nil, errwrap.Wrapf("Error storing the checksum: {{err}}", err)
+		}
+	}
+	result.Checksum = hex.EncodeToString(r.Hash.Sum(nil))
+
+	return &result, nil
+}
+
+// artifacts sets the Artifacts of the context ctx from the build record
+// of its app, if there is one.
+func (c *Core) artifacts(ctx *app.Context) error {
+	record, err := c.dir.GetBuild(&directory.Build{
+		Lookup: c.deployLookup(ctx.Appfile.ID)})
+	if err != nil {
+		return errwrap.Wrapf(
+			"Error loading the build: {{err}}", directoryErr(err))
+	}
+	if record == nil {
+		return nil
+	}
+
+	result := make(map[string]*app.ArtifactFile)
+	for k, v := range record.Artifact {
+		if !strings.HasPrefix(k, artifactFilePrefix) ||
+			!strings.HasSuffix(k, artifactURLSuffix) {
+			continue
+		}
+
+		name := k[len(artifactFilePrefix) : len(k)-len(artifactURLSuffix)]
+		result[name] = &app.ArtifactFile{
+			URL:      v,
+			Checksum: record.Artifact[artifactFilePrefix+name+artifactSHA256Suffix],
+		}
+	}
+	if len(result) > 0 {
+		ctx.Artifacts = result
+	}
+
+	return nil
+}
+
+// artifactReader is the reader of an upload, which hashes the data and
+// reports the progress as it is read. It has a Size so that the stores
+// can stream it.
+type artifactReader struct {
+	io.Reader
+	Hash     hash.Hash
+	Progress ui.ProgressHandle
+	Total    int64
+	Current  int64
+}
+
+func (r *artifactReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		r.Hash.Write(p[:n])
+		r.Current += int64(n)
+		r.Progress.Update(r.Current, r.Total)
+	}
+
+	return n, err
+}
+
+func (r *artifactReader) Size() int64 { return r.Total - r.Current }
@@ -0,0 +1,164 @@
+package otto
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+// The SHA-256 checksum of "hello"
+const testArtifactChecksum = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+func TestCoreBuild_artifacts(t *testing.T) {
+	store := new(testArtifactStore)
+	core, appMock, _ := testArtifactCore(t, "basic", store)
+
+	if err := core.Build(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.BuildArtifactsCalled || appMock.BuildCalled {
+		t.Fatalf("bad: %#v", appMock)
+	}
+
+	// The file and its checksum are uploaded
+	var key string
+	for k := range store.Data {
+		if strings.HasSuffix(k, "/image/image.tar") {
+			key = k
+		}
+	}
+	if key == "" || !strings.HasPrefix(key, core.appfile.ID+"/"+core.environment+"/") {
+		t.Fatalf("bad: %#v", store.Data)
+	}
+	if store.Data[key] != "hello" {
+		t.Fatalf("bad: %#v", store.Data)
+	}
+	if store.Data[key+".sha256"] != testArtifactChecksum+"  image.tar\n" {
+		t.Fatalf("bad: %#v", store.Data)
+	}
+
+	// The URL and checksum are recorded in the build
+	build, err := core.dir.GetBuild(&directory.Build{
+		Lookup: core.deployLookup(core.appfile.ID)})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if build.Artifact["file.image.url"] != "mem://"+key ||
+		build.Artifact["file.image.sha256"] != testArtifactChecksum {
+		t.Fatalf("bad: %#v", build.Artifact)
+	}
+
+	// The deploy gets the stored files
+	if err := core.Deploy("", nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	f := appMock.DeployContext.Artifacts["image"]
+	if f == nil || f.URL != "mem://"+key || f.Checksum != testArtifactChecksum {
+		t.Fatalf("bad: %#v", appMock.DeployContext.Artifacts)
+	}
+}
+
+func TestCoreBuild_artifactsNoStore(t *testing.T) {
+	core, appMock, uiMock := testArtifactCore(t, "basic", nil)
+
+	if err := core.Build(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, "No artifact store is configured")
+
+	// The local path is recorded
+	if err := core.Deploy("", nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	f := appMock.DeployContext.Artifacts["image"]
+	if f == nil || !strings.HasPrefix(f.URL, "file://") ||
+		!strings.HasSuffix(f.URL, "/image.tar") || f.Checksum != testArtifactChecksum {
+		t.Fatalf("bad: %#v", appMock.DeployContext.Artifacts)
+	}
+}
+
+func TestCoreBuild_artifactsNotSupported(t *testing.T) {
+	store := new(testArtifactStore)
+	core, appMock, _ := testArtifactCore(t, "basic", store)
+	appMock.BuildArtifactsErr = app.ErrArtifactsNotSupported
+
+	if err := core.Build(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.BuildCalled {
+		t.Fatal("build should be called")
+	}
+	if len(store.Data) > 0 {
+		t.Fatalf("bad: %#v", store.Data)
+	}
+}
+
+// testArtifactStore is an ArtifactStore in memory.
+type testArtifactStore struct {
+	Data map[string]string
+}
+
+func (s *testArtifactStore) Put(key string, r io.Reader) (string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	if s.Data == nil {
+		s.Data = make(map[string]string)
+	}
+	s.Data[key] = string(data)
+
+	return "mem://" + key, nil
+}
+
+func (s *testArtifactStore) Get(key string) (io.ReadCloser, error) {
+	data, ok := s.Data[key]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	return ioutil.NopCloser(bytes.NewBufferString(data)), nil
+}
+
+func (s *testArtifactStore) Exists(key string) (bool, error) {
+	_, ok := s.Data[key]
+	return ok, nil
+}
+
+// testArtifactCore returns a core for the Appfile of the fixture with the
+// artifact store, whose app builds the file "image.tar".
+func testArtifactCore(t *testing.T, fixture string, store ArtifactStore) (*Core, *app.MockArtifactBuilder, *ui.Mock) {
+	dir, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	path := filepath.Join(dir, "image.tar")
+	if err := ioutil.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.ArtifactStore = store
+	appMock := &app.MockArtifactBuilder{
+		BuildArtifactsResult: &app.BuildResult{
+			Files: map[string]string{"image": path},
+		},
+	}
+	core := TestCore(t, &TestCoreOpts{
+		Config: coreConfig,
+		Path:   testPath(fixture, "Appfile"),
+		App:    appMock,
+		Ui:     uiMock,
+	})
+
+	return core, appMock, uiMock
+}
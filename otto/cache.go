@@ -0,0 +1,523 @@
+package otto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/foundation"
+	"github.com/hashicorp/otto/helper/installdir"
+	"github.com/hashicorp/otto/ui"
+)
+
+// errCacheLocked is returned by lockFile when the file is locked by
+// another process.
+var errCacheLocked = errors.New("cache is in use")
+
+// credsCacheName is the directory in the cache directory that has the
+// cached infrastructure credentials. This isn't an app cache and it is
+// never pruned.
+const credsCacheName = "creds"
+
+// CacheItem is a single item that Otto caches, such as the cache of an
+// app or a dev dependency in the global cache.
+type CacheItem struct {
+	// Kind is the kind of the item: "app", "dev-dep", "binaries", or
+	// "global-data".
+	Kind string
+
+	// Name identifies the item within its kind: the ID of the app, the
+	// fingerprint of the dev dependency, or the name of the directory of
+	// the binaries or global data.
+	Name string
+
+	Path string
+	Size int64
+
+	// LastUsed is the time a dev dependency or binaries were last used,
+	// or the time global data was last modified. This isn't set for apps.
+	LastUsed time.Time
+
+	// RequiredBy are the implementations that required binaries, from
+	// the manifest their installer wrote. This is nil if it isn't known.
+	RequiredBy []string
+
+	// Current is true if the item belongs to an app in the Appfile of
+	// this Core, including its dependencies. Binaries are current unless
+	// their manifest says that only implementations this Core doesn't
+	// have required them.
+	Current bool
+}
+
+// CacheInfo is the disk space Otto uses for caching, returned by
+// Core.CacheInfo.
+type CacheInfo struct {
+	// Apps are the caches of all the apps, sorted by ID.
+	Apps []*CacheItem
+
+	// DevDeps are the entries of the global cache of dev dependencies,
+	// least recently used first.
+	DevDeps []*CacheItem
+
+	// AddrDBSize is the size of the databases of dev IP addresses and
+	// ports. These are never pruned; use GC to release stale leases.
+	AddrDBSize int64
+
+	// Binaries are the directories of the binaries that Otto installed,
+	// such as Packer and Terraform, sorted by name.
+	Binaries []*CacheItem
+
+	// GlobalData are the entries of the data that apps share between
+	// projects, sorted by name.
+	GlobalData []*CacheItem
+}
+
+// Size returns the total size of everything in the CacheInfo.
+func (i *CacheInfo) Size() int64 {
+	result := i.AddrDBSize
+	for _, items := range [][]*CacheItem{
+		i.Apps, i.DevDeps, i.Binaries, i.GlobalData} {
+		for _, item := range items {
+			result += item.Size
+		}
+	}
+
+	return result
+}
+
+// PruneCacheOpts are the options for Core.PruneCache.
+type PruneCacheOpts struct {
+	// DevDepAge is how long a dev dependency in the global cache can go
+	// without being used before it is deleted. This defaults to
+	// DefaultLeaseAge.
+	DevDepAge time.Duration
+
+	// GlobalDataAge is how long an entry of the global data can go
+	// without being modified before it is deleted. If this is zero, the
+	// global data is only deleted with All.
+	GlobalDataAge time.Duration
+
+	// All, if true, deletes the caches of all apps including the current
+	// ones, the whole global cache of dev dependencies, all the installed
+	// binaries, and all the global data. Cached credentials and the dev
+	// IP and port databases are never deleted.
+	All bool
+}
+
+// PruneCacheResult is the result of Core.PruneCache.
+type PruneCacheResult struct {
+	// Deleted are the items that were deleted.
+	Deleted []*CacheItem
+
+	// Skipped are the items that would've been deleted but were in use
+	// by another Otto process, such as a running Dev.
+	Skipped []*CacheItem
+}
+
+// Size returns the total size of the deleted items.
+func (r *PruneCacheResult) Size() int64 {
+	var result int64
+	for _, item := range r.Deleted {
+		result += item.Size
+	}
+
+	return result
+}
+
+// CacheInfo returns the disk space that Otto uses for caching.
+func (c *Core) CacheInfo() (*CacheInfo, error) {
+	current := make(map[string]struct{})
+	for _, raw := range c.appfileCompiled.Graph.Vertices() {
+		current[raw.(*appfile.CompiledGraphVertex).File.ID] = struct{}{}
+	}
+
+	result := new(CacheInfo)
+
+	cacheDir := filepath.Join(c.dataDir, "cache")
+	infos, err := ioutil.ReadDir(cacheDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, info := range infos {
+		name := info.Name()
+		if !info.IsDir() || name == credsCacheName || strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		path := filepath.Join(cacheDir, name)
+		size, err := dirSize(path)
+		if err != nil {
+			return nil, err
+		}
+
+		_, ok := current[name]
+		result.Apps = append(result.Apps, &CacheItem{
+			Kind:    "app",
+			Name:    name,
+			Path:    path,
+			Size:    size,
+			Current: ok,
+		})
+	}
+
+	store := c.devDepStore()
+	entries, err := store.Entries()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		result.DevDeps = append(result.DevDeps, &CacheItem{
+			Kind:     "dev-dep",
+			Name:     e.Fingerprint,
+			Path:     filepath.Join(store.Dir, e.Fingerprint),
+			Size:     e.Size,
+			LastUsed: e.LastUsed,
+		})
+	}
+
+	for _, path := range []string{c.devIPDB().Path, c.devPortDB().Path} {
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		result.AddrDBSize += info.Size()
+	}
+
+	result.Binaries, err = c.binariesCacheItems()
+	if err != nil {
+		return nil, err
+	}
+
+	globalDir := filepath.Join(c.dataDir, "global-data")
+	infos, err = ioutil.ReadDir(globalDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, info := range infos {
+		path := filepath.Join(globalDir, info.Name())
+		size, modified, err := dirUsage(path)
+		if err != nil {
+			return nil, err
+		}
+
+		result.GlobalData = append(result.GlobalData, &CacheItem{
+			Kind:     "global-data",
+			Name:     info.Name(),
+			Path:     path,
+			Size:     size,
+			LastUsed: modified,
+		})
+	}
+
+	return result, nil
+}
+
+// appCacheDir returns the cache directory of the app with the given ID.
+// This is the CacheDir of the app context. It is the same for every
+// compilation of the app, so apps can bake it into what they compile.
+func (c *Core) appCacheDir(id string) string {
+	return filepath.Join(c.dataDir, "cache", id)
+}
+
+// appCacheGeneration returns the generation of the compilation result of
+// an app, which is nil if it wasn't compiled. Anything cached for one
+// compilation of an app, such as its dev dependency, may be invalid for
+// a compilation with other foundations or by another version of the app,
+// so it is stamped with the generation it was cached for.
+func appCacheGeneration(result *app.CompileResult) string {
+	data, err := json.Marshal(result)
+	if err != nil {
+		data = nil
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// binariesCacheItems returns the cache items of the directories of the
+// installed binaries. Each project is installed into its own directory,
+// see installdir.GoInstaller.
+func (c *Core) binariesCacheItems() ([]*CacheItem, error) {
+	binariesDir := filepath.Join(c.dataDir, "binaries")
+	infos, err := ioutil.ReadDir(binariesDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var result []*CacheItem
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(binariesDir, info.Name())
+		size, modified, err := dirUsage(path)
+		if err != nil {
+			return nil, err
+		}
+		item := &CacheItem{
+			Kind:     "binaries",
+			Name:     info.Name(),
+			Path:     path,
+			Size:     size,
+			LastUsed: modified,
+			Current:  true,
+		}
+
+		// Binaries installed by older versions of Otto have no manifest,
+		// so nothing is known about them and they're kept.
+		manifest, err := installdir.ReadManifest(path)
+		if err != nil {
+			c.logger.Warn("error reading the manifest of binaries",
+				"name", info.Name(), "err", err)
+		}
+		if manifest != nil {
+			if !manifest.LastUsed.IsZero() {
+				item.LastUsed = manifest.LastUsed
+			}
+			item.RequiredBy = manifest.RequiredBy
+			if len(manifest.RequiredBy) > 0 {
+				item.Current = false
+				for _, name := range manifest.RequiredBy {
+					if c.hasImplementation(name) {
+						item.Current = true
+						break
+					}
+				}
+			}
+		}
+
+		result = append(result, item)
+	}
+
+	return result, nil
+}
+
+// hasImplementation returns true if this Core has the implementation
+// with the given name, as in the Implementation field of the shared
+// context. Names it doesn't understand are assumed to be implemented,
+// since they may be from a newer version of Otto.
+func (c *Core) hasImplementation(name string) bool {
+	idx := strings.Index(name, ":")
+	if idx < 0 {
+		return true
+	}
+
+	v := name[idx+1:]
+	switch name[:idx] {
+	case "app":
+		tuple, err := app.ParseTuple(v)
+		if err != nil {
+			return true
+		}
+
+		return app.TupleMap(c.apps).Lookup(tuple) != nil
+	case "infra":
+		_, ok := c.infras[v]
+		return ok
+	case "foundation":
+		tuple, err := foundation.ParseTuple(v)
+		if err != nil {
+			return true
+		}
+
+		return foundation.TupleMap(c.foundationMap).Lookup(tuple) != nil
+	default:
+		return true
+	}
+}
+
+// dirUsage returns the size of the directory or file at path and the
+// last time anything in it was modified.
+func dirUsage(path string) (int64, time.Time, error) {
+	var size int64
+	var modified time.Time
+	err := filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		if info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+
+		return nil
+	})
+
+	return size, modified, err
+}
+
+// PruneCache deletes cached data that is no longer needed: the caches of
+// apps that aren't in the Appfile of this Core, dev dependencies that
+// haven't been used in a long time, binaries that were only required by
+// implementations this Core doesn't have, and, with
+// PruneCacheOpts.GlobalDataAge, global data that hasn't been modified in
+// a long time. The user is asked to confirm before anything is deleted.
+//
+// App caches that are in use by another Otto process are skipped.
+func (c *Core) PruneCache(opts *PruneCacheOpts) (result *PruneCacheResult, err error) {
+	if opts == nil {
+		opts = new(PruneCacheOpts)
+	}
+	devDepAge := opts.DevDepAge
+	if devDepAge <= 0 {
+		devDepAge = DefaultLeaseAge
+	}
+
+	op := c.operation("cache.prune", nil)
+	defer func() { op.End(err) }()
+
+	info, err := c.CacheInfo()
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the cache: %s", err)
+	}
+
+	// Find everything to delete
+	var items []*CacheItem
+	for _, item := range info.Apps {
+		if opts.All || !item.Current {
+			items = append(items, item)
+		}
+	}
+	cutoff := time.Now().Add(-devDepAge)
+	for _, item := range info.DevDeps {
+		if opts.All || item.LastUsed.Before(cutoff) {
+			items = append(items, item)
+		}
+	}
+	for _, item := range info.Binaries {
+		if opts.All || !item.Current {
+			items = append(items, item)
+		}
+	}
+	if opts.All || opts.GlobalDataAge > 0 {
+		globalCutoff := time.Now().Add(-opts.GlobalDataAge)
+		for _, item := range info.GlobalData {
+			if opts.All || item.LastUsed.Before(globalCutoff) {
+				items = append(items, item)
+			}
+		}
+	}
+
+	result = new(PruneCacheResult)
+	if len(items) == 0 {
+		ui.Result(c.ui, "Nothing to prune.")
+		return result, nil
+	}
+
+	details := make([]string, len(items))
+	for i, item := range items {
+		details[i] = fmt.Sprintf(
+			"%s %s (%s)", item.Kind, item.Name, summarySize(item.Size))
+	}
+	err = c.confirm(&confirmOpts{
+		Id:      "cache_prune_confirm",
+		Message: "Otto will delete the following cached data:",
+		Details: details,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.ui.Header("Pruning the cache...")
+	for _, item := range items {
+		deleted, err := c.pruneCacheItem(item)
+		if err != nil {
+			return result, fmt.Errorf(
+				"Error deleting %s %s: %s", item.Kind, item.Name, err)
+		}
+		if !deleted {
+			ui.Warn(c.ui, fmt.Sprintf(
+				"Skipped %s %s, it is in use by another Otto process.",
+				item.Kind, item.Name))
+			result.Skipped = append(result.Skipped, item)
+			continue
+		}
+
+		c.ui.Message(fmt.Sprintf("Deleted: %s %s", item.Kind, item.Name))
+		result.Deleted = append(result.Deleted, item)
+	}
+
+	ui.Result(c.ui, fmt.Sprintf(
+		"Pruned %s, %s.",
+		pluralize(len(result.Deleted), "item", "items"),
+		summarySize(result.Size())))
+	return result, nil
+}
+
+// pruneCacheItem deletes a single cache item, returning false if it is
+// in use. App caches are locked while they're deleted so that a Dev that
+// starts meanwhile waits for the deletion to complete.
+func (c *Core) pruneCacheItem(item *CacheItem) (bool, error) {
+	if item.Kind == "app" {
+		lock, err := c.lockAppCache(item.Name, true, false)
+		if err == errCacheLocked {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		defer lock.Close()
+	}
+
+	return true, os.RemoveAll(item.Path)
+}
+
+// lockAppCache locks the cache of the app with the given ID. Operations
+// that use the cache, like Dev, hold a shared lock while they run, and
+// pruning the cache takes an exclusive lock. See lockFile.
+func (c *Core) lockAppCache(id string, exclusive, wait bool) (*os.File, error) {
+	dir := filepath.Join(c.dataDir, "cache", ".locks")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return lockFile(filepath.Join(dir, id+".lock"), exclusive, wait)
+}
+
+// lockAppCaches takes a shared lock on the caches of all the apps
+// in the Appfile so they aren't pruned while they're used. The returned
+// function releases the locks.
+func (c *Core) lockAppCaches() (func(), error) {
+	var locks []*os.File
+	unlock := func() {
+		for _, l := range locks {
+			l.Close()
+		}
+	}
+
+	ids := make([]string, 0, len(c.appfileCompiled.Graph.Vertices()))
+	for _, raw := range c.appfileCompiled.Graph.Vertices() {
+		ids = append(ids, raw.(*appfile.CompiledGraphVertex).File.ID)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		lock, err := c.lockAppCache(id, false, true)
+		if err != nil {
+			unlock()
+			return nil, fmt.Errorf("Error locking the cache: %s", err)
+		}
+
+		locks = append(locks, lock)
+	}
+
+	return unlock, nil
+}
@@ -0,0 +1,37 @@
+// +build darwin freebsd linux netbsd openbsd
+
+package otto
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile locks the file at path, creating it if it doesn't exist. The
+// lock is released by closing the returned file. If wait is false and the
+// file is locked by another process, errCacheLocked is returned.
+func lockFile(path string, exclusive, wait bool) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	if !wait {
+		how |= syscall.LOCK_NB
+	}
+
+	if err := syscall.Flock(int(f.Fd()), how); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, errCacheLocked
+		}
+
+		return nil, err
+	}
+
+	return f, nil
+}
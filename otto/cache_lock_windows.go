@@ -0,0 +1,53 @@
+// +build windows
+
+package otto
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	modkernel32    = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx = modkernel32.NewProc("LockFileEx")
+)
+
+const (
+	lockfileFailImmediately = 1
+	lockfileExclusiveLock   = 2
+
+	errLockViolation syscall.Errno = 0x21
+)
+
+// lockFile locks the file at path, creating it if it doesn't exist. The
+// lock is released by closing the returned file. If wait is false and the
+// file is locked by another process, errCacheLocked is returned.
+func lockFile(path string, exclusive, wait bool) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	var flags uint32
+	if exclusive {
+		flags |= lockfileExclusiveLock
+	}
+	if !wait {
+		flags |= lockfileFailImmediately
+	}
+
+	ol := new(syscall.Overlapped)
+	r, _, err := procLockFileEx.Call(
+		f.Fd(), uintptr(flags), 0, 1, 0, uintptr(unsafe.Pointer(ol)))
+	if r == 0 {
+		f.Close()
+		if err == errLockViolation {
+			return nil, errCacheLocked
+		}
+
+		return nil, err
+	}
+
+	return f, nil
+}
@@ -0,0 +1,293 @@
+package otto
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/helper/installdir"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreCacheInfo(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	core := testCore(t, coreConfig)
+	if _, err := core.appContext(core.appfile); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Another project and the cached credentials
+	testDevDepDir(t, filepath.Join(coreConfig.DataDir, "cache", "other"), "hello")
+	testDevDepDir(t, filepath.Join(coreConfig.DataDir, "cache", "creds"), "secret")
+
+	info, err := core.CacheInfo()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(info.Apps) != 2 {
+		t.Fatalf("bad: %#v", info.Apps)
+	}
+	for _, item := range info.Apps {
+		if item.Current != (item.Name == core.appfile.ID) {
+			t.Fatalf("bad: %#v", item)
+		}
+		if item.Name == "other" && item.Size == 0 {
+			t.Fatalf("bad: %#v", item)
+		}
+	}
+	if info.AddrDBSize == 0 {
+		t.Fatal("should have the IP database size")
+	}
+}
+
+func TestCorePruneCache(t *testing.T) {
+	uiMock := new(ui.Mock)
+	uiMock.AddAnswer("^cache_prune_confirm$", "yes")
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	core := testCore(t, coreConfig)
+	if _, err := core.appContext(core.appfile); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cacheDir := filepath.Join(coreConfig.DataDir, "cache")
+	for _, id := range []string{"other", "locked", "creds"} {
+		testDevDepDir(t, filepath.Join(cacheDir, id), "hello")
+	}
+
+	// A Dev of another project is running
+	lock, err := core.lockAppCache("locked", false, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer lock.Close()
+
+	// An old and a new dev dependency
+	store := &devDepStore{Dir: core.devDepStore().Dir}
+	dep := &app.DevDep{Files: []string{"data/file"}}
+	for _, fp := range []string{"old", "new"} {
+		src := testDevDepDir(t, filepath.Join(coreConfig.DataDir, fp), "hello")
+		if err := store.Put(fp, src, dep); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	used := time.Now().Add(-2 * DefaultLeaseAge)
+	if err := os.Chtimes(filepath.Join(store.Dir, "old"), used, used); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	result, err := core.PruneCache(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !uiMock.InputRequested("cache_prune_confirm") {
+		t.Fatal("should confirm")
+	}
+
+	deleted := make(map[string]struct{})
+	for _, item := range result.Deleted {
+		deleted[item.Name] = struct{}{}
+	}
+	if len(deleted) != 2 {
+		t.Fatalf("bad: %#v", deleted)
+	}
+	for _, name := range []string{"other", "old"} {
+		if _, ok := deleted[name]; !ok {
+			t.Fatalf("bad: %#v", deleted)
+		}
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].Name != "locked" {
+		t.Fatalf("bad: %#v", result.Skipped)
+	}
+
+	for _, path := range []string{
+		filepath.Join(cacheDir, core.appfile.ID),
+		filepath.Join(cacheDir, "locked"),
+		filepath.Join(cacheDir, "creds"),
+		filepath.Join(store.Dir, "new"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "other")); !os.IsNotExist(err) {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestCorePruneCache_all(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Force = true
+	core := testCore(t, coreConfig)
+	if _, err := core.appContext(core.appfile); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	result, err := core.PruneCache(&PruneCacheOpts{All: true})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(result.Deleted) != 1 || result.Deleted[0].Name != core.appfile.ID {
+		t.Fatalf("bad: %#v", result.Deleted)
+	}
+}
+
+func TestCorePruneCache_binariesGlobalData(t *testing.T) {
+	uiMock := new(ui.Mock)
+	uiMock.AddAnswer("^cache_prune_confirm$", "yes")
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	core := testCore(t, coreConfig)
+	if _, err := core.appContext(core.appfile); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Binaries required by an infrastructure the core has, by one it
+	// doesn't have, and by nothing known.
+	binariesDir := filepath.Join(coreConfig.DataDir, "binaries")
+	for name, requiredBy := range map[string]string{
+		"terraform": "infra:test",
+		"packer":    "infra:gone",
+		"vagrant":   "",
+	} {
+		dir := testDevDepDir(t, filepath.Join(binariesDir, name), "bin")
+		if requiredBy == "" {
+			continue
+		}
+
+		manifest := &installdir.Manifest{Name: name}
+		manifest.Require(requiredBy)
+		if err := installdir.WriteManifest(dir, manifest); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// Old and new global data
+	globalDir := filepath.Join(coreConfig.DataDir, "global-data")
+	for _, name := range []string{"old", "new"} {
+		testDevDepDir(t, filepath.Join(globalDir, name), "data")
+	}
+	modified := time.Now().Add(-2 * DefaultLeaseAge)
+	filepath.Walk(filepath.Join(globalDir, "old"), func(path string, _ os.FileInfo, _ error) error {
+		return os.Chtimes(path, modified, modified)
+	})
+
+	info, err := core.CacheInfo()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(info.Binaries) != 3 || len(info.GlobalData) != 2 {
+		t.Fatalf("bad: %#v", info)
+	}
+	for _, item := range info.Binaries {
+		if item.Current != (item.Name != "packer") || item.Size == 0 {
+			t.Fatalf("bad: %#v", item)
+		}
+	}
+	if item := info.GlobalData[1]; item.Name != "old" || !item.LastUsed.Equal(modified) {
+		t.Fatalf("bad: %#v", item)
+	}
+
+	// Global data is only pruned by age if asked to
+	result, err := core.PruneCache(&PruneCacheOpts{GlobalDataAge: DefaultLeaseAge})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	deleted := make(map[string]struct{})
+	for _, item := range result.Deleted {
+		deleted[item.Kind+" "+item.Name] = struct{}{}
+	}
+	if len(deleted) != 2 {
+		t.Fatalf("bad: %#v", deleted)
+	}
+	for _, name := range []string{"binaries packer", "global-data old"} {
+		if _, ok := deleted[name]; !ok {
+			t.Fatalf("bad: %#v", deleted)
+		}
+	}
+
+	for _, path := range []string{
+		filepath.Join(binariesDir, "terraform"),
+		filepath.Join(binariesDir, "vagrant"),
+		filepath.Join(globalDir, "new"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+}
+
+func TestCoreCacheDir_compile(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The app may compile its cache directory into its files, so Dev
+	// must see the same one.
+	expected := core.appCacheDir(core.appfile.ID)
+	if dir := appMock.CompileContext.CacheDir; dir != expected {
+		t.Fatalf("bad: %s", dir)
+	}
+	if dir := appMock.DevContext.CacheDir; dir != expected {
+		t.Fatalf("bad: %s", dir)
+	}
+}
+
+func TestCoreCacheDir_staleDevDep(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The dependency is cached for this compilation
+	id := "afb66054-75d6-43ed-b47b-23d0bace94a8"
+	cacheDir := testCachedDevDep(t, core, id, "hello")
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if appMock.DevDepCalled {
+		t.Fatal("DevDep should not be called")
+	}
+
+	// A new version of the app compiles differently, so the cached
+	// dependency isn't used, but the cache directory stays the same.
+	appMock.CompileResult = &app.CompileResult{Version: 2}
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	appMock.DevDepResult = &app.DevDep{Files: []string{"data/file"}}
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.DevDepCalled {
+		t.Fatal("DevDep should be called")
+	}
+	if dir := appMock.DevDepContextSrc.CacheDir; dir != cacheDir {
+		t.Fatalf("bad: %s", dir)
+	}
+
+	// The rebuilt dependency is cached for the new compilation
+	appMock.DevDepCalled = false
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if appMock.DevDepCalled {
+		t.Fatal("DevDep should not be called")
+	}
+}
@@ -1,10 +1,14 @@
 package otto
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/otto/app"
 	"github.com/hashicorp/otto/foundation"
 	"github.com/hashicorp/otto/infrastructure"
@@ -30,10 +34,97 @@ type CompileMetadata struct {
 
 	// Foundations is the listing of top-level foundation compilation results.
 	Foundations map[string]*foundation.CompileResult `json:"foundations"`
+
+	// Timings are the timings of every unit that was compiled, in the
+	// order that they completed.
+	//
+	// Duration is the wall time of the entire compilation and Parallelism
+	// is the most apps that were compiled at the same time.
+	Timings     []*CompileTiming `json:"timings,omitempty"`
+	Duration    time.Duration    `json:"duration,omitempty"`
+	Parallelism int              `json:"parallelism,omitempty"`
+
+	// AppfileHash is the hash of the Appfile that was compiled, which
+	// tells if the Appfile changed since. This is empty for metadata
+	// stored by older versions of Otto.
+	AppfileHash string `json:"appfile_hash,omitempty"`
+}
+
+// CompileTiming is the timing information for a single compiled unit.
+type CompileTiming struct {
+	// Kind is the kind of unit: "infra", "foundation", "app" (the root
+	// application), or "dependency".
+	Kind string `json:"kind"`
+
+	// Name is the name of the unit
+	Name string `json:"name"`
+
+	// Duration is how long the compilation took and Warnings is the
+	// number of warnings that were output during the compilation.
+	Duration time.Duration `json:"duration"`
+	Warnings int           `json:"warnings"`
+
+	// Steps are the timings of the parts of compiling an app: compiling
+	// each foundation for the app (kind "foundation") and the app itself
+	// (the kind of the app).
+	Steps []*CompileTiming `json:"steps,omitempty"`
 }
 
+// CompileOpts are the options for Core.CompileWithOpts.
+type CompileOpts struct {
+	// SerialWalk, if true, compiles the applications one at a time in a
+	// stable order for this compilation, as if CoreConfig.SerialWalk were
+	// set.
+	SerialWalk bool
+}
+
+// CompileMetadata returns the metadata of the last successful Compile.
+// This returns an error matching ErrNoCompileMetadata if the Appfile was
+// never compiled, and the metadata along with an error matching
+// ErrStaleCompile if the Appfile changed since.
+func (c *Core) CompileMetadata() (*CompileMetadata, error) {
+	md, err := c.compileMetadata()
+	if err != nil {
+		return nil, errwrap.Wrapf("Error loading compilation metadata: {{err}}", err)
+	}
+	if md == nil {
+		return nil, &HintedError{
+			Err:    ErrNoCompileMetadata,
+			Hint:   "Run `otto compile` first.",
+			DocKey: "compile",
+		}
+	}
+	if md.AppfileHash != "" && md.AppfileHash != c.appfileHash() {
+		return md, &HintedError{
+			Err:    ErrStaleCompile,
+			Hint:   "Run `otto compile` again to recompile the Appfile.",
+			DocKey: "compile",
+		}
+	}
+
+	return md, nil
+}
+
+// appfileHash returns the hash of the root Appfile for CompileMetadata.
+// The path isn't part of it, so that moving the project doesn't change it.
+func (c *Core) appfileHash() string {
+	copy := *c.appfile
+	copy.Path = ""
+
+	data, err := json.Marshal(&copy)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// resetCompileMetadata clears the cached compilation metadata and the
+// app contexts built from it.
 func (c *Core) resetCompileMetadata() {
 	c.metadataCache = nil
+	c.resetAppContexts()
 }
 
 func (c *Core) compileMetadata() (*CompileMetadata, error) {
@@ -73,5 +164,10 @@ func (c *Core) saveCompileMetadata(md *CompileMetadata) error {
 	defer f.Close()
 
 	enc := json.NewEncoder(f)
-	return enc.Encode(md)
+	if err := enc.Encode(md); err != nil {
+		return err
+	}
+
+	c.resetCompileMetadata()
+	return nil
 }
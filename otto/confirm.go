@@ -0,0 +1,263 @@
+package otto
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+// errDestroyCancelled is returned by confirm when the user doesn't
+// confirm the action.
+var errDestroyCancelled = errors.New("Destroy cancelled.")
+
+// ErrDeployDeclined is returned by Deploy when it asks to confirm the
+// deploy with DeployOpts.Confirm and the user doesn't confirm it.
+var ErrDeployDeclined = errors.New("Deploy cancelled.")
+
+// confirmOpts are the options for confirm.
+type confirmOpts struct {
+	// Id is the id of the input that asks for confirmation.
+	Id string
+
+	// Message describes what will be destroyed and Details are lines
+	// listing specifics, such as names and counts.
+	Message string
+	Details []string
+
+	// Expected is the value that the user must type to confirm. This
+	// defaults to "yes". High-risk actions should use a name instead.
+	Expected string
+
+	// Args are the arguments to the action. If these contain "-force",
+	// the confirmation is skipped.
+	Args []string
+}
+
+// confirm asks the user to confirm a destructive action. It returns nil
+// if the action is confirmed or forced, errDestroyCancelled if the user
+// didn't confirm, and any other error if confirmation couldn't be asked.
+//
+// Running non-interactively without forcing the action is an error.
+func (c *Core) confirm(opts *confirmOpts) error {
+	if c.force {
+		return nil
+	}
+	for _, arg := range opts.Args {
+		if arg == "-force" {
+			return nil
+		}
+	}
+
+	expected := opts.Expected
+	if expected == "" {
+		expected = "yes"
+	}
+
+	if c.nonInteractive {
+		return fmt.Errorf(
+			"%s\n\n"+
+				"This requires confirmation and Otto is running non-interactively.\n"+
+				"Use the -force flag to continue without confirmation.",
+			opts.Message)
+	}
+
+	var desc bytes.Buffer
+	desc.WriteString(opts.Message + "\n")
+	if len(opts.Details) > 0 {
+		desc.WriteString("\n")
+		for _, d := range opts.Details {
+			desc.WriteString("  " + d + "\n")
+		}
+		desc.WriteString("\n")
+	}
+	fmt.Fprintf(&desc,
+		"There is no undo. Only '%s' will be accepted to confirm.", expected)
+
+	v, err := c.ui.Input(&ui.InputOpts{
+		Id:          opts.Id,
+		Query:       "Do you really want to destroy?",
+		Description: desc.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("Error asking for confirmation: %s", err)
+	}
+	if v != expected {
+		return errDestroyCancelled
+	}
+
+	return nil
+}
+
+// confirmDeploy shows what the deploy of the root app impl with the
+// context ctx is about to do and asks the user to confirm it. It returns
+// ErrDeployDeclined if the user doesn't confirm. Running non-interactively
+// requires DeployOpts.AutoApprove.
+func (c *Core) confirmDeploy(impl app.App, ctx *app.Context, opts *DeployOpts) error {
+	var plan string
+	if p, ok := impl.(app.Planner); ok {
+		var err error
+		plan, err = p.Plan(ctx)
+		if err != nil && err != app.ErrPlanNotSupported {
+			return errwrap.Wrapf("Error planning the deploy: {{err}}", err)
+		}
+	}
+
+	c.ui.Header("Deploy plan")
+	ui.Info(c.ui, c.deployPlanTable(ctx, opts).String())
+	if plan = strings.TrimSpace(plan); plan != "" {
+		c.ui.Message("")
+		c.ui.Raw(plan + "\n")
+	}
+
+	return c.approveDeploy("the application", opts.AutoApprove)
+}
+
+// approveDeploy asks the user to confirm the deploy of what, which was
+// just shown. It returns ErrDeployDeclined if the user doesn't confirm.
+func (c *Core) approveDeploy(what string, autoApprove bool) error {
+	if autoApprove || c.force {
+		return nil
+	}
+	if c.nonInteractive {
+		return fmt.Errorf(
+			"The deploy requires confirmation and Otto is running non-interactively.\n" +
+				"Use the -auto-approve flag to deploy without confirmation.")
+	}
+
+	v, err := c.ui.Input(&ui.InputOpts{
+		Id:    "deploy_confirm",
+		Query: "Do you want to deploy?",
+		Description: fmt.Sprintf("Otto will deploy %s as shown above.\n", what) +
+			"Only 'yes' will be accepted to confirm.",
+	})
+	if err != nil {
+		return fmt.Errorf("Error asking for confirmation: %s", err)
+	}
+	if v != "yes" {
+		return ErrDeployDeclined
+	}
+
+	return nil
+}
+
+// deployPlanTable returns the table of what a deploy of the application
+// in ctx is about to do, for confirmDeploy. What can't be looked up from
+// the directory is omitted.
+func (c *Core) deployPlanTable(ctx *app.Context, opts *DeployOpts) *ui.Table {
+	infra := c.appfile.ActiveInfrastructure()
+	table := &ui.Table{MaxWidth: ui.TerminalWidth()}
+	table.AddRow("Application:", ctx.Appfile.Application.Name)
+	table.AddRow("Infrastructure:", fmt.Sprintf(
+		"%s (%s, flavor %s)", infra.Name, infra.Type, infra.Flavor))
+	table.AddRow("Environment:", c.environment)
+	if opts.Strategy != "" {
+		table.AddRow("Strategy:", string(opts.Strategy))
+	}
+
+	lookup := c.deployLookup(ctx.Appfile.ID)
+	build, err := c.dir.GetBuild(&directory.Build{Lookup: lookup})
+	if err != nil {
+		c.logger.Warn("error looking up build for plan", "err", err)
+	}
+	if build != nil {
+		table.AddRow("Artifact:", artifactText(build.Artifact))
+	}
+
+	last, err := c.lastDeploy()
+	if err != nil {
+		c.logger.Warn("error looking up last deploy for plan", "err", err)
+	}
+	switch {
+	case last != nil:
+		text := fmt.Sprintf("%s (%s ago)",
+			last.DeployedAt.Local().Format(time.RFC1123),
+			summaryDuration(time.Since(last.DeployedAt)))
+		if last.Build != "" {
+			text += fmt.Sprintf(", build %s", last.Build)
+		}
+		table.AddRow("Last deploy:", text)
+	case err == nil:
+		table.AddRow("Last deploy:", "never")
+	}
+
+	deploy, err := c.dir.GetDeploy(&directory.Deploy{Lookup: lookup})
+	if err != nil {
+		c.logger.Warn("error looking up deploy for plan", "err", err)
+	}
+	if deploy.IsDeployed() {
+		report, err := c.healthReport()
+		if err != nil {
+			c.logger.Warn("error looking up health for plan", "err", err)
+		}
+		if report != nil {
+			table.AddRow("Health:", fmt.Sprintf("%s (checked %s ago)",
+				healthStatusText(report.Status),
+				summaryDuration(time.Since(report.CheckedAt))))
+		}
+	}
+
+	return table
+}
+
+// lastDeployRecord is the record of the latest successful deploy with the
+// default action, which is stored in the directory.
+type lastDeployRecord struct {
+	DeployedAt time.Time
+
+	// Build is the name of the build that was deployed, if it has one
+	Build string `json:",omitempty"`
+}
+
+// lastDeployKey is the key of the blob in the directory with the record
+// of the latest deploy of the app on the active infrastructure and
+// environment.
+func (c *Core) lastDeployKey() string {
+	infra := c.appfile.ActiveInfrastructure()
+	return directory.EnvironmentKey(c.environment, fmt.Sprintf(
+		"last-deploy-%s-%s-%s", c.appfile.ID, infra.Type, infra.Flavor))
+}
+
+// lastDeploy returns the record of the latest deploy, or nil if there was
+// no deploy since Otto started recording them.
+func (c *Core) lastDeploy() (*lastDeployRecord, error) {
+	data, err := c.dir.GetBlob(c.lastDeployKey())
+	if err != nil {
+		return nil, directoryErr(err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+	defer data.Close()
+
+	var result lastDeployRecord
+	if err := json.NewDecoder(data.Data).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (c *Core) saveLastDeploy(r *lastDeployRecord) error {
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	err = c.dir.PutBlob(c.lastDeployKey(), &directory.BlobData{
+		Data: bytes.NewReader(raw),
+	})
+	if err != nil {
+		return errwrap.Wrapf(
+			"Error storing the deploy time: {{err}}", directoryErr(err))
+	}
+
+	return nil
+}
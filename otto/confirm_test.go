@@ -0,0 +1,171 @@
+package otto
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreConfirm(t *testing.T) {
+	cases := []struct {
+		Answer   string
+		Expected string
+		Args     []string
+		Err      error
+	}{
+		{"yes", "", nil, nil},
+		{"no", "", nil, errDestroyCancelled},
+		{"yes", "foo", nil, errDestroyCancelled},
+		{"foo", "foo", nil, nil},
+		{"", "", []string{"-force"}, nil},
+	}
+
+	for _, tc := range cases {
+		uiMock := new(ui.Mock)
+		uiMock.AddAnswer("^destroy$", tc.Answer)
+		coreConfig := TestCoreConfig(t)
+		coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+		coreConfig.Ui = uiMock
+		core := testCore(t, coreConfig)
+
+		err := core.confirm(&confirmOpts{
+			Id:       "destroy",
+			Message:  "Otto will destroy things.",
+			Details:  []string{"Things: 2"},
+			Expected: tc.Expected,
+			Args:     tc.Args,
+		})
+		if err != tc.Err {
+			t.Fatalf("%#v: bad: %v", tc, err)
+		}
+
+		if tc.Args == nil {
+			if !strings.Contains(uiMock.InputOpts.Description, "Things: 2") {
+				t.Fatalf("bad: %#v", uiMock.InputOpts)
+			}
+		} else if uiMock.InputCalled {
+			t.Fatal("input should not be called")
+		}
+	}
+}
+
+func TestCoreConfirm_nonInteractive(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.NonInteractive = true
+	core := testCore(t, coreConfig)
+
+	err := core.confirm(&confirmOpts{Id: "destroy", Message: "Destroy."})
+	if err == nil || !strings.Contains(err.Error(), "non-interactively") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Forcing works non-interactively
+	coreConfig.Force = true
+	core = testCore(t, coreConfig)
+	if err := core.confirm(&confirmOpts{Id: "destroy"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestCoreDeploy_destroyCancelled(t *testing.T) {
+	uiMock := new(ui.Mock)
+	uiMock.AddAnswer("^destroy$", "yes")
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+
+	// Deploys require the application name, not just "yes"
+	if err := core.Deploy("destroy", nil); err != errDestroyCancelled {
+		t.Fatalf("bad: %v", err)
+	}
+	if appMock.DeployCalled {
+		t.Fatal("deploy should not be called")
+	}
+}
+
+func TestCoreDeploy_confirm(t *testing.T) {
+	uiMock := new(ui.Mock)
+	uiMock.AddAnswer("^creds_password$", "hunter2")
+	uiMock.InputResult = "no"
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	appMock := new(app.MockPlanner)
+	appMock.PlanResult = "+ aws_instance.app"
+	coreConfig.Apps[TestAppTuple] = func() (app.App, error) {
+		return appMock, nil
+	}
+	core := testCore(t, coreConfig)
+
+	// Declining doesn't deploy
+	opts := &DeployOpts{Confirm: true}
+	if err := core.DeployWithOpts("", nil, opts); err != ErrDeployDeclined {
+		t.Fatalf("bad: %v", err)
+	}
+	if appMock.DeployCalled {
+		t.Fatal("deploy should not be called")
+	}
+	uiMock.AssertMessageContains(t, "Environment:")
+	uiMock.AssertMessageContains(t, "never")
+	if !appMock.PlanCalled || !strings.Contains(strings.Join(uiMock.RawBuf, ""), "+ aws_instance.app") {
+		t.Fatalf("bad: %#v", appMock.Calls)
+	}
+
+	// Confirming deploys and records the deploy time
+	uiMock.InputResult = "yes"
+	if err := core.DeployWithOpts("", nil, opts); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.DeployCalled {
+		t.Fatal("deploy should be called")
+	}
+	last, err := core.lastDeploy()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if last == nil || last.DeployedAt.IsZero() {
+		t.Fatalf("bad: %#v", last)
+	}
+
+	// Without the option nothing is asked
+	uiMock.InputOpts = nil
+	if err := core.Deploy("", nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if uiMock.InputOpts != nil && uiMock.InputOpts.Id == "deploy_confirm" {
+		t.Fatal("confirmation should not be asked")
+	}
+}
+
+func TestCoreDeploy_confirmNonInteractive(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.NonInteractive = true
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+	defer os.Setenv("OTTO_CREDS_PASSWORD", os.Getenv("OTTO_CREDS_PASSWORD"))
+	os.Setenv("OTTO_CREDS_PASSWORD", "hunter2")
+
+	opts := &DeployOpts{Confirm: true}
+	err := core.DeployWithOpts("", nil, opts)
+	if err == nil || !strings.Contains(err.Error(), "-auto-approve") {
+		t.Fatalf("bad: %v", err)
+	}
+	if appMock.DeployCalled {
+		t.Fatal("deploy should not be called")
+	}
+
+	opts.AutoApprove = true
+	if err := core.DeployWithOpts("", nil, opts); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.DeployCalled {
+		t.Fatal("deploy should be called")
+	}
+}
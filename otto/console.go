@@ -0,0 +1,112 @@
+package otto
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+// ErrConsoleNoTerminal is returned by Console when the standard input or
+// output isn't a terminal, since the console is interactive.
+var ErrConsoleNoTerminal = errors.New(
+	"A console can only be opened from a terminal. The standard input\n" +
+		"and output must not be redirected.")
+
+// consoleTerminal returns whether the standard input and output are a
+// terminal. This is a variable for tests.
+var consoleTerminal = func() bool {
+	return ui.IsTerminal(os.Stdin) && ui.IsTerminal(os.Stdout)
+}
+
+// Console opens an interactive shell into the development environment or
+// onto the deployed application, depending on the target, and returns
+// once the shell exits. This requires the app type to implement
+// app.ConsoleProvider, and the standard input and output to be a
+// terminal.
+func (c *Core) Console(target app.ConsoleTarget) (err error) {
+	op := c.operation("console", map[string]string{"instance": target.Instance})
+	defer func() { op.End(err) }()
+
+	// Fail before anything is started if the console can't be used
+	if !consoleTerminal() {
+		return ErrConsoleNoTerminal
+	}
+
+	impl, ctx, err := c.App()
+	if err != nil {
+		return err
+	}
+	defer maybeClose(impl)
+
+	provider, ok := impl.(app.ConsoleProvider)
+	if !ok {
+		return c.consoleErr(app.ErrConsoleNotSupported)
+	}
+
+	if target.Dev {
+		dev, err := c.dir.GetDev(&directory.Dev{Lookup: directory.Lookup{
+			AppID: c.appfile.ID}})
+		if err != nil {
+			return fmt.Errorf(
+				"Error loading development status: %s", directoryErr(err))
+		}
+		if !dev.IsReady() {
+			return fmt.Errorf(
+				"The development environment of '%s' isn't running. Run\n"+
+					"`otto dev` to create it.",
+				c.appfile.Application.Name)
+		}
+	} else {
+		// Connecting to the deploy usually needs the credentials
+		infra, infraCtx, err := c.infra()
+		if err != nil {
+			return err
+		}
+		defer maybeClose(infra)
+		if err := c.creds(infra, infraCtx); err != nil {
+			return err
+		}
+		ctx.Shared.InfraCreds = infraCtx.Shared.InfraCreds
+
+		if err := c.deployedContext(ctx, "there is nothing to connect to"); err != nil {
+			return err
+		}
+	}
+
+	return c.consoleErr(provider.Console(ctx, target))
+}
+
+// executeConsole runs Console for Execute. The action is "dev" for the
+// development environment or empty for the deploy, and the first
+// argument, if any, is the instance.
+func (c *Core) executeConsole(opts *ExecuteOpts) error {
+	var target app.ConsoleTarget
+	switch opts.Action {
+	case "":
+	case "dev":
+		target.Dev = true
+	default:
+		return fmt.Errorf("unknown console action: %s", opts.Action)
+	}
+	if len(opts.Args) > 0 {
+		target.Instance = opts.Args[0]
+	}
+
+	return c.Console(target)
+}
+
+// consoleErr turns app.ErrConsoleNotSupported, which apps served over RPC
+// return, into an error that names the app type.
+func (c *Core) consoleErr(err error) error {
+	if err == app.ErrConsoleNotSupported {
+		return fmt.Errorf(
+			"Consoles are not supported by app type %q.",
+			c.appfile.Application.Type)
+	}
+
+	return err
+}
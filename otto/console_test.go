@@ -0,0 +1,97 @@
+package otto
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreConsole(t *testing.T) {
+	defer func(f func() bool) { consoleTerminal = f }(consoleTerminal)
+	consoleTerminal = func() bool { return true }
+
+	uiMock := new(ui.Mock)
+	uiMock.AddAnswer("^creds_password$", "hunter2")
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	appMock := new(app.MockConsoleProvider)
+	coreConfig.Apps[TestAppTuple] = func() (app.App, error) {
+		return appMock, nil
+	}
+	core := testCore(t, coreConfig)
+
+	// The app must be deployed
+	err := core.Console(app.ConsoleTarget{})
+	if err == nil || !strings.Contains(err.Error(), "isn't deployed") {
+		t.Fatalf("err: %v", err)
+	}
+
+	testDeployed(t, core, map[string]string{"url": "http://foo.com"})
+	target := app.ConsoleTarget{Instance: "i-1234"}
+	if err := core.Console(target); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if appMock.ConsoleTarget != target {
+		t.Fatalf("bad: %#v", appMock.ConsoleTarget)
+	}
+	if appMock.ConsoleContext.DeployInfo["url"] != "http://foo.com" {
+		t.Fatalf("bad: %#v", appMock.ConsoleContext)
+	}
+
+	// The dev environment must be running
+	err = core.Execute(&ExecuteOpts{Task: ExecuteTaskConsole, Action: "dev"})
+	if err == nil || !strings.Contains(err.Error(), "isn't running") {
+		t.Fatalf("err: %v", err)
+	}
+
+	dev := &directory.Dev{Lookup: directory.Lookup{AppID: core.appfile.ID}}
+	dev.MarkReady()
+	if err := core.dir.PutDev(dev); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	err = core.Execute(&ExecuteOpts{Task: ExecuteTaskConsole, Action: "dev"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.ConsoleTarget.Dev {
+		t.Fatalf("bad: %#v", appMock.ConsoleTarget)
+	}
+}
+
+func TestCoreConsole_noTerminal(t *testing.T) {
+	defer func(f func() bool) { consoleTerminal = f }(consoleTerminal)
+	consoleTerminal = func() bool { return false }
+
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	appMock := new(app.MockConsoleProvider)
+	coreConfig.Apps[TestAppTuple] = func() (app.App, error) {
+		return appMock, nil
+	}
+	core := testCore(t, coreConfig)
+
+	if err := core.Console(app.ConsoleTarget{Dev: true}); err != ErrConsoleNoTerminal {
+		t.Fatalf("err: %v", err)
+	}
+	if appMock.ConsoleCalled {
+		t.Fatal("console should not be called")
+	}
+}
+
+func TestCoreConsole_notSupported(t *testing.T) {
+	defer func(f func() bool) { consoleTerminal = f }(consoleTerminal)
+	consoleTerminal = func() bool { return true }
+
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	core := testCore(t, coreConfig)
+
+	err := core.Console(app.ConsoleTarget{Dev: true})
+	if err == nil || !strings.Contains(err.Error(), `app type "test"`) {
+		t.Fatalf("err: %v", err)
+	}
+}
@@ -3,20 +3,26 @@ package otto
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"net"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/otto/app"
 	"github.com/hashicorp/otto/appfile"
 	"github.com/hashicorp/otto/context"
 	"github.com/hashicorp/otto/directory"
 	"github.com/hashicorp/otto/foundation"
 	"github.com/hashicorp/otto/helper/localaddr"
+	"github.com/hashicorp/otto/helper/logger"
 	"github.com/hashicorp/otto/infrastructure"
 	"github.com/hashicorp/otto/ui"
 	"github.com/hashicorp/terraform/dag"
@@ -35,8 +41,61 @@ type Core struct {
 	localDir        string
 	compileDir      string
 	ui              ui.Ui
+	secrets         *ui.Redacted
+	warnings        *ui.WarningRecorder
+	timestamps      *ui.Timestamped
+	logger          logger.Logger
+	telemetry       Telemetry
+	eventSink       func(Event)
+	webhooks        []*Webhook
+	webhookDryRun   bool
+	logFiles        int
+	warningsAsErrs  bool
+	serialWalk      bool
+	opTimeouts      map[string]time.Duration
+	allowSkew       bool
+	environment     string
+	nonInteractive  bool
+	force           bool
+	devSubnet       *net.IPNet
+	devDepCacheSize int64
+	devDepSource    DevDepSource
+	interfaces      localaddr.InterfaceLister
+	plugins         []io.Closer
+	pluginInfos     []*PluginInfo
+	pluginOrigins   map[interface{}]string
+
+	// devIPLock protects the state for checking dev IP conflicts.
+	devIPLock    sync.Mutex
+	devIPChecked map[string]struct{}
+	devNetworks  []*net.IPNet
 
 	metadataCache *CompileMetadata
+
+	// appContexts caches the app contexts by Appfile ID during an
+	// operation. See cacheAppContexts.
+	appContextLock sync.Mutex
+	appContexts    map[string]*app.Context
+
+	artifactStore ArtifactStore
+	artifactName  string
+	recordName    string
+	extraEnv      map[string]string
+
+	// tempLock guards the temporary directories of the operations and of
+	// the Core. See scratchDir.
+	tempLock        sync.Mutex
+	opTempDir       string
+	coreTempDir     string
+	tempDirWarnSize int64
+
+	// projectLock guards the project lock of this Core. See lockProject.
+	projectLock      sync.Mutex
+	projectLockDepth int
+	projectLockOwner *projectLockOwner
+
+	// sourceLock guards the source index. See cachedSourceFingerprint.
+	sourceLock sync.Mutex
 }
 
 // CoreConfig is configuration for creating a new core with NewCore.
@@ -73,6 +132,182 @@ type CoreConfig struct {
 
 	// Ui is the Ui that will be used to communicate with the user.
 	Ui ui.Ui
+
+	// Verbosity is the minimum level of output that is sent to the Ui.
+	// The zero value outputs everything but debug messages.
+	//
+	// Quiet, if true, only outputs errors and the final result of each
+	// operation. This takes precedence over Verbosity.
+	Verbosity ui.Level
+	Quiet     bool
+
+	// DisableColor, if true, disables color output. See ui.DisableColor
+	// for how this affects the configured Ui.
+	DisableColor bool
+
+	// WarningsAsErrors, if true, fails any operation that outputs a
+	// warning, after the operation otherwise completes.
+	WarningsAsErrors bool
+
+	// TimestampOutput, if true, prefixes all output with the time and
+	// the time elapsed since the operation started.
+	TimestampOutput bool
+
+	// Logger is the logger for debugging information that isn't meant
+	// for the user. If this is nil, the standard library logger is used.
+	Logger logger.Logger
+
+	// LogFiles is the number of log files to keep in LocalDir/logs. Each
+	// operation writes its log and a copy of its output to a new file.
+	// If this is zero, no log files are written.
+	LogFiles int
+
+	// Telemetry, if set, records metrics about the operations that the
+	// Core performs.
+	Telemetry Telemetry
+
+	// EventSink, if set, is called with the lifecycle events of the
+	// operations that the Core performs. The sink is called synchronously
+	// and possibly concurrently, so it must be cheap and safe for
+	// concurrent use. A panic in the sink is logged and ignored.
+	EventSink func(Event)
+
+	// Webhooks are notified when a deploy or an infrastructure change
+	// completes.
+	//
+	// WebhookDryRun, if true, outputs the payloads that would be sent
+	// to the webhooks rather than sending them.
+	Webhooks      []*Webhook
+	WebhookDryRun bool
+
+	// NonInteractive, if true, fails any request for input that can't
+	// be answered from the environment rather than asking the user.
+	//
+	// InputTimeout, if non-zero, is the maximum time to wait for the
+	// user to answer a request for input.
+	NonInteractive bool
+	InputTimeout   time.Duration
+
+	// Force, if true, skips the confirmation before destructive actions
+	// such as destroying infrastructure. This is the same as passing
+	// the "-force" argument to the action.
+	Force bool
+
+	// DevSubnet is the subnet (in CIDR notation) that IP addresses for
+	// development environments are allocated from. This must be a private
+	// subnet that is at least a /24. If empty, 100.64.0.0/10 is used.
+	//
+	// Changing the subnet releases all the addresses that aren't in the
+	// new subnet and allocates new addresses the next time they're
+	// requested. Existing development environments keep the old address
+	// until they're reloaded or destroyed and recreated.
+	//
+	// Otto warns if the subnet overlaps a network that this machine is
+	// connected to.
+	DevSubnet string
+
+	// DevDepCacheSize is the maximum size in bytes of the global cache
+	// of dev dependencies, which is shared by all projects so that the
+	// same dependency is only built once. The least recently used
+	// dependencies are evicted when the cache is larger than this. If
+	// zero, DefaultDevDepCacheSize is used. If negative, the cache has
+	// no limit.
+	DevDepCacheSize int64
+
+	// DevDepSource, if set, is where prebuilt dev dependencies are
+	// fetched from when they aren't cached locally. If a dependency can't
+	// be fetched, it is built locally.
+	DevDepSource DevDepSource
+
+	// Plugins are the plugin processes that provide implementations in
+	// Apps, Infrastructures, and Foundations. They're shut down by
+	// Core.Close.
+	Plugins []io.Closer
+
+	// PluginOrigins are where the implementations in Apps,
+	// Infrastructures, and Foundations come from, such as the path of a
+	// plugin binary, keyed by the app.Tuple, the infrastructure type, or
+	// the foundation.Tuple. Implementations without an origin are
+	// in-process: they're shown as built in by Core.Plugins, and the API
+	// version of the ones that are APIVersioned is checked when they're
+	// loaded.
+	PluginOrigins map[interface{}]string
+
+	// PluginPaths are plugin binaries, or directories with plugin
+	// binaries, to load in addition to the ones in DataDir/plugins. See
+	// PluginGlob. Plugins in later paths take precedence over earlier
+	// ones.
+	//
+	// PreferPlugins, if true, makes the discovered plugins take
+	// precedence over the implementations in Apps, Infrastructures, and
+	// Foundations. By default, those take precedence.
+	//
+	// DisablePluginDiscovery, if true, doesn't load any plugins, such as
+	// when the plugins are already loaded and given in Plugins.
+	//
+	// RequirePluginChecksums, if true, refuses to run discovered plugins
+	// that have no checksum. A plugin "otto-plugin-foo" has a checksum if
+	// there is an "otto-plugin-foo.sha256" file next to it or it is
+	// listed in a "plugins.sha256" file in the same directory, both in
+	// the format of sha256sum. Plugins that don't match their checksum
+	// are never run.
+	PluginPaths            []string
+	PreferPlugins          bool
+	DisablePluginDiscovery bool
+	RequirePluginChecksums bool
+
+	// SerialWalk, if true, compiles and builds the applications in the
+	// Appfile one at a time in a stable order rather than in parallel,
+	// so that failures that depend on the order can be reproduced. This
+	// is meant for debugging. The order is logged when a walk starts.
+	SerialWalk bool
+
+	// OperationTimeouts are the maximum wall-clock times of operations,
+	// keyed by "compile", "build", "deploy", "dev", and "apply", which is
+	// Infra with no action. Other infrastructure actions use "infra".
+	// The deadline is checked between the phases of an operation, such
+	// as between apps or between foundations, and an operation past its
+	// deadline fails with ErrOperationTimeout. Operations that aren't in
+	// the map have no timeout.
+	OperationTimeouts map[string]time.Duration
+
+	// AllowPluginSkew, if true, uses the compiled files of an app even if
+	// they were compiled by a plugin with another major version than the
+	// one that is loaded, which otherwise is an error. The environment
+	// variable OTTO_ALLOW_PLUGIN_SKEW does the same.
+	AllowPluginSkew bool
+
+	// Environment is the environment that builds, deploys, and their
+	// health are stored for, so that the same Appfile can be deployed to
+	// more than one environment, such as "staging" and "production".
+	// This defaults to the environment variable OTTO_ENV, and otherwise
+	// to directory.DefaultEnvironment. The compilation is shared by every
+	// environment.
+	Environment string
+
+	// ArtifactStore, if set, is where the files that the builds of apps
+	// return are uploaded, so that they can be deployed from another
+	// machine. See app.ArtifactBuilder.
+	ArtifactStore ArtifactStore
+
+	// ArtifactNameTemplate and RecordNameTemplate are the naming
+	// templates of the files of builds in the artifact store and of the
+	// build and deploy records, for the Appfiles that have none in their
+	// project. See appfile.NameVars.
+	ArtifactNameTemplate string
+	RecordNameTemplate   string
+
+	// TempDirWarnSize is the size of the temporary directory of an
+	// operation, in bytes, above which a warning is output when it's
+	// removed. This defaults to DefaultTempDirWarnSize, and a negative
+	// size disables the warning. See context.Shared.TempDir.
+	TempDirWarnSize int64
+
+	// ExtraEnv are environment variables for the apps, the
+	// infrastructure, and the foundations, such as from the command line.
+	// These take precedence over the env of the Appfile. See
+	// context.Shared.Env.
+	ExtraEnv map[string]string
 }
 
 // NewCore creates a new core.
@@ -80,7 +315,68 @@ type CoreConfig struct {
 // Once this function is called, this CoreConfig should not be used again
 // or modified, since the Core may use parts of it without deep copying.
 func NewCore(c *CoreConfig) (*Core, error) {
-	return &Core{
+	coreLogger := c.Logger
+	if coreLogger == nil {
+		coreLogger = logger.Default()
+	}
+	telemetry := c.Telemetry
+	if telemetry == nil {
+		telemetry = nullTelemetry{}
+	}
+	secrets := new(ui.Redacted)
+	warnings := new(ui.WarningRecorder)
+	var timestamps *ui.Timestamped
+	if c.TimestampOutput {
+		timestamps = ui.NewTimestamped(nil, "")
+		timestamps.Elapsed = true
+	}
+
+	devSubnetRaw := c.DevSubnet
+	if devSubnetRaw == "" {
+		devSubnetRaw = localaddr.DefaultSubnet
+	}
+	devSubnet, err := localaddr.ParseSubnet(devSubnetRaw)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing dev subnet: %s", err)
+	}
+
+	environment := c.Environment
+	if environment == "" {
+		environment = os.Getenv("OTTO_ENV")
+	}
+	if environment == "" {
+		environment = directory.DefaultEnvironment
+	}
+	if !environmentRe.MatchString(environment) {
+		return nil, fmt.Errorf(
+			"Invalid environment %q. Environments may only contain letters,\n"+
+				"numbers, '_', and '-'.", environment)
+	}
+
+	names := []struct{ Key, Template string }{
+		{"artifact", c.ArtifactNameTemplate},
+		{"record", c.RecordNameTemplate},
+	}
+	for _, n := range names {
+		if n.Template == "" {
+			continue
+		}
+		if _, err := appfile.ParseNameTemplate(n.Template); err != nil {
+			return nil, fmt.Errorf(
+				"Error parsing the %s name template: %s", n.Key, err)
+		}
+	}
+
+	devDepCacheSize := c.DevDepCacheSize
+	if devDepCacheSize == 0 {
+		devDepCacheSize = DefaultDevDepCacheSize
+	}
+	tempDirWarnSize := c.TempDirWarnSize
+	if tempDirWarnSize == 0 {
+		tempDirWarnSize = DefaultTempDirWarnSize
+	}
+
+	core := &Core{
 		appfile:         c.Appfile.File,
 		appfileCompiled: c.Appfile,
 		apps:            c.Apps,
@@ -90,8 +386,115 @@ func NewCore(c *CoreConfig) (*Core, error) {
 		dataDir:         c.DataDir,
 		localDir:        c.LocalDir,
 		compileDir:      c.CompileDir,
-		ui:              c.Ui,
-	}, nil
+		ui:              newCoreUi(c, secrets, warnings, timestamps),
+		secrets:         secrets,
+		warnings:        warnings,
+		timestamps:      timestamps,
+		logger:          coreLogger.With("appfile", c.Appfile.File.ID),
+		telemetry:       telemetry,
+		eventSink:       c.EventSink,
+		webhooks:        c.Webhooks,
+		webhookDryRun:   c.WebhookDryRun,
+		logFiles:        c.LogFiles,
+		warningsAsErrs:  c.WarningsAsErrors,
+		serialWalk:      c.SerialWalk,
+		opTimeouts:      c.OperationTimeouts,
+		allowSkew:       c.AllowPluginSkew || os.Getenv("OTTO_ALLOW_PLUGIN_SKEW") != "",
+		environment:     environment,
+		nonInteractive:  c.NonInteractive,
+		force:           c.Force,
+		devSubnet:       devSubnet,
+		devDepCacheSize: devDepCacheSize,
+		devDepSource:    c.DevDepSource,
+		interfaces:      localaddr.InterfaceNetworks,
+		plugins:         c.Plugins,
+		artifactStore:   c.ArtifactStore,
+		artifactName:    c.ArtifactNameTemplate,
+		recordName:      c.RecordNameTemplate,
+		extraEnv:        c.ExtraEnv,
+		tempDirWarnSize: tempDirWarnSize,
+	}
+	core.loadPlugins(c)
+
+	return core, nil
+}
+
+// Close shuts down the plugins that the Core was configured with. The
+// Core can't use implementations from plugins after this is called, so
+// this should be deferred once the Core is no longer needed. It is safe
+// to call this multiple times.
+func (c *Core) Close() error {
+	var result error
+	for _, p := range c.plugins {
+		if err := p.Close(); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+	c.plugins = nil
+
+	// Remove the temporary directories, including the one of an
+	// operation that never ended, such as from a panic.
+	c.tempLock.Lock()
+	dirs := []string{c.opTempDir, c.coreTempDir}
+	c.opTempDir, c.coreTempDir = "", ""
+	c.tempLock.Unlock()
+	for _, dir := range dirs {
+		if dir != "" {
+			c.removeTempDir(dir)
+		}
+	}
+
+	return result
+}
+
+// newCoreUi wraps the Ui in the CoreConfig according to the output
+// and input settings in the configuration. All output is redacted
+// with the secrets in secrets, and all warnings are recorded in warnings.
+// If the output is timestamped, timestamps is the Ui that does it.
+func newCoreUi(c *CoreConfig, secrets *ui.Redacted, warnings *ui.WarningRecorder, timestamps *ui.Timestamped) ui.Ui {
+	if c.Ui == nil {
+		return nil
+	}
+
+	result := c.Ui
+	if c.DisableColor {
+		result = ui.DisableColor(result)
+	}
+	if c.InputTimeout > 0 {
+		result = &ui.InputTimeout{Ui: result, Timeout: c.InputTimeout}
+	}
+
+	// The Ui is shared by the goroutines of the parallel walk and status
+	// loading, and the configured Ui isn't required to be safe for
+	// concurrent use, so we always serialize access to it. This must
+	// be above InputTimeout so an abandoned input doesn't hold the lock.
+	result = ui.NewLocked(result)
+
+	if c.NonInteractive {
+		result = &ui.NonInteractive{Ui: result}
+	}
+
+	// Redact secrets above NonInteractive so hidden values that are
+	// answered from the environment are redacted too.
+	secrets.Ui = result
+	result = secrets
+
+	if timestamps != nil {
+		timestamps.Ui = result
+		result = timestamps
+	}
+
+	// Filter the output to the configured verbosity
+	if c.Quiet {
+		result = &ui.Quiet{Ui: result}
+	} else {
+		result = &ui.Filtered{Ui: result, Level: c.Verbosity}
+	}
+
+	// Record warnings outside of the filtering so that they're still
+	// counted when they're not shown.
+	warnings.Ui = result
+	return warnings
 }
 
 // App returns the app implementation and context for this configured Core.
@@ -104,13 +507,11 @@ func (c *Core) App() (app.App, *app.Context, error) {
 	}
 	rootCtx, err := c.appContext(root.(*appfile.CompiledGraphVertex).File)
 	if err != nil {
-		return nil, nil, fmt.Errorf(
-			"Error loading App: %s", err)
+		return nil, nil, errwrap.Wrapf("Error loading App: {{err}}", err)
 	}
 	rootApp, err := c.app(rootCtx)
 	if err != nil {
-		return nil, nil, fmt.Errorf(
-			"Error loading App: %s", err)
+		return nil, nil, errwrap.Wrapf("Error loading App: {{err}}", err)
 	}
 
 	return rootApp, rootCtx, nil
@@ -118,6 +519,39 @@ func (c *Core) App() (app.App, *app.Context, error) {
 
 // Compile takes the Appfile and compiles all the resulting data.
 func (c *Core) Compile() error {
+	return c.CompileWithOpts(nil)
+}
+
+// CompileWithOpts is Compile with options for this compilation only.
+func (c *Core) CompileWithOpts(opts *CompileOpts) (err error) {
+	if opts == nil {
+		opts = new(CompileOpts)
+	}
+
+	unlock, err := c.lockProject("compile", true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	start := time.Now()
+	c.startOutput()
+	endLog := c.startOpLog("compile")
+	op := c.operation("compile", nil)
+	defer c.cacheAppContexts()()
+	deadline := c.deadline("compile")
+	c.emit(&CompileStarted{EventInfo: c.eventInfo(c.appfile)})
+	defer func() {
+		err = c.finishWarnings(err)
+		op.End(err)
+		c.emit(&CompileFinished{
+			EventInfo: c.eventInfo(c.appfile),
+			Duration:  time.Since(start),
+			Err:       err,
+		})
+		endLog(err)
+	}()
+
 	// md stores the metadata about the compilation. This is only written
 	// on a successful compile.
 	var md CompileMetadata
@@ -139,8 +573,22 @@ func (c *Core) Compile() error {
 		defer maybeClose(f)
 	}
 
-	// Delete the prior output directory
-	log.Printf("[INFO] deleting prior compilation contents: %s", c.compileDir)
+	// Validate everything before anything expensive is compiled
+	if err := c.validateInfra(infra, infraCtx, foundations, foundationCtxs); err != nil {
+		return err
+	}
+
+	// The results of the previous compilation have the foundation
+	// configuration of the apps for the foundations compiled before them.
+	prevMd, err := c.compileMetadata()
+	if err != nil {
+		c.logger.Warn("error loading the previous compilation metadata", "err", err)
+		prevMd = nil
+	}
+
+	// Delete the prior output directory. The exclusive project lock keeps
+	// the operations that use the compilation from running meanwhile.
+	c.logger.Info("deleting prior compilation contents", "dir", c.compileDir)
 	if err := os.RemoveAll(c.compileDir); err != nil {
 		return err
 	}
@@ -149,35 +597,110 @@ func (c *Core) Compile() error {
 	c.resetCompileMetadata()
 
 	// Compile the infrastructure for our application
-	log.Printf("[INFO] running infra compile...")
-	c.ui.Message("Compiling infra...")
-	infraResult, err := infra.Compile(infraCtx)
+	if err := deadline.Check("infrastructure"); err != nil {
+		return err
+	}
+	c.logger.Info("running infra compile", "infra", infraCtx.Infra.Name)
+	ui.Info(c.ui, "Compiling infra...")
+	timing, err := c.timeCompile("infra", infraCtx.Infra.Name, nil, &infraCtx.Ui, func() error {
+		var err error
+		md.Infra, err = infra.Compile(infraCtx)
+		return err
+	})
 	if err != nil {
 		return err
 	}
-	md.Infra = infraResult
+	md.Timings = append(md.Timings, timing)
+	deadline.Done("infrastructure")
 
 	// Compile the foundation (not tied to any app). This compilation
 	// of the foundation is used for `otto infra` to set everything up.
-	log.Printf("[INFO] running foundation compilations")
+	c.logger.Info("running foundation compilations", "count", len(foundations))
 	md.Foundations = make(map[string]*foundation.CompileResult, len(foundations))
+	foundationProgress := ui.Progress(c.ui, "Foundations")
 	for i, f := range foundations {
 		ctx := foundationCtxs[i]
-		c.ui.Message(fmt.Sprintf(
+		phase := fmt.Sprintf("foundation '%s'", ctx.Tuple.Type)
+		if err := deadline.Check(phase); err != nil {
+			foundationProgress.Done()
+			return err
+		}
+
+		ui.Info(c.ui, fmt.Sprintf(
 			"Compiling foundation: %s", ctx.Tuple.Type))
-		result, err := f.Compile(ctx)
+		var result *foundation.CompileResult
+		attrs := map[string]string{"tuple": ctx.Tuple.String()}
+		timing, err := c.timeCompile("foundation", ctx.Tuple.Type, attrs, &ctx.Ui, func() error {
+			var err error
+			result, err = f.Compile(ctx)
+			return err
+		})
 		if err != nil {
+			foundationProgress.Done()
 			return err
 		}
 
+		if result == nil {
+			result = new(foundation.CompileResult)
+		}
+		result.Plugin = ctx.Tuple.String()
+		result.PluginVersion = pluginVersion(f)
 		md.Foundations[ctx.Tuple.Type] = result
+		md.Timings = append(md.Timings, timing)
+		deadline.Done(phase)
+		foundationProgress.Update(int64(i+1), int64(len(foundations)))
 	}
+	foundationProgress.Done()
 
 	// Walk through the dependencies and compile all of them.
 	// We have to compile every dependency for dev building.
 	var mdLock sync.Mutex
 	md.AppDeps = make(map[string]*app.CompileResult)
-	err = c.walk(func(app app.App, ctx *app.Context, root bool) error {
+	appProgress := c.walkProgress(
+		"Applications", len(c.appfileCompiled.Graph.Vertices()))
+	defer appProgress.Done()
+	var parallelism peakCounter
+	err = c.walk(c.serialWalk || opts.SerialWalk, func(app app.App, ctx *app.Context, root bool) (err error) {
+		defer appProgress.Increment()
+
+		phase := fmt.Sprintf("app '%s'", ctx.Appfile.Application.Name)
+		if err := deadline.Check(phase); err != nil {
+			return err
+		}
+		defer func() {
+			if err == nil {
+				deadline.Done(phase)
+			}
+		}()
+		parallelism.Enter()
+		defer parallelism.Exit()
+
+		// Record the timing of the entire compilation of this app
+		kind := "dependency"
+		if root {
+			kind = "app"
+		}
+		appOp := c.operation("compile."+kind, map[string]string{
+			"app":   ctx.Appfile.Application.Name,
+			"tuple": ctx.Tuple.String(),
+		})
+		appStart := time.Now()
+		defer func() {
+			appOp.End(err)
+			c.emit(&UnitCompiled{
+				EventInfo: c.eventInfo(ctx.Appfile),
+				Kind:      kind,
+				Name:      ctx.Appfile.Application.Name,
+				Duration:  time.Since(appStart),
+				Err:       err,
+			})
+		}()
+		warnUi := &warnCountUi{Ui: ctx.Ui}
+		ctx.Ui = warnUi
+		if err := c.validateApp(app, ctx); err != nil {
+			return err
+		}
+
 		if !root {
 			c.ui.Header(fmt.Sprintf(
 				"Compiling dependency '%s'...",
@@ -193,8 +716,9 @@ func (c *Core) Compile() error {
 			// root this should be serialized.
 			mdLock.Lock()
 			ctx.DevDepFragments = make([]string, 0, len(md.AppDeps))
-			for _, result := range md.AppDeps {
-				if result.DevDepFragmentPath != "" {
+			for _, id := range c.devDepOrder() {
+				result, ok := md.AppDeps[id]
+				if ok && result.DevDepFragmentPath != "" {
 					ctx.DevDepFragments = append(
 						ctx.DevDepFragments, result.DevDepFragmentPath)
 				}
@@ -202,47 +726,69 @@ func (c *Core) Compile() error {
 			mdLock.Unlock()
 		}
 
-		// Compile the foundations for this app
-		subdirs := []string{"app-dev", "app-dev-dep", "app-build", "app-deploy"}
-		for i, f := range foundations {
-			fCtx := foundationCtxs[i]
+		// Compile the foundations for this app. Each foundation is
+		// compiled before and after the app, and the time of both is
+		// recorded as a single step. Apps may be compiled concurrently, so
+		// each gets its own copy of the foundation contexts.
+		appConfig, err := c.provisionalFoundationConfig(app, ctx, prevMd)
+		if err != nil {
+			return err
+		}
+		appFoundationCtxs := make([]*foundation.Context, len(foundations))
+		for i := range foundations {
+			fCtx := *foundationCtxs[i]
 			fCtx.Dir = ctx.FoundationDirs[i]
+			fCtx.AppConfig = appConfig
+			appFoundationCtxs[i] = &fCtx
+		}
+		foundationTimes := make([]time.Duration, len(foundations))
+		for i, f := range foundations {
+			fCtx := appFoundationCtxs[i]
 
+			stepStart := time.Now()
 			if _, err := f.Compile(fCtx); err != nil {
 				return err
 			}
-
-			// Make sure the subdirs exist
-			for _, dir := range subdirs {
-				if err := os.MkdirAll(filepath.Join(fCtx.Dir, dir), 0755); err != nil {
-					return err
-				}
-			}
+			foundationTimes[i] += time.Since(stepStart)
 		}
 
 		// Compile!
+		stepStart := time.Now()
 		result, err := app.Compile(ctx)
 		if err != nil {
 			return err
 		}
+		appTime := time.Since(stepStart)
+		phases := foundation.AppPhases
+		if result != nil {
+			result.Plugin = ctx.Tuple.String()
+			result.PluginVersion = pluginVersion(app)
+
+			if err := validateFoundationPhases(result.FoundationPhases); err != nil {
+				return err
+			}
+			if len(result.FoundationPhases) == 0 {
+				result.FoundationPhases = copyStrings(foundation.AppPhases)
+			}
+			phases = result.FoundationPhases
+		}
 
 		// Compile the foundations for this app
 		for i, f := range foundations {
-			fCtx := foundationCtxs[i]
-			fCtx.Dir = ctx.FoundationDirs[i]
+			fCtx := appFoundationCtxs[i]
 			if result != nil {
 				fCtx.AppConfig = &result.FoundationConfig
+				fCtx.AppPhases = copyStrings(phases)
 			}
 
+			stepStart := time.Now()
 			if _, err := f.Compile(fCtx); err != nil {
 				return err
 			}
+			foundationTimes[i] += time.Since(stepStart)
 
-			// Make sure the subdirs exist
-			for _, dir := range subdirs {
-				if err := os.MkdirAll(filepath.Join(fCtx.Dir, dir), 0755); err != nil {
-					return err
-				}
+			if err := foundationPhaseDirs(fCtx.Dir, phases); err != nil {
+				return err
 			}
 		}
 
@@ -250,6 +796,27 @@ func (c *Core) Compile() error {
 		mdLock.Lock()
 		defer mdLock.Unlock()
 
+		steps := make([]*CompileTiming, 0, len(foundations)+1)
+		for i, d := range foundationTimes {
+			steps = append(steps, &CompileTiming{
+				Kind:     "foundation",
+				Name:     foundationCtxs[i].Tuple.Type,
+				Duration: d,
+			})
+		}
+		steps = append(steps, &CompileTiming{
+			Kind:     kind,
+			Name:     ctx.Appfile.Application.Name,
+			Duration: appTime,
+		})
+		md.Timings = append(md.Timings, &CompileTiming{
+			Kind:     kind,
+			Name:     ctx.Appfile.Application.Name,
+			Duration: time.Since(appStart),
+			Warnings: warnUi.Warnings(),
+			Steps:    steps,
+		})
+
 		if root {
 			md.App = result
 		} else {
@@ -266,19 +833,78 @@ func (c *Core) Compile() error {
 	}
 
 	// We had no compilation errors! Let's save the metadata
-	return c.saveCompileMetadata(&md)
+	md.AppfileHash = c.appfileHash()
+	md.Duration = time.Since(start)
+	md.Parallelism = parallelism.Peak()
+	if err := c.saveCompileMetadata(&md); err != nil {
+		return err
+	}
+
+	// The dependency edges let the apps that depend on a shared app be
+	// found from the directory. Nothing else needs them, so an error
+	// storing them is only a warning.
+	if err := c.storeAppGraph(); err != nil {
+		ui.Warn(c.ui, err.Error())
+	}
+
+	c.ui.Header("Compilation summary")
+	ui.Info(c.ui, compileSummary(&md))
+
+	deps := len(c.appfileCompiled.Graph.Vertices()) - 1
+	ui.Result(c.ui, fmt.Sprintf(
+		"Compiled 1 app, %s in %s",
+		pluralize(deps, "dependency", "dependencies"),
+		summaryDuration(time.Since(start))))
+	return nil
+}
+
+// walkProgress returns a progress counter for a walk that visits
+// total applications.
+func (c *Core) walkProgress(name string, total int) *walkProgress {
+	return &walkProgress{
+		Handle: ui.Progress(c.ui, name),
+		Total:  int64(total),
+	}
+}
+
+// walkProgress counts the completed vertices of a parallel walk.
+type walkProgress struct {
+	Handle ui.ProgressHandle
+	Total  int64
+
+	current int64
 }
 
-func (c *Core) walk(f func(app.App, *app.Context, bool) error) error {
+// Increment marks one more vertex as complete. This is safe to call
+// concurrently.
+func (p *walkProgress) Increment() {
+	p.Handle.Update(atomic.AddInt64(&p.current, 1), p.Total)
+}
+
+// Done completes the progress.
+func (p *walkProgress) Done() {
+	p.Handle.Done()
+}
+
+// walk calls f for every application in the Appfile graph, after the
+// applications it depends on. The applications are visited in parallel
+// unless serial is true, in which case they're visited one at a time in
+// the order of walkOrder so that the walk can be reproduced.
+func (c *Core) walk(serial bool, f func(app.App, *app.Context, bool) error) error {
 	root, err := c.appfileCompiled.Graph.Root()
 	if err != nil {
 		return fmt.Errorf(
 			"Error loading app: %s", err)
 	}
 
+	// If there is more than one application then the walk below will
+	// run in parallel, so we prefix the output of each application
+	// to keep it legible. The output is the same for serial walks.
+	prefixUi := len(c.appfileCompiled.Graph.Vertices()) > 1
+
 	// Walk the appfile graph.
 	var stop int32 = 0
-	return c.appfileCompiled.Graph.Walk(func(raw dag.Vertex) (err error) {
+	visit := func(raw dag.Vertex) (err error) {
 		// If we're told to stop (something else had an error), then stop early.
 		// Graphs walks by default will complete all disjoint parts of the
 		// graph before failing, but Otto doesn't have to do that.
@@ -297,31 +923,189 @@ func (c *Core) walk(f func(app.App, *app.Context, bool) error) error {
 		v := raw.(*appfile.CompiledGraphVertex)
 
 		// Do some logging to help ourselves out
-		log.Printf("[DEBUG] core walking app: %s", v.File.Application.Name)
+		ui.Debug(c.ui, fmt.Sprintf(
+			"Walking app: %s", v.File.Application.Name))
 
 		// Get the context and app for this appfile
 		appCtx, err := c.appContext(v.File)
 		if err != nil {
-			return fmt.Errorf(
-				"Error loading Appfile for '%s': %s",
-				dag.VertexName(raw), err)
+			return errwrap.Wrapf(fmt.Sprintf(
+				"Error loading Appfile for '%s': {{err}}", dag.VertexName(raw)), err)
 		}
 		app, err := c.app(appCtx)
 		if err != nil {
-			return fmt.Errorf(
-				"Error loading App implementation for '%s': %s",
-				dag.VertexName(raw), err)
+			return errwrap.Wrapf(fmt.Sprintf(
+				"Error loading App implementation for '%s': {{err}}", dag.VertexName(raw)), err)
 		}
 		defer maybeClose(app)
 
-		// Call our callback
-		return f(app, appCtx, raw == root)
-	})
+		// Prefix the output of the app if we have to
+		var prefixed *ui.Prefixed
+		if prefixUi {
+			prefixed = ui.NewPrefixed(appCtx.Ui, v.File.Application.Name)
+			defer prefixed.Flush()
+			appCtx.Ui = prefixed
+		}
+
+		// Call our callback. If the output is prefixed then we also
+		// report the error inline so it is clear which app failed. Note
+		// that we can't use appCtx.Ui here since a plugin call may have
+		// cleared it.
+		if err := f(app, appCtx, raw == root); err != nil {
+			if prefixed != nil {
+				ui.ErrorHint(prefixed,
+					fmt.Sprintf("Error: %s", err), ErrorHint(err))
+			}
+
+			return err
+		}
+
+		return nil
+	}
+	if !serial {
+		return c.appfileCompiled.Graph.Walk(visit)
+	}
+
+	order := c.walkOrder()
+	names := make([]string, len(order))
+	for i, v := range order {
+		names[i] = v.File.Application.Name
+	}
+	c.logger.Info("walking apps serially", "order", strings.Join(names, ", "))
+
+	// Errors are returned the same way as by the parallel walk. Once one
+	// app fails, the stop atomic makes the rest return immediately.
+	var result error
+	for _, v := range order {
+		if err := visit(v); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+
+	return result
+}
+
+// devDepOrder returns the IDs of all the dependencies of the root app in
+// the order of walkOrder.
+func (c *Core) devDepOrder() []string {
+	root, err := c.appfileCompiled.Graph.Root()
+	if err != nil {
+		return nil
+	}
+
+	var result []string
+	for _, v := range c.walkOrder() {
+		if v != root {
+			result = append(result, v.File.ID)
+		}
+	}
+
+	return result
+}
+
+// walkOrder returns the vertices of the Appfile graph in dependency graph
+// order: every app comes after the apps it depends on, so the root app is
+// last. Apps that don't depend on each other are ordered by name, then ID,
+// so the order is the same for every compilation.
+func (c *Core) walkOrder() []*appfile.CompiledGraphVertex {
+	graph := c.appfileCompiled.Graph
+	root, err := graph.Root()
+	if err != nil {
+		return nil
+	}
+
+	var result []*appfile.CompiledGraphVertex
+	visited := make(map[string]struct{})
+	var visit func(v *appfile.CompiledGraphVertex)
+	visit = func(v *appfile.CompiledGraphVertex) {
+		if _, ok := visited[v.File.ID]; ok {
+			return
+		}
+		visited[v.File.ID] = struct{}{}
+
+		raw := graph.DownEdges(v).List()
+		deps := make([]*appfile.CompiledGraphVertex, len(raw))
+		for i, d := range raw {
+			deps[i] = d.(*appfile.CompiledGraphVertex)
+		}
+		sort.Sort(vertexesByName(deps))
+		for _, d := range deps {
+			visit(d)
+		}
+
+		result = append(result, v)
+	}
+	visit(root.(*appfile.CompiledGraphVertex))
+
+	return result
+}
+
+// vertexesByName sorts vertexes of the Appfile graph by application
+// name, then ID.
+type vertexesByName []*appfile.CompiledGraphVertex
+
+func (s vertexesByName) Len() int      { return len(s) }
+func (s vertexesByName) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s vertexesByName) Less(i, j int) bool {
+	if s[i].File.Application.Name != s[j].File.Application.Name {
+		return s[i].File.Application.Name < s[j].File.Application.Name
+	}
+
+	return s[i].File.ID < s[j].File.ID
+}
+
+// BuildOpts are the options for Core.BuildWithOpts.
+type BuildOpts struct {
+	// Version is the version of the build for the naming templates, such
+	// as a release number or a commit.
+	Version string
+
+	// Action and Args are the sub-action of the build and its arguments,
+	// like the action of a deploy. Action is "" for the default build.
+	Action string
+	Args   []string
+
+	// Vars are free-form variables for the app, such as "tag" for the
+	// tag of an image. They're set as BuildVars on the context and are
+	// recorded in the build record.
+	Vars map[string]string
 }
 
 // Build builds the deployable artifact for the currently compiled
 // Appfile.
 func (c *Core) Build() error {
+	return c.BuildWithOpts(nil)
+}
+
+// BuildWithOpts is Build with options. The names of the build come from
+// the naming templates of the project and are resolved before the build
+// starts, so a version that makes an invalid name fails early.
+//
+// The build record is only labeled, and the files of the build only
+// uploaded, for the default action.
+func (c *Core) BuildWithOpts(opts *BuildOpts) (err error) {
+	if opts == nil {
+		opts = new(BuildOpts)
+	}
+
+	unlock, err := c.lockProject("build", false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	start := time.Now()
+	c.startOutput()
+	endLog := c.startOpLog("build")
+	op := c.operation("build", map[string]string{"action": opts.Action})
+	defer c.cacheAppContexts()()
+	deadline := c.deadline("build")
+	defer func() {
+		err = c.finishWarnings(err)
+		op.End(err)
+		endLog(err)
+	}()
+
 	// Get the infra implementation for this
 	infra, infraCtx, err := c.infra()
 	if err != nil {
@@ -340,20 +1124,48 @@ func (c *Core) Build() error {
 	}
 	rootCtx, err := c.appContext(root.(*appfile.CompiledGraphVertex).File)
 	if err != nil {
-		return fmt.Errorf(
-			"Error loading App: %s", err)
+		return errwrap.Wrapf("Error loading App: {{err}}", err)
 	}
 	rootApp, err := c.app(rootCtx)
 	if err != nil {
-		return fmt.Errorf(
-			"Error loading App: %s", err)
+		return errwrap.Wrapf("Error loading App: {{err}}", err)
 	}
 	defer maybeClose(rootApp)
+	if err := c.checkPluginSkew(rootApp, rootCtx); err != nil {
+		return err
+	}
 
 	// Just update our shared data so we get the creds
 	rootCtx.Shared.InfraCreds = infraCtx.Shared.InfraCreds
 
-	return rootApp.Build(rootCtx)
+	// Pass through the requested action and variables
+	rootCtx.Action = opts.Action
+	rootCtx.ActionArgs = opts.Args
+	rootCtx.BuildVars = opts.Vars
+
+	var names *buildNames
+	if opts.Action == "" {
+		names, err = c.buildNames(rootCtx, opts.Version)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := deadline.Check("build"); err != nil {
+		return err
+	}
+	if err := c.build(rootApp, rootCtx, names); err != nil {
+		return err
+	}
+	if opts.Action != "" {
+		return nil
+	}
+
+	ui.Result(c.ui, fmt.Sprintf(
+		"Built '%s' in %s",
+		rootCtx.Appfile.Application.Name,
+		summaryDuration(time.Since(start))))
+	return nil
 }
 
 // Deploy deploys the application.
@@ -361,6 +1173,103 @@ func (c *Core) Build() error {
 // Deploy supports subactions, which can be specified with action and args.
 // Action can be "" to get the default deploy behavior.
 func (c *Core) Deploy(action string, args []string) error {
+	return c.DeployWithOpts(action, args, nil)
+}
+
+// DeployOpts are the options for Core.DeployWithOpts.
+type DeployOpts struct {
+	// Health, if true, checks the health of the application after a deploy
+	// with the default action, like Core.Health. The health is checked
+	// until it is healthy or HealthGrace passes, after which the deploy
+	// fails as unhealthy.
+	Health      bool
+	HealthGrace time.Duration
+
+	// Strategy is how the default action deploys the new version, and
+	// CanarySteps are the percentages of the traffic that the canary
+	// strategy moves to the new version before it switches all of it.
+	// The strategies other than the standard one require the app type to
+	// implement the rollout interfaces of the app package. They always
+	// check the health between the steps, if the app type supports it.
+	Strategy    DeployStrategy
+	CanarySteps []int
+
+	// Resume, if true, resumes the unfinished rollout instead of starting
+	// a new deploy. See Core.RolloutStatus.
+	Resume bool
+
+	// Confirm, if true, shows what the default action is about to deploy
+	// and asks the user to confirm it before deploying. The deploy then
+	// fails with ErrDeployDeclined if it isn't confirmed. AutoApprove
+	// skips the question, which is required to confirm when running
+	// non-interactively.
+	Confirm     bool
+	AutoApprove bool
+}
+
+// DeployWithOpts is Deploy with options for this deploy only.
+func (c *Core) DeployWithOpts(action string, args []string, opts *DeployOpts) (err error) {
+	if opts == nil {
+		opts = new(DeployOpts)
+	}
+
+	// The help and info actions only read the local data
+	if action != "help" && action != "info" {
+		unlock, err := c.lockProject("deploy", false)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	start := time.Now()
+	c.startOutput()
+	endLog := c.startOpLog("deploy")
+	op := c.operation("deploy", map[string]string{"action": action})
+	defer c.cacheAppContexts()()
+	deadline := c.deadline("deploy")
+	c.emit(&DeployStarted{EventInfo: c.eventInfo(c.appfile), Action: action})
+	defer func() {
+		err = c.finishWarnings(err)
+		op.End(err)
+		c.emit(&DeployFinished{
+			EventInfo: c.eventInfo(c.appfile),
+			Action:    action,
+			Duration:  time.Since(start),
+			Err:       err,
+		})
+
+		switch {
+		case action == "" && err != ErrDeployDeclined:
+			c.notify("deploy", start, err)
+		case action == "destroy" && err != errDestroyCancelled:
+			c.notify("deploy-destroy", start, err)
+		}
+		endLog(err)
+	}()
+
+	// Destroying a deploy requires typing the application name
+	if action == "destroy" {
+		details := []string{
+			fmt.Sprintf("Application: %s", c.appfile.Application.Name),
+			fmt.Sprintf("Infrastructure: %s", c.appfile.Project.Infrastructure),
+		}
+		if url := c.deployURL(); url != "" {
+			details = append(details, fmt.Sprintf("URL: %s", url))
+		}
+
+		err := c.confirm(&confirmOpts{
+			Id:       "destroy",
+			Message:  "Otto will delete all resources associated with the deploy.",
+			Details:  details,
+			Expected: c.appfile.Application.Name,
+			Args:     args,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	// Get the infra implementation for this
 	infra, infraCtx, err := c.infra()
 	if err != nil {
@@ -385,15 +1294,16 @@ func (c *Core) Deploy(action string, args []string) error {
 	}
 	rootCtx, err := c.appContext(root.(*appfile.CompiledGraphVertex).File)
 	if err != nil {
-		return fmt.Errorf(
-			"Error loading App: %s", err)
+		return errwrap.Wrapf("Error loading App: {{err}}", err)
 	}
 	rootApp, err := c.app(rootCtx)
 	if err != nil {
-		return fmt.Errorf(
-			"Error loading App: %s", err)
+		return errwrap.Wrapf("Error loading App: {{err}}", err)
 	}
 	defer maybeClose(rootApp)
+	if err := c.checkPluginSkew(rootApp, rootCtx); err != nil {
+		return err
+	}
 
 	// Update our shared data so we get the creds
 	rootCtx.Shared.InfraCreds = infraCtx.Shared.InfraCreds
@@ -402,13 +1312,165 @@ func (c *Core) Deploy(action string, args []string) error {
 	rootCtx.Action = action
 	rootCtx.ActionArgs = args
 
-	return rootApp.Deploy(rootCtx)
+	if err := deadline.Check("deploy"); err != nil {
+		return err
+	}
+	// The deploy and its plan get the connection details of the deployed
+	// dependencies and the stored files of the build
+	if action == "" {
+		if err := c.depOutputs(root.(*appfile.CompiledGraphVertex), rootCtx); err != nil {
+			return err
+		}
+		if err := c.artifacts(rootCtx); err != nil {
+			return err
+		}
+	}
+
+	if action == "" && opts.Confirm {
+		if err := c.confirmDeploy(rootApp, rootCtx, opts); err != nil {
+			return err
+		}
+	}
+
+	rollout := false
+	if action == "" {
+		rollout, err = c.rollout(rootApp, rootCtx, opts)
+		if err != nil {
+			return err
+		}
+	}
+	if !rollout {
+		if err := rootApp.Deploy(rootCtx); err != nil {
+			return err
+		}
+	}
+	if action == "" && opts.Health && !rollout {
+		if err := c.waitHealthy(rootApp, rootCtx, opts.HealthGrace); err != nil {
+			return err
+		}
+	}
+	if action == "" {
+		if err := c.smokeTest(rootCtx); err != nil {
+			return err
+		}
+	}
+
+	// Only the default action is a deploy worth summarizing
+	if action == "" {
+		name, err := c.labelDeploy(rootCtx.Appfile.ID)
+		if err != nil {
+			c.logger.Warn("error labeling the deploy", "err", err)
+		}
+		err = c.saveLastDeploy(&lastDeployRecord{
+			DeployedAt: time.Now().UTC(),
+			Build:      name,
+		})
+		if err != nil {
+			c.logger.Warn("error storing the deploy time", "err", err)
+		}
+
+		duration := time.Since(start)
+		c.ui.Header("Deploy summary")
+		ui.Info(c.ui, c.deploySummary(rootCtx, duration).String())
+		ui.Result(c.ui, fmt.Sprintf(
+			"Deployed '%s' in %s",
+			rootCtx.Appfile.Application.Name,
+			summaryDuration(duration)))
+	}
+
+	return nil
 }
 
-// Dev starts a dev environment for the current application. For destroying
-// and other tasks against the dev environment, use the generic `Execute`
-// method.
-func (c *Core) Dev() error {
+// deploySummary returns the table summarizing a successful deploy of
+// the application in ctx. The artifact and URL are looked up from the
+// directory and are omitted if they can't be found.
+func (c *Core) deploySummary(ctx *app.Context, d time.Duration) *ui.Table {
+	table := &ui.Table{MaxWidth: ui.TerminalWidth()}
+	table.AddRow("Application:", ctx.Appfile.Application.Name)
+
+	lookup := c.deployLookup(ctx.Appfile.ID)
+	build, err := c.dir.GetBuild(&directory.Build{Lookup: lookup})
+	if err != nil {
+		c.logger.Warn("error looking up build for summary", "err", err)
+	}
+	if build != nil {
+		table.AddRow("Artifact:", artifactText(build.Artifact))
+	}
+
+	table.AddRow("Duration:", summaryDuration(d))
+
+	deploy, err := c.dir.GetDeploy(&directory.Deploy{Lookup: lookup})
+	if err != nil {
+		c.logger.Warn("error looking up deploy for summary", "err", err)
+	}
+	if deploy != nil && deploy.Deploy["url"] != "" {
+		table.AddRow("URL:", deploy.Deploy["url"])
+	}
+
+	return table
+}
+
+// artifactText returns the artifact of a build as "key=value" pairs
+// sorted by key, to show to the user.
+func artifactText(artifact map[string]string) string {
+	keys := make([]string, 0, len(artifact))
+	for k := range artifact {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for i, k := range keys {
+		keys[i] = fmt.Sprintf("%s=%s", k, artifact[k])
+	}
+
+	return strings.Join(keys, ", ")
+}
+
+// DevOpts are the options for Core.DevWithOpts.
+type DevOpts struct {
+	// SerialWalk, if true, builds the dependencies one at a time in a
+	// stable order for this call, as if CoreConfig.SerialWalk were set.
+	SerialWalk bool
+}
+
+// Dev starts a dev environment for the current application. For destroying
+// and other tasks against the dev environment, use the generic `Execute`
+// method.
+func (c *Core) Dev() error {
+	return c.DevWithOpts(nil)
+}
+
+// DevWithOpts is Dev with options for this call only.
+func (c *Core) DevWithOpts(opts *DevOpts) (err error) {
+	if opts == nil {
+		opts = new(DevOpts)
+	}
+
+	unlockProject, err := c.lockProject("dev", false)
+	if err != nil {
+		return err
+	}
+	defer unlockProject()
+
+	start := time.Now()
+	c.startOutput()
+	endLog := c.startOpLog("dev")
+	op := c.operation("dev", nil)
+	defer c.cacheAppContexts()()
+	deadline := c.deadline("dev")
+	defer func() {
+		err = c.finishWarnings(err)
+		op.End(err)
+		endLog(err)
+	}()
+
+	// The caches of the apps must not be pruned while they're used
+	unlock, err := c.lockAppCaches()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// We need to get the root data separately since we need that for
 	// all the function calls into the dependencies.
 	root, err := c.appfileCompiled.Graph.Root()
@@ -417,84 +1479,131 @@ func (c *Core) Dev() error {
 	}
 	rootCtx, err := c.appContext(root.(*appfile.CompiledGraphVertex).File)
 	if err != nil {
-		return fmt.Errorf(
-			"Error loading App: %s", err)
+		return errwrap.Wrapf("Error loading App: {{err}}", err)
 	}
 	rootApp, err := c.app(rootCtx)
 	if err != nil {
-		return fmt.Errorf(
-			"Error loading App: %s", err)
+		return errwrap.Wrapf("Error loading App: {{err}}", err)
 	}
 	defer maybeClose(rootApp)
+	if err := c.checkPluginSkew(rootApp, rootCtx); err != nil {
+		return err
+	}
 
 	// Go through all the dependencies and build their immutable
 	// dev environment pieces for the final configuration.
-	err = c.walk(func(appImpl app.App, ctx *app.Context, root bool) error {
+	var depIPLock sync.Mutex
+	depIPs := make(map[string]string)
+	depPorts := make(map[string][]int)
+	depFingerprints := make(map[string]string)
+	depProgress := c.walkProgress(
+		"Dependencies", len(c.appfileCompiled.Graph.Vertices())-1)
+	err = c.walk(c.serialWalk || opts.SerialWalk, func(appImpl app.App, ctx *app.Context, root bool) (err error) {
 		// If it is the root, we just return and do nothing else since
 		// the root is a special case where we're building the actual
 		// dev environment.
 		if root {
 			return nil
 		}
+		defer depProgress.Increment()
 
-		// Get the path to where we'd cache the dependency if we have
-		// cached it...
-		cachePath := filepath.Join(ctx.CacheDir, "dev-dep.json")
-
-		// Check if we've cached this. If so, then use the cache.
-		if _, err := app.ReadDevDep(cachePath); err == nil {
-			ctx.Ui.Header(fmt.Sprintf(
-				"Using cached dev dependency for '%s'",
-				ctx.Appfile.Application.Name))
-			return nil
+		phase := fmt.Sprintf("dependency '%s'", ctx.Appfile.Application.Name)
+		if err := deadline.Check(phase); err != nil {
+			return err
 		}
+		defer func() {
+			if err == nil {
+				deadline.Done(phase)
+			}
+		}()
 
-		// Copy the root context so it isn't modified by the call below
-		rootCtxCopy := *rootCtx
-
-		// Build the development dependency
-		log.Printf(
-			"[DEBUG] core: calling DevDep for '%s'",
-			ctx.Appfile.Application.Name)
-		dep, err := appImpl.DevDep(&rootCtxCopy, ctx)
-		if err != nil {
-			return fmt.Errorf(
-				"Error building dependency for dev '%s': %s",
-				ctx.Appfile.Application.Name,
-				err)
+		if err := c.checkPluginSkew(appImpl, ctx); err != nil {
+			return err
 		}
 
-		// If we have a dependency with files, then verify the files
-		// and store it in our cache directory so we can retrieve it
-		// later.
-		if dep != nil && len(dep.Files) > 0 {
-			if err := dep.RelFiles(ctx.CacheDir); err != nil {
-				return fmt.Errorf(
-					"Error caching dependency for dev '%s': %s",
-					ctx.Appfile.Application.Name,
-					err)
-			}
+		depStart := time.Now()
+		cached := false
+		defer func() {
+			c.emit(&DevDepBuilt{
+				EventInfo: c.eventInfo(ctx.Appfile),
+				Cached:    cached,
+				Duration:  time.Since(depStart),
+				Err:       err,
+			})
 
-			if err := app.WriteDevDep(cachePath, dep); err != nil {
-				return fmt.Errorf(
-					"Error caching dependency for dev '%s': %s",
-					ctx.Appfile.Application.Name,
-					err)
+			// Only the dependencies that are built are reachable from
+			// the dev environment.
+			if err == nil {
+				depIPLock.Lock()
+				defer depIPLock.Unlock()
+				depIPs[ctx.Appfile.Application.Name] = ctx.DevIPAddress
+				if len(ctx.DevPorts) > 0 {
+					depPorts[ctx.Appfile.Application.Name] = ctx.DevPorts
+				}
+				if fp, err := c.devDepFingerprint(ctx); err == nil {
+					depFingerprints[ctx.Appfile.Application.Name] = fp
+				}
 			}
-		}
+		}()
 
-		return nil
+		cached, err = c.devDep(appImpl, rootCtx, ctx)
+		return err
 	})
+	depProgress.Done()
 	if err != nil {
 		return err
 	}
 
 	// All the development dependencies are built/loaded. We now have
 	// everything we need to build the complete development environment.
-	log.Printf(
-		"[DEBUG] core: calling Dev for root app '%s'",
-		rootCtx.Appfile.Application.Name)
-	return rootApp.Dev(rootCtx)
+	rootCtx.DevDepAddresses = depIPs
+	rootCtx.DevDepPorts = depPorts
+	rootCtx.DevLayers, err = c.prepareDevLayers(rootCtx)
+	if err != nil {
+		return fmt.Errorf("Error preparing dev layers: %s", err)
+	}
+	if err := deadline.Check("dev environment"); err != nil {
+		return err
+	}
+	c.logger.Debug(
+		"calling Dev for root app", "app", rootCtx.Appfile.Application.Name)
+	if err := rootApp.Dev(rootCtx); err != nil {
+		return err
+	}
+
+	info := &DevInfo{
+		DepAddresses:    depIPs,
+		DepPorts:        depPorts,
+		CreatedAt:       time.Now().UTC(),
+		IPAddress:       rootCtx.DevIPAddress,
+		DepFingerprints: depFingerprints,
+	}
+	if md, err := c.compileMetadata(); err == nil && md != nil {
+		info.CompileHash = compileMetadataHash(md)
+	}
+
+	// If the dev environment already existed, it still has what it was
+	// created with.
+	if old, err := c.DevInfo(); err == nil && old != nil && !old.CreatedAt.IsZero() {
+		info.CreatedAt = old.CreatedAt
+		info.DepFingerprints = old.DepFingerprints
+		info.CompileHash = old.CompileHash
+	}
+	if err := c.saveDevInfo(info); err != nil {
+		return fmt.Errorf("Error saving dev environment info: %s", err)
+	}
+
+	if len(depIPs) > 0 {
+		c.ui.Message(fmt.Sprintf(
+			"Dev IP addresses for the dependencies:\n\n%s",
+			devIPTable(depIPs)))
+	}
+
+	ui.Result(c.ui, fmt.Sprintf(
+		"Dev environment for '%s' ready in %s",
+		rootCtx.Appfile.Application.Name,
+		summaryDuration(time.Since(start))))
+	return nil
 }
 
 // Infra manages the infrastructure for this Appfile.
@@ -503,7 +1612,59 @@ func (c *Core) Dev() error {
 // Infra recognizes two special actions: "" (blank string) and "destroy".
 // The former expects to create or update the complete infrastructure,
 // and the latter will destroy the infrastructure.
-func (c *Core) Infra(action string, args []string) error {
+func (c *Core) Infra(action string, args []string) (err error) {
+	// The help and info actions only read the local data
+	if action != "help" && action != "info" {
+		unlock, err := c.lockProject("infra", false)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	start := time.Now()
+	c.startOutput()
+	endLog := c.startOpLog("infra")
+	op := c.operation("infra", map[string]string{"action": action})
+	deadline := c.deadline("infra")
+	if action == "" {
+		deadline = c.deadline("apply")
+	}
+	defer func() {
+		err = c.finishWarnings(err)
+		op.End(err)
+
+		switch {
+		case action == "":
+			c.notify("infra", start, err)
+		case action == "destroy" && err != errDestroyCancelled:
+			c.notify("infra-destroy", start, err)
+		}
+		endLog(err)
+	}()
+
+	// Destroying the infrastructure affects every application using it,
+	// so it requires typing the infrastructure name.
+	if action == "destroy" {
+		var details []string
+		if infra := c.appfile.ActiveInfrastructure(); infra != nil {
+			details = append(details,
+				fmt.Sprintf("Infrastructure: %s (%s)", infra.Name, infra.Type),
+				fmt.Sprintf("Foundations: %d", len(infra.Foundations)))
+		}
+
+		err := c.confirm(&confirmOpts{
+			Id:       "destroy",
+			Message:  "Otto will delete all your managed infrastructure.",
+			Details:  details,
+			Expected: c.appfile.Project.Infrastructure,
+			Args:     args,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	// Get the infra implementation for this
 	infra, infraCtx, err := c.infra()
 	if err != nil {
@@ -536,9 +1697,13 @@ func (c *Core) Infra(action string, args []string) error {
 	// If we're doing anything other than destroying, then
 	// run the execution now.
 	if action != "destroy" {
+		if err := deadline.Check("infrastructure"); err != nil {
+			return err
+		}
 		if err := infra.Execute(infraCtx); err != nil {
 			return err
 		}
+		deadline.Done("infrastructure")
 	}
 
 	// If we have any foundations, we now run their infra deployment.
@@ -550,9 +1715,14 @@ func (c *Core) Infra(action string, args []string) error {
 		ctx.ActionArgs = args
 		ctx.InfraCreds = infraCtx.InfraCreds
 
-		log.Printf(
-			"[INFO] infra action '%s' on foundation '%s'",
-			action, ctx.Tuple.Type)
+		phase := fmt.Sprintf("foundation '%s'", ctx.Tuple.Type)
+		if err := deadline.Check(phase); err != nil {
+			return err
+		}
+
+		c.logger.Info(
+			"infra action on foundation",
+			"action", action, "foundation", ctx.Tuple.String())
 
 		switch action {
 		case "":
@@ -568,6 +1738,7 @@ func (c *Core) Infra(action string, args []string) error {
 		if err := f.Infra(ctx); err != nil {
 			return err
 		}
+		deadline.Done(phase)
 	}
 
 	// If the action is destroy, we run the infrastructure execution
@@ -575,6 +1746,9 @@ func (c *Core) Infra(action string, args []string) error {
 	// we need to first destroy all applications and foundations that
 	// are using this infra.
 	if action == "destroy" {
+		if err := deadline.Check("infrastructure"); err != nil {
+			return err
+		}
 		if err := infra.Execute(infraCtx); err != nil {
 			return err
 		}
@@ -583,15 +1757,23 @@ func (c *Core) Infra(action string, args []string) error {
 	// Output the right thing
 	switch action {
 	case "":
-		infraCtx.Ui.Header("[green]Infrastructure successfully created!")
-		infraCtx.Ui.Message(
-			"[green]The infrastructure necessary to deploy this application\n" +
-				"is now available. You can now deploy using `otto deploy`.")
+		infraCtx.Ui.Header(ui.Style("Infrastructure successfully created!", "green"))
+		infraCtx.Ui.Message(ui.Style(
+			"The infrastructure necessary to deploy this application\n"+
+				"is now available. You can now deploy using `otto deploy`.",
+			"green"))
+		ui.Result(c.ui, fmt.Sprintf(
+			"Created infrastructure '%s' in %s",
+			infraCtx.Infra.Name, summaryDuration(time.Since(start))))
 	case "destroy":
-		infraCtx.Ui.Header("[green]Infrastructure successfully destroyed!")
-		infraCtx.Ui.Message(
-			"[green]The infrastructure necessary to run this application and\n" +
-				"all other applications in this project has been destroyed.")
+		infraCtx.Ui.Header(ui.Style("Infrastructure successfully destroyed!", "green"))
+		infraCtx.Ui.Message(ui.Style(
+			"The infrastructure necessary to run this application and\n"+
+				"all other applications in this project has been destroyed.",
+			"green"))
+		ui.Result(c.ui, fmt.Sprintf(
+			"Destroyed infrastructure '%s' in %s",
+			infraCtx.Infra.Name, summaryDuration(time.Since(start))))
 	}
 
 	return nil
@@ -599,47 +1781,68 @@ func (c *Core) Infra(action string, args []string) error {
 
 // Status outputs to the UI the status of all the stages of this application.
 func (c *Core) Status() error {
-	// Start loading the status info in a goroutine
-	statusCh := make(chan *statusInfo, 1)
-	go c.statusInfo(statusCh)
-
-	// Wait for the status. If this takes longer than a certain amount
-	// of time then we show a loading message.
-	var status *statusInfo
-	select {
-	case status = <-statusCh:
-	case <-time.After(150 * time.Millisecond):
-		c.ui.Header("Loading status...")
-		c.ui.Message(fmt.Sprintf(
-			"Depending on your configured directory backend, this may require\n" +
-				"network operations and can take some time. On a typical broadband\n" +
-				"connection, this shouldn't take more than a few seconds."))
-	}
-	if status == nil {
-		status = <-statusCh
+	return c.StatusWithOpts(nil)
+}
+
+// StatusWithOpts is Status with options.
+func (c *Core) StatusWithOpts(opts *StatusOpts) error {
+	if opts == nil {
+		opts = new(StatusOpts)
 	}
 
-	// Create the status texts
-	devStatus := "[reset]NOT CREATED"
-	if status.Dev.IsReady() {
-		devStatus = "[green]CREATED"
+	status, err := c.waitStatus(opts)
+	if err != nil {
+		return err
+	}
+	if err := status.failed(); err != nil {
+		return err
 	}
-	buildStatus := "[reset]NOT BUILT"
-	if status.Build != nil {
-		buildStatus = "[green]BUILD READY"
+	if status.Err != nil {
+		c.logger.Warn("error loading part of the status", "err", status.Err)
 	}
-	deployStatus := "[reset]NOT DEPLOYED"
-	if status.Deploy.IsDeployed() {
-		deployStatus = "[green]DEPLOYED"
+
+	// Check the infrastructure and the deploy for real if asked
+	var discrepancies []string
+	if opts.Refresh {
+		discrepancies, err = c.refreshStatus(status)
+		if err != nil {
+			return errwrap.Wrapf("Error refreshing the status: {{err}}", err)
+		}
+	}
+
+	// Create the status texts. A component whose lookup failed is shown
+	// as unknown rather than as not created.
+	devStatus := "NOT CREATED"
+	if status.DevErr != nil {
+		devStatus = statusUnknown(status.DevErr)
+	} else if status.Dev.IsReady() {
+		devStatus = ui.Style("CREATED", "green")
+	}
+	buildStatus := "NOT BUILT"
+	if status.BuildErr != nil {
+		buildStatus = statusUnknown(status.BuildErr)
+	} else if status.Build != nil {
+		buildStatus = ui.Style("BUILD READY", "green")
+	}
+	deployStatus := "NOT DEPLOYED"
+	if status.DeployErr != nil {
+		deployStatus = statusUnknown(status.DeployErr)
+	} else if status.Deploy.IsDeployed() {
+		deployStatus = ui.Style("DEPLOYED", "green")
 	} else if status.Deploy.IsFailed() {
-		deployStatus = "[reset]DEPLOY FAILED"
+		deployStatus = "DEPLOY FAILED"
 	}
-	infraStatus := "[reset]NOT CREATED"
-	if status.Infra.IsReady() {
-		infraStatus = "[green]READY"
+	infraStatus := "NOT CREATED"
+	if status.InfraErr != nil {
+		infraStatus = statusUnknown(status.InfraErr)
+	} else if status.Infra.IsReady() {
+		infraStatus = ui.Style("READY", "green")
 	} else if status.Infra.IsPartial() {
-		infraStatus = "[yellow]PARTIAL"
+		infraStatus = ui.Style("PARTIAL", "yellow")
 	}
+	devStatus = withDrift(devStatus, status.DevDrift, "it was created")
+	buildStatus = withDrift(buildStatus, status.BuildDrift, "last build")
+	deployStatus = withDrift(deployStatus, status.DeployDrift, "last deploy")
 
 	// Get the active infra
 	infra := c.appfile.ActiveInfrastructure()
@@ -652,21 +1855,131 @@ func (c *Core) Status() error {
 	c.ui.Message(fmt.Sprintf(
 		"Infrastructure: %s (%s)",
 		infra.Type, infra.Flavor))
+	c.ui.Message(fmt.Sprintf("Environment:    %s", c.environment))
+
+	// List the dependencies if there are any
+	if deps := c.statusDeps(); len(deps.Rows) > 0 {
+		c.ui.Header("Dependencies")
+		c.ui.Message(deps.String())
+	}
 
 	c.ui.Header("Component Status")
 	c.ui.Message(fmt.Sprintf("Dev environment: %s", devStatus))
+	if info := status.DevInfo; status.Dev.IsReady() && info != nil {
+		if info.IPAddress != "" {
+			c.ui.Message(fmt.Sprintf("Dev IP:          %s", info.IPAddress))
+		}
+		if !info.CreatedAt.IsZero() {
+			c.ui.Message(fmt.Sprintf(
+				"Dev created:     %s (%s ago)",
+				info.CreatedAt.Local().Format(time.RFC1123),
+				summaryDuration(time.Since(info.CreatedAt))))
+		}
+		if len(info.DepAddresses) > 0 {
+			names := make([]string, 0, len(info.DepAddresses))
+			for name := range info.DepAddresses {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			c.ui.Message(fmt.Sprintf("Dev deps:        %s", strings.Join(names, ", ")))
+		}
+	}
+	if len(status.DevPorts) > 0 {
+		ports := make([]string, len(status.DevPorts))
+		for i, p := range status.DevPorts {
+			ports[i] = strconv.Itoa(p)
+		}
+		c.ui.Message(fmt.Sprintf("Dev ports:       %s", strings.Join(ports, ", ")))
+	}
+	if len(status.DevSnapshots) > 0 {
+		names := make([]string, len(status.DevSnapshots))
+		for i, s := range status.DevSnapshots {
+			names[i] = s.Name
+		}
+		c.ui.Message(fmt.Sprintf("Dev snapshots:   %s", strings.Join(names, ", ")))
+	}
 	c.ui.Message(fmt.Sprintf("Infra:           %s", infraStatus))
 	c.ui.Message(fmt.Sprintf("Build:           %s", buildStatus))
 	c.ui.Message(fmt.Sprintf("Deploy:          %s", deployStatus))
+	if h := status.Health; h != nil && status.Deploy.IsDeployed() {
+		c.ui.Message(fmt.Sprintf(
+			"Health:          %s (checked %s ago)",
+			healthStatusText(h.Status), summaryDuration(time.Since(h.CheckedAt))))
+	}
+	if r := status.Rollout; r.Active() {
+		c.ui.Message(fmt.Sprintf(
+			"Rollout:         %s, %s at step %d of %d",
+			r.Strategy, r.State, r.Step+1, len(r.Steps)))
+	}
+	if r := status.Smoke; r != nil && status.Deploy.IsDeployed() {
+		passed := 0
+		for _, result := range r.Results {
+			if result.Passed {
+				passed++
+			}
+		}
+		c.ui.Message(fmt.Sprintf(
+			"Smoke tests:     %d/%d passed (ran %s ago)",
+			passed, len(r.Results), summaryDuration(time.Since(r.CheckedAt))))
+	}
+
+	if len(discrepancies) > 0 {
+		c.ui.Header(ui.Style("Discrepancies", "red"))
+		for _, d := range discrepancies {
+			ui.Warn(c.ui, ui.Style(d, "red"))
+		}
+	}
+
+	if status.Dev.IsReady() && status.DevInfo.Stale(status.Compile) {
+		ui.Warn(c.ui,
+			"The dev environment was created from an older compilation of\n"+
+				"this Appfile, so it doesn't have the latest changes. Run\n"+
+				"`otto dev destroy` and `otto dev` to recreate it.")
+	}
 
 	return nil
 }
 
+// statusDeps returns the table of the dependencies of the application
+// for Status.
+func (c *Core) statusDeps() *ui.Table {
+	table := &ui.Table{
+		Headers:  []string{"NAME", "TYPE"},
+		MaxWidth: ui.TerminalWidth(),
+	}
+
+	root, err := c.appfileCompiled.Graph.Root()
+	if err != nil {
+		return table
+	}
+
+	var names []string
+	types := make(map[string]string)
+	for _, raw := range c.appfileCompiled.Graph.Vertices() {
+		if raw == root {
+			continue
+		}
+
+		app := raw.(*appfile.CompiledGraphVertex).File.Application
+		names = append(names, app.Name)
+		types[app.Name] = app.Type
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		table.AddRow(n, types[n])
+	}
+
+	return table
+}
+
 // Execute executes the given task for this Appfile.
 func (c *Core) Execute(opts *ExecuteOpts) error {
 	switch opts.Task {
 	case ExecuteTaskDev:
 		return c.executeApp(opts)
+	case ExecuteTaskConsole:
+		return c.executeConsole(opts)
 	default:
 		return fmt.Errorf("unknown task: %s", opts.Task)
 	}
@@ -697,6 +2010,15 @@ func (c *Core) creds(
 		}
 	}
 
+	// If we're not interactive, we need the password from the environment
+	// for both reading and writing the credentials so check up front.
+	if c.nonInteractive && os.Getenv("OTTO_CREDS_PASSWORD") == "" {
+		return fmt.Errorf(
+			"The password for the encrypted infrastructure credentials is\n" +
+				"required and Otto is running non-interactively. Set the\n" +
+				"OTTO_CREDS_PASSWORD environment variable to provide it.")
+	}
+
 	var creds map[string]string
 	if exists {
 		infraCtx.Ui.Message(
@@ -746,6 +2068,12 @@ func (c *Core) creds(
 		var err error
 		creds, err = infra.Creds(infraCtx)
 		if err != nil {
+			if nerr, ok := err.(*ui.ErrNonInteractive); ok {
+				return fmt.Errorf(
+					"missing credential '%s' and running non-interactively: %s",
+					nerr.Id, nerr)
+			}
+
 			return err
 		}
 
@@ -791,6 +2119,12 @@ func (c *Core) creds(
 }
 
 func (c *Core) executeApp(opts *ExecuteOpts) error {
+	// Destroying the dev environment releases what Core keeps for it too
+	if opts.Task == ExecuteTaskDev && opts.Action == "destroy" {
+		return c.DevDestroy(&DevDestroyOpts{Args: opts.Args})
+	}
+	defer c.cacheAppContexts()()
+
 	// Get the infra implementation for this
 	appCtx, err := c.appContext(c.appfile)
 	if err != nil {
@@ -815,7 +2149,188 @@ func (c *Core) executeApp(opts *ExecuteOpts) error {
 	}
 }
 
+// appContext returns the context for the app of the Appfile f. During an
+// operation the context is only built once per Appfile, and every call
+// returns a copy that the caller can modify.
 func (c *Core) appContext(f *appfile.File) (*app.Context, error) {
+	c.appContextLock.Lock()
+	ctx, ok := c.appContexts[f.ID]
+	c.appContextLock.Unlock()
+	if ok {
+		return copyAppContext(ctx), nil
+	}
+
+	// The lock isn't held while building the context so that the apps of
+	// a parallel walk don't wait on each other.
+	ctx, err := c.newAppContext(f)
+	if err != nil {
+		return nil, err
+	}
+
+	c.appContextLock.Lock()
+	if c.appContexts != nil {
+		c.appContexts[f.ID] = ctx
+	}
+	c.appContextLock.Unlock()
+
+	return copyAppContext(ctx), nil
+}
+
+// cacheAppContexts caches the app contexts until the returned function is
+// called, which must be done at the end of the operation since the
+// contexts have the Ui and logger of the operation. Nested calls use the
+// cache of the outermost one.
+//
+// The operation also gets its own temporary directory for the contexts,
+// which is removed when the returned function is called.
+func (c *Core) cacheAppContexts() func() {
+	c.appContextLock.Lock()
+	defer c.appContextLock.Unlock()
+
+	if c.appContexts != nil {
+		return func() {}
+	}
+
+	c.appContexts = make(map[string]*app.Context)
+	endTempDir := c.startTempDir()
+	return func() {
+		c.appContextLock.Lock()
+		c.appContexts = nil
+		c.appContextLock.Unlock()
+
+		endTempDir()
+	}
+}
+
+// resetAppContexts clears the cached app contexts, such as when the
+// compilation metadata that they have changes.
+func (c *Core) resetAppContexts() {
+	c.appContextLock.Lock()
+	defer c.appContextLock.Unlock()
+
+	if c.appContexts != nil {
+		c.appContexts = make(map[string]*app.Context)
+	}
+}
+
+// copyAppContext returns a copy of ctx that shares nothing that callers
+// modify with it.
+func copyAppContext(ctx *app.Context) *app.Context {
+	result := *ctx
+	result.ActionArgs = copyStrings(ctx.ActionArgs)
+	result.DevDepFragments = copyStrings(ctx.DevDepFragments)
+	result.DevLayers = copyStrings(ctx.DevLayers)
+	result.FoundationDirs = copyStrings(ctx.FoundationDirs)
+	if ctx.DevPorts != nil {
+		result.DevPorts = append([]int(nil), ctx.DevPorts...)
+	}
+	if ctx.InfraCreds != nil {
+		result.InfraCreds = make(map[string]string, len(ctx.InfraCreds))
+		for k, v := range ctx.InfraCreds {
+			result.InfraCreds[k] = v
+		}
+	}
+	if ctx.Env != nil {
+		result.Env = make(map[string]string, len(ctx.Env))
+		for k, v := range ctx.Env {
+			result.Env[k] = v
+		}
+	}
+	if ctx.DevDepAddresses != nil {
+		result.DevDepAddresses = make(map[string]string, len(ctx.DevDepAddresses))
+		for k, v := range ctx.DevDepAddresses {
+			result.DevDepAddresses[k] = v
+		}
+	}
+	if ctx.DevDepPorts != nil {
+		result.DevDepPorts = make(map[string][]int, len(ctx.DevDepPorts))
+		for k, v := range ctx.DevDepPorts {
+			result.DevDepPorts[k] = append([]int(nil), v...)
+		}
+	}
+
+	return &result
+}
+
+func copyStrings(v []string) []string {
+	if v == nil {
+		return nil
+	}
+
+	return append([]string(nil), v...)
+}
+
+// provisionalFoundationConfig returns the foundation configuration of the
+// app for the foundations that are compiled before it. This is from the
+// app if it implements app.FoundationConfigurer, and otherwise from the
+// previous compilation metadata prevMd if the same app type compiled it.
+// It is nil if neither has it.
+func (c *Core) provisionalFoundationConfig(
+	impl app.App, ctx *app.Context, prevMd *CompileMetadata) (*foundation.Config, error) {
+	if configurer, ok := impl.(app.FoundationConfigurer); ok {
+		result, err := configurer.FoundationConfig(ctx)
+		if err == nil && result != nil {
+			return result, nil
+		}
+		if err != nil && err != app.ErrFoundationConfigNotSupported {
+			return nil, errwrap.Wrapf(
+				"Error loading the foundation configuration of the app: {{err}}", err)
+		}
+	}
+
+	if prevMd == nil {
+		return nil, nil
+	}
+	prev := prevMd.AppDeps[ctx.Appfile.ID]
+	if ctx.Appfile.ID == c.appfile.ID {
+		prev = prevMd.App
+	}
+	if prev == nil || prev.Plugin != ctx.Tuple.String() {
+		return nil, nil
+	}
+
+	result := prev.FoundationConfig
+	return &result, nil
+}
+
+// validateFoundationPhases returns an error if phases, which an app
+// declared, has a phase that isn't one of foundation.AppPhases.
+func validateFoundationPhases(phases []string) error {
+	for _, p := range phases {
+		if !foundation.HasAppPhase(foundation.AppPhases, p) {
+			return fmt.Errorf(
+				"Error compiling app: unknown foundation phase '%s'. The\n"+
+					"phases are: %s", p, strings.Join(foundation.AppPhases, ", "))
+		}
+	}
+
+	return nil
+}
+
+// foundationPhaseDirs creates the subdirectories of the foundation
+// directory dir for the app phases, and removes the ones of the other
+// phases, which foundations may have written before the app declared its
+// phases.
+func foundationPhaseDirs(dir string, phases []string) error {
+	for _, p := range foundation.AppPhases {
+		path := foundation.AppPhaseDir(dir, p)
+		if !foundation.HasAppPhase(phases, p) {
+			if err := os.RemoveAll(path); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Core) newAppContext(f *appfile.File) (*app.Context, error) {
 	// Whether or not this is the root Appfile
 	root := f.ID == c.appfile.ID
 
@@ -846,14 +2361,6 @@ func (c *Core) appContext(f *appfile.File) (*app.Context, error) {
 			c.compileDir, fmt.Sprintf("dep-%s", f.ID))
 	}
 
-	// The cache directory for this app
-	cacheDir := filepath.Join(c.dataDir, "cache", f.ID)
-	if err := os.MkdirAll(cacheDir, 0755); err != nil {
-		return nil, fmt.Errorf(
-			"error making cache directory '%s': %s",
-			cacheDir, err)
-	}
-
 	// The directory for global data
 	globalDir := filepath.Join(c.dataDir, "global-data")
 	if err := os.MkdirAll(globalDir, 0755); err != nil {
@@ -870,23 +2377,33 @@ func (c *Core) appContext(f *appfile.File) (*app.Context, error) {
 			outputDir, fmt.Sprintf("foundation-%s", f.Name))
 	}
 
-	// Get the dev IP address
+	// Get the dev IP address. Each app gets its own address so that
+	// dependencies can run their own dev environments.
 	ipDB := &localaddr.CachedDB{
-		DB:        &localaddr.DB{Path: filepath.Join(c.dataDir, "ip.db")},
-		CachePath: filepath.Join(c.localDir, "dev_ip"),
+		DB:        c.devIPDB(),
+		CachePath: c.devIPCachePath(f),
+		Owner:     c.devIPOwner(f),
+		OnReallocate: func(old, new net.IP) {
+			ui.Warn(c.ui, fmt.Sprintf(
+				"The dev IP address of '%s' changed from %s to %s, since the\n"+
+					"old address is no longer leased to this project. If the dev\n"+
+					"environment exists, it may need to be re-provisioned to pick\n"+
+					"up the new address: run `otto dev destroy` and `otto dev`.",
+				f.Application.Name, old, new))
+		},
 	}
 	ip, err := ipDB.IP()
 	if err != nil {
 		return nil, fmt.Errorf(
 			"Error retrieving dev IP address: %s", err)
 	}
+	c.checkDevIP(ip)
 
 	// Get the metadata
 	var compileResult *app.CompileResult
 	md, err := c.compileMetadata()
 	if err != nil {
-		return nil, fmt.Errorf(
-			"Error loading compilation metadata: %s", err)
+		return nil, errwrap.Wrapf("Error loading compilation metadata: {{err}}", err)
 	}
 	if md != nil {
 		if root {
@@ -896,20 +2413,42 @@ func (c *Core) appContext(f *appfile.File) (*app.Context, error) {
 		}
 	}
 
-	// Get the customizations. If we don't have any at all, we fast-path
-	// this by doing nothing. If we do, we have to make a deep copy in
-	// order to prune out the irrelevant ones.
-	if f.Customization != nil && len(f.Customization.Raw) > 0 {
-		// Perform a deep copy of the Appfile so we can modify it
-		fRaw, err := copystructure.Copy(f)
+	// The cache directory for this app
+	cacheDir := c.appCacheDir(f.ID)
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, fmt.Errorf(
+			"error making cache directory '%s': %s",
+			cacheDir, err)
+	}
+
+	// Allocate the dev ports the app asked for when it compiled
+	var ports []int
+	if compileResult != nil && compileResult.DevPorts > 0 {
+		ports, err = c.devPortDB().AllocatePorts(
+			f.ID, compileResult.DevPorts, &localaddr.PortOpts{
+				Path: c.devIPOwner(f).Path,
+			})
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf(
+				"Error allocating dev ports: %s", err)
 		}
-		f = fRaw.(*appfile.File)
+	}
 
-		// Get the app-only customizations and set it on the Appfile
-		cs := f.Customization.Filter("app")
-		f.Customization = &appfile.CustomizationSet{Raw: cs}
+	env, err := c.appEnv(f)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the customizations. If we don't have any at all, we fast-path
+	// this by doing nothing. If we do, we have to copy the Appfile in
+	// order to prune out the irrelevant ones. Only the customizations are
+	// deep copied since nothing else in the Appfile is ever modified.
+	if f.Customization != nil && len(f.Customization.Raw) > 0 {
+		fCopy := *f
+		fCopy.Customization = &appfile.CustomizationSet{
+			Raw: c.copyCustomizations(f, f.Customization.Filter("app")),
+		}
+		f = &fCopy
 	}
 
 	return &app.Context{
@@ -920,37 +2459,104 @@ func (c *Core) appContext(f *appfile.File) (*app.Context, error) {
 		GlobalDir:     globalDir,
 		Tuple:         tuple,
 		Application:   f.Application,
+		Environment:   c.environment,
 		DevIPAddress:  ip.String(),
+		DevPorts:      ports,
 		Shared: context.Shared{
 			Appfile:        f,
 			FoundationDirs: foundationDirs,
 			InstallDir:     filepath.Join(c.dataDir, "binaries"),
+			Implementation: "app:" + tuple.String(),
+			ScratchDir:     c.scratchDir(),
+			Env:            env,
 			Directory:      c.dir,
 			Ui:             c.ui,
+			Logger: c.logger.With(
+				"app", f.Application.Name, "tuple", tuple.String()),
 		},
 	}, nil
 }
 
 func (c *Core) app(ctx *app.Context) (app.App, error) {
-	log.Printf("[INFO] Loading app implementation for Tuple: %s", ctx.Tuple)
+	c.logger.Info("loading app implementation", "tuple", ctx.Tuple.String())
 
 	// Look for the app impl. factory
 	f := app.TupleMap(c.apps).Lookup(ctx.Tuple)
 	if f == nil {
-		return nil, fmt.Errorf(
-			"app implementation for tuple not found: %s", ctx.Tuple)
+		return nil, &HintedError{
+			Err:  &ErrAppNotFound{Tuple: ctx.Tuple},
+			Hint: c.appNotFoundHint(ctx.Tuple),
+		}
 	}
 
 	// Start the impl.
 	result, err := f()
 	if err != nil {
-		return nil, fmt.Errorf(
-			"app failed to start properly: %s", err)
+		return nil, &ErrPluginStart{Kind: "app", Tuple: ctx.Tuple.String(), Err: err}
+	}
+	if err := checkAPIVersion(result, "app", ctx.Tuple.String()); err != nil {
+		maybeClose(result)
+		return nil, err
 	}
 
 	return result, nil
 }
 
+// appNotFoundHint returns the hint for an app tuple that has no
+// implementation, listing the tuples that are implemented for the app type
+// or, if there are none, all the tuples.
+func (c *Core) appNotFoundHint(t app.Tuple) string {
+	var all, supported []string
+	for k := range c.apps {
+		all = append(all, k.String())
+		if k.App == t.App || k.App == "*" {
+			supported = append(supported, k.String())
+		}
+	}
+	if len(supported) == 0 {
+		supported = all
+	}
+	sort.Strings(supported)
+
+	return fmt.Sprintf(
+		"The '%s' application type isn't supported for infra '%s'. "+
+			"Supported tuples: %s",
+		t.App, t.Infra, strings.Join(supported, ", "))
+}
+
+// copyCustomizations deep copies the customizations cs of the Appfile f.
+// Some values that HCL decodes to can't be deep copied. Rather than fail,
+// the configuration of such a customization is only copied one level deep
+// and a warning is output, since the app normally doesn't modify it.
+func (c *Core) copyCustomizations(
+	f *appfile.File, cs []*appfile.Customization) []*appfile.Customization {
+	result := make([]*appfile.Customization, len(cs))
+	for i, cust := range cs {
+		raw, err := copystructure.Copy(cust)
+		if err == nil {
+			result[i] = raw.(*appfile.Customization)
+			continue
+		}
+
+		c.logger.Warn(
+			"error copying customization", "app", f.Application.Name,
+			"path", f.Path, "type", cust.Type, "err", err)
+		ui.Warn(c.ui, fmt.Sprintf(
+			"Error copying the '%s' customization of '%s' (%s): %s\n\n"+
+				"Only its top-level settings are copied, so changes that the app\n"+
+				"makes to nested settings also change the loaded Appfile.",
+			cust.Type, f.Application.Name, f.Path, err))
+
+		config := make(map[string]interface{}, len(cust.Config))
+		for k, v := range cust.Config {
+			config[k] = v
+		}
+		result[i] = &appfile.Customization{Type: cust.Type, Config: config}
+	}
+
+	return result
+}
+
 func (c *Core) infra() (infrastructure.Infrastructure, *infrastructure.Context, error) {
 	// Get the infrastructure configuration
 	config := c.appfile.ActiveInfrastructure()
@@ -963,14 +2569,22 @@ func (c *Core) infra() (infrastructure.Infrastructure, *infrastructure.Context,
 	// Get the infrastructure factory
 	f, ok := c.infras[config.Type]
 	if !ok {
-		return nil, nil, fmt.Errorf(
-			"infrastructure type not supported: %s",
-			config.Type)
+		available := make([]string, 0, len(c.infras))
+		for k := range c.infras {
+			available = append(available, k)
+		}
+		sort.Strings(available)
+
+		return nil, nil, &ErrInfraNotFound{Type: config.Type, Available: available}
 	}
 
 	// Start the infrastructure implementation
 	infra, err := f()
 	if err != nil {
+		return nil, nil, &ErrPluginStart{Kind: "infra", Tuple: config.Type, Err: err}
+	}
+	if err := checkAPIVersion(infra, "infrastructure", config.Type); err != nil {
+		maybeClose(infra)
 		return nil, nil, err
 	}
 
@@ -983,10 +2597,14 @@ func (c *Core) infra() (infrastructure.Infrastructure, *infrastructure.Context,
 		Dir:   outputDir,
 		Infra: config,
 		Shared: context.Shared{
-			Appfile:    c.appfile,
-			InstallDir: filepath.Join(c.dataDir, "binaries"),
-			Directory:  c.dir,
-			Ui:         c.ui,
+			Appfile:        c.appfile,
+			InstallDir:     filepath.Join(c.dataDir, "binaries"),
+			Implementation: "infra:" + config.Type,
+			ScratchDir:     c.scratchDir(),
+			Env:            c.projectEnv(),
+			Directory:      c.dir,
+			Ui:             c.ui,
+			Logger:         c.logger.With("infra", config.Name),
 		},
 	}, nil
 }
@@ -1018,17 +2636,25 @@ func (c *Core) foundations() ([]foundation.Foundation, []*foundation.Context, er
 			InfraFlavor: config.Flavor,
 		}
 
-		// Look for the matching foundation
+		// Look for the matching foundation. If there isn't one, then tell
+		// the user if the type is unknown or just not implemented for
+		// this infrastructure, since those are fixed differently.
 		fun := foundation.TupleMap(c.foundationMap).Lookup(tuple)
 		if fun == nil {
-			return nil, nil, fmt.Errorf(
-				"foundation implementation for tuple not found: %s",
-				tuple)
+			return nil, nil, &ErrFoundationNotFound{
+				Tuple:     tuple,
+				Available: foundation.TupleMap(c.foundationMap).Type(f.Name),
+			}
 		}
 
 		// Instantiate the implementation
 		impl, err := fun()
 		if err != nil {
+			return nil, nil, &ErrPluginStart{
+				Kind: "foundation", Tuple: tuple.String(), Err: err}
+		}
+		if err := checkAPIVersion(impl, "foundation", tuple.String()); err != nil {
+			maybeClose(impl)
 			return nil, nil, err
 		}
 
@@ -1042,10 +2668,14 @@ func (c *Core) foundations() ([]foundation.Foundation, []*foundation.Context, er
 			Dir:    outputDir,
 			Tuple:  tuple,
 			Shared: context.Shared{
-				Appfile:    c.appfile,
-				InstallDir: filepath.Join(c.dataDir, "binaries"),
-				Directory:  c.dir,
-				Ui:         c.ui,
+				Appfile:        c.appfile,
+				InstallDir:     filepath.Join(c.dataDir, "binaries"),
+				Implementation: "foundation:" + tuple.String(),
+				ScratchDir:     c.scratchDir(),
+				Env:            c.projectEnv(),
+				Directory:      c.dir,
+				Ui:             c.ui,
+				Logger:         c.logger.With("foundation", tuple.String()),
 			},
 		}
 
@@ -1,11 +1,25 @@
 package otto
 
 import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/foundation"
+	"github.com/hashicorp/otto/helper/logger"
+	"github.com/hashicorp/otto/ui"
 )
 
 func TestCoreApp(t *testing.T) {
@@ -107,10 +121,888 @@ func TestCoreDev_compileMetadata(t *testing.T) {
 }
 
 func testCore(t *testing.T, config *CoreConfig) *Core {
-	core, err := NewCore(config)
+	return TestCore(t, &TestCoreOpts{Config: config})
+}
+
+func TestNewCore_devSubnet(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.DevSubnet = "10.200.0.0/16"
+	core := testCore(t, coreConfig)
+	if core.devSubnet.String() != "10.200.0.0/16" {
+		t.Fatalf("bad: %s", core.devSubnet)
+	}
+
+	// Public subnets aren't allowed
+	coreConfig.DevSubnet = "8.8.0.0/16"
+	if _, err := NewCore(coreConfig); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestCoreClose(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	p1 := new(testPlugin)
+	p2 := &testPlugin{Err: errors.New("kill failed")}
+	coreConfig.Plugins = []io.Closer{p1, p2}
+	core := testCore(t, coreConfig)
+
+	err := core.Close()
+	if err == nil || !strings.Contains(err.Error(), "kill failed") {
+		t.Fatalf("bad: %v", err)
+	}
+	if p1.Closed != 1 || p2.Closed != 1 {
+		t.Fatalf("bad: %d %d", p1.Closed, p2.Closed)
+	}
+
+	// Closing again does nothing
+	if err := core.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p1.Closed != 1 || p2.Closed != 1 {
+		t.Fatalf("bad: %d %d", p1.Closed, p2.Closed)
+	}
+}
+
+func TestNewCore_wildcardTuples(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	specific := TestApp(t, app.Tuple{App: "rails", Infra: "aws", InfraFlavor: "*"}, coreConfig)
+	TestApp(t, app.Tuple{App: "rails", Infra: "*", InfraFlavor: "vpc"}, coreConfig)
+	TestApp(t, app.Tuple{App: "rails", Infra: "*", InfraFlavor: "*"}, coreConfig)
+	TestFoundation(t, foundation.Tuple{Type: "consul", Infra: "*", InfraFlavor: "*"}, coreConfig)
+	TestFoundation(t, foundation.Tuple{Type: "*", Infra: "aws", InfraFlavor: "*"}, coreConfig)
+
+	// Wildcards in different fields are ordered by the priority of the
+	// fields, so the most specific implementation is used
+	core := testCore(t, coreConfig)
+	f := app.TupleMap(core.apps).Lookup(app.Tuple{App: "rails", Infra: "aws", InfraFlavor: "vpc"})
+	if f == nil {
+		t.Fatal("should find")
+	}
+	if impl, err := f(); err != nil || impl != specific {
+		t.Fatalf("bad: %#v %v", impl, err)
+	}
+}
+
+func TestCoreFoundations_wildcard(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	generic := TestFoundation(t, foundation.Tuple{Type: "consul", Infra: "aws", InfraFlavor: "*"}, coreConfig)
+	specific := TestFoundation(t, foundation.Tuple{Type: "consul", Infra: "aws", InfraFlavor: "vpc"}, coreConfig)
+	TestFoundation(t, foundation.Tuple{Type: "consul", Infra: "google", InfraFlavor: "simple"}, coreConfig)
+	core := testCore(t, coreConfig)
+
+	infra := core.appfile.ActiveInfrastructure()
+	infra.Type = "aws"
+	infra.Foundations = []*appfile.Foundation{&appfile.Foundation{Name: "consul"}}
+
+	cases := []struct {
+		Flavor   string
+		Expected foundation.Foundation
+	}{
+		{"simple", generic},
+		{"vpc-public-private", generic},
+		{"vpc", specific},
+	}
+	for _, tc := range cases {
+		infra.Flavor = tc.Flavor
+		fs, ctxs, err := core.foundations()
+		if err != nil {
+			t.Fatalf("%s err: %s", tc.Flavor, err)
+		}
+		if len(fs) != 1 || fs[0] != tc.Expected {
+			t.Fatalf("%s bad: %#v", tc.Flavor, fs)
+		}
+		if ctxs[0].Tuple.InfraFlavor != tc.Flavor {
+			t.Fatalf("%s bad: %#v", tc.Flavor, ctxs[0].Tuple)
+		}
+	}
+
+	// A type that is implemented, but not for the infrastructure
+	infra.Type = "azure"
+	_, _, err := core.foundations()
+	if err == nil {
+		t.Fatal("should error")
+	}
+	expected := "foundation 'consul' has no implementation for the infrastructure\n" +
+		"azure with flavor 'vpc'. It is implemented for: aws/*, aws/vpc, google/simple"
+	if err.Error() != expected {
+		t.Fatalf("bad: %s", err)
+	}
+
+	// A type that isn't implemented at all
+	infra.Foundations[0].Name = "nope"
+	_, _, err = core.foundations()
+	if err == nil || err.Error() != "unknown foundation type: nope" {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+// testPlugin is an io.Closer that stands in for a plugin process.
+type testPlugin struct {
+	Closed int
+	Err    error
+}
+
+func (p *testPlugin) Close() error {
+	p.Closed++
+	return p.Err
+}
+
+func TestCoreCompile_verbosity(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Verbosity = ui.LevelDebug
+	uiMock := new(ui.Mock)
+	coreConfig.Ui = uiMock
+	core := testCore(t, coreConfig)
+
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	uiMock.AssertMessageContains(t, "Walking app:")
+
+	// At the default verbosity, the debug output is hidden
+	coreConfig.Verbosity = ui.LevelInfo
+	uiMock = new(ui.Mock)
+	coreConfig.Ui = uiMock
+	core = testCore(t, coreConfig)
+
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	uiMock.AssertMessageNotContains(t, "Walking app:")
+}
+
+func TestCoreCompile_timestampOutput(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.TimestampOutput = true
+	uiMock := new(ui.Mock)
+	coreConfig.Ui = uiMock
+	core := testCore(t, coreConfig)
+
+	done := func() int {
+		result := 0
+		for _, msg := range uiMock.MessageBuf {
+			if strings.Contains(msg, "Done in") {
+				result++
+			}
+		}
+
+		return result
+	}
+
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	first := done()
+
+	// The timing restarts with each operation, so the first header of
+	// the next one isn't timed from the last header of this one.
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if n := done(); n != 2*first {
+		t.Fatalf("bad: %d %d", first, n)
+	}
+}
+
+func TestCoreCompile_quiet(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Quiet = true
+	uiMock := new(ui.Mock)
+	coreConfig.Ui = uiMock
+	core := testCore(t, coreConfig)
+
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(uiMock.HeaderBuf) != 0 {
+		t.Fatalf("bad: %#v", uiMock.HeaderBuf)
+	}
+	if len(uiMock.MessageBuf) != 1 {
+		t.Fatalf("bad: %#v", uiMock.MessageBuf)
+	}
+	if !strings.HasPrefix(uiMock.MessageBuf[0], "[green]Compiled 1 app, 0 dependencies in ") {
+		t.Fatalf("bad: %#v", uiMock.MessageBuf[0])
+	}
+}
+
+func TestCoreCompile_disableColor(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.DisableColor = true
+	uiMock := new(ui.Mock)
+	coreConfig.Ui = uiMock
+	core := testCore(t, coreConfig)
+
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, buf := range [][]string{uiMock.HeaderBuf, uiMock.MessageBuf} {
+		for _, msg := range buf {
+			if strings.ContainsAny(msg, "[]\x1b") {
+				t.Fatalf("bad: %#v", msg)
+			}
+		}
+	}
+}
+
+func TestCoreBuild_nonInteractive(t *testing.T) {
+	defer os.Setenv("OTTO_CREDS_PASSWORD", os.Getenv("OTTO_CREDS_PASSWORD"))
+	os.Setenv("OTTO_CREDS_PASSWORD", "")
+
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.NonInteractive = true
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+
+	err := core.Build()
+	if err == nil {
+		t.Fatal("should error")
+	}
+	if !strings.Contains(err.Error(), "OTTO_CREDS_PASSWORD") {
+		t.Fatalf("bad: %s", err)
+	}
+	if appMock.BuildCalled {
+		t.Fatal("build should not be called")
+	}
+}
+
+func TestCoreBuild_credsPassword(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	uiMock := new(ui.Mock)
+	uiMock.AddAnswer("^creds_password$", "hunter2")
+	coreConfig.Ui = uiMock
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+
+	if err := core.Build(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.BuildCalled {
+		t.Fatal("build should be called")
+	}
+	if !uiMock.InputRequested("creds_password") {
+		t.Fatal("password should be requested")
+	}
+	uiMock.AssertMessageContains(t, "Built '")
+}
+
+func TestCoreBuildWithOpts(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	uiMock := new(ui.Mock)
+	uiMock.AddAnswer("^creds_password$", "hunter2")
+	coreConfig.Ui = uiMock
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+
+	// The app stores the build record
+	err := core.dir.PutBuild(&directory.Build{
+		Lookup:   core.deployLookup(core.appfile.ID),
+		Artifact: map[string]string{"ami": "ami-123456"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	opts := &BuildOpts{
+		Action: "release",
+		Args:   []string{"-fast"},
+		Vars:   map[string]string{"tag": "v1.2.3"},
+	}
+	if err := core.BuildWithOpts(opts); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	ctx := appMock.BuildContext
+	if ctx.Action != "release" || !reflect.DeepEqual(ctx.ActionArgs, []string{"-fast"}) {
+		t.Fatalf("bad: %#v", ctx)
+	}
+	if !reflect.DeepEqual(ctx.BuildVars, opts.Vars) {
+		t.Fatalf("bad: %#v", ctx.BuildVars)
+	}
+
+	// The vars are recorded with the default build
+	opts.Action, opts.Args = "", nil
+	if err := core.BuildWithOpts(opts); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	build, err := core.dir.GetBuild(&directory.Build{
+		Lookup: core.deployLookup(core.appfile.ID)})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if build.Artifact["var.tag"] != "v1.2.3" || build.Artifact["ami"] != "ami-123456" {
+		t.Fatalf("bad: %#v", build.Artifact)
+	}
+}
+
+func TestCoreCompile_timings(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	core := testCore(t, coreConfig)
+
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	md, err := core.compileMetadata()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var kinds []string
+	for _, timing := range md.Timings {
+		kinds = append(kinds, timing.Kind)
+	}
+
+	expected := []string{"infra", "app"}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Fatalf("bad: %#v", kinds)
+	}
+
+	if md.Duration <= 0 || md.Parallelism != 1 {
+		t.Fatalf("bad: %#v", md)
+	}
+	steps := md.Timings[1].Steps
+	if len(steps) != 1 || steps[0].Kind != "app" {
+		t.Fatalf("bad: %#v", steps)
+	}
+}
+
+func TestCoreCompile_timingsParallel(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps-order", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	appMock.CompileFunc = func(ctx *app.Context) (*app.CompileResult, error) {
+		// "cache" and "db" don't depend on anything so they're compiled
+		// at the same time.
+		switch ctx.Appfile.Application.Name {
+		case "cache", "db":
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		return nil, nil
+	}
+	core := testCore(t, coreConfig)
+
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	md, err := core.CompileMetadata()
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
 
-	return core
+	if md.Parallelism < 2 {
+		t.Fatalf("bad: %d", md.Parallelism)
+	}
+	for _, timing := range md.Timings {
+		switch timing.Name {
+		case "cache", "db":
+			if timing.Duration < 50*time.Millisecond {
+				t.Fatalf("bad: %#v", timing)
+			}
+		case "alpha":
+			if timing.Duration >= 50*time.Millisecond {
+				t.Fatalf("bad: %#v", timing)
+			}
+		}
+	}
+}
+
+func TestCoreCompile_logger(t *testing.T) {
+	l := new(testLogger)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Logger = l
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(l.Messages) == 0 {
+		t.Fatal("should log")
+	}
+	if appMock.CompileContext.Logger == nil {
+		t.Fatal("context should have logger")
+	}
+}
+
+// testLogger is a logger.Logger that records messages.
+type testLogger struct {
+	sync.Mutex
+	Messages []string
+}
+
+func (l *testLogger) Debug(msg string, kv ...interface{}) { l.log(msg) }
+func (l *testLogger) Info(msg string, kv ...interface{})  { l.log(msg) }
+func (l *testLogger) Warn(msg string, kv ...interface{})  { l.log(msg) }
+func (l *testLogger) Error(msg string, kv ...interface{}) { l.log(msg) }
+
+func (l *testLogger) With(kv ...interface{}) logger.Logger { return l }
+
+func (l *testLogger) log(msg string) {
+	l.Lock()
+	defer l.Unlock()
+	l.Messages = append(l.Messages, msg)
+}
+
+func TestCoreCompile_telemetry(t *testing.T) {
+	telemetry := new(MemoryTelemetry)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Telemetry = telemetry
+	TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ops := make(map[string]*TelemetryOperation)
+	for _, op := range telemetry.Operations() {
+		if op.Err != nil {
+			t.Fatalf("bad: %#v", op)
+		}
+		if op.Attrs["app"] == "" || op.Attrs["infra"] == "" {
+			t.Fatalf("bad: %#v", op.Attrs)
+		}
+		for k, _ := range op.Attrs {
+			if strings.Contains(k, "cred") {
+				t.Fatalf("bad: %#v", op.Attrs)
+			}
+		}
+
+		ops[op.Op] = op
+	}
+
+	for _, name := range []string{"compile", "compile.infra", "compile.app"} {
+		if _, ok := ops[name]; !ok {
+			t.Fatalf("missing %s: %#v", name, ops)
+		}
+	}
+	if v := ops["compile.app"].Attrs["tuple"]; v != TestAppTuple.String() {
+		t.Fatalf("bad: %#v", v)
+	}
+
+	// The overall compile should be the last to complete
+	all := telemetry.Operations()
+	if all[len(all)-1].Op != "compile" {
+		t.Fatalf("bad: %#v", all[len(all)-1])
+	}
+}
+
+func TestCoreCompile_events(t *testing.T) {
+	var lock sync.Mutex
+	var events []Event
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.EventSink = func(e Event) {
+		lock.Lock()
+		defer lock.Unlock()
+		events = append(events, e)
+	}
+	TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(events) < 3 {
+		t.Fatalf("bad: %#v", events)
+	}
+	if _, ok := events[0].(*CompileStarted); !ok {
+		t.Fatalf("bad: %#v", events[0])
+	}
+	finished, ok := events[len(events)-1].(*CompileFinished)
+	if !ok {
+		t.Fatalf("bad: %#v", events[len(events)-1])
+	}
+	if finished.Err != nil {
+		t.Fatalf("err: %s", finished.Err)
+	}
+
+	found := false
+	for _, e := range events {
+		info := e.Info()
+		if info.AppfileID != coreConfig.Appfile.File.ID {
+			t.Fatalf("bad: %#v", info)
+		}
+
+		if u, ok := e.(*UnitCompiled); ok && u.Kind == "app" {
+			found = true
+			if u.Tuple != TestAppTuple {
+				t.Fatalf("bad: %#v", u.Tuple)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("bad: %#v", events)
+	}
+}
+
+func TestCoreCompile_eventsPanic(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.EventSink = func(Event) { panic("bad sink") }
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.CompileCalled {
+		t.Fatal("compile should be called")
+	}
+}
+
+func TestCoreCompile_warnings(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	uiMock := new(ui.Mock)
+	coreConfig.Ui = uiMock
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	appMock.CompileFunc = func(ctx *app.Context) (*app.CompileResult, error) {
+		ui.Warn(ctx.Ui, "careful")
+		return nil, nil
+	}
+	core := testCore(t, coreConfig)
+
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	uiMock.AssertHeaderContains(t, "1 warning during this operation")
+
+	count := 0
+	for _, msg := range uiMock.MessageBuf {
+		if strings.Contains(msg, "WARNING: careful") {
+			count++
+		}
+	}
+	if count != 2 {
+		t.Fatalf("bad: %#v", uiMock.MessageBuf)
+	}
+}
+
+func TestCoreCompile_warningsAsErrors(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.WarningsAsErrors = true
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	appMock.CompileFunc = func(ctx *app.Context) (*app.CompileResult, error) {
+		ui.Warn(ctx.Ui, "careful")
+		return nil, nil
+	}
+	core := testCore(t, coreConfig)
+
+	err := core.Compile()
+	if err == nil {
+		t.Fatal("should error")
+	}
+	if !strings.Contains(err.Error(), "warnings are treated as errors") {
+		t.Fatalf("bad: %s", err)
+	}
+
+	// Without warnings, the next operation succeeds
+	appMock.CompileFunc = nil
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestCoreCompile_devDepFragmentOrder(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps-order", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+
+	var lock sync.Mutex
+	var fragments []string
+	appMock.CompileFunc = func(ctx *app.Context) (*app.CompileResult, error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if ctx.Appfile.Application.Name == "web" {
+			fragments = ctx.DevDepFragments
+			return nil, nil
+		}
+
+		return &app.CompileResult{
+			DevDepFragmentPath: "fragment-" + ctx.Appfile.Application.Name,
+		}, nil
+	}
+	core := testCore(t, coreConfig)
+
+	// Dependencies come after what they depend on, otherwise by name
+	expected := []string{"fragment-cache", "fragment-alpha", "fragment-db"}
+	for i := 0; i < 10; i++ {
+		if err := core.Compile(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !reflect.DeepEqual(fragments, expected) {
+			t.Fatalf("bad: %#v", fragments)
+		}
+	}
+}
+
+func TestCoreCompile_serialWalk(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps-order", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+
+	var active int32
+	var order []string
+	appMock.CompileFunc = func(ctx *app.Context) (*app.CompileResult, error) {
+		if atomic.AddInt32(&active, 1) != 1 {
+			t.Errorf("compiled in parallel: %s", ctx.Appfile.Application.Name)
+		}
+		defer atomic.AddInt32(&active, -1)
+		time.Sleep(10 * time.Millisecond)
+
+		order = append(order, ctx.Appfile.Application.Name)
+		return nil, nil
+	}
+	core := testCore(t, coreConfig)
+
+	err := core.CompileWithOpts(&CompileOpts{SerialWalk: true})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{"cache", "alpha", "db", "web"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Fatalf("bad: %#v", order)
+	}
+}
+
+func TestCoreCompile_serialWalkStop(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps-order", "Appfile"))
+	coreConfig.SerialWalk = true
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+
+	var order []string
+	appMock.CompileFunc = func(ctx *app.Context) (*app.CompileResult, error) {
+		order = append(order, ctx.Appfile.Application.Name)
+		if ctx.Appfile.Application.Name == "alpha" {
+			return nil, errors.New("alpha failed")
+		}
+
+		return nil, nil
+	}
+	core := testCore(t, coreConfig)
+
+	// Nothing is compiled after the first failure
+	err := core.Compile()
+	if err == nil || !strings.Contains(err.Error(), "alpha failed") {
+		t.Fatalf("err: %v", err)
+	}
+	expected := []string{"cache", "alpha"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Fatalf("bad: %#v", order)
+	}
+}
+
+func TestCoreCompile_foundationPhases(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	appMock.CompileResult = &app.CompileResult{
+		FoundationPhases: []string{foundation.AppPhaseBuild, foundation.AppPhaseDeploy},
+	}
+
+	// The foundation writes every phase, as the bundled ones do
+	f := new(testPhaseFoundation)
+	coreConfig.Foundations = map[foundation.Tuple]foundation.Factory{
+		foundation.Tuple{Type: "consul", Infra: "*", InfraFlavor: "*"}: func() (foundation.Foundation, error) {
+			return f, nil
+		},
+	}
+	core := testCore(t, coreConfig)
+	core.appfile.ActiveInfrastructure().Foundations = []*appfile.Foundation{
+		&appfile.Foundation{Name: "consul"},
+	}
+
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	ctx := f.CompileContext
+	if !reflect.DeepEqual(ctx.AppPhases, appMock.CompileResult.FoundationPhases) {
+		t.Fatalf("bad: %#v", ctx.AppPhases)
+	}
+	for _, p := range foundation.AppPhases {
+		_, err := os.Stat(foundation.AppPhaseDir(ctx.Dir, p))
+		expected := p == foundation.AppPhaseBuild || p == foundation.AppPhaseDeploy
+		if expected != (err == nil) {
+			t.Fatalf("bad: %s %v", p, err)
+		}
+	}
+
+	md, err := core.CompileMetadata()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(md.App.FoundationPhases, ctx.AppPhases) {
+		t.Fatalf("bad: %#v", md.App.FoundationPhases)
+	}
+
+	// Not declaring the phases has all of them
+	appMock.CompileResult = &app.CompileResult{}
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, p := range foundation.AppPhases {
+		if _, err := os.Stat(foundation.AppPhaseDir(f.CompileContext.Dir, p)); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	md, err = core.CompileMetadata()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(md.App.FoundationPhases, foundation.AppPhases) {
+		t.Fatalf("bad: %#v", md.App.FoundationPhases)
+	}
+
+	// Unknown phases are an error
+	appMock.CompileResult = &app.CompileResult{FoundationPhases: []string{"nope"}}
+	err = core.Compile()
+	if err == nil || !strings.Contains(err.Error(), "unknown foundation phase 'nope'") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+// testPhaseFoundation is a foundation that writes the subdirectories of
+// every app phase when it's compiled.
+type testPhaseFoundation struct {
+	foundation.Mock
+}
+
+func (f *testPhaseFoundation) Compile(ctx *foundation.Context) (*foundation.CompileResult, error) {
+	for _, p := range foundation.AppPhases {
+		if err := os.MkdirAll(foundation.AppPhaseDir(ctx.Dir, p), 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	return f.Mock.Compile(ctx)
+}
+
+func TestCoreCompile_foundationAppConfig(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	appMock := new(app.MockFoundationConfigurer)
+	appMock.FoundationConfigResult = &foundation.Config{ServiceName: "web"}
+	appMock.CompileResult = &app.CompileResult{
+		FoundationConfig: foundation.Config{ServiceName: "web"},
+	}
+	coreConfig.Apps[TestAppTuple] = func() (app.App, error) { return appMock, nil }
+	f := testConfigFoundationCore(t, coreConfig)
+
+	// Both passes for the app have the configuration of the app
+	if err := f.Core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.FoundationConfigCalled {
+		t.Fatal("should be called")
+	}
+	if actual := f.AppOutputs(t); !reflect.DeepEqual(actual, []string{"web", "web"}) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestCoreCompile_foundationAppConfigPrevious(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	appMock.CompileResult = &app.CompileResult{
+		FoundationConfig: foundation.Config{ServiceName: "web"},
+	}
+	f := testConfigFoundationCore(t, coreConfig)
+
+	// The first compile knows nothing before the app is compiled
+	if err := f.Core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual := f.AppOutputs(t); !reflect.DeepEqual(actual, []string{"none", "web"}) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Compiling again uses the configuration of the previous compile
+	f.Outputs = nil
+	if err := f.Core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual := f.AppOutputs(t); !reflect.DeepEqual(actual, []string{"web", "web"}) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+// testConfigFoundation is a foundation that writes the service name of
+// the app configuration it's compiled with, and records what it wrote
+// for each compilation.
+type testConfigFoundation struct {
+	foundation.Mock
+
+	Core    *Core
+	Outputs []string
+}
+
+func (f *testConfigFoundation) Compile(ctx *foundation.Context) (*foundation.CompileResult, error) {
+	output := "none"
+	if ctx.AppConfig != nil {
+		output = ctx.AppConfig.ServiceName
+	}
+	if err := os.MkdirAll(ctx.Dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(ctx.Dir, "service")
+	if err := ioutil.WriteFile(path, []byte(output), 0644); err != nil {
+		return nil, err
+	}
+	f.Outputs = append(f.Outputs, output)
+
+	return f.Mock.Compile(ctx)
+}
+
+// AppOutputs returns the outputs of the compilations for the app, which
+// are after the one of the foundation itself.
+func (f *testConfigFoundation) AppOutputs(t *testing.T) []string {
+	if len(f.Outputs) != 3 {
+		t.Fatalf("bad: %#v", f.Outputs)
+	}
+
+	return f.Outputs[1:]
+}
+
+// testConfigFoundationCore returns a testConfigFoundation for the consul
+// foundation and the core with the foundation enabled.
+func testConfigFoundationCore(t *testing.T, c *CoreConfig) *testConfigFoundation {
+	result := new(testConfigFoundation)
+	c.Foundations = map[foundation.Tuple]foundation.Factory{
+		foundation.Tuple{Type: "consul", Infra: "*", InfraFlavor: "*"}: func() (foundation.Foundation, error) {
+			return result, nil
+		},
+	}
+	result.Core = testCore(t, c)
+	result.Core.appfile.ActiveInfrastructure().Foundations = []*appfile.Foundation{
+		&appfile.Foundation{Name: "consul"},
+	}
+
+	return result
 }
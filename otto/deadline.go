@@ -0,0 +1,80 @@
+package otto
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrOperationTimeout is returned when an operation takes longer than its
+// timeout in CoreConfig.OperationTimeouts. The deadline is only checked
+// between the phases of an operation, such as between the compilation of
+// two apps, so a phase that is running is never interrupted.
+type ErrOperationTimeout struct {
+	// Op is the operation, such as "compile", and Timeout is its timeout.
+	Op      string
+	Timeout time.Duration
+
+	// Completed are the phases that completed before the deadline, in the
+	// order they completed, and Next is the phase that wasn't started.
+	Completed []string
+	Next      string
+}
+
+func (e *ErrOperationTimeout) Error() string {
+	result := fmt.Sprintf(
+		"%s exceeded its timeout of %s before the %s", e.Op, e.Timeout, e.Next)
+	if len(e.Completed) > 0 {
+		result += fmt.Sprintf(" (completed: %s)", strings.Join(e.Completed, ", "))
+	}
+
+	return result
+}
+
+// opDeadline is the deadline of a single operation. The phases of an
+// operation call Check before they start and Done once they complete.
+// This is safe for concurrent use since the apps are walked in parallel.
+type opDeadline struct {
+	op      string
+	timeout time.Duration
+	at      time.Time
+
+	lock      sync.Mutex
+	completed []string
+}
+
+// deadline returns the deadline for a new operation op, starting now.
+// Operations without a timeout have a deadline that never passes.
+func (c *Core) deadline(op string) *opDeadline {
+	result := &opDeadline{op: op, timeout: c.opTimeouts[op]}
+	if result.timeout > 0 {
+		result.at = time.Now().Add(result.timeout)
+	}
+
+	return result
+}
+
+// Check returns an ErrOperationTimeout if the deadline passed, so that
+// the phase next shouldn't be started.
+func (d *opDeadline) Check(next string) error {
+	if d.at.IsZero() || time.Now().Before(d.at) {
+		return nil
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return &ErrOperationTimeout{
+		Op:        d.op,
+		Timeout:   d.timeout,
+		Completed: append([]string(nil), d.completed...),
+		Next:      next,
+	}
+}
+
+// Done records that the phase completed.
+func (d *opDeadline) Done(phase string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.completed = append(d.completed, phase)
+}
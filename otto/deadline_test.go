@@ -0,0 +1,75 @@
+package otto
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreCompile_operationTimeout(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps-order", "Appfile"))
+	coreConfig.SerialWalk = true
+	coreConfig.OperationTimeouts = map[string]time.Duration{
+		"compile": 50 * time.Millisecond,
+	}
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+
+	var order []string
+	appMock.CompileFunc = func(ctx *app.Context) (*app.CompileResult, error) {
+		order = append(order, ctx.Appfile.Application.Name)
+		if ctx.Appfile.Application.Name == "cache" {
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		return nil, nil
+	}
+	core := testCore(t, coreConfig)
+
+	err := core.Compile()
+	timeout, ok := errwrap.GetType(err, new(ErrOperationTimeout)).(*ErrOperationTimeout)
+	if !ok {
+		t.Fatalf("err: %v", err)
+	}
+	if timeout.Op != "compile" || timeout.Next != "app 'alpha'" {
+		t.Fatalf("bad: %#v", timeout)
+	}
+	expected := []string{"infrastructure", "app 'cache'"}
+	if !reflect.DeepEqual(timeout.Completed, expected) {
+		t.Fatalf("bad: %#v", timeout.Completed)
+	}
+	if !reflect.DeepEqual(order, []string{"cache"}) {
+		t.Fatalf("bad: %#v", order)
+	}
+}
+
+func TestCoreBuild_operationTimeout(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	uiMock := new(ui.Mock)
+	uiMock.AddAnswer("^creds_password$", "hunter2")
+	coreConfig.Ui = uiMock
+	coreConfig.OperationTimeouts = map[string]time.Duration{
+		"build": time.Nanosecond,
+	}
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+
+	err := core.Build()
+	timeout, ok := errwrap.GetType(err, new(ErrOperationTimeout)).(*ErrOperationTimeout)
+	if !ok || timeout.Next != "build" {
+		t.Fatalf("err: %v", err)
+	}
+	if appMock.BuildCalled {
+		t.Fatal("build should not be called")
+	}
+
+	// Other operations have no timeout
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
@@ -0,0 +1,303 @@
+package otto
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/helper/localaddr"
+)
+
+// DebugBundleOpts are the options for Core.DebugBundle.
+type DebugBundleOpts struct {
+	// Version is the version of Otto to record in the bundle.
+	Version string
+
+	// ExcludeSource, if true, doesn't include any content that comes
+	// from the source of the application, such as the Appfiles.
+	ExcludeSource bool
+
+	// Secrets are additional values to redact from the bundle. Secrets
+	// entered as hidden input through the Core's Ui are always redacted.
+	Secrets []string
+}
+
+// debugEnv is the environment information stored in a debug bundle.
+type debugEnv struct {
+	Version     string   `json:"version"`
+	OS          string   `json:"os"`
+	Arch        string   `json:"arch"`
+	GoVersion   string   `json:"go_version"`
+	Apps        []string `json:"apps"`
+	Infras      []string `json:"infras"`
+	Foundations []string `json:"foundations"`
+}
+
+// debugDirectory is the summary of the directory records for the root
+// application stored in a debug bundle. Only the states and keys of the
+// records are stored, never their values.
+type debugDirectory struct {
+	Dev          string   `json:"dev,omitempty"`
+	DevPorts     []int    `json:"dev_ports,omitempty"`
+	Build        []string `json:"build,omitempty"`
+	Deploy       string   `json:"deploy,omitempty"`
+	Infra        string   `json:"infra,omitempty"`
+	InfraOutputs []string `json:"infra_outputs,omitempty"`
+	Err          string   `json:"error,omitempty"`
+}
+
+// debugDiskUsage is the summary of the disk usage of Otto stored in a
+// debug bundle.
+type debugDiskUsage struct {
+	Size       int64                      `json:"size"`
+	Files      int64                      `json:"files"`
+	Categories map[string]*debugDiskTotal `json:"categories,omitempty"`
+	Err        string                     `json:"error,omitempty"`
+}
+
+// debugDiskTotal is the disk usage of a category in debugDiskUsage.
+type debugDiskTotal struct {
+	Size  int64 `json:"size"`
+	Files int64 `json:"files"`
+}
+
+// debugLocalAddr is the health, summary, and leases of the local address
+// database stored in a debug bundle.
+type debugLocalAddr struct {
+	Summary *localaddr.Summary     `json:"summary,omitempty"`
+	Leases  []*localaddr.LeaseInfo `json:"leases,omitempty"`
+	Err     string                 `json:"error,omitempty"`
+}
+
+// DebugBundle writes a gzipped tar archive to w with the information
+// needed to diagnose a problem with Otto: the environment, the plugins,
+// the compile metadata, the Appfiles, the operation log files, the disk
+// usage of each category of data, a summary of the directory records,
+// and a summary of the local address database.
+//
+// All known secrets are redacted from the contents of the bundle.
+func (c *Core) DebugBundle(w io.Writer, opts *DebugBundleOpts) error {
+	if opts == nil {
+		opts = new(DebugBundleOpts)
+	}
+	for _, s := range opts.Secrets {
+		c.secrets.AddSecret(s)
+	}
+
+	gzipW := gzip.NewWriter(w)
+	tarW := tar.NewWriter(gzipW)
+	add := func(name string, data []byte) error {
+		data = []byte(c.secrets.Redact(string(data)))
+		err := tarW.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: time.Now(),
+		})
+		if err == nil {
+			_, err = tarW.Write(data)
+		}
+		if err != nil {
+			return fmt.Errorf("Error writing '%s' to debug bundle: %s", name, err)
+		}
+
+		return nil
+	}
+	addJSON := func(name string, v interface{}) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		return add(name, data)
+	}
+
+	// Environment
+	if err := addJSON("env.json", c.debugEnv(opts)); err != nil {
+		return err
+	}
+
+	// The implementations and where they come from. Versions are
+	// best-effort since a broken plugin is often why there's a bundle.
+	inventory, err := c.Plugins()
+	if err != nil {
+		return err
+	}
+	if err := inventory.LoadVersions(); err != nil {
+		c.logger.Warn("error loading plugin versions for debug bundle", "err", err)
+	}
+	if err := addJSON("plugins.json", inventory); err != nil {
+		return err
+	}
+
+	// Compile metadata, if we've compiled
+	data, err := ioutil.ReadFile(filepath.Join(c.compileDir, "metadata.json"))
+	if err == nil {
+		err = add("compile/metadata.json", data)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// Appfiles
+	if !opts.ExcludeSource {
+		for i, raw := range c.appfileCompiled.Graph.Vertices() {
+			f := raw.(*appfile.CompiledGraphVertex).File
+			if f.Path == "" {
+				continue
+			}
+
+			data, err := ioutil.ReadFile(f.Path)
+			if err != nil {
+				return fmt.Errorf(
+					"Error reading Appfile for debug bundle: %s", err)
+			}
+
+			name := fmt.Sprintf("appfiles/%d-%s/Appfile", i, f.Application.Name)
+			if err := add(name, data); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Operation log files
+	logs, err := opLogs(c.opLogDir())
+	if err != nil {
+		return fmt.Errorf("Error listing log files for debug bundle: %s", err)
+	}
+	for _, path := range logs {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("Error reading log file for debug bundle: %s", err)
+		}
+		if err := add("logs/"+filepath.Base(path), data); err != nil {
+			return err
+		}
+	}
+
+	// Disk usage, only the totals of each category since the entries
+	// name the apps.
+	if err := addJSON("disk_usage.json", c.debugDiskUsage()); err != nil {
+		return err
+	}
+
+	// Directory records
+	if err := addJSON("directory.json", c.debugDirectory()); err != nil {
+		return err
+	}
+
+	// Local address database, only if it exists since loading it
+	// would create it otherwise. If it is unhealthy we only record why.
+	ipDB := c.devIPDB()
+	if _, err := os.Stat(ipDB.Path); err == nil {
+		var result debugLocalAddr
+		if err := ipDB.Verify(); err != nil {
+			result.Err = err.Error()
+		} else {
+			result.Summary, err = ipDB.Summary()
+			if err != nil {
+				return fmt.Errorf(
+					"Error reading local address database: %s", err)
+			}
+			result.Leases, err = ipDB.Dump()
+			if err != nil {
+				return fmt.Errorf(
+					"Error reading local address database: %s", err)
+			}
+		}
+		if err := addJSON("localaddr.json", &result); err != nil {
+			return err
+		}
+	}
+
+	if err := tarW.Close(); err != nil {
+		return err
+	}
+	return gzipW.Close()
+}
+
+func (c *Core) debugEnv(opts *DebugBundleOpts) *debugEnv {
+	result := &debugEnv{
+		Version:   opts.Version,
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		GoVersion: runtime.Version(),
+	}
+	for t := range c.apps {
+		result.Apps = append(result.Apps, t.String())
+	}
+	for t := range c.infras {
+		result.Infras = append(result.Infras, t)
+	}
+	for t := range c.foundationMap {
+		result.Foundations = append(result.Foundations, t.String())
+	}
+	sort.Strings(result.Apps)
+	sort.Strings(result.Infras)
+	sort.Strings(result.Foundations)
+
+	return result
+}
+
+func (c *Core) debugDiskUsage() *debugDiskUsage {
+	var result debugDiskUsage
+	usage, err := c.DiskUsage()
+	if err != nil {
+		result.Err = err.Error()
+		return &result
+	}
+
+	result.Size = usage.Size
+	result.Files = usage.Files
+	result.Categories = make(map[string]*debugDiskTotal)
+	for _, t := range usage.Totals() {
+		result.Categories[t.Category] = &debugDiskTotal{
+			Size:  t.Size,
+			Files: t.Files,
+		}
+	}
+
+	return &result
+}
+
+func (c *Core) debugDirectory() *debugDirectory {
+	infoCh := make(chan *statusInfo, 1)
+	c.statusInfo(infoCh)
+	info := <-infoCh
+
+	var result debugDirectory
+	if info.Err != nil {
+		result.Err = info.Err.Error()
+	}
+	if info.Dev != nil {
+		result.Dev = info.Dev.State.String()
+	}
+	result.DevPorts = info.DevPorts
+	if info.Build != nil {
+		for k := range info.Build.Artifact {
+			result.Build = append(result.Build, k)
+		}
+		sort.Strings(result.Build)
+	}
+	if info.Deploy != nil {
+		result.Deploy = info.Deploy.State.String()
+	}
+	if info.Infra != nil {
+		result.Infra = info.Infra.State.String()
+		for k := range info.Infra.Outputs {
+			result.InfraOutputs = append(result.InfraOutputs, k)
+		}
+		sort.Strings(result.InfraOutputs)
+	}
+
+	return &result
+}
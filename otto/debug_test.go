@@ -0,0 +1,107 @@
+package otto
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreDebugBundle(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("customization-app-filter", "Appfile"))
+	core := testCore(t, coreConfig)
+
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var buf bytes.Buffer
+	err := core.DebugBundle(&buf, &DebugBundleOpts{
+		Version: "1.2.3",
+		Secrets: []string{"nope"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	files := testReadBundle(t, &buf)
+	for _, name := range []string{"env.json", "plugins.json", "compile/metadata.json", "directory.json", "disk_usage.json"} {
+		if _, ok := files[name]; !ok {
+			t.Fatalf("missing %s: %#v", name, files)
+		}
+	}
+	if !strings.Contains(files["env.json"], `"1.2.3"`) {
+		t.Fatalf("bad: %s", files["env.json"])
+	}
+	if !strings.Contains(files["plugins.json"], PluginOriginBuiltin) {
+		t.Fatalf("bad: %s", files["plugins.json"])
+	}
+	if !strings.Contains(files["disk_usage.json"], `"compile"`) {
+		t.Fatalf("bad: %s", files["disk_usage.json"])
+	}
+
+	var appfile string
+	for name, data := range files {
+		if strings.HasPrefix(name, "appfiles/") {
+			appfile = data
+		}
+	}
+	if appfile == "" {
+		t.Fatalf("missing appfile: %#v", files)
+	}
+	if strings.Contains(appfile, "nope") || !strings.Contains(appfile, ui.RedactedText) {
+		t.Fatalf("bad: %s", appfile)
+	}
+}
+
+func TestCoreDebugBundle_excludeSource(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("customization-app-filter", "Appfile"))
+	core := testCore(t, coreConfig)
+
+	var buf bytes.Buffer
+	err := core.DebugBundle(&buf, &DebugBundleOpts{ExcludeSource: true})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for name, _ := range testReadBundle(t, &buf) {
+		if strings.HasPrefix(name, "appfiles/") {
+			t.Fatalf("bad: %s", name)
+		}
+	}
+}
+
+func testReadBundle(t *testing.T, r io.Reader) map[string]string {
+	gzipR, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	result := make(map[string]string)
+	tarR := tar.NewReader(gzipR)
+	for {
+		hdr, err := tarR.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		data, err := ioutil.ReadAll(tarR)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		result[hdr.Name] = string(data)
+	}
+
+	return result
+}
@@ -0,0 +1,160 @@
+package otto
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/directory"
+)
+
+// Dependents are the apps of the project that depend on an app, returned
+// by Core.Dependents.
+type Dependents struct {
+	// Known is false if the directory has no dependency edges for the
+	// project, such as when it was last compiled by an older version of
+	// Otto. Nothing else is set then.
+	Known bool
+
+	// Direct are the apps that depend on the app directly, sorted by name.
+	Direct []*DependentApp
+
+	// Unknown are the apps whose dependencies aren't known, so they may
+	// also depend on the app, sorted by name.
+	Unknown []*DependentApp
+}
+
+// DependentApp is an app in Dependents.
+type DependentApp struct {
+	ID   string
+	Name string
+}
+
+// appGraph is the record of the dependency edges of the apps of a project
+// stored in the directory by Compile, keyed by the ID of the app.
+type appGraph struct {
+	Apps map[string]*appGraphApp `json:"apps"`
+}
+
+// appGraphApp is a single app in an appGraph.
+type appGraphApp struct {
+	Name string `json:"name"`
+
+	// Deps are the IDs of the apps this app depends on directly. This is
+	// nil if they aren't known, and empty if it has no dependencies.
+	Deps []string `json:"deps"`
+}
+
+// Dependents returns the apps of the project that depend on the app with
+// the given ID, from the dependency edges stored in the directory by the
+// last Compile. This doesn't need the app to be in the Appfile of this
+// Core, so a shared app can be looked up from any project.
+func (c *Core) Dependents(id string) (*Dependents, error) {
+	graph, err := c.loadAppGraph()
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(Dependents)
+	if graph == nil {
+		return result, nil
+	}
+
+	result.Known = true
+	for depID, a := range graph.Apps {
+		if depID == id {
+			continue
+		}
+
+		dep := &DependentApp{ID: depID, Name: a.Name}
+		if a.Deps == nil {
+			result.Unknown = append(result.Unknown, dep)
+			continue
+		}
+		for _, v := range a.Deps {
+			if v == id {
+				result.Direct = append(result.Direct, dep)
+				break
+			}
+		}
+	}
+	sort.Sort(dependentAppsByName(result.Direct))
+	sort.Sort(dependentAppsByName(result.Unknown))
+
+	return result, nil
+}
+
+// dependentAppsByName sorts dependent apps by name, and then by ID.
+type dependentAppsByName []*DependentApp
+
+func (s dependentAppsByName) Len() int      { return len(s) }
+func (s dependentAppsByName) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s dependentAppsByName) Less(i, j int) bool {
+	if s[i].Name != s[j].Name {
+		return s[i].Name < s[j].Name
+	}
+
+	return s[i].ID < s[j].ID
+}
+
+// appGraphKey is the key of the blob in the directory with the dependency
+// edges of the apps of the project. The edges are the same in every
+// environment, so this isn't scoped to one.
+func (c *Core) appGraphKey() string {
+	return fmt.Sprintf("app-graph-%s", c.appfile.Project.Name)
+}
+
+// loadAppGraph returns the stored dependency edges of the project, or nil
+// if there are none.
+func (c *Core) loadAppGraph() (*appGraph, error) {
+	data, err := c.dir.GetBlob(c.appGraphKey())
+	if err != nil {
+		return nil, errwrap.Wrapf(
+			"Error loading the app dependencies: {{err}}", directoryErr(err))
+	}
+	if data == nil {
+		return nil, nil
+	}
+	defer data.Close()
+
+	var result appGraph
+	if err := json.NewDecoder(data.Data).Decode(&result); err != nil {
+		return nil, fmt.Errorf("Error loading the app dependencies: %s", err)
+	}
+
+	return &result, nil
+}
+
+// storeAppGraph stores the direct dependencies of every app in the
+// Appfile graph in the directory.
+func (c *Core) storeAppGraph() error {
+	graph := &appGraph{Apps: make(map[string]*appGraphApp)}
+	for _, raw := range c.appfileCompiled.Graph.Vertices() {
+		v := raw.(*appfile.CompiledGraphVertex)
+		a := &appGraphApp{Name: v.File.Application.Name, Deps: []string{}}
+		for _, rawDep := range c.appfileCompiled.Graph.DownEdges(v).List() {
+			a.Deps = append(a.Deps, rawDep.(*appfile.CompiledGraphVertex).File.ID)
+		}
+		sort.Strings(a.Deps)
+
+		graph.Apps[v.File.ID] = a
+	}
+
+	raw, err := json.Marshal(graph)
+	if err != nil {
+		return err
+	}
+
+	err = c.dir.PutBlob(c.appGraphKey(), &directory.BlobData{
+		Data: bytes.NewReader(raw),
+	})
+	if err != nil {
+		return errwrap.Wrapf(
+			"Error storing the app dependencies: {{err}}", directoryErr(err))
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package otto
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/otto/directory"
+)
+
+func TestCoreDependents(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps-order", "Appfile"))
+	core := testCore(t, coreConfig)
+	cache, err := core.depVertex("cache")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Nothing is known before the Appfile is compiled
+	result, err := core.Dependents(cache.File.ID)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if result.Known {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	result, err = core.Dependents(cache.File.ID)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var names []string
+	for _, a := range result.Direct {
+		names = append(names, a.Name)
+	}
+	if !result.Known || len(result.Unknown) != 0 {
+		t.Fatalf("bad: %#v", result)
+	}
+	if !reflect.DeepEqual(names, []string{"alpha", "web"}) {
+		t.Fatalf("bad: %#v", names)
+	}
+
+	// Nothing depends on the root
+	result, err = core.Dependents(core.appfile.ID)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(result.Direct) != 0 {
+		t.Fatalf("bad: %#v", result.Direct)
+	}
+}
+
+func TestCoreDependents_unknown(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps-order", "Appfile"))
+	core := testCore(t, coreConfig)
+
+	// An app without its dependencies may depend on anything
+	raw := `{"apps": {"a": {"name": "old"}, "b": {"name": "new", "deps": []}}}`
+	err := core.dir.PutBlob(core.appGraphKey(), &directory.BlobData{
+		Data: bytes.NewReader([]byte(raw)),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	result, err := core.Dependents("c")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !result.Known || len(result.Direct) != 0 {
+		t.Fatalf("bad: %#v", result)
+	}
+	if len(result.Unknown) != 1 || result.Unknown[0].Name != "old" {
+		t.Fatalf("bad: %#v", result.Unknown)
+	}
+}
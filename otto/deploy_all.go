@@ -0,0 +1,180 @@
+package otto
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+// DeployAllOpts are the options for Core.DeployAll.
+type DeployAllOpts struct {
+	// Force, if true, deploys the applications that are already deployed
+	// again instead of skipping them.
+	Force bool
+
+	// Confirm and AutoApprove are like the options of DeployOpts, for the
+	// whole sequence of deploys.
+	Confirm     bool
+	AutoApprove bool
+}
+
+// The results of the applications of DeployAll.
+const (
+	deployAllDeployed = "deployed"
+	deployAllSkipped  = "already deployed"
+	deployAllFailed   = "failed"
+	deployAllPending  = "not deployed"
+)
+
+// DeployAll deploys all the applications of the Appfile graph in order:
+// every application is deployed after the applications it depends on, so
+// the root application is last. The applications that are already
+// deployed in the environment, according to the directory, are skipped
+// unless opts.Force is set.
+//
+// The deploys stop at the first failure, and the summary shows which
+// applications were deployed and which weren't.
+func (c *Core) DeployAll(opts *DeployAllOpts) (err error) {
+	if opts == nil {
+		opts = new(DeployAllOpts)
+	}
+
+	unlock, err := c.lockProject("deploy", false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	start := time.Now()
+	c.startOutput()
+	op := c.operation("deploy.all", nil)
+	defer c.cacheAppContexts()()
+	defer func() {
+		err = c.finishWarnings(err)
+		op.End(err)
+	}()
+
+	// The applications are deployed in the order of the serial walk
+	order := c.walkOrder()
+	vertexes := make(map[string]*appfile.CompiledGraphVertex, len(order))
+	results := make(map[string]string, len(order))
+	for _, v := range order {
+		vertexes[v.File.ID] = v
+		deploy, err := c.dir.GetDeploy(&directory.Deploy{
+			Lookup: c.deployLookup(v.File.ID)})
+		if err != nil {
+			return errwrap.Wrapf(
+				"Error loading deploy status: {{err}}", directoryErr(err))
+		}
+
+		results[v.File.ID] = deployAllPending
+		if deploy.IsDeployed() && !opts.Force {
+			results[v.File.ID] = deployAllSkipped
+		}
+	}
+
+	if opts.Confirm {
+		table := &ui.Table{
+			Headers:  []string{"", "NAME", "ACTION"},
+			MaxWidth: ui.TerminalWidth(),
+		}
+		for i, v := range order {
+			action := "deploy"
+			if results[v.File.ID] == deployAllSkipped {
+				action = "skip, already deployed"
+			}
+			table.AddRow(fmt.Sprintf("%d.", i+1), v.File.Application.Name, action)
+		}
+
+		c.ui.Header("Deploy plan")
+		ui.Info(c.ui, fmt.Sprintf("Environment: %s\n", c.environment))
+		ui.Info(c.ui, table.String())
+		if err := c.approveDeploy("the applications", opts.AutoApprove); err != nil {
+			return err
+		}
+	}
+
+	infra, infraCtx, err := c.infra()
+	if err != nil {
+		return err
+	}
+	defer maybeClose(infra)
+	if err := c.creds(infra, infraCtx); err != nil {
+		return err
+	}
+
+	var failed string
+	err = c.walk(true, func(impl app.App, ctx *app.Context, root bool) error {
+		name := ctx.Appfile.Application.Name
+		if results[ctx.Appfile.ID] == deployAllSkipped {
+			ui.Info(ctx.Ui, fmt.Sprintf(
+				"'%s' is already deployed, skipping", name))
+			return nil
+		}
+
+		if err := c.checkPluginSkew(impl, ctx); err != nil {
+			return err
+		}
+
+		c.ui.Header(fmt.Sprintf("Deploying '%s'...", name))
+		ctx.Shared.InfraCreds = infraCtx.Shared.InfraCreds
+		if err := c.depOutputs(vertexes[ctx.Appfile.ID], ctx); err != nil {
+			return err
+		}
+		if err := c.artifacts(ctx); err != nil {
+			return err
+		}
+		if err := impl.Deploy(ctx); err != nil {
+			results[ctx.Appfile.ID] = deployAllFailed
+			failed = name
+			return err
+		}
+		results[ctx.Appfile.ID] = deployAllDeployed
+
+		name, err := c.labelDeploy(ctx.Appfile.ID)
+		if err != nil {
+			c.logger.Warn("error labeling the deploy", "err", err)
+		}
+		if root {
+			err := c.saveLastDeploy(&lastDeployRecord{
+				DeployedAt: time.Now().UTC(),
+				Build:      name,
+			})
+			if err != nil {
+				c.logger.Warn("error storing the deploy time", "err", err)
+			}
+		}
+
+		return nil
+	})
+
+	table := &ui.Table{
+		Headers:  []string{"NAME", "RESULT"},
+		MaxWidth: ui.TerminalWidth(),
+	}
+	for _, v := range order {
+		table.AddRow(v.File.Application.Name, results[v.File.ID])
+	}
+	c.ui.Header("Deploy summary")
+	ui.Info(c.ui, table.String())
+
+	if err != nil {
+		if failed == "" {
+			return err
+		}
+
+		return errwrap.Wrapf(fmt.Sprintf(
+			"Error deploying '%s'. The applications after it weren't deployed: {{err}}",
+			failed), err)
+	}
+
+	ui.Result(c.ui, fmt.Sprintf(
+		"Deployed '%s' with its dependencies in %s",
+		c.appfile.Application.Name, summaryDuration(time.Since(start))))
+	return nil
+}
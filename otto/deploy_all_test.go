@@ -0,0 +1,115 @@
+package otto
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreDeployAll(t *testing.T) {
+	core, appMock, uiMock := testDeployAllCore(t)
+
+	// Already deployed apps are skipped
+	db, err := core.depVertex("db")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	deploy := &directory.Deploy{Lookup: core.deployLookup(db.File.ID)}
+	deploy.MarkSuccessful()
+	if err := core.dir.PutDeploy(deploy); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := core.DeployAll(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []string{"cache", "alpha", "web"}
+	if !reflect.DeepEqual(appMock.Deployed, expected) {
+		t.Fatalf("bad: %#v", appMock.Deployed)
+	}
+	uiMock.AssertMessageContains(t, "already deployed")
+
+	// Forcing deploys everything
+	appMock.Deployed = nil
+	if err := core.DeployAll(&DeployAllOpts{Force: true}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected = []string{"cache", "alpha", "db", "web"}
+	if !reflect.DeepEqual(appMock.Deployed, expected) {
+		t.Fatalf("bad: %#v", appMock.Deployed)
+	}
+}
+
+func TestCoreDeployAll_fail(t *testing.T) {
+	core, appMock, uiMock := testDeployAllCore(t)
+
+	// The deploys stop at the first failure
+	appMock.FailOn = "alpha"
+	err := core.DeployAll(nil)
+	if err == nil || !strings.Contains(err.Error(), "Error deploying 'alpha'") {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(appMock.Deployed, []string{"cache"}) {
+		t.Fatalf("bad: %#v", appMock.Deployed)
+	}
+	uiMock.AssertMessageContains(t, "failed")
+	uiMock.AssertMessageContains(t, "not deployed")
+}
+
+func TestCoreDeployAll_confirm(t *testing.T) {
+	core, appMock, uiMock := testDeployAllCore(t)
+
+	uiMock.InputResult = "no"
+	err := core.DeployAll(&DeployAllOpts{Confirm: true})
+	if err != ErrDeployDeclined {
+		t.Fatalf("bad: %v", err)
+	}
+	if len(appMock.Deployed) > 0 {
+		t.Fatalf("bad: %#v", appMock.Deployed)
+	}
+	uiMock.AssertMessageContains(t, "4.")
+
+	uiMock.InputResult = "yes"
+	if err := core.DeployAll(&DeployAllOpts{Confirm: true}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(appMock.Deployed) != 4 {
+		t.Fatalf("bad: %#v", appMock.Deployed)
+	}
+}
+
+// testDeployAllApp is an app that records the names of the applications
+// it deploys, and fails to deploy the one named FailOn.
+type testDeployAllApp struct {
+	app.Mock
+
+	Deployed []string
+	FailOn   string
+}
+
+func (a *testDeployAllApp) Deploy(ctx *app.Context) error {
+	name := ctx.Appfile.Application.Name
+	if name == a.FailOn {
+		return errors.New("deploy failed")
+	}
+
+	a.Deployed = append(a.Deployed, name)
+	return nil
+}
+
+func testDeployAllCore(t *testing.T) (*Core, *testDeployAllApp, *ui.Mock) {
+	uiMock := new(ui.Mock)
+	appMock := new(testDeployAllApp)
+	core := TestCore(t, &TestCoreOpts{
+		Path: testPath("dev-deps-order", "Appfile"),
+		App:  appMock,
+		Ui:   uiMock,
+	})
+
+	return core, appMock, uiMock
+}
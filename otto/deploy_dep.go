@@ -0,0 +1,253 @@
+package otto
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+// DeployDep is Deploy for the dependency named name instead of the root
+// application, such as to redeploy a shared database. The name is the
+// application name of the dependency, or its ID if more than one
+// dependency has the same name.
+//
+// The dependency is deployed with its own context and compilation, so
+// the deploy is recorded under its identity in the directory and the
+// deploy of the root application isn't changed. The dependencies of the
+// dependency must already be deployed.
+func (c *Core) DeployDep(name string, action string, args []string) (err error) {
+	// The help and info actions only read the local data
+	if action != "help" && action != "info" {
+		unlock, err := c.lockProject("deploy", false)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	start := time.Now()
+	c.startOutput()
+	op := c.operation("deploy.dep", map[string]string{"dep": name, "action": action})
+	defer c.cacheAppContexts()()
+	defer func() {
+		err = c.finishWarnings(err)
+		op.End(err)
+	}()
+
+	v, err := c.depVertex(name)
+	if err != nil {
+		return err
+	}
+	depName := v.File.Application.Name
+
+	// Destroying a deploy requires typing the application name
+	if action == "destroy" {
+		err := c.confirm(&confirmOpts{
+			Id: "destroy",
+			Message: fmt.Sprintf(
+				"Otto will delete all resources associated with the deploy of\n"+
+					"the dependency '%s'.", depName),
+			Details: []string{
+				fmt.Sprintf("Dependency: %s", depName),
+				fmt.Sprintf("Infrastructure: %s", c.appfile.Project.Infrastructure),
+			},
+			Expected: depName,
+			Args:     args,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if action == "" {
+		if err := c.depsDeployed(v); err != nil {
+			return err
+		}
+	}
+
+	infra, infraCtx, err := c.infra()
+	if err != nil {
+		return err
+	}
+	defer maybeClose(infra)
+
+	// Special case: don't try to fetch creds during `help` or `info`
+	if action != "help" && action != "info" {
+		if err := c.creds(infra, infraCtx); err != nil {
+			return err
+		}
+	}
+
+	ctx, err := c.appContext(v.File)
+	if err != nil {
+		return errwrap.Wrapf(fmt.Sprintf(
+			"Error loading Appfile for '%s': {{err}}", depName), err)
+	}
+	impl, err := c.app(ctx)
+	if err != nil {
+		return errwrap.Wrapf(fmt.Sprintf(
+			"Error loading App implementation for '%s': {{err}}", depName), err)
+	}
+	defer maybeClose(impl)
+	if err := c.checkPluginSkew(impl, ctx); err != nil {
+		return err
+	}
+
+	ctx.Shared.InfraCreds = infraCtx.Shared.InfraCreds
+	ctx.Action = action
+	ctx.ActionArgs = args
+	if action == "" {
+		if err := c.depOutputs(v, ctx); err != nil {
+			return err
+		}
+		if err := c.artifacts(ctx); err != nil {
+			return err
+		}
+	}
+	if err := impl.Deploy(ctx); err != nil {
+		return err
+	}
+
+	if action == "" {
+		if _, err := c.labelDeploy(v.File.ID); err != nil {
+			c.logger.Warn("error labeling the deploy", "err", err)
+		}
+
+		c.ui.Header("Deploy summary")
+		ui.Info(c.ui, c.deploySummary(ctx, time.Since(start)).String())
+	}
+
+	return nil
+}
+
+// depVertex returns the vertex of the dependency named name, which is the
+// application name or the ID of one of the dependencies of the Appfile.
+// The errors list the dependencies that could be meant.
+func (c *Core) depVertex(name string) (*appfile.CompiledGraphVertex, error) {
+	var deps, matches []*appfile.CompiledGraphVertex
+	order := c.walkOrder()
+	for i, v := range order {
+		// The root is last
+		if i == len(order)-1 {
+			if v.File.Application.Name == name || v.File.ID == name {
+				return nil, fmt.Errorf(
+					"'%s' is the application itself, not a dependency. Use\n"+
+						"`otto deploy` to deploy it.", name)
+			}
+
+			continue
+		}
+
+		if v.File.ID == name {
+			return v, nil
+		}
+		if v.File.Application.Name == name {
+			matches = append(matches, v)
+		}
+		deps = append(deps, v)
+	}
+
+	switch {
+	case len(matches) == 1:
+		return matches[0], nil
+	case len(matches) > 1:
+		return nil, fmt.Errorf(
+			"More than one dependency is named '%s'. Use the ID of the one to\n"+
+				"deploy instead:\n\n%s", name, depCandidates(matches))
+	case len(deps) == 0:
+		return nil, fmt.Errorf(
+			"The application '%s' has no dependencies.", c.appfile.Application.Name)
+	default:
+		return nil, fmt.Errorf(
+			"No dependency is named '%s'. The dependencies are:\n\n%s",
+			name, depCandidates(deps))
+	}
+}
+
+// depCandidates returns the list of the dependencies vs for an error.
+func depCandidates(vs []*appfile.CompiledGraphVertex) string {
+	lines := make([]string, len(vs))
+	for i, v := range vs {
+		lines[i] = fmt.Sprintf("  %s (ID: %s)", v.File.Application.Name, v.File.ID)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// depsDeployed returns an error listing the dependencies of the vertex v
+// that aren't deployed in the environment of the core.
+func (c *Core) depsDeployed(v *appfile.CompiledGraphVertex) error {
+	var missing []string
+	for _, raw := range c.appfileCompiled.Graph.DownEdges(v).List() {
+		dep := raw.(*appfile.CompiledGraphVertex)
+		deploy, err := c.dir.GetDeploy(&directory.Deploy{
+			Lookup: c.deployLookup(dep.File.ID)})
+		if err != nil {
+			return errwrap.Wrapf(
+				"Error loading deploy status: {{err}}", directoryErr(err))
+		}
+		if !deploy.IsDeployed() {
+			missing = append(missing, dep.File.Application.Name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf(
+			"The dependency '%s' depends on applications that aren't deployed:\n"+
+				"%s. Deploy them first with `otto deploy -dep NAME`.",
+			v.File.Application.Name, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// depOutputs sets the DepOutputs of the context ctx of the app of the
+// vertex v from the deploys of its dependencies in the environment. The
+// dependencies that aren't deployed are left out, and a warning lists the
+// deployed ones without deploy information, which the app likely needs.
+func (c *Core) depOutputs(v *appfile.CompiledGraphVertex, ctx *app.Context) error {
+	var missing []string
+	result := make(map[string]map[string]string)
+	for _, raw := range c.appfileCompiled.Graph.DownEdges(v).List() {
+		dep := raw.(*appfile.CompiledGraphVertex)
+		deploy, err := c.dir.GetDeploy(&directory.Deploy{
+			Lookup: c.deployLookup(dep.File.ID)})
+		if err != nil {
+			return errwrap.Wrapf(
+				"Error loading deploy status: {{err}}", directoryErr(err))
+		}
+		if !deploy.IsDeployed() {
+			continue
+		}
+
+		name := dep.File.Application.Name
+		if len(deploy.Deploy) == 0 {
+			missing = append(missing, name)
+			continue
+		}
+
+		outputs := make(map[string]string, len(deploy.Deploy))
+		for k, v := range deploy.Deploy {
+			outputs[k] = v
+		}
+		result[name] = outputs
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		ui.Warn(c.ui, fmt.Sprintf(
+			"These dependencies of '%s' are deployed but have no deploy outputs,\n"+
+				"so their connection details aren't available to it: %s",
+			v.File.Application.Name, strings.Join(missing, ", ")))
+	}
+
+	ctx.DepOutputs = result
+	return nil
+}
@@ -0,0 +1,185 @@
+package otto
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreDeployDep(t *testing.T) {
+	uiMock := new(ui.Mock)
+	uiMock.AddAnswer("^creds_password$", "hunter2")
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps-order", "Appfile"))
+	coreConfig.Ui = uiMock
+	infraMock := TestInfra(t, "test", coreConfig)
+	infraMock.CredsResult = map[string]string{"key": "secret"}
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	appMock.CompileFunc = func(ctx *app.Context) (*app.CompileResult, error) {
+		return &app.CompileResult{Version: 1, DevPorts: len(ctx.Appfile.Application.Name)}, nil
+	}
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testDeployed(t, core, map[string]string{"url": "http://web.com"})
+
+	alpha, err := core.depVertex("alpha")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cache, err := core.depVertex("cache")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The dependencies of the dependency must be deployed
+	err = core.DeployDep("alpha", "", nil)
+	if err == nil || !strings.Contains(err.Error(), "aren't deployed:\ncache.") {
+		t.Fatalf("err: %v", err)
+	}
+	if appMock.DeployCalled {
+		t.Fatal("deploy should not be called")
+	}
+
+	deploy := &directory.Deploy{Lookup: core.deployLookup(cache.File.ID)}
+	deploy.MarkSuccessful()
+	if err := core.dir.PutDeploy(deploy); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := core.DeployDep("alpha", "", nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The dependency is deployed with its own context and compilation
+	ctx := appMock.DeployContext
+	if ctx.Appfile.ID != alpha.File.ID || ctx.CompileResult.DevPorts != len("alpha") {
+		t.Fatalf("bad: %#v", ctx)
+	}
+	if ctx.Shared.InfraCreds["key"] != "secret" {
+		t.Fatalf("bad: %#v", ctx.Shared.InfraCreds)
+	}
+
+	// The deploy of the root isn't changed
+	root, err := core.dir.GetDeploy(&directory.Deploy{
+		Lookup: core.deployLookup(core.appfile.ID)})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !root.IsDeployed() || !reflect.DeepEqual(root.Deploy, map[string]string{"url": "http://web.com"}) {
+		t.Fatalf("bad: %#v", root)
+	}
+}
+
+func TestCoreDeployDep_name(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps-order", "Appfile"))
+	core := testCore(t, coreConfig)
+
+	err := core.DeployDep("nope", "", nil)
+	if err == nil || !strings.Contains(err.Error(), "No dependency is named 'nope'") {
+		t.Fatalf("err: %v", err)
+	}
+	for _, name := range []string{"alpha", "cache", "db"} {
+		if !strings.Contains(err.Error(), "  "+name+" (ID: ") {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	err = core.DeployDep("web", "", nil)
+	if err == nil || !strings.Contains(err.Error(), "the application itself") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestCoreDeployDep_ambiguous(t *testing.T) {
+	uiMock := new(ui.Mock)
+	uiMock.AddAnswer("^creds_password$", "hunter2")
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("deploy-dep", "Appfile"))
+	coreConfig.Ui = uiMock
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+
+	// Both dependencies are named "db", so one must be chosen by ID
+	err := core.DeployDep("db", "", nil)
+	if err == nil || !strings.Contains(err.Error(), "More than one") {
+		t.Fatalf("err: %v", err)
+	}
+	ids := []string{
+		"0b6f4a3e-1c2d-4e5f-8a9b-7c6d5e4f3a21",
+		"9e8d7c6b-5a4f-4e3d-9c2b-1a0f9e8d7c62",
+	}
+	for _, id := range ids {
+		if !strings.Contains(err.Error(), id) {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	if err := core.DeployDep(ids[1], "", nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if appMock.DeployContext.Appfile.ID != ids[1] {
+		t.Fatalf("bad: %#v", appMock.DeployContext)
+	}
+}
+
+func TestCoreDeploy_depOutputs(t *testing.T) {
+	uiMock := new(ui.Mock)
+	uiMock.AddAnswer("^creds_password$", "hunter2")
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps-order", "Appfile"))
+	coreConfig.Ui = uiMock
+	appMock := new(app.MockPlanner)
+	coreConfig.Apps[TestAppTuple] = func() (app.App, error) {
+		return appMock, nil
+	}
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// "db" has outputs, "cache" is deployed without any, and "alpha"
+	// isn't deployed. The labels of the deploys aren't outputs.
+	deploys := map[string]map[string]string{
+		"db":    map[string]string{"address": "db.service.consul"},
+		"cache": nil,
+	}
+	for name, info := range deploys {
+		v, err := core.depVertex(name)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		deploy := &directory.Deploy{
+			Lookup: core.deployLookup(v.File.ID),
+			Deploy: info,
+		}
+		deploy.MarkSuccessful()
+		if err := core.dir.PutDeploy(deploy); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if _, err := core.labelDeploy(v.File.ID); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	opts := &DeployOpts{Confirm: true, AutoApprove: true}
+	if err := core.DeployWithOpts("", nil, opts); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := map[string]map[string]string{
+		"db": map[string]string{"address": "db.service.consul"},
+	}
+	if actual := appMock.DeployContext.DepOutputs; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+	if actual := appMock.PlanContext.DepOutputs; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+	uiMock.AssertMessageContains(t, "no deploy outputs")
+	uiMock.AssertMessageContains(t, ": cache")
+}
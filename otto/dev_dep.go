@@ -0,0 +1,214 @@
+package otto
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/ui"
+)
+
+// devDepFingerprint returns the fingerprint of a dependency, which
+// identifies the dev dependency it builds in the global store. Every
+// project that depends on the same application at the same version gets
+// the same fingerprint.
+func (c *Core) devDepFingerprint(ctx *app.Context) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00",
+		ctx.Tuple.App, ctx.Tuple.Infra, ctx.Tuple.InfraFlavor, ctx.Appfile.ID)
+
+	// The Appfile of the dependency is the configuration it's built with
+	if ctx.Appfile.Path != "" {
+		data, err := ioutil.ReadFile(ctx.Appfile.Path)
+		if err != nil {
+			return "", err
+		}
+
+		h.Write(data)
+	}
+
+	var version uint32
+	if ctx.CompileResult != nil {
+		version = ctx.CompileResult.Version
+	}
+	if err := binary.Write(h, binary.BigEndian, version); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// devDepGenerationFile is the file in the cache directory of a dependency
+// with the generation of the compilation its dev dependency was cached
+// for, see appCacheGeneration.
+const devDepGenerationFile = "dev-dep.generation"
+
+// devDep builds the dev dependency for the dependency with the context
+// ctx, or loads it from the cache. This returns true if the cached
+// dependency was used.
+//
+// The dependency is cached in its own cache directory, and in the
+// global store so that other projects with the same dependency don't
+// have to build it again. If it isn't cached, it is fetched from the
+// DevDepSource before it is built.
+func (c *Core) devDep(appImpl app.App, rootCtx, ctx *app.Context) (bool, error) {
+	name := ctx.Appfile.Application.Name
+
+	// Get the path to where we'd cache the dependency if we have
+	// cached it...
+	cachePath := filepath.Join(ctx.CacheDir, "dev-dep.json")
+
+	// The cached dependency is only used for the compilation it was
+	// cached for, anything else is stale.
+	data, err := ioutil.ReadFile(filepath.Join(ctx.CacheDir, devDepGenerationFile))
+	if err != nil || string(data) != appCacheGeneration(ctx.CompileResult) {
+		if err := os.Remove(cachePath); err != nil && !os.IsNotExist(err) {
+			return false, fmt.Errorf(
+				"Error removing stale dev dependency '%s': %s", name, err)
+		}
+	}
+
+	// Check if we've cached this. If so, then use the cache. A corrupt
+	// cache is built again, just as if there was no cache.
+	_, err = app.ReadDevDep(cachePath)
+	if err == nil {
+		ctx.Ui.Header(fmt.Sprintf(
+			"Using cached dev dependency for '%s'", name))
+		return true, nil
+	}
+	if app.IsCorruptDevDep(err) {
+		ui.Warn(ctx.Ui, fmt.Sprintf(
+			"The cached dev dependency '%s' is corrupt and will be built\n"+
+				"again: %s", name, err))
+	}
+
+	// Check if another project has built this. If the store can't be
+	// used, we just build the dependency.
+	store := c.devDepStore()
+	fp, err := c.devDepFingerprint(ctx)
+	if err != nil {
+		ui.Warn(ctx.Ui, fmt.Sprintf(
+			"Error fingerprinting dev dependency '%s', it won't be\n"+
+				"shared with other projects: %s", name, err))
+		fp = ""
+	}
+	if fp != "" {
+		ok, err := store.Get(fp, ctx.CacheDir)
+		if err != nil {
+			ui.Warn(ctx.Ui, fmt.Sprintf(
+				"Error loading dev dependency '%s' from the global cache,\n"+
+					"it will be built: %s", name, err))
+		}
+		if ok {
+			if err := stampDevDep(ctx); err != nil {
+				c.logger.Warn("error stamping dev dependency",
+					"name", name, "err", err)
+			}
+
+			ctx.Ui.Header(fmt.Sprintf(
+				"Using cached dev dependency for '%s' from the global cache", name))
+			return true, nil
+		}
+	}
+
+	// Check if it was published prebuilt. If it can't be fetched, we just
+	// build the dependency.
+	if fp != "" {
+		ok, err := c.fetchDevDep(fp, ctx.CacheDir)
+		if err != nil {
+			ui.Warn(ctx.Ui, fmt.Sprintf(
+				"Error fetching prebuilt dev dependency '%s', it will be\n"+
+					"built: %s", name, err))
+			os.Remove(cachePath)
+		}
+		if ok {
+			if err := stampDevDep(ctx); err != nil {
+				c.logger.Warn("error stamping dev dependency",
+					"name", name, "err", err)
+			}
+
+			dep, err := app.ReadDevDep(cachePath)
+			if err == nil {
+				err = store.Put(fp, ctx.CacheDir, dep)
+			}
+			if err != nil {
+				ui.Warn(ctx.Ui, fmt.Sprintf(
+					"Error adding dev dependency '%s' to the global cache: %s",
+					name, err))
+			}
+
+			ctx.Ui.Header(fmt.Sprintf(
+				"Using prebuilt dev dependency for '%s'", name))
+			return true, nil
+		}
+	}
+
+	return false, c.buildDevDep(appImpl, rootCtx, ctx, fp)
+}
+
+// buildDevDep builds the dev dependency for the dependency with the
+// context ctx and caches it in its cache directory. If fp isn't empty,
+// the dependency is also added to the global store with that fingerprint.
+func (c *Core) buildDevDep(appImpl app.App, rootCtx, ctx *app.Context, fp string) error {
+	name := ctx.Appfile.Application.Name
+	cachePath := filepath.Join(ctx.CacheDir, "dev-dep.json")
+
+	// Copy the root context so it isn't modified by the call below
+	rootCtxCopy := *rootCtx
+
+	// Build the development dependency
+	ui.Debug(ctx.Ui, fmt.Sprintf("Calling DevDep for '%s'", name))
+	dep, err := appImpl.DevDep(&rootCtxCopy, ctx)
+	if err != nil {
+		return fmt.Errorf(
+			"Error building dependency for dev '%s': %s", name, err)
+	}
+
+	// If we have a dependency with files, then verify the files
+	// and store it in our cache directory so we can retrieve it
+	// later.
+	if dep != nil && len(dep.Files) > 0 {
+		if err := dep.RelFiles(ctx.CacheDir); err != nil {
+			return fmt.Errorf(
+				"Error caching dependency for dev '%s': %s", name, err)
+		}
+		if err := dep.ValidateFiles(ctx.CacheDir); err != nil {
+			return fmt.Errorf(
+				"Error caching dependency for dev '%s': the app %s\n"+
+					"returned an %s", name, ctx.Tuple, err)
+		}
+
+		if err := app.WriteDevDep(cachePath, dep); err != nil {
+			return fmt.Errorf(
+				"Error caching dependency for dev '%s': %s", name, err)
+		}
+		if err := stampDevDep(ctx); err != nil {
+			return fmt.Errorf(
+				"Error caching dependency for dev '%s': %s", name, err)
+		}
+
+		if fp != "" {
+			if err := c.devDepStore().Put(fp, ctx.CacheDir, dep); err != nil {
+				ui.Warn(ctx.Ui, fmt.Sprintf(
+					"Error adding dev dependency '%s' to the global cache: %s",
+					name, err))
+			}
+		}
+	}
+
+	return nil
+}
+
+// stampDevDep stamps the dev dependency in the cache directory of the
+// dependency with the context ctx with the generation of its current
+// compilation.
+func stampDevDep(ctx *app.Context) error {
+	return ioutil.WriteFile(
+		filepath.Join(ctx.CacheDir, devDepGenerationFile),
+		[]byte(appCacheGeneration(ctx.CompileResult)), 0644)
+}
@@ -0,0 +1,383 @@
+package otto
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/ui"
+)
+
+// ErrDevDepNotFound is returned by a DevDepSource when it doesn't have
+// the requested dev dependency.
+var ErrDevDepNotFound = errors.New("dev dependency not found")
+
+// DevDepSource is a source of prebuilt dev dependencies, such as ones
+// published by CI with Core.PublishDevDep. Dev dependencies are looked up
+// by the same fingerprint as the global cache of dev dependencies uses.
+type DevDepSource interface {
+	// Fetch returns the artifact of the dev dependency with the given
+	// fingerprint, which is a gzipped tar of the cache directory of the
+	// dependency. This returns ErrDevDepNotFound if the source doesn't
+	// have it.
+	Fetch(fingerprint string) (io.ReadCloser, error)
+}
+
+// DevDepSink is where Core.PublishDevDep publishes dev dependencies.
+type DevDepSink interface {
+	// Publish stores the artifact of the dev dependency with the given
+	// fingerprint, in the same format that DevDepSource.Fetch returns.
+	Publish(fingerprint string, r io.Reader) error
+}
+
+// HTTPDevDepSource is a DevDepSource and DevDepSink for an HTTP server.
+// The artifact of a dev dependency is at URL/<fingerprint>.tar.gz and its
+// hex-encoded SHA-256 checksum is at URL/<fingerprint>.tar.gz.sha256.
+// Artifacts are fetched with GET and published with PUT.
+type HTTPDevDepSource struct {
+	// URL is the base URL of the artifacts.
+	URL string
+
+	// AuthHeader, if set, is sent as the Authorization header of every
+	// request.
+	AuthHeader string
+}
+
+// Fetch implements DevDepSource. The artifact is downloaded completely
+// and verified against its checksum before it is returned.
+func (s *HTTPDevDepSource) Fetch(fp string) (io.ReadCloser, error) {
+	url := s.artifactURL(fp)
+	var checksum bytes.Buffer
+	if err := s.get(url+".sha256", &checksum); err != nil {
+		return nil, err
+	}
+
+	f, err := ioutil.TempFile("", "otto-dev-dep")
+	if err != nil {
+		return nil, err
+	}
+	result := &tempFile{File: f}
+
+	h := sha256.New()
+	if err := s.get(url, io.MultiWriter(f, h)); err != nil {
+		result.Close()
+		return nil, err
+	}
+
+	expected := strings.TrimSpace(checksum.String())
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != expected {
+		result.Close()
+		return nil, fmt.Errorf(
+			"checksum mismatch for %s: expected %s, got %s", url, expected, actual)
+	}
+
+	if _, err := f.Seek(0, 0); err != nil {
+		result.Close()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Publish implements DevDepSink.
+func (s *HTTPDevDepSource) Publish(fp string, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	// The checksum is published last so that the artifact is never
+	// fetched before it is complete.
+	url := s.artifactURL(fp)
+	sum := sha256.Sum256(data)
+	if err := s.put(url, data); err != nil {
+		return err
+	}
+
+	return s.put(url+".sha256", []byte(hex.EncodeToString(sum[:])))
+}
+
+func (s *HTTPDevDepSource) artifactURL(fp string) string {
+	return fmt.Sprintf("%s/%s.tar.gz", strings.TrimRight(s.URL, "/"), fp)
+}
+
+func (s *HTTPDevDepSource) get(url string, w io.Writer) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrDevDepNotFound
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("unexpected status for %s: %s", url, resp.Status)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func (s *HTTPDevDepSource) put(url string, data []byte) error {
+	req, err := http.NewRequest("PUT", url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status for %s: %s", url, resp.Status)
+	}
+
+	return nil
+}
+
+func (s *HTTPDevDepSource) do(req *http.Request) (*http.Response, error) {
+	if s.AuthHeader != "" {
+		req.Header.Set("Authorization", s.AuthHeader)
+	}
+
+	return cleanhttp.DefaultClient().Do(req)
+}
+
+// tempFile is a temporary file that is removed when it is closed.
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.File.Name())
+	return err
+}
+
+// PublishDevDep builds the dev dependency with the given name, or uses the
+// cached one, and publishes it to sink so that a DevDepSource can fetch it.
+// This is meant to be run by CI so that developers don't have to build
+// the dependency themselves.
+func (c *Core) PublishDevDep(name string, sink DevDepSink) (err error) {
+	op := c.operation("dev.publish", map[string]string{"dependency": name})
+	defer c.cacheAppContexts()()
+	defer func() { op.End(err) }()
+
+	root, err := c.appfileCompiled.Graph.Root()
+	if err != nil {
+		return err
+	}
+	rootCtx, err := c.appContext(root.(*appfile.CompiledGraphVertex).File)
+	if err != nil {
+		return errwrap.Wrapf("Error loading App: {{err}}", err)
+	}
+
+	found := false
+	err = c.walk(c.serialWalk, func(appImpl app.App, ctx *app.Context, root bool) error {
+		if root || ctx.Appfile.Application.Name != name {
+			return nil
+		}
+		found = true
+
+		if _, err := c.devDep(appImpl, rootCtx, ctx); err != nil {
+			return err
+		}
+
+		fp, err := c.devDepFingerprint(ctx)
+		if err != nil {
+			return fmt.Errorf(
+				"Error fingerprinting dev dependency '%s': %s", name, err)
+		}
+		dep, err := app.ReadDevDep(filepath.Join(ctx.CacheDir, "dev-dep.json"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf(
+					"Dev dependency '%s' has no files to publish", name)
+			}
+
+			return err
+		}
+
+		var buf bytes.Buffer
+		if err := packDevDep(&buf, ctx.CacheDir, dep); err != nil {
+			return fmt.Errorf(
+				"Error packing dev dependency '%s': %s", name, err)
+		}
+		if err := sink.Publish(fp, &buf); err != nil {
+			return fmt.Errorf(
+				"Error publishing dev dependency '%s': %s", name, err)
+		}
+
+		ui.Result(ctx.Ui, fmt.Sprintf(
+			"Published dev dependency '%s': %s", name, fp))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("Dependency not found: %s", name)
+	}
+
+	return nil
+}
+
+// fetchDevDep fetches the dev dependency with the given fingerprint from
+// the configured DevDepSource into dir, which is the cache directory of
+// the dependency. This returns false if there is no source or the source
+// doesn't have the dependency.
+func (c *Core) fetchDevDep(fp string, dir string) (bool, error) {
+	if c.devDepSource == nil {
+		return false, nil
+	}
+
+	r, err := c.devDepSource.Fetch(fp)
+	if err != nil {
+		if err == ErrDevDepNotFound {
+			return false, nil
+		}
+
+		return false, err
+	}
+	defer r.Close()
+
+	if err := unpackDevDep(r, dir); err != nil {
+		return false, err
+	}
+
+	// The artifact must be just like a dependency that was built locally
+	dep, err := app.ReadDevDep(filepath.Join(dir, "dev-dep.json"))
+	if err != nil {
+		return false, err
+	}
+	if err := dep.ValidateFiles(dir); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// packDevDep writes the gzipped tar of the dev dependency in dir to w.
+func packDevDep(w io.Writer, dir string, dep *app.DevDep) error {
+	gzipW := gzip.NewWriter(w)
+	tarW := tar.NewWriter(gzipW)
+
+	for _, f := range append(dep.Files, "dev-dep.json") {
+		root := filepath.Join(dir, f)
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(rel)
+			if err := tarW.WriteHeader(header); err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+
+			src, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer src.Close()
+
+			_, err = io.Copy(tarW, src)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tarW.Close(); err != nil {
+		return err
+	}
+
+	return gzipW.Close()
+}
+
+// unpackDevDep unpacks the gzipped tar of a dev dependency into dir.
+// Only regular files and directories inside dir are allowed.
+func unpackDevDep(r io.Reader, dir string) error {
+	gzipR, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gzipR.Close()
+
+	tarR := tar.NewReader(gzipR)
+	for {
+		header, err := tarR.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(name) || name == ".." ||
+			strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path in dev dependency: %s", header.Name)
+		}
+
+		path := filepath.Join(dir, name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+
+			f, err := os.OpenFile(
+				path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tarR)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf(
+				"unsupported file type in dev dependency: %s", header.Name)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package otto
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/ui"
+)
+
+// testDevDepServer is an HTTP server that stores the artifacts PUT to
+// it in memory.
+type testDevDepServer struct {
+	sync.Mutex
+	Files map[string][]byte
+	Auth  []string
+}
+
+func (s *testDevDepServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.Auth = append(s.Auth, r.Header.Get("Authorization"))
+	switch r.Method {
+	case "GET":
+		data, ok := s.Files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write(data)
+	case "PUT":
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		s.Files[r.URL.Path] = data
+	}
+}
+
+func TestCorePublishDevDep(t *testing.T) {
+	server := &testDevDepServer{Files: make(map[string][]byte)}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	source := &HTTPDevDepSource{URL: ts.URL + "/deps", AuthHeader: "Bearer foo"}
+
+	// CI builds and publishes the dependency
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cacheDir := testCachedDevDep(t, core, "afb66054-75d6-43ed-b47b-23d0bace94a8", "hello")
+	if err := os.Remove(filepath.Join(cacheDir, "dev-dep.json")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	appMock.DevDepResult = &app.DevDep{Files: []string{"data/file"}}
+	if err := core.PublishDevDep("bar", source); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(server.Files) != 2 {
+		t.Fatalf("bad: %#v", server.Files)
+	}
+	for _, auth := range server.Auth {
+		if auth != "Bearer foo" {
+			t.Fatalf("bad: %#v", server.Auth)
+		}
+	}
+
+	if err := core.PublishDevDep("nope", source); err == nil {
+		t.Fatal("should error")
+	}
+
+	// A developer fetches it instead of building it
+	coreConfig = TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps", "Appfile"))
+	coreConfig.DevDepSource = source
+	appMock = TestApp(t, TestAppTuple, coreConfig)
+	appMock.DevDepErr = os.ErrInvalid
+	core = testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if appMock.DevDepCalled {
+		t.Fatal("DevDep should not be called")
+	}
+
+	cacheDir = filepath.Join(
+		coreConfig.DataDir, "cache", "afb66054-75d6-43ed-b47b-23d0bace94a8")
+	data, err := ioutil.ReadFile(filepath.Join(cacheDir, "data", "file"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(data) != "hello" {
+		t.Fatalf("bad: %s", data)
+	}
+
+	// It is also in the global cache
+	entries, err := core.devDepStore().Entries()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("bad: %#v", entries)
+	}
+}
+
+func TestCoreDev_devDepSourceChecksum(t *testing.T) {
+	server := &testDevDepServer{Files: make(map[string][]byte)}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps", "Appfile"))
+	coreConfig.DevDepSource = &HTTPDevDepSource{URL: ts.URL}
+	coreConfig.Ui = uiMock
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The artifact has the wrong checksum
+	fp := testDevDepFingerprint(t, core, "bar")
+	server.Files["/"+fp+".tar.gz"] = []byte("foo")
+	server.Files["/"+fp+".tar.gz.sha256"] = []byte(strings.Repeat("0", 64))
+
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.DevDepCalled {
+		t.Fatal("DevDep should be called")
+	}
+	uiMock.AssertMessageContains(t, "checksum mismatch")
+}
+
+// testDevDepFingerprint returns the fingerprint of the dependency with
+// the given name.
+func testDevDepFingerprint(t *testing.T, core *Core, name string) string {
+	var fp string
+	err := core.walk(true, func(appImpl app.App, ctx *app.Context, root bool) error {
+		if root || ctx.Appfile.Application.Name != name {
+			return nil
+		}
+
+		var err error
+		fp, err = core.devDepFingerprint(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return fp
+}
+
+func TestUnpackDevDep_invalid(t *testing.T) {
+	td, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	var buf bytes.Buffer
+	gzipW := gzip.NewWriter(&buf)
+	tarW := tar.NewWriter(gzipW)
+	err = tarW.WriteHeader(&tar.Header{
+		Name:     "../evil",
+		Mode:     0644,
+		Size:     3,
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tarW.Write([]byte("foo"))
+	tarW.Close()
+	gzipW.Close()
+
+	err = unpackDevDep(&buf, filepath.Join(td, "dst"))
+	if err == nil || !strings.Contains(err.Error(), "invalid path") {
+		t.Fatalf("bad: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(td, "evil")); !os.IsNotExist(err) {
+		t.Fatalf("bad: %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package otto
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/helper/logger"
+)
+
+// DefaultDevDepCacheSize is the default maximum size in bytes of the
+// global cache of dev dependencies.
+const DefaultDevDepCacheSize int64 = 10 * 1024 * 1024 * 1024
+
+// devDepTempPrefix is the prefix of the temporary directories that
+// entries are written to before they're added to the store.
+const devDepTempPrefix = ".tmp-"
+
+// devDepStore is a content-addressed store of built dev dependencies,
+// shared by all projects. Entries are keyed by the fingerprint of the
+// dependency and contain the dev-dep.json of the dependency along with
+// all its files.
+//
+// Entries are written to a temporary directory and renamed into place,
+// so concurrent Otto processes never see partial entries. The modification
+// time of an entry is the last time it was used, which is used to evict
+// the least recently used entries when the store is too large.
+type devDepStore struct {
+	// Dir is the directory of the store.
+	Dir string
+
+	// MaxSize is the maximum size of the store in bytes. Entries are
+	// evicted when adding an entry makes the store larger than this. If
+	// this is zero or less, the store is never pruned automatically.
+	MaxSize int64
+
+	// Logger is the logger for problems with the store that don't fail
+	// the operation, such as a corrupt entry that is removed.
+	Logger logger.Logger
+}
+
+// devDepEntry is a single entry of the store.
+type devDepEntry struct {
+	Fingerprint string
+	Size        int64
+	LastUsed    time.Time
+}
+
+// devDepStore returns the global store of dev dependencies.
+func (c *Core) devDepStore() *devDepStore {
+	return &devDepStore{
+		Dir:     filepath.Join(c.dataDir, "cas"),
+		MaxSize: c.devDepCacheSize,
+		Logger:  c.logger,
+	}
+}
+
+// Get copies the entry with the given fingerprint into dst, which is
+// the cache directory of the dependency. This returns false if there is
+// no such entry. Corrupt entries are removed and treated as missing.
+func (s *devDepStore) Get(fp string, dst string) (bool, error) {
+	entry := filepath.Join(s.Dir, fp)
+	dep, err := app.ReadDevDep(filepath.Join(entry, "dev-dep.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		if app.IsCorruptDevDep(err) {
+			s.Logger.Warn("removing corrupt dev dependency",
+				"fingerprint", fp, "err", err)
+			return false, os.RemoveAll(entry)
+		}
+
+		return false, err
+	}
+	if err := dep.ValidateFiles(entry); err != nil {
+		return false, fmt.Errorf("dev dependency %s is invalid: %s", fp, err)
+	}
+
+	for _, f := range append(dep.Files, "dev-dep.json") {
+		if err := linkOrCopy(filepath.Join(entry, f), filepath.Join(dst, f)); err != nil {
+			return false, err
+		}
+	}
+
+	// Mark the entry as used
+	now := time.Now()
+	return true, os.Chtimes(entry, now, now)
+}
+
+// Put adds the dependency that was just built in src, which is the
+// cache directory of the dependency, to the store with the given
+// fingerprint. If an entry already exists, it is kept.
+func (s *devDepStore) Put(fp string, src string, dep *app.DevDep) error {
+	entry := filepath.Join(s.Dir, fp)
+	if _, err := os.Stat(entry); err == nil {
+		now := time.Now()
+		return os.Chtimes(entry, now, now)
+	}
+
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	td, err := ioutil.TempDir(s.Dir, devDepTempPrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range append(dep.Files, "dev-dep.json") {
+		if err := linkOrCopy(filepath.Join(src, f), filepath.Join(td, f)); err != nil {
+			os.RemoveAll(td)
+			return err
+		}
+	}
+
+	if err := os.Rename(td, entry); err != nil {
+		os.RemoveAll(td)
+
+		// Another process may have added the same entry first
+		if _, statErr := os.Stat(entry); statErr == nil {
+			return nil
+		}
+
+		return err
+	}
+
+	if s.MaxSize > 0 {
+		if _, err := s.Prune(s.MaxSize); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Entries returns all the entries of the store, least recently used
+// first.
+func (s *devDepStore) Entries() ([]*devDepEntry, error) {
+	infos, err := ioutil.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+
+		return nil, err
+	}
+
+	result := make([]*devDepEntry, 0, len(infos))
+	for _, info := range infos {
+		if !info.IsDir() || strings.HasPrefix(info.Name(), devDepTempPrefix) {
+			continue
+		}
+
+		size, err := dirSize(filepath.Join(s.Dir, info.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &devDepEntry{
+			Fingerprint: info.Name(),
+			Size:        size,
+			LastUsed:    info.ModTime(),
+		})
+	}
+
+	sort.Sort(devDepEntriesByLastUsed(result))
+	return result, nil
+}
+
+// Prune evicts the least recently used entries until the store is at
+// most max bytes, and returns the evicted entries.
+func (s *devDepStore) Prune(max int64) ([]*devDepEntry, error) {
+	entries, err := s.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	var size int64
+	for _, e := range entries {
+		size += e.Size
+	}
+
+	var result []*devDepEntry
+	for _, e := range entries {
+		if size <= max {
+			break
+		}
+
+		if err := os.RemoveAll(filepath.Join(s.Dir, e.Fingerprint)); err != nil {
+			return result, err
+		}
+
+		size -= e.Size
+		result = append(result, e)
+	}
+
+	return result, nil
+}
+
+// devDepEntriesByLastUsed sorts entries by the time they were last used,
+// oldest first.
+type devDepEntriesByLastUsed []*devDepEntry
+
+func (s devDepEntriesByLastUsed) Len() int      { return len(s) }
+func (s devDepEntriesByLastUsed) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s devDepEntriesByLastUsed) Less(i, j int) bool {
+	return s[i].LastUsed.Before(s[j].LastUsed)
+}
+
+// linkOrCopy makes dst a hard link to src, or a copy if that isn't
+// possible, such as across file systems. Directories are copied
+// recursively. Any existing dst is replaced.
+func linkOrCopy(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			rel, err := filepath.Rel(src, path)
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return os.MkdirAll(filepath.Join(dst, rel), info.Mode().Perm())
+			}
+
+			return linkOrCopy(path, filepath.Join(dst, rel))
+		})
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	srcF, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcF.Close()
+
+	dstF, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dstF, srcF); err != nil {
+		dstF.Close()
+		return err
+	}
+
+	return dstF.Close()
+}
+
+// dirSize returns the total size of the files in dir.
+func dirSize(dir string) (int64, error) {
+	var result int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			result += info.Size()
+		}
+
+		return nil
+	})
+
+	return result, err
+}
@@ -0,0 +1,311 @@
+package otto
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/helper/logger"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestDevDepStore(t *testing.T) {
+	td, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	src := testDevDepDir(t, filepath.Join(td, "src"), "hello")
+	store := &devDepStore{Dir: filepath.Join(td, "cas")}
+
+	// Nothing is stored yet
+	dst := filepath.Join(td, "dst")
+	ok, err := store.Get("foo", dst)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if ok {
+		t.Fatal("should not be found")
+	}
+
+	dep := &app.DevDep{Files: []string{"data/file"}}
+	if err := store.Put("foo", src, dep); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ok, err = store.Get("foo", dst)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !ok {
+		t.Fatal("should be found")
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dst, "data", "file"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(data) != "hello" {
+		t.Fatalf("bad: %s", data)
+	}
+	if _, err := app.ReadDevDep(filepath.Join(dst, "dev-dep.json")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// No temporary directories are left behind
+	entries, err := store.Entries()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	infos, err := ioutil.ReadDir(store.Dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(entries) != 1 || len(infos) != 1 {
+		t.Fatalf("bad: %#v %#v", entries, infos)
+	}
+}
+
+func TestDevDepStore_corrupt(t *testing.T) {
+	td, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	var logs bytes.Buffer
+	store := &devDepStore{
+		Dir:    filepath.Join(td, "cas"),
+		Logger: logger.New(&logs),
+	}
+
+	// A write of the entry was interrupted
+	entry := filepath.Join(store.Dir, "foo")
+	if err := os.MkdirAll(entry, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	path := filepath.Join(entry, "dev-dep.json")
+	if err := ioutil.WriteFile(path, []byte(`{"files": [`), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ok, err := store.Get("foo", filepath.Join(td, "dst"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if ok {
+		t.Fatal("should not be found")
+	}
+	if _, err := os.Stat(entry); !os.IsNotExist(err) {
+		t.Fatalf("err: %v", err)
+	}
+	if !strings.Contains(logs.String(), "fingerprint=foo err=") {
+		t.Fatalf("bad: %s", logs.String())
+	}
+}
+
+func TestDevDepStorePrune(t *testing.T) {
+	td, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	store := &devDepStore{Dir: filepath.Join(td, "cas")}
+	dep := &app.DevDep{Files: []string{"data/file"}}
+	for i, fp := range []string{"a", "b", "c"} {
+		src := testDevDepDir(t, filepath.Join(td, fp), "0123456789")
+		if err := store.Put(fp, src, dep); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		// Make the use times distinct
+		used := time.Now().Add(time.Duration(i-10) * time.Minute)
+		if err := os.Chtimes(filepath.Join(store.Dir, fp), used, used); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// Using an entry makes it the most recently used
+	if _, err := store.Get("a", filepath.Join(td, "dst")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	entries, err := store.Entries()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("bad: %#v", entries)
+	}
+
+	evicted, err := store.Prune(entries[0].Size)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(evicted) != 2 || evicted[0].Fingerprint != "b" || evicted[1].Fingerprint != "c" {
+		t.Fatalf("bad: %#v", evicted)
+	}
+
+	ok, err := store.Get("a", filepath.Join(td, "dst"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !ok {
+		t.Fatal("should be found")
+	}
+}
+
+func TestCoreDev_devDepStore(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The dependency builds a file in its cache directory
+	cacheDir := testCachedDevDep(t, core, "afb66054-75d6-43ed-b47b-23d0bace94a8", "hello")
+	if err := os.Remove(filepath.Join(cacheDir, "dev-dep.json")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	appMock.DevDepResult = &app.DevDep{Files: []string{"data/file"}}
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.DevDepCalled {
+		t.Fatal("DevDep should be called")
+	}
+
+	// Another project with the same dependency uses the stored one
+	if err := os.RemoveAll(cacheDir); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	appMock.DevDepCalled = false
+	appMock.DevDepErr = errors.New("should not build")
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if appMock.DevDepCalled {
+		t.Fatal("DevDep should not be called")
+	}
+	if _, err := app.ReadDevDep(filepath.Join(cacheDir, "dev-dep.json")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(cacheDir, "data", "file"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(data) != "hello" {
+		t.Fatalf("bad: %s", data)
+	}
+}
+
+// testDevDepDir writes a dev dependency with a single file with the
+// given contents to dir.
+func testDevDepDir(t *testing.T, dir string, contents string) string {
+	if err := os.MkdirAll(filepath.Join(dir, "data"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	path := filepath.Join(dir, "data", "file")
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	dep := &app.DevDep{Files: []string{"data/file"}}
+	if err := app.WriteDevDep(filepath.Join(dir, "dev-dep.json"), dep); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return dir
+}
+
+// testCachedDevDep writes a dev dependency like testDevDepDir to the
+// cache directory of the app with the given ID, stamped for its current
+// compilation so Dev uses it.
+func testCachedDevDep(t *testing.T, core *Core, id string, contents string) string {
+	md, err := core.compileMetadata()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var result *app.CompileResult
+	if md != nil {
+		result = md.AppDeps[id]
+	}
+
+	dir := testDevDepDir(t, core.appCacheDir(id), contents)
+	gen := []byte(appCacheGeneration(result))
+	path := filepath.Join(dir, devDepGenerationFile)
+	if err := ioutil.WriteFile(path, gen, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return dir
+}
+
+func TestCoreDev_corruptDevDep(t *testing.T) {
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps", "Appfile"))
+	coreConfig.Ui = uiMock
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// A write of the cache was interrupted
+	cacheDir := testCachedDevDep(t, core, "afb66054-75d6-43ed-b47b-23d0bace94a8", "hello")
+	path := filepath.Join(cacheDir, "dev-dep.json")
+	if err := ioutil.WriteFile(path, []byte(`{"files": [`), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.DevDepCalled {
+		t.Fatal("DevDep should be called")
+	}
+	uiMock.AssertMessageContains(t, "is corrupt and will be built")
+}
+
+func TestCoreDev_invalidDevDepFiles(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	appMock.DevDepResult = &app.DevDep{Files: []string{"../../secret"}}
+	err := core.Dev()
+	if err == nil {
+		t.Fatal("should error")
+	}
+	if !strings.Contains(err.Error(), "../../secret") ||
+		!strings.Contains(err.Error(), TestAppTuple.String()) {
+		t.Fatalf("bad: %s", err)
+	}
+
+	// Nothing is cached
+	entries, err := core.devDepStore().Entries()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("bad: %#v", entries)
+	}
+}
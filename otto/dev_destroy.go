@@ -0,0 +1,164 @@
+package otto
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+// DevDestroyOpts are the options for Core.DevDestroy.
+type DevDestroyOpts struct {
+	// Args are the arguments to the destroy action of the app, such as
+	// "-force" to skip the confirmation.
+	Args []string
+
+	// ClearDevDepCache, if true, also deletes the caches of the
+	// dependencies of this project, so they're built again by the next
+	// Dev. The global cache of dev dependencies isn't touched.
+	ClearDevDepCache bool
+}
+
+// DevDestroy destroys the development environment with the destroy action
+// of the app, then releases everything that Otto kept for it: the dev IP
+// addresses and ports, the dev record in the directory, the dev
+// environment info, and the references to dev layers.
+//
+// If the app fails to destroy the dev environment, nothing is released
+// since the environment may still exist. Failing to release something
+// after the environment is destroyed is only a warning, since GC can
+// release it later.
+func (c *Core) DevDestroy(opts *DevDestroyOpts) (err error) {
+	if opts == nil {
+		opts = new(DevDestroyOpts)
+	}
+
+	unlock, err := c.lockProject("dev destroy", false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	c.startOutput()
+	endLog := c.startOpLog("dev-destroy")
+	op := c.operation("dev.destroy", nil)
+	defer c.cacheAppContexts()()
+	defer func() {
+		err = c.finishWarnings(err)
+		op.End(err)
+		endLog(err)
+	}()
+
+	details := []string{
+		fmt.Sprintf("Application: %s", c.appfile.Application.Name),
+	}
+	if opts.ClearDevDepCache {
+		details = append(details, "The caches of the dev dependencies")
+	}
+	err = c.confirm(&confirmOpts{
+		Id:      "destroy",
+		Message: "Otto will delete the local development environment.",
+		Details: details,
+		Args:    opts.Args,
+	})
+	if err != nil {
+		return err
+	}
+
+	appCtx, err := c.appContext(c.appfile)
+	if err != nil {
+		return err
+	}
+	appImpl, err := c.app(appCtx)
+	if err != nil {
+		return err
+	}
+	defer maybeClose(appImpl)
+
+	appCtx.Action = "destroy"
+	appCtx.ActionArgs = opts.Args
+	if err := appImpl.Dev(appCtx); err != nil {
+		return err
+	}
+
+	// The dev environment is gone. Apps delete their dev record
+	// themselves, but we make sure it is gone so Status doesn't show a
+	// dev environment that doesn't exist.
+	err = c.dir.DeleteDev(&directory.Dev{Lookup: directory.Lookup{
+		AppID: c.appfile.ID}})
+	if err != nil {
+		ui.Warn(c.ui, fmt.Sprintf(
+			"The dev environment was destroyed but its record in the "+
+				"directory couldn't be deleted: %s", err))
+	}
+
+	// Its addresses can be reused and its layers deleted once no other
+	// project uses them.
+	if err := c.ReleaseDevIP(); err != nil {
+		ui.Warn(c.ui, fmt.Sprintf(
+			"The dev environment was destroyed but its IP address "+
+				"couldn't be released: %s", err))
+	}
+	if err := c.devPortDB().Release(c.appfile.ID); err != nil {
+		ui.Warn(c.ui, fmt.Sprintf(
+			"The dev environment was destroyed but its ports "+
+				"couldn't be released: %s", err))
+	}
+	if err := c.deleteDevInfo(); err != nil {
+		ui.Warn(c.ui, fmt.Sprintf(
+			"The dev environment was destroyed but its info "+
+				"couldn't be deleted: %s", err))
+	}
+	if err := c.releaseDevLayers(); err != nil {
+		ui.Warn(c.ui, fmt.Sprintf(
+			"The dev environment was destroyed but its dev layers "+
+				"couldn't be released: %s", err))
+	}
+
+	if opts.ClearDevDepCache {
+		c.clearDevDepCaches()
+	}
+
+	return nil
+}
+
+// clearDevDepCaches deletes the caches of the dependencies of the
+// Appfile. Caches in use by another Otto process are skipped.
+func (c *Core) clearDevDepCaches() {
+	root, err := c.appfileCompiled.Graph.Root()
+	if err != nil {
+		ui.Warn(c.ui, fmt.Sprintf(
+			"Error clearing the dev dependency caches: %s", err))
+		return
+	}
+
+	for _, raw := range c.appfileCompiled.Graph.Vertices() {
+		if raw == root {
+			continue
+		}
+
+		f := raw.(*appfile.CompiledGraphVertex).File
+		item := &CacheItem{
+			Kind: "app",
+			Name: f.ID,
+			Path: c.appCacheDir(f.ID),
+		}
+		deleted, err := c.pruneCacheItem(item)
+		if err != nil {
+			ui.Warn(c.ui, fmt.Sprintf(
+				"Error clearing the cache of dev dependency '%s': %s",
+				f.Application.Name, err))
+			continue
+		}
+		if !deleted {
+			ui.Warn(c.ui, fmt.Sprintf(
+				"Skipped clearing the cache of dev dependency '%s', it is\n"+
+					"in use by another Otto process.", f.Application.Name))
+			continue
+		}
+
+		c.ui.Message(fmt.Sprintf(
+			"Cleared the cache of dev dependency '%s'", f.Application.Name))
+	}
+}
@@ -0,0 +1,143 @@
+package otto
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/directory"
+)
+
+func TestCoreDevDestroy(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Force = true
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	appMock.CompileResult = &app.CompileResult{DevPorts: 1}
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testDevReady(t, core)
+
+	if err := core.DevDestroy(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if appMock.DevContext.Action != "destroy" {
+		t.Fatalf("bad: %#v", appMock.DevContext)
+	}
+
+	dev, err := core.dir.GetDev(&directory.Dev{Lookup: directory.Lookup{
+		AppID: core.appfile.ID}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if dev.IsReady() {
+		t.Fatalf("bad: %#v", dev)
+	}
+
+	info, err := core.DevInfo()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if info != nil {
+		t.Fatalf("bad: %#v", info)
+	}
+
+	summary, err := core.devIPDB().Summary()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if summary.Leases != 0 {
+		t.Fatalf("bad: %#v", summary)
+	}
+	ports, err := core.devPortDB().Ports(core.appfile.ID)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(ports) != 0 {
+		t.Fatalf("bad: %#v", ports)
+	}
+}
+
+func TestCoreDevDestroy_failed(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Force = true
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testDevReady(t, core)
+
+	// The dev environment may still exist, so nothing is released
+	appMock.DevErr = errors.New("failed")
+	if err := core.DevDestroy(nil); err == nil {
+		t.Fatal("should error")
+	}
+
+	dev, err := core.dir.GetDev(&directory.Dev{Lookup: directory.Lookup{
+		AppID: core.appfile.ID}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !dev.IsReady() {
+		t.Fatalf("bad: %#v", dev)
+	}
+
+	info, err := core.DevInfo()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if info == nil {
+		t.Fatal("info should exist")
+	}
+
+	summary, err := core.devIPDB().Summary()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if summary.Leases != 1 {
+		t.Fatalf("bad: %#v", summary)
+	}
+}
+
+func TestCoreDevDestroy_clearDevDepCache(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps", "Appfile"))
+	coreConfig.Force = true
+	TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cacheDir := testCachedDevDep(t, core, "afb66054-75d6-43ed-b47b-23d0bace94a8", "hello")
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The caches are kept by default
+	if err := core.DevDestroy(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := os.Stat(cacheDir); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err := core.DevDestroy(&DevDestroyOpts{ClearDevDepCache: true})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := os.Stat(cacheDir); !os.IsNotExist(err) {
+		t.Fatalf("cache should be deleted: %s", err)
+	}
+}
@@ -0,0 +1,117 @@
+package otto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DevInfo is information about the development environment of the
+// application, saved when the dev environment is created.
+type DevInfo struct {
+	// DepAddresses are the dev IP addresses of the dependencies that
+	// were built into the dev environment, by dependency name.
+	DepAddresses map[string]string `json:"dep_addresses"`
+
+	// DepPorts are the dev ports of the dependencies that have any, by
+	// dependency name.
+	DepPorts map[string][]int `json:"dep_ports,omitempty"`
+
+	// CreatedAt is when the dev environment was created and IPAddress is
+	// its dev IP address.
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty"`
+
+	// DepFingerprints are the fingerprints of the dev dependencies that
+	// the dev environment was created with, by dependency name.
+	DepFingerprints map[string]string `json:"dep_fingerprints,omitempty"`
+
+	// CompileHash is the hash of the compilation that the dev environment
+	// was created from. If this differs from the hash of the current
+	// compilation, the dev environment is stale.
+	CompileHash string `json:"compile_hash,omitempty"`
+}
+
+// Stale returns true if the dev environment was created from a different
+// compilation than the given one. Info saved by older versions of Otto
+// is never stale since it has no hash.
+func (i *DevInfo) Stale(md *CompileMetadata) bool {
+	if i == nil || i.CompileHash == "" || md == nil {
+		return false
+	}
+
+	return i.CompileHash != compileMetadataHash(md)
+}
+
+// DevInfo returns the information about the development environment.
+// This returns nil if the development environment was never created
+// with this version of Otto.
+func (c *Core) DevInfo() (*DevInfo, error) {
+	f, err := os.Open(c.devInfoPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+	defer f.Close()
+
+	var result DevInfo
+	dec := json.NewDecoder(f)
+	if err := dec.Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (c *Core) saveDevInfo(info *DevInfo) error {
+	if err := os.MkdirAll(c.localDir, 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(c.devInfoPath())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	return enc.Encode(info)
+}
+
+// deleteDevInfo removes the information about the development
+// environment, since it was destroyed.
+func (c *Core) deleteDevInfo() error {
+	err := os.Remove(c.devInfoPath())
+	if err != nil && os.IsNotExist(err) {
+		err = nil
+	}
+
+	return err
+}
+
+// compileMetadataHash returns a hash of the compilation results in md.
+// The timings are excluded since they change with every compilation.
+func compileMetadataHash(md *CompileMetadata) string {
+	copy := *md
+	copy.Timings = nil
+	copy.Duration = 0
+	copy.Parallelism = 0
+
+	data, err := json.Marshal(&copy)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Core) devInfoPath() string {
+	return filepath.Join(c.localDir, "dev.json")
+}
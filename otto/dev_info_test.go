@@ -0,0 +1,144 @@
+package otto
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreDevInfo(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	info, err := core.DevInfo()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if info.CreatedAt.IsZero() || info.CompileHash == "" {
+		t.Fatalf("bad: %#v", info)
+	}
+	if info.IPAddress != appMock.DevContext.DevIPAddress {
+		t.Fatalf("bad: %#v", info)
+	}
+	if len(info.DepFingerprints) != 1 || info.DepFingerprints["bar"] == "" {
+		t.Fatalf("bad: %#v", info.DepFingerprints)
+	}
+
+	// Running dev again keeps when it was created
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	info2, err := core.DevInfo()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !info2.CreatedAt.Equal(info.CreatedAt) {
+		t.Fatalf("bad: %s %s", info.CreatedAt, info2.CreatedAt)
+	}
+
+	// Destroying it deletes the info
+	err = core.Execute(&ExecuteOpts{
+		Task:   ExecuteTaskDev,
+		Action: "destroy",
+		Args:   []string{"-force"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	info, err = core.DevInfo()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if info != nil {
+		t.Fatalf("bad: %#v", info)
+	}
+}
+
+func TestCoreStatus_devInfo(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testDevReady(t, core)
+
+	uiMock := new(ui.Mock)
+	coreConfig.Ui = uiMock
+	core = testCore(t, coreConfig)
+	if err := core.Status(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, "Dev IP:          "+appMock.DevContext.DevIPAddress)
+	uiMock.AssertMessageContains(t, "Dev created:")
+	uiMock.AssertMessageNotContains(t, "older compilation")
+
+	// The compilation changes
+	appMock.CompileResult = &app.CompileResult{Version: 2}
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	uiMock = new(ui.Mock)
+	coreConfig.Ui = uiMock
+	core = testCore(t, coreConfig)
+	if err := core.Status(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, "older compilation")
+}
+
+func TestCoreStatus_devInfoOld(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testDevReady(t, core)
+
+	// Info saved by an older version of Otto
+	if err := os.MkdirAll(coreConfig.LocalDir, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	err := ioutil.WriteFile(core.devInfoPath(), []byte(`{"dep_addresses": {}}`), 0644)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	uiMock := new(ui.Mock)
+	coreConfig.Ui = uiMock
+	core = testCore(t, coreConfig)
+	if err := core.Status(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, "CREATED")
+	uiMock.AssertMessageNotContains(t, "Dev created:")
+	uiMock.AssertMessageNotContains(t, "older compilation")
+}
+
+// testDevReady marks the dev environment of the core as created, like
+// an app does.
+func testDevReady(t *testing.T, core *Core) {
+	dev := &directory.Dev{Lookup: directory.Lookup{AppID: core.appfile.ID}}
+	dev.MarkReady()
+	if err := core.dir.PutDev(dev); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
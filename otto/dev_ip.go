@@ -0,0 +1,163 @@
+package otto
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/helper/localaddr"
+	"github.com/hashicorp/otto/helper/oneline"
+	"github.com/hashicorp/otto/ui"
+)
+
+// devIPDB returns the database of dev IP addresses.
+func (c *Core) devIPDB() *localaddr.DB {
+	return &localaddr.DB{
+		Path:   filepath.Join(c.dataDir, "ip.db"),
+		Subnet: c.devSubnet,
+		OnRecover: func(path string) {
+			ui.Warn(c.ui, fmt.Sprintf(
+				"The database of dev IP addresses was corrupt and was rebuilt.\n"+
+					"The corrupt database was moved to: %s\n\n"+
+					"This project keeps its dev IP address, but other projects\n"+
+					"may be assigned new addresses the next time they're used,\n"+
+					"which may require reloading their dev environments.", path))
+		},
+	}
+}
+
+// devPortDB returns the database of dev ports.
+func (c *Core) devPortDB() *localaddr.PortDB {
+	return &localaddr.PortDB{Path: filepath.Join(c.dataDir, "ports.db")}
+}
+
+// devIPCachePath returns the path to the file that caches the dev IP
+// address of the given Appfile. The root uses "dev_ip" for compatibility
+// with the single address that was used for all apps before.
+func (c *Core) devIPCachePath(f *appfile.File) string {
+	if f.ID == c.appfile.ID {
+		return filepath.Join(c.localDir, "dev_ip")
+	}
+
+	return filepath.Join(c.localDir, fmt.Sprintf("dev_ip-%s", f.ID))
+}
+
+// devIPOwner returns the owner recorded with the dev IP lease of the
+// given Appfile. The leases of dependencies are owned by this project,
+// since that is where they're cached.
+func (c *Core) devIPOwner(f *appfile.File) *localaddr.Owner {
+	result := &localaddr.Owner{AppID: f.ID}
+	if c.appfile.Path != "" {
+		path, err := filepath.Abs(filepath.Dir(c.appfile.Path))
+		if err == nil {
+			result.Path = path
+		}
+	}
+
+	return result
+}
+
+// ReleaseDevIP releases the dev IP addresses of this project, including
+// the addresses of all the dependencies, and removes the files caching
+// them. The dev environments shouldn't exist anymore, since new addresses
+// are allocated the next time they're needed.
+//
+// This is called automatically when the dev environment is destroyed.
+func (c *Core) ReleaseDevIP() error {
+	paths, err := filepath.Glob(filepath.Join(c.localDir, "dev_ip*"))
+	if err != nil {
+		return err
+	}
+
+	ipDB := c.devIPDB()
+	for _, path := range paths {
+		raw, err := oneline.Read(path)
+		if err != nil {
+			return fmt.Errorf("Error reading dev IP address: %s", err)
+		}
+
+		if ip := net.ParseIP(raw); ip != nil {
+			c.logger.Debug("releasing dev IP", "ip", raw)
+			if err := ipDB.Release(ip); err != nil {
+				return fmt.Errorf("Error releasing dev IP address: %s", err)
+			}
+		}
+
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("Error removing dev IP address cache: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// checkDevIP warns if the dev IP address or the subnet it is allocated
+// from overlaps a network that this machine is already connected to,
+// since the dev environment would be unreachable or break routing.
+//
+// The interfaces are only listed once and each address is only checked
+// once for the lifetime of the Core, so this is cheap to call for
+// every context.
+func (c *Core) checkDevIP(ip net.IP) {
+	c.devIPLock.Lock()
+	defer c.devIPLock.Unlock()
+
+	if c.devIPChecked == nil {
+		c.devIPChecked = make(map[string]struct{})
+	}
+	if _, ok := c.devIPChecked[ip.String()]; ok {
+		return
+	}
+	c.devIPChecked[ip.String()] = struct{}{}
+
+	if c.devNetworks == nil {
+		networks, err := c.interfaces()
+		if err != nil {
+			// Not being able to check isn't worth failing over
+			c.logger.Warn("error listing network interfaces", "err", err)
+			return
+		}
+
+		c.devNetworks = networks
+	}
+
+	conflicts := localaddr.Conflicts(c.devSubnet, c.devNetworks)
+	if len(conflicts) == 0 {
+		return
+	}
+
+	names := make([]string, len(conflicts))
+	for i, n := range conflicts {
+		names[i] = n.String()
+	}
+	ui.Warn(c.ui, fmt.Sprintf(
+		"The dev IP address %s is allocated from the subnet %s, which\n"+
+			"overlaps networks this machine is connected to: %s\n\n"+
+			"The dev environment may be unreachable or break networking\n"+
+			"on this machine. Configure a different dev subnet that doesn't\n"+
+			"overlap these networks to fix this.",
+		ip, c.devSubnet, strings.Join(names, ", ")))
+}
+
+// devIPTable returns a table of the dev IP addresses by app name.
+func devIPTable(ips map[string]string) string {
+	names := make([]string, 0, len(ips))
+	for n := range ips {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	table := &ui.Table{
+		Headers:  []string{"NAME", "DEV IP"},
+		MaxWidth: ui.TerminalWidth(),
+	}
+	for _, n := range names {
+		table.AddRow(n, ips[n])
+	}
+
+	return table.String()
+}
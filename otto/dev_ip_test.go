@@ -0,0 +1,195 @@
+package otto
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreCheckDevIP(t *testing.T) {
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.DevSubnet = "10.42.0.0/16"
+	coreConfig.Ui = uiMock
+	core := testCore(t, coreConfig)
+
+	calls := 0
+	core.interfaces = func() ([]*net.IPNet, error) {
+		calls++
+		_, ipnet, err := net.ParseCIDR("10.0.0.0/8")
+		return []*net.IPNet{ipnet}, err
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := core.appContext(core.appfile); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("bad: %d", calls)
+	}
+
+	uiMock.AssertMessageContains(t, "overlaps networks this machine is connected to")
+}
+
+func TestCoreDev_depIPs(t *testing.T) {
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps", "Appfile"))
+	coreConfig.Ui = uiMock
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Each app gets its own address
+	ips := make(map[string]string)
+	for _, raw := range core.appfileCompiled.Graph.Vertices() {
+		f := raw.(*appfile.CompiledGraphVertex).File
+		ctx, err := core.appContext(f)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		ips[f.Application.Name] = ctx.DevIPAddress
+	}
+	if len(ips) != 2 || ips["foo"] == ips["bar"] {
+		t.Fatalf("bad: %#v", ips)
+	}
+
+	// The root keeps the compatible cache path
+	if _, err := os.Stat(filepath.Join(coreConfig.LocalDir, "dev_ip")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, ips["bar"])
+}
+
+func TestCoreExecute_devDestroyReleasesIP(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+	cachePath := filepath.Join(coreConfig.LocalDir, "dev_ip")
+
+	// A failed destroy keeps the address
+	appMock.DevErr = errors.New("failed")
+	err := core.Execute(&ExecuteOpts{
+		Task:   ExecuteTaskDev,
+		Action: "destroy",
+		Args:   []string{"-force"},
+	})
+	if err == nil {
+		t.Fatal("should error")
+	}
+	if _, err := os.Stat(cachePath); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	appMock.DevErr = nil
+	err = core.Execute(&ExecuteOpts{
+		Task:   ExecuteTaskDev,
+		Action: "destroy",
+		Args:   []string{"-force"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := os.Stat(cachePath); !os.IsNotExist(err) {
+		t.Fatalf("cache should be removed: %s", err)
+	}
+
+	summary, err := core.devIPDB().Summary()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if summary.Leases != 0 {
+		t.Fatalf("bad: %#v", summary)
+	}
+}
+
+func TestCoreDev_ports(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	appMock.CompileResult = &app.CompileResult{DevPorts: 2}
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	ports := appMock.DevContext.DevPorts
+	if len(ports) != 2 {
+		t.Fatalf("bad: %#v", ports)
+	}
+
+	// The ports are stable
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(appMock.DevContext.DevPorts, ports) {
+		t.Fatalf("bad: %#v", appMock.DevContext.DevPorts)
+	}
+
+	// And shown in the status
+	uiMock := new(ui.Mock)
+	coreConfig.Ui = uiMock
+	core = testCore(t, coreConfig)
+	if err := core.Status(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, fmt.Sprintf("Dev ports:       %d, %d", ports[0], ports[1]))
+}
+
+func TestCoreDev_depAddresses(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// No dev environment yet
+	info, err := core.DevInfo()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if info != nil {
+		t.Fatalf("bad: %#v", info)
+	}
+
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	addrs := appMock.DevContext.DevDepAddresses
+	if len(addrs) != 1 || addrs["bar"] == "" {
+		t.Fatalf("bad: %#v", addrs)
+	}
+	if addrs["bar"] == appMock.DevContext.DevIPAddress {
+		t.Fatalf("dependency has the root address: %#v", addrs)
+	}
+
+	info, err = core.DevInfo()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(info.DepAddresses, addrs) {
+		t.Fatalf("bad: %#v", info)
+	}
+}
@@ -0,0 +1,220 @@
+package otto
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/hashicorp/otto/app"
+)
+
+// devLayerNameRegexp matches the valid IDs and versions of dev layers.
+// These are used as directory names so they must be safe ones.
+var devLayerNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// devLayerRefs are the references to the dev layers, kept in the
+// refs.json file in the layers directory. The key is the name of the
+// layer, "<id>/<version>", and the value maps the IDs of the apps that
+// use the layer to the path of their project.
+type devLayerRefs map[string]map[string]string
+
+// devLayersDir returns the directory of the dev layers. Each layer is
+// in the directory <id>/<version> in this directory.
+func (c *Core) devLayersDir() string {
+	return filepath.Join(c.dataDir, "layers")
+}
+
+// prepareDevLayers makes the directories of the dev layers that the app
+// of ctx asked for, references them from the app, and drops the
+// references to the layers the app no longer uses. This returns the
+// layer directories in the order of CompileResult.DevLayers.
+func (c *Core) prepareDevLayers(ctx *app.Context) ([]string, error) {
+	if ctx.CompileResult == nil || len(ctx.CompileResult.DevLayers) == 0 {
+		return nil, c.releaseDevLayers()
+	}
+
+	layers := ctx.CompileResult.DevLayers
+	for _, l := range layers {
+		if !validDevLayerName(l.ID) || !validDevLayerName(l.Version) {
+			return nil, fmt.Errorf(
+				"invalid dev layer %q version %q from the app %s",
+				l.ID, l.Version, ctx.Tuple)
+		}
+	}
+
+	appID := ctx.Appfile.ID
+	projectPath := c.devIPOwner(ctx.Appfile).Path
+	result := make([]string, len(layers))
+	err := c.withDevLayerRefs(func(refs devLayerRefs) error {
+		used := make(map[string]struct{})
+		for i, l := range layers {
+			name := l.ID + "/" + l.Version
+			used[name] = struct{}{}
+
+			dir := filepath.Join(c.devLayersDir(), l.ID, l.Version)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+			if l.FragmentPath != "" {
+				dst := filepath.Join(dir, filepath.Base(l.FragmentPath))
+				if err := linkOrCopy(l.FragmentPath, dst); err != nil {
+					return fmt.Errorf(
+						"Error copying the fragment of dev layer '%s': %s", name, err)
+				}
+			}
+
+			if refs[name] == nil {
+				refs[name] = make(map[string]string)
+			}
+			refs[name][appID] = projectPath
+			result[i] = dir
+		}
+
+		for name, apps := range refs {
+			if _, ok := used[name]; !ok {
+				delete(apps, appID)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// releaseDevLayers drops the references of this project to all the dev
+// layers. The layers are deleted by GC once no project uses them.
+func (c *Core) releaseDevLayers() error {
+	if _, err := os.Stat(filepath.Join(c.devLayersDir(), "refs.json")); os.IsNotExist(err) {
+		return nil
+	}
+
+	return c.withDevLayerRefs(func(refs devLayerRefs) error {
+		for _, apps := range refs {
+			delete(apps, c.appfile.ID)
+		}
+
+		return nil
+	})
+}
+
+// gcDevLayers deletes the dev layers that no project references and
+// returns their names. References from projects that no longer exist are
+// dropped first.
+func (c *Core) gcDevLayers() ([]string, error) {
+	var result []string
+	err := c.withDevLayerRefs(func(refs devLayerRefs) error {
+		for _, apps := range refs {
+			for id, path := range apps {
+				if path == "" {
+					continue
+				}
+				if _, err := os.Stat(path); os.IsNotExist(err) {
+					c.logger.Info("dropping dev layer reference, project is gone",
+						"app", id, "path", path)
+					delete(apps, id)
+				}
+			}
+		}
+
+		// Go through the layers on disk, since a layer may have been made
+		// without its reference being saved.
+		dirs, err := filepath.Glob(filepath.Join(c.devLayersDir(), "*", "*"))
+		if err != nil {
+			return err
+		}
+		for _, dir := range dirs {
+			info, err := os.Stat(dir)
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				continue
+			}
+
+			name := filepath.Base(filepath.Dir(dir)) + "/" + filepath.Base(dir)
+			if len(refs[name]) > 0 {
+				continue
+			}
+
+			if err := os.RemoveAll(dir); err != nil {
+				return err
+			}
+
+			// Remove the directory of the ID if this was the last version,
+			// ignoring the error if it isn't empty.
+			os.Remove(filepath.Dir(dir))
+
+			delete(refs, name)
+			result = append(result, name)
+		}
+
+		for name, apps := range refs {
+			if len(apps) == 0 {
+				delete(refs, name)
+			}
+		}
+
+		return nil
+	})
+
+	sort.Strings(result)
+	return result, err
+}
+
+// withDevLayerRefs calls f with the references to the dev layers and
+// saves them after f returns. Other Otto processes are locked out while
+// f runs.
+func (c *Core) withDevLayerRefs(f func(devLayerRefs) error) error {
+	dir := c.devLayersDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	lock, err := lockFile(filepath.Join(dir, ".lock"), true, true)
+	if err != nil {
+		return fmt.Errorf("Error locking dev layers: %s", err)
+	}
+	defer lock.Close()
+
+	path := filepath.Join(dir, "refs.json")
+	refs := make(devLayerRefs)
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &refs); err != nil {
+			return fmt.Errorf("Error reading dev layer references: %s", err)
+		}
+	}
+
+	if err := f(refs); err != nil {
+		return err
+	}
+
+	data, err = json.MarshalIndent(refs, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so the references are never lost
+	// to an interrupted write.
+	tempPath := path + ".tmp"
+	if err := ioutil.WriteFile(tempPath, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tempPath, path)
+}
+
+func validDevLayerName(v string) bool {
+	return v != "." && v != ".." && devLayerNameRegexp.MatchString(v)
+}
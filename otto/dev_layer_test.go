@@ -0,0 +1,115 @@
+package otto
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+)
+
+func TestCoreDev_layers(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+
+	fragment := filepath.Join(coreConfig.DataDir, "fragment.sh")
+	if err := os.MkdirAll(coreConfig.DataDir, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := ioutil.WriteFile(fragment, []byte("install go"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	appMock.CompileResult = &app.CompileResult{
+		DevLayers: []*app.DevLayer{
+			&app.DevLayer{ID: "go", Version: "1.5", FragmentPath: fragment},
+		},
+	}
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	dir := filepath.Join(coreConfig.DataDir, "layers", "go", "1.5")
+	if !reflect.DeepEqual(appMock.DevContext.DevLayers, []string{dir}) {
+		t.Fatalf("bad: %#v", appMock.DevContext.DevLayers)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "fragment.sh"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(data) != "install go" {
+		t.Fatalf("bad: %s", data)
+	}
+
+	// Another project uses the same layer
+	other, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(other)
+	err = core.withDevLayerRefs(func(refs devLayerRefs) error {
+		refs["go/1.5"]["other"] = other
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Destroying the dev environment keeps the layer for the other project
+	err = core.Execute(&ExecuteOpts{
+		Task:   ExecuteTaskDev,
+		Action: "destroy",
+		Args:   []string{"-force"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	layers, err := core.gcDevLayers()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(layers) != 0 {
+		t.Fatalf("bad: %#v", layers)
+	}
+
+	// Once the other project is gone the layer is deleted
+	if err := os.RemoveAll(other); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	layers, err = core.gcDevLayers()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(layers, []string{"go/1.5"}) {
+		t.Fatalf("bad: %#v", layers)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestCoreDev_layersInvalid(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+
+	appMock.CompileResult = &app.CompileResult{
+		DevLayers: []*app.DevLayer{&app.DevLayer{ID: "..", Version: "1"}},
+	}
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := core.Dev(); err == nil {
+		t.Fatal("should error")
+	}
+	if appMock.DevCalled {
+		t.Fatal("Dev should not be called")
+	}
+}
@@ -0,0 +1,243 @@
+package otto
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/ui"
+)
+
+// devSnapshotNameRegexp matches the valid names of dev snapshots. App
+// types will likely use the names as file names, so they can't have
+// path separators.
+var devSnapshotNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// DevSnapshot saves the state of the development environment as the
+// snapshot with the given name. This requires the app type to implement
+// app.DevSnapshotter.
+func (c *Core) DevSnapshot(name string) (err error) {
+	unlock, err := c.lockProject("dev snapshot", false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	op := c.operation("dev.snapshot", map[string]string{"snapshot": name})
+	defer func() { op.End(err) }()
+
+	if err := validateDevSnapshotName(name); err != nil {
+		return err
+	}
+
+	impl, ctx, err := c.devSnapshotter()
+	if err != nil {
+		return err
+	}
+	defer maybeClose(impl)
+
+	c.ui.Header(fmt.Sprintf("Saving dev snapshot '%s'...", name))
+	if err := impl.DevSnapshot(ctx, name); err != nil {
+		return c.devSnapshotErr(err)
+	}
+
+	// Record the snapshot. The app's list is preferred since it knows the
+	// size, but the snapshot was made either way so this only warns.
+	infos, err := impl.DevSnapshots(ctx)
+	if err != nil {
+		ui.Warn(c.ui, fmt.Sprintf(
+			"Error listing the dev snapshots, the size of the snapshot\n"+
+				"won't be shown: %s", err))
+
+		infos, err = c.devSnapshotRecord()
+		if err != nil {
+			return err
+		}
+		infos = append(devSnapshotsWithout(infos, name), app.SnapshotInfo{
+			Name: name,
+			Time: time.Now().UTC(),
+		})
+	}
+	if err := c.saveDevSnapshotRecord(infos); err != nil {
+		return fmt.Errorf("Error saving the dev snapshots: %s", err)
+	}
+
+	ui.Result(c.ui, fmt.Sprintf("Saved dev snapshot '%s'.", name))
+	return nil
+}
+
+// DevRestore restores the development environment to the snapshot with
+// the given name, replacing its current state. The user is asked to
+// confirm first. This requires the app type to implement
+// app.DevSnapshotter.
+func (c *Core) DevRestore(name string) (err error) {
+	unlock, err := c.lockProject("dev restore", false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	op := c.operation("dev.restore", map[string]string{"snapshot": name})
+	defer func() { op.End(err) }()
+
+	if err := validateDevSnapshotName(name); err != nil {
+		return err
+	}
+
+	impl, ctx, err := c.devSnapshotter()
+	if err != nil {
+		return err
+	}
+	defer maybeClose(impl)
+
+	err = c.confirm(&confirmOpts{
+		Id: "dev_restore_confirm",
+		Message: "Otto will replace the state of the local development " +
+			"environment\nwith a snapshot.",
+		Details: []string{
+			fmt.Sprintf("Application: %s", c.appfile.Application.Name),
+			fmt.Sprintf("Snapshot: %s", name),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	c.ui.Header(fmt.Sprintf("Restoring dev snapshot '%s'...", name))
+	if err := impl.DevRestore(ctx, name); err != nil {
+		return c.devSnapshotErr(err)
+	}
+
+	ui.Result(c.ui, fmt.Sprintf("Restored dev snapshot '%s'.", name))
+	return nil
+}
+
+// DevSnapshots returns the snapshots of the development environment,
+// sorted by name. The recorded snapshots that Status shows are updated
+// with the result. This requires the app type to implement
+// app.DevSnapshotter.
+func (c *Core) DevSnapshots() ([]app.SnapshotInfo, error) {
+	impl, ctx, err := c.devSnapshotter()
+	if err != nil {
+		return nil, err
+	}
+	defer maybeClose(impl)
+
+	result, err := impl.DevSnapshots(ctx)
+	if err != nil {
+		return nil, c.devSnapshotErr(err)
+	}
+	if err := c.saveDevSnapshotRecord(result); err != nil {
+		return nil, fmt.Errorf("Error saving the dev snapshots: %s", err)
+	}
+
+	return result, nil
+}
+
+// devSnapshotter returns the root app as a DevSnapshotter with its
+// context.
+func (c *Core) devSnapshotter() (app.DevSnapshotter, *app.Context, error) {
+	impl, ctx, err := c.App()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, ok := impl.(app.DevSnapshotter)
+	if !ok {
+		maybeClose(impl)
+		return nil, nil, c.devSnapshotErr(app.ErrDevSnapshotNotSupported)
+	}
+
+	return result, ctx, nil
+}
+
+// devSnapshotErr turns app.ErrDevSnapshotNotSupported, which apps served
+// over RPC return, into an error that names the app type.
+func (c *Core) devSnapshotErr(err error) error {
+	if err == app.ErrDevSnapshotNotSupported {
+		return fmt.Errorf(
+			"Dev snapshots are not supported by app type %q.",
+			c.appfile.Application.Type)
+	}
+
+	return err
+}
+
+// devSnapshotRecord returns the recorded snapshots of the development
+// environment, sorted by name. The record is kept when the environment is
+// destroyed since the snapshots are meant to outlive it.
+func (c *Core) devSnapshotRecord() ([]app.SnapshotInfo, error) {
+	f, err := os.Open(c.devSnapshotsPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+	defer f.Close()
+
+	var result []app.SnapshotInfo
+	dec := json.NewDecoder(f)
+	if err := dec.Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// saveDevSnapshotRecord sorts infos by name and records them.
+func (c *Core) saveDevSnapshotRecord(infos []app.SnapshotInfo) error {
+	if err := os.MkdirAll(c.localDir, 0755); err != nil {
+		return err
+	}
+
+	sort.Sort(snapshotInfosByName(infos))
+
+	f, err := os.Create(c.devSnapshotsPath())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	return enc.Encode(infos)
+}
+
+func (c *Core) devSnapshotsPath() string {
+	return filepath.Join(c.localDir, "dev-snapshots.json")
+}
+
+// devSnapshotsWithout returns infos without the snapshot with the given
+// name.
+func devSnapshotsWithout(infos []app.SnapshotInfo, name string) []app.SnapshotInfo {
+	result := make([]app.SnapshotInfo, 0, len(infos))
+	for _, info := range infos {
+		if info.Name != name {
+			result = append(result, info)
+		}
+	}
+
+	return result
+}
+
+func validateDevSnapshotName(v string) error {
+	if v == "." || v == ".." || !devSnapshotNameRegexp.MatchString(v) {
+		return fmt.Errorf(
+			"Invalid snapshot name %q. Snapshot names can only contain\n"+
+				"letters, numbers, '.', '_', and '-'.", v)
+	}
+
+	return nil
+}
+
+type snapshotInfosByName []app.SnapshotInfo
+
+func (s snapshotInfosByName) Len() int           { return len(s) }
+func (s snapshotInfosByName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+func (s snapshotInfosByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
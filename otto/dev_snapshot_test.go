@@ -0,0 +1,194 @@
+package otto
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreDevSnapshot(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	appMock := testAppDevSnapshotter(t, coreConfig)
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	appMock.DevSnapshotsResult = []app.SnapshotInfo{
+		app.SnapshotInfo{Name: "seeded", Time: time.Now().UTC(), Size: 42},
+	}
+	if err := core.DevSnapshot("seeded"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.DevSnapshotCalled || appMock.DevSnapshotName != "seeded" {
+		t.Fatalf("bad: %#v", appMock)
+	}
+
+	// The snapshot is recorded for Status, even after the dev environment
+	// is destroyed
+	if err := core.deleteDevInfo(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	infos, err := core.devSnapshotRecord()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(infos) != 1 || infos[0].Name != "seeded" || infos[0].Size != 42 {
+		t.Fatalf("bad: %#v", infos)
+	}
+
+	uiMock := new(ui.Mock)
+	coreConfig.Ui = uiMock
+	core = testCore(t, coreConfig)
+	if err := core.Status(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, "Dev snapshots:   seeded")
+}
+
+func TestCoreDevSnapshot_listErr(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	appMock := testAppDevSnapshotter(t, coreConfig)
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The snapshot is still recorded if the app can't list them
+	appMock.DevSnapshotsErr = errors.New("broken")
+	for _, name := range []string{"b", "a", "b"} {
+		if err := core.DevSnapshot(name); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	infos, err := core.devSnapshotRecord()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(infos) != 2 || infos[0].Name != "a" || infos[1].Name != "b" {
+		t.Fatalf("bad: %#v", infos)
+	}
+}
+
+func TestCoreDevSnapshot_invalidName(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	appMock := testAppDevSnapshotter(t, coreConfig)
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, name := range []string{"", "..", "a/b", `a\b`, "../a"} {
+		if err := core.DevSnapshot(name); err == nil {
+			t.Fatalf("should error: %q", name)
+		}
+		if err := core.DevRestore(name); err == nil {
+			t.Fatalf("should error: %q", name)
+		}
+	}
+	if appMock.DevSnapshotCalled || appMock.DevRestoreCalled {
+		t.Fatal("should not be called")
+	}
+}
+
+func TestCoreDevSnapshot_notSupported(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err := core.DevSnapshot("foo")
+	if err == nil {
+		t.Fatal("should error")
+	}
+	if !strings.Contains(err.Error(), "not supported by app type") {
+		t.Fatalf("bad: %s", err)
+	}
+
+	if _, err := core.DevSnapshots(); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestCoreDevRestore(t *testing.T) {
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	appMock := testAppDevSnapshotter(t, coreConfig)
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Not confirmed
+	uiMock.InputResult = "no"
+	if err := core.DevRestore("seeded"); err != errDestroyCancelled {
+		t.Fatalf("bad: %#v", err)
+	}
+	if appMock.DevRestoreCalled {
+		t.Fatal("should not be called")
+	}
+
+	uiMock.InputResult = "yes"
+	if err := core.DevRestore("seeded"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.DevRestoreCalled || appMock.DevRestoreName != "seeded" {
+		t.Fatalf("bad: %#v", appMock)
+	}
+}
+
+func TestCoreDevSnapshots(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	appMock := testAppDevSnapshotter(t, coreConfig)
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	appMock.DevSnapshotsResult = []app.SnapshotInfo{
+		app.SnapshotInfo{Name: "b"},
+		app.SnapshotInfo{Name: "a"},
+	}
+	actual, err := core.DevSnapshots()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []app.SnapshotInfo{
+		app.SnapshotInfo{Name: "a"},
+		app.SnapshotInfo{Name: "b"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+// testAppDevSnapshotter adds a mock app that implements
+// app.DevSnapshotter with the test tuple to the core config.
+func testAppDevSnapshotter(t *testing.T, c *CoreConfig) *app.MockDevSnapshotter {
+	if c.Apps == nil {
+		c.Apps = make(map[app.Tuple]app.Factory)
+	}
+
+	result := new(app.MockDevSnapshotter)
+	c.Apps[TestAppTuple] = func() (app.App, error) {
+		return result, nil
+	}
+
+	return result
+}
@@ -0,0 +1,473 @@
+package otto
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/ui"
+)
+
+// DefaultDevWatchDebounce is the default for DevWatchOpts.Debounce.
+const DefaultDevWatchDebounce = 500 * time.Millisecond
+
+// DefaultDevWatchIgnore are the default for DevWatchOpts.Ignore.
+var DefaultDevWatchIgnore = []string{".git", ".hg", ".svn", ".otto", "node_modules"}
+
+// DevWatchOpts are the options for Core.DevWatch.
+type DevWatchOpts struct {
+	// Deps are the names of the dependencies to watch. If this is empty,
+	// all the dependencies with a local source are watched.
+	Deps []string
+
+	// Ignore are the patterns of the files and directories whose changes
+	// are ignored, in the syntax of filepath.Match. The patterns are
+	// matched against every element of the path of a file in the source
+	// directory, so "node_modules" ignores everything in any node_modules
+	// directory. This defaults to DefaultDevWatchIgnore.
+	Ignore []string
+
+	// Debounce is how long the source of a dependency must go without
+	// changes before it is rebuilt, so that a burst of changes such as a
+	// checkout only rebuilds it once. This defaults to
+	// DefaultDevWatchDebounce.
+	Debounce time.Duration
+
+	// ShutdownCh stops watching when it is closed.
+	ShutdownCh <-chan struct{}
+}
+
+// DevWatch watches the source directories of dependencies and rebuilds
+// the dev dependency of a dependency when its source changes. If the root
+// app implements app.DevDepRefresher, the rebuilt dependency is applied
+// to the running dev environment. Otherwise, the dev environment has to
+// be created again to pick up the changes.
+//
+// Only dependencies with a local source can be watched. This blocks until
+// opts.ShutdownCh is closed.
+func (c *Core) DevWatch(opts *DevWatchOpts) (err error) {
+	if opts == nil {
+		opts = new(DevWatchOpts)
+	}
+	ignore := opts.Ignore
+	if ignore == nil {
+		ignore = DefaultDevWatchIgnore
+	}
+	debounce := opts.Debounce
+	if debounce <= 0 {
+		debounce = DefaultDevWatchDebounce
+	}
+
+	op := c.operation("dev.watch", nil)
+	defer func() { op.End(err) }()
+
+	dirs, err := c.devWatchDirs(opts.Deps)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(dirs))
+	for name := range dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("Error starting the file watcher: %s", err)
+	}
+	defer watcher.Close()
+
+	c.ui.Header("Watching dev dependencies for changes...")
+	for _, name := range names {
+		if err := devWatchAdd(watcher, dirs[name], ignore); err != nil {
+			return fmt.Errorf(
+				"Error watching dependency '%s': %s", name, err)
+		}
+
+		c.ui.Message(fmt.Sprintf("%s: %s", name, dirs[name]))
+	}
+
+	// pending are the dependencies with changes and when they can be
+	// rebuilt. Every change pushes this back by the debounce duration.
+	// The timer fires for the earliest one.
+	pending := make(map[string]time.Time)
+	var timer *time.Timer
+	var timerCh <-chan time.Time
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-opts.ShutdownCh:
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			name := devWatchOwner(dirs, event.Name, ignore)
+			if name == "" {
+				continue
+			}
+			c.logger.Debug("dev dependency changed", "dependency", name, "event", event.String())
+
+			// New directories have to be watched too
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := devWatchAdd(watcher, event.Name, ignore); err != nil {
+						ui.Warn(c.ui, fmt.Sprintf(
+							"Error watching '%s': %s", event.Name, err))
+					}
+				}
+			}
+
+			pending[name] = time.Now().Add(debounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			ui.Warn(c.ui, fmt.Sprintf("Error watching dev dependencies: %s", err))
+		case <-timerCh:
+			timer, timerCh = nil, nil
+
+			var ready []string
+			now := time.Now()
+			for name, t := range pending {
+				if !t.After(now) {
+					ready = append(ready, name)
+				}
+			}
+			sort.Strings(ready)
+
+			for _, name := range ready {
+				delete(pending, name)
+				c.devWatchRebuild(name)
+			}
+		}
+
+		if timer == nil && len(pending) > 0 {
+			var next time.Time
+			for _, t := range pending {
+				if next.IsZero() || t.Before(next) {
+					next = t
+				}
+			}
+
+			timer = time.NewTimer(next.Sub(time.Now()))
+			timerCh = timer.C
+		}
+	}
+}
+
+// RebuildDevDep builds the dev dependency of the dependency with the
+// given name again, even if it is cached. Since this is meant for
+// dependencies whose source changed locally, the result only replaces the
+// dependency's own cache and not the global cache of dev dependencies.
+func (c *Core) RebuildDevDep(name string) (err error) {
+	unlock, err := c.lockProject("dev rebuild", false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	op := c.operation("dev.rebuild", map[string]string{"dependency": name})
+	defer c.cacheAppContexts()()
+	defer func() { op.End(err) }()
+
+	root, err := c.appfileCompiled.Graph.Root()
+	if err != nil {
+		return err
+	}
+	rootCtx, err := c.appContext(root.(*appfile.CompiledGraphVertex).File)
+	if err != nil {
+		return errwrap.Wrapf("Error loading App: {{err}}", err)
+	}
+
+	v, err := c.devDepVertex(name)
+	if err != nil {
+		return err
+	}
+	ctx, err := c.appContext(v.File)
+	if err != nil {
+		return errwrap.Wrapf(fmt.Sprintf(
+			"Error loading Appfile for '%s': {{err}}", name), err)
+	}
+	appImpl, err := c.app(ctx)
+	if err != nil {
+		return errwrap.Wrapf(fmt.Sprintf(
+			"Error loading App implementation for '%s': {{err}}", name), err)
+	}
+	defer maybeClose(appImpl)
+
+	// The cache must not be pruned while it is rebuilt
+	lock, err := c.lockAppCache(v.File.ID, false, true)
+	if err != nil {
+		return fmt.Errorf("Error locking the cache: %s", err)
+	}
+	defer lock.Close()
+
+	cachePath := filepath.Join(ctx.CacheDir, "dev-dep.json")
+	if err := os.Remove(cachePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	start := time.Now()
+	c.ui.Header(fmt.Sprintf("Rebuilding dev dependency '%s'...", name))
+	err = c.buildDevDep(appImpl, rootCtx, ctx, "")
+	c.emit(&DevDepBuilt{
+		EventInfo: c.eventInfo(ctx.Appfile),
+		Duration:  time.Since(start),
+		Err:       err,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The dev environment now has the dependency at its current
+	// fingerprint, if its Appfile changed.
+	info, err := c.DevInfo()
+	if err != nil || info == nil {
+		return err
+	}
+	fp, err := c.devDepFingerprint(ctx)
+	if err != nil {
+		return fmt.Errorf(
+			"Error fingerprinting dev dependency '%s': %s", name, err)
+	}
+	if info.DepFingerprints == nil {
+		info.DepFingerprints = make(map[string]string)
+	}
+	info.DepFingerprints[name] = fp
+	if err := c.saveDevInfo(info); err != nil {
+		return fmt.Errorf("Error saving dev environment info: %s", err)
+	}
+
+	return nil
+}
+
+// devWatchRebuild rebuilds a watched dependency and applies it to the dev
+// environment. Errors are shown rather than returned so that watching
+// continues.
+func (c *Core) devWatchRebuild(name string) {
+	if err := c.RebuildDevDep(name); err != nil {
+		ui.ErrorHint(c.ui, fmt.Sprintf(
+			"Error rebuilding dev dependency '%s': %s", name, err),
+			ErrorHint(err))
+		return
+	}
+
+	refreshed, err := c.refreshDevDep(name)
+	if err != nil {
+		ui.ErrorHint(c.ui, fmt.Sprintf(
+			"Error refreshing dev dependency '%s': %s", name, err),
+			ErrorHint(err))
+		return
+	}
+	if !refreshed {
+		c.ui.Message(fmt.Sprintf(
+			"The dev dependency '%s' was rebuilt, but the dev environment\n"+
+				"can't pick it up while it runs. Re-run `otto dev` to pick up\n"+
+				"changes.", name))
+		return
+	}
+
+	ui.Result(c.ui, fmt.Sprintf(
+		"Dev dependency '%s' rebuilt and applied to the dev environment.", name))
+}
+
+// refreshDevDep applies the rebuilt dev dependency with the given name to
+// the dev environment. This returns false if the root app can't do that
+// or the dev environment doesn't exist.
+func (c *Core) refreshDevDep(name string) (bool, error) {
+	info, err := c.DevInfo()
+	if err != nil || info == nil {
+		return false, err
+	}
+
+	rootImpl, rootCtx, err := c.App()
+	if err != nil {
+		return false, err
+	}
+	defer maybeClose(rootImpl)
+
+	refresher, ok := rootImpl.(app.DevDepRefresher)
+	if !ok {
+		return false, nil
+	}
+
+	v, err := c.devDepVertex(name)
+	if err != nil {
+		return false, err
+	}
+	ctx, err := c.appContext(v.File)
+	if err != nil {
+		return false, errwrap.Wrapf(fmt.Sprintf(
+			"Error loading Appfile for '%s': {{err}}", name), err)
+	}
+
+	rootCtx.DevDepAddresses = info.DepAddresses
+	rootCtx.DevDepPorts = info.DepPorts
+	err = refresher.DevDepRefresh(rootCtx, ctx)
+	if err == app.ErrDevDepRefreshNotSupported {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// devDepVertex returns the vertex of the dependency with the given name.
+func (c *Core) devDepVertex(name string) (*appfile.CompiledGraphVertex, error) {
+	root, err := c.appfileCompiled.Graph.Root()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, raw := range c.appfileCompiled.Graph.Vertices() {
+		if raw == root {
+			continue
+		}
+
+		v := raw.(*appfile.CompiledGraphVertex)
+		if v.File.Application.Name == name {
+			return v, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Dependency not found: %s", name)
+}
+
+// devWatchDirs returns the source directories of the dependencies with
+// the given names, or all the dependencies with a local source if there
+// are no names, by dependency name.
+func (c *Core) devWatchDirs(names []string) (map[string]string, error) {
+	result := make(map[string]string)
+	if len(names) == 0 {
+		root, err := c.appfileCompiled.Graph.Root()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, raw := range c.appfileCompiled.Graph.Vertices() {
+			if raw == root {
+				continue
+			}
+
+			f := raw.(*appfile.CompiledGraphVertex).File
+			if dir, ok := devDepSourceDir(f.Source); ok {
+				result[f.Application.Name] = dir
+			}
+		}
+
+		if len(result) == 0 {
+			return nil, fmt.Errorf(
+				"There are no dependencies with a local source to watch.")
+		}
+
+		return result, nil
+	}
+
+	for _, name := range names {
+		v, err := c.devDepVertex(name)
+		if err != nil {
+			return nil, err
+		}
+
+		dir, ok := devDepSourceDir(v.File.Source)
+		if !ok {
+			return nil, fmt.Errorf(
+				"Dependency '%s' can't be watched since its source isn't\n"+
+					"local: %s", name, v.File.Source)
+		}
+
+		result[name] = dir
+	}
+
+	return result, nil
+}
+
+// devDepSourceDir returns the directory of a dependency source if it is
+// a local one.
+func devDepSourceDir(source string) (string, bool) {
+	u, err := url.Parse(source)
+	if err != nil || u.Scheme != "file" || u.Path == "" {
+		return "", false
+	}
+
+	// Windows paths are like /C:/foo in a URL
+	path := u.Path
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+
+	return filepath.FromSlash(path), true
+}
+
+// devWatchAdd adds dir and all the directories in it that aren't ignored
+// to the watcher.
+func devWatchAdd(watcher *fsnotify.Watcher, dir string, ignore []string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != dir && devWatchIgnored(info.Name(), ignore) {
+			return filepath.SkipDir
+		}
+
+		return watcher.Add(path)
+	})
+}
+
+// devWatchOwner returns the name of the dependency whose source has the
+// given path, or "" if the path is ignored. The deepest directory wins
+// when sources are in each other.
+func devWatchOwner(dirs map[string]string, path string, ignore []string) string {
+	var result, resultDir string
+	for name, dir := range dirs {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == ".." ||
+			strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if len(dir) <= len(resultDir) {
+			continue
+		}
+
+		result, resultDir = name, dir
+		for _, part := range strings.Split(rel, string(filepath.Separator)) {
+			if devWatchIgnored(part, ignore) {
+				result = ""
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+func devWatchIgnored(name string, ignore []string) bool {
+	for _, pattern := range ignore {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,198 @@
+package otto
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/otto/app"
+)
+
+func TestCoreRebuildDevDep(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The dependency is cached
+	testCachedDevDep(t, core, "afb66054-75d6-43ed-b47b-23d0bace94a8", "hello")
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if appMock.DevDepCalled {
+		t.Fatal("DevDep should not be called")
+	}
+
+	// Rebuilding ignores the cache
+	appMock.DevDepResult = &app.DevDep{Files: []string{"data/file"}}
+	if err := core.RebuildDevDep("bar"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.DevDepCalled {
+		t.Fatal("DevDep should be called")
+	}
+
+	// The rebuilt dependency isn't shared with other projects
+	entries, err := core.devDepStore().Entries()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("bad: %#v", entries)
+	}
+
+	if err := core.RebuildDevDep("nope"); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestCoreDevWatch(t *testing.T) {
+	dir := testCopyDir(t, testPath("dev-deps"))
+	builtCh := make(chan *DevDepBuilt, 10)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, filepath.Join(dir, "Appfile"))
+	coreConfig.EventSink = func(e Event) {
+		if built, ok := e.(*DevDepBuilt); ok {
+			builtCh <- built
+		}
+	}
+	appMock := new(app.MockDevDepRefresher)
+	coreConfig.Apps = map[app.Tuple]app.Factory{
+		TestAppTuple: func() (app.App, error) { return appMock, nil },
+	}
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	<-builtCh
+
+	shutdownCh := make(chan struct{})
+	doneCh := make(chan error, 1)
+	go func() {
+		doneCh <- core.DevWatch(&DevWatchOpts{
+			Debounce:   200 * time.Millisecond,
+			ShutdownCh: shutdownCh,
+		})
+	}()
+
+	// Give the watcher time to start
+	time.Sleep(200 * time.Millisecond)
+
+	// Ignored changes don't rebuild
+	ignored := filepath.Join(dir, "child", "node_modules")
+	if err := os.MkdirAll(ignored, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testWriteFile(t, filepath.Join(ignored, "foo.js"), "foo")
+
+	// A burst of changes only rebuilds once
+	for i := 0; i < 3; i++ {
+		testWriteFile(t, filepath.Join(dir, "child", "main.go"), string(rune('a'+i)))
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	select {
+	case built := <-builtCh:
+		if built.Err != nil {
+			t.Fatalf("err: %s", built.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("should rebuild")
+	}
+	select {
+	case <-builtCh:
+		t.Fatal("should rebuild once")
+	case <-time.After(time.Second):
+	}
+
+	close(shutdownCh)
+	select {
+	case err := <-doneCh:
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("should shut down")
+	}
+	if !appMock.DevDepRefreshCalled {
+		t.Fatal("DevDepRefresh should be called")
+	}
+	if name := appMock.DevDepRefreshContextSrc.Appfile.Application.Name; name != "bar" {
+		t.Fatalf("bad: %s", name)
+	}
+}
+
+func TestDevWatchOwner(t *testing.T) {
+	dirs := map[string]string{
+		"foo": filepath.Join("src", "foo"),
+		"bar": filepath.Join("src", "foo", "bar"),
+	}
+
+	cases := []struct {
+		Path     string
+		Expected string
+	}{
+		{filepath.Join("src", "foo", "main.go"), "foo"},
+		{filepath.Join("src", "foo", "bar", "main.go"), "bar"},
+		{filepath.Join("src", "foo", ".git", "HEAD"), ""},
+		{filepath.Join("src", "foo", "bar", "node_modules", "a", "b.js"), ""},
+		{filepath.Join("src", "baz", "main.go"), ""},
+	}
+
+	for _, tc := range cases {
+		actual := devWatchOwner(dirs, tc.Path, DefaultDevWatchIgnore)
+		if actual != tc.Expected {
+			t.Fatalf("%s: bad: %q", tc.Path, actual)
+		}
+	}
+}
+
+// testCopyDir copies the files of the directory src to a temporary
+// directory and returns it.
+func testCopyDir(t *testing.T, src string) string {
+	td, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(td, rel)
+		if info.IsDir() {
+			return os.MkdirAll(dst, 0755)
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		return ioutil.WriteFile(dst, data, info.Mode())
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return td
+}
+
+func testWriteFile(t *testing.T, path string, contents string) {
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
@@ -0,0 +1,328 @@
+package otto
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/otto/helper/semaphore"
+	"github.com/hashicorp/otto/ui"
+)
+
+// The categories of a DiskUsageEntry.
+const (
+	DiskUsageAppCache   = "app-cache"
+	DiskUsageCreds      = "creds"
+	DiskUsageCAS        = "cas"
+	DiskUsageLayers     = "layers"
+	DiskUsageBinaries   = "binaries"
+	DiskUsageGlobalData = "global-data"
+	DiskUsagePlugins    = "plugins"
+	DiskUsageLocalAddr  = "localaddr"
+	DiskUsageCompile    = "compile"
+	DiskUsageLogs       = "logs"
+	DiskUsageOther      = "other"
+)
+
+// DiskUsage is everything Otto stores on disk for a project and globally,
+// returned by Core.DiskUsage.
+type DiskUsage struct {
+	// Entries are the things that use disk space, largest first.
+	Entries []*DiskUsageEntry
+
+	// Size and Files are the totals of all the entries.
+	Size  int64
+	Files int64
+}
+
+// DiskUsageEntry is a single thing that uses disk space, such as the
+// cache of an app or the compiled output of an infrastructure.
+type DiskUsageEntry struct {
+	// Size and Files are the bytes and the number of files used. These
+	// are first so they're aligned for atomic updates while walking.
+	Size  int64
+	Files int64
+
+	// Category is one of the DiskUsage constants, such as
+	// DiskUsageAppCache, and Name identifies the entry within its
+	// category, such as the ID of the app.
+	Category string
+	Name     string
+
+	Path string
+}
+
+// Totals returns the totals of each category of the entries, with the
+// name of the category as the name of the entry, largest first.
+func (u *DiskUsage) Totals() []*DiskUsageEntry {
+	totals := make(map[string]*DiskUsageEntry)
+	var result []*DiskUsageEntry
+	for _, e := range u.Entries {
+		t, ok := totals[e.Category]
+		if !ok {
+			t = &DiskUsageEntry{Category: e.Category, Name: e.Category}
+			totals[e.Category] = t
+			result = append(result, t)
+		}
+
+		t.Size += e.Size
+		t.Files += e.Files
+	}
+
+	sort.Sort(diskUsageSort(result))
+	return result
+}
+
+// Table returns a table of the entries, largest first, followed by the
+// totals.
+func (u *DiskUsage) Table() *ui.Table {
+	table := &ui.Table{
+		Headers:  []string{"CATEGORY", "NAME", "SIZE", "FILES"},
+		MaxWidth: ui.TerminalWidth(),
+	}
+	for _, e := range u.Entries {
+		table.AddRow(e.Category, e.Name, summarySize(e.Size), fmt.Sprintf("%d", e.Files))
+	}
+	table.AddRow("", ui.Style("Total", "bold"),
+		summarySize(u.Size), fmt.Sprintf("%d", u.Files))
+
+	return table
+}
+
+// diskUsageSort sorts entries largest first, and then by category and
+// name so the order is stable.
+type diskUsageSort []*DiskUsageEntry
+
+func (s diskUsageSort) Len() int      { return len(s) }
+func (s diskUsageSort) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s diskUsageSort) Less(i, j int) bool {
+	if s[i].Size != s[j].Size {
+		return s[i].Size > s[j].Size
+	}
+	if s[i].Category != s[j].Category {
+		return s[i].Category < s[j].Category
+	}
+
+	return s[i].Name < s[j].Name
+}
+
+// DiskUsage walks everything that Otto stores in the data directory, the
+// local data directory of the project, and the compiled output, and
+// returns how much disk space each part of it uses.
+//
+// The directories are walked concurrently. Symlinks aren't followed, so a
+// link can't make the walk loop or count anything twice.
+func (c *Core) DiskUsage() (*DiskUsage, error) {
+	entries, err := c.diskUsageEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries may be within each other, such as when the compiled output
+	// is in the local data directory, so no entry walks into another.
+	skip := make(map[string]struct{}, len(entries))
+	for _, e := range entries {
+		skip[e.Path] = struct{}{}
+	}
+
+	w := &diskWalker{
+		Sem:  semaphore.New(runtime.NumCPU() * 4),
+		Skip: skip,
+	}
+	for _, e := range entries {
+		e := e
+		w.Sem.Acquire()
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			defer w.Sem.Release()
+			w.Walk(e)
+		}()
+	}
+	w.wg.Wait()
+	if w.err != nil {
+		return nil, fmt.Errorf("Error reading the disk usage: %s", w.err)
+	}
+
+	result := new(DiskUsage)
+	for _, e := range entries {
+		if e.Size == 0 && e.Files == 0 {
+			continue
+		}
+
+		result.Entries = append(result.Entries, e)
+		result.Size += e.Size
+		result.Files += e.Files
+	}
+	sort.Sort(diskUsageSort(result.Entries))
+
+	return result, nil
+}
+
+// diskUsageEntries returns the entries to walk, with nothing counted yet.
+func (c *Core) diskUsageEntries() ([]*DiskUsageEntry, error) {
+	var result []*DiskUsageEntry
+	add := func(category, name, path string) {
+		result = append(result, &DiskUsageEntry{
+			Category: category,
+			Name:     name,
+			Path:     path,
+		})
+	}
+
+	// addEach adds an entry for everything in dir, if it exists.
+	addEach := func(category, dir string) error {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for _, info := range infos {
+			add(category, info.Name(), filepath.Join(dir, info.Name()))
+		}
+
+		return nil
+	}
+
+	// The data directory, grouped by what the top-level entries are for
+	infos, err := ioutil.ReadDir(c.dataDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, info := range infos {
+		name := info.Name()
+		path := filepath.Join(c.dataDir, name)
+		switch name {
+		case "cache":
+			cacheInfos, err := ioutil.ReadDir(path)
+			if err != nil {
+				return nil, err
+			}
+			for _, info := range cacheInfos {
+				switch info.Name() {
+				case credsCacheName:
+					add(DiskUsageCreds, info.Name(), filepath.Join(path, info.Name()))
+				case ".locks":
+					add(DiskUsageOther, "cache/.locks", filepath.Join(path, info.Name()))
+				default:
+					add(DiskUsageAppCache, info.Name(), filepath.Join(path, info.Name()))
+				}
+			}
+		case "binaries", "global-data":
+			if err := addEach(name, path); err != nil {
+				return nil, err
+			}
+		case "cas":
+			add(DiskUsageCAS, name, path)
+		case "layers":
+			add(DiskUsageLayers, name, path)
+		case "plugins":
+			add(DiskUsagePlugins, name, path)
+		case filepath.Base(c.devIPDB().Path), filepath.Base(c.devPortDB().Path):
+			add(DiskUsageLocalAddr, name, path)
+		default:
+			add(DiskUsageOther, name, path)
+		}
+	}
+
+	// The local data of the project
+	infos, err = ioutil.ReadDir(c.localDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, info := range infos {
+		path := filepath.Join(c.localDir, info.Name())
+		if path == c.opLogDir() {
+			add(DiskUsageLogs, info.Name(), path)
+			continue
+		}
+
+		add(DiskUsageOther, info.Name(), path)
+	}
+
+	// The compiled output of each app, infrastructure, and foundation
+	if err := addEach(DiskUsageCompile, c.compileDir); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// diskWalker counts the sizes and files of directory trees concurrently.
+// Subdirectories are walked in new goroutines while Sem has room, and in
+// the walking goroutine otherwise.
+type diskWalker struct {
+	Sem semaphore.Semaphore
+
+	// Skip are paths that aren't walked into since they're counted
+	// on their own.
+	Skip map[string]struct{}
+
+	wg   sync.WaitGroup
+	lock sync.Mutex
+	err  error
+}
+
+// Walk counts the file or directory at the path of the entry e into it.
+func (w *diskWalker) Walk(e *DiskUsageEntry) {
+	info, err := os.Lstat(e.Path)
+	if err != nil {
+		w.setErr(err)
+		return
+	}
+	if !info.IsDir() {
+		atomic.AddInt64(&e.Size, info.Size())
+		atomic.AddInt64(&e.Files, 1)
+		return
+	}
+
+	w.walkDir(e, e.Path)
+}
+
+func (w *diskWalker) walkDir(e *DiskUsageEntry, dir string) {
+	// Reading the directory gets the information of every entry in it,
+	// which is all that's needed, so nothing is stat'ed again.
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			w.setErr(err)
+		}
+
+		return
+	}
+
+	for _, info := range infos {
+		if !info.IsDir() {
+			atomic.AddInt64(&e.Size, info.Size())
+			atomic.AddInt64(&e.Files, 1)
+			continue
+		}
+
+		path := filepath.Join(dir, info.Name())
+		if _, ok := w.Skip[path]; ok {
+			continue
+		}
+		if !w.Sem.TryAcquire() {
+			w.walkDir(e, path)
+			continue
+		}
+
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			defer w.Sem.Release()
+			w.walkDir(e, path)
+		}()
+	}
+}
+
+func (w *diskWalker) setErr(err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.err = multierror.Append(w.err, err)
+}
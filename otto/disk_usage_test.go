@@ -0,0 +1,81 @@
+package otto
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCoreDiskUsage(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	testDevDepDir(t, filepath.Join(coreConfig.DataDir, "cache", "other"), "hello")
+	testDevDepDir(t, filepath.Join(coreConfig.DataDir, "binaries", "terraform"), "bin")
+	testDevDepDir(t, filepath.Join(coreConfig.LocalDir, "logs"), "log")
+
+	// Links aren't followed, even if they make a cycle
+	link := filepath.Join(coreConfig.DataDir, "cache", "other", "data", "loop")
+	if err := os.Symlink(filepath.Join(coreConfig.DataDir, "cache"), link); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	usage, err := core.DiskUsage()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	entries := make(map[string]*DiskUsageEntry)
+	var size, files int64
+	for _, e := range usage.Entries {
+		entries[e.Category+" "+e.Name] = e
+		size += e.Size
+		files += e.Files
+	}
+	if size != usage.Size || files != usage.Files {
+		t.Fatalf("bad: %#v", usage)
+	}
+	for _, name := range []string{
+		"app-cache other",
+		"binaries terraform",
+		"compile metadata.json",
+		"logs logs",
+		"localaddr ip.db",
+	} {
+		if _, ok := entries[name]; !ok {
+			t.Fatalf("missing %s: %#v", name, entries)
+		}
+	}
+
+	// The dev dependency directory has a file and its manifest, and the
+	// link is counted as a file.
+	if e := entries["app-cache other"]; e.Files != 3 {
+		t.Fatalf("bad: %#v", e)
+	}
+
+	for i := 1; i < len(usage.Entries); i++ {
+		if usage.Entries[i].Size > usage.Entries[i-1].Size {
+			t.Fatalf("not sorted: %#v", usage.Entries)
+		}
+	}
+
+	var compile *DiskUsageEntry
+	for _, total := range usage.Totals() {
+		if total.Category == DiskUsageCompile {
+			compile = total
+		}
+	}
+	if compile == nil || compile.Size != entries["compile metadata.json"].Size {
+		t.Fatalf("bad: %#v", compile)
+	}
+
+	table := usage.Table().String()
+	if !strings.Contains(table, "terraform") || !strings.Contains(table, "Total") {
+		t.Fatalf("bad: %s", table)
+	}
+}
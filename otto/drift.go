@@ -0,0 +1,97 @@
+package otto
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/otto/ui"
+)
+
+// The keys of the hash of the compilation and the fingerprint of the
+// source that build records and deploys are labeled with, which tell if
+// the component is outdated. Records stored by older versions of Otto
+// don't have them.
+const (
+	driftCompileHashKey = "compile_hash"
+	driftFingerprintKey = "source_fingerprint"
+)
+
+// StatusDrift tells how a component of the application differs from
+// what it would be if it were made again now.
+type StatusDrift struct {
+	// Appfile is true if the Appfile changed since the component was
+	// made, and Source is true if the source of the application changed.
+	Appfile bool `json:"appfile"`
+	Source  bool `json:"source"`
+}
+
+// Drifted returns true if the component is outdated.
+func (d StatusDrift) Drifted() bool {
+	return d.Appfile || d.Source
+}
+
+// text returns the reason the component is outdated for Status, with
+// since saying when the component was made, or "" if it isn't outdated.
+func (d StatusDrift) text(since string) string {
+	var reason string
+	switch {
+	case d.Appfile && d.Source:
+		reason = "Appfile and source"
+	case d.Appfile:
+		reason = "Appfile"
+	case d.Source:
+		reason = "source"
+	default:
+		return ""
+	}
+
+	return fmt.Sprintf("outdated — %s changed since %s", reason, since)
+}
+
+// labelsDrift returns the drift of a component from the labels of its
+// record, compared to the current compilation md and the current source
+// fingerprint fp, which is "" if it isn't known. Missing labels aren't
+// drift, since there is nothing to compare.
+func (c *Core) labelsDrift(labels map[string]string, md *CompileMetadata, fp string) StatusDrift {
+	var result StatusDrift
+	if v := labels[driftCompileHashKey]; v != "" && md != nil {
+		// The Appfile changed if it was compiled since, or if it changed
+		// since the current compilation.
+		result.Appfile = v != compileMetadataHash(md) ||
+			(md.AppfileHash != "" && md.AppfileHash != c.appfileHash())
+	}
+	if v := labels[driftFingerprintKey]; v != "" && fp != "" {
+		result.Source = v != fp
+	}
+
+	return result
+}
+
+// statusFingerprint returns the current fingerprint of the source of the
+// application for the status information info, or "" if no record of it
+// has a fingerprint to compare it to, since fingerprinting walks all the
+// files of the source.
+func (c *Core) statusFingerprint(info *statusInfo) (string, error) {
+	compare := false
+	if info.Build != nil && info.Build.Artifact[driftFingerprintKey] != "" {
+		compare = true
+	}
+	if info.DeployLabels[driftFingerprintKey] != "" {
+		compare = true
+	}
+	if !compare {
+		return "", nil
+	}
+
+	return c.cachedSourceFingerprint(filepath.Dir(c.appfile.Path))
+}
+
+// withDrift returns the status text of a component with the reason it is
+// outdated, if it is.
+func withDrift(status string, d StatusDrift, since string) string {
+	if !d.Drifted() {
+		return status
+	}
+
+	return fmt.Sprintf("%s %s", status, ui.Style("("+d.text(since)+")", "yellow"))
+}
@@ -0,0 +1,96 @@
+package otto
+
+import (
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreStatus_drift(t *testing.T) {
+	core, appMock, _ := testArtifactCore(t, "build-naming", new(testArtifactStore))
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := core.Build(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testDeployed(t, core, nil)
+	if err := core.Deploy("", nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Nothing changed since the deploy
+	result, err := core.StatusResult()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if result.Drifted || result.Build != "built" || result.Deploy != "deployed" {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	// The source changed since the build, which was deployed before
+	build, err := core.dir.GetBuild(&directory.Build{
+		Lookup: core.deployLookup(core.appfile.ID)})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	build.Artifact[driftFingerprintKey] = "000000000000"
+	if err := core.dir.PutBuild(build); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	result, err = core.StatusResult()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !result.BuildDrift.Source || result.BuildDrift.Appfile || result.DeployDrift.Drifted() {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	// The Appfile is compiled differently since the deploy
+	appMock.CompileResult = &app.CompileResult{Version: 2}
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	result, err = core.StatusResult()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !result.Drifted || !result.DeployDrift.Appfile || result.DeployDrift.Source {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	uiMock := new(ui.Mock)
+	core.ui = uiMock
+	if err := core.Status(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, "(outdated — Appfile changed since last deploy)")
+	uiMock.AssertMessageContains(t, "(outdated — Appfile and source changed since last build)")
+}
+
+func TestCoreStatus_driftOld(t *testing.T) {
+	core, _, _ := testArtifactCore(t, "build-naming", new(testArtifactStore))
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Records of older versions of Otto have no hashes to compare
+	build := &directory.Build{
+		Lookup:   core.deployLookup(core.appfile.ID),
+		Artifact: map[string]string{"name": "old"},
+	}
+	if err := core.dir.PutBuild(build); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testDeployed(t, core, map[string]string{"url": "http://web.com"})
+
+	result, err := core.StatusResult()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if result.Drifted || result.Deploy != "deployed" {
+		t.Fatalf("bad: %#v", result)
+	}
+}
@@ -0,0 +1,137 @@
+package otto
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/otto/appfile"
+)
+
+// projectEnv returns the environment variables for the infrastructure
+// and the foundations: the env of the project of the Appfile, and then
+// the ExtraEnv of the CoreConfig.
+func (c *Core) projectEnv() map[string]string {
+	result := make(map[string]string)
+	c.mergeProjectEnv(result)
+	mergeEnv(result, c.extraEnv)
+	return result
+}
+
+// appEnv returns the environment variables for the app of the Appfile f.
+// These are merged from, in increasing precedence, the env of the project
+// of the Appfile of the Core, the env customizations of the app, and the
+// ExtraEnv of the CoreConfig.
+//
+// Every value can reference the environment variables of the sources
+// before it, and of the process with lower precedence than those. This
+// lets a source extend a variable, such as "/opt/bin:${PATH}".
+func (c *Core) appEnv(f *appfile.File) (map[string]string, error) {
+	result := make(map[string]string)
+	c.mergeProjectEnv(result)
+
+	custom, err := customizationEnv(f)
+	if err != nil {
+		return nil, err
+	}
+	mergeEnv(result, custom)
+	mergeEnv(result, c.extraEnv)
+
+	return result, nil
+}
+
+// mergeProjectEnv merges the env of the project into env, and adds the
+// values of the secret variables as secrets to redact.
+func (c *Core) mergeProjectEnv(env map[string]string) {
+	if c.appfile.Project == nil {
+		return
+	}
+
+	values := make(map[string]string, len(c.appfile.Project.Env))
+	for _, v := range c.appfile.Project.Env {
+		values[v.Name] = v.Value
+	}
+	mergeEnv(env, values)
+
+	for _, v := range c.appfile.Project.Env {
+		if v.Secret {
+			c.secrets.AddSecret(env[v.Name])
+		}
+	}
+}
+
+// mergeEnv sets the variables of values in env, interpolating the
+// references in the values. The references are to env as it was before
+// the merge, and then to the environment of the process.
+func mergeEnv(env, values map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+
+	prev := make(map[string]string, len(env))
+	for k, v := range env {
+		prev[k] = v
+	}
+	lookup := func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		if v, ok := prev[name]; ok {
+			return v
+		}
+
+		return os.Getenv(name)
+	}
+
+	for k, v := range values {
+		env[k] = os.Expand(v, lookup)
+	}
+}
+
+// customizationEnv returns the variables of the "env" settings of the app
+// customizations of the Appfile f, such as:
+//
+//	customization "app" {
+//	    env {
+//	        GOFLAGS = "-mod=vendor"
+//	    }
+//	}
+//
+// Later customizations take precedence over earlier ones.
+func customizationEnv(f *appfile.File) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, cust := range f.Customization.Filter("app") {
+		raw, ok := cust.Config["env"]
+		if !ok {
+			continue
+		}
+
+		// HCL decodes a block to a list of maps, one for each time the
+		// block is in the customization.
+		var blocks []map[string]interface{}
+		switch v := raw.(type) {
+		case map[string]interface{}:
+			blocks = append(blocks, v)
+		case []map[string]interface{}:
+			blocks = v
+		default:
+			return nil, fmt.Errorf(
+				"Error in the customizations of '%s': env must be a block "+
+					"of variables", f.Application.Name)
+		}
+
+		for _, block := range blocks {
+			for k, raw := range block {
+				v, ok := raw.(string)
+				if !ok {
+					return nil, fmt.Errorf(
+						"Error in the customizations of '%s': the value of env "+
+							"'%s' must be a string", f.Application.Name, k)
+				}
+
+				result[k] = v
+			}
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package otto
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreAppContext_env(t *testing.T) {
+	defer os.Setenv("OTTO_TEST_ENV_TOKEN", os.Getenv("OTTO_TEST_ENV_TOKEN"))
+	os.Setenv("OTTO_TEST_ENV_TOKEN", "s3cret")
+
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("env", "Appfile"))
+	coreConfig.Ui = uiMock
+	coreConfig.ExtraEnv = map[string]string{
+		"HTTP_PROXY": "http://other:3128",
+	}
+	core := testCore(t, coreConfig)
+
+	ctx, err := core.appContext(core.appfile)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := map[string]string{
+		"HTTP_PROXY": "http://other:3128",
+		"API_TOKEN":  "s3cret",
+		"TOOL_FLAGS": "-v -race",
+		"GOFLAGS":    "-mod=vendor",
+		"COST":       "$5",
+	}
+	if !reflect.DeepEqual(ctx.Env, expected) {
+		t.Fatalf("bad: %#v", ctx.Env)
+	}
+
+	// The infrastructure gets the project env only
+	_, infraCtx, err := core.infra()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	delete(expected, "GOFLAGS")
+	expected["TOOL_FLAGS"] = "-v"
+	if !reflect.DeepEqual(infraCtx.Env, expected) {
+		t.Fatalf("bad: %#v", infraCtx.Env)
+	}
+
+	// Secret values are redacted
+	core.ui.Message("token: s3cret")
+	uiMock.AssertMessageContains(t, "token: "+ui.RedactedText)
+	uiMock.AssertMessageNotContains(t, "s3cret")
+}
+
+func TestCustomizationEnv_bad(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("env", "Appfile"))
+	core := testCore(t, coreConfig)
+	core.appfile.Customization.Raw[0].Config["env"] = []map[string]interface{}{
+		map[string]interface{}{"PORT": 8080},
+	}
+
+	_, err := core.appContext(core.appfile)
+	if err == nil || !strings.Contains(err.Error(), "env 'PORT' must be a string") {
+		t.Fatalf("bad: %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package otto
+
+import (
+	"regexp"
+
+	"github.com/hashicorp/otto/directory"
+)
+
+// environmentRe matches the valid names of environments.
+var environmentRe = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Environment returns the environment that this Core builds and deploys
+// for.
+func (c *Core) Environment() string {
+	return c.environment
+}
+
+// deployLookup returns the directory lookup of the builds and deploys of
+// the app with the given ID on the active infrastructure in the
+// environment of this Core.
+func (c *Core) deployLookup(appID string) directory.Lookup {
+	infra := c.appfile.ActiveInfrastructure()
+	return directory.Lookup{
+		AppID:       appID,
+		Infra:       infra.Type,
+		InfraFlavor: infra.Flavor,
+		Environment: c.environment,
+	}
+}
@@ -0,0 +1,108 @@
+package otto
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreEnvironment(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	core := testCore(t, coreConfig)
+	if env := core.Environment(); env != directory.DefaultEnvironment {
+		t.Fatalf("bad: %s", env)
+	}
+
+	coreConfig.Environment = "no spaces"
+	if _, err := NewCore(coreConfig); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestCoreEnvironment_deploys(t *testing.T) {
+	uiMock := new(ui.Mock)
+	uiMock.AddAnswer("^creds_password$", "hunter2")
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	appMock := new(app.MockConsoleProvider)
+	coreConfig.Apps[TestAppTuple] = func() (app.App, error) {
+		return appMock, nil
+	}
+	core := testCore(t, coreConfig)
+
+	stagingConfig := *coreConfig
+	stagingConfig.Environment = "staging"
+	staging := testCore(t, &stagingConfig)
+
+	// A deploy in one environment doesn't affect another
+	testDeployed(t, staging, map[string]string{"url": "http://staging.foo.com"})
+	status := make(chan *statusInfo, 1)
+	core.statusInfo(status)
+	if info := <-status; info.Deploy != nil {
+		t.Fatalf("bad: %#v", info.Deploy)
+	}
+	staging.statusInfo(status)
+	if info := <-status; !info.Deploy.IsDeployed() {
+		t.Fatalf("bad: %#v", info.Deploy)
+	}
+
+	testDeployed(t, core, map[string]string{"url": "http://foo.com"})
+	if url := staging.deployURL(); url != "http://staging.foo.com" {
+		t.Fatalf("bad: %s", url)
+	}
+	if url := core.deployURL(); url != "http://foo.com" {
+		t.Fatalf("bad: %s", url)
+	}
+
+	// The apps get the environment and its deploy info
+	defer func(f func() bool) { consoleTerminal = f }(consoleTerminal)
+	consoleTerminal = func() bool { return true }
+	if err := staging.Console(app.ConsoleTarget{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	ctx := appMock.ConsoleContext
+	if ctx.Environment != "staging" || ctx.DeployInfo["url"] != "http://staging.foo.com" {
+		t.Fatalf("bad: %#v", ctx)
+	}
+
+	if err := staging.Status(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, "staging")
+}
+
+func TestCoreEnvironment_unscoped(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	core := testCore(t, coreConfig)
+
+	// Deploys stored before there were environments are in the default
+	// environment.
+	infra := core.appfile.ActiveInfrastructure()
+	deploy := &directory.Deploy{
+		Lookup: directory.Lookup{
+			AppID: core.appfile.ID, Infra: infra.Type, InfraFlavor: infra.Flavor},
+		Deploy: map[string]string{"url": "http://foo.com"},
+	}
+	deploy.MarkSuccessful()
+	if err := core.dir.PutDeploy(deploy); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if url := core.deployURL(); url != "http://foo.com" {
+		t.Fatalf("bad: %s", url)
+	}
+
+	coreConfig.Environment = "production"
+	production := testCore(t, coreConfig)
+	if url := production.deployURL(); url != "" {
+		t.Fatalf("bad: %s", url)
+	}
+	if !strings.HasSuffix(production.healthKey(), "@production") {
+		t.Fatalf("bad: %s", production.healthKey())
+	}
+}
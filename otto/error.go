@@ -1,5 +1,15 @@
 package otto
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/foundation"
+)
+
 // Error is the interface implemented by many errors within Otto. You
 // can use it to check what the type of an error is via the list of
 // error codes below.
@@ -18,5 +28,166 @@ func (e *codedError) Error() string        { return e.err.Error() }
 func (e *codedError) OriginalError() error { return e.err }
 func (e *codedError) Code() string         { return e.code }
 
+// Unwrap is for errors.Is and errors.As. Otto itself uses errwrap, which
+// builds with older versions of Go.
+func (e *codedError) Unwrap() error { return e.err }
+
 // errwrap.Wrapper impl.
 func (e *codedError) WrappedErrors() []error { return []error{e.OriginalError()} }
+
+// ErrNoCompileMetadata is returned when an operation needs the results of
+// Core.Compile, but the Appfile hasn't been compiled.
+var ErrNoCompileMetadata = errors.New("the Appfile hasn't been compiled")
+
+// ErrStaleCompile is returned when the Appfile changed since it was last
+// compiled successfully.
+var ErrStaleCompile = errors.New("the Appfile changed since it was compiled")
+
+// HintedError wraps an error with a short suggestion of how to fix it, for
+// the failures where Otto knows the likely fix. Use ErrorHint to get the
+// hint of an error that may be wrapped further.
+type HintedError struct {
+	Err  error
+	Hint string
+
+	// DocKey optionally names the page of the documentation that explains
+	// the error in more detail, such as "compile".
+	DocKey string
+}
+
+func (e *HintedError) Error() string { return e.Err.Error() }
+func (e *HintedError) Unwrap() error { return e.Err }
+
+// errwrap.Wrapper impl.
+func (e *HintedError) WrappedErrors() []error { return []error{e.Err} }
+
+// ErrorHint returns the hint of the first HintedError in the chain of err,
+// or "" if there is none.
+func ErrorHint(err error) string {
+	if hinted, ok := errwrap.GetType(err, new(HintedError)).(*HintedError); ok {
+		return hinted.Hint
+	}
+
+	return ""
+}
+
+// ErrDirectoryUnavailable matches, with errors.Is or errwrap.Contains, the
+// errors from the directory backend. The error itself describes what
+// failed.
+var ErrDirectoryUnavailable = errors.New("the directory is unavailable")
+
+// ErrAppNotFound is returned when there is no app implementation for the
+// tuple of an Appfile.
+type ErrAppNotFound struct {
+	Tuple app.Tuple
+}
+
+func (e *ErrAppNotFound) Error() string {
+	return fmt.Sprintf("app implementation for tuple not found: %s", e.Tuple)
+}
+
+// ErrInfraNotFound is returned when there is no infrastructure
+// implementation for the type in the Appfile. Available are the types
+// there are implementations for, sorted.
+type ErrInfraNotFound struct {
+	Type      string
+	Available []string
+}
+
+func (e *ErrInfraNotFound) Error() string {
+	if len(e.Available) == 0 {
+		return fmt.Sprintf("infrastructure type not supported: %s", e.Type)
+	}
+
+	return fmt.Sprintf(
+		"infrastructure type not supported: %s. Supported types: %s",
+		e.Type, strings.Join(e.Available, ", "))
+}
+
+// ErrFoundationNotFound is returned when there is no foundation
+// implementation for a foundation in the Appfile. Available are the
+// tuples that implement the type of the foundation for other
+// infrastructures, so if this is empty the type is unknown.
+type ErrFoundationNotFound struct {
+	Tuple     foundation.Tuple
+	Available foundation.TupleSlice
+}
+
+func (e *ErrFoundationNotFound) Error() string {
+	if len(e.Available) == 0 {
+		return fmt.Sprintf("unknown foundation type: %s", e.Tuple.Type)
+	}
+
+	supported := make([]string, len(e.Available))
+	for i, t := range e.Available {
+		supported[i] = fmt.Sprintf("%s/%s", t.Infra, t.InfraFlavor)
+	}
+
+	return fmt.Sprintf(
+		"foundation '%s' has no implementation for the infrastructure\n"+
+			"%s with flavor '%s'. It is implemented for: %s",
+		e.Tuple.Type, e.Tuple.Infra, e.Tuple.InfraFlavor,
+		strings.Join(supported, ", "))
+}
+
+// ErrLogsNotSupported is returned by Logs and DevLogs when the app type
+// of the Appfile can't fetch the logs.
+type ErrLogsNotSupported struct {
+	AppType string
+	Dev     bool
+}
+
+func (e *ErrLogsNotSupported) Error() string {
+	if e.Dev {
+		return fmt.Sprintf(
+			"Dev environment logs are not supported by app type %q.", e.AppType)
+	}
+
+	return fmt.Sprintf("Logs are not supported by app type %q.", e.AppType)
+}
+
+// ErrPluginStart is returned when an implementation fails to start, such
+// as a plugin process that can't be reached. Kind is "app", "infra", or
+// "foundation", and Tuple is the app or foundation tuple or the
+// infrastructure type.
+type ErrPluginStart struct {
+	Kind  string
+	Tuple string
+	Err   error
+}
+
+func (e *ErrPluginStart) Error() string {
+	return fmt.Sprintf("%s %s failed to start properly: %s", e.Kind, e.Tuple, e.Err)
+}
+
+func (e *ErrPluginStart) Unwrap() error { return e.Err }
+
+// errwrap.Wrapper impl.
+func (e *ErrPluginStart) WrappedErrors() []error { return []error{e.Err} }
+
+// directoryError is an error from the directory backend, which matches
+// ErrDirectoryUnavailable.
+type directoryError struct {
+	err error
+}
+
+func (e *directoryError) Error() string          { return e.err.Error() }
+func (e *directoryError) Is(target error) bool   { return target == ErrDirectoryUnavailable }
+func (e *directoryError) Unwrap() error          { return e.err }
+func (e *directoryError) WrappedErrors() []error { return []error{ErrDirectoryUnavailable, e.err} }
+
+// directoryErr wraps an error from the directory backend so that it
+// matches ErrDirectoryUnavailable, with a hint to check the backend. A nil
+// error stays nil.
+func directoryErr(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &HintedError{
+		Err: &directoryError{err: err},
+		Hint: "Check the configuration of the directory backend and that " +
+			"it is reachable.",
+		DocKey: "directory",
+	}
+}
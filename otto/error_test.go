@@ -1,9 +1,233 @@
 package otto
 
 import (
+	"errors"
+	"strings"
 	"testing"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/foundation"
+	"github.com/hashicorp/otto/ui"
 )
 
 func TestCodedError_impl(t *testing.T) {
 	var _ Error = new(codedError)
 }
+
+func TestCoreApp_errAppNotFound(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	delete(coreConfig.Apps, TestAppTuple)
+	core := testCore(t, coreConfig)
+
+	_, _, err := core.App()
+	if err == nil {
+		t.Fatal("should error")
+	}
+
+	// Wrapping it again by an embedder still matches
+	err = errwrap.Wrapf("embedder: {{err}}", err)
+	notFound, ok := errwrap.GetType(err, new(ErrAppNotFound)).(*ErrAppNotFound)
+	if !ok {
+		t.Fatalf("bad: %#v", err)
+	}
+	if notFound.Tuple != TestAppTuple {
+		t.Fatalf("bad: %#v", notFound)
+	}
+	if !errwrap.ContainsType(err, new(ErrAppNotFound)) {
+		t.Fatalf("bad: %#v", err)
+	}
+
+	// The hint says what is supported instead
+	hint := ErrorHint(err)
+	if !strings.Contains(hint, "'test' application type") {
+		t.Fatalf("bad: %q", hint)
+	}
+}
+
+func TestCoreApp_errPluginStart(t *testing.T) {
+	errStart := errors.New("connection refused")
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Apps[TestAppTuple] = func() (app.App, error) {
+		return nil, errStart
+	}
+	core := testCore(t, coreConfig)
+
+	_, _, err := core.App()
+	start, ok := errwrap.GetType(err, new(ErrPluginStart)).(*ErrPluginStart)
+	if !ok {
+		t.Fatalf("bad: %#v", err)
+	}
+	if start.Kind != "app" || start.Tuple != TestAppTuple.String() {
+		t.Fatalf("bad: %#v", start)
+	}
+	if !errwrap.Contains(err, errStart.Error()) {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
+func TestCoreCompile_errInfraNotFound(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	core := testCore(t, coreConfig)
+	core.appfile.ActiveInfrastructure().Type = "nope"
+
+	err := core.Compile()
+	notFound, ok := errwrap.GetType(err, new(ErrInfraNotFound)).(*ErrInfraNotFound)
+	if !ok {
+		t.Fatalf("bad: %#v", err)
+	}
+	if notFound.Type != "nope" || len(notFound.Available) != 1 || notFound.Available[0] != "test" {
+		t.Fatalf("bad: %#v", notFound)
+	}
+}
+
+func TestCoreCompile_errFoundationNotFound(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	TestFoundation(t, foundation.Tuple{Type: "consul", Infra: "aws", InfraFlavor: "*"}, coreConfig)
+	core := testCore(t, coreConfig)
+	core.appfile.ActiveInfrastructure().Foundations = []*appfile.Foundation{
+		&appfile.Foundation{Name: "consul"},
+	}
+
+	err := core.Compile()
+	notFound, ok := errwrap.GetType(err, new(ErrFoundationNotFound)).(*ErrFoundationNotFound)
+	if !ok {
+		t.Fatalf("bad: %#v", err)
+	}
+	if notFound.Tuple.Infra != "test" || len(notFound.Available) != 1 {
+		t.Fatalf("bad: %#v", notFound)
+	}
+}
+
+func TestCoreCompileMetadata(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	core := testCore(t, coreConfig)
+
+	_, err := core.CompileMetadata()
+	if !errwrap.Contains(err, ErrNoCompileMetadata.Error()) {
+		t.Fatalf("err: %v", err)
+	}
+	if hint := ErrorHint(err); !strings.Contains(hint, "otto compile") {
+		t.Fatalf("bad: %q", hint)
+	}
+
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	md, err := core.CompileMetadata()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if md == nil || md.AppfileHash == "" {
+		t.Fatalf("bad: %#v", md)
+	}
+
+	// Changing the Appfile makes the compilation stale
+	core.appfile.Application.Name = "changed"
+	md, err = core.CompileMetadata()
+	if !errwrap.Contains(err, ErrStaleCompile.Error()) {
+		t.Fatalf("err: %v", err)
+	}
+	if hint := ErrorHint(err); !strings.Contains(hint, "again") {
+		t.Fatalf("bad: %q", hint)
+	}
+	if md == nil {
+		t.Fatal("should return the metadata")
+	}
+}
+
+func TestCoreStatus_errDirectoryUnavailable(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Directory = &testBrokenDirectory{Backend: coreConfig.Directory}
+	core := testCore(t, coreConfig)
+
+	infoCh := make(chan *statusInfo, 1)
+	core.statusInfo(infoCh)
+	info := <-infoCh
+	if !errwrap.Contains(info.Err, ErrDirectoryUnavailable.Error()) {
+		t.Fatalf("bad: %#v", info.Err)
+	}
+	if !errwrap.Contains(info.Err, errTestDirectory.Error()) {
+		t.Fatalf("bad: %#v", info.Err)
+	}
+	if ErrorHint(info.Err) == "" {
+		t.Fatalf("bad: %#v", info.Err)
+	}
+}
+
+func TestCoreStatus_unknown(t *testing.T) {
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Directory = &testBrokenDirectory{Backend: coreConfig.Directory}
+	coreConfig.Ui = uiMock
+	core := testCore(t, coreConfig)
+	testDeployed(t, core, nil)
+
+	// Only the component whose lookup failed is unknown
+	if err := core.Status(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, "UNKNOWN: ")
+	uiMock.AssertMessageContains(t, errTestDirectory.Error())
+	uiMock.AssertMessageContains(t, "DEPLOYED")
+	uiMock.AssertMessageNotContains(t, "NOT DEPLOYED")
+}
+
+func TestCoreStatus_failed(t *testing.T) {
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Directory = &testDownDirectory{Backend: coreConfig.Directory}
+	coreConfig.Ui = uiMock
+	core := testCore(t, coreConfig)
+
+	// Nothing is shown if no component could be loaded
+	err := core.Status()
+	if !errwrap.Contains(err, errTestDirectory.Error()) {
+		t.Fatalf("err: %v", err)
+	}
+	uiMock.AssertMessageNotContains(t, "NOT CREATED")
+}
+
+var errTestDirectory = errors.New("directory is down")
+
+// testBrokenDirectory is a directory backend that can't load the dev
+// environment.
+type testBrokenDirectory struct {
+	directory.Backend
+}
+
+func (d *testBrokenDirectory) GetDev(*directory.Dev) (*directory.Dev, error) {
+	return nil, errTestDirectory
+}
+
+// testDownDirectory is a directory backend that can't load any of the
+// components of the status.
+type testDownDirectory struct {
+	directory.Backend
+}
+
+func (d *testDownDirectory) GetDev(*directory.Dev) (*directory.Dev, error) {
+	return nil, errTestDirectory
+}
+
+func (d *testDownDirectory) GetBuild(*directory.Build) (*directory.Build, error) {
+	return nil, errTestDirectory
+}
+
+func (d *testDownDirectory) GetDeploy(*directory.Deploy) (*directory.Deploy, error) {
+	return nil, errTestDirectory
+}
+
+func (d *testDownDirectory) GetInfra(*directory.Infra) (*directory.Infra, error) {
+	return nil, errTestDirectory
+}
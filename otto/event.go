@@ -0,0 +1,118 @@
+package otto
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/appfile"
+)
+
+// Event is an event about the lifecycle of an operation that is sent
+// to the CoreConfig.EventSink. Use a type switch to determine the kind
+// of event.
+//
+// Events only contain copies of data so they are safe to keep after
+// the sink returns.
+type Event interface {
+	// Info returns the information common to all events.
+	Info() EventInfo
+}
+
+// EventInfo is the information common to all events. It identifies the
+// application that the event is about.
+type EventInfo struct {
+	Time      time.Time
+	AppfileID string
+	App       string
+	Tuple     app.Tuple
+}
+
+// Info implements Event.
+func (i EventInfo) Info() EventInfo { return i }
+
+// CompileStarted is sent when Compile starts.
+type CompileStarted struct {
+	EventInfo
+}
+
+// UnitCompiled is sent when a single unit of the compilation completes.
+// Kind and Name are the same as in CompileTiming. Err is set if the unit
+// failed to compile.
+type UnitCompiled struct {
+	EventInfo
+
+	Kind     string
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// CompileFinished is sent when Compile completes.
+type CompileFinished struct {
+	EventInfo
+
+	Duration time.Duration
+	Err      error
+}
+
+// DeployStarted is sent when Deploy starts.
+type DeployStarted struct {
+	EventInfo
+
+	Action string
+}
+
+// DeployFinished is sent when Deploy completes.
+type DeployFinished struct {
+	EventInfo
+
+	Action   string
+	Duration time.Duration
+	Err      error
+}
+
+// DevDepBuilt is sent when the dev dependency for a dependency has been
+// built or loaded from the cache during Dev.
+type DevDepBuilt struct {
+	EventInfo
+
+	Cached   bool
+	Duration time.Duration
+	Err      error
+}
+
+// eventInfo returns the EventInfo for the application in f.
+func (c *Core) eventInfo(f *appfile.File) EventInfo {
+	result := EventInfo{
+		Time:      time.Now(),
+		AppfileID: f.ID,
+		App:       f.Application.Name,
+	}
+	if infra := f.ActiveInfrastructure(); infra != nil {
+		result.Tuple = app.Tuple{
+			App:         f.Application.Type,
+			Infra:       infra.Type,
+			InfraFlavor: infra.Flavor,
+		}
+	}
+
+	return result
+}
+
+// emit sends the event to the event sink, if there is one. A panic in
+// the sink is logged rather than failing the operation.
+func (c *Core) emit(e Event) {
+	if c.eventSink == nil {
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			c.logger.Error(
+				"panic in event sink", "event", fmt.Sprintf("%T", e), "panic", r)
+		}
+	}()
+
+	c.eventSink(e)
+}
@@ -4,8 +4,9 @@ package otto
 type ExecuteTask uint
 
 const (
-	ExecuteTaskInvalid ExecuteTask = 0
+	ExecuteTaskInvalid ExecuteTask = iota
 	ExecuteTaskDev
+	ExecuteTaskConsole
 )
 
 //go:generate stringer -type=ExecuteTask execute.go
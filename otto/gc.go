@@ -0,0 +1,171 @@
+package otto
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/otto/helper/localaddr"
+	"github.com/hashicorp/otto/ui"
+)
+
+// DefaultLeaseAge is the default time a dev IP lease can go without
+// being renewed before it is garbage collected.
+const DefaultLeaseAge = 30 * 24 * time.Hour
+
+// GCOpts are the options for Core.GC.
+type GCOpts struct {
+	// LeaseAge is how long a dev IP lease can go without being renewed
+	// before it is released. This defaults to DefaultLeaseAge.
+	LeaseAge time.Duration
+
+	// Force, if true, releases stale resources even if the project that
+	// owns them still exists.
+	Force bool
+}
+
+// GC garbage collects the global resources that Otto allocates for
+// projects that are gone or haven't been used in a long time, such as
+// dev IP addresses and dev layers. This also prunes the global cache of dev dependencies
+// down to its maximum size.
+func (c *Core) GC(opts *GCOpts) (err error) {
+	if opts == nil {
+		opts = new(GCOpts)
+	}
+	leaseAge := opts.LeaseAge
+	if leaseAge <= 0 {
+		leaseAge = DefaultLeaseAge
+	}
+
+	op := c.operation("gc", nil)
+	defer func() { op.End(err) }()
+
+	if opts.Force {
+		err := c.confirm(&confirmOpts{
+			Id: "gc_confirm",
+			Message: fmt.Sprintf(
+				"Otto will release all dev IP addresses that haven't been used\n"+
+					"in %s, even for projects that still exist. Dev environments\n"+
+					"for those projects will get a new address the next time\n"+
+					"they're created.", leaseAge),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	c.ui.Header("Releasing stale dev IP addresses...")
+	ipDB := c.devIPDB()
+
+	// The addresses of projects that are gone can never be renewed, so
+	// they're released no matter how old they are.
+	released, err := ipDB.ReleaseOrphans()
+	if err != nil {
+		return fmt.Errorf("Error releasing dev IP addresses: %s", err)
+	}
+	stale, err := ipDB.GC(leaseAge, opts.Force)
+	if err != nil {
+		return fmt.Errorf("Error releasing dev IP addresses: %s", err)
+	}
+	released = append(released, stale...)
+	for _, ip := range released {
+		c.ui.Message(fmt.Sprintf("Released: %s", ip))
+	}
+	ui.Result(c.ui, fmt.Sprintf("Released %d dev IP address(es).", len(released)))
+
+	// Released leases leave free space behind in the database, so compact
+	// it once enough of it can be reclaimed. This is only an optimization,
+	// so errors are just warnings.
+	if err := c.compactDevIPDB(); err != nil {
+		ui.Warn(c.ui, fmt.Sprintf(
+			"Error compacting the dev IP address database: %s", err))
+	}
+
+	// Dev ports are treated just like the dev IP addresses
+	c.ui.Header("Releasing stale dev ports...")
+	portDB := c.devPortDB()
+	releasedPorts, err := portDB.ReleaseOrphans()
+	if err != nil {
+		return fmt.Errorf("Error releasing dev ports: %s", err)
+	}
+	stalePorts, err := portDB.GC(leaseAge, opts.Force)
+	if err != nil {
+		return fmt.Errorf("Error releasing dev ports: %s", err)
+	}
+	releasedPorts = append(releasedPorts, stalePorts...)
+	for _, id := range releasedPorts {
+		c.ui.Message(fmt.Sprintf("Released ports for app: %s", id))
+	}
+	ui.Result(c.ui, fmt.Sprintf(
+		"Released dev ports for %d app(s).", len(releasedPorts)))
+
+	// Dev layers are only deleted once no project uses them
+	c.ui.Header("Deleting unused dev layers...")
+	layers, err := c.gcDevLayers()
+	if err != nil {
+		return fmt.Errorf("Error deleting dev layers: %s", err)
+	}
+	for _, name := range layers {
+		c.ui.Message(fmt.Sprintf("Deleted layer: %s", name))
+	}
+	ui.Result(c.ui, fmt.Sprintf("Deleted %d dev layer(s).", len(layers)))
+
+	// Evict the least recently used dev dependencies if the cache is
+	// too large.
+	c.ui.Header("Pruning the dev dependency cache...")
+	store := c.devDepStore()
+	if store.MaxSize > 0 {
+		evicted, err := store.Prune(store.MaxSize)
+		if err != nil {
+			return fmt.Errorf("Error pruning the dev dependency cache: %s", err)
+		}
+		for _, e := range evicted {
+			c.ui.Message(fmt.Sprintf(
+				"Evicted: %s (%s)", e.Fingerprint, summarySize(e.Size)))
+		}
+	}
+	entries, err := store.Entries()
+	if err != nil {
+		return fmt.Errorf("Error reading the dev dependency cache: %s", err)
+	}
+	var size int64
+	for _, e := range entries {
+		size += e.Size
+	}
+	ui.Result(c.ui, fmt.Sprintf(
+		"Dev dependency cache: %s, %s.",
+		pluralize(len(entries), "dependency", "dependencies"),
+		summarySize(size)))
+
+	return nil
+}
+
+// compactDevIPDB compacts the database of dev IP addresses if enough of
+// it is reclaimable, see localaddr.CompactThreshold.
+func (c *Core) compactDevIPDB() error {
+	ipDB := c.devIPDB()
+	frac, err := ipDB.Reclaimable()
+	if err != nil {
+		return err
+	}
+	if frac <= localaddr.CompactThreshold {
+		return nil
+	}
+
+	before, err := os.Stat(ipDB.Path)
+	if err != nil {
+		return err
+	}
+	if err := ipDB.Compact(); err != nil {
+		return err
+	}
+	after, err := os.Stat(ipDB.Path)
+	if err != nil {
+		return err
+	}
+
+	c.ui.Message(fmt.Sprintf(
+		"Compacted the dev IP address database: %s to %s",
+		summarySize(before.Size()), summarySize(after.Size())))
+	return nil
+}
@@ -0,0 +1,67 @@
+package otto
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreGC(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	core := testCore(t, coreConfig)
+
+	// Allocate the dev IP for this project
+	ctx, err := core.appContext(core.appfile)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The project still exists so the lease isn't released
+	uiMock := new(ui.Mock)
+	coreConfig.Ui = uiMock
+	core = testCore(t, coreConfig)
+	if err := core.GC(&GCOpts{LeaseAge: time.Nanosecond}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, "Released 0 dev IP address(es).")
+
+	// Forcing releases it after confirmation
+	uiMock = new(ui.Mock)
+	uiMock.AddAnswer("^gc_confirm$", "yes")
+	coreConfig.Ui = uiMock
+	core = testCore(t, coreConfig)
+	if err := core.GC(&GCOpts{LeaseAge: time.Nanosecond, Force: true}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !uiMock.InputRequested("gc_confirm") {
+		t.Fatal("should confirm")
+	}
+	uiMock.AssertMessageContains(t, "Released: "+ctx.DevIPAddress)
+}
+
+func TestCoreGC_devDepStore(t *testing.T) {
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.DevDepCacheSize = 1
+	coreConfig.Ui = uiMock
+	core := testCore(t, coreConfig)
+
+	// Add an entry without pruning the store right away
+	store := &devDepStore{Dir: core.devDepStore().Dir}
+	src := testDevDepDir(t, filepath.Join(coreConfig.DataDir, "src"), "hello")
+	err := store.Put("foo", src, &app.DevDep{Files: []string{"data/file"}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := core.GC(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, "Evicted: foo")
+	uiMock.AssertMessageContains(t, "Dev dependency cache: 0 dependencies, 0 B.")
+}
@@ -0,0 +1,233 @@
+package otto
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+// healthRetryInterval is how long Deploy waits between health checks
+// during the grace period. This is a variable for tests.
+var healthRetryInterval = 5 * time.Second
+
+// Health checks whether the deployed application is serving, if the app
+// implements app.Healthchecker. The report is stored in the directory so
+// that Status can show it, and an unhealthy application is an error.
+func (c *Core) Health() (err error) {
+	c.startOutput()
+	op := c.operation("health", nil)
+	defer c.cacheAppContexts()()
+	defer func() {
+		err = c.finishWarnings(err)
+		op.End(err)
+	}()
+
+	root, err := c.appfileCompiled.Graph.Root()
+	if err != nil {
+		return err
+	}
+	rootCtx, err := c.appContext(root.(*appfile.CompiledGraphVertex).File)
+	if err != nil {
+		return errwrap.Wrapf("Error loading App: {{err}}", err)
+	}
+	rootApp, err := c.app(rootCtx)
+	if err != nil {
+		return errwrap.Wrapf("Error loading App: {{err}}", err)
+	}
+	defer maybeClose(rootApp)
+
+	report, err := c.health(rootApp, rootCtx)
+	if err != nil {
+		return err
+	}
+
+	c.ui.Header("Health")
+	ui.Info(c.ui, healthTable(report).String())
+	if report.Status != app.HealthHealthy {
+		return fmt.Errorf(
+			"The application '%s' is %s.", rootCtx.Appfile.Application.Name, report.Status)
+	}
+
+	ui.Result(c.ui, fmt.Sprintf(
+		"The application '%s' is healthy", rootCtx.Appfile.Application.Name))
+	return nil
+}
+
+// health checks the health of the deploy of the root app impl with the
+// context ctx and stores the report.
+func (c *Core) health(impl app.App, ctx *app.Context) (*app.HealthReport, error) {
+	checker, ok := impl.(app.Healthchecker)
+	if !ok {
+		return nil, fmt.Errorf(
+			"The '%s' application type doesn't support health checks.", ctx.Tuple.App)
+	}
+
+	if err := c.deployedContext(ctx, "its health can't be checked"); err != nil {
+		return nil, err
+	}
+
+	report, err := checker.Health(ctx)
+	if err == app.ErrHealthNotSupported {
+		return nil, fmt.Errorf(
+			"The '%s' application type doesn't support health checks.", ctx.Tuple.App)
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("Error checking the health: {{err}}", err)
+	}
+
+	return c.storeHealth(report)
+}
+
+// storeHealth completes the report of a health check that was just done
+// and stores it.
+func (c *Core) storeHealth(report *app.HealthReport) (*app.HealthReport, error) {
+	if report == nil {
+		report = &app.HealthReport{Status: app.HealthUnknown}
+	}
+	report.CheckedAt = time.Now().UTC()
+
+	if err := c.saveHealthReport(report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// deployedContext sets the DeployInfo and InfraOutputs of the context of
+// the root app from the directory. If the app isn't deployed, the error
+// explains that because of this, what describes what can't be done.
+func (c *Core) deployedContext(ctx *app.Context, what string) error {
+	infra := c.appfile.ActiveInfrastructure()
+	deploy, err := c.dir.GetDeploy(&directory.Deploy{
+		Lookup: c.deployLookup(ctx.Appfile.ID)})
+	if err != nil {
+		return errwrap.Wrapf(
+			"Error loading deploy status: {{err}}", directoryErr(err))
+	}
+	if !deploy.IsDeployed() {
+		return fmt.Errorf(
+			"The application '%s' isn't deployed, so %s.",
+			ctx.Appfile.Application.Name, what)
+	}
+	infraData, err := c.dir.GetInfra(&directory.Infra{Lookup: directory.Lookup{
+		Infra: infra.Name}})
+	if err != nil {
+		return errwrap.Wrapf(
+			"Error loading infra status: {{err}}", directoryErr(err))
+	}
+
+	ctx.DeployInfo = deploy.Deploy
+	if infraData != nil {
+		ctx.InfraOutputs = infraData.Outputs
+	}
+
+	return nil
+}
+
+// waitHealthy checks the health of the deploy until it is healthy or the
+// grace period passes, for a deploy that was just done.
+func (c *Core) waitHealthy(impl app.App, ctx *app.Context, grace time.Duration) error {
+	deadline := time.Now().Add(grace)
+	for {
+		report, err := c.health(impl, ctx)
+		if err != nil {
+			return err
+		}
+		if report.Status == app.HealthHealthy {
+			ui.Info(c.ui, fmt.Sprintf(
+				"The application '%s' is healthy", ctx.Appfile.Application.Name))
+			return nil
+		}
+
+		if !time.Now().Add(healthRetryInterval).Before(deadline) {
+			ui.Info(c.ui, healthTable(report).String())
+			return fmt.Errorf(
+				"The deploy of '%s' is %s after waiting %s for it to become healthy.",
+				ctx.Appfile.Application.Name, report.Status, summaryDuration(grace))
+		}
+
+		c.logger.Info("waiting for the deploy to become healthy",
+			"status", string(report.Status))
+		time.Sleep(healthRetryInterval)
+	}
+}
+
+// healthTable returns the health report as a table to show to the user.
+func healthTable(report *app.HealthReport) *ui.Table {
+	table := &ui.Table{MaxWidth: ui.TerminalWidth()}
+	table.AddRow("Status:", healthStatusText(report.Status))
+	for _, check := range report.Checks {
+		text := fmt.Sprintf("%s (%s)",
+			healthStatusText(check.Status), summaryDuration(check.Latency))
+		if check.Message != "" {
+			text += ": " + check.Message
+		}
+		table.AddRow(check.Endpoint+":", text)
+	}
+
+	return table
+}
+
+// healthStatusText returns the status as it is shown to the user.
+func healthStatusText(s app.HealthStatus) string {
+	switch s {
+	case app.HealthHealthy:
+		return ui.Style("HEALTHY", "green")
+	case app.HealthUnhealthy:
+		return ui.Style("UNHEALTHY", "red")
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// healthKey is the key of the blob in the directory with the latest
+// health report of the app on the active infrastructure and environment.
+func (c *Core) healthKey() string {
+	infra := c.appfile.ActiveInfrastructure()
+	return directory.EnvironmentKey(c.environment, fmt.Sprintf(
+		"health-%s-%s-%s", c.appfile.ID, infra.Type, infra.Flavor))
+}
+
+// healthReport returns the latest stored health report, or nil if the
+// health was never checked.
+func (c *Core) healthReport() (*app.HealthReport, error) {
+	data, err := c.dir.GetBlob(c.healthKey())
+	if err != nil {
+		return nil, directoryErr(err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+	defer data.Close()
+
+	var result app.HealthReport
+	if err := json.NewDecoder(data.Data).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (c *Core) saveHealthReport(report *app.HealthReport) error {
+	raw, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	err = c.dir.PutBlob(c.healthKey(), &directory.BlobData{
+		Data: bytes.NewReader(raw),
+	})
+	if err != nil {
+		return errwrap.Wrapf(
+			"Error storing the health report: {{err}}", directoryErr(err))
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package otto
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreHealth(t *testing.T) {
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	appMock := testHealthApp(t, coreConfig)
+	appMock.HealthResult = &app.HealthReport{
+		Status: app.HealthHealthy,
+		Checks: []*app.HealthCheck{
+			&app.HealthCheck{Endpoint: "http://foo.com", Status: app.HealthHealthy},
+		},
+	}
+	core := testCore(t, coreConfig)
+
+	// The app must be deployed
+	err := core.Health()
+	if err == nil || !strings.Contains(err.Error(), "isn't deployed") {
+		t.Fatalf("err: %v", err)
+	}
+	if appMock.HealthCalled {
+		t.Fatal("health should not be called")
+	}
+
+	testDeployed(t, core, map[string]string{"url": "http://foo.com"})
+	err = core.dir.PutInfra(&directory.Infra{
+		Lookup:  directory.Lookup{Infra: core.appfile.ActiveInfrastructure().Name},
+		State:   directory.InfraStateReady,
+		Outputs: map[string]string{"region": "us-east-1"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := core.Health(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	ctx := appMock.HealthContext
+	if ctx.DeployInfo["url"] != "http://foo.com" || ctx.InfraOutputs["region"] != "us-east-1" {
+		t.Fatalf("bad: %#v", ctx)
+	}
+	uiMock.AssertMessageContains(t, "http://foo.com:")
+
+	// The report is stored for the status
+	report, err := core.healthReport()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if report == nil || report.Status != app.HealthHealthy || report.CheckedAt.IsZero() {
+		t.Fatalf("bad: %#v", report)
+	}
+	if err := core.Status(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, "Health:")
+	uiMock.AssertMessageContains(t, "(checked")
+
+	// Unhealthy is an error
+	appMock.HealthResult = &app.HealthReport{Status: app.HealthUnhealthy}
+	if err := core.Health(); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestCoreHealth_notSupported(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	core := testCore(t, coreConfig)
+	testDeployed(t, core, nil)
+
+	err := core.Health()
+	if err == nil || !strings.Contains(err.Error(), "doesn't support health checks") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestCoreDeploy_health(t *testing.T) {
+	defer func(d time.Duration) { healthRetryInterval = d }(healthRetryInterval)
+	healthRetryInterval = time.Millisecond
+
+	uiMock := new(ui.Mock)
+	uiMock.AddAnswer("^creds_password$", "hunter2")
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	appMock := testHealthApp(t, coreConfig)
+	core := testCore(t, coreConfig)
+	testDeployed(t, core, nil)
+
+	// The health is checked until it is healthy
+	var checks int
+	appMock.HealthFunc = func(*app.Context) (*app.HealthReport, error) {
+		checks++
+		if checks < 3 {
+			return &app.HealthReport{Status: app.HealthUnhealthy}, nil
+		}
+
+		return &app.HealthReport{Status: app.HealthHealthy}, nil
+	}
+	opts := &DeployOpts{Health: true, HealthGrace: time.Minute}
+	if err := core.DeployWithOpts("", nil, opts); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if checks != 3 {
+		t.Fatalf("bad: %d", checks)
+	}
+
+	// The deploy fails if it isn't healthy within the grace period
+	checks = -100
+	opts.HealthGrace = 10 * time.Millisecond
+	err := core.DeployWithOpts("", nil, opts)
+	if err == nil || !strings.Contains(err.Error(), "is unhealthy after waiting") {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Without the option the health isn't checked
+	checks = 0
+	if err := core.Deploy("", nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if checks != 0 {
+		t.Fatalf("bad: %d", checks)
+	}
+}
+
+// testHealthApp registers an app that implements app.Healthchecker with
+// the TestAppTuple.
+func testHealthApp(t *testing.T, c *CoreConfig) *app.MockHealthchecker {
+	result := new(app.MockHealthchecker)
+	c.Apps[TestAppTuple] = func() (app.App, error) {
+		return result, nil
+	}
+
+	return result
+}
+
+// testDeployed records a successful deploy of the root app with info in
+// the environment of the core.
+func testDeployed(t *testing.T, core *Core, info map[string]string) {
+	deploy := &directory.Deploy{
+		Lookup: core.deployLookup(core.appfile.ID),
+		Deploy: info,
+	}
+	deploy.MarkSuccessful()
+	if err := core.dir.PutDeploy(deploy); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
@@ -0,0 +1,217 @@
+package otto
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+// ImpactOpts are the options for Core.Impact.
+type ImpactOpts struct {
+	// Environments are the environments to look up the builds and the
+	// deploys in. This defaults to the environment of the Core.
+	Environments []string
+}
+
+// ImpactReport is what is affected by a change to an app of the
+// Appfile, returned by Core.Impact.
+type ImpactReport struct {
+	// App is the changed app.
+	App *ImpactApp `json:"app"`
+
+	// Recompile are the changed app and the apps that depend on it,
+	// directly or transitively, in the order they're compiled.
+	Recompile []*ImpactApp `json:"recompile"`
+
+	// Rebuild are the builds in the directory that are likely invalid,
+	// since the source they were built from includes the changed app.
+	Rebuild []*ImpactRecord `json:"rebuild"`
+
+	// Redeploy are the deploys in the directory of the apps to recompile,
+	// which become outdated once they're recompiled.
+	Redeploy []*ImpactRecord `json:"redeploy"`
+}
+
+// ImpactApp is an app in an ImpactReport.
+type ImpactApp struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+
+	// Root is true for the application of the Appfile itself, and false
+	// for its dependencies.
+	Root bool `json:"root,omitempty"`
+}
+
+// ImpactRecord is a build or a deploy of an app in an environment in an
+// ImpactReport.
+type ImpactRecord struct {
+	ImpactApp
+	Environment string `json:"environment"`
+}
+
+// Render outputs the report to the Ui u.
+func (r *ImpactReport) Render(u ui.Ui) {
+	u.Header(fmt.Sprintf("Impact of changing '%s'", r.App.Name))
+
+	names := make([]string, len(r.Recompile))
+	for i, a := range r.Recompile {
+		names[i] = a.Name
+	}
+	u.Message(fmt.Sprintf("Recompile: %s", strings.Join(names, ", ")))
+
+	for _, section := range []struct {
+		Name    string
+		Records []*ImpactRecord
+	}{
+		{"Builds likely invalidated", r.Rebuild},
+		{"Deploys that become outdated", r.Redeploy},
+	} {
+		if len(section.Records) == 0 {
+			u.Message(fmt.Sprintf("%s: none", section.Name))
+			continue
+		}
+
+		table := &ui.Table{
+			Headers:  []string{"APP", "ENVIRONMENT"},
+			MaxWidth: ui.TerminalWidth(),
+		}
+		for _, record := range section.Records {
+			table.AddRow(record.Name, record.Environment)
+		}
+		u.Message(fmt.Sprintf("%s:\n\n%s", section.Name, table))
+	}
+}
+
+// Impact returns what is affected by a change to the app of the Appfile
+// with the given name or ID: the apps to compile again, and the builds
+// and deploys in the directory that the change makes outdated.
+func (c *Core) Impact(name string, opts *ImpactOpts) (*ImpactReport, error) {
+	if opts == nil {
+		opts = new(ImpactOpts)
+	}
+	envs := opts.Environments
+	if len(envs) == 0 {
+		envs = []string{c.environment}
+	}
+
+	changed, err := c.impactVertex(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// The apps that depend on the changed app, including itself
+	affected := map[string]struct{}{changed.File.ID: struct{}{}}
+	next := []*appfile.CompiledGraphVertex{changed}
+	for len(next) > 0 {
+		v := next[0]
+		next = next[1:]
+		for _, raw := range c.appfileCompiled.Graph.UpEdges(v).List() {
+			dep := raw.(*appfile.CompiledGraphVertex)
+			if _, ok := affected[dep.File.ID]; ok {
+				continue
+			}
+
+			affected[dep.File.ID] = struct{}{}
+			next = append(next, dep)
+		}
+	}
+
+	changedDir, changedLocal := c.impactSourceDir(changed)
+	result := &ImpactReport{App: c.impactApp(changed)}
+	for _, v := range c.walkOrder() {
+		if _, ok := affected[v.File.ID]; !ok {
+			continue
+		}
+
+		a := c.impactApp(v)
+		result.Recompile = append(result.Recompile, a)
+
+		// The source fingerprint of a build covers the directory of the
+		// app, which may have the changed app within it.
+		includes := v == changed
+		if dir, ok := c.impactSourceDir(v); ok && changedLocal && !includes {
+			includes = pathWithin(changedDir, dir)
+		}
+		for _, env := range envs {
+			lookup := c.deployLookup(v.File.ID)
+			lookup.Environment = env
+			record := &ImpactRecord{ImpactApp: *a, Environment: env}
+
+			if includes {
+				build, err := c.dir.GetBuild(&directory.Build{Lookup: lookup})
+				if err != nil {
+					return nil, errwrap.Wrapf(
+						"Error loading build status: {{err}}", directoryErr(err))
+				}
+				if build != nil {
+					result.Rebuild = append(result.Rebuild, record)
+				}
+			}
+
+			deploy, err := c.dir.GetDeploy(&directory.Deploy{Lookup: lookup})
+			if err != nil {
+				return nil, errwrap.Wrapf(
+					"Error loading deploy status: {{err}}", directoryErr(err))
+			}
+			if deploy.IsDeployed() {
+				result.Redeploy = append(result.Redeploy, record)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// impactVertex returns the vertex of the app with the given name or ID,
+// which is the application of the Appfile itself or one of its
+// dependencies.
+func (c *Core) impactVertex(name string) (*appfile.CompiledGraphVertex, error) {
+	order := c.walkOrder()
+	if len(order) > 0 {
+		root := order[len(order)-1]
+		if root.File.Application.Name == name || root.File.ID == name {
+			return root, nil
+		}
+	}
+
+	return c.depVertex(name)
+}
+
+// impactSourceDir returns the local directory of the source of the app of
+// v. Dependencies are copied before they're compiled, so this is their
+// source rather than the directory of their loaded Appfile, and false if
+// the source isn't local.
+func (c *Core) impactSourceDir(v *appfile.CompiledGraphVertex) (string, bool) {
+	if v.File.ID == c.appfile.ID {
+		if v.File.Path == "" {
+			return "", false
+		}
+
+		return filepath.Dir(v.File.Path), true
+	}
+
+	return devDepSourceDir(v.File.Source)
+}
+
+func (c *Core) impactApp(v *appfile.CompiledGraphVertex) *ImpactApp {
+	return &ImpactApp{
+		ID:   v.File.ID,
+		Name: v.File.Application.Name,
+		Root: v.File.ID == c.appfile.ID,
+	}
+}
+
+// pathWithin returns true if the path is dir or within it.
+func pathWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
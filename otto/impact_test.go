@@ -0,0 +1,108 @@
+package otto
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreImpact(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps-order", "Appfile"))
+	core := testCore(t, coreConfig)
+
+	// Everything is built and deployed in the default environment, and
+	// only the root in staging.
+	for _, v := range core.walkOrder() {
+		lookup := core.deployLookup(v.File.ID)
+		build := &directory.Build{Lookup: lookup, Artifact: map[string]string{"id": "1"}}
+		if err := core.dir.PutBuild(build); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		deploy := &directory.Deploy{Lookup: lookup}
+		deploy.MarkSuccessful()
+		if err := core.dir.PutDeploy(deploy); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	lookup := core.deployLookup(core.appfile.ID)
+	lookup.Environment = "staging"
+	deploy := &directory.Deploy{Lookup: lookup}
+	deploy.MarkSuccessful()
+	if err := core.dir.PutDeploy(deploy); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	report, err := core.Impact("cache", &ImpactOpts{
+		Environments: []string{"default", "staging"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	names := func(records []*ImpactRecord) []string {
+		var result []string
+		for _, r := range records {
+			result = append(result, r.Name+"@"+r.Environment)
+		}
+		return result
+	}
+	var recompile []string
+	for _, a := range report.Recompile {
+		recompile = append(recompile, a.Name)
+	}
+	if !reflect.DeepEqual(recompile, []string{"cache", "alpha", "web"}) {
+		t.Fatalf("bad: %#v", recompile)
+	}
+
+	// The source of alpha doesn't include cache, so its build is fine
+	if actual := names(report.Rebuild); !reflect.DeepEqual(actual, []string{
+		"cache@default", "web@default"}) {
+		t.Fatalf("bad: %#v", actual)
+	}
+	if actual := names(report.Redeploy); !reflect.DeepEqual(actual, []string{
+		"cache@default", "alpha@default", "web@default", "web@staging"}) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Nothing depends on the root
+	report, err = core.Impact("web", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(report.Recompile) != 1 || !report.Recompile[0].Root {
+		t.Fatalf("bad: %#v", report.Recompile)
+	}
+
+	if _, err := core.Impact("nope", nil); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestImpactReport(t *testing.T) {
+	report := &ImpactReport{
+		App:       &ImpactApp{ID: "1", Name: "db"},
+		Recompile: []*ImpactApp{{ID: "1", Name: "db"}, {ID: "2", Name: "web", Root: true}},
+		Redeploy: []*ImpactRecord{
+			{ImpactApp: ImpactApp{ID: "2", Name: "web", Root: true}, Environment: "staging"},
+		},
+	}
+
+	uiMock := new(ui.Mock)
+	report.Render(uiMock)
+	uiMock.AssertMessageContains(t, "Recompile: db, web")
+	uiMock.AssertMessageContains(t, "Builds likely invalidated: none")
+	uiMock.AssertMessageContains(t, "staging")
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.Contains(string(data), `"redeploy":[{"id":"2","name":"web","root":true,"environment":"staging"}]`) {
+		t.Fatalf("bad: %s", data)
+	}
+}
@@ -0,0 +1,98 @@
+package otto
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/directory"
+)
+
+// Logs writes the logs of the deployed application to w until the logs
+// are done, or until opts.ShutdownCh is closed if they're followed. This
+// requires the app type to implement app.LogViewer, and returns an
+// *ErrLogsNotSupported otherwise.
+func (c *Core) Logs(opts app.LogOptions, w io.Writer) (err error) {
+	op := c.operation("logs", nil)
+	defer func() { op.End(err) }()
+
+	impl, ctx, err := c.logViewer(false)
+	if err != nil {
+		return err
+	}
+	defer maybeClose(impl)
+
+	// The logs are usually fetched from the infrastructure, so this needs
+	// the credentials.
+	infra, infraCtx, err := c.infra()
+	if err != nil {
+		return err
+	}
+	defer maybeClose(infra)
+	if err := c.creds(infra, infraCtx); err != nil {
+		return err
+	}
+	ctx.Shared.InfraCreds = infraCtx.Shared.InfraCreds
+
+	if err := c.deployedContext(ctx, "there are no logs"); err != nil {
+		return err
+	}
+
+	return c.logsErr(impl.Logs(ctx, opts, w), false)
+}
+
+// DevLogs is Logs for the application in the development environment.
+func (c *Core) DevLogs(opts app.LogOptions, w io.Writer) (err error) {
+	op := c.operation("dev.logs", nil)
+	defer func() { op.End(err) }()
+
+	impl, ctx, err := c.logViewer(true)
+	if err != nil {
+		return err
+	}
+	defer maybeClose(impl)
+
+	dev, err := c.dir.GetDev(&directory.Dev{Lookup: directory.Lookup{
+		AppID: c.appfile.ID}})
+	if err != nil {
+		return fmt.Errorf(
+			"Error loading development status: %s", directoryErr(err))
+	}
+	if !dev.IsReady() {
+		return fmt.Errorf(
+			"The development environment of '%s' isn't running, so there\n"+
+				"are no logs. Run `otto dev` to create it.",
+			c.appfile.Application.Name)
+	}
+
+	return c.logsErr(impl.DevLogs(ctx, opts, w), true)
+}
+
+// logViewer returns the root app as an app.LogViewer with its context.
+func (c *Core) logViewer(dev bool) (app.LogViewer, *app.Context, error) {
+	impl, ctx, err := c.App()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, ok := impl.(app.LogViewer)
+	if !ok {
+		maybeClose(impl)
+		return nil, nil, c.logsErr(app.ErrLogsNotSupported, dev)
+	}
+
+	return result, ctx, nil
+}
+
+// logsErr turns app.ErrLogsNotSupported, which apps served over RPC
+// return, into an error that names the app type.
+func (c *Core) logsErr(err error, dev bool) error {
+	if err == app.ErrLogsNotSupported {
+		return &ErrLogsNotSupported{
+			AppType: c.appfile.Application.Type,
+			Dev:     dev,
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,103 @@
+package otto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreLogs(t *testing.T) {
+	uiMock := new(ui.Mock)
+	uiMock.AddAnswer("^creds_password$", "hunter2")
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	infraMock := TestInfra(t, "test", coreConfig)
+	infraMock.CredsResult = map[string]string{"key": "secret"}
+	appMock := new(app.MockLogViewer)
+	appMock.LogsOutput = "hello\n"
+	coreConfig.Apps[TestAppTuple] = func() (app.App, error) {
+		return appMock, nil
+	}
+	core := testCore(t, coreConfig)
+
+	// The app must be deployed
+	var buf bytes.Buffer
+	if err := core.Logs(app.LogOptions{}, &buf); err == nil {
+		t.Fatal("should error")
+	}
+	if appMock.LogsCalled {
+		t.Fatal("logs should not be called")
+	}
+
+	testDeployed(t, core, map[string]string{"url": "http://foo.com"})
+	opts := app.LogOptions{Follow: true, Tail: 5}
+	if err := core.Logs(opts, &buf); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if buf.String() != "hello\n" {
+		t.Fatalf("bad: %q", buf.String())
+	}
+	if !appMock.LogsOpts.Follow || appMock.LogsOpts.Tail != 5 {
+		t.Fatalf("bad: %#v", appMock.LogsOpts)
+	}
+	ctx := appMock.LogsContext
+	if ctx.DeployInfo["url"] != "http://foo.com" || ctx.InfraCreds["key"] != "secret" {
+		t.Fatalf("bad: %#v", ctx)
+	}
+}
+
+func TestCoreLogs_notSupported(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	core := testCore(t, coreConfig)
+
+	var buf bytes.Buffer
+	err := core.Logs(app.LogOptions{}, &buf)
+	nsErr, ok := err.(*ErrLogsNotSupported)
+	if !ok {
+		t.Fatalf("err: %#v", err)
+	}
+	if nsErr.AppType != core.appfile.Application.Type || nsErr.Dev {
+		t.Fatalf("bad: %#v", nsErr)
+	}
+}
+
+func TestCoreDevLogs(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	appMock := new(app.MockLogViewer)
+	appMock.DevLogsOutput = "dev\n"
+	coreConfig.Apps[TestAppTuple] = func() (app.App, error) {
+		return appMock, nil
+	}
+	core := testCore(t, coreConfig)
+
+	// The dev environment must be running
+	var buf bytes.Buffer
+	if err := core.DevLogs(app.LogOptions{}, &buf); err == nil {
+		t.Fatal("should error")
+	}
+
+	dev := &directory.Dev{Lookup: directory.Lookup{AppID: core.appfile.ID}}
+	dev.MarkReady()
+	if err := core.dir.PutDev(dev); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := core.DevLogs(app.LogOptions{Tail: 3}, &buf); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if buf.String() != "dev\n" || appMock.DevLogsOpts.Tail != 3 {
+		t.Fatalf("bad: %q %#v", buf.String(), appMock.DevLogsOpts)
+	}
+
+	// A plugin that doesn't support it is an error naming the app type
+	appMock.DevLogsErr = app.ErrLogsNotSupported
+	err := core.DevLogs(app.LogOptions{}, &buf)
+	if nsErr, ok := err.(*ErrLogsNotSupported); !ok || !nsErr.Dev {
+		t.Fatalf("err: %#v", err)
+	}
+}
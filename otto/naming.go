@@ -0,0 +1,329 @@
+package otto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/directory"
+)
+
+// The default naming templates, used if neither the Appfile nor the
+// CoreConfig has one.
+const (
+	defaultArtifactName = "{{.AppID}}/{{.Environment}}/{{.Timestamp}}"
+	defaultRecordName   = "{{.Project}}-{{.App}}-{{.Timestamp}}"
+)
+
+// The keys of the resolved names in the artifacts of build records and
+// in the deploy labels.
+const (
+	buildNameKey        = "name"
+	buildArtifactKey    = "artifact_name"
+	deployBuildNameKey  = "build_name"
+	deployArtifactKey   = "artifact_name"
+	nameTimestampFormat = "20060102150405"
+)
+
+// buildNames are the resolved names of a build.
+type buildNames struct {
+	// Artifact is the prefix of the keys of the files of the build in
+	// the artifact store.
+	Artifact string
+
+	// Record is the name of the build, which the build record and the
+	// deploys of the build are labeled with.
+	Record string
+
+	// Fingerprint is the fingerprint of the source of the build, which
+	// the build record is labeled with to tell if the source changed.
+	Fingerprint string
+}
+
+// nameTemplates returns the naming templates for the Appfile f. The
+// templates of the project of the Appfile take precedence over the ones
+// of the CoreConfig.
+func (c *Core) nameTemplates(f *appfile.File) (*appfile.NameTemplate, *appfile.NameTemplate, error) {
+	artifact, record := defaultArtifactName, defaultRecordName
+	if c.artifactName != "" {
+		artifact = c.artifactName
+	}
+	if c.recordName != "" {
+		record = c.recordName
+	}
+	if f.Project != nil && f.Project.ArtifactName != "" {
+		artifact = f.Project.ArtifactName
+	}
+	if f.Project != nil && f.Project.RecordName != "" {
+		record = f.Project.RecordName
+	}
+
+	artifactTpl, err := appfile.ParseNameTemplate(artifact)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error parsing artifact_name: %s", err)
+	}
+	recordTpl, err := appfile.ParseNameTemplate(record)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error parsing record_name: %s", err)
+	}
+
+	return artifactTpl, recordTpl, nil
+}
+
+// buildNames resolves the names of a build of the app of the context ctx
+// with the version given by the user.
+func (c *Core) buildNames(ctx *app.Context, version string) (*buildNames, error) {
+	artifactTpl, recordTpl, err := c.nameTemplates(ctx.Appfile)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := &appfile.NameVars{
+		App:         ctx.Appfile.Application.Name,
+		AppID:       ctx.Appfile.ID,
+		Environment: c.environment,
+		Infra:       c.appfile.ActiveInfrastructure().Type,
+		Version:     version,
+		Timestamp:   time.Now().UTC().Format(nameTimestampFormat),
+	}
+	if ctx.Appfile.Project != nil {
+		vars.Project = ctx.Appfile.Project.Name
+	}
+	vars.Fingerprint, err = c.cachedSourceFingerprint(filepath.Dir(ctx.Appfile.Path))
+	if err != nil {
+		return nil, fmt.Errorf("Error fingerprinting the source: %s", err)
+	}
+
+	result := buildNames{Fingerprint: vars.Fingerprint}
+	result.Artifact, err = artifactTpl.Execute(vars)
+	if err != nil {
+		return nil, fmt.Errorf("Error naming the artifacts of the build: %s", err)
+	}
+	result.Record, err = recordTpl.Execute(vars)
+	if err != nil {
+		return nil, fmt.Errorf("Error naming the build: %s", err)
+	}
+
+	return &result, nil
+}
+
+// labelDeploy labels the deploy of the app with the ID appID with the
+// names of the build it deployed, and with the hash of the compilation
+// and the fingerprint of the source it came from, so that Status can tell
+// if it is outdated. This returns the name of the build, or "" if the
+// build has no name.
+//
+// The labels are stored apart from the deploy information, which is the
+// outputs of the app that its dependents read. See deployLabels.
+func (c *Core) labelDeploy(appID string) (string, error) {
+	lookup := c.deployLookup(appID)
+	deploy, err := c.dir.GetDeploy(&directory.Deploy{Lookup: lookup})
+	if err != nil {
+		return "", directoryErr(err)
+	}
+	if deploy == nil {
+		return "", nil
+	}
+	build, err := c.dir.GetBuild(&directory.Build{Lookup: lookup})
+	if err != nil {
+		return "", directoryErr(err)
+	}
+	md, err := c.compileMetadata()
+	if err != nil {
+		return "", err
+	}
+
+	labels := make(map[string]string)
+	if build != nil {
+		labels[deployBuildNameKey] = build.Artifact[buildNameKey]
+		labels[deployArtifactKey] = build.Artifact[buildArtifactKey]
+		labels[driftFingerprintKey] = build.Artifact[driftFingerprintKey]
+	}
+	if md != nil {
+		labels[driftCompileHashKey] = compileMetadataHash(md)
+	}
+	for k, v := range labels {
+		if v == "" {
+			delete(labels, k)
+		}
+	}
+
+	// The labels are always stored so that the labels of an earlier
+	// deploy don't outlive it.
+	raw, err := json.Marshal(labels)
+	if err != nil {
+		return "", err
+	}
+	err = c.dir.PutBlob(c.deployLabelsKey(appID), &directory.BlobData{
+		Data: bytes.NewReader(raw),
+	})
+	if err != nil {
+		return "", errwrap.Wrapf(
+			"Error storing the deploy labels: {{err}}", directoryErr(err))
+	}
+
+	return labels[deployBuildNameKey], nil
+}
+
+// deployLabelsKey is the key of the blob in the directory with the labels
+// of the deploy of the app with the ID appID on the active infrastructure
+// and environment.
+func (c *Core) deployLabelsKey(appID string) string {
+	infra := c.appfile.ActiveInfrastructure()
+	return directory.EnvironmentKey(c.environment, fmt.Sprintf(
+		"deploy-labels-%s-%s-%s", appID, infra.Type, infra.Flavor))
+}
+
+// deployLabels returns the labels of the deploy of the app with the ID
+// appID, or nil if it was never labeled. See labelDeploy.
+func (c *Core) deployLabels(appID string) (map[string]string, error) {
+	data, err := c.dir.GetBlob(c.deployLabelsKey(appID))
+	if err != nil {
+		return nil, directoryErr(err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+	defer data.Close()
+
+	var result map[string]string
+	if err := json.NewDecoder(data.Data).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// sourceIndex is the fingerprint of a source directory along with the
+// hash of the names, sizes, and modification times of its files that it
+// was computed from. See cachedSourceFingerprint.
+type sourceIndex struct {
+	Stat        string `json:"stat"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// cachedSourceFingerprint returns the sourceFingerprint of dir. The
+// contents of the files are only read if the names, sizes, or
+// modification times of the files changed since the last time, so that
+// Status doesn't read the whole source each time.
+func (c *Core) cachedSourceFingerprint(dir string) (string, error) {
+	stat, err := sourceStat(dir)
+	if err != nil {
+		return "", err
+	}
+
+	c.sourceLock.Lock()
+	defer c.sourceLock.Unlock()
+
+	index := make(map[string]sourceIndex)
+	path := c.sourceIndexPath()
+	if raw, err := ioutil.ReadFile(path); err == nil {
+		if err := json.Unmarshal(raw, &index); err != nil {
+			c.logger.Debug("ignoring invalid source index", "err", err)
+		}
+	}
+	if v, ok := index[dir]; ok && v.Stat == stat {
+		return v.Fingerprint, nil
+	}
+
+	fp, err := sourceFingerprint(dir)
+	if err != nil {
+		return "", err
+	}
+
+	// The index is only a cache, so failing to save it isn't an error.
+	index[dir] = sourceIndex{Stat: stat, Fingerprint: fp}
+	raw, err := json.Marshal(index)
+	if err == nil {
+		err = os.MkdirAll(c.localDir, 0755)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(path, raw, 0644)
+	}
+	if err != nil {
+		c.logger.Debug("error saving the source index", "err", err)
+	}
+
+	return fp, nil
+}
+
+func (c *Core) sourceIndexPath() string {
+	return filepath.Join(c.localDir, "source-index.json")
+}
+
+// sourceFingerprint returns a short hash of the names and the contents of
+// the files in the source directory dir. The directories that dev
+// ignores, such as .git and .otto, are left out.
+func sourceFingerprint(dir string) (string, error) {
+	h := sha256.New()
+	err := walkSource(dir, func(path, rel string, info os.FileInfo) error {
+		fmt.Fprintf(h, "%s\x00%d\x00", rel, info.Size())
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:12], nil
+}
+
+// sourceStat returns a hash of the names, sizes, and modification times
+// of the files that sourceFingerprint reads.
+func sourceStat(dir string) (string, error) {
+	h := sha256.New()
+	err := walkSource(dir, func(path, rel string, info os.FileInfo) error {
+		fmt.Fprintf(h, "%s\x00%d\x00%d\x00",
+			rel, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// walkSource calls f with the path, the slash-separated path relative to
+// dir, and the info of each regular file of the source in dir, leaving
+// out the directories that dev ignores.
+func walkSource(dir string, f func(path, rel string, info os.FileInfo) error) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && devWatchIgnored(info.Name(), DefaultDevWatchIgnore) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		return f(path, filepath.ToSlash(rel), info)
+	})
+}
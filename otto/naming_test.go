@@ -0,0 +1,210 @@
+package otto
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/directory"
+)
+
+func TestCoreBuild_naming(t *testing.T) {
+	store := new(testArtifactStore)
+	core, appMock, _ := testArtifactCore(t, "build-naming", store)
+
+	if err := core.BuildWithOpts(&BuildOpts{Version: "1.2"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The files are uploaded under the artifact name
+	if _, ok := store.Data["acme/web-1.2/image/image.tar"]; !ok {
+		t.Fatalf("bad: %#v", store.Data)
+	}
+
+	// The names are recorded in the build
+	build, err := core.dir.GetBuild(&directory.Build{
+		Lookup: core.deployLookup(core.appfile.ID)})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	name := build.Artifact["name"]
+	if !regexp.MustCompile(`^acme-web-1\.2-[0-9a-f]{12}$`).MatchString(name) {
+		t.Fatalf("bad: %#v", build.Artifact)
+	}
+	if build.Artifact["artifact_name"] != "acme/web-1.2" {
+		t.Fatalf("bad: %#v", build.Artifact)
+	}
+
+	// The deploy is labeled with the names of the build
+	testDeployed(t, core, map[string]string{"url": "http://web.com"})
+	if err := core.Deploy("", nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	deploy, err := core.dir.GetDeploy(&directory.Deploy{
+		Lookup: core.deployLookup(core.appfile.ID)})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(deploy.Deploy) != 1 || deploy.Deploy["url"] != "http://web.com" {
+		t.Fatalf("bad: %#v", deploy.Deploy)
+	}
+	labels, err := core.deployLabels(core.appfile.ID)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if labels["build_name"] != name || labels["artifact_name"] != "acme/web-1.2" {
+		t.Fatalf("bad: %#v", labels)
+	}
+	last, err := core.lastDeploy()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if last.Build != name {
+		t.Fatalf("bad: %#v", last)
+	}
+	if !appMock.DeployCalled {
+		t.Fatal("deploy should be called")
+	}
+}
+
+func TestCoreBuild_namingInvalid(t *testing.T) {
+	core, appMock, _ := testArtifactCore(t, "build-naming", new(testArtifactStore))
+
+	// The version makes an invalid name, which fails before the build
+	err := core.BuildWithOpts(&BuildOpts{Version: "1.2 beta"})
+	if err == nil || !strings.Contains(err.Error(), "invalid name") {
+		t.Fatalf("err: %v", err)
+	}
+	if appMock.BuildArtifactsCalled {
+		t.Fatal("build should not be called")
+	}
+}
+
+func TestNewCore_nameTemplates(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.RecordNameTemplate = "{{.Project}}-{{.Commit}}"
+	if _, err := NewCore(coreConfig); err == nil {
+		t.Fatal("should error")
+	}
+
+	// The templates of the CoreConfig are used if the Appfile has none
+	coreConfig.RecordNameTemplate = "{{.App}}-{{.Environment}}"
+	core := testCore(t, coreConfig)
+	_, record, err := core.nameTemplates(core.appfile)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	name, err := record.Execute(&appfile.NameVars{App: "web", Environment: "staging"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if name != "web-staging" {
+		t.Fatalf("bad: %s", name)
+	}
+}
+
+func TestSourceFingerprint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(path, data string) {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	fingerprint := func() string {
+		fp, err := sourceFingerprint(dir)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		return fp
+	}
+
+	write("main.go", "package main")
+	fp := fingerprint()
+	if len(fp) != 12 {
+		t.Fatalf("bad: %s", fp)
+	}
+
+	// Ignored directories don't change it
+	write(".git/HEAD", "ref: refs/heads/master")
+	write(".otto/data/foo", "bar")
+	if v := fingerprint(); v != fp {
+		t.Fatalf("bad: %s != %s", v, fp)
+	}
+
+	// The contents of the source do
+	write("main.go", "package main\n")
+	if v := fingerprint(); v == fp {
+		t.Fatalf("bad: %s", v)
+	}
+}
+
+func TestCoreCachedSourceFingerprint(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	core := testCore(t, coreConfig)
+	dir, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "main.go")
+	if err := ioutil.WriteFile(path, []byte("package main"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	fp, err := core.cachedSourceFingerprint(dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected, err := sourceFingerprint(dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if fp != expected {
+		t.Fatalf("bad: %s != %s", fp, expected)
+	}
+
+	// The contents aren't read again if the files look the same
+	stat, err := sourceStat(dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	raw, err := json.Marshal(map[string]sourceIndex{
+		dir: sourceIndex{Stat: stat, Fingerprint: "cached"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := ioutil.WriteFile(core.sourceIndexPath(), raw, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if fp, err := core.cachedSourceFingerprint(dir); err != nil || fp != "cached" {
+		t.Fatalf("bad: %s %v", fp, err)
+	}
+
+	// A newer modification time reads them again
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if fp, err := core.cachedSourceFingerprint(dir); err != nil || fp != expected {
+		t.Fatalf("bad: %s %v", fp, err)
+	}
+}
@@ -0,0 +1,131 @@
+package otto
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/otto/helper/logger"
+	"github.com/hashicorp/otto/ui"
+)
+
+// opLogSuffix is the suffix of operation log files.
+const opLogSuffix = ".log"
+
+// opLogDir is the directory where operation log files are written.
+func (c *Core) opLogDir() string {
+	return filepath.Join(c.localDir, "logs")
+}
+
+// startOpLog starts writing the log file for the given operation, if
+// log files are enabled. While the log file is open, the logger and a
+// copy of all Ui output are written to it.
+//
+// The returned function must be called with the result of the operation
+// when it completes. If the operation failed, the path to the log file
+// is output.
+func (c *Core) startOpLog(op string) func(error) {
+	if c.logFiles <= 0 {
+		return func(error) {}
+	}
+
+	dir := c.opLogDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		ui.Warn(c.ui, fmt.Sprintf(
+			"Error creating log directory, using a temporary directory: %s", err))
+
+		dir, err = ioutil.TempDir("", "otto-logs")
+		if err != nil {
+			ui.Warn(c.ui, fmt.Sprintf(
+				"Error creating temporary log directory, not writing a log: %s", err))
+			return func(error) {}
+		}
+	}
+
+	// Prune so that including the new file we have the configured amount
+	c.pruneOpLogs(dir, c.logFiles-1)
+
+	path := filepath.Join(dir, fmt.Sprintf(
+		"%s-%s%s", op, time.Now().UTC().Format("20060102-150405.000"), opLogSuffix))
+	f, err := os.Create(path)
+	if err != nil {
+		ui.Warn(c.ui, fmt.Sprintf(
+			"Error creating log file, not writing a log: %s", err))
+		return func(error) {}
+	}
+
+	oldLogger, oldUi := c.logger, c.ui
+	c.logger = logger.Tee(
+		oldLogger, logger.New(f).With("appfile", c.appfile.ID, "op", op))
+	c.ui = &ui.Tee{Ui: oldUi, Writer: f}
+
+	return func(err error) {
+		c.logger, c.ui = oldLogger, oldUi
+		if err != nil {
+			fmt.Fprintf(f, "operation failed: %s\n", err)
+		}
+		f.Close()
+
+		if err != nil {
+			ui.Error(c.ui, fmt.Sprintf("Full log: %s", path))
+		}
+	}
+}
+
+// opLogs returns the paths to the operation log files in dir, oldest first.
+func opLogs(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+
+		return nil, err
+	}
+
+	sort.Sort(byModTime(infos))
+	result := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), opLogSuffix) {
+			result = append(result, filepath.Join(dir, info.Name()))
+		}
+	}
+
+	return result, nil
+}
+
+// pruneOpLogs deletes the oldest log files in dir so that at most keep
+// remain. Errors are logged since pruning is best effort.
+func (c *Core) pruneOpLogs(dir string, keep int) {
+	paths, err := opLogs(dir)
+	if err != nil {
+		c.logger.Warn("error listing log files", "dir", dir, "err", err)
+		return
+	}
+
+	for len(paths) > keep {
+		if err := os.Remove(paths[0]); err != nil {
+			c.logger.Warn("error pruning log file", "path", paths[0], "err", err)
+		}
+
+		paths = paths[1:]
+	}
+}
+
+// byModTime sorts files by modification time, oldest first. Files with
+// the same modification time are sorted by name.
+type byModTime []os.FileInfo
+
+func (s byModTime) Len() int      { return len(s) }
+func (s byModTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byModTime) Less(i, j int) bool {
+	if s[i].ModTime().Equal(s[j].ModTime()) {
+		return s[i].Name() < s[j].Name()
+	}
+
+	return s[i].ModTime().Before(s[j].ModTime())
+}
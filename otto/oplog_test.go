@@ -0,0 +1,86 @@
+package otto
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreCompile_logFile(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.LogFiles = 2
+	core := testCore(t, coreConfig)
+
+	for i := 0; i < 3; i++ {
+		if err := core.Compile(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	paths, err := opLogs(filepath.Join(coreConfig.LocalDir, "logs"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("bad: %#v", paths)
+	}
+
+	data, err := ioutil.ReadFile(paths[1])
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.Contains(string(data), "Compiling main application") {
+		t.Fatalf("bad: %s", data)
+	}
+	if !strings.Contains(string(data), "running infra compile") {
+		t.Fatalf("bad: %s", data)
+	}
+}
+
+func TestCoreBuild_logFileFailed(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.LogFiles = 1
+	uiMock := new(ui.Mock)
+	uiMock.AddAnswer("creds_password", "password")
+	coreConfig.Ui = uiMock
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	appMock.BuildErr = errors.New("failed")
+	core := testCore(t, coreConfig)
+
+	if err := core.Build(); err == nil {
+		t.Fatal("should error")
+	}
+
+	uiMock.AssertMessageContains(t, "Full log: "+filepath.Join(coreConfig.LocalDir, "logs"))
+}
+
+func TestCoreCompile_logFileNotWritable(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.LogFiles = 1
+	uiMock := new(ui.Mock)
+	coreConfig.Ui = uiMock
+
+	// Make the log directory a file so it can't be created
+	if err := os.MkdirAll(coreConfig.LocalDir, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	path := filepath.Join(coreConfig.LocalDir, "logs")
+	if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	uiMock.AssertMessageContains(t, "using a temporary directory")
+}
@@ -0,0 +1,291 @@
+package otto
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/foundation"
+	"github.com/hashicorp/otto/infrastructure"
+	"github.com/hashicorp/otto/plugin"
+	pluginrpc "github.com/hashicorp/otto/rpc"
+	"github.com/hashicorp/otto/ui"
+)
+
+// PluginGlob is the pattern of the file names of plugin binaries.
+const PluginGlob = "otto-plugin-*"
+
+// pluginStartTimeout is how long a discovered plugin has to start before
+// it is ignored, so that a broken binary doesn't hang NewCore.
+const pluginStartTimeout = 10 * time.Second
+
+// PluginInfo describes a plugin binary that a Core loaded, listed in
+// PluginInventory.
+type PluginInfo struct {
+	// Path is the path of the plugin binary.
+	Path string
+
+	Apps        app.TupleSlice
+	Infras      []string
+	Foundations foundation.TupleSlice
+}
+
+// MarshalJSON encodes the tuples in their canonical string form, such as
+// "rails/aws/simple".
+func (i *PluginInfo) MarshalJSON() ([]byte, error) {
+	var result struct {
+		Path        string   `json:"path"`
+		Apps        []string `json:"apps,omitempty"`
+		Infras      []string `json:"infras,omitempty"`
+		Foundations []string `json:"foundations,omitempty"`
+	}
+	result.Path = i.Path
+	result.Infras = i.Infras
+	for _, t := range i.Apps {
+		result.Apps = append(result.Apps, t.String())
+	}
+	for _, t := range i.Foundations {
+		result.Foundations = append(result.Foundations, t.String())
+	}
+
+	return json.Marshal(&result)
+}
+
+// loadPlugins discovers the plugins of the configuration and merges them
+// into the implementations of the Core. A plugin that can't be loaded is
+// ignored with a warning.
+func (c *Core) loadPlugins(config *CoreConfig) {
+	c.pluginOrigins = make(map[interface{}]string, len(config.PluginOrigins))
+	for k, v := range config.PluginOrigins {
+		c.pluginOrigins[k] = v
+	}
+
+	if config.DisablePluginDiscovery {
+		return
+	}
+
+	paths, err := discoverPlugins(append(
+		[]string{filepath.Join(c.dataDir, "plugins")}, config.PluginPaths...))
+	if err != nil {
+		c.pluginWarn(fmt.Sprintf("Error discovering plugins: %s", err))
+	}
+	if len(paths) == 0 {
+		return
+	}
+
+	// Copy the maps since they belong to the CoreConfig
+	apps := make(map[app.Tuple]app.Factory, len(c.apps))
+	for k, v := range c.apps {
+		apps[k] = v
+	}
+	infras := make(map[string]infrastructure.Factory, len(c.infras))
+	for k, v := range c.infras {
+		infras[k] = v
+	}
+	foundations := make(map[foundation.Tuple]foundation.Factory, len(c.foundationMap))
+	for k, v := range c.foundationMap {
+		foundations[k] = v
+	}
+
+	// Everything that is set from here on is from a plugin, and later
+	// plugins take precedence over earlier ones.
+	fromPlugin := make(map[interface{}]struct{})
+	override := func(k interface{}, exists bool, path string) bool {
+		if _, ok := fromPlugin[k]; ok || !exists || config.PreferPlugins {
+			fromPlugin[k] = struct{}{}
+			c.pluginOrigins[k] = path
+			return true
+		}
+
+		return false
+	}
+
+	checksums := c.pluginChecksums()
+	defer checksums.Save()
+
+	for _, path := range paths {
+		if err := checksums.Verify(path, config.RequirePluginChecksums); err != nil {
+			c.pluginWarn(fmt.Sprintf(
+				"Refusing to run the plugin %s: %s", path, err))
+			continue
+		}
+
+		client, info, err := loadPlugin(path)
+		if err != nil {
+			c.pluginWarn(fmt.Sprintf(
+				"Error loading the plugin %s, it will be ignored: %s", path, err))
+			continue
+		}
+
+		c.logger.Info("loaded plugin", "path", path)
+		if w, _ := plugin.CheckAPIVersion(path, client.APIVersion()); w != "" {
+			c.logger.Warn(w)
+		}
+		c.plugins = append(c.plugins, client)
+		c.pluginInfos = append(c.pluginInfos, info)
+
+		rpcClient, err := client.Client()
+		if err != nil {
+			// This was already connected by loadPlugin
+			continue
+		}
+		for _, t := range info.Apps {
+			if _, ok := apps[t]; override(t, ok, path) {
+				apps[t] = rpcClient.App
+			}
+		}
+		for _, n := range info.Infras {
+			if _, ok := infras[n]; override(n, ok, path) {
+				infras[n] = rpcClient.Infra
+			}
+		}
+		for _, t := range info.Foundations {
+			if _, ok := foundations[t]; override(t, ok, path) {
+				foundations[t] = rpcClient.Foundation
+			}
+		}
+	}
+
+	c.apps = apps
+	c.infras = infras
+	c.foundationMap = foundations
+}
+
+// APIVersioned is implemented by in-process apps, infrastructures, and
+// foundations to declare the major version of the plugin API that they
+// were built against. Plugin processes declare it in the handshake
+// instead.
+type APIVersioned interface {
+	APIVersion() int
+}
+
+// checkAPIVersion checks that the implementation raw speaks the API
+// version of this Otto if it is APIVersioned. This is called when an
+// implementation is loaded, so that factories aren't called before
+// they're used. The implementations from plugin processes were checked
+// when they were started, and they aren't APIVersioned.
+func checkAPIVersion(raw interface{}, kind, name string) error {
+	v, ok := raw.(APIVersioned)
+	if !ok || v.APIVersion() == plugin.APIVersionMajor {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"%s %s speaks API v%d, this otto speaks v%d",
+		kind, name, v.APIVersion(), plugin.APIVersionMajor)
+}
+
+// pluginWarn warns about a plugin that can't be used. This happens while
+// the Core is created, so there may not be a Ui yet.
+func (c *Core) pluginWarn(msg string) {
+	c.logger.Warn(msg)
+	if c.ui != nil {
+		ui.Warn(c.ui, msg)
+	}
+}
+
+// discoverPlugins returns the plugin binaries in the given paths. Each
+// path is either a plugin binary or a directory of plugin binaries that
+// match PluginGlob. Paths that don't exist are skipped.
+func discoverPlugins(paths []string) ([]string, error) {
+	var result []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return result, err
+		}
+		if !info.IsDir() {
+			result = append(result, path)
+			continue
+		}
+
+		matches, err := plugin.Discover(PluginGlob, path)
+		if err != nil {
+			return result, err
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return result, err
+			}
+			if info.IsDir() || strings.HasSuffix(match, pluginChecksumExt) {
+				continue
+			}
+			if runtime.GOOS != "windows" && info.Mode().Perm()&0111 == 0 {
+				continue
+			}
+
+			result = append(result, match)
+		}
+	}
+
+	return result, nil
+}
+
+// loadPlugin starts the plugin binary at path and asks it what it serves.
+// The returned client must be closed to end the plugin process.
+func loadPlugin(path string) (*plugin.Client, *PluginInfo, error) {
+	client := plugin.NewClient(&plugin.ClientConfig{
+		Cmd:          exec.Command(path),
+		StartTimeout: pluginStartTimeout,
+		SyncStdout:   os.Stdout,
+		SyncStderr:   os.Stderr,
+	})
+
+	info, err := pluginInfo(client)
+	if err != nil {
+		client.Close()
+		return nil, nil, err
+	}
+	info.Path = path
+
+	return client, info, nil
+}
+
+func pluginInfo(client *plugin.Client) (*PluginInfo, error) {
+	rpcClient, err := client.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	// Plugins built for older versions of Otto only serve an app
+	meta, err := rpcClient.Meta()
+	if err != nil {
+		meta = &pluginrpc.ServerMeta{App: true}
+	}
+
+	result := &PluginInfo{
+		Infras:      meta.Infras,
+		Foundations: meta.Foundations,
+	}
+	if meta.App {
+		appImpl, err := rpcClient.App()
+		if err != nil {
+			return nil, err
+		}
+		defer maybeClose(appImpl)
+
+		appMeta, err := appImpl.Meta()
+		if err != nil {
+			return nil, err
+		}
+		if appMeta != nil {
+			result.Apps = appMeta.Tuples
+		}
+	}
+	if len(result.Apps) == 0 && len(result.Infras) == 0 && len(result.Foundations) == 0 {
+		return nil, fmt.Errorf("the plugin doesn't serve anything")
+	}
+
+	return result, nil
+}
@@ -0,0 +1,233 @@
+package otto
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/otto/helper/logger"
+)
+
+// pluginChecksumManifest is the name of the file that lists the checksums
+// of the plugins in a directory, and pluginChecksumExt is the extension
+// of the file with the checksum of a single plugin.
+const (
+	pluginChecksumManifest = "plugins.sha256"
+	pluginChecksumExt      = ".sha256"
+)
+
+// pluginChecksums verifies the checksums of plugins. Hashes are cached by
+// the path, size, and modification time of the plugin so that large
+// binaries aren't hashed by every operation.
+type pluginChecksums struct {
+	Path   string
+	Logger logger.Logger
+
+	cache   map[string]*pluginChecksumEntry
+	changed bool
+}
+
+// pluginChecksumEntry is the cached hash of a single plugin binary.
+type pluginChecksumEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	SHA256  string    `json:"sha256"`
+}
+
+// pluginChecksums returns the checksums of plugins with the cache in the
+// data directory. A cache that can't be read is ignored.
+func (c *Core) pluginChecksums() *pluginChecksums {
+	result := &pluginChecksums{
+		Path:   filepath.Join(c.dataDir, "plugin-checksums.json"),
+		Logger: c.logger,
+		cache:  make(map[string]*pluginChecksumEntry),
+	}
+
+	data, err := ioutil.ReadFile(result.Path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			c.logger.Warn("error reading plugin checksum cache", "err", err)
+		}
+
+		return result
+	}
+	if err := json.Unmarshal(data, &result.cache); err != nil {
+		c.logger.Warn("ignoring corrupt plugin checksum cache", "err", err)
+		result.cache = make(map[string]*pluginChecksumEntry)
+	}
+
+	return result
+}
+
+// Verify checks the plugin at path against its checksum. If the plugin
+// has no checksum, this is an error only if require is true.
+func (s *pluginChecksums) Verify(path string, require bool) error {
+	expected, err := pluginExpectedChecksum(path)
+	if err != nil {
+		return fmt.Errorf("Error reading the checksum: %s", err)
+	}
+	if expected == "" {
+		if require {
+			return fmt.Errorf(
+				"no checksum for the plugin in %s%s or %s, and plugin\n"+
+					"checksums are required",
+				filepath.Base(path), pluginChecksumExt, pluginChecksumManifest)
+		}
+
+		return nil
+	}
+
+	actual, err := s.hash(path)
+	if err != nil {
+		return fmt.Errorf("Error hashing the plugin: %s", err)
+	}
+	if actual != expected {
+		return fmt.Errorf(
+			"checksum mismatch for %s: expected %s, got %s", path, expected, actual)
+	}
+
+	return nil
+}
+
+// Save writes the cache if it changed. Errors are only logged since the
+// cache is just an optimization.
+func (s *pluginChecksums) Save() {
+	if !s.changed {
+		return
+	}
+
+	data, err := json.MarshalIndent(s.cache, "", "    ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(s.Path), 0755)
+	}
+	if err == nil {
+		// Write to a temporary file first so that concurrent processes
+		// never read a partial cache.
+		tempPath := s.Path + ".tmp"
+		err = ioutil.WriteFile(tempPath, data, 0644)
+		if err == nil {
+			err = os.Rename(tempPath, s.Path)
+		}
+	}
+	if err != nil {
+		s.Logger.Warn("error saving plugin checksum cache", "err", err)
+		return
+	}
+
+	s.changed = false
+}
+
+// hash returns the hex-encoded SHA-256 of the file at path, from the
+// cache if the file hasn't changed.
+func (s *pluginChecksums) hash(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if e, ok := s.cache[path]; ok &&
+		e.Size == info.Size() && e.ModTime.Equal(info.ModTime()) {
+		return e.SHA256, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	result := hex.EncodeToString(h.Sum(nil))
+	s.cache[path] = &pluginChecksumEntry{
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		SHA256:  result,
+	}
+	s.changed = true
+
+	return result, nil
+}
+
+// pluginExpectedChecksum returns the checksum that the plugin at path
+// must have, or "" if it has none. The checksum file of the plugin takes
+// precedence over the manifest of its directory.
+func pluginExpectedChecksum(path string) (string, error) {
+	name := filepath.Base(path)
+	single, err := readChecksumFile(path + pluginChecksumExt)
+	if err != nil {
+		return "", err
+	}
+	if len(single) > 0 {
+		if sum, ok := single[name]; ok {
+			return sum, nil
+		}
+		if sum, ok := single[""]; ok {
+			return sum, nil
+		}
+
+		return "", fmt.Errorf(
+			"%s%s doesn't have a checksum for %s", name, pluginChecksumExt, name)
+	}
+
+	manifest, err := readChecksumFile(
+		filepath.Join(filepath.Dir(path), pluginChecksumManifest))
+	if err != nil {
+		return "", err
+	}
+
+	return manifest[name], nil
+}
+
+// readChecksumFile reads a file in the format of sha256sum and returns
+// the checksums by file name. A line with only a checksum has the name
+// "". A file that doesn't exist has no checksums.
+func readChecksumFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+	defer f.Close()
+
+	result := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		sum := strings.ToLower(fields[0])
+		if _, err := hex.DecodeString(sum); err != nil || len(sum) != sha256.Size*2 {
+			return nil, fmt.Errorf("invalid checksum in %s: %s", path, fields[0])
+		}
+
+		// A "*" before the name marks binary mode in sha256sum
+		name := ""
+		if len(fields) > 1 {
+			name = strings.TrimPrefix(strings.Join(fields[1:], " "), "*")
+		}
+		result[name] = sum
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
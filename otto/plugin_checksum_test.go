@@ -0,0 +1,149 @@
+package otto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/otto/helper/logger"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestPluginChecksums(t *testing.T) {
+	td, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "otto-plugin-foo")
+	testWriteFile(t, path, "plugin")
+	sum := testSHA256("plugin")
+	checksums := &pluginChecksums{
+		Path:   filepath.Join(td, "cache.json"),
+		Logger: logger.Default(),
+		cache:  make(map[string]*pluginChecksumEntry),
+	}
+
+	// No checksum is only an error if it is required
+	if err := checksums.Verify(path, false); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := checksums.Verify(path, true); err == nil {
+		t.Fatal("should error")
+	}
+
+	// The manifest of the directory
+	testWriteFile(t, filepath.Join(td, "plugins.sha256"), fmt.Sprintf(
+		"%s  otto-plugin-bar\n%s *otto-plugin-foo\n", testSHA256("bar"), sum))
+	if err := checksums.Verify(path, true); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The checksum file of the plugin takes precedence
+	testWriteFile(t, path+".sha256", testSHA256("other")+"\n")
+	err = checksums.Verify(path, true)
+	if err == nil {
+		t.Fatal("should error")
+	}
+	for _, v := range []string{path, testSHA256("other"), sum} {
+		if !strings.Contains(err.Error(), v) {
+			t.Fatalf("bad: %s", err)
+		}
+	}
+}
+
+func TestPluginChecksums_cache(t *testing.T) {
+	td, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "otto-plugin-foo")
+	testWriteFile(t, path, "plugin")
+	testWriteFile(t, path+".sha256", testSHA256("plugin"))
+
+	core := &Core{dataDir: td, logger: logger.Default()}
+	checksums := core.pluginChecksums()
+	if err := checksums.Verify(path, true); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	checksums.Save()
+
+	// The saved hash is used while the file is unchanged, which this
+	// proves by corrupting it.
+	checksums = core.pluginChecksums()
+	checksums.cache[path].SHA256 = testSHA256("cached")
+	if err := checksums.Verify(path, true); err == nil {
+		t.Fatal("should use the cache")
+	}
+
+	// A changed file is hashed again
+	testWriteFile(t, path, "plugin v2")
+	testWriteFile(t, path+".sha256", testSHA256("plugin v2"))
+	if err := checksums.Verify(path, true); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestNewCore_pluginChecksumMismatch(t *testing.T) {
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	path := testPluginBinary(t, filepath.Join(coreConfig.DataDir, "plugins"), "infra")
+	testWriteFile(t, path+".sha256", testSHA256("something else"))
+
+	core := testCore(t, coreConfig)
+	defer core.Close()
+
+	uiMock.AssertMessageContains(t, "checksum mismatch for "+path)
+	if plugins := testPlugins(t, core); len(plugins) != 0 {
+		t.Fatalf("bad: %#v", plugins)
+	}
+	if _, ok := core.infras["test-plugin"]; ok {
+		t.Fatalf("bad: %#v", core.infras)
+	}
+}
+
+func TestNewCore_pluginChecksumRequired(t *testing.T) {
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	coreConfig.RequirePluginChecksums = true
+	dir := filepath.Join(coreConfig.DataDir, "plugins")
+	path := testPluginBinary(t, dir, "infra")
+
+	core := testCore(t, coreConfig)
+	defer core.Close()
+	uiMock.AssertMessageContains(t, "checksums are required")
+	if plugins := testPlugins(t, core); len(plugins) != 0 {
+		t.Fatalf("bad: %#v", plugins)
+	}
+
+	// With the right checksum in the manifest the plugin runs
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testWriteFile(t, filepath.Join(dir, "plugins.sha256"), fmt.Sprintf(
+		"%s  %s\n", testSHA256(string(data)), filepath.Base(path)))
+
+	core = testCore(t, coreConfig)
+	defer core.Close()
+	if plugins := testPlugins(t, core); len(plugins) != 1 {
+		t.Fatalf("bad: %#v", plugins)
+	}
+}
+
+func testSHA256(v string) string {
+	sum := sha256.Sum256([]byte(v))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,172 @@
+package otto
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/otto/ui"
+)
+
+// PluginOriginBuiltin is the origin of implementations that are built in
+// to Otto rather than loaded from a plugin binary.
+const PluginOriginBuiltin = "builtin"
+
+// Versioned is implemented by apps, infrastructures, and foundations that
+// know their own version, which is shown by the PluginInventory.
+type Versioned interface {
+	PluginVersion() string
+}
+
+// PluginInventory lists the implementations that a Core supports, from
+// Core.Plugins.
+type PluginInventory struct {
+	Apps        []*PluginImpl `json:"apps"`
+	Infras      []*PluginImpl `json:"infras"`
+	Foundations []*PluginImpl `json:"foundations"`
+	Tasks       []*PluginImpl `json:"tasks"`
+
+	// Plugins are the plugin binaries that the Core loaded.
+	Plugins []*PluginInfo `json:"plugins,omitempty"`
+}
+
+// PluginImpl is a single implementation in a PluginInventory.
+type PluginImpl struct {
+	// Name is the app or foundation tuple, infrastructure type, or task.
+	Name string `json:"name"`
+
+	// Origin is PluginOriginBuiltin or the path of the plugin binary
+	// that implements this.
+	Origin string `json:"origin"`
+
+	// Version is only set by PluginInventory.LoadVersions, and only if
+	// the implementation implements Versioned.
+	Version string `json:"version,omitempty"`
+
+	factory func() (interface{}, error)
+}
+
+// executeTaskNames are the names of the tasks that Core.Execute runs.
+// These are always run by the app, so they're built in.
+var executeTaskNames = map[ExecuteTask]string{
+	ExecuteTaskDev:     "dev",
+	ExecuteTaskConsole: "console",
+}
+
+// Plugins returns the inventory of the app types, infrastructure types,
+// foundations, and tasks that this Core supports and where they come
+// from. The implementations aren't instantiated for this, so versions are
+// only known after calling LoadVersions on the result.
+func (c *Core) Plugins() (*PluginInventory, error) {
+	result := &PluginInventory{Plugins: c.pluginInfos}
+	for t, f := range c.apps {
+		f := f
+		result.Apps = append(result.Apps, &PluginImpl{
+			Name:    t.String(),
+			Origin:  c.pluginOrigin(t),
+			factory: func() (interface{}, error) { return f() },
+		})
+	}
+	for n, f := range c.infras {
+		f := f
+		result.Infras = append(result.Infras, &PluginImpl{
+			Name:    n,
+			Origin:  c.pluginOrigin(n),
+			factory: func() (interface{}, error) { return f() },
+		})
+	}
+	for t, f := range c.foundationMap {
+		f := f
+		result.Foundations = append(result.Foundations, &PluginImpl{
+			Name:    t.String(),
+			Origin:  c.pluginOrigin(t),
+			factory: func() (interface{}, error) { return f() },
+		})
+	}
+	for _, n := range executeTaskNames {
+		result.Tasks = append(result.Tasks, &PluginImpl{
+			Name:   n,
+			Origin: PluginOriginBuiltin,
+		})
+	}
+
+	for _, impls := range result.kinds() {
+		sort.Sort(pluginImplSlice(impls))
+	}
+
+	return result, nil
+}
+
+// pluginOrigin returns the origin of the implementation with the key k,
+// which is the key of its factory.
+func (c *Core) pluginOrigin(k interface{}) string {
+	if v := c.pluginOrigins[k]; v != "" {
+		return v
+	}
+
+	return PluginOriginBuiltin
+}
+
+// LoadVersions sets the versions of the implementations in the inventory.
+// This instantiates every implementation, which starts a plugin process
+// for each one that comes from a plugin, so it isn't done by Core.Plugins.
+//
+// Implementations that can't be instantiated have no version, and the
+// errors are returned together once every implementation was tried.
+func (i *PluginInventory) LoadVersions() error {
+	var result error
+	for _, impls := range i.kinds() {
+		for _, impl := range impls {
+			if impl.factory == nil {
+				continue
+			}
+
+			raw, err := impl.factory()
+			if err != nil {
+				result = multierror.Append(result, fmt.Errorf(
+					"Error loading %s: %s", impl.Name, err))
+				continue
+			}
+
+			if v, ok := raw.(Versioned); ok {
+				impl.Version = v.PluginVersion()
+			}
+			maybeClose(raw)
+		}
+	}
+
+	return result
+}
+
+// Table returns the inventory as a table to show to the user.
+func (i *PluginInventory) Table() *ui.Table {
+	table := &ui.Table{
+		Headers:  []string{"KIND", "NAME", "ORIGIN", "VERSION"},
+		MaxWidth: ui.TerminalWidth(),
+	}
+
+	kinds := []string{"app", "infra", "foundation", "task"}
+	for idx, impls := range i.kinds() {
+		for _, impl := range impls {
+			version := impl.Version
+			if version == "" {
+				version = "-"
+			}
+
+			table.AddRow(kinds[idx], impl.Name, impl.Origin, version)
+		}
+	}
+
+	return table
+}
+
+func (i *PluginInventory) kinds() [][]*PluginImpl {
+	return [][]*PluginImpl{i.Apps, i.Infras, i.Foundations, i.Tasks}
+}
+
+// pluginImplSlice is a sort.Interface for sorting implementations by name.
+type pluginImplSlice []*PluginImpl
+
+func (s pluginImplSlice) Len() int           { return len(s) }
+func (s pluginImplSlice) Less(i, j int) bool { return s[i].Name < s[j].Name }
+func (s pluginImplSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,138 @@
+package otto
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/foundation"
+	"github.com/hashicorp/otto/infrastructure"
+)
+
+func TestCorePlugins(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Apps[TestAppTuple] = func() (app.App, error) {
+		return &testVersionedApp{Mock: new(app.Mock), version: "1.0.0"}, nil
+	}
+	coreConfig.PluginOrigins = map[interface{}]string{TestAppTuple: "/bin/otto-app"}
+
+	// Factories aren't called unless the versions are loaded
+	var called bool
+	coreConfig.Infrastructures["broken"] = func() (infrastructure.Infrastructure, error) {
+		called = true
+		return nil, errors.New("broken")
+	}
+
+	core := testCore(t, coreConfig)
+	defer core.Close()
+
+	// NewCore creates the in-process implementations to check their API
+	// versions, but the inventory doesn't create anything.
+	called = false
+	inventory, err := core.Plugins()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if called {
+		t.Fatal("factory should not be called")
+	}
+	if len(inventory.Apps) != 1 || inventory.Apps[0].Origin != "/bin/otto-app" {
+		t.Fatalf("bad: %#v", inventory.Apps)
+	}
+	if inventory.Apps[0].Version != "" {
+		t.Fatalf("bad: %#v", inventory.Apps[0])
+	}
+	if len(inventory.Infras) != 2 ||
+		inventory.Infras[0].Name != "broken" ||
+		inventory.Infras[1].Origin != PluginOriginBuiltin {
+		t.Fatalf("bad: %#v", inventory.Infras)
+	}
+	if len(inventory.Tasks) != 2 ||
+		inventory.Tasks[0].Name != "console" ||
+		inventory.Tasks[1].Name != "dev" {
+		t.Fatalf("bad: %#v", inventory.Tasks)
+	}
+
+	// Loading the versions tries everything and reports what failed
+	err = inventory.LoadVersions()
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Fatalf("err: %v", err)
+	}
+	if inventory.Apps[0].Version != "1.0.0" {
+		t.Fatalf("bad: %#v", inventory.Apps[0])
+	}
+
+	table := inventory.Table()
+	table.MaxWidth = 0
+	actual := table.String()
+	if !strings.Contains(actual, "/bin/otto-app") || !strings.Contains(actual, "1.0.0") {
+		t.Fatalf("bad: %s", actual)
+	}
+}
+
+func TestCorePlugins_discovered(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	path := testPluginBinary(t, filepath.Join(coreConfig.DataDir, "plugins"), "infra")
+
+	core := testCore(t, coreConfig)
+	defer core.Close()
+
+	inventory, err := core.Plugins()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var origin string
+	for _, impl := range inventory.Infras {
+		if impl.Name == "test-plugin" {
+			origin = impl.Origin
+		}
+	}
+	if origin != path {
+		t.Fatalf("bad: %#v", inventory.Infras)
+	}
+	if len(inventory.Foundations) != 1 || inventory.Foundations[0].Origin != path {
+		t.Fatalf("bad: %#v", inventory.Foundations)
+	}
+}
+
+func TestPluginInfo_json(t *testing.T) {
+	info := &PluginInfo{
+		Path:        "/bin/otto-plugin-foo",
+		Apps:        app.TupleSlice{{App: "rails", Infra: "aws", InfraFlavor: "*"}},
+		Foundations: foundation.TupleSlice{{Type: "consul", Infra: "aws", InfraFlavor: "vpc"}},
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := `{"path":"/bin/otto-plugin-foo","apps":["rails/aws/*"],"foundations":["consul/aws/vpc"]}`
+	if string(data) != expected {
+		t.Fatalf("bad: %s", data)
+	}
+}
+
+// testPlugins returns the plugin binaries that the core loaded.
+func testPlugins(t *testing.T, core *Core) []*PluginInfo {
+	inventory, err := core.Plugins()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return inventory.Plugins
+}
+
+type testVersionedApp struct {
+	*app.Mock
+
+	version string
+}
+
+func (a *testVersionedApp) PluginVersion() string {
+	return a.version
+}
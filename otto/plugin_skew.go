@@ -0,0 +1,75 @@
+package otto
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/ui"
+)
+
+// pluginVersion returns the version of the implementation raw, or "" if
+// it doesn't report one.
+func pluginVersion(raw interface{}) string {
+	if v, ok := raw.(Versioned); ok {
+		return v.PluginVersion()
+	}
+
+	return ""
+}
+
+// checkPluginSkew compares the version of the app that compiled the files
+// of ctx with the version of impl, which is about to use them. A newer
+// minor version should still understand them, so that is a warning. Files
+// compiled with another major version are an error unless skew is allowed.
+//
+// Nothing is checked if either version is unknown.
+func (c *Core) checkPluginSkew(impl app.App, ctx *app.Context) error {
+	result := ctx.CompileResult
+	if result == nil || result.PluginVersion == "" {
+		return nil
+	}
+	current := pluginVersion(impl)
+	if current == "" || current == result.PluginVersion {
+		return nil
+	}
+
+	compiledV, err := version.NewVersion(result.PluginVersion)
+	if err != nil {
+		c.logger.Warn("invalid compiled plugin version",
+			"plugin", result.Plugin, "version", result.PluginVersion)
+		return nil
+	}
+	currentV, err := version.NewVersion(current)
+	if err != nil {
+		c.logger.Warn("invalid plugin version",
+			"plugin", result.Plugin, "version", current)
+		return nil
+	}
+
+	name := ctx.Appfile.Application.Name
+	if compiledV.Segments()[0] == currentV.Segments()[0] {
+		ui.Warn(c.ui, fmt.Sprintf(
+			"The app '%s' was compiled with version %s of the %s plugin,\n"+
+				"but version %s is loaded. It should work, but recompiling\n"+
+				"with `otto compile` is recommended.",
+			name, result.PluginVersion, result.Plugin, current))
+		return nil
+	}
+
+	err = fmt.Errorf(
+		"The app '%s' was compiled with version %s of the %s plugin,\n"+
+			"but version %s is loaded, which may not understand the compiled files.",
+		name, result.PluginVersion, result.Plugin, current)
+	if c.allowSkew {
+		ui.Warn(c.ui, err.Error())
+		return nil
+	}
+
+	return &HintedError{
+		Err: err,
+		Hint: "Run `otto compile` to recompile with the current plugin, or set\n" +
+			"OTTO_ALLOW_PLUGIN_SKEW=1 to use the compiled files anyway.",
+		DocKey: "compile",
+	}
+}
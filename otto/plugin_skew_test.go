@@ -0,0 +1,78 @@
+package otto
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreCompile_pluginVersion(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	appImpl := &testVersionedApp{Mock: new(app.Mock), version: "1.3.0"}
+	appImpl.CompileResult = new(app.CompileResult)
+	coreConfig.Apps[TestAppTuple] = func() (app.App, error) {
+		return appImpl, nil
+	}
+	core := testCore(t, coreConfig)
+
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	md, err := core.CompileMetadata()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if md.App.Plugin != TestAppTuple.String() || md.App.PluginVersion != "1.3.0" {
+		t.Fatalf("bad: %#v", md.App)
+	}
+}
+
+func TestCoreDev_pluginSkew(t *testing.T) {
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	appImpl := &testVersionedApp{Mock: new(app.Mock), version: "1.3.0"}
+	appImpl.CompileResult = new(app.CompileResult)
+	coreConfig.Apps[TestAppTuple] = func() (app.App, error) {
+		return appImpl, nil
+	}
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// A minor version skew is a warning
+	appImpl.version = "1.4.0"
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, "version 1.4.0 is loaded")
+
+	// A major version skew is an error
+	appImpl.version = "2.0.0"
+	appImpl.DevCalled = false
+	err := core.Dev()
+	if err == nil || !strings.Contains(err.Error(), "version 2.0.0 is loaded") {
+		t.Fatalf("err: %v", err)
+	}
+	if !strings.Contains(ErrorHint(err), "otto compile") {
+		t.Fatalf("bad: %s", ErrorHint(err))
+	}
+	if appImpl.DevCalled {
+		t.Fatal("dev should not be called")
+	}
+
+	// Unless it is allowed
+	coreConfig.AllowPluginSkew = true
+	core = testCore(t, coreConfig)
+	if err := core.Dev(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appImpl.DevCalled {
+		t.Fatal("dev should be called")
+	}
+}
@@ -0,0 +1,274 @@
+package otto
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/foundation"
+	"github.com/hashicorp/otto/helper/logger"
+	"github.com/hashicorp/otto/infrastructure"
+	"github.com/hashicorp/otto/plugin"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestNewCore_plugins(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	path := testPluginBinary(t, filepath.Join(coreConfig.DataDir, "plugins"), "infra")
+
+	// Files that aren't plugins are ignored
+	other := filepath.Join(coreConfig.DataDir, "plugins", "otto-plugin-notes.txt")
+	if err := ioutil.WriteFile(other, []byte("hello"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	core := testCore(t, coreConfig)
+	defer core.Close()
+
+	plugins := testPlugins(t, core)
+	if len(plugins) != 1 || plugins[0].Path != path {
+		t.Fatalf("bad: %#v", plugins)
+	}
+	if len(plugins[0].Infras) != 1 || plugins[0].Infras[0] != "test-plugin" {
+		t.Fatalf("bad: %#v", plugins[0])
+	}
+
+	f := core.infras["test-plugin"]
+	if f == nil {
+		t.Fatalf("bad: %#v", core.infras)
+	}
+	infra, err := f()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer maybeClose(infra)
+	if v := infra.Flavors(); len(v) != 1 || v[0] != "plugin" {
+		t.Fatalf("bad: %#v", v)
+	}
+	if core.foundationMap[testPluginFoundation] == nil {
+		t.Fatalf("bad: %#v", core.foundationMap)
+	}
+
+	// The maps of the configuration are left alone
+	if _, ok := coreConfig.Infrastructures["test-plugin"]; ok {
+		t.Fatalf("bad: %#v", coreConfig.Infrastructures)
+	}
+}
+
+func TestNewCore_pluginPrecedence(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	testPluginBinary(t, filepath.Join(coreConfig.DataDir, "plugins"), "infra")
+	coreConfig.Infrastructures["test-plugin"] = func() (infrastructure.Infrastructure, error) {
+		return &infrastructure.Mock{FlavorsResult: []string{"builtin"}}, nil
+	}
+
+	// The configuration takes precedence by default
+	core := testCore(t, coreConfig)
+	defer core.Close()
+	if v := testPluginFlavors(t, core); v != "builtin" {
+		t.Fatalf("bad: %s", v)
+	}
+
+	coreConfig.PreferPlugins = true
+	core = testCore(t, coreConfig)
+	defer core.Close()
+	if v := testPluginFlavors(t, core); v != "plugin" {
+		t.Fatalf("bad: %s", v)
+	}
+}
+
+func TestNewCore_pluginInvalid(t *testing.T) {
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	path := testPluginBinary(t, filepath.Join(coreConfig.DataDir, "extra"), "invalid")
+	coreConfig.PluginPaths = []string{path}
+
+	core := testCore(t, coreConfig)
+	defer core.Close()
+
+	uiMock.AssertMessageContains(t, path)
+	if plugins := testPlugins(t, core); len(plugins) != 0 {
+		t.Fatalf("bad: %#v", plugins)
+	}
+}
+
+func TestNewCore_pluginDiscoveryDisabled(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	testPluginBinary(t, filepath.Join(coreConfig.DataDir, "plugins"), "infra")
+	coreConfig.DisablePluginDiscovery = true
+
+	core := testCore(t, coreConfig)
+	defer core.Close()
+	if plugins := testPlugins(t, core); len(plugins) != 0 {
+		t.Fatalf("bad: %#v", plugins)
+	}
+	if _, ok := core.infras["test-plugin"]; ok {
+		t.Fatalf("bad: %#v", core.infras)
+	}
+}
+
+func TestNewCore_pluginAPIVersion(t *testing.T) {
+	var logs bytes.Buffer
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	coreConfig.Logger = logger.New(&logs)
+	dir := filepath.Join(coreConfig.DataDir, "plugins")
+	oldPath := testPluginBinary(t, dir, "old-api")
+	newPath := testPluginBinary(t, dir, "new-api")
+
+	core := testCore(t, coreConfig)
+	defer core.Close()
+
+	// A newer major version can't be used
+	plugins := testPlugins(t, core)
+	if len(plugins) != 1 || plugins[0].Path != oldPath {
+		t.Fatalf("bad: %#v", plugins)
+	}
+	uiMock.AssertMessageContains(t, fmt.Sprintf(
+		"Error loading the plugin %s, it will be ignored: plugin otto-plugin-new-api "+
+			"speaks API v%d, this otto speaks v%d",
+		newPath, plugin.APIVersionMajor+1, plugin.APIVersionMajor))
+
+	// An older minor version is only a warning
+	expected := fmt.Sprintf("plugin %s speaks API v%d.0, this otto speaks v%s",
+		oldPath, plugin.APIVersionMajor, plugin.APIVersion)
+	if !strings.Contains(logs.String(), expected) {
+		t.Fatalf("bad: %s", logs.String())
+	}
+}
+
+func TestCoreApp_apiVersioned(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Apps[TestAppTuple] = func() (app.App, error) {
+		return &testAPIVersionedApp{Mock: new(app.Mock), version: plugin.APIVersionMajor}, nil
+	}
+	core := testCore(t, coreConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	core.Close()
+
+	// The factory isn't called until the app is used
+	called := false
+	coreConfig.Apps[TestAppTuple] = func() (app.App, error) {
+		called = true
+		return &testAPIVersionedApp{Mock: new(app.Mock), version: plugin.APIVersionMajor + 1}, nil
+	}
+	core = testCore(t, coreConfig)
+	if called {
+		t.Fatal("factory should not be called")
+	}
+
+	err := core.Compile()
+	if err == nil {
+		t.Fatal("should error")
+	}
+	expected := fmt.Sprintf("app %s speaks API v%d, this otto speaks v%d",
+		TestAppTuple, plugin.APIVersionMajor+1, plugin.APIVersionMajor)
+	if !strings.Contains(err.Error(), expected) {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+// This is not a real test. This is the plugin that testPluginBinary
+// runs.
+func TestPluginHelperProcess(*testing.T) {
+	kind := os.Getenv("OTTO_TEST_PLUGIN")
+	if kind == "" {
+		return
+	}
+
+	defer os.Exit(0)
+
+	switch kind {
+	case "infra", "old-api", "new-api":
+		// The skewed API versions are what plugins built for other
+		// versions of Otto output.
+		if kind == "old-api" {
+			plugin.APIVersion = fmt.Sprintf("%d.0", plugin.APIVersionMajor)
+		}
+		if kind == "new-api" {
+			plugin.APIVersion = fmt.Sprintf("%d.0", plugin.APIVersionMajor+1)
+		}
+
+		plugin.Serve(&plugin.ServeOpts{
+			InfraFunc: func() infrastructure.Infrastructure {
+				return &infrastructure.Mock{FlavorsResult: []string{"plugin"}}
+			},
+			Infras: []string{"test-plugin"},
+			FoundationFunc: func() foundation.Foundation {
+				return new(foundation.Mock)
+			},
+			Foundations: foundation.TupleSlice{testPluginFoundation},
+		})
+	case "invalid":
+		fmt.Println("not a plugin")
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown plugin: %q\n", kind)
+		os.Exit(2)
+	}
+}
+
+var testPluginFoundation = foundation.Tuple{
+	Type: "test-plugin", Infra: "test-plugin", InfraFlavor: "*"}
+
+// testPluginBinary writes a plugin binary to dir that runs the given kind
+// of plugin from TestPluginHelperProcess.
+func testPluginBinary(t *testing.T, dir, kind string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin binaries are shell scripts")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	path := filepath.Join(dir, "otto-plugin-"+kind)
+	script := fmt.Sprintf(
+		"#!/bin/sh\nOTTO_TEST_PLUGIN=%s exec %q -test.run=^TestPluginHelperProcess$\n",
+		kind, os.Args[0])
+	if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return path
+}
+
+type testAPIVersionedApp struct {
+	*app.Mock
+
+	version int
+}
+
+func (a *testAPIVersionedApp) APIVersion() int {
+	return a.version
+}
+
+func testPluginFlavors(t *testing.T, core *Core) string {
+	infra, err := core.infras["test-plugin"]()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer maybeClose(infra)
+
+	v := infra.Flavors()
+	if len(v) != 1 {
+		t.Fatalf("bad: %#v", v)
+	}
+
+	return v[0]
+}
@@ -0,0 +1,265 @@
+package otto
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// projectLockWait is how long an operation waits for the project lock
+// held by another operation before it fails, and projectLockRetryInterval
+// is how often it checks the lock while waiting.
+var (
+	projectLockWait          = 5 * time.Second
+	projectLockRetryInterval = 250 * time.Millisecond
+)
+
+// projectLockOwner is an owner of the project lock.
+type projectLockOwner struct {
+	ID        string
+	Pid       int
+	Host      string
+	Operation string
+	Exclusive bool
+	StartedAt time.Time
+}
+
+// projectLockState is the content of the owner file of the project lock.
+type projectLockState struct {
+	Owners []*projectLockOwner
+}
+
+// alive returns true if the process that owns the lock may still be
+// running. Processes on other hosts, such as with a local directory on a
+// network share, can't be checked and are assumed to be running.
+func (o *projectLockOwner) alive() bool {
+	if host, err := os.Hostname(); err != nil || host != o.Host {
+		return true
+	}
+
+	return processAlive(o.Pid)
+}
+
+// lockProject takes the lock of the local directory of the project for
+// the operation op, so that operations in other processes don't change
+// the local data and the compilation while it is used, such as a compile
+// in one terminal while a dev runs in another. The returned function
+// releases the lock. Operations that only read the local data, like
+// Status, don't take it.
+//
+// Within the Core, operations share a shared lock like those of other
+// processes do. While an operation holds the lock exclusively, the lock
+// can only be taken by the operations it runs itself, which take it with
+// lockProjectNested. A shared lock can't be taken exclusively while it is
+// held, not even by a nested operation.
+//
+// Compile takes the lock exclusively since it deletes the compilation,
+// and the operations that use the compilation share it, so a compile
+// can't run while any of them run and vice versa.
+//
+// While another operation holds the lock, lockProject waits for it for a
+// short time and then fails with an error naming the operation. Owners
+// whose process is gone are stale, and are removed.
+//
+// The lock is the owner file, which has the processes and the operations
+// that hold the lock. The file is only read and written while holding a
+// lockFile lock on a guard file, which is held briefly so that it works
+// the same with the file locks of every platform.
+func (c *Core) lockProject(op string, exclusive bool) (func(), error) {
+	return c.takeProjectLock(op, exclusive, false)
+}
+
+// lockProjectNested is lockProject for the operation op that runs within
+// an operation of this Core that holds the project lock, and on its
+// goroutine. If the lock isn't held, this is the same as lockProject.
+func (c *Core) lockProjectNested(op string, exclusive bool) (func(), error) {
+	return c.takeProjectLock(op, exclusive, true)
+}
+
+// takeProjectLock takes the project lock for lockProject and
+// lockProjectNested.
+func (c *Core) takeProjectLock(op string, exclusive, nested bool) (func(), error) {
+	c.projectLock.Lock()
+	defer c.projectLock.Unlock()
+
+	if c.projectLockDepth > 0 {
+		held := c.projectLockOwner
+
+		// Upgrading the lock could deadlock with another process that
+		// shares it and waits to upgrade too.
+		if exclusive && !held.Exclusive {
+			return nil, fmt.Errorf(
+				"The %s operation can't run while the %s operation of this\n"+
+					"Otto runs, since it needs the project to itself.",
+				op, held.Operation)
+		}
+
+		if held.Exclusive && !nested {
+			return nil, fmt.Errorf(
+				"The %s operation can't run while the %s operation of this\n"+
+					"Otto runs, since that one needs the project to itself.",
+				op, held.Operation)
+		}
+
+		c.projectLockDepth++
+		return c.unlockProject, nil
+	}
+
+	if err := os.MkdirAll(c.localDir, 0755); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(projectLockWait)
+	for {
+		owner, err := c.tryLockProject(op, exclusive)
+		if err != nil {
+			return nil, fmt.Errorf("Error locking the project: %s", err)
+		}
+		if owner == nil {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf(
+				"Another otto operation (%s, pid %d) is running in this project\n"+
+					"since %s. Wait for it to finish and try again.",
+				owner.Operation, owner.Pid,
+				owner.StartedAt.Local().Format(time.RFC1123))
+		}
+
+		time.Sleep(projectLockRetryInterval)
+	}
+
+	c.projectLockDepth = 1
+	return c.unlockProject, nil
+}
+
+// tryLockProject takes the project lock for the operation op if nothing
+// conflicting holds it, and otherwise returns the conflicting owner.
+func (c *Core) tryLockProject(op string, exclusive bool) (*projectLockOwner, error) {
+	guard, err := lockFile(c.projectLockPath()+".guard", true, true)
+	if err != nil {
+		return nil, err
+	}
+	defer guard.Close()
+
+	state, err := readProjectLock(c.projectLockPath())
+	if err != nil {
+		return nil, err
+	}
+
+	owners := state.Owners[:0]
+	for _, o := range state.Owners {
+		if !o.alive() {
+			c.logger.Warn("removing stale project lock owner",
+				"pid", o.Pid, "operation", o.Operation)
+			continue
+		}
+
+		owners = append(owners, o)
+	}
+	state.Owners = owners
+	for _, o := range state.Owners {
+		if exclusive || o.Exclusive {
+			return o, nil
+		}
+	}
+
+	host, _ := os.Hostname()
+	now := time.Now().UTC()
+	c.projectLockOwner = &projectLockOwner{
+		ID:        fmt.Sprintf("%d-%d", os.Getpid(), now.UnixNano()),
+		Pid:       os.Getpid(),
+		Host:      host,
+		Operation: op,
+		Exclusive: exclusive,
+		StartedAt: now,
+	}
+	state.Owners = append(state.Owners, c.projectLockOwner)
+
+	return nil, writeProjectLock(c.projectLockPath(), state)
+}
+
+// unlockProject releases the project lock taken by lockProject.
+func (c *Core) unlockProject() {
+	c.projectLock.Lock()
+	defer c.projectLock.Unlock()
+
+	c.projectLockDepth--
+	if c.projectLockDepth > 0 {
+		return
+	}
+
+	guard, err := lockFile(c.projectLockPath()+".guard", true, true)
+	if err != nil {
+		c.logger.Warn("error unlocking the project", "err", err)
+		return
+	}
+	defer guard.Close()
+
+	state, err := readProjectLock(c.projectLockPath())
+	if err != nil {
+		c.logger.Warn("error unlocking the project", "err", err)
+		return
+	}
+
+	// Our owner may have been removed if it was thought to be stale
+	owners := state.Owners[:0]
+	for _, o := range state.Owners {
+		if o.ID != c.projectLockOwner.ID {
+			owners = append(owners, o)
+		}
+	}
+	state.Owners = owners
+
+	if err := writeProjectLock(c.projectLockPath(), state); err != nil {
+		c.logger.Warn("error unlocking the project", "err", err)
+	}
+}
+
+func (c *Core) projectLockPath() string {
+	return filepath.Join(c.localDir, "project.lock")
+}
+
+// readProjectLock reads the owner file of the project lock at path. An
+// owner file that can't be parsed, such as one that was partially
+// written, is treated as having no owners.
+func readProjectLock(path string) (*projectLockState, error) {
+	var result projectLockState
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &result); err != nil {
+		return new(projectLockState), nil
+	}
+
+	return &result, nil
+}
+
+// writeProjectLock writes the owner file of the project lock at path, or
+// removes it if the lock has no owners.
+func writeProjectLock(path string, state *projectLockState) error {
+	if len(state.Owners) == 0 {
+		err := os.Remove(path)
+		if os.IsNotExist(err) {
+			err = nil
+		}
+
+		return err
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,273 @@
+package otto
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreLockProject(t *testing.T) {
+	defer func(d time.Duration) { projectLockWait = d }(projectLockWait)
+	projectLockWait = 10 * time.Millisecond
+	core, other := testProjectLockCores(t)
+
+	unlock, err := core.lockProject("compile", true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The lock is reentrant for nested operations
+	unlock2, err := core.lockProjectNested("build", false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	unlock2()
+
+	// Mutating operations of other cores fail after waiting
+	err = other.Compile()
+	if err == nil || !strings.Contains(err.Error(), "operation (compile, pid ") {
+		t.Fatalf("err: %v", err)
+	}
+
+	unlock()
+	unlock, err = other.lockProject("dev", false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	unlock()
+	if _, err := os.Stat(core.projectLockPath()); !os.IsNotExist(err) {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestCoreLockProject_upgrade(t *testing.T) {
+	core, _ := testProjectLockCores(t)
+
+	unlock, err := core.lockProject("dev", false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer unlock()
+
+	// The shared lock can't be taken exclusively
+	_, err = core.lockProject("compile", true)
+	if err == nil || !strings.Contains(err.Error(), "while the dev operation") {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The failure doesn't change the depth of the lock
+	unlock2, err := core.lockProject("build", false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	unlock2()
+	if core.projectLockDepth != 1 {
+		t.Fatalf("bad: %d", core.projectLockDepth)
+	}
+}
+
+func TestCoreLockProject_exclusive(t *testing.T) {
+	core, _ := testProjectLockCores(t)
+
+	unlock, err := core.lockProject("compile", true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer unlock()
+
+	// Operations that aren't nested can't share the exclusive lock, such
+	// as one on another goroutine.
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := core.lockProject("build", false)
+		errCh <- err
+	}()
+	err = <-errCh
+	if err == nil || !strings.Contains(err.Error(), "while the compile operation") {
+		t.Fatalf("err: %v", err)
+	}
+	if core.projectLockDepth != 1 {
+		t.Fatalf("bad: %d", core.projectLockDepth)
+	}
+
+	// Nested operations can, even exclusively
+	unlock2, err := core.lockProjectNested("compile", true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	unlock2()
+}
+
+func TestCoreLockProject_wait(t *testing.T) {
+	defer func(d time.Duration) { projectLockRetryInterval = d }(projectLockRetryInterval)
+	projectLockRetryInterval = time.Millisecond
+	core, other := testProjectLockCores(t)
+
+	unlock, err := core.lockProject("compile", true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	time.AfterFunc(20*time.Millisecond, unlock)
+
+	// The operation waits for the lock to be released
+	unlock, err = other.lockProject("compile", true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	unlock()
+}
+
+func TestCoreLockProject_stale(t *testing.T) {
+	defer func(d time.Duration) { projectLockWait = d }(projectLockWait)
+	projectLockWait = 10 * time.Millisecond
+	core, _ := testProjectLockCores(t)
+
+	// A process that is gone owns the lock
+	cmd := exec.Command("go", "version")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("can't run a process: %s", err)
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	data, err := json.Marshal(&projectLockState{
+		Owners: []*projectLockOwner{
+			&projectLockOwner{
+				Pid:       cmd.Process.Pid,
+				Host:      host,
+				Operation: "compile",
+				Exclusive: true,
+				StartedAt: time.Now(),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := os.MkdirAll(core.localDir, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := ioutil.WriteFile(core.projectLockPath(), data, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	unlock, err := core.lockProject("dev", false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	unlock()
+}
+
+func TestCoreLockProject_shared(t *testing.T) {
+	defer func(d time.Duration) { projectLockWait = d }(projectLockWait)
+	projectLockWait = 10 * time.Millisecond
+	core, other := testProjectLockCores(t)
+
+	// Operations that use the compilation share the lock
+	unlock, err := core.lockProject("dev", false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	unlock2, err := other.lockProject("build", false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// A compile needs it exclusively
+	unlock()
+	err = core.Compile()
+	if err == nil || !strings.Contains(err.Error(), "operation (build, pid ") {
+		t.Fatalf("err: %v", err)
+	}
+
+	unlock2()
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := os.Stat(core.projectLockPath()); !os.IsNotExist(err) {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestCoreLockProject_compileDuringDeploy(t *testing.T) {
+	defer func(d time.Duration) { projectLockWait = d }(projectLockWait)
+	projectLockWait = 10 * time.Millisecond
+	uiMock := new(ui.Mock)
+	uiMock.AddAnswer("^creds_password$", "hunter2")
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	appMock := &testSlowDeployApp{
+		Started: make(chan struct{}),
+		Release: make(chan struct{}),
+	}
+	coreConfig.Apps[TestAppTuple] = func() (app.App, error) {
+		return appMock, nil
+	}
+	core := testCore(t, coreConfig)
+	otherConfig := TestCoreConfig(t)
+	otherConfig.Appfile = coreConfig.Appfile
+	otherConfig.LocalDir = coreConfig.LocalDir
+	other := testCore(t, otherConfig)
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- core.Deploy("", nil)
+	}()
+	select {
+	case <-appMock.Started:
+	case err := <-errCh:
+		t.Fatalf("err: %v", err)
+	}
+
+	// The compilation can't be deleted while the deploy uses it
+	err := other.Compile()
+	close(appMock.Release)
+	if err == nil || !strings.Contains(err.Error(), "operation (deploy, pid ") {
+		t.Fatalf("err: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := other.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+// testSlowDeployApp is an app whose Deploy closes Started and then waits
+// until Release is closed.
+type testSlowDeployApp struct {
+	app.Mock
+
+	Started chan struct{}
+	Release chan struct{}
+}
+
+func (a *testSlowDeployApp) Deploy(ctx *app.Context) error {
+	close(a.Started)
+	<-a.Release
+	return nil
+}
+
+// testProjectLockCores returns two cores for the basic Appfile with the
+// same local directory.
+func testProjectLockCores(t *testing.T) (*Core, *Core) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	otherConfig := TestCoreConfig(t)
+	otherConfig.Appfile = coreConfig.Appfile
+	otherConfig.LocalDir = coreConfig.LocalDir
+
+	return testCore(t, coreConfig), testCore(t, otherConfig)
+}
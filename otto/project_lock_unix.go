@@ -0,0 +1,13 @@
+// +build darwin freebsd linux netbsd openbsd
+
+package otto
+
+import (
+	"syscall"
+)
+
+// processAlive returns true if the process with the pid is running.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
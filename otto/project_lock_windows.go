@@ -0,0 +1,32 @@
+// +build windows
+
+package otto
+
+import (
+	"syscall"
+)
+
+const (
+	processQueryLimitedInformation = 0x1000
+	stillActive                    = 259
+
+	errInvalidParameter syscall.Errno = 87
+)
+
+// processAlive returns true if the process with the pid is running.
+func processAlive(pid int) bool {
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		// The process doesn't exist if the pid is invalid. Other errors,
+		// such as access denied, mean that the process exists.
+		return err != errInvalidParameter
+	}
+	defer syscall.CloseHandle(h)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return true
+	}
+
+	return code == stillActive
+}
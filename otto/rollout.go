@@ -0,0 +1,473 @@
+package otto
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+// DeployStrategy is how a deploy gets the new version of the application
+// in front of its traffic.
+type DeployStrategy string
+
+const (
+	// DeployStrategyStandard is the deploy of the app type with
+	// app.App.Deploy. This is the default.
+	DeployStrategyStandard DeployStrategy = "standard"
+
+	// DeployStrategyBlueGreen deploys the new version next to the live
+	// one, switches all the traffic to it and tears down the old one.
+	DeployStrategyBlueGreen DeployStrategy = "blue-green"
+
+	// DeployStrategyCanary deploys the new version next to the live one
+	// and moves the traffic to it gradually, using the percentages of
+	// DeployOpts.CanarySteps, before it switches all the traffic to it
+	// and tears down the old one.
+	DeployStrategyCanary DeployStrategy = "canary"
+)
+
+// rolloutDefaultCanarySteps are the percentages of the traffic that a
+// canary rollout sends to the new version if DeployOpts.CanarySteps is
+// empty.
+var rolloutDefaultCanarySteps = []int{10, 50}
+
+// rolloutDefaultGrace is how long a rollout waits for the application
+// to become healthy after each step if DeployOpts.HealthGrace is zero.
+const rolloutDefaultGrace = time.Minute
+
+// RolloutState is the state of a rollout.
+type RolloutState string
+
+const (
+	RolloutInProgress RolloutState = "in-progress"
+	RolloutFailed     RolloutState = "failed"
+	RolloutComplete   RolloutState = "complete"
+	RolloutRolledBack RolloutState = "rolled-back"
+)
+
+// The actions of the steps of a rollout. Each maps to a method of one of
+// the optional rollout interfaces in the app package.
+const (
+	rolloutDeploy   = "DeployInactive"
+	rolloutWeight   = "SetTrafficWeight"
+	rolloutSwitch   = "SwitchTraffic"
+	rolloutTeardown = "TeardownInactive"
+)
+
+// RolloutStep is a single step of a rollout.
+type RolloutStep struct {
+	// Action is the name of the app method that does the step, such as
+	// "SwitchTraffic".
+	Action string
+
+	// Weight is the percentage of the traffic for the new version, for
+	// the "SetTrafficWeight" action.
+	Weight int
+}
+
+func (s *RolloutStep) String() string {
+	switch s.Action {
+	case rolloutDeploy:
+		return "deploy the new version next to the live one"
+	case rolloutWeight:
+		return fmt.Sprintf("send %d%% of the traffic to the new version", s.Weight)
+	case rolloutSwitch:
+		return "switch all the traffic to the new version"
+	case rolloutTeardown:
+		return "tear down the old version"
+	default:
+		return s.Action
+	}
+}
+
+// Rollout is the state of the latest rollout of the application on the
+// active infrastructure and environment. It is stored in the directory
+// after every step so that an interrupted rollout can be resumed or
+// rolled back.
+type Rollout struct {
+	Strategy DeployStrategy
+	State    RolloutState
+	Steps    []*RolloutStep
+
+	// Step is the index of the next step to do. If the rollout was
+	// interrupted during a step, that step is done again on resume.
+	Step int
+
+	// Weight is the percentage of the traffic that the new version gets.
+	// Switched is true once all the traffic was switched to the new
+	// version, at which point the old one is the inactive deployment.
+	Weight   int
+	Switched bool
+
+	// Error is the error of the step that failed, if the rollout failed.
+	Error string
+
+	StartedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Active returns true if the rollout is unfinished, so it can be resumed
+// or rolled back.
+func (r *Rollout) Active() bool {
+	return r != nil && (r.State == RolloutInProgress || r.State == RolloutFailed)
+}
+
+// RolloutStatus returns the state of the latest rollout of the
+// application, or nil if it was never deployed with a rollout strategy.
+func (c *Core) RolloutStatus() (*Rollout, error) {
+	data, err := c.dir.GetBlob(c.rolloutKey())
+	if err != nil {
+		return nil, directoryErr(err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+	defer data.Close()
+
+	var result Rollout
+	if err := json.NewDecoder(data.Data).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// RolloutRollback rolls back the unfinished rollout of the application:
+// the traffic goes back to the old version and the new version is torn
+// down. A complete rollout can't be rolled back; deploy the old version
+// instead.
+func (c *Core) RolloutRollback() (err error) {
+	unlock, err := c.lockProject("rollback", false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	c.startOutput()
+	op := c.operation("deploy.rollback", nil)
+	defer c.cacheAppContexts()()
+	defer func() {
+		err = c.finishWarnings(err)
+		op.End(err)
+	}()
+
+	r, err := c.RolloutStatus()
+	if err != nil {
+		return errwrap.Wrapf("Error loading the rollout state: {{err}}", err)
+	}
+	if !r.Active() {
+		return fmt.Errorf(
+			"There is no unfinished rollout of '%s' to roll back.",
+			c.appfile.Application.Name)
+	}
+
+	impl, ctx, err := c.rolloutApp()
+	if err != nil {
+		return err
+	}
+	defer maybeClose(impl)
+
+	var steps []*RolloutStep
+	switch {
+	case r.Switched:
+		steps = append(steps, &RolloutStep{Action: rolloutSwitch})
+	case r.Weight > 0:
+		steps = append(steps, &RolloutStep{Action: rolloutWeight})
+	}
+	steps = append(steps, &RolloutStep{Action: rolloutTeardown})
+	if err := c.rolloutCheck(impl, r.Strategy, steps); err != nil {
+		return err
+	}
+
+	c.ui.Header(fmt.Sprintf("Rolling back the %s rollout...", r.Strategy))
+	for _, s := range steps {
+		if err := c.rolloutStep(impl, ctx, r, s); err != nil {
+			return errwrap.Wrapf(fmt.Sprintf(
+				"Error rolling back, while trying to %s: {{err}}", s), err)
+		}
+	}
+
+	r.State = RolloutRolledBack
+	r.Error = ""
+	if err := c.saveRollout(r); err != nil {
+		return err
+	}
+
+	ui.Result(c.ui, fmt.Sprintf(
+		"Rolled back '%s' to the old version", c.appfile.Application.Name))
+	return nil
+}
+
+// rollout deploys the root app impl with the context ctx with the
+// strategy of opts, or resumes the unfinished rollout if opts.Resume is
+// set. It returns false without doing anything if the deploy should be
+// done with the standard strategy.
+func (c *Core) rollout(impl app.App, ctx *app.Context, opts *DeployOpts) (bool, error) {
+	existing, err := c.RolloutStatus()
+	if err != nil {
+		return false, errwrap.Wrapf("Error loading the rollout state: {{err}}", err)
+	}
+
+	var r *Rollout
+	switch {
+	case existing.Active() && opts.Resume:
+		if opts.Strategy != "" && opts.Strategy != existing.Strategy {
+			return false, fmt.Errorf(
+				"The unfinished rollout uses the %s strategy, so it can't be\n"+
+					"resumed with the %s strategy.", existing.Strategy, opts.Strategy)
+		}
+
+		r = existing
+		r.State = RolloutInProgress
+		r.Error = ""
+
+	case existing.Active():
+		return false, fmt.Errorf(
+			"The %s rollout of '%s' is unfinished, at step %d of %d. Resume it\n"+
+				"with `otto deploy -resume` or roll it back with\n"+
+				"`otto deploy -rollback` before deploying again.",
+			existing.Strategy, c.appfile.Application.Name,
+			existing.Step+1, len(existing.Steps))
+
+	case opts.Resume:
+		return false, fmt.Errorf(
+			"There is no unfinished rollout of '%s' to resume.",
+			c.appfile.Application.Name)
+
+	case opts.Strategy == "" || opts.Strategy == DeployStrategyStandard:
+		return false, nil
+
+	default:
+		steps, err := rolloutSteps(opts.Strategy, opts.CanarySteps)
+		if err != nil {
+			return false, err
+		}
+
+		now := time.Now().UTC()
+		r = &Rollout{
+			Strategy:  opts.Strategy,
+			State:     RolloutInProgress,
+			Steps:     steps,
+			StartedAt: now,
+		}
+	}
+
+	if err := c.rolloutCheck(impl, r.Strategy, r.Steps[r.Step:]); err != nil {
+		return false, err
+	}
+
+	grace := opts.HealthGrace
+	if grace == 0 {
+		grace = rolloutDefaultGrace
+	}
+	_, health := impl.(app.Healthchecker)
+	if !health {
+		ui.Warn(c.ui, fmt.Sprintf(
+			"The '%s' application type doesn't support health checks, so the\n"+
+				"rollout continues without checking the health between steps.",
+			ctx.Tuple.App))
+	}
+
+	for r.Step < len(r.Steps) {
+		s := r.Steps[r.Step]
+		if err := c.saveRollout(r); err != nil {
+			return true, err
+		}
+
+		c.ui.Header(fmt.Sprintf(
+			"Rollout step %d of %d: %s", r.Step+1, len(r.Steps), s))
+		err := c.rolloutStep(impl, ctx, r, s)
+		if err == nil && health && (s.Action == rolloutWeight || s.Action == rolloutSwitch) {
+			err = c.waitHealthy(impl, ctx, grace)
+		}
+		if err != nil {
+			r.State = RolloutFailed
+			r.Error = err.Error()
+			if saveErr := c.saveRollout(r); saveErr != nil {
+				c.logger.Warn("error storing the rollout state", "err", saveErr)
+			}
+
+			return true, fmt.Errorf(
+				"The rollout failed at step %d of %d, which was to %s:\n\n%s\n\n"+
+					"Resume it with `otto deploy -resume` or roll it back with\n"+
+					"`otto deploy -rollback`.",
+				r.Step+1, len(r.Steps), s, err)
+		}
+
+		r.Step++
+	}
+
+	r.State = RolloutComplete
+	return true, c.saveRollout(r)
+}
+
+// rolloutStep does a single step of the rollout r with the app impl and
+// updates r with where the traffic goes.
+func (c *Core) rolloutStep(impl app.App, ctx *app.Context, r *Rollout, s *RolloutStep) error {
+	c.logger.Info("rollout step", "action", s.Action, "weight", s.Weight)
+
+	var err error
+	switch s.Action {
+	case rolloutDeploy:
+		err = impl.(app.InactiveDeployer).DeployInactive(ctx)
+	case rolloutWeight:
+		if err = impl.(app.TrafficWeighter).SetTrafficWeight(ctx, s.Weight); err == nil {
+			r.Weight = s.Weight
+		}
+	case rolloutSwitch:
+		if err = impl.(app.TrafficSwitcher).SwitchTraffic(ctx); err == nil {
+			r.Switched = !r.Switched
+			if r.Switched {
+				r.Weight = 100
+			} else {
+				r.Weight = 0
+			}
+		}
+	case rolloutTeardown:
+		err = impl.(app.InactiveTeardowner).TeardownInactive(ctx)
+	default:
+		err = fmt.Errorf("unknown rollout step '%s'", s.Action)
+	}
+
+	if err == app.ErrRolloutNotSupported {
+		return c.rolloutErr(r.Strategy, []string{s.Action})
+	}
+
+	return err
+}
+
+// rolloutSteps returns the steps of a rollout with the strategy s. The
+// canary percentages must increase and be below 100, which is the final
+// switch of the traffic.
+func rolloutSteps(s DeployStrategy, canary []int) ([]*RolloutStep, error) {
+	result := []*RolloutStep{&RolloutStep{Action: rolloutDeploy}}
+	switch s {
+	case DeployStrategyBlueGreen:
+	case DeployStrategyCanary:
+		if len(canary) == 0 {
+			canary = rolloutDefaultCanarySteps
+		}
+
+		last := 0
+		for _, w := range canary {
+			if w <= last || w > 100 {
+				return nil, fmt.Errorf(
+					"The canary steps must be increasing percentages from 1 to 100,\n"+
+						"but %d follows %d.", w, last)
+			}
+			if w == 100 {
+				// The final switch sends all the traffic
+				last = w
+				continue
+			}
+
+			result = append(result, &RolloutStep{Action: rolloutWeight, Weight: w})
+			last = w
+		}
+	default:
+		return nil, fmt.Errorf(
+			"Unknown deploy strategy %q. The strategy must be one of: %s, %s, %s.",
+			s, DeployStrategyStandard, DeployStrategyBlueGreen, DeployStrategyCanary)
+	}
+
+	return append(result,
+		&RolloutStep{Action: rolloutSwitch},
+		&RolloutStep{Action: rolloutTeardown}), nil
+}
+
+// rolloutCheck returns an error listing what the app impl is missing to
+// do the steps of a rollout with strategy s.
+func (c *Core) rolloutCheck(impl app.App, s DeployStrategy, steps []*RolloutStep) error {
+	var supported []string
+	if rs, ok := impl.(app.RolloutSupporter); ok {
+		var err error
+		supported, err = rs.RolloutSupport()
+		if err != nil {
+			return errwrap.Wrapf(
+				"Error checking the rollout support of the app: {{err}}", err)
+		}
+	} else {
+		supported = app.RolloutMethods(impl)
+	}
+
+	seen := make(map[string]struct{})
+	for _, name := range supported {
+		seen[name] = struct{}{}
+	}
+
+	var missing []string
+	for _, step := range steps {
+		if _, ok := seen[step.Action]; !ok {
+			seen[step.Action] = struct{}{}
+			missing = append(missing, step.Action)
+		}
+	}
+	if len(missing) > 0 {
+		return c.rolloutErr(s, missing)
+	}
+
+	return nil
+}
+
+// rolloutErr returns the error for a strategy s that isn't supported by
+// the app type, which is missing the methods in missing.
+func (c *Core) rolloutErr(s DeployStrategy, missing []string) error {
+	return fmt.Errorf(
+		"Strategy %q is not supported by app type %q, which doesn't\n"+
+			"implement: %s.", s, c.appfile.Application.Type, strings.Join(missing, ", "))
+}
+
+// rolloutApp returns the root app and its context for a rollout, with the
+// infrastructure credentials.
+func (c *Core) rolloutApp() (app.App, *app.Context, error) {
+	infra, infraCtx, err := c.infra()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer maybeClose(infra)
+	if err := c.creds(infra, infraCtx); err != nil {
+		return nil, nil, err
+	}
+
+	impl, ctx, err := c.App()
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx.Shared.InfraCreds = infraCtx.Shared.InfraCreds
+
+	return impl, ctx, nil
+}
+
+// rolloutKey is the key of the blob in the directory with the state of
+// the latest rollout of the app on the active infrastructure and
+// environment.
+func (c *Core) rolloutKey() string {
+	infra := c.appfile.ActiveInfrastructure()
+	return directory.EnvironmentKey(c.environment, fmt.Sprintf(
+		"rollout-%s-%s-%s", c.appfile.ID, infra.Type, infra.Flavor))
+}
+
+func (c *Core) saveRollout(r *Rollout) error {
+	r.UpdatedAt = time.Now().UTC()
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	err = c.dir.PutBlob(c.rolloutKey(), &directory.BlobData{
+		Data: bytes.NewReader(raw),
+	})
+	if err != nil {
+		return errwrap.Wrapf(
+			"Error storing the rollout state: {{err}}", directoryErr(err))
+	}
+
+	return nil
+}
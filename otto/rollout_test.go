@@ -0,0 +1,255 @@
+package otto
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreDeploy_blueGreen(t *testing.T) {
+	core, appMock, _ := testRolloutCore(t)
+	testDeployed(t, core, nil)
+
+	opts := &DeployOpts{Strategy: DeployStrategyBlueGreen}
+	if err := core.DeployWithOpts("", nil, opts); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if appMock.DeployCalled {
+		t.Fatal("deploy should not be called")
+	}
+	expected := []string{"DeployInactive", "SwitchTraffic", "TeardownInactive"}
+	if actual := testRolloutCalls(appMock); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	r, err := core.RolloutStatus()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if r.State != RolloutComplete || r.Step != 3 || !r.Switched || r.Active() {
+		t.Fatalf("bad: %#v", r)
+	}
+
+	// A complete rollout can't be rolled back
+	if err := core.RolloutRollback(); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestCoreDeploy_canary(t *testing.T) {
+	defer func(d time.Duration) { healthRetryInterval = d }(healthRetryInterval)
+	healthRetryInterval = time.Millisecond
+
+	core, appMock, uiMock := testRolloutCore(t)
+	testDeployed(t, core, nil)
+
+	// The health is checked after every change of the traffic, and the
+	// rollout stops when it is unhealthy.
+	var checks int
+	appMock.HealthFunc = func(*app.Context) (*app.HealthReport, error) {
+		checks++
+		if checks == 2 {
+			return &app.HealthReport{Status: app.HealthUnhealthy}, nil
+		}
+
+		return &app.HealthReport{Status: app.HealthHealthy}, nil
+	}
+	opts := &DeployOpts{
+		Strategy:    DeployStrategyCanary,
+		CanarySteps: []int{10, 50},
+		HealthGrace: time.Millisecond,
+	}
+	err := core.DeployWithOpts("", nil, opts)
+	if err == nil || !strings.Contains(err.Error(), "step 3 of 5") {
+		t.Fatalf("err: %v", err)
+	}
+	r, err := core.RolloutStatus()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if r.State != RolloutFailed || r.Step != 2 || r.Weight != 50 || r.Error == "" {
+		t.Fatalf("bad: %#v", r)
+	}
+	if err := core.Status(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, "canary, failed at step 3 of 5")
+
+	// A new deploy can't start while the rollout is unfinished
+	err = core.DeployWithOpts("", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "unfinished") {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Resuming redoes the failed step
+	opts.Resume = true
+	if err := core.DeployWithOpts("", nil, opts); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(appMock.TrafficWeights, []int{10, 50, 50}) {
+		t.Fatalf("bad: %#v", appMock.TrafficWeights)
+	}
+	r, err = core.RolloutStatus()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if r.State != RolloutComplete || r.Weight != 100 {
+		t.Fatalf("bad: %#v", r)
+	}
+
+	// There is nothing to resume anymore
+	err = core.DeployWithOpts("", nil, opts)
+	if err == nil || !strings.Contains(err.Error(), "no unfinished rollout") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestCoreRolloutRollback(t *testing.T) {
+	core, appMock, _ := testRolloutCore(t)
+	testDeployed(t, core, nil)
+
+	appMock.TeardownInactiveErr = errors.New("teardown")
+	opts := &DeployOpts{Strategy: DeployStrategyBlueGreen}
+	if err := core.DeployWithOpts("", nil, opts); err == nil {
+		t.Fatal("should error")
+	}
+
+	// The traffic is switched back before the new version is torn down
+	appMock.Calls = nil
+	appMock.TeardownInactiveErr = nil
+	if err := core.RolloutRollback(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []string{"SwitchTraffic", "TeardownInactive"}
+	if actual := testRolloutCalls(appMock); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	r, err := core.RolloutStatus()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if r.State != RolloutRolledBack || r.Switched {
+		t.Fatalf("bad: %#v", r)
+	}
+
+	// A standard deploy works again
+	if err := core.Deploy("", nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestCoreDeploy_strategyNotSupported(t *testing.T) {
+	uiMock := new(ui.Mock)
+	uiMock.AddAnswer("^creds_password$", "hunter2")
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+
+	opts := &DeployOpts{Strategy: DeployStrategyCanary}
+	err := core.DeployWithOpts("", nil, opts)
+	if err == nil {
+		t.Fatal("should error")
+	}
+	expected := `Strategy "canary" is not supported by app type "test"`
+	if !strings.Contains(err.Error(), expected) ||
+		!strings.Contains(err.Error(), "DeployInactive, SetTrafficWeight, SwitchTraffic, TeardownInactive") {
+		t.Fatalf("err: %s", err)
+	}
+	if appMock.DeployCalled {
+		t.Fatal("deploy should not be called")
+	}
+
+	// Nothing is stored, so a standard deploy works
+	if err := core.Deploy("", nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestRolloutSteps(t *testing.T) {
+	cases := []struct {
+		Strategy DeployStrategy
+		Canary   []int
+		Weights  []int
+		Err      bool
+	}{
+		{DeployStrategyBlueGreen, nil, nil, false},
+		{DeployStrategyCanary, nil, []int{10, 50}, false},
+		{DeployStrategyCanary, []int{5, 25, 100}, []int{5, 25}, false},
+		{DeployStrategyCanary, []int{50, 10}, nil, true},
+		{DeployStrategyCanary, []int{0}, nil, true},
+		{DeployStrategyCanary, []int{100, 100}, nil, true},
+		{"rolling", nil, nil, true},
+	}
+
+	for _, tc := range cases {
+		steps, err := rolloutSteps(tc.Strategy, tc.Canary)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s %v: %v", tc.Strategy, tc.Canary, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		var weights []int
+		for _, s := range steps[1 : len(steps)-2] {
+			weights = append(weights, s.Weight)
+		}
+		if !reflect.DeepEqual(weights, tc.Weights) {
+			t.Fatalf("%s %v: %#v", tc.Strategy, tc.Canary, weights)
+		}
+		if steps[0].Action != rolloutDeploy || steps[len(steps)-1].Action != rolloutTeardown {
+			t.Fatalf("%s %v: %#v", tc.Strategy, tc.Canary, steps)
+		}
+	}
+}
+
+// testRolloutApp is an app that implements the rollout interfaces and
+// app.Healthchecker.
+type testRolloutApp struct {
+	app.MockRollout
+
+	HealthFunc func(*app.Context) (*app.HealthReport, error)
+}
+
+func (a *testRolloutApp) Health(ctx *app.Context) (*app.HealthReport, error) {
+	if a.HealthFunc == nil {
+		return &app.HealthReport{Status: app.HealthHealthy}, nil
+	}
+
+	return a.HealthFunc(ctx)
+}
+
+// testRolloutCore returns a core for the basic Appfile whose app type is
+// a testRolloutApp, with the mock UI of the core.
+func testRolloutCore(t *testing.T) (*Core, *testRolloutApp, *ui.Mock) {
+	uiMock := new(ui.Mock)
+	appMock := new(testRolloutApp)
+	core := TestCore(t, &TestCoreOpts{
+		Path: testPath("basic", "Appfile"),
+		App:  appMock,
+		Ui:   uiMock,
+	})
+
+	return core, appMock, uiMock
+}
+
+// testRolloutCalls returns the calls of the rollout methods of the app.
+func testRolloutCalls(a *testRolloutApp) []string {
+	var result []string
+	for _, c := range a.Calls {
+		switch c {
+		case rolloutDeploy, rolloutWeight, rolloutSwitch, rolloutTeardown:
+			result = append(result, c)
+		}
+	}
+
+	return result
+}
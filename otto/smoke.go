@@ -0,0 +1,290 @@
+package otto
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+// smokeDefaultTimeout is how long a smoke test can take if the Appfile
+// doesn't set a timeout.
+const smokeDefaultTimeout = 30 * time.Second
+
+// smokeInterpRe matches the references in the target of a smoke test,
+// such as "${deploy.url}".
+var smokeInterpRe = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// smokeResult is the result of a single smoke test.
+type smokeResult struct {
+	Name     string
+	Target   string
+	Passed   bool
+	Warn     bool
+	Message  string
+	Duration time.Duration
+}
+
+// smokeReport is the result of the smoke tests of the latest deploy,
+// which is stored in the directory.
+type smokeReport struct {
+	Results   []*smokeResult
+	CheckedAt time.Time
+}
+
+// smokeTest runs the smoke tests of the Appfile against the deploy of the
+// root app that was just done with the context ctx. The results are stored
+// in the directory. Failing smoke tests fail the deploy unless they're set
+// to only warn.
+func (c *Core) smokeTest(ctx *app.Context) error {
+	tests := c.appfile.SmokeTests
+	if len(tests) == 0 {
+		return nil
+	}
+	if err := c.deployedContext(ctx, "it can't be smoke tested"); err != nil {
+		return err
+	}
+
+	c.ui.Header("Running smoke tests...")
+	report := &smokeReport{CheckedAt: time.Now().UTC()}
+	var failed []string
+	for _, t := range tests {
+		result := c.runSmokeTest(t, ctx)
+		report.Results = append(report.Results, result)
+
+		switch {
+		case result.Passed:
+			ui.Info(c.ui, fmt.Sprintf("%s: %s (%s)",
+				t.Name, ui.Style("PASSED", "green"), summaryDuration(result.Duration)))
+		case result.Warn:
+			ui.Warn(c.ui, fmt.Sprintf(
+				"Smoke test '%s' failed: %s", t.Name, result.Message))
+		default:
+			ui.Info(c.ui, fmt.Sprintf("%s: %s: %s",
+				t.Name, ui.Style("FAILED", "red"), result.Message))
+			failed = append(failed, t.Name)
+		}
+	}
+
+	if err := c.saveSmokeReport(report); err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf(
+			"The deploy of '%s' failed the smoke tests: %s",
+			ctx.Appfile.Application.Name, strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// runSmokeTest runs a single smoke test and returns its result.
+func (c *Core) runSmokeTest(t *appfile.SmokeTest, ctx *app.Context) *smokeResult {
+	result := &smokeResult{Name: t.Name, Warn: t.OnFailure == "warn"}
+
+	target, err := smokeInterpolate(t.Target, ctx)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	result.Target = target
+
+	timeout := smokeDefaultTimeout
+	if t.Timeout != "" {
+		// The Appfile validation makes sure this parses
+		timeout, _ = time.ParseDuration(t.Timeout)
+	}
+
+	c.logger.Info("running smoke test", "name", t.Name, "type", t.Type)
+	start := time.Now()
+	switch t.Type {
+	case "http":
+		err = smokeHTTP(target, t.Expect, timeout)
+	case "command":
+		err = smokeCommand(target, t.Expect, timeout)
+	default:
+		err = fmt.Errorf("unknown type '%s'", t.Type)
+	}
+	result.Duration = time.Since(start)
+
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+
+	result.Passed = true
+	return result
+}
+
+// smokeInterpolate replaces the references to the deploy information and
+// the infrastructure outputs in the target of a smoke test.
+func smokeInterpolate(target string, ctx *app.Context) (string, error) {
+	var err error
+	result := smokeInterpRe.ReplaceAllStringFunc(target, func(ref string) string {
+		name := smokeInterpRe.FindStringSubmatch(ref)[1]
+		parts := strings.SplitN(name, ".", 2)
+		if len(parts) == 2 {
+			var values map[string]string
+			switch parts[0] {
+			case "deploy":
+				values = ctx.DeployInfo
+			case "infra":
+				values = ctx.InfraOutputs
+			}
+
+			if v, ok := values[parts[1]]; ok {
+				return v
+			}
+		}
+
+		if err == nil {
+			err = fmt.Errorf("unknown reference '%s'", ref)
+		}
+		return ref
+	})
+
+	return result, err
+}
+
+// smokeHTTP requests the URL target and checks that the status code is
+// expect, or 200 if expect is empty.
+func smokeHTTP(target, expect string, timeout time.Duration) error {
+	code := 200
+	if expect != "" {
+		code, _ = strconv.Atoi(expect)
+	}
+
+	client := cleanhttp.DefaultClient()
+	client.Timeout = timeout
+	resp, err := client.Get(target)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != code {
+		return fmt.Errorf("%s returned %d, expected %d (%s)",
+			target, resp.StatusCode, code, http.StatusText(code))
+	}
+
+	return nil
+}
+
+// smokeCommand runs target with the shell and checks that the exit status
+// is expect, or 0 if expect is empty.
+func smokeCommand(target, expect string, timeout time.Duration) error {
+	code := 0
+	if expect != "" {
+		code, _ = strconv.Atoi(expect)
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", target)
+	} else {
+		cmd = exec.Command("sh", "-c", target)
+	}
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	doneCh := make(chan error, 1)
+	go func() { doneCh <- cmd.Wait() }()
+
+	var err error
+	select {
+	case err = <-doneCh:
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-doneCh
+		return fmt.Errorf("timed out after %s", summaryDuration(timeout))
+	}
+
+	status := 0
+	if err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return err
+		}
+		ws, ok := exitErr.Sys().(syscall.WaitStatus)
+		if !ok {
+			return err
+		}
+		status = ws.ExitStatus()
+	}
+
+	if status != code {
+		msg := fmt.Sprintf("exited with %d, expected %d", status, code)
+		if out := strings.TrimSpace(output.String()); out != "" {
+			msg += ": " + out
+		}
+		return errors.New(msg)
+	}
+
+	return nil
+}
+
+// smokeKey is the key of the blob in the directory with the smoke test
+// results of the latest deploy of the app on the active infrastructure
+// and environment.
+func (c *Core) smokeKey() string {
+	infra := c.appfile.ActiveInfrastructure()
+	return directory.EnvironmentKey(c.environment, fmt.Sprintf(
+		"smoke-%s-%s-%s", c.appfile.ID, infra.Type, infra.Flavor))
+}
+
+// smokeReport returns the smoke test results of the latest deploy, or nil
+// if no smoke tests were run.
+func (c *Core) smokeReport() (*smokeReport, error) {
+	data, err := c.dir.GetBlob(c.smokeKey())
+	if err != nil {
+		return nil, directoryErr(err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+	defer data.Close()
+
+	var result smokeReport
+	if err := json.NewDecoder(data.Data).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (c *Core) saveSmokeReport(report *smokeReport) error {
+	raw, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	err = c.dir.PutBlob(c.smokeKey(), &directory.BlobData{
+		Data: bytes.NewReader(raw),
+	})
+	if err != nil {
+		return errwrap.Wrapf(
+			"Error storing the smoke test results: {{err}}", directoryErr(err))
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package otto
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreDeploy_smokeTest(t *testing.T) {
+	healthy := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/health" && healthy:
+		case r.URL.Path == "/health":
+			w.WriteHeader(500)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+
+	uiMock := new(ui.Mock)
+	uiMock.AddAnswer("^creds_password$", "hunter2")
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("smoke-test", "Appfile"))
+	coreConfig.Ui = uiMock
+	core := testCore(t, coreConfig)
+	testDeployed(t, core, map[string]string{"url": server.URL})
+	err := core.dir.PutInfra(&directory.Infra{
+		Lookup:  directory.Lookup{Infra: core.appfile.ActiveInfrastructure().Name},
+		State:   directory.InfraStateReady,
+		Outputs: map[string]string{"code": "3"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The failing test that only warns doesn't fail the deploy
+	if err := core.Deploy("", nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, "Smoke test 'optional' failed")
+
+	report, err := core.smokeReport()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if report == nil || len(report.Results) != 3 {
+		t.Fatalf("bad: %#v", report)
+	}
+	if r := report.Results[0]; !r.Passed || r.Target != server.URL+"/health" {
+		t.Fatalf("bad: %#v", r)
+	}
+	if r := report.Results[1]; !r.Passed || r.Target != "exit 3" {
+		t.Fatalf("bad: %#v", r)
+	}
+	if r := report.Results[2]; r.Passed || !r.Warn {
+		t.Fatalf("bad: %#v", r)
+	}
+	if err := core.Status(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, "2/3 passed")
+
+	// A failing test fails the deploy
+	healthy = false
+	err = core.Deploy("", nil)
+	if err == nil || !strings.Contains(err.Error(), "failed the smoke tests: home") {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Other actions don't run the smoke tests
+	if err := core.Deploy("info", nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestSmokeInterpolate(t *testing.T) {
+	ctx := &app.Context{
+		DeployInfo:   map[string]string{"url": "http://foo.com"},
+		InfraOutputs: map[string]string{"region": "us-east-1"},
+	}
+
+	cases := []struct {
+		Input  string
+		Output string
+		Err    bool
+	}{
+		{"${deploy.url}/health", "http://foo.com/health", false},
+		{"check ${infra.region} ${deploy.url}", "check us-east-1 http://foo.com", false},
+		{"no references", "no references", false},
+		{"${infra.missing}", "", true},
+		{"${build.url}", "", true},
+		{"${deploy}", "", true},
+	}
+
+	for _, tc := range cases {
+		actual, err := smokeInterpolate(tc.Input, ctx)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s: err: %v", tc.Input, err)
+		}
+		if err == nil && actual != tc.Output {
+			t.Fatalf("%s: bad: %s", tc.Input, actual)
+		}
+	}
+}
+
+func TestSmokeCommand(t *testing.T) {
+	if err := smokeCommand("true", "", time.Second); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err := smokeCommand("echo nope; exit 2", "", time.Second)
+	if err == nil || !strings.Contains(err.Error(), "exited with 2, expected 0: nope") {
+		t.Fatalf("err: %v", err)
+	}
+
+	err = smokeCommand("sleep 5", "", 10*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("err: %v", err)
+	}
+}
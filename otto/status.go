@@ -1,65 +1,463 @@
 package otto
 
 import (
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
+	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/otto/app"
 	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
 )
 
+// DefaultStatusLoadingDelay is the default for StatusOpts.LoadingDelay.
+const DefaultStatusLoadingDelay = 150 * time.Millisecond
+
+// DefaultStatusTimeout is the default for StatusOpts.Timeout.
+const DefaultStatusTimeout = 2 * time.Minute
+
+// ErrStatusCancelled is returned by Status when StatusOpts.ShutdownCh is
+// closed before the status loaded.
+var ErrStatusCancelled = errors.New("Loading the status was cancelled.")
+
+// StatusOpts are the options for Core.StatusWithOpts.
+type StatusOpts struct {
+	// Refresh, if true, checks the infrastructure and the deploy for real
+	// in addition to reading their records in the directory, if the
+	// infrastructure implements infrastructure.Verifier and the app
+	// implements app.Healthchecker. This requires the credentials of the
+	// infrastructure.
+	Refresh bool
+
+	// LoadingDelay is how long Status waits for the status to load before
+	// it shows that it is loading. This defaults to
+	// DefaultStatusLoadingDelay.
+	LoadingDelay time.Duration
+
+	// Timeout is how long Status waits for the status to load at most.
+	// The components that didn't load in time are shown as unknown. This
+	// defaults to DefaultStatusTimeout.
+	Timeout time.Duration
+
+	// ShutdownCh stops waiting for the status when it is closed, and
+	// Status returns ErrStatusCancelled.
+	ShutdownCh <-chan struct{}
+}
+
 // statusInfo holds the complete status information for the Core.Status
 // function.
 type statusInfo struct {
+	// Err is the error of all the lookups that failed. The value of a
+	// component whose lookup failed is nil, and its own error is in the
+	// Err field of the component, so that the status can show it as
+	// unknown instead of as not created.
 	Err    error
 	Dev    *directory.Dev
 	Build  *directory.Build
 	Deploy *directory.Deploy
 	Infra  *directory.Infra
+
+	DevErr    error
+	BuildErr  error
+	DeployErr error
+	InfraErr  error
+
+	// DevPorts are the dev ports allocated to the app.
+	DevPorts []int
+
+	// DevInfo is the information about the dev environment, which is nil
+	// if it wasn't saved, and Compile is the current compilation.
+	DevInfo *DevInfo
+	Compile *CompileMetadata
+
+	// DevSnapshots are the recorded snapshots of the dev environment.
+	DevSnapshots []app.SnapshotInfo
+
+	// DeployLabels are the labels of the deploy, if any. See labelDeploy.
+	DeployLabels map[string]string
+
+	// Health is the latest health report of the deploy, if any.
+	Health *app.HealthReport
+
+	// Smoke is the result of the smoke tests of the latest deploy, if any.
+	Smoke *smokeReport
+
+	// Rollout is the state of the latest rollout, if any.
+	Rollout *Rollout
+
+	// DevDrift, BuildDrift, and DeployDrift tell if the components are
+	// outdated compared to the current Appfile and source.
+	DevDrift    StatusDrift
+	BuildDrift  StatusDrift
+	DeployDrift StatusDrift
+}
+
+// StatusResult is the status of the stages of the application in a form
+// for scripts, such as to fail a CI job if the deploy is outdated.
+type StatusResult struct {
+	Application string `json:"application"`
+	Environment string `json:"environment"`
+
+	// Dev, Build, Deploy, and Infra are the states of the components:
+	// "not_created" or "created" for Dev, "not_built" or "built" for
+	// Build, "not_deployed", "deployed" or "failed" for Deploy, and
+	// "not_created", "partial" or "ready" for Infra. The state of a
+	// component whose lookup failed is "unknown", and Errors has the
+	// error keyed by the component.
+	Dev    string            `json:"dev"`
+	Build  string            `json:"build"`
+	Deploy string            `json:"deploy"`
+	Infra  string            `json:"infra"`
+	Errors map[string]string `json:"errors,omitempty"`
+
+	// DevDrift, BuildDrift, and DeployDrift tell if the components are
+	// outdated, and Drifted is true if any of them is.
+	DevDrift    StatusDrift `json:"dev_drift"`
+	BuildDrift  StatusDrift `json:"build_drift"`
+	DeployDrift StatusDrift `json:"deploy_drift"`
+	Drifted     bool        `json:"drifted"`
 }
 
-// statusInfo gets the information for the Status call.
+// StatusResult returns the status that Status outputs, in a structured
+// form.
+func (c *Core) StatusResult() (*StatusResult, error) {
+	infoCh := make(chan *statusInfo, 1)
+	c.statusInfo(infoCh)
+	status := <-infoCh
+	if err := status.failed(); err != nil {
+		return nil, err
+	}
+
+	result := &StatusResult{
+		Application: c.appfile.Application.Name,
+		Environment: c.environment,
+		Dev:         "not_created",
+		Build:       "not_built",
+		Deploy:      "not_deployed",
+		Infra:       "not_created",
+		DevDrift:    status.DevDrift,
+		BuildDrift:  status.BuildDrift,
+		DeployDrift: status.DeployDrift,
+	}
+	result.Drifted = result.DevDrift.Drifted() ||
+		result.BuildDrift.Drifted() || result.DeployDrift.Drifted()
+
+	if status.Dev.IsReady() {
+		result.Dev = "created"
+	}
+	if status.Build != nil {
+		result.Build = "built"
+	}
+	if status.Deploy.IsDeployed() {
+		result.Deploy = "deployed"
+	} else if status.Deploy.IsFailed() {
+		result.Deploy = "failed"
+	}
+	if status.Infra.IsReady() {
+		result.Infra = "ready"
+	} else if status.Infra.IsPartial() {
+		result.Infra = "partial"
+	}
+
+	errs := map[string]error{
+		"dev":    status.DevErr,
+		"build":  status.BuildErr,
+		"deploy": status.DeployErr,
+		"infra":  status.InfraErr,
+	}
+	states := map[string]*string{
+		"dev":    &result.Dev,
+		"build":  &result.Build,
+		"deploy": &result.Deploy,
+		"infra":  &result.Infra,
+	}
+	for k, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		if result.Errors == nil {
+			result.Errors = make(map[string]string)
+		}
+		result.Errors[k] = err.Error()
+		*states[k] = "unknown"
+	}
+
+	return result, nil
+}
+
+// waitStatus starts loading the status info and waits for it, showing a
+// loading message if it takes longer than the loading delay of opts. If
+// it takes longer than the timeout, the components that were loaded are
+// returned and the others are unknown.
 //
-// This is meant to be called in a goroutine.
-func (c *Core) statusInfo(resultCh chan<- *statusInfo) {
-	infra := c.appfile.ActiveInfrastructure()
-	if infra == nil {
-		panic("infra not found")
+// Nothing is left blocked if this returns before the status loaded: the
+// loading finishes in the background when the directory responds.
+func (c *Core) waitStatus(opts *StatusOpts) (*statusInfo, error) {
+	delay := opts.LoadingDelay
+	if delay <= 0 {
+		delay = DefaultStatusLoadingDelay
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultStatusTimeout
 	}
 
+	// The channel is buffered so the loading can always finish
+	progress := new(statusProgress)
+	statusCh := make(chan *statusInfo, 1)
+	go c.loadStatus(statusCh, progress)
+
+	loadingCh := time.After(delay)
+	timeoutCh := time.After(timeout)
+	for {
+		select {
+		case status := <-statusCh:
+			return status, nil
+		case <-loadingCh:
+			c.ui.Header("Loading status...")
+			c.ui.Message(fmt.Sprintf(
+				"Waiting for the directory (%s). Depending on the directory\n"+
+					"backend, this may require network operations and can take some\n"+
+					"time. On a typical broadband connection, this shouldn't take more\n"+
+					"than a few seconds.", directoryName(c.dir)))
+		case <-timeoutCh:
+			ui.Warn(c.ui, fmt.Sprintf(
+				"The directory didn't respond within %s, so the status below is\n"+
+					"incomplete.", summaryDuration(timeout)))
+			return progress.partial(fmt.Errorf(
+				"timed out after %s waiting for the directory",
+				summaryDuration(timeout))), nil
+		case <-opts.ShutdownCh:
+			return nil, ErrStatusCancelled
+		}
+	}
+}
+
+// directoryName returns the description of the directory backend b for
+// messages.
+func directoryName(b directory.Backend) string {
+	if s, ok := b.(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	return fmt.Sprintf("%T", b)
+}
+
+// statusInfo gets the information for the Status call and sends it on
+// resultCh. It sends exactly one result, even if it fails.
+//
+// This is meant to be called in a goroutine. resultCh must be buffered
+// so that the goroutine finishes even if the caller stops waiting for
+// the result.
+func (c *Core) statusInfo(resultCh chan<- *statusInfo) {
+	c.loadStatus(resultCh, nil)
+}
+
+// loadStatus is statusInfo that also records the components in progress
+// as they're loaded, if it isn't nil.
+func (c *Core) loadStatus(resultCh chan<- *statusInfo, progress *statusProgress) {
 	var err error
 	var result statusInfo
+	defer func() { resultCh <- &result }()
+
+	infra := c.appfile.ActiveInfrastructure()
+	if infra == nil {
+		result.Err = fmt.Errorf(
+			"Infrastructure '%s' not found in the Appfile.",
+			c.appfile.Project.Infrastructure)
+		result.DevErr = result.Err
+		result.BuildErr = result.Err
+		result.DeployErr = result.Err
+		result.InfraErr = result.Err
+		return
+	}
 
 	// Dev
 	result.Dev, err = c.dir.GetDev(&directory.Dev{Lookup: directory.Lookup{
 		AppID: c.appfile.ID}})
 	if err != nil {
-		result.Err = multierror.Append(result.Err, fmt.Errorf(
-			"Error loading development status: %s", err))
+		result.Dev = nil
+		result.DevErr = directoryErr(err)
+		result.Err = multierror.Append(result.Err, errwrap.Wrapf(
+			"Error loading development status: {{err}}", result.DevErr))
 	}
+	progress.loaded(statusDev, &result)
 
 	// Build
-	result.Build, err = c.dir.GetBuild(&directory.Build{Lookup: directory.Lookup{
-		AppID: c.appfile.ID, Infra: infra.Type, InfraFlavor: infra.Flavor}})
+	result.Build, err = c.dir.GetBuild(&directory.Build{
+		Lookup: c.deployLookup(c.appfile.ID)})
 	if err != nil {
-		result.Err = multierror.Append(result.Err, fmt.Errorf(
-			"Error loading build status: %s", err))
+		result.Build = nil
+		result.BuildErr = directoryErr(err)
+		result.Err = multierror.Append(result.Err, errwrap.Wrapf(
+			"Error loading build status: {{err}}", result.BuildErr))
 	}
+	progress.loaded(statusBuild, &result)
 
 	// Deploy
-	result.Deploy, err = c.dir.GetDeploy(&directory.Deploy{Lookup: directory.Lookup{
-		AppID: c.appfile.ID, Infra: infra.Type, InfraFlavor: infra.Flavor}})
+	result.Deploy, err = c.dir.GetDeploy(&directory.Deploy{
+		Lookup: c.deployLookup(c.appfile.ID)})
 	if err != nil {
-		result.Err = multierror.Append(result.Err, fmt.Errorf(
-			"Error loading deploy status: %s", err))
+		result.Deploy = nil
+		result.DeployErr = directoryErr(err)
+		result.Err = multierror.Append(result.Err, errwrap.Wrapf(
+			"Error loading deploy status: {{err}}", result.DeployErr))
 	}
+	progress.loaded(statusDeploy, &result)
 
 	// Infra
 	result.Infra, err = c.dir.GetInfra(&directory.Infra{Lookup: directory.Lookup{
 		Infra: infra.Name}})
+	if err != nil {
+		result.Infra = nil
+		result.InfraErr = directoryErr(err)
+		result.Err = multierror.Append(result.Err, errwrap.Wrapf(
+			"Error loading infra status: {{err}}", result.InfraErr))
+	}
+	progress.loaded(statusInfra, &result)
+
+	// Dev ports
+	result.DevPorts, err = c.devPortDB().Ports(c.appfile.ID)
+	if err != nil {
+		result.Err = multierror.Append(result.Err, fmt.Errorf(
+			"Error loading dev ports: %s", err))
+	}
+
+	// Dev info. The compilation may be missing, in which case we can't
+	// tell if the dev environment is stale.
+	result.DevInfo, err = c.DevInfo()
+	if err != nil {
+		result.Err = multierror.Append(result.Err, fmt.Errorf(
+			"Error loading dev environment info: %s", err))
+	}
+	result.Compile, err = c.compileMetadata()
+	if err != nil {
+		result.Err = multierror.Append(result.Err, errwrap.Wrapf(
+			"Error loading compilation metadata: {{err}}", err))
+	}
+	result.DevSnapshots, err = c.devSnapshotRecord()
+	if err != nil {
+		result.Err = multierror.Append(result.Err, fmt.Errorf(
+			"Error loading dev snapshots: %s", err))
+	}
+	result.DeployLabels, err = c.deployLabels(c.appfile.ID)
+	if err != nil {
+		result.Err = multierror.Append(result.Err, errwrap.Wrapf(
+			"Error loading deploy labels: {{err}}", err))
+	}
+	result.Health, err = c.healthReport()
+	if err != nil {
+		result.Err = multierror.Append(result.Err, errwrap.Wrapf(
+			"Error loading health report: {{err}}", err))
+	}
+	result.Smoke, err = c.smokeReport()
+	if err != nil {
+		result.Err = multierror.Append(result.Err, errwrap.Wrapf(
+			"Error loading smoke test results: {{err}}", err))
+	}
+	result.Rollout, err = c.RolloutStatus()
+	if err != nil {
+		result.Err = multierror.Append(result.Err, errwrap.Wrapf(
+			"Error loading the rollout state: {{err}}", err))
+	}
+
+	// Drift
+	fp, err := c.statusFingerprint(&result)
 	if err != nil {
 		result.Err = multierror.Append(result.Err, fmt.Errorf(
-			"Error loading infra status: %s", err))
+			"Error fingerprinting the source: %s", err))
+	}
+	if result.Dev.IsReady() && result.DevInfo.Stale(result.Compile) {
+		result.DevDrift.Appfile = true
+	}
+	if result.Build != nil {
+		result.BuildDrift = c.labelsDrift(result.Build.Artifact, result.Compile, fp)
+	}
+	if result.Deploy.IsDeployed() {
+		result.DeployDrift = c.labelsDrift(result.DeployLabels, result.Compile, fp)
+	}
+}
+
+// The components of the status in statusProgress.
+const (
+	statusDev    = "dev"
+	statusBuild  = "build"
+	statusDeploy = "deploy"
+	statusInfra  = "infra"
+)
+
+// statusProgress is the status info that was loaded so far, so that
+// Status can show the components that were loaded if the others take too
+// long, such as with a directory that doesn't respond.
+type statusProgress struct {
+	lock sync.Mutex
+	info statusInfo
+	done map[string]bool
+}
+
+// loaded records that the component was loaded into the status info
+// info, which is copied. The progress may be nil.
+func (p *statusProgress) loaded(component string, info *statusInfo) {
+	if p == nil {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.done == nil {
+		p.done = make(map[string]bool)
 	}
+	p.info = *info
+	p.done[component] = true
+
+	// The loading goes on appending to the errors
+	if err, ok := info.Err.(*multierror.Error); ok {
+		errs := make([]error, len(err.Errors))
+		copy(errs, err.Errors)
+		p.info.Err = &multierror.Error{Errors: errs}
+	}
+}
+
+// partial returns the status info loaded so far, with err as the error
+// of the components that weren't loaded yet.
+func (p *statusProgress) partial(err error) *statusInfo {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	result := p.info
+	missing := map[string]*error{
+		statusDev:    &result.DevErr,
+		statusBuild:  &result.BuildErr,
+		statusDeploy: &result.DeployErr,
+		statusInfra:  &result.InfraErr,
+	}
+	for component, e := range missing {
+		if !p.done[component] {
+			*e = err
+		}
+	}
+	result.Err = multierror.Append(result.Err, err)
+
+	return &result
+}
+
+// failed returns the error of the status if none of the components could
+// be loaded, such as when the directory is unreachable, in which case
+// there is nothing to show.
+func (s *statusInfo) failed() error {
+	if s.DevErr == nil || s.BuildErr == nil || s.DeployErr == nil || s.InfraErr == nil {
+		return nil
+	}
+
+	return s.Err
+}
 
-	resultCh <- &result
+// statusUnknown returns the status text of a component whose lookup
+// failed with err.
+func statusUnknown(err error) string {
+	return ui.Style(fmt.Sprintf("UNKNOWN: %s", err), "red")
 }
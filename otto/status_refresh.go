@@ -0,0 +1,140 @@
+package otto
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/infrastructure"
+	"github.com/hashicorp/otto/ui"
+)
+
+// refreshStatus checks the infrastructure and the deploy of the status
+// info status for real, and returns the discrepancies between them and
+// the directory. The health report of status is replaced by a new one.
+//
+// Running non-interactively without the password of the credentials, the
+// refresh is skipped with a notice since the credentials can't be read.
+func (c *Core) refreshStatus(status *statusInfo) ([]string, error) {
+	infraReady := status.Infra.IsReady() || status.Infra.IsPartial()
+	deployed := status.Deploy.IsDeployed()
+	if !infraReady && !deployed {
+		return nil, nil
+	}
+	if c.nonInteractive && os.Getenv("OTTO_CREDS_PASSWORD") == "" {
+		ui.Warn(c.ui,
+			"Skipping the refresh of the status since it needs the infrastructure\n"+
+				"credentials and Otto is running non-interactively. Set\n"+
+				"OTTO_CREDS_PASSWORD to refresh it. The status below is from the\n"+
+				"directory only.")
+		return nil, nil
+	}
+
+	infra, infraCtx, err := c.infra()
+	if err != nil {
+		return nil, err
+	}
+	defer maybeClose(infra)
+	if err := c.creds(infra, infraCtx); err != nil {
+		return nil, err
+	}
+
+	var result []string
+	if infraReady {
+		missing, err := c.verifyInfra(infra, infraCtx, status)
+		if err != nil {
+			return nil, err
+		}
+		if len(missing) > 0 {
+			result = append(result, fmt.Sprintf(
+				"The directory says the infrastructure is READY, but these of its\n"+
+					"resources no longer exist: %s", strings.Join(missing, ", ")))
+		}
+	}
+
+	if deployed {
+		report, err := c.refreshHealth(infraCtx, status)
+		if err != nil {
+			return nil, err
+		}
+		if report != nil {
+			status.Health = report
+			if report.Status != app.HealthHealthy {
+				result = append(result, fmt.Sprintf(
+					"The directory says DEPLOYED, but the health check failed: the\n"+
+						"application is %s.", report.Status))
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// verifyInfra verifies the infrastructure infra and returns its missing
+// resources. Infrastructures that can't be verified have none.
+func (c *Core) verifyInfra(
+	infra infrastructure.Infrastructure,
+	infraCtx *infrastructure.Context,
+	status *statusInfo) ([]string, error) {
+	verifier, ok := infra.(infrastructure.Verifier)
+	if !ok {
+		c.logger.Info("infrastructure doesn't support verification")
+		return nil, nil
+	}
+
+	infraCtx.Outputs = status.Infra.Outputs
+	result, err := verifier.Verify(infraCtx)
+	if err == infrastructure.ErrVerifyNotSupported {
+		c.logger.Info("infrastructure doesn't support verification")
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("Error verifying the infrastructure: {{err}}", err)
+	}
+	if result == nil {
+		return nil, nil
+	}
+
+	return result.Missing, nil
+}
+
+// refreshHealth checks the health of the deploy of the root app, or
+// returns nil if the app can't check its health.
+func (c *Core) refreshHealth(
+	infraCtx *infrastructure.Context, status *statusInfo) (*app.HealthReport, error) {
+	root, err := c.appfileCompiled.Graph.Root()
+	if err != nil {
+		return nil, err
+	}
+	rootCtx, err := c.appContext(root.(*appfile.CompiledGraphVertex).File)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error loading App: {{err}}", err)
+	}
+	rootApp, err := c.app(rootCtx)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error loading App: {{err}}", err)
+	}
+	defer maybeClose(rootApp)
+
+	checker, ok := rootApp.(app.Healthchecker)
+	if !ok {
+		return nil, nil
+	}
+	if err := c.deployedContext(rootCtx, "its health can't be checked"); err != nil {
+		return nil, err
+	}
+
+	rootCtx.Shared.InfraCreds = infraCtx.Shared.InfraCreds
+	report, err := checker.Health(rootCtx)
+	if err == app.ErrHealthNotSupported {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("Error checking the health: {{err}}", err)
+	}
+
+	return c.storeHealth(report)
+}
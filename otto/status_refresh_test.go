@@ -0,0 +1,112 @@
+package otto
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/infrastructure"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreStatus_refresh(t *testing.T) {
+	core, infraMock, appMock, uiMock := testRefreshCore(t)
+	infraMock.VerifyResult = &infrastructure.VerifyResult{
+		Missing: []string{"i-1234"},
+	}
+	appMock.HealthResult = &app.HealthReport{Status: app.HealthUnhealthy}
+
+	// Without refreshing, only the directory is read
+	if err := core.Status(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if infraMock.VerifyCalled || appMock.HealthCalled {
+		t.Fatal("nothing should be checked")
+	}
+	uiMock.AssertMessageNotContains(t, "directory says")
+
+	if err := core.StatusWithOpts(&StatusOpts{Refresh: true}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if infraMock.VerifyContext.Outputs["vpc_id"] != "vpc-1" {
+		t.Fatalf("bad: %#v", infraMock.VerifyContext)
+	}
+	if appMock.HealthContext.InfraCreds["key"] != "secret" {
+		t.Fatalf("bad: %#v", appMock.HealthContext)
+	}
+	uiMock.AssertMessageContains(t, "no longer exist: i-1234")
+	uiMock.AssertMessageContains(t, "directory says DEPLOYED, but the health check failed")
+
+	// The new health report is stored
+	report, err := core.healthReport()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if report == nil || report.Status != app.HealthUnhealthy {
+		t.Fatalf("bad: %#v", report)
+	}
+}
+
+func TestCoreStatus_refreshHealthy(t *testing.T) {
+	core, infraMock, appMock, uiMock := testRefreshCore(t)
+	infraMock.VerifyResult = new(infrastructure.VerifyResult)
+	appMock.HealthResult = &app.HealthReport{Status: app.HealthHealthy}
+
+	if err := core.StatusWithOpts(&StatusOpts{Refresh: true}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !infraMock.VerifyCalled || !appMock.HealthCalled {
+		t.Fatal("everything should be checked")
+	}
+	uiMock.AssertMessageNotContains(t, "directory says")
+}
+
+func TestCoreStatus_refreshNonInteractive(t *testing.T) {
+	defer os.Setenv("OTTO_CREDS_PASSWORD", os.Getenv("OTTO_CREDS_PASSWORD"))
+	os.Setenv("OTTO_CREDS_PASSWORD", "")
+	core, infraMock, appMock, uiMock := testRefreshCore(t)
+	core.nonInteractive = true
+
+	if err := core.StatusWithOpts(&StatusOpts{Refresh: true}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if infraMock.CredsCalled || infraMock.VerifyCalled || appMock.HealthCalled {
+		t.Fatal("nothing should be checked")
+	}
+	uiMock.AssertMessageContains(t, "Skipping the refresh")
+	uiMock.AssertMessageContains(t, "DEPLOYED")
+}
+
+// testRefreshCore returns a core for the basic Appfile whose deploy and
+// infrastructure are recorded in the directory, with an infrastructure
+// that can be verified and an app that checks its health.
+func testRefreshCore(t *testing.T) (*Core, *infrastructure.MockVerifier, *app.MockHealthchecker, *ui.Mock) {
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	infraMock := &infrastructure.MockVerifier{
+		Mock: infrastructure.Mock{CredsResult: map[string]string{"key": "secret"}},
+	}
+	coreConfig.Infrastructures["test"] = func() (infrastructure.Infrastructure, error) {
+		return infraMock, nil
+	}
+	appMock := new(app.MockHealthchecker)
+	core := TestCore(t, &TestCoreOpts{
+		Config: coreConfig,
+		Path:   testPath("basic", "Appfile"),
+		App:    appMock,
+		Ui:     uiMock,
+	})
+
+	testDeployed(t, core, map[string]string{"url": "http://web.com"})
+	infra := &directory.Infra{
+		Lookup:  directory.Lookup{Infra: core.appfile.ActiveInfrastructure().Name},
+		State:   directory.InfraStateReady,
+		Outputs: map[string]string{"vpc_id": "vpc-1"},
+	}
+	if err := core.dir.PutInfra(infra); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return core, infraMock, appMock, uiMock
+}
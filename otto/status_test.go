@@ -0,0 +1,98 @@
+package otto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreStatus_loading(t *testing.T) {
+	core, dir, uiMock := testSlowStatusCore(t)
+	dir.Delay = 50 * time.Millisecond
+
+	// The loading message names the directory it waits for
+	opts := &StatusOpts{LoadingDelay: time.Millisecond}
+	if err := core.StatusWithOpts(opts); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, "Waiting for the directory (in-memory)")
+
+	// The loading message isn't shown within the delay
+	core, _, uiMock = testSlowStatusCore(t)
+	if err := core.Status(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageNotContains(t, "Waiting for the directory")
+}
+
+func TestCoreStatus_timeout(t *testing.T) {
+	core, dir, uiMock := testSlowStatusCore(t)
+	testDeployed(t, core, nil)
+	dir.BlockDeploy = make(chan struct{})
+	defer close(dir.BlockDeploy)
+
+	// The components loaded before the deploy are shown
+	opts := &StatusOpts{Timeout: 20 * time.Millisecond}
+	if err := core.StatusWithOpts(opts); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, "NOT BUILT")
+	uiMock.AssertMessageContains(t, "UNKNOWN: timed out after")
+	uiMock.AssertMessageNotContains(t, "DEPLOYED")
+	uiMock.AssertMessageContains(t, "didn't respond within")
+}
+
+func TestCoreStatus_cancel(t *testing.T) {
+	core, dir, _ := testSlowStatusCore(t)
+	dir.BlockDeploy = make(chan struct{})
+	defer close(dir.BlockDeploy)
+
+	shutdownCh := make(chan struct{})
+	time.AfterFunc(10*time.Millisecond, func() { close(shutdownCh) })
+	err := core.StatusWithOpts(&StatusOpts{ShutdownCh: shutdownCh})
+	if err != ErrStatusCancelled {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+// testSlowDirectory is a directory backend that waits before every
+// lookup of the status, and is blocked on the deploy until BlockDeploy is
+// closed if it isn't nil.
+type testSlowDirectory struct {
+	*directory.InMemBackend
+
+	Delay       time.Duration
+	BlockDeploy chan struct{}
+}
+
+func (d *testSlowDirectory) GetDev(dev *directory.Dev) (*directory.Dev, error) {
+	time.Sleep(d.Delay)
+	return d.InMemBackend.GetDev(dev)
+}
+
+func (d *testSlowDirectory) GetDeploy(deploy *directory.Deploy) (*directory.Deploy, error) {
+	time.Sleep(d.Delay)
+	if d.BlockDeploy != nil {
+		<-d.BlockDeploy
+	}
+
+	return d.InMemBackend.GetDeploy(deploy)
+}
+
+// testSlowStatusCore returns a core for the basic Appfile with a slow
+// directory, and its mock UI.
+func testSlowStatusCore(t *testing.T) (*Core, *testSlowDirectory, *ui.Mock) {
+	uiMock := new(ui.Mock)
+	dir := &testSlowDirectory{InMemBackend: directory.NewInMemBackend()}
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Directory = dir
+	core := TestCore(t, &TestCoreOpts{
+		Config: coreConfig,
+		Path:   testPath("basic", "Appfile"),
+		Ui:     uiMock,
+	})
+
+	return core, dir, uiMock
+}
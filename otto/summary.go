@@ -0,0 +1,213 @@
+package otto
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/otto/ui"
+)
+
+// pluralize returns "n thing" with the singular or plural form of thing
+// depending on n.
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, singular)
+	}
+
+	return fmt.Sprintf("%d %s", n, plural)
+}
+
+// summaryDuration truncates a duration to a precision that is reasonable
+// to show in an operation summary.
+func summaryDuration(d time.Duration) string {
+	if d < time.Second {
+		return (d - d%time.Millisecond).String()
+	}
+
+	return (d - d%time.Second).String()
+}
+
+// summarySize formats a size in bytes with the largest binary unit
+// that keeps it at least 1.
+func summarySize(n int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	v := float64(n)
+	i := 0
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	return fmt.Sprintf("%.1f %s", v, units[i])
+}
+
+// startOutput resets the output for an operation that is starting: the
+// warnings recorded so far are forgotten, and timestamped output shows
+// the time elapsed since now.
+func (c *Core) startOutput() {
+	c.warnings.Reset()
+	if c.timestamps != nil {
+		c.timestamps.Restart()
+	}
+}
+
+// finishWarnings repeats the warnings of the operation that just
+// completed with the result err, and returns the result of the operation
+// taking WarningsAsErrors into account.
+func (c *Core) finishWarnings(err error) error {
+	warnings := c.warnings.Warnings()
+	c.warnings.Reset()
+	if len(warnings) == 0 {
+		return err
+	}
+
+	c.ui.Header(fmt.Sprintf(
+		"%s during this operation:", pluralize(len(warnings), "warning", "warnings")))
+	for _, w := range warnings {
+		ui.Warn(c.ui, w)
+	}
+
+	if err == nil && c.warningsAsErrs {
+		err = fmt.Errorf(
+			"%s and warnings are treated as errors",
+			pluralize(len(warnings), "warning was output", "warnings were output"))
+	}
+
+	return err
+}
+
+// warnCountUi is a ui.Ui that counts the warnings that pass through it.
+type warnCountUi struct {
+	ui.Ui
+
+	warnings int32
+}
+
+// Log implements ui.LevelUi.
+func (u *warnCountUi) Log(level ui.Level, msg string) {
+	if level == ui.LevelWarn {
+		atomic.AddInt32(&u.warnings, 1)
+	}
+
+	ui.Log(u.Ui, level, msg)
+}
+
+// Progress implements ui.ProgressUi.
+func (u *warnCountUi) Progress(name string) ui.ProgressHandle {
+	return ui.Progress(u.Ui, name)
+}
+
+// Warnings returns the number of warnings so far.
+func (u *warnCountUi) Warnings() int {
+	return int(atomic.LoadInt32(&u.warnings))
+}
+
+// timeCompile runs f and returns the timing information for it. The Ui
+// pointed to by u is replaced while f runs so that warnings are counted.
+// This also records the "compile.<kind>" telemetry operation with the
+// given attributes and emits the UnitCompiled event.
+func (c *Core) timeCompile(
+	kind, name string,
+	attrs map[string]string,
+	u *ui.Ui, f func() error) (*CompileTiming, error) {
+	counter := &warnCountUi{Ui: *u}
+	*u = counter
+	defer func() { *u = counter.Ui }()
+
+	op := c.operation("compile."+kind, attrs)
+	start := time.Now()
+	err := f()
+	op.End(err)
+
+	duration := time.Since(start)
+	c.emit(&UnitCompiled{
+		EventInfo: c.eventInfo(c.appfile),
+		Kind:      kind,
+		Name:      name,
+		Duration:  duration,
+		Err:       err,
+	})
+
+	return &CompileTiming{
+		Kind:     kind,
+		Name:     name,
+		Duration: duration,
+		Warnings: counter.Warnings(),
+	}, err
+}
+
+// compileSummaryMax is the number of units shown in the compilation
+// summary. The slowest ones are shown.
+const compileSummaryMax = 5
+
+// compileSummary returns the summary of the timings of the compilation md,
+// with the slowest units first.
+func compileSummary(md *CompileMetadata) string {
+	timings := make([]*CompileTiming, len(md.Timings))
+	copy(timings, md.Timings)
+	sort.Stable(compileTimingsBySlowest(timings))
+
+	table := &ui.Table{
+		Headers:  []string{"KIND", "NAME", "TIME", "WARNINGS"},
+		MaxWidth: ui.TerminalWidth(),
+	}
+	for i, t := range timings {
+		if i == compileSummaryMax {
+			break
+		}
+
+		table.AddRow(
+			t.Kind, t.Name, summaryDuration(t.Duration), fmt.Sprintf("%d", t.Warnings))
+	}
+
+	result := table.String()
+	if n := len(timings) - compileSummaryMax; n > 0 {
+		result += fmt.Sprintf(
+			"\n%s not shown.", pluralize(n, "faster unit", "faster units"))
+	}
+	result += fmt.Sprintf(
+		"\nTotal time %s, with up to %s compiled in parallel.",
+		summaryDuration(md.Duration), pluralize(md.Parallelism, "app", "apps"))
+
+	return result
+}
+
+// compileTimingsBySlowest sorts timings by duration, slowest first.
+type compileTimingsBySlowest []*CompileTiming
+
+func (s compileTimingsBySlowest) Len() int           { return len(s) }
+func (s compileTimingsBySlowest) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s compileTimingsBySlowest) Less(i, j int) bool { return s[i].Duration > s[j].Duration }
+
+// peakCounter counts how many of something are active at once and the
+// most that ever were. This is safe for concurrent use.
+type peakCounter struct {
+	active int32
+	peak   int32
+}
+
+// Enter marks one more as active.
+func (c *peakCounter) Enter() {
+	n := atomic.AddInt32(&c.active, 1)
+	for {
+		peak := atomic.LoadInt32(&c.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(&c.peak, peak, n) {
+			return
+		}
+	}
+}
+
+// Exit marks one less as active.
+func (c *peakCounter) Exit() {
+	atomic.AddInt32(&c.active, -1)
+}
+
+// Peak returns the most that were ever active at once.
+func (c *peakCounter) Peak() int {
+	return int(atomic.LoadInt32(&c.peak))
+}
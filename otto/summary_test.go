@@ -0,0 +1,32 @@
+package otto
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileSummary(t *testing.T) {
+	md := &CompileMetadata{Duration: 3 * time.Second, Parallelism: 2}
+	for i := 1; i <= 7; i++ {
+		md.Timings = append(md.Timings, &CompileTiming{
+			Kind:     "dependency",
+			Name:     fmt.Sprintf("dep%d", i),
+			Duration: time.Duration(i) * time.Millisecond,
+		})
+	}
+
+	actual := compileSummary(md)
+	if strings.Contains(actual, "dep1 ") || strings.Contains(actual, "dep2 ") {
+		t.Fatalf("bad: %s", actual)
+	}
+	if strings.Index(actual, "dep7") > strings.Index(actual, "dep3") {
+		t.Fatalf("bad: %s", actual)
+	}
+	for _, v := range []string{"2 faster units not shown", "Total time 3s", "2 apps"} {
+		if !strings.Contains(actual, v) {
+			t.Fatalf("bad: %s", actual)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package otto
+
+import (
+	"sync"
+	"time"
+)
+
+// Telemetry is the interface that can be implemented to record metrics
+// about the operations that Core performs, such as how long each
+// compilation takes and how often deploys fail.
+//
+// OperationStart is called when an operation starts and the returned
+// handle's End is called when it completes with the resulting error, if
+// any. The op names currently used are "compile", "compile.infra",
+// "compile.foundation", "compile.app", "compile.dependency", "build",
+// "deploy", "dev", and "infra".
+//
+// The attributes identify what the operation is working with using
+// the keys "app", "tuple", and "infra", as well as "action" for
+// operations that take one. Attributes never contain any credentials.
+//
+// Telemetry implementations must be safe for concurrent use since
+// operations for dependencies may run concurrently.
+type Telemetry interface {
+	OperationStart(op string, attrs map[string]string) TelemetryHandle
+}
+
+// TelemetryHandle is the handle for a single operation that was started
+// with Telemetry.OperationStart.
+type TelemetryHandle interface {
+	End(err error)
+}
+
+// nullTelemetry is a Telemetry implementation that does nothing.
+type nullTelemetry struct{}
+
+func (nullTelemetry) OperationStart(string, map[string]string) TelemetryHandle {
+	return nullTelemetry{}
+}
+
+func (nullTelemetry) End(error) {}
+
+// MemoryTelemetry is a Telemetry implementation that stores all the
+// operations in memory. This is primarily useful for testing.
+type MemoryTelemetry struct {
+	lock       sync.Mutex
+	operations []*TelemetryOperation
+}
+
+// TelemetryOperation is a single completed operation recorded by
+// MemoryTelemetry.
+type TelemetryOperation struct {
+	Op       string
+	Attrs    map[string]string
+	Duration time.Duration
+	Err      error
+}
+
+// OperationStart implements Telemetry.
+func (t *MemoryTelemetry) OperationStart(
+	op string, attrs map[string]string) TelemetryHandle {
+	return &memoryTelemetryHandle{
+		telemetry: t,
+		op:        op,
+		attrs:     attrs,
+		start:     time.Now(),
+	}
+}
+
+// Operations returns the operations that have completed, in the order
+// that they completed.
+func (t *MemoryTelemetry) Operations() []*TelemetryOperation {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	result := make([]*TelemetryOperation, len(t.operations))
+	copy(result, t.operations)
+	return result
+}
+
+type memoryTelemetryHandle struct {
+	telemetry *MemoryTelemetry
+	op        string
+	attrs     map[string]string
+	start     time.Time
+}
+
+func (h *memoryTelemetryHandle) End(err error) {
+	h.telemetry.lock.Lock()
+	defer h.telemetry.lock.Unlock()
+
+	h.telemetry.operations = append(h.telemetry.operations, &TelemetryOperation{
+		Op:       h.op,
+		Attrs:    h.attrs,
+		Duration: time.Since(h.start),
+		Err:      err,
+	})
+}
+
+// operation starts a telemetry operation for the Core. The attributes
+// for the Core (the application name and infrastructure type) are
+// added to attrs.
+func (c *Core) operation(op string, attrs map[string]string) TelemetryHandle {
+	result := map[string]string{
+		"app": c.appfile.Application.Name,
+	}
+	if infra := c.appfile.ActiveInfrastructure(); infra != nil {
+		result["infra"] = infra.Type
+	}
+	for k, v := range attrs {
+		result[k] = v
+	}
+
+	return c.telemetry.OperationStart(op, result)
+}
@@ -0,0 +1,88 @@
+package otto
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/otto/helper/uuid"
+	"github.com/hashicorp/otto/ui"
+)
+
+// DefaultTempDirWarnSize is the size of the temporary directory of an
+// operation above which a warning is output when it's removed.
+const DefaultTempDirWarnSize = 1 << 30
+
+// tempDirRoot returns the directory with the temporary directories of
+// every operation.
+func (c *Core) tempDirRoot() string {
+	return filepath.Join(c.localDir, "tmp")
+}
+
+// scratchDir returns the temporary directory for the contexts of the
+// current operation, for context.Shared.TempDir. Outside an operation
+// this is a directory for the Core, which is removed by Close. The
+// directory is only created once something is stored in it.
+func (c *Core) scratchDir() string {
+	c.tempLock.Lock()
+	defer c.tempLock.Unlock()
+
+	if c.opTempDir != "" {
+		return c.opTempDir
+	}
+	if c.coreTempDir == "" {
+		c.coreTempDir = filepath.Join(c.tempDirRoot(), uuid.GenerateUUID())
+	}
+
+	return c.coreTempDir
+}
+
+// startTempDir starts the temporary directory of an operation. The
+// returned function removes it, and must be called at the end of the
+// operation whether or not it succeeded.
+func (c *Core) startTempDir() func() {
+	c.tempLock.Lock()
+	defer c.tempLock.Unlock()
+
+	c.opTempDir = filepath.Join(c.tempDirRoot(), uuid.GenerateUUID())
+	dir := c.opTempDir
+	return func() {
+		c.tempLock.Lock()
+		if c.opTempDir == dir {
+			c.opTempDir = ""
+		}
+		c.tempLock.Unlock()
+
+		c.removeTempDir(dir)
+	}
+}
+
+// removeTempDir removes the temporary directory dir, warning if it got
+// larger than the configured size.
+func (c *Core) removeTempDir(dir string) {
+	if _, err := os.Lstat(dir); err != nil {
+		return
+	}
+
+	if c.tempDirWarnSize > 0 {
+		size, _, err := dirUsage(dir)
+		if err == nil && size > c.tempDirWarnSize {
+			ui.Warn(c.ui, fmt.Sprintf(
+				"The temporary files of this operation used %s, more than the\n"+
+					"%s they're expected to. This may be a plugin that stores\n"+
+					"more than scratch data in its temporary directory.",
+				summarySize(size), summarySize(c.tempDirWarnSize)))
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		c.logger.Warn("error removing temporary directory", "dir", dir, "err", err)
+		ui.Warn(c.ui, fmt.Sprintf(
+			"Error removing the temporary directory %s: %s", dir, err))
+		return
+	}
+
+	// Remove the root too once no operation has a directory in it. This
+	// fails if another one does, which is fine.
+	os.Remove(c.tempDirRoot())
+}
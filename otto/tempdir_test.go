@@ -0,0 +1,102 @@
+package otto
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreTempDir_failedOperation(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+
+	var dir string
+	appMock.CompileFunc = func(ctx *app.Context) (*app.CompileResult, error) {
+		var err error
+		dir, err = ctx.TempDir()
+		if err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "scratch"), []byte("data"), 0644); err != nil {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("compile failed")
+	}
+
+	err := core.Compile()
+	if err == nil || !strings.Contains(err.Error(), "compile failed") {
+		t.Fatalf("bad: %v", err)
+	}
+	if !strings.HasPrefix(dir, core.tempDirRoot()) {
+		t.Fatalf("bad: %s", dir)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("should be removed: %v", err)
+	}
+	if _, err := os.Stat(core.tempDirRoot()); !os.IsNotExist(err) {
+		t.Fatalf("should be removed: %v", err)
+	}
+}
+
+func TestCoreTempDir_warnSize(t *testing.T) {
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	coreConfig.TempDirWarnSize = 2
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+
+	appMock.CompileFunc = func(ctx *app.Context) (*app.CompileResult, error) {
+		dir, err := ctx.TempDir()
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, ioutil.WriteFile(filepath.Join(dir, "scratch"), []byte("data"), 0644)
+	}
+
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uiMock.AssertMessageContains(t, "temporary files of this operation used")
+}
+
+func TestCoreTempDir_close(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	core := testCore(t, coreConfig)
+
+	// Outside an operation the directory is the one of the Core
+	ctx, err := core.appContext(core.appfile)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	dir, err := ctx.TempDir()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	other, err := ctx.TempDir()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if dir == other {
+		t.Fatalf("bad: %s", dir)
+	}
+
+	if err := core.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := os.Stat(core.tempDirRoot()); !os.IsNotExist(err) {
+		t.Fatalf("should be removed: %v", err)
+	}
+}
@@ -1,7 +1,9 @@
 package otto
 
 import (
+	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 
 	"github.com/hashicorp/otto/appfile"
@@ -63,3 +65,51 @@ func TestAppfile(t TestT, path string) *appfile.Compiled {
 
 	return result
 }
+
+// TestAppfileDeps writes an Appfile for the TestAppTuple app "root" in
+// dir with n dependencies, each in its own directory, and returns its
+// path. With n set to zero the Appfile has no dependencies. The result is
+// meant to be loaded with TestAppfile.
+func TestAppfileDeps(t TestT, dir string, n int) string {
+	const project = `
+project {
+    name = "%[1]s"
+    infrastructure = "test"
+}
+
+infrastructure "test" {
+    type = "test"
+    flavor = "test"
+}
+`
+
+	root := "application {\n    name = \"root\"\n    type = \"test\"\n"
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("dep%d", i)
+		root += fmt.Sprintf("\n    dependency {\n        source = \"./%s\"\n    }\n", name)
+
+		depDir := filepath.Join(dir, name)
+		if err := os.MkdirAll(depDir, 0755); err != nil {
+			t.Fatal("err: ", err)
+		}
+		data := fmt.Sprintf(
+			"application {\n    name = \"%[1]s\"\n    type = \"test\"\n}\n"+project, name)
+		if err := ioutil.WriteFile(filepath.Join(depDir, "Appfile"), []byte(data), 0644); err != nil {
+			t.Fatal("err: ", err)
+		}
+
+		// Dependencies must have an ID
+		id := fmt.Sprintf("00000000-0000-0000-0000-%012d\n", i)
+		if err := ioutil.WriteFile(filepath.Join(depDir, ".ottoid"), []byte(id), 0644); err != nil {
+			t.Fatal("err: ", err)
+		}
+	}
+	root += "}\n" + fmt.Sprintf(project, "root")
+
+	path := filepath.Join(dir, "Appfile")
+	if err := ioutil.WriteFile(path, []byte(root), 0644); err != nil {
+		t.Fatal("err: ", err)
+	}
+
+	return path
+}
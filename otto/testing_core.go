@@ -13,21 +13,43 @@ import (
 // has all fields set to "test".
 var TestAppTuple = app.Tuple{"test", "test", "test"}
 
+// TestCredsPassword is the password that the Ui of TestCoreOpts answers
+// when the Core asks for the password of the infrastructure credentials.
+const TestCredsPassword = "hunter2"
+
 // TestCoreOpts is a specialized struct that is used to create a Core,
 // focused on the most common usage for tests.
+//
+// TestCore, TestCoreConfig, the TestApp, TestInfra and TestFoundation
+// mocks and the TestAppfile fixtures are the supported way to test plugins
+// against a real Core. Otto's own tests use them too.
 type TestCoreOpts struct {
+	// Config is the config to create the core with. If this is nil, then
+	// TestCoreConfig is used.
+	Config *CoreConfig
+
 	// Path is the path to an Appfile to compile
 	Path string
 
 	// App to register with the TestAppTuple as a fixed result
 	App app.App
+
+	// Ui, if set, is the Ui of the core. It answers the prompt for the
+	// password of the infrastructure credentials with TestCredsPassword.
+	Ui *ui.Mock
 }
 
 // TestCore returns a *Core for testing. If TestCoreOpts is nil then
 // this is equivalent to creating a core with TestCoreConfig set.
 func TestCore(t TestT, config *TestCoreOpts) *Core {
 	// Get the base config because we'll need this anyways
-	coreConfig := TestCoreConfig(t)
+	var coreConfig *CoreConfig
+	if config != nil {
+		coreConfig = config.Config
+	}
+	if coreConfig == nil {
+		coreConfig = TestCoreConfig(t)
+	}
 
 	// If a config is set, then use that to do things
 	if config != nil {
@@ -40,6 +62,11 @@ func TestCore(t TestT, config *TestCoreOpts) *Core {
 				return config.App, nil
 			}
 		}
+
+		if config.Ui != nil {
+			config.Ui.AddAnswer("^creds_password$", TestCredsPassword)
+			coreConfig.Ui = config.Ui
+		}
 	}
 
 	// Create the core!
@@ -52,6 +79,9 @@ func TestCore(t TestT, config *TestCoreOpts) *Core {
 }
 
 // TestCoreConfig returns a CoreConfig that can be used for testing.
+// The directory is in memory, and the "test" infrastructure and the
+// TestAppTuple app are mocks that can be replaced with TestInfra and
+// TestApp.
 func TestCoreConfig(t TestT) *CoreConfig {
 	// Temporary directory for data
 	td, err := ioutil.TempDir("", "otto")
@@ -64,7 +94,7 @@ func TestCoreConfig(t TestT) *CoreConfig {
 		DataDir:    filepath.Join(td, "data"),
 		LocalDir:   filepath.Join(td, "local"),
 		CompileDir: filepath.Join(td, "compile"),
-		Directory:  &directory.BoltBackend{Dir: filepath.Join(td, "directory")},
+		Directory:  directory.NewInMemBackend(),
 		Ui:         &ui.Logged{Ui: new(ui.Mock)},
 	}
 
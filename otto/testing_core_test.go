@@ -0,0 +1,69 @@
+package otto
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestTestCore(t *testing.T) {
+	td, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	coreConfig := TestCoreConfig(t)
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	infraMock := TestInfra(t, "test", coreConfig)
+	core := TestCore(t, &TestCoreOpts{
+		Config: coreConfig,
+		Path:   TestAppfileDeps(t, td, 0),
+	})
+	defer core.Close()
+
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(infraMock.Calls, []string{"Compile"}) {
+		t.Fatalf("bad: %#v", infraMock.Calls)
+	}
+	expected := []string{"Compile", "Close"}
+	if !reflect.DeepEqual(appMock.Calls, expected) {
+		t.Fatalf("bad: %#v", appMock.Calls)
+	}
+}
+
+func TestTestCore_failAt(t *testing.T) {
+	td, err := ioutil.TempDir("", "otto")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	coreConfig := TestCoreConfig(t)
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	appMock.CompileErr = errors.New("compile failed")
+	core := TestCore(t, &TestCoreOpts{
+		Config: coreConfig,
+		Path:   TestAppfileDeps(t, td, 2),
+	})
+	defer core.Close()
+
+	if err := core.Compile(); err == nil {
+		t.Fatal("should error")
+	}
+
+	// The root isn't compiled once a dependency fails
+	var compiles int
+	for _, c := range appMock.Calls {
+		if c == "Compile" {
+			compiles++
+		}
+	}
+	if compiles == 0 || compiles > 2 {
+		t.Fatalf("bad: %#v", appMock.Calls)
+	}
+}
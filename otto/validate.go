@@ -0,0 +1,113 @@
+package otto
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/foundation"
+	"github.com/hashicorp/otto/infrastructure"
+)
+
+// Validate checks the configuration of the infrastructure, foundations,
+// and apps that implement the optional Validator interfaces, without
+// compiling anything. Everything is validated and the errors are returned
+// together. Implementations that don't implement Validator are skipped.
+//
+// Compile does the same validation, but stops at the first error.
+func (c *Core) Validate() (err error) {
+	c.startOutput()
+	op := c.operation("validate", nil)
+	defer c.cacheAppContexts()()
+	defer func() {
+		err = c.finishWarnings(err)
+		op.End(err)
+	}()
+
+	infra, infraCtx, err := c.infra()
+	if err != nil {
+		return err
+	}
+	defer maybeClose(infra)
+
+	foundations, foundationCtxs, err := c.foundations()
+	if err != nil {
+		return err
+	}
+	for _, f := range foundations {
+		defer maybeClose(f)
+	}
+
+	var result error
+	if err := c.validateInfra(infra, infraCtx, foundations, foundationCtxs); err != nil {
+		result = multierror.Append(result, err)
+	}
+
+	var resultLock sync.Mutex
+	err = c.walk(c.serialWalk, func(impl app.App, ctx *app.Context, root bool) error {
+		if err := c.validateApp(impl, ctx); err != nil {
+			resultLock.Lock()
+			defer resultLock.Unlock()
+			result = multierror.Append(result, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		result = multierror.Append(result, err)
+	}
+
+	return result
+}
+
+// validateInfra validates the infrastructure and the foundations if they
+// implement Validator. The errors of all of them are returned together.
+func (c *Core) validateInfra(
+	infra infrastructure.Infrastructure,
+	infraCtx *infrastructure.Context,
+	foundations []foundation.Foundation,
+	foundationCtxs []*foundation.Context) error {
+	var result error
+	if v, ok := infra.(infrastructure.Validator); ok {
+		c.logger.Info("validating infra", "infra", infraCtx.Infra.Name)
+		if err := v.Validate(infraCtx); err != nil {
+			result = multierror.Append(result, errwrap.Wrapf(fmt.Sprintf(
+				"Error validating infrastructure '%s': {{err}}", infraCtx.Infra.Name), err))
+		}
+	}
+
+	for i, f := range foundations {
+		v, ok := f.(foundation.Validator)
+		if !ok {
+			continue
+		}
+
+		ctx := foundationCtxs[i]
+		c.logger.Info("validating foundation", "foundation", ctx.Tuple.Type)
+		if err := v.Validate(ctx); err != nil {
+			result = multierror.Append(result, errwrap.Wrapf(fmt.Sprintf(
+				"Error validating foundation '%s': {{err}}", ctx.Tuple.Type), err))
+		}
+	}
+
+	return result
+}
+
+// validateApp validates the app if it implements app.Validator.
+func (c *Core) validateApp(impl app.App, ctx *app.Context) error {
+	v, ok := impl.(app.Validator)
+	if !ok {
+		return nil
+	}
+
+	name := ctx.Appfile.Application.Name
+	c.logger.Info("validating app", "app", name)
+	if err := v.Validate(ctx); err != nil {
+		return errwrap.Wrapf(fmt.Sprintf(
+			"Error validating app '%s': {{err}}", name), err)
+	}
+
+	return nil
+}
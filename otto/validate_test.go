@@ -0,0 +1,91 @@
+package otto
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/infrastructure"
+)
+
+func TestCoreValidate(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("dev-deps", "Appfile"))
+	appMock := new(app.MockValidator)
+	appMock.ValidateErr = errors.New("missing customization")
+	coreConfig.Apps[TestAppTuple] = func() (app.App, error) {
+		return appMock, nil
+	}
+	infraMock := new(infrastructure.MockValidator)
+	infraMock.ValidateErr = errors.New("unknown region")
+	coreConfig.Infrastructures["test"] = func() (infrastructure.Infrastructure, error) {
+		return infraMock, nil
+	}
+	core := testCore(t, coreConfig)
+
+	// Everything is validated and nothing is compiled
+	err := core.Validate()
+	if err == nil {
+		t.Fatal("should error")
+	}
+	for _, expected := range []string{"unknown region", "app 'foo'", "app 'bar'"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("bad: %s", err)
+		}
+	}
+	if appMock.CompileCalled || infraMock.CompileCalled {
+		t.Fatal("compile should not be called")
+	}
+
+	infraMock.ValidateErr = nil
+	appMock.ValidateErr = nil
+	if err := core.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestCoreCompile_validate(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	appMock := new(app.MockValidator)
+	coreConfig.Apps[TestAppTuple] = func() (app.App, error) {
+		return appMock, nil
+	}
+	core := testCore(t, coreConfig)
+
+	// A validation error fails before the app is compiled
+	appMock.ValidateErr = errors.New("unsupported runtime")
+	err := core.Compile()
+	if err == nil || !strings.Contains(err.Error(), "unsupported runtime") {
+		t.Fatalf("err: %v", err)
+	}
+	if appMock.CompileCalled {
+		t.Fatal("compile should not be called")
+	}
+
+	appMock.ValidateErr = nil
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.ValidateCalled || !appMock.CompileCalled {
+		t.Fatalf("bad: %#v", appMock.Calls)
+	}
+}
+
+func TestCoreCompile_validateNotImplemented(t *testing.T) {
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	appMock := TestApp(t, TestAppTuple, coreConfig)
+	core := testCore(t, coreConfig)
+
+	if err := core.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := core.Compile(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.CompileCalled {
+		t.Fatal("compile should be called")
+	}
+}
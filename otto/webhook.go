@@ -0,0 +1,163 @@
+package otto
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/otto/directory"
+	"github.com/hashicorp/otto/ui"
+)
+
+// Webhook is a URL that is notified when a deploy or an infrastructure
+// change completes, successfully or not.
+type Webhook struct {
+	// URL is the URL that the payload is POSTed to.
+	URL string
+
+	// Template, if set, is a text/template that renders the body of the
+	// request. The template is executed with a WebhookPayload and has a
+	// "json" function that JSON-encodes its argument. If this isn't set,
+	// the body is the JSON encoding of the WebhookPayload.
+	Template string
+}
+
+// WebhookPayload is the data sent to a Webhook. This never contains any
+// credentials or other secrets.
+type WebhookPayload struct {
+	App         string `json:"app"`
+	Project     string `json:"project"`
+	Environment string `json:"environment"`
+	Action      string `json:"action"`
+	Result      string `json:"result"`
+	Duration    string `json:"duration"`
+	URL         string `json:"url,omitempty"`
+}
+
+var (
+	// webhookTimeout is the timeout for a single webhook request.
+	webhookTimeout = 10 * time.Second
+
+	// webhookRetryWait is the time to wait before retrying a failed
+	// webhook request. Requests are retried once.
+	webhookRetryWait = 1 * time.Second
+)
+
+// notify sends the result of the given action to all the configured
+// webhooks. Errors sending to a webhook are output as warnings and never
+// fail the operation.
+func (c *Core) notify(action string, start time.Time, err error) {
+	if len(c.webhooks) == 0 {
+		return
+	}
+
+	payload := &WebhookPayload{
+		App:         c.appfile.Application.Name,
+		Project:     c.appfile.Project.Name,
+		Environment: c.environment,
+		Action:      action,
+		Result:      "success",
+		Duration:    summaryDuration(time.Since(start)),
+	}
+	if err != nil {
+		payload.Result = "failure"
+	}
+	if action == "deploy" && err == nil {
+		payload.URL = c.deployURL()
+	}
+
+	for _, hook := range c.webhooks {
+		body, err := hook.render(payload)
+		if err != nil {
+			ui.Warn(c.ui, fmt.Sprintf(
+				"Error rendering webhook payload for %s: %s", hook.URL, err))
+			continue
+		}
+
+		if c.webhookDryRun {
+			ui.Info(c.ui, fmt.Sprintf(
+				"Webhook payload for %s:\n%s", hook.URL, body))
+			continue
+		}
+
+		if err := hook.send(body); err != nil {
+			ui.Warn(c.ui, fmt.Sprintf(
+				"Error sending webhook to %s: %s", hook.URL, err))
+		}
+	}
+}
+
+// deployURL returns the URL of the deployed application as recorded in
+// the directory, or the empty string if it isn't known.
+func (c *Core) deployURL() string {
+	infra := c.appfile.ActiveInfrastructure()
+	if infra == nil {
+		return ""
+	}
+
+	deploy, err := c.dir.GetDeploy(&directory.Deploy{
+		Lookup: c.deployLookup(c.appfile.ID)})
+	if err != nil {
+		c.logger.Warn("error looking up deploy for webhook", "err", err)
+		return ""
+	}
+	if deploy == nil {
+		return ""
+	}
+
+	return deploy.Deploy["url"]
+}
+
+func (w *Webhook) render(payload *WebhookPayload) ([]byte, error) {
+	if w.Template == "" {
+		return json.Marshal(payload)
+	}
+
+	tpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			raw, err := json.Marshal(v)
+			return string(raw), err
+		},
+	}).Parse(w.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, payload); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (w *Webhook) send(body []byte) error {
+	client := cleanhttp.DefaultClient()
+	client.Timeout = webhookTimeout
+
+	var err error
+	for i := 0; i < 2; i++ {
+		if i > 0 {
+			time.Sleep(webhookRetryWait)
+		}
+
+		var resp *http.Response
+		resp, err = client.Post(w.URL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+
+		err = fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	return err
+}
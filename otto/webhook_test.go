@@ -0,0 +1,126 @@
+package otto
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestCoreNotify(t *testing.T) {
+	var payload WebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}))
+	defer server.Close()
+
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Webhooks = []*Webhook{&Webhook{URL: server.URL}}
+	coreConfig.Environment = "staging"
+	core := testCore(t, coreConfig)
+
+	core.notify("deploy", time.Now(), errors.New("failed"))
+
+	if payload.App != coreConfig.Appfile.File.Application.Name {
+		t.Fatalf("bad: %#v", payload)
+	}
+	if payload.Environment != "staging" {
+		t.Fatalf("bad: %#v", payload)
+	}
+	if payload.Action != "deploy" || payload.Result != "failure" {
+		t.Fatalf("bad: %#v", payload)
+	}
+}
+
+func TestCoreNotify_retry(t *testing.T) {
+	defer func(v time.Duration) { webhookRetryWait = v }(webhookRetryWait)
+	webhookRetryWait = 0
+
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) == 1 {
+			w.WriteHeader(500)
+		}
+	}))
+	defer server.Close()
+
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	coreConfig.Webhooks = []*Webhook{&Webhook{URL: server.URL}}
+	core := testCore(t, coreConfig)
+
+	core.notify("infra", time.Now(), nil)
+
+	if v := atomic.LoadInt32(&count); v != 2 {
+		t.Fatalf("bad: %d", v)
+	}
+	uiMock.AssertMessageNotContains(t, "Error sending webhook")
+}
+
+func TestCoreNotify_dryRun(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+	}))
+	defer server.Close()
+
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	coreConfig.Webhooks = []*Webhook{&Webhook{URL: server.URL}}
+	coreConfig.WebhookDryRun = true
+	core := testCore(t, coreConfig)
+
+	core.notify("deploy", time.Now(), nil)
+
+	if v := atomic.LoadInt32(&count); v != 0 {
+		t.Fatalf("bad: %d", v)
+	}
+	uiMock.AssertMessageContains(t, `"result":"success"`)
+	uiMock.AssertMessageContains(t, `"environment":"default"`)
+}
+
+func TestWebhookRender_template(t *testing.T) {
+	w := &Webhook{Template: `{"text": {{json .App}}}`}
+	actual, err := w.render(&WebhookPayload{App: `fo"o`})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := `{"text": "fo\"o"}`
+	if string(actual) != expected {
+		t.Fatalf("bad: %s", actual)
+	}
+}
+
+func TestCoreNotify_unreachable(t *testing.T) {
+	defer func(v time.Duration) { webhookRetryWait = v }(webhookRetryWait)
+	webhookRetryWait = 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	}))
+	defer server.Close()
+
+	uiMock := new(ui.Mock)
+	coreConfig := TestCoreConfig(t)
+	coreConfig.Appfile = TestAppfile(t, testPath("basic", "Appfile"))
+	coreConfig.Ui = uiMock
+	coreConfig.Webhooks = []*Webhook{&Webhook{URL: server.URL}}
+	core := testCore(t, coreConfig)
+
+	core.notify("deploy", time.Now(), nil)
+
+	uiMock.AssertMessageContains(t, "Error sending webhook")
+}
@@ -0,0 +1,63 @@
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// APIVersionMajor and APIVersionMinor are the version of the plugin API
+// that this Otto speaks. The major version changes when the structures
+// exchanged with plugins, such as app.Context and CompileResult, change
+// in ways that break older plugins. The minor version changes when
+// something is added that older plugins just don't use.
+const (
+	APIVersionMajor = 1
+	APIVersionMinor = 3
+)
+
+// APIVersion is output along with the RPC address during the handshake.
+// The plugin client validates this with CheckAPIVersion.
+var APIVersion = fmt.Sprintf("%d.%d", APIVersionMajor, APIVersionMinor)
+
+// ParseAPIVersion parses an API version in the format "major.minor" that
+// a plugin outputs during the handshake. Plugins built before there were
+// minor versions output only the major version, which is minor version 0.
+func ParseAPIVersion(v string) (major int, minor int, err error) {
+	parts := strings.SplitN(v, ".", 2)
+	major, err = strconv.Atoi(parts[0])
+	if err == nil && len(parts) > 1 {
+		minor, err = strconv.Atoi(parts[1])
+	}
+	if err != nil || major < 0 || minor < 0 {
+		return 0, 0, fmt.Errorf("invalid plugin API version: %q", v)
+	}
+
+	return major, minor, nil
+}
+
+// CheckAPIVersion checks if the plugin name, which speaks the API version
+// v, can be used by this Otto. This returns an error if the major versions
+// differ. If only the minor versions differ, the plugin can be used and
+// this returns a warning that describes the skew.
+func CheckAPIVersion(name, v string) (string, error) {
+	major, minor, err := ParseAPIVersion(v)
+	if err != nil {
+		return "", fmt.Errorf("plugin %s: %s", name, err)
+	}
+
+	if major != APIVersionMajor {
+		return "", fmt.Errorf(
+			"plugin %s speaks API v%d, this otto speaks v%d. The plugin must be\n"+
+				"built for this version of otto.",
+			name, major, APIVersionMajor)
+	}
+	if minor != APIVersionMinor {
+		return fmt.Sprintf(
+			"plugin %s speaks API v%d.%d, this otto speaks v%d.%d. It should\n"+
+				"work, but updating it is recommended.",
+			name, major, minor, APIVersionMajor, APIVersionMinor), nil
+	}
+
+	return "", nil
+}
@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseAPIVersion(t *testing.T) {
+	cases := []struct {
+		Input        string
+		Major, Minor int
+		Err          bool
+	}{
+		{"1", 1, 0, false},
+		{"1.2", 1, 2, false},
+		{"12.0", 12, 0, false},
+		{"", 0, 0, true},
+		{"v1", 0, 0, true},
+		{"1.x", 0, 0, true},
+		{"-1.0", 0, 0, true},
+	}
+
+	for _, tc := range cases {
+		major, minor, err := ParseAPIVersion(tc.Input)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%q err: %s", tc.Input, err)
+		}
+		if major != tc.Major || minor != tc.Minor {
+			t.Fatalf("%q bad: %d.%d", tc.Input, major, minor)
+		}
+	}
+}
+
+func TestCheckAPIVersion(t *testing.T) {
+	cases := []struct {
+		Version string
+		Warning string
+		Err     string
+	}{
+		{
+			APIVersion,
+			"",
+			"",
+		},
+		{
+			fmt.Sprintf("%d.%d", APIVersionMajor, APIVersionMinor+1),
+			fmt.Sprintf("plugin foo speaks API v%d.%d, this otto speaks v%d.%d",
+				APIVersionMajor, APIVersionMinor+1, APIVersionMajor, APIVersionMinor),
+			"",
+		},
+		{
+			fmt.Sprintf("%d", APIVersionMajor),
+			fmt.Sprintf("plugin foo speaks API v%d.0, this otto speaks v%d.%d",
+				APIVersionMajor, APIVersionMajor, APIVersionMinor),
+			"",
+		},
+		{
+			fmt.Sprintf("%d.%d", APIVersionMajor+1, APIVersionMinor),
+			"",
+			fmt.Sprintf("plugin foo speaks API v%d, this otto speaks v%d",
+				APIVersionMajor+1, APIVersionMajor),
+		},
+		{
+			fmt.Sprintf("%d.%d", APIVersionMajor-1, APIVersionMinor),
+			"",
+			fmt.Sprintf("plugin foo speaks API v%d, this otto speaks v%d",
+				APIVersionMajor-1, APIVersionMajor),
+		},
+		{
+			"bad",
+			"",
+			"invalid plugin API version",
+		},
+	}
+
+	for _, tc := range cases {
+		warning, err := CheckAPIVersion("foo", tc.Version)
+		if tc.Err == "" && err != nil {
+			t.Fatalf("%s err: %s", tc.Version, err)
+		}
+		if tc.Err != "" && (err == nil || !strings.Contains(err.Error(), tc.Err)) {
+			t.Fatalf("%s err: %v", tc.Version, err)
+		}
+		if !strings.HasPrefix(warning, tc.Warning) || (tc.Warning == "") != (warning == "") {
+			t.Fatalf("%s bad: %q", tc.Version, warning)
+		}
+	}
+}
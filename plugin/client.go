@@ -36,6 +36,7 @@ type Client struct {
 	doneLogging chan struct{}
 	l           sync.Mutex
 	address     net.Addr
+	apiVersion  string
 	client      *pluginrpc.Client
 }
 
@@ -173,6 +174,14 @@ func (c *Client) Client() (*pluginrpc.Client, error) {
 	return c.client, nil
 }
 
+// APIVersion returns the version of the plugin API that the plugin
+// speaks, which is only known once it is started.
+func (c *Client) APIVersion() string {
+	c.l.Lock()
+	defer c.l.Unlock()
+	return c.apiVersion
+}
+
 // Tells whether or not the underlying process has exited.
 func (c *Client) Exited() bool {
 	c.l.Lock()
@@ -199,6 +208,24 @@ func (c *Client) Kill() {
 	<-c.doneLogging
 }
 
+// Close closes the RPC connection to the plugin and then ends the
+// subprocess just like Kill. This implements io.Closer so that the client
+// can be shut down along with what uses it, such as an otto.Core.
+func (c *Client) Close() error {
+	c.l.Lock()
+	client := c.client
+	c.client = nil
+	c.l.Unlock()
+
+	var err error
+	if client != nil {
+		err = client.Close()
+	}
+
+	c.Kill()
+	return err
+}
+
 // Starts the underlying subprocess, communicating with it to negotiate
 // a port for RPC connections, and returning the address to connect via RPC.
 //
@@ -325,12 +352,17 @@ func (c *Client) Start() (addr net.Addr, err error) {
 			return
 		}
 
-		// Test the API version
-		if parts[0] != APIVersion {
-			err = fmt.Errorf("Incompatible API version with plugin. "+
-				"Plugin version: %s, Ours: %s", parts[0], APIVersion)
+		// Test the API version. A different minor version still works,
+		// so that is only logged here.
+		var warning string
+		warning, err = CheckAPIVersion(filepath.Base(cmd.Path), parts[0])
+		if err != nil {
 			return
 		}
+		if warning != "" {
+			log.Printf("[WARN] %s", warning)
+		}
+		c.apiVersion = parts[0]
 
 		switch parts[1] {
 		case "tcp":
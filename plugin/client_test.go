@@ -2,8 +2,10 @@ package plugin
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -54,6 +56,23 @@ func TestClientStart_badVersion(t *testing.T) {
 	if err == nil {
 		t.Fatal("err should not be nil")
 	}
+	expected := fmt.Sprintf("speaks API v%d, this otto speaks v%d",
+		APIVersionMajor+1, APIVersionMajor)
+	if !strings.Contains(err.Error(), expected) {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestClientStart_oldMinorVersion(t *testing.T) {
+	c := NewClient(&ClientConfig{Cmd: helperProcess("old-version")})
+	defer c.Kill()
+
+	if _, err := c.Start(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v := c.APIVersion(); v != strconv.Itoa(APIVersionMajor) {
+		t.Fatalf("bad: %s", v)
+	}
 }
 
 func TestClient_Start_Timeout(t *testing.T) {
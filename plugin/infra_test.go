@@ -0,0 +1,32 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInfra(t *testing.T) {
+	c := NewClient(&ClientConfig{Cmd: helperProcess("infra")})
+	defer c.Kill()
+
+	client, err := c.Client()
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	meta, err := client.Meta()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if meta.App || !reflect.DeepEqual(meta.Infras, []string{"test"}) {
+		t.Fatalf("bad: %#v", meta)
+	}
+
+	infra, err := client.Infra()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual := infra.Flavors(); !reflect.DeepEqual(actual, []string{"simple"}) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
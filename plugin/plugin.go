@@ -7,4 +7,20 @@
 //
 // plugin.Serve fully manages listeners to expose an RPC server from a binary
 // that plugin.Client can connect to.
+//
+// This is the package that plugin authors import. A plugin is a binary
+// named "otto-plugin-*" whose main function calls Serve with the app,
+// infrastructure, or foundation it implements:
+//
+//	func main() {
+//		plugin.Serve(&plugin.ServeOpts{
+//			InfraFunc: func() infrastructure.Infrastructure {
+//				return new(MyInfra)
+//			},
+//			Infras: []string{"my-cloud"},
+//		})
+//	}
+//
+// The Ui and Directory of the contexts that are given to the plugin talk
+// back to the Otto process over the plugin connection.
 package plugin
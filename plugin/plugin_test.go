@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/infrastructure"
 	pluginrpc "github.com/hashicorp/otto/rpc"
 )
 
@@ -53,12 +54,22 @@ func TestHelperProcess(*testing.T) {
 	cmd, args := args[0], args[1:]
 	switch cmd {
 	case "bad-version":
-		fmt.Printf("%s1|tcp|:1234\n", APIVersion)
+		fmt.Printf("%d.0|tcp|:1234\n", APIVersionMajor+1)
+		<-make(chan int)
+	case "old-version":
+		fmt.Printf("%d|tcp|127.0.0.1:1234\n", APIVersionMajor)
 		<-make(chan int)
 	case "app":
 		Serve(&ServeOpts{
 			AppFunc: testAppFixed(new(app.Mock)),
 		})
+	case "infra":
+		Serve(&ServeOpts{
+			InfraFunc: func() infrastructure.Infrastructure {
+				return &infrastructure.Mock{FlavorsResult: []string{"simple"}}
+			},
+			Infras: []string{"test"},
+		})
 	case "invalid-rpc-address":
 		fmt.Println("lolinvalid")
 	case "mock":
@@ -12,23 +12,33 @@ import (
 	"strconv"
 	"sync/atomic"
 
+	"github.com/hashicorp/otto/foundation"
 	pluginrpc "github.com/hashicorp/otto/rpc"
 )
 
-// The APIVersion is outputted along with the RPC address. The plugin
-// client validates this API version and will show an error if it doesn't
-// know how to speak it.
-const APIVersion = "1"
-
 // The "magic cookie" is used to verify that the user intended to
 // actually run this binary. If this cookie isn't present as an
 // environmental variable, then we bail out early with an error.
 const MagicCookieKey = "OTTO_PLUGIN_MAGIC_COOKIE"
 const MagicCookieValue = "11aab7ff21cb9ff7b0e9975d53f17a8dab571eac9b5ff0191730046698f07b7f"
 
-// ServeOpts configures what sorts of plugins are served.
+// ServeOpts configures what sorts of plugins are served. A single plugin
+// can serve any combination of an app, an infrastructure, and a
+// foundation.
 type ServeOpts struct {
+	// AppFunc creates the app. The tuples it implements come from its
+	// Meta.
 	AppFunc pluginrpc.AppFunc
+
+	// InfraFunc creates the infrastructure, which implements the
+	// infrastructure types in Infras, such as "aws".
+	InfraFunc pluginrpc.InfraFunc
+	Infras    []string
+
+	// FoundationFunc creates the foundation, which implements the tuples
+	// in Foundations.
+	FoundationFunc pluginrpc.FoundationFunc
+	Foundations    foundation.TupleSlice
 }
 
 // Serve serves the plugins given by ServeOpts.
@@ -71,9 +81,13 @@ func Serve(opts *ServeOpts) {
 
 	// Create the RPC server to dispense
 	server := &pluginrpc.Server{
-		AppFunc: opts.AppFunc,
-		Stdout:  stdout_r,
-		Stderr:  stderr_r,
+		AppFunc:        opts.AppFunc,
+		InfraFunc:      opts.InfraFunc,
+		Infras:         opts.Infras,
+		FoundationFunc: opts.FoundationFunc,
+		Foundations:    opts.Foundations,
+		Stdout:         stdout_r,
+		Stderr:         stderr_r,
 	}
 
 	// Output the address and service name to stdout so that core can bring it up.
@@ -1,10 +1,14 @@
 package rpc
 
 import (
+	"io"
+	"io/ioutil"
+	"log"
 	"net/rpc"
 
 	"github.com/hashicorp/otto/app"
 	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/foundation"
 )
 
 // App is an implementation of app.App that communicates over RPC.
@@ -146,6 +150,321 @@ func (c *App) DevDep(dst, src *app.Context) (*app.DevDep, error) {
 	return resp.Result, nil
 }
 
+func (c *App) DevDepRefresh(dst, src *app.Context) error {
+	var resp AppDevDepRefreshResponse
+	args := AppDevDepArgs{
+		ContextDst: dst,
+		ContextSrc: src,
+	}
+
+	// Serve the shared context data
+	serveContext(c.Broker, &dst.Shared, &args.ContextDstShared)
+	serveContext(c.Broker, &src.Shared, &args.ContextSrcShared)
+
+	// Call
+	err := c.Client.Call(c.Name+".DevDepRefresh", &args, &resp)
+	if err == nil {
+		if resp.NotSupported {
+			err = app.ErrDevDepRefreshNotSupported
+		} else if resp.Error != nil {
+			err = resp.Error
+		}
+	}
+
+	return err
+}
+
+// Validate calls Validate on the app if it implements app.Validator.
+// Apps that don't, including plugins built before Validate existed, have
+// nothing to validate.
+func (c *App) Validate(ctx *app.Context) error {
+	var resp AppSimpleResponse
+	args := AppContextArgs{Context: ctx}
+
+	// Serve the shared context data
+	serveContext(c.Broker, &ctx.Shared, &args.ContextSharedArgs)
+
+	// Call
+	err := c.Client.Call(c.Name+".Validate", &args, &resp)
+	if isMethodNotFound(err) {
+		return nil
+	}
+	if err == nil && resp.Error != nil {
+		err = resp.Error
+	}
+
+	return err
+}
+
+func (c *App) FoundationConfig(ctx *app.Context) (*foundation.Config, error) {
+	var resp AppFoundationConfigResponse
+	args := AppContextArgs{Context: ctx}
+
+	// Serve the shared context data
+	serveContext(c.Broker, &ctx.Shared, &args.ContextSharedArgs)
+
+	// Call. Plugins built before this existed don't have the method at all.
+	err := c.Client.Call(c.Name+".FoundationConfig", &args, &resp)
+	if isMethodNotFound(err) || (err == nil && resp.NotSupported) {
+		return nil, app.ErrFoundationConfigNotSupported
+	}
+	if err == nil && resp.Error != nil {
+		err = resp.Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result, nil
+}
+
+func (c *App) Health(ctx *app.Context) (*app.HealthReport, error) {
+	var resp AppHealthResponse
+	args := AppContextArgs{Context: ctx}
+
+	// Serve the shared context data
+	serveContext(c.Broker, &ctx.Shared, &args.ContextSharedArgs)
+
+	// Call. Plugins built before health checks existed don't have the
+	// method at all.
+	err := c.Client.Call(c.Name+".Health", &args, &resp)
+	if isMethodNotFound(err) || (err == nil && resp.NotSupported) {
+		return nil, app.ErrHealthNotSupported
+	}
+	if err == nil && resp.Error != nil {
+		err = resp.Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result, nil
+}
+
+func (c *App) Logs(ctx *app.Context, opts app.LogOptions, w io.Writer) error {
+	return c.logsCall("Logs", ctx, opts, w)
+}
+
+func (c *App) DevLogs(ctx *app.Context, opts app.LogOptions, w io.Writer) error {
+	return c.logsCall("DevLogs", ctx, opts, w)
+}
+
+func (c *App) logsCall(
+	method string, ctx *app.Context, opts app.LogOptions, w io.Writer) error {
+	args := AppLogsArgs{Context: ctx, Opts: opts}
+
+	// Serve the shared context data
+	serveContext(c.Broker, &ctx.Shared, &args.ContextSharedArgs)
+
+	// The logs are written to a stream. Closing the stream when the
+	// options say to stop tells the plugin to stop.
+	args.StreamId = c.Broker.NextId()
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		conn, err := c.Broker.Accept(args.StreamId)
+		if err != nil {
+			log.Printf("[ERR] rpc/app: %s stream accept error: %s", method, err)
+			return
+		}
+		defer conn.Close()
+
+		copyDoneCh := make(chan struct{})
+		defer close(copyDoneCh)
+		go func() {
+			select {
+			case <-opts.ShutdownCh:
+				conn.Close()
+			case <-copyDoneCh:
+			}
+		}()
+
+		io.Copy(w, conn)
+	}()
+
+	// Call. Plugins built before logs existed don't have the method at
+	// all, so they never connect to the stream.
+	var resp AppLogsResponse
+	err := c.Client.Call(c.Name+"."+method, &args, &resp)
+	if isMethodNotFound(err) {
+		return app.ErrLogsNotSupported
+	}
+	if err == nil {
+		// Wait for all the logs to be written
+		<-doneCh
+
+		if resp.NotSupported {
+			return app.ErrLogsNotSupported
+		}
+		if resp.Error != nil {
+			err = resp.Error
+		}
+	}
+
+	return err
+}
+
+func (c *App) Console(ctx *app.Context, target app.ConsoleTarget) error {
+	var resp AppConsoleResponse
+	args := AppConsoleArgs{Context: ctx, Target: target}
+
+	// Serve the shared context data
+	serveContext(c.Broker, &ctx.Shared, &args.ContextSharedArgs)
+
+	// Call. Plugins built before consoles existed don't have the method
+	// at all.
+	err := c.Client.Call(c.Name+".Console", &args, &resp)
+	if isMethodNotFound(err) || (err == nil && resp.NotSupported) {
+		return app.ErrConsoleNotSupported
+	}
+	if err == nil && resp.Error != nil {
+		err = resp.Error
+	}
+
+	return err
+}
+
+func (c *App) BuildArtifacts(ctx *app.Context) (*app.BuildResult, error) {
+	var resp AppBuildArtifactsResponse
+	args := AppContextArgs{Context: ctx}
+
+	// Serve the shared context data
+	serveContext(c.Broker, &ctx.Shared, &args.ContextSharedArgs)
+
+	// Call. Plugins built before build artifacts existed don't have the
+	// method at all.
+	err := c.Client.Call(c.Name+".BuildArtifacts", &args, &resp)
+	if isMethodNotFound(err) || (err == nil && resp.NotSupported) {
+		return nil, app.ErrArtifactsNotSupported
+	}
+	if err == nil && resp.Error != nil {
+		err = resp.Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result, nil
+}
+
+func (c *App) Plan(ctx *app.Context) (string, error) {
+	var resp AppPlanResponse
+	args := AppContextArgs{Context: ctx}
+
+	// Serve the shared context data
+	serveContext(c.Broker, &ctx.Shared, &args.ContextSharedArgs)
+
+	// Call. Plugins built before plans existed don't have the method at
+	// all.
+	err := c.Client.Call(c.Name+".Plan", &args, &resp)
+	if isMethodNotFound(err) || (err == nil && resp.NotSupported) {
+		return "", app.ErrPlanNotSupported
+	}
+	if err == nil && resp.Error != nil {
+		err = resp.Error
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Result, nil
+}
+
+func (c *App) DeployInactive(ctx *app.Context) error {
+	return c.rolloutCall("DeployInactive", ctx, 0)
+}
+
+func (c *App) SwitchTraffic(ctx *app.Context) error {
+	return c.rolloutCall("SwitchTraffic", ctx, 0)
+}
+
+func (c *App) SetTrafficWeight(ctx *app.Context, percent int) error {
+	return c.rolloutCall("SetTrafficWeight", ctx, percent)
+}
+
+func (c *App) TeardownInactive(ctx *app.Context) error {
+	return c.rolloutCall("TeardownInactive", ctx, 0)
+}
+
+func (c *App) rolloutCall(method string, ctx *app.Context, weight int) error {
+	var resp AppRolloutResponse
+	args := AppRolloutArgs{Context: ctx, Weight: weight}
+
+	// Serve the shared context data
+	serveContext(c.Broker, &ctx.Shared, &args.ContextSharedArgs)
+
+	// Call. Plugins built before rollouts existed don't have the method
+	// at all.
+	err := c.Client.Call(c.Name+"."+method, &args, &resp)
+	if isMethodNotFound(err) || (err == nil && resp.NotSupported) {
+		return app.ErrRolloutNotSupported
+	}
+	if err == nil && resp.Error != nil {
+		err = resp.Error
+	}
+
+	return err
+}
+
+func (c *App) RolloutSupport() ([]string, error) {
+	var resp AppRolloutSupportResponse
+	err := c.Client.Call(c.Name+".RolloutSupport", new(struct{}), &resp)
+	if isMethodNotFound(err) {
+		return nil, nil
+	}
+	if err == nil && resp.Error != nil {
+		err = resp.Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result, nil
+}
+
+func (c *App) DevSnapshot(ctx *app.Context, name string) error {
+	return c.devSnapshotCall("DevSnapshot", ctx, name)
+}
+
+func (c *App) DevRestore(ctx *app.Context, name string) error {
+	return c.devSnapshotCall("DevRestore", ctx, name)
+}
+
+func (c *App) DevSnapshots(ctx *app.Context) ([]app.SnapshotInfo, error) {
+	var resp AppDevSnapshotResponse
+	args := AppDevSnapshotArgs{Context: ctx}
+
+	// Serve the shared context data
+	serveContext(c.Broker, &ctx.Shared, &args.ContextSharedArgs)
+
+	// Call
+	err := c.Client.Call(c.Name+".DevSnapshots", &args, &resp)
+	if err == nil {
+		err = resp.err()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result, nil
+}
+
+func (c *App) devSnapshotCall(method string, ctx *app.Context, name string) error {
+	var resp AppDevSnapshotResponse
+	args := AppDevSnapshotArgs{Context: ctx, Name: name}
+
+	// Serve the shared context data
+	serveContext(c.Broker, &ctx.Shared, &args.ContextSharedArgs)
+
+	// Call
+	err := c.Client.Call(c.Name+"."+method, &args, &resp)
+	if err == nil {
+		err = resp.err()
+	}
+
+	return err
+}
+
 func (c *App) Close() error {
 	return c.Client.Close()
 }
@@ -190,6 +509,123 @@ type AppDevDepResponse struct {
 	Error  *BasicError
 }
 
+// AppDevDepRefreshResponse is the response of DevDepRefresh.
+// NotSupported is set if the app doesn't implement app.DevDepRefresher.
+type AppDevDepRefreshResponse struct {
+	NotSupported bool
+	Error        *BasicError
+}
+
+// AppFoundationConfigResponse is the response of FoundationConfig.
+// NotSupported is set if the app doesn't implement
+// app.FoundationConfigurer.
+type AppFoundationConfigResponse struct {
+	Result       *foundation.Config
+	NotSupported bool
+	Error        *BasicError
+}
+
+// AppHealthResponse is the response of Health. NotSupported is set if
+// the app doesn't implement app.Healthchecker.
+type AppHealthResponse struct {
+	Result       *app.HealthReport
+	NotSupported bool
+	Error        *BasicError
+}
+
+type AppLogsArgs struct {
+	ContextSharedArgs
+
+	Context  *app.Context
+	Opts     app.LogOptions
+	StreamId uint32
+}
+
+// AppLogsResponse is the response of the LogViewer calls. NotSupported is
+// set if the app doesn't implement app.LogViewer.
+type AppLogsResponse struct {
+	NotSupported bool
+	Error        *BasicError
+}
+
+type AppConsoleArgs struct {
+	ContextSharedArgs
+
+	Context *app.Context
+	Target  app.ConsoleTarget
+}
+
+// AppConsoleResponse is the response of Console. NotSupported is set if
+// the app doesn't implement app.ConsoleProvider.
+type AppConsoleResponse struct {
+	NotSupported bool
+	Error        *BasicError
+}
+
+// AppBuildArtifactsResponse is the response of BuildArtifacts.
+// NotSupported is set if the app doesn't implement app.ArtifactBuilder.
+type AppBuildArtifactsResponse struct {
+	Result       *app.BuildResult
+	NotSupported bool
+	Error        *BasicError
+}
+
+// AppPlanResponse is the response of Plan. NotSupported is set if the
+// app doesn't implement app.Planner.
+type AppPlanResponse struct {
+	Result       string
+	NotSupported bool
+	Error        *BasicError
+}
+
+type AppRolloutArgs struct {
+	ContextSharedArgs
+
+	Context *app.Context
+	Weight  int
+}
+
+// AppRolloutResponse is the response of the rollout calls. NotSupported
+// is set if the app doesn't implement the interface of the call.
+type AppRolloutResponse struct {
+	NotSupported bool
+	Error        *BasicError
+}
+
+// AppRolloutSupportResponse is the response of RolloutSupport, with the
+// names of the rollout methods that the app implements.
+type AppRolloutSupportResponse struct {
+	Result []string
+	Error  *BasicError
+}
+
+type AppDevSnapshotArgs struct {
+	ContextSharedArgs
+
+	Context *app.Context
+	Name    string
+}
+
+// AppDevSnapshotResponse is the response of the DevSnapshotter calls.
+// NotSupported is set if the app doesn't implement app.DevSnapshotter,
+// since that can't be known by the client.
+type AppDevSnapshotResponse struct {
+	Result       []app.SnapshotInfo
+	NotSupported bool
+	Error        *BasicError
+}
+
+func (r *AppDevSnapshotResponse) err() error {
+	if r.NotSupported {
+		return app.ErrDevSnapshotNotSupported
+	}
+	if r.Error != nil {
+		return r.Error
+	}
+
+	return nil
+}
+
 type AppSimpleResponse struct {
 	Error *BasicError
 }
@@ -343,3 +779,399 @@ func (s *AppServer) DevDep(
 
 	return nil
 }
+
+func (s *AppServer) Validate(
+	args *AppContextArgs,
+	reply *AppSimpleResponse) error {
+	closer, err := connectContext(s.Broker, &args.Context.Shared, &args.ContextSharedArgs)
+	defer closer.Close()
+	if err != nil {
+		*reply = AppSimpleResponse{
+			Error: NewBasicError(err),
+		}
+
+		return nil
+	}
+
+	impl, ok := s.App.(app.Validator)
+	if !ok {
+		*reply = AppSimpleResponse{}
+		return nil
+	}
+
+	*reply = AppSimpleResponse{
+		Error: NewBasicError(impl.Validate(args.Context)),
+	}
+
+	return nil
+}
+
+func (s *AppServer) FoundationConfig(
+	args *AppContextArgs,
+	reply *AppFoundationConfigResponse) error {
+	closer, err := connectContext(s.Broker, &args.Context.Shared, &args.ContextSharedArgs)
+	defer closer.Close()
+	if err != nil {
+		*reply = AppFoundationConfigResponse{
+			Error: NewBasicError(err),
+		}
+
+		return nil
+	}
+
+	impl, ok := s.App.(app.FoundationConfigurer)
+	if !ok {
+		*reply = AppFoundationConfigResponse{NotSupported: true}
+		return nil
+	}
+
+	result, err := impl.FoundationConfig(args.Context)
+	*reply = AppFoundationConfigResponse{
+		Result: result,
+		Error:  NewBasicError(err),
+	}
+
+	return nil
+}
+
+func (s *AppServer) Health(
+	args *AppContextArgs,
+	reply *AppHealthResponse) error {
+	closer, err := connectContext(s.Broker, &args.Context.Shared, &args.ContextSharedArgs)
+	defer closer.Close()
+	if err != nil {
+		*reply = AppHealthResponse{
+			Error: NewBasicError(err),
+		}
+
+		return nil
+	}
+
+	impl, ok := s.App.(app.Healthchecker)
+	if !ok {
+		*reply = AppHealthResponse{NotSupported: true}
+		return nil
+	}
+
+	result, err := impl.Health(args.Context)
+	*reply = AppHealthResponse{
+		Result: result,
+		Error:  NewBasicError(err),
+	}
+
+	return nil
+}
+
+func (s *AppServer) Logs(
+	args *AppLogsArgs,
+	reply *AppLogsResponse) error {
+	return s.logsCall(args, reply, app.LogViewer.Logs)
+}
+
+func (s *AppServer) DevLogs(
+	args *AppLogsArgs,
+	reply *AppLogsResponse) error {
+	return s.logsCall(args, reply, app.LogViewer.DevLogs)
+}
+
+func (s *AppServer) logsCall(
+	args *AppLogsArgs,
+	reply *AppLogsResponse,
+	f func(app.LogViewer, *app.Context, app.LogOptions, io.Writer) error) error {
+	// Connect to the stream first so the client isn't left waiting for
+	// it if anything below fails.
+	conn, err := s.Broker.Dial(args.StreamId)
+	if err != nil {
+		*reply = AppLogsResponse{Error: NewBasicError(err)}
+		return nil
+	}
+	defer conn.Close()
+
+	closer, err := connectContext(s.Broker, &args.Context.Shared, &args.ContextSharedArgs)
+	defer closer.Close()
+	if err != nil {
+		*reply = AppLogsResponse{
+			Error: NewBasicError(err),
+		}
+
+		return nil
+	}
+
+	impl, ok := s.App.(app.LogViewer)
+	if !ok {
+		*reply = AppLogsResponse{NotSupported: true}
+		return nil
+	}
+
+	// Nothing is ever written by the client, so a read only returns when
+	// the client closes the stream to stop the logs.
+	shutdownCh := make(chan struct{})
+	go func() {
+		io.Copy(ioutil.Discard, conn)
+		close(shutdownCh)
+	}()
+	args.Opts.ShutdownCh = shutdownCh
+
+	*reply = AppLogsResponse{
+		Error: NewBasicError(f(impl, args.Context, args.Opts, conn)),
+	}
+
+	return nil
+}
+
+func (s *AppServer) Console(
+	args *AppConsoleArgs,
+	reply *AppConsoleResponse) error {
+	closer, err := connectContext(s.Broker, &args.Context.Shared, &args.ContextSharedArgs)
+	defer closer.Close()
+	if err != nil {
+		*reply = AppConsoleResponse{
+			Error: NewBasicError(err),
+		}
+
+		return nil
+	}
+
+	impl, ok := s.App.(app.ConsoleProvider)
+	if !ok {
+		*reply = AppConsoleResponse{NotSupported: true}
+		return nil
+	}
+
+	*reply = AppConsoleResponse{
+		Error: NewBasicError(impl.Console(args.Context, args.Target)),
+	}
+
+	return nil
+}
+
+func (s *AppServer) BuildArtifacts(
+	args *AppContextArgs,
+	reply *AppBuildArtifactsResponse) error {
+	closer, err := connectContext(s.Broker, &args.Context.Shared, &args.ContextSharedArgs)
+	defer closer.Close()
+	if err != nil {
+		*reply = AppBuildArtifactsResponse{
+			Error: NewBasicError(err),
+		}
+
+		return nil
+	}
+
+	impl, ok := s.App.(app.ArtifactBuilder)
+	if !ok {
+		*reply = AppBuildArtifactsResponse{NotSupported: true}
+		return nil
+	}
+
+	result, err := impl.BuildArtifacts(args.Context)
+	*reply = AppBuildArtifactsResponse{
+		Result: result,
+		Error:  NewBasicError(err),
+	}
+
+	return nil
+}
+
+func (s *AppServer) Plan(
+	args *AppContextArgs,
+	reply *AppPlanResponse) error {
+	closer, err := connectContext(s.Broker, &args.Context.Shared, &args.ContextSharedArgs)
+	defer closer.Close()
+	if err != nil {
+		*reply = AppPlanResponse{
+			Error: NewBasicError(err),
+		}
+
+		return nil
+	}
+
+	impl, ok := s.App.(app.Planner)
+	if !ok {
+		*reply = AppPlanResponse{NotSupported: true}
+		return nil
+	}
+
+	result, err := impl.Plan(args.Context)
+	*reply = AppPlanResponse{
+		Result: result,
+		Error:  NewBasicError(err),
+	}
+
+	return nil
+}
+
+func (s *AppServer) DeployInactive(
+	args *AppRolloutArgs,
+	reply *AppRolloutResponse) error {
+	return s.rolloutCall(args, reply, func(ctx *app.Context) (bool, error) {
+		impl, ok := s.App.(app.InactiveDeployer)
+		if !ok {
+			return false, nil
+		}
+
+		return true, impl.DeployInactive(ctx)
+	})
+}
+
+func (s *AppServer) SwitchTraffic(
+	args *AppRolloutArgs,
+	reply *AppRolloutResponse) error {
+	return s.rolloutCall(args, reply, func(ctx *app.Context) (bool, error) {
+		impl, ok := s.App.(app.TrafficSwitcher)
+		if !ok {
+			return false, nil
+		}
+
+		return true, impl.SwitchTraffic(ctx)
+	})
+}
+
+func (s *AppServer) SetTrafficWeight(
+	args *AppRolloutArgs,
+	reply *AppRolloutResponse) error {
+	return s.rolloutCall(args, reply, func(ctx *app.Context) (bool, error) {
+		impl, ok := s.App.(app.TrafficWeighter)
+		if !ok {
+			return false, nil
+		}
+
+		return true, impl.SetTrafficWeight(ctx, args.Weight)
+	})
+}
+
+func (s *AppServer) TeardownInactive(
+	args *AppRolloutArgs,
+	reply *AppRolloutResponse) error {
+	return s.rolloutCall(args, reply, func(ctx *app.Context) (bool, error) {
+		impl, ok := s.App.(app.InactiveTeardowner)
+		if !ok {
+			return false, nil
+		}
+
+		return true, impl.TeardownInactive(ctx)
+	})
+}
+
+// rolloutCall connects the context of args and calls f with it. f returns
+// false if the app doesn't implement the interface of the call.
+func (s *AppServer) rolloutCall(
+	args *AppRolloutArgs,
+	reply *AppRolloutResponse,
+	f func(*app.Context) (bool, error)) error {
+	closer, err := connectContext(s.Broker, &args.Context.Shared, &args.ContextSharedArgs)
+	defer closer.Close()
+	if err != nil {
+		*reply = AppRolloutResponse{
+			Error: NewBasicError(err),
+		}
+
+		return nil
+	}
+
+	ok, err := f(args.Context)
+	*reply = AppRolloutResponse{
+		NotSupported: !ok,
+		Error:        NewBasicError(err),
+	}
+
+	return nil
+}
+
+func (s *AppServer) RolloutSupport(
+	args *struct{},
+	reply *AppRolloutSupportResponse) error {
+	*reply = AppRolloutSupportResponse{Result: app.RolloutMethods(s.App)}
+	return nil
+}
+
+func (s *AppServer) DevDepRefresh(
+	args *AppDevDepArgs,
+	reply *AppDevDepRefreshResponse) error {
+	// Dst
+	closer, err := connectContext(s.Broker, &args.ContextDst.Shared, &args.ContextDstShared)
+	defer closer.Close()
+	if err != nil {
+		*reply = AppDevDepRefreshResponse{
+			Error: NewBasicError(err),
+		}
+
+		return nil
+	}
+
+	// Src
+	closer, err = connectContext(s.Broker, &args.ContextSrc.Shared, &args.ContextSrcShared)
+	defer closer.Close()
+	if err != nil {
+		*reply = AppDevDepRefreshResponse{
+			Error: NewBasicError(err),
+		}
+
+		return nil
+	}
+
+	impl, ok := s.App.(app.DevDepRefresher)
+	if !ok {
+		*reply = AppDevDepRefreshResponse{NotSupported: true}
+		return nil
+	}
+
+	*reply = AppDevDepRefreshResponse{
+		Error: NewBasicError(impl.DevDepRefresh(args.ContextDst, args.ContextSrc)),
+	}
+
+	return nil
+}
+
+func (s *AppServer) DevSnapshot(
+	args *AppDevSnapshotArgs,
+	reply *AppDevSnapshotResponse) error {
+	return s.devSnapshotCall(args, reply, func(impl app.DevSnapshotter) error {
+		return impl.DevSnapshot(args.Context, args.Name)
+	})
+}
+
+func (s *AppServer) DevRestore(
+	args *AppDevSnapshotArgs,
+	reply *AppDevSnapshotResponse) error {
+	return s.devSnapshotCall(args, reply, func(impl app.DevSnapshotter) error {
+		return impl.DevRestore(args.Context, args.Name)
+	})
+}
+
+func (s *AppServer) DevSnapshots(
+	args *AppDevSnapshotArgs,
+	reply *AppDevSnapshotResponse) error {
+	return s.devSnapshotCall(args, reply, func(impl app.DevSnapshotter) error {
+		result, err := impl.DevSnapshots(args.Context)
+		reply.Result = result
+		return err
+	})
+}
+
+func (s *AppServer) devSnapshotCall(
+	args *AppDevSnapshotArgs,
+	reply *AppDevSnapshotResponse,
+	f func(app.DevSnapshotter) error) error {
+	// The context is always connected, even if the app doesn't support
+	// snapshots, so the client isn't left serving it.
+	closer, err := connectContext(s.Broker, &args.Context.Shared, &args.ContextSharedArgs)
+	defer closer.Close()
+	if err != nil {
+		*reply = AppDevSnapshotResponse{
+			Error: NewBasicError(err),
+		}
+
+		return nil
+	}
+
+	impl, ok := s.App.(app.DevSnapshotter)
+	if !ok {
+		*reply = AppDevSnapshotResponse{NotSupported: true}
+		return nil
+	}
+
+	*reply = AppDevSnapshotResponse{}
+	reply.Error = NewBasicError(f(impl))
+	return nil
+}
@@ -1,17 +1,37 @@
 package rpc
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"io"
+	"io/ioutil"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/otto/app"
 	"github.com/hashicorp/otto/appfile"
+	"github.com/hashicorp/otto/foundation"
 	"github.com/hashicorp/otto/ui"
 )
 
 func TestApp_impl(t *testing.T) {
 	var _ app.App = new(App)
+	var _ app.DevDepRefresher = new(App)
+	var _ app.DevSnapshotter = new(App)
+	var _ app.Validator = new(App)
+	var _ app.Healthchecker = new(App)
+	var _ app.FoundationConfigurer = new(App)
+	var _ app.LogViewer = new(App)
+	var _ app.ConsoleProvider = new(App)
+	var _ app.Planner = new(App)
+	var _ app.ArtifactBuilder = new(App)
+	var _ app.InactiveDeployer = new(App)
+	var _ app.TrafficSwitcher = new(App)
+	var _ app.TrafficWeighter = new(App)
+	var _ app.InactiveTeardowner = new(App)
+	var _ app.RolloutSupporter = new(App)
 	var _ io.Closer = new(App)
 }
 
@@ -214,3 +234,604 @@ func TestApp_devDep(t *testing.T) {
 		t.Fatalf("bad: %#v", err)
 	}
 }
+
+func TestApp_devDepRefresh(t *testing.T) {
+	clientConn, serverConn := testConn(t)
+	appMock := new(app.MockDevDepRefresher)
+	server := &Server{AppFunc: testAppFixed(appMock)}
+	streams := testNewStreams(t, server)
+	defer streams.Close()
+	go server.ServeConn(serverConn)
+
+	client, err := NewClient(clientConn)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err = appReal.(app.DevDepRefresher).DevDepRefresh(new(app.Context), new(app.Context))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.DevDepRefreshCalled {
+		t.Fatal("should be called")
+	}
+}
+
+func TestApp_devDepRefreshNotSupported(t *testing.T) {
+	client, _, streams := testNewClientServer(t)
+	defer streams.Close()
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err = appReal.(app.DevDepRefresher).DevDepRefresh(new(app.Context), new(app.Context))
+	if err != app.ErrDevDepRefreshNotSupported {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
+func TestApp_validate(t *testing.T) {
+	clientConn, serverConn := testConn(t)
+	appMock := new(app.MockValidator)
+	appMock.ValidateErr = errors.New("missing customization")
+	server := &Server{AppFunc: testAppFixed(appMock)}
+	streams := testNewStreams(t, server)
+	defer streams.Close()
+	go server.ServeConn(serverConn)
+
+	client, err := NewClient(clientConn)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err = appReal.(app.Validator).Validate(new(app.Context))
+	if err == nil || err.Error() != "missing customization" {
+		t.Fatalf("err: %v", err)
+	}
+	if !appMock.ValidateCalled {
+		t.Fatal("should be called")
+	}
+}
+
+func TestApp_validateNotSupported(t *testing.T) {
+	client, _, streams := testNewClientServer(t)
+	defer streams.Close()
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := appReal.(app.Validator).Validate(new(app.Context)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestApp_health(t *testing.T) {
+	clientConn, serverConn := testConn(t)
+	appMock := new(app.MockHealthchecker)
+	appMock.HealthResult = &app.HealthReport{
+		Status: app.HealthHealthy,
+		Checks: []*app.HealthCheck{
+			&app.HealthCheck{Endpoint: "http://foo", Status: app.HealthHealthy},
+		},
+	}
+	server := &Server{AppFunc: testAppFixed(appMock)}
+	streams := testNewStreams(t, server)
+	defer streams.Close()
+	go server.ServeConn(serverConn)
+
+	client, err := NewClient(clientConn)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := appReal.(app.Healthchecker).Health(new(app.Context))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, appMock.HealthResult) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestApp_healthNotSupported(t *testing.T) {
+	client, _, streams := testNewClientServer(t)
+	defer streams.Close()
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, err = appReal.(app.Healthchecker).Health(new(app.Context))
+	if err != app.ErrHealthNotSupported {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
+func TestApp_foundationConfig(t *testing.T) {
+	clientConn, serverConn := testConn(t)
+	appMock := new(app.MockFoundationConfigurer)
+	appMock.FoundationConfigResult = &foundation.Config{
+		ServiceName: "foo",
+		ServicePort: 8080,
+	}
+	server := &Server{AppFunc: testAppFixed(appMock)}
+	streams := testNewStreams(t, server)
+	defer streams.Close()
+	go server.ServeConn(serverConn)
+
+	client, err := NewClient(clientConn)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := appReal.(app.FoundationConfigurer).FoundationConfig(new(app.Context))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.FoundationConfigCalled {
+		t.Fatal("should be called")
+	}
+	if !reflect.DeepEqual(actual, appMock.FoundationConfigResult) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestApp_foundationConfigNotSupported(t *testing.T) {
+	client, _, streams := testNewClientServer(t)
+	defer streams.Close()
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, err = appReal.(app.FoundationConfigurer).FoundationConfig(new(app.Context))
+	if err != app.ErrFoundationConfigNotSupported {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
+func TestApp_logs(t *testing.T) {
+	clientConn, serverConn := testConn(t)
+	appMock := new(app.MockLogViewer)
+	appMock.LogsOutput = "foo\nbar\n"
+	appMock.DevLogsOutput = "dev\n"
+	server := &Server{AppFunc: testAppFixed(appMock)}
+	streams := testNewStreams(t, server)
+	defer streams.Close()
+	go server.ServeConn(serverConn)
+
+	client, err := NewClient(clientConn)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	viewer := appReal.(app.LogViewer)
+
+	var buf bytes.Buffer
+	since := time.Date(2015, 10, 1, 0, 0, 0, 0, time.UTC)
+	opts := app.LogOptions{Tail: 10, Since: since}
+	if err := viewer.Logs(new(app.Context), opts, &buf); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if buf.String() != "foo\nbar\n" {
+		t.Fatalf("bad: %q", buf.String())
+	}
+	if appMock.LogsOpts.Tail != 10 || !appMock.LogsOpts.Since.Equal(since) {
+		t.Fatalf("bad: %#v", appMock.LogsOpts)
+	}
+
+	buf.Reset()
+	if err := viewer.DevLogs(new(app.Context), opts, &buf); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if buf.String() != "dev\n" {
+		t.Fatalf("bad: %q", buf.String())
+	}
+}
+
+func TestApp_logsFollow(t *testing.T) {
+	clientConn, serverConn := testConn(t)
+	server := &Server{AppFunc: testAppFixed(new(testLogFollower))}
+	streams := testNewStreams(t, server)
+	defer streams.Close()
+	go server.ServeConn(serverConn)
+
+	client, err := NewClient(clientConn)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The logs are followed until the shutdown channel is closed
+	pr, pw := io.Pipe()
+	shutdownCh := make(chan struct{})
+	errCh := make(chan error, 1)
+	go func() {
+		opts := app.LogOptions{Follow: true, ShutdownCh: shutdownCh}
+		errCh <- appReal.(app.LogViewer).Logs(new(app.Context), opts, pw)
+	}()
+
+	line, err := bufio.NewReader(pr).ReadString('\n')
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if line != "foo\n" {
+		t.Fatalf("bad: %q", line)
+	}
+
+	close(shutdownCh)
+	go io.Copy(ioutil.Discard, pr)
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("logs should stop")
+	}
+}
+
+func TestApp_logsNotSupported(t *testing.T) {
+	client, _, streams := testNewClientServer(t)
+	defer streams.Close()
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var buf bytes.Buffer
+	err = appReal.(app.LogViewer).Logs(new(app.Context), app.LogOptions{}, &buf)
+	if err != app.ErrLogsNotSupported {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
+func TestApp_console(t *testing.T) {
+	clientConn, serverConn := testConn(t)
+	appMock := new(app.MockConsoleProvider)
+	server := &Server{AppFunc: testAppFixed(appMock)}
+	streams := testNewStreams(t, server)
+	defer streams.Close()
+	go server.ServeConn(serverConn)
+
+	client, err := NewClient(clientConn)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	target := app.ConsoleTarget{Dev: true, Instance: "i-1234"}
+	if err := appReal.(app.ConsoleProvider).Console(new(app.Context), target); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.ConsoleCalled || !reflect.DeepEqual(appMock.ConsoleTarget, target) {
+		t.Fatalf("bad: %#v", appMock.ConsoleTarget)
+	}
+}
+
+func TestApp_consoleNotSupported(t *testing.T) {
+	client, _, streams := testNewClientServer(t)
+	defer streams.Close()
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err = appReal.(app.ConsoleProvider).Console(new(app.Context), app.ConsoleTarget{})
+	if err != app.ErrConsoleNotSupported {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
+func TestApp_buildArtifacts(t *testing.T) {
+	clientConn, serverConn := testConn(t)
+	appMock := new(app.MockArtifactBuilder)
+	appMock.BuildArtifactsResult = &app.BuildResult{
+		Files: map[string]string{"image": "/tmp/image.tar"},
+	}
+	server := &Server{AppFunc: testAppFixed(appMock)}
+	streams := testNewStreams(t, server)
+	defer streams.Close()
+	go server.ServeConn(serverConn)
+
+	client, err := NewClient(clientConn)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	result, err := appReal.(app.ArtifactBuilder).BuildArtifacts(new(app.Context))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.BuildArtifactsCalled || !reflect.DeepEqual(result, appMock.BuildArtifactsResult) {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestApp_buildArtifactsNotSupported(t *testing.T) {
+	client, _, streams := testNewClientServer(t)
+	defer streams.Close()
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, err = appReal.(app.ArtifactBuilder).BuildArtifacts(new(app.Context))
+	if err != app.ErrArtifactsNotSupported {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
+func TestApp_plan(t *testing.T) {
+	clientConn, serverConn := testConn(t)
+	appMock := new(app.MockPlanner)
+	appMock.PlanResult = "+ aws_instance.app"
+	server := &Server{AppFunc: testAppFixed(appMock)}
+	streams := testNewStreams(t, server)
+	defer streams.Close()
+	go server.ServeConn(serverConn)
+
+	client, err := NewClient(clientConn)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	result, err := appReal.(app.Planner).Plan(new(app.Context))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.PlanCalled || result != appMock.PlanResult {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestApp_planNotSupported(t *testing.T) {
+	client, _, streams := testNewClientServer(t)
+	defer streams.Close()
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, err = appReal.(app.Planner).Plan(new(app.Context))
+	if err != app.ErrPlanNotSupported {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
+func TestApp_rollout(t *testing.T) {
+	clientConn, serverConn := testConn(t)
+	appMock := new(app.MockRollout)
+	server := &Server{AppFunc: testAppFixed(appMock)}
+	streams := testNewStreams(t, server)
+	defer streams.Close()
+	go server.ServeConn(serverConn)
+
+	client, err := NewClient(clientConn)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	supported, err := appReal.(app.RolloutSupporter).RolloutSupport()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []string{
+		"DeployInactive", "SetTrafficWeight", "SwitchTraffic", "TeardownInactive"}
+	if !reflect.DeepEqual(supported, expected) {
+		t.Fatalf("bad: %#v", supported)
+	}
+
+	ctx := new(app.Context)
+	if err := appReal.(app.InactiveDeployer).DeployInactive(ctx); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := appReal.(app.TrafficWeighter).SetTrafficWeight(ctx, 25); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	appMock.SwitchTrafficErr = errors.New("switch")
+	if err := appReal.(app.TrafficSwitcher).SwitchTraffic(ctx); err == nil {
+		t.Fatal("should error")
+	}
+	if err := appReal.(app.InactiveTeardowner).TeardownInactive(ctx); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected = expected[:0]
+	for _, c := range appMock.Calls {
+		if c != "Meta" {
+			expected = append(expected, c)
+		}
+	}
+	if !reflect.DeepEqual(expected, []string{
+		"DeployInactive", "SetTrafficWeight", "SwitchTraffic", "TeardownInactive"}) {
+		t.Fatalf("bad: %#v", appMock.Calls)
+	}
+	if !reflect.DeepEqual(appMock.TrafficWeights, []int{25}) {
+		t.Fatalf("bad: %#v", appMock.TrafficWeights)
+	}
+}
+
+func TestApp_rolloutNotSupported(t *testing.T) {
+	client, _, streams := testNewClientServer(t)
+	defer streams.Close()
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	supported, err := appReal.(app.RolloutSupporter).RolloutSupport()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(supported) != 0 {
+		t.Fatalf("bad: %#v", supported)
+	}
+
+	err = appReal.(app.TrafficSwitcher).SwitchTraffic(new(app.Context))
+	if err != app.ErrRolloutNotSupported {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
+func TestApp_devSnapshot(t *testing.T) {
+	clientConn, serverConn := testConn(t)
+	appMock := new(app.MockDevSnapshotter)
+	server := &Server{AppFunc: testAppFixed(appMock)}
+	streams := testNewStreams(t, server)
+	defer streams.Close()
+	go server.ServeConn(serverConn)
+
+	client, err := NewClient(clientConn)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer client.Close()
+
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	snapshotter := appReal.(app.DevSnapshotter)
+
+	if err := snapshotter.DevSnapshot(new(app.Context), "foo"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.DevSnapshotCalled || appMock.DevSnapshotName != "foo" {
+		t.Fatalf("bad: %#v", appMock)
+	}
+
+	if err := snapshotter.DevRestore(new(app.Context), "foo"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !appMock.DevRestoreCalled || appMock.DevRestoreName != "foo" {
+		t.Fatalf("bad: %#v", appMock)
+	}
+
+	appMock.DevSnapshotsResult = []app.SnapshotInfo{
+		app.SnapshotInfo{Name: "foo", Size: 42},
+	}
+	actual, err := snapshotter.DevSnapshots(new(app.Context))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, appMock.DevSnapshotsResult) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestApp_devSnapshotNotSupported(t *testing.T) {
+	client, server, streams := testNewClientServer(t)
+	defer streams.Close()
+	defer client.Close()
+
+	appMock := server.AppFunc().(*app.Mock)
+	appReal, err := client.App()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err = appReal.(app.DevSnapshotter).DevSnapshot(new(app.Context), "foo")
+	if err != app.ErrDevSnapshotNotSupported {
+		t.Fatalf("bad: %#v", err)
+	}
+	if appMock.DevCalled {
+		t.Fatal("nothing should be called")
+	}
+}
+
+// testLogFollower is an app that writes a single line of logs and then
+// waits for the logs to be stopped.
+type testLogFollower struct {
+	app.Mock
+}
+
+func (a *testLogFollower) Logs(ctx *app.Context, opts app.LogOptions, w io.Writer) error {
+	if _, err := io.WriteString(w, "foo\n"); err != nil {
+		return err
+	}
+
+	<-opts.ShutdownCh
+	return nil
+}
+
+func (a *testLogFollower) DevLogs(ctx *app.Context, opts app.LogOptions, w io.Writer) error {
+	return app.ErrLogsNotSupported
+}
@@ -6,6 +6,8 @@ import (
 	"net/rpc"
 
 	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/foundation"
+	"github.com/hashicorp/otto/infrastructure"
 	"github.com/hashicorp/yamux"
 )
 
@@ -103,21 +105,71 @@ func (c *Client) Close() error {
 	return c.broker.Close()
 }
 
-func (c *Client) App() (app.App, error) {
-	var id uint32
+// Meta returns what the server dispenses. Servers of older versions of
+// Otto only dispense apps and don't support this, so this returns an
+// error for them.
+func (c *Client) Meta() (*ServerMeta, error) {
+	var result ServerMeta
 	if err := c.control.Call(
-		"Dispenser.App", new(interface{}), &id); err != nil {
+		"Dispenser.Meta", new(interface{}), &result); err != nil {
 		return nil, err
 	}
 
-	conn, err := c.broker.Dial(id)
+	return &result, nil
+}
+
+func (c *Client) App() (app.App, error) {
+	client, err := c.dispense("App")
 	if err != nil {
 		return nil, err
 	}
 
 	return &App{
 		Broker: c.broker,
-		Client: rpc.NewClient(conn),
+		Client: client,
 		Name:   "App",
 	}, nil
 }
+
+func (c *Client) Infra() (infrastructure.Infrastructure, error) {
+	client, err := c.dispense("Infra")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Infrastructure{
+		Broker: c.broker,
+		Client: client,
+		Name:   "Infrastructure",
+	}, nil
+}
+
+func (c *Client) Foundation() (foundation.Foundation, error) {
+	client, err := c.dispense("Foundation")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Foundation{
+		Broker: c.broker,
+		Client: client,
+		Name:   "Foundation",
+	}, nil
+}
+
+// dispense requests an implementation from the dispenser and returns the
+// RPC client for it.
+func (c *Client) dispense(method string) (*rpc.Client, error) {
+	var id uint32
+	if err := c.control.Call(
+		"Dispenser."+method, new(interface{}), &id); err != nil {
+		return nil, err
+	}
+
+	conn, err := c.broker.Dial(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return rpc.NewClient(conn), nil
+}
@@ -3,10 +3,12 @@ package rpc
 import (
 	"bytes"
 	"io"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/foundation"
 )
 
 func TestClient_App(t *testing.T) {
@@ -40,6 +42,57 @@ func TestClient_App(t *testing.T) {
 	}
 }
 
+func TestClient_Meta(t *testing.T) {
+	client, _, streams := testNewClientServer(t)
+	defer streams.Close()
+	defer client.Close()
+
+	meta, err := client.Meta()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &ServerMeta{
+		App:         true,
+		Infras:      []string{"test"},
+		Foundations: foundation.TupleSlice{{Type: "test", Infra: "test", InfraFlavor: "test"}},
+	}
+	if !reflect.DeepEqual(meta, expected) {
+		t.Fatalf("bad: %#v", meta)
+	}
+}
+
+func TestClient_notServed(t *testing.T) {
+	clientConn, serverConn := testConn(t)
+
+	server := &Server{AppFunc: testAppFixed(new(app.Mock))}
+	streams := testNewStreams(t, server)
+	defer streams.Close()
+
+	go server.ServeConn(serverConn)
+
+	client, err := NewClient(clientConn)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer client.Close()
+
+	meta, err := client.Meta()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !meta.App || len(meta.Infras) != 0 || len(meta.Foundations) != 0 {
+		t.Fatalf("bad: %#v", meta)
+	}
+
+	if _, err := client.Infra(); err == nil {
+		t.Fatal("should error")
+	}
+	if _, err := client.Foundation(); err == nil {
+		t.Fatal("should error")
+	}
+}
+
 func TestClient_syncStreams(t *testing.T) {
 	client, _, streams := testNewClientServer(t)
 
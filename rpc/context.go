@@ -6,6 +6,7 @@ import (
 	"net/rpc"
 
 	"github.com/hashicorp/otto/context"
+	"github.com/hashicorp/otto/helper/logger"
 )
 
 // ContextSharedArgs is a struct that should be embedded directly into
@@ -46,6 +47,10 @@ func connectContext(
 		Name:   "Ui",
 	}
 
+	// Logs from plugins go to the plugin's standard logger, which is
+	// already captured by the host.
+	ctx.Logger = logger.Default()
+
 	return closer, nil
 }
 
@@ -69,6 +74,7 @@ func serveContext(broker *muxBroker, ctx *context.Shared, args *ContextSharedArg
 	// network (Go will just panic if we didn't do this).
 	ctx.Directory = nil
 	ctx.Ui = nil
+	ctx.Logger = nil
 }
 
 // multiCloser is an io.Closer that closes multiple closers.
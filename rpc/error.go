@@ -1,5 +1,10 @@
 package rpc
 
+import (
+	"net/rpc"
+	"strings"
+)
+
 // This is a type that wraps error types so that they can be messaged
 // across RPC channels. Since "error" is an interface, we can't always
 // gob-encode the underlying structure. This is a valid error interface
@@ -25,3 +30,11 @@ func (e *BasicError) Error() string {
 type ErrorResponse struct {
 	Error *BasicError
 }
+
+// isMethodNotFound returns true if err is the error of net/rpc for a call
+// to a method that the server doesn't have. Plugins built before a method
+// was added to the plugin API return this.
+func isMethodNotFound(err error) bool {
+	e, ok := err.(rpc.ServerError)
+	return ok && strings.HasPrefix(string(e), "rpc: can't find method")
+}
@@ -2,6 +2,7 @@ package rpc
 
 import (
 	"errors"
+	"net/rpc"
 	"testing"
 )
 
@@ -24,3 +25,12 @@ func TestNewBasicError_nil(t *testing.T) {
 		t.Fatalf("bad: %#v", r)
 	}
 }
+
+func TestIsMethodNotFound(t *testing.T) {
+	if !isMethodNotFound(rpc.ServerError("rpc: can't find method App.Validate")) {
+		t.Fatal("should be not found")
+	}
+	if isMethodNotFound(rpc.ServerError("boom")) || isMethodNotFound(nil) {
+		t.Fatal("should not be not found")
+	}
+}
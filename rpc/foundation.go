@@ -0,0 +1,166 @@
+package rpc
+
+import (
+	"net/rpc"
+
+	"github.com/hashicorp/otto/foundation"
+)
+
+// Foundation is an implementation of foundation.Foundation that
+// communicates over RPC.
+type Foundation struct {
+	Broker *muxBroker
+	Client *rpc.Client
+	Name   string
+}
+
+func (c *Foundation) Compile(
+	ctx *foundation.Context) (*foundation.CompileResult, error) {
+	var resp FoundationCompileResponse
+	args := c.args(ctx)
+
+	// Call
+	err := c.Client.Call(c.Name+".Compile", args, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		err = resp.Error
+		return nil, err
+	}
+
+	return resp.Result, nil
+}
+
+func (c *Foundation) Infra(ctx *foundation.Context) error {
+	var resp ErrorResponse
+	args := c.args(ctx)
+
+	// Call
+	err := c.Client.Call(c.Name+".Infra", args, &resp)
+	if err == nil {
+		if resp.Error != nil {
+			err = resp.Error
+		}
+	}
+
+	return err
+}
+
+// Validate calls Validate on the foundation if it implements
+// foundation.Validator. Plugins built before Validate existed have
+// nothing to validate.
+func (c *Foundation) Validate(ctx *foundation.Context) error {
+	var resp ErrorResponse
+	args := c.args(ctx)
+
+	// Call
+	err := c.Client.Call(c.Name+".Validate", args, &resp)
+	if isMethodNotFound(err) {
+		return nil
+	}
+	if err == nil && resp.Error != nil {
+		err = resp.Error
+	}
+
+	return err
+}
+
+func (c *Foundation) Close() error {
+	return c.Client.Close()
+}
+
+// args serves the shared context data of ctx and returns the arguments
+// to send it with, like Infrastructure.args.
+func (c *Foundation) args(ctx *foundation.Context) *FoundationContextArgs {
+	ctxCopy := *ctx
+	args := &FoundationContextArgs{Context: &ctxCopy}
+	serveContext(c.Broker, &ctxCopy.Shared, &args.ContextSharedArgs)
+	return args
+}
+
+// FoundationServer is a net/rpc compatible structure for serving a
+// Foundation. This should not be used directly.
+type FoundationServer struct {
+	Broker     *muxBroker
+	Foundation foundation.Foundation
+}
+
+type FoundationContextArgs struct {
+	ContextSharedArgs
+
+	Context *foundation.Context
+}
+
+type FoundationCompileResponse struct {
+	Result *foundation.CompileResult
+	Error  *BasicError
+}
+
+func (s *FoundationServer) Compile(
+	args *FoundationContextArgs,
+	reply *FoundationCompileResponse) error {
+	closer, err := connectContext(s.Broker, &args.Context.Shared, &args.ContextSharedArgs)
+	defer closer.Close()
+	if err != nil {
+		*reply = FoundationCompileResponse{
+			Error: NewBasicError(err),
+		}
+
+		return nil
+	}
+
+	result, err := s.Foundation.Compile(args.Context)
+	*reply = FoundationCompileResponse{
+		Result: result,
+		Error:  NewBasicError(err),
+	}
+
+	return nil
+}
+
+func (s *FoundationServer) Infra(
+	args *FoundationContextArgs,
+	reply *ErrorResponse) error {
+	closer, err := connectContext(s.Broker, &args.Context.Shared, &args.ContextSharedArgs)
+	defer closer.Close()
+	if err != nil {
+		*reply = ErrorResponse{
+			Error: NewBasicError(err),
+		}
+
+		return nil
+	}
+
+	*reply = ErrorResponse{
+		Error: NewBasicError(s.Foundation.Infra(args.Context)),
+	}
+
+	return nil
+}
+
+func (s *FoundationServer) Validate(
+	args *FoundationContextArgs,
+	reply *ErrorResponse) error {
+	closer, err := connectContext(s.Broker, &args.Context.Shared, &args.ContextSharedArgs)
+	defer closer.Close()
+	if err != nil {
+		*reply = ErrorResponse{
+			Error: NewBasicError(err),
+		}
+
+		return nil
+	}
+
+	impl, ok := s.Foundation.(foundation.Validator)
+	if !ok {
+		*reply = ErrorResponse{}
+		return nil
+	}
+
+	*reply = ErrorResponse{
+		Error: NewBasicError(impl.Validate(args.Context)),
+	}
+
+	return nil
+}
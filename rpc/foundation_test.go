@@ -0,0 +1,71 @@
+package rpc
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/otto/foundation"
+)
+
+func TestFoundation_impl(t *testing.T) {
+	var _ foundation.Foundation = new(Foundation)
+	var _ io.Closer = new(Foundation)
+}
+
+func TestFoundation_compile(t *testing.T) {
+	client, server, streams := testNewClientServer(t)
+	defer streams.Close()
+	defer client.Close()
+
+	fMock := server.FoundationFunc().(*foundation.Mock)
+	fReal, err := client.Foundation()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	fMock.CompileResult = new(foundation.CompileResult)
+
+	// The configuration is decoded HCL
+	config := map[string]interface{}{
+		"servers": 3,
+		"tags":    []interface{}{"a", "b"},
+		"nested":  []map[string]interface{}{{"key": "value"}},
+	}
+	actual, err := fReal.Compile(&foundation.Context{Config: config})
+	if !fMock.CompileCalled {
+		t.Fatal("compile should be called")
+	}
+	if err != nil {
+		t.Fatalf("bad: %#v", err)
+	}
+	if !reflect.DeepEqual(actual, fMock.CompileResult) {
+		t.Fatalf("bad: %#v", actual)
+	}
+	if !reflect.DeepEqual(fMock.CompileContext.Config, config) {
+		t.Fatalf("bad: %#v", fMock.CompileContext.Config)
+	}
+}
+
+func TestFoundation_infra(t *testing.T) {
+	client, server, streams := testNewClientServer(t)
+	defer streams.Close()
+	defer client.Close()
+
+	fMock := server.FoundationFunc().(*foundation.Mock)
+	fReal, err := client.Foundation()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err = fReal.Infra(&foundation.Context{Action: "destroy"})
+	if !fMock.InfraCalled {
+		t.Fatal("infra should be called")
+	}
+	if err != nil {
+		t.Fatalf("bad: %#v", err)
+	}
+	if fMock.InfraContext.Action != "destroy" {
+		t.Fatalf("bad: %#v", fMock.InfraContext)
+	}
+}
@@ -0,0 +1,289 @@
+package rpc
+
+import (
+	"log"
+	"net/rpc"
+
+	"github.com/hashicorp/otto/infrastructure"
+)
+
+// Infrastructure is an implementation of infrastructure.Infrastructure
+// that communicates over RPC.
+type Infrastructure struct {
+	Broker *muxBroker
+	Client *rpc.Client
+	Name   string
+}
+
+func (c *Infrastructure) Creds(ctx *infrastructure.Context) (map[string]string, error) {
+	var resp InfraCredsResponse
+	args := c.args(ctx)
+
+	// Call
+	err := c.Client.Call(c.Name+".Creds", args, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		err = resp.Error
+		return nil, err
+	}
+
+	return resp.Result, nil
+}
+
+func (c *Infrastructure) VerifyCreds(ctx *infrastructure.Context) error {
+	return c.simpleCall("VerifyCreds", ctx)
+}
+
+func (c *Infrastructure) Execute(ctx *infrastructure.Context) error {
+	return c.simpleCall("Execute", ctx)
+}
+
+// Validate calls Validate on the infrastructure if it implements
+// infrastructure.Validator. Plugins built before Validate existed have
+// nothing to validate.
+func (c *Infrastructure) Validate(ctx *infrastructure.Context) error {
+	err := c.simpleCall("Validate", ctx)
+	if isMethodNotFound(err) {
+		return nil
+	}
+
+	return err
+}
+
+// Verify calls Verify on the infrastructure if it implements
+// infrastructure.Verifier.
+func (c *Infrastructure) Verify(
+	ctx *infrastructure.Context) (*infrastructure.VerifyResult, error) {
+	var resp InfraVerifyResponse
+	args := c.args(ctx)
+
+	// Call. Plugins built before Verify existed don't have the method at
+	// all.
+	err := c.Client.Call(c.Name+".Verify", args, &resp)
+	if isMethodNotFound(err) || (err == nil && resp.NotSupported) {
+		return nil, infrastructure.ErrVerifyNotSupported
+	}
+	if err == nil && resp.Error != nil {
+		err = resp.Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result, nil
+}
+
+func (c *Infrastructure) Compile(
+	ctx *infrastructure.Context) (*infrastructure.CompileResult, error) {
+	var resp InfraCompileResponse
+	args := c.args(ctx)
+
+	// Call
+	err := c.Client.Call(c.Name+".Compile", args, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		err = resp.Error
+		return nil, err
+	}
+
+	return resp.Result, nil
+}
+
+func (c *Infrastructure) Flavors() []string {
+	var resp []string
+	err := c.Client.Call(c.Name+".Flavors", new(struct{}), &resp)
+	if err != nil {
+		log.Printf("[ERR] rpc/infrastructure: flavors error: %s", err)
+		return nil
+	}
+
+	return resp
+}
+
+func (c *Infrastructure) Close() error {
+	return c.Client.Close()
+}
+
+func (c *Infrastructure) simpleCall(method string, ctx *infrastructure.Context) error {
+	var resp ErrorResponse
+	args := c.args(ctx)
+
+	// Call
+	err := c.Client.Call(c.Name+"."+method, args, &resp)
+	if err == nil {
+		if resp.Error != nil {
+			err = resp.Error
+		}
+	}
+
+	return err
+}
+
+// args serves the shared context data of ctx and returns the arguments
+// to send it with. A copy of the context is sent so that the Ui and
+// Directory of ctx are still set for the caller afterwards.
+func (c *Infrastructure) args(ctx *infrastructure.Context) *InfraContextArgs {
+	ctxCopy := *ctx
+	args := &InfraContextArgs{Context: &ctxCopy}
+	serveContext(c.Broker, &ctxCopy.Shared, &args.ContextSharedArgs)
+	return args
+}
+
+// InfrastructureServer is a net/rpc compatible structure for serving an
+// Infrastructure. This should not be used directly.
+type InfrastructureServer struct {
+	Broker *muxBroker
+	Infra  infrastructure.Infrastructure
+}
+
+type InfraContextArgs struct {
+	ContextSharedArgs
+
+	Context *infrastructure.Context
+}
+
+type InfraCredsResponse struct {
+	Result map[string]string
+	Error  *BasicError
+}
+
+type InfraCompileResponse struct {
+	Result *infrastructure.CompileResult
+	Error  *BasicError
+}
+
+// InfraVerifyResponse is the response of Verify. NotSupported is set if
+// the infrastructure doesn't implement infrastructure.Verifier.
+type InfraVerifyResponse struct {
+	Result       *infrastructure.VerifyResult
+	NotSupported bool
+	Error        *BasicError
+}
+
+func (s *InfrastructureServer) Creds(
+	args *InfraContextArgs,
+	reply *InfraCredsResponse) error {
+	closer, err := connectContext(s.Broker, &args.Context.Shared, &args.ContextSharedArgs)
+	defer closer.Close()
+	if err != nil {
+		*reply = InfraCredsResponse{
+			Error: NewBasicError(err),
+		}
+
+		return nil
+	}
+
+	result, err := s.Infra.Creds(args.Context)
+	*reply = InfraCredsResponse{
+		Result: result,
+		Error:  NewBasicError(err),
+	}
+
+	return nil
+}
+
+func (s *InfrastructureServer) VerifyCreds(
+	args *InfraContextArgs,
+	reply *ErrorResponse) error {
+	return s.simpleCall(args, reply, s.Infra.VerifyCreds)
+}
+
+func (s *InfrastructureServer) Execute(
+	args *InfraContextArgs,
+	reply *ErrorResponse) error {
+	return s.simpleCall(args, reply, s.Infra.Execute)
+}
+
+func (s *InfrastructureServer) Validate(
+	args *InfraContextArgs,
+	reply *ErrorResponse) error {
+	return s.simpleCall(args, reply, func(ctx *infrastructure.Context) error {
+		if impl, ok := s.Infra.(infrastructure.Validator); ok {
+			return impl.Validate(ctx)
+		}
+
+		return nil
+	})
+}
+
+func (s *InfrastructureServer) Verify(
+	args *InfraContextArgs,
+	reply *InfraVerifyResponse) error {
+	closer, err := connectContext(s.Broker, &args.Context.Shared, &args.ContextSharedArgs)
+	defer closer.Close()
+	if err != nil {
+		*reply = InfraVerifyResponse{
+			Error: NewBasicError(err),
+		}
+
+		return nil
+	}
+
+	impl, ok := s.Infra.(infrastructure.Verifier)
+	if !ok {
+		*reply = InfraVerifyResponse{NotSupported: true}
+		return nil
+	}
+
+	result, err := impl.Verify(args.Context)
+	*reply = InfraVerifyResponse{
+		Result: result,
+		Error:  NewBasicError(err),
+	}
+
+	return nil
+}
+
+func (s *InfrastructureServer) Compile(
+	args *InfraContextArgs,
+	reply *InfraCompileResponse) error {
+	closer, err := connectContext(s.Broker, &args.Context.Shared, &args.ContextSharedArgs)
+	defer closer.Close()
+	if err != nil {
+		*reply = InfraCompileResponse{
+			Error: NewBasicError(err),
+		}
+
+		return nil
+	}
+
+	result, err := s.Infra.Compile(args.Context)
+	*reply = InfraCompileResponse{
+		Result: result,
+		Error:  NewBasicError(err),
+	}
+
+	return nil
+}
+
+func (s *InfrastructureServer) Flavors(
+	args *struct{},
+	reply *[]string) error {
+	*reply = s.Infra.Flavors()
+	return nil
+}
+
+func (s *InfrastructureServer) simpleCall(
+	args *InfraContextArgs,
+	reply *ErrorResponse,
+	f func(*infrastructure.Context) error) error {
+	closer, err := connectContext(s.Broker, &args.Context.Shared, &args.ContextSharedArgs)
+	defer closer.Close()
+	if err != nil {
+		*reply = ErrorResponse{
+			Error: NewBasicError(err),
+		}
+
+		return nil
+	}
+
+	*reply = ErrorResponse{
+		Error: NewBasicError(f(args.Context)),
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package rpc
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/otto/infrastructure"
+	"github.com/hashicorp/otto/ui"
+)
+
+func TestInfrastructure_impl(t *testing.T) {
+	var _ infrastructure.Infrastructure = new(Infrastructure)
+	var _ io.Closer = new(Infrastructure)
+}
+
+func TestInfrastructure_creds(t *testing.T) {
+	client, server, streams := testNewClientServer(t)
+	defer streams.Close()
+	defer client.Close()
+
+	infraMock := server.InfraFunc().(*infrastructure.Mock)
+	infraReal, err := client.Infra()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	infraMock.CredsFunc = func(ctx *infrastructure.Context) (map[string]string, error) {
+		ctx.Ui.Message("HELLO!")
+		return map[string]string{"key": "value"}, nil
+	}
+
+	ui := new(ui.Mock)
+	ctx := new(infrastructure.Context)
+	ctx.Ui = ui
+
+	actual, err := infraReal.Creds(ctx)
+	if !infraMock.CredsCalled {
+		t.Fatal("creds should be called")
+	}
+	if err != nil {
+		t.Fatalf("bad: %#v", err)
+	}
+
+	expected := map[string]string{"key": "value"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+	if ui.MessageBuf[0] != "HELLO!" {
+		t.Fatalf("bad: %#v", ui)
+	}
+
+	// The context is still usable by the caller
+	if ctx.Ui != ui {
+		t.Fatalf("bad: %#v", ctx.Ui)
+	}
+}
+
+func TestInfrastructure_execute(t *testing.T) {
+	client, server, streams := testNewClientServer(t)
+	defer streams.Close()
+	defer client.Close()
+
+	infraMock := server.InfraFunc().(*infrastructure.Mock)
+	infraReal, err := client.Infra()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	infraMock.ExecuteErr = errors.New("foo")
+
+	err = infraReal.Execute(&infrastructure.Context{Action: "destroy"})
+	if !infraMock.ExecuteCalled {
+		t.Fatal("execute should be called")
+	}
+	if err == nil || err.Error() != "foo" {
+		t.Fatalf("bad: %#v", err)
+	}
+	if infraMock.ExecuteContext.Action != "destroy" {
+		t.Fatalf("bad: %#v", infraMock.ExecuteContext)
+	}
+}
+
+func TestInfrastructure_flavors(t *testing.T) {
+	client, server, streams := testNewClientServer(t)
+	defer streams.Close()
+	defer client.Close()
+
+	infraMock := server.InfraFunc().(*infrastructure.Mock)
+	infraReal, err := client.Infra()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	infraMock.FlavorsResult = []string{"simple", "vpc-public-private"}
+
+	actual := infraReal.Flavors()
+	if !reflect.DeepEqual(actual, infraMock.FlavorsResult) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestInfrastructure_verify(t *testing.T) {
+	clientConn, serverConn := testConn(t)
+	infraMock := new(infrastructure.MockVerifier)
+	infraMock.VerifyResult = &infrastructure.VerifyResult{
+		Missing: []string{"i-1234"},
+	}
+	server := &Server{InfraFunc: testInfraFixed(infraMock)}
+	streams := testNewStreams(t, server)
+	defer streams.Close()
+	go server.ServeConn(serverConn)
+
+	client, err := NewClient(clientConn)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer client.Close()
+
+	infraReal, err := client.Infra()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ctx := &infrastructure.Context{Outputs: map[string]string{"vpc_id": "vpc-1"}}
+	actual, err := infraReal.(infrastructure.Verifier).Verify(ctx)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, infraMock.VerifyResult) {
+		t.Fatalf("bad: %#v", actual)
+	}
+	if infraMock.VerifyContext.Outputs["vpc_id"] != "vpc-1" {
+		t.Fatalf("bad: %#v", infraMock.VerifyContext)
+	}
+}
+
+func TestInfrastructure_verifyNotSupported(t *testing.T) {
+	client, _, streams := testNewClientServer(t)
+	defer streams.Close()
+	defer client.Close()
+
+	infraReal, err := client.Infra()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, err = infraReal.(infrastructure.Verifier).Verify(new(infrastructure.Context))
+	if err != infrastructure.ErrVerifyNotSupported {
+		t.Fatalf("bad: %#v", err)
+	}
+}
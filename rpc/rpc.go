@@ -8,6 +8,8 @@ import (
 	"sync"
 
 	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/foundation"
+	"github.com/hashicorp/otto/infrastructure"
 )
 
 // nextId is the next ID to use for names registered.
@@ -18,6 +20,12 @@ func init() {
 	// We need this to avoid gob errors in logs when responding to UI
 	// calls (which are a no-op response).
 	gob.Register(new(struct{}))
+
+	// The configuration of foundations is decoded HCL, so these are the
+	// types that are in it.
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+	gob.Register([]map[string]interface{}{})
 }
 
 // Register registers an Otto thing with the RPC server and returns
@@ -30,6 +38,12 @@ func Register(server *rpc.Server, thing interface{}) (name string, err error) {
 	case app.App:
 		name = fmt.Sprintf("Otto%d", nextId)
 		err = server.RegisterName(name, &AppServer{App: t})
+	case infrastructure.Infrastructure:
+		name = fmt.Sprintf("Otto%d", nextId)
+		err = server.RegisterName(name, &InfrastructureServer{Infra: t})
+	case foundation.Foundation:
+		name = fmt.Sprintf("Otto%d", nextId)
+		err = server.RegisterName(name, &FoundationServer{Foundation: t})
 	default:
 		return "", errors.New("Unknown type to register for RPC server.")
 	}
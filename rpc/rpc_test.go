@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/foundation"
+	"github.com/hashicorp/otto/infrastructure"
 )
 
 func testConn(t *testing.T) (net.Conn, net.Conn) {
@@ -51,7 +53,11 @@ func testNewClientServer(t *testing.T) (*Client, *Server, *testStreams) {
 	clientConn, serverConn := testConn(t)
 
 	server := &Server{
-		AppFunc: testAppFixed(new(app.Mock)),
+		AppFunc:        testAppFixed(new(app.Mock)),
+		InfraFunc:      testInfraFixed(new(infrastructure.Mock)),
+		Infras:         []string{"test"},
+		FoundationFunc: testFoundationFixed(new(foundation.Mock)),
+		Foundations:    foundation.TupleSlice{{Type: "test", Infra: "test", InfraFlavor: "test"}},
 	}
 	streams := testNewStreams(t, server)
 	go server.ServeConn(serverConn)
@@ -83,6 +89,18 @@ func testAppFixed(c app.App) AppFunc {
 	}
 }
 
+func testInfraFixed(c infrastructure.Infrastructure) InfraFunc {
+	return func() infrastructure.Infrastructure {
+		return c
+	}
+}
+
+func testFoundationFixed(c foundation.Foundation) FoundationFunc {
+	return func() foundation.Foundation {
+		return c
+	}
+}
+
 type testStreams struct {
 	Stdout io.WriteCloser
 	Stderr io.WriteCloser
@@ -1,12 +1,15 @@
 package rpc
 
 import (
+	"errors"
 	"io"
 	"log"
 	"net"
 	"net/rpc"
 
 	"github.com/hashicorp/otto/app"
+	"github.com/hashicorp/otto/foundation"
+	"github.com/hashicorp/otto/infrastructure"
 	"github.com/hashicorp/yamux"
 )
 
@@ -15,6 +18,16 @@ import (
 type Server struct {
 	AppFunc AppFunc
 
+	// InfraFunc creates the infrastructure this server dispenses, which
+	// implements the infrastructure types in Infras.
+	InfraFunc InfraFunc
+	Infras    []string
+
+	// FoundationFunc creates the foundation this server dispenses, which
+	// implements the tuples in Foundations.
+	FoundationFunc FoundationFunc
+	Foundations    foundation.TupleSlice
+
 	// Stdout, Stderr are what this server will use instead of the
 	// normal stdin/out/err. This is because due to the multi-process nature
 	// of our plugin system, we can't use the normal process values so we
@@ -26,6 +39,22 @@ type Server struct {
 // AppFunc creates app.App when they're requested from the server.
 type AppFunc func() app.App
 
+// InfraFunc creates infrastructure.Infrastructure when they're requested
+// from the server.
+type InfraFunc func() infrastructure.Infrastructure
+
+// FoundationFunc creates foundation.Foundation when they're requested
+// from the server.
+type FoundationFunc func() foundation.Foundation
+
+// ServerMeta describes what a server dispenses, so that the client knows
+// which implementations to request.
+type ServerMeta struct {
+	App         bool
+	Infras      []string
+	Foundations foundation.TupleSlice
+}
+
 // Accept accepts connections on a listener and serves requests for
 // each incoming connection. Accept blocks; the caller typically invokes
 // it in a go statement.
@@ -84,25 +113,88 @@ func (s *Server) ServeConn(conn io.ReadWriteCloser) {
 	// connection.
 	server := rpc.NewServer()
 	server.RegisterName("Dispenser", &dispenseServer{
-		AppFunc: s.AppFunc,
+		AppFunc:        s.AppFunc,
+		InfraFunc:      s.InfraFunc,
+		FoundationFunc: s.FoundationFunc,
 
 		broker: broker,
+		meta: &ServerMeta{
+			App:         s.AppFunc != nil,
+			Infras:      s.Infras,
+			Foundations: s.Foundations,
+		},
 	})
 	server.ServeConn(control)
 }
 
 // dispenseServer dispenses variousinterface implementations for Terraform.
 type dispenseServer struct {
-	AppFunc AppFunc
+	AppFunc        AppFunc
+	InfraFunc      InfraFunc
+	FoundationFunc FoundationFunc
 
 	broker *muxBroker
+	meta   *ServerMeta
+}
+
+func (d *dispenseServer) Meta(
+	args interface{}, response *ServerMeta) error {
+	*response = *d.meta
+	return nil
 }
 
 func (d *dispenseServer) App(
 	args interface{}, response *uint32) error {
-	id := d.broker.NextId()
-	*response = id
+	if d.AppFunc == nil {
+		return errors.New("plugin doesn't serve an app")
+	}
+
+	*response = d.dispense("App", func() interface{} {
+		return &AppServer{
+			Broker: d.broker,
+			App:    d.AppFunc(),
+		}
+	})
 
+	return nil
+}
+
+func (d *dispenseServer) Infra(
+	args interface{}, response *uint32) error {
+	if d.InfraFunc == nil {
+		return errors.New("plugin doesn't serve an infrastructure")
+	}
+
+	*response = d.dispense("Infrastructure", func() interface{} {
+		return &InfrastructureServer{
+			Broker: d.broker,
+			Infra:  d.InfraFunc(),
+		}
+	})
+
+	return nil
+}
+
+func (d *dispenseServer) Foundation(
+	args interface{}, response *uint32) error {
+	if d.FoundationFunc == nil {
+		return errors.New("plugin doesn't serve a foundation")
+	}
+
+	*response = d.dispense("Foundation", func() interface{} {
+		return &FoundationServer{
+			Broker:     d.broker,
+			Foundation: d.FoundationFunc(),
+		}
+	})
+
+	return nil
+}
+
+// dispense serves the value that f creates under the given name on a
+// new connection of the broker and returns its ID.
+func (d *dispenseServer) dispense(name string, f func() interface{}) uint32 {
+	id := d.broker.NextId()
 	go func() {
 		conn, err := d.broker.Accept(id)
 		if err != nil {
@@ -110,13 +202,10 @@ func (d *dispenseServer) App(
 			return
 		}
 
-		serve(conn, "App", &AppServer{
-			Broker: d.broker,
-			App:    d.AppFunc(),
-		})
+		serve(conn, name, f())
 	}()
 
-	return nil
+	return id
 }
 
 func acceptAndServe(mux *muxBroker, id uint32, n string, v interface{}) {
@@ -1,6 +1,8 @@
 package ui
 
 import (
+	"os"
+
 	"github.com/mitchellh/colorstring"
 )
 
@@ -11,8 +13,13 @@ var colorstringDisable = colorstring.Colorize{
 }
 
 // Colorize is a helper to colorize the string according to the colorstring
-// library defaults.
+// library defaults. If the NO_COLOR environment variable is set, the
+// color tags are stripped instead.
 func Colorize(t string) string {
+	if os.Getenv("NO_COLOR") != "" {
+		return StripColors(t)
+	}
+
 	return colorstring.Color("[reset]" + t)
 }
 
@@ -20,3 +27,78 @@ func Colorize(t string) string {
 func StripColors(t string) string {
 	return colorstringDisable.Color(t)
 }
+
+// Style returns text styled with the given colorstring style, such as
+// "green" or "bold". The style only applies to text, and how (or if) it
+// is rendered is up to the Ui. Use this rather than embedding markup
+// directly in strings.
+func Style(text, style string) string {
+	return "[" + style + "]" + text + "[reset]"
+}
+
+// ColorUi is an optional interface implemented by Uis that render color
+// markup, allowing color to be turned off.
+type ColorUi interface {
+	SetColor(enabled bool)
+}
+
+// DisableColor disables color output for the Ui u. If u implements
+// ColorUi then color is disabled on u itself and u is returned. Otherwise,
+// u is wrapped with NoColor so all markup is stripped before reaching it.
+func DisableColor(u Ui) Ui {
+	if c, ok := u.(ColorUi); ok {
+		c.SetColor(false)
+		return u
+	}
+
+	return &NoColor{Ui: u}
+}
+
+// NoColor is a wrapper around an existing Ui that strips all color markup
+// from the output before it reaches the wrapped Ui.
+type NoColor struct {
+	Ui Ui
+}
+
+func (u *NoColor) Header(msg string) {
+	u.Ui.Header(StripColors(msg))
+}
+
+func (u *NoColor) Message(msg string) {
+	u.Ui.Message(StripColors(msg))
+}
+
+func (u *NoColor) Raw(msg string) {
+	u.Ui.Raw(StripColors(msg))
+}
+
+func (u *NoColor) Input(opts *InputOpts) (string, error) {
+	return u.Ui.Input(opts)
+}
+
+// Log implements LevelUi. If the wrapped Ui doesn't handle levels itself
+// then the message is output with Message without the level color.
+func (u *NoColor) Log(level Level, msg string) {
+	msg = StripColors(msg)
+	if l, ok := u.Ui.(LevelUi); ok {
+		l.Log(level, msg)
+		return
+	}
+
+	u.Ui.Message(levelPrefix(level) + msg)
+}
+
+// ErrorHint implements HintUi.
+func (u *NoColor) ErrorHint(msg, hint string) {
+	if h, ok := u.Ui.(HintUi); ok {
+		h.ErrorHint(StripColors(msg), StripColors(hint))
+		return
+	}
+
+	errorHintLines(u, msg, hint)
+}
+
+// Progress implements ProgressUi.
+func (u *NoColor) Progress(name string) ProgressHandle {
+	return Progress(u.Ui, StripColors(name))
+}
@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNoColor_impl(t *testing.T) {
+	var _ Ui = new(NoColor)
+	var _ LevelUi = new(NoColor)
+}
+
+func TestColorize_noColorEnv(t *testing.T) {
+	defer os.Setenv("NO_COLOR", os.Getenv("NO_COLOR"))
+	os.Setenv("NO_COLOR", "1")
+
+	actual := Colorize(Style("foo", "green"))
+	if actual != "foo" {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestStyle(t *testing.T) {
+	actual := Style("foo", "green")
+	if actual != "[green]foo[reset]" {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	actual = StripColors(actual)
+	if actual != "foo" {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestDisableColor(t *testing.T) {
+	u := new(Mock)
+	d := DisableColor(u)
+	d.Header(Style("header", "bold"))
+	d.Message(Style("message", "green"))
+	Warn(d, "warn")
+	Result(d, "result")
+
+	for _, buf := range [][]string{u.HeaderBuf, u.MessageBuf} {
+		for _, msg := range buf {
+			if strings.ContainsAny(msg, "[]\x1b") {
+				t.Fatalf("bad: %#v", msg)
+			}
+		}
+	}
+	if len(u.MessageBuf) != 3 {
+		t.Fatalf("bad: %#v", u.MessageBuf)
+	}
+}
+
+func TestDisableColor_colorUi(t *testing.T) {
+	u := new(mockColorUi)
+	d := DisableColor(&Styled{Ui: u})
+	if _, ok := d.(*Styled); !ok {
+		t.Fatalf("bad: %#v", d)
+	}
+	if !u.disabled {
+		t.Fatal("should disable")
+	}
+}
+
+type mockColorUi struct {
+	Mock
+
+	disabled bool
+}
+
+func (u *mockColorUi) SetColor(enabled bool) {
+	u.disabled = !enabled
+}
@@ -0,0 +1,39 @@
+package ui
+
+// HintUi is an optional interface that Ui implementations can implement
+// to render errors with a remediation hint themselves, for example to
+// report the hint separately from the message.
+//
+// Callers should use the ErrorHint function, which works with any Ui.
+type HintUi interface {
+	ErrorHint(msg, hint string)
+}
+
+// ErrorHint outputs the error msg at LevelError along with hint, a short
+// suggestion of how to fix the error. If u doesn't implement HintUi, the
+// hint is output on its own line after the message, styled by HintLine.
+// An empty hint is the same as calling Error.
+func ErrorHint(u Ui, msg, hint string) {
+	if h, ok := u.(HintUi); ok {
+		h.ErrorHint(msg, hint)
+		return
+	}
+
+	errorHintLines(u, msg, hint)
+}
+
+// errorHintLines outputs msg and hint as separate errors to u. This is
+// the ErrorHint fallback, and wrappers that style their output use it
+// when the Ui they wrap doesn't implement HintUi so that both lines are
+// styled by the wrapper's own Log.
+func errorHintLines(u Ui, msg, hint string) {
+	Error(u, msg)
+	if hint != "" {
+		Error(u, HintLine(hint))
+	}
+}
+
+// HintLine returns the line that shows hint to the user.
+func HintLine(hint string) string {
+	return Style("Hint:", "bold") + " " + hint
+}
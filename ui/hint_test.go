@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestJSON_hintUi(t *testing.T) {
+	var _ HintUi = new(JSON)
+}
+
+func TestErrorHint_adapter(t *testing.T) {
+	u := new(Mock)
+	ErrorHint(u, "failed", "try again")
+	ErrorHint(u, "no hint", "")
+
+	expected := []string{"[red]failed", "[red][bold]Hint:[reset] try again", "[red]no hint"}
+	if !reflect.DeepEqual(u.MessageBuf, expected) {
+		t.Fatalf("bad: %#v", u.MessageBuf)
+	}
+}
+
+func TestErrorHint_json(t *testing.T) {
+	var buf bytes.Buffer
+	u := &JSON{Writer: &buf}
+	ErrorHint(u, "failed", "run [bold]otto compile")
+
+	events := testJSONEvents(t, &buf)
+	if len(events) != 1 {
+		t.Fatalf("bad: %#v", events)
+	}
+	if events[0].Type != "error" || events[0].Text != "failed" || events[0].Hint != "run otto compile" {
+		t.Fatalf("bad: %#v", events[0])
+	}
+}
+
+func TestErrorHint_wrapped(t *testing.T) {
+	var buf bytes.Buffer
+	secrets := &Redacted{Ui: NewLocked(&JSON{Writer: &buf})}
+	secrets.AddSecret("hunter2")
+	u := &Filtered{Ui: secrets, Level: LevelInfo}
+	ErrorHint(u, "failed with hunter2", "rotate hunter2")
+
+	events := testJSONEvents(t, &buf)
+	if len(events) != 1 {
+		t.Fatalf("bad: %#v", events)
+	}
+	e := events[0]
+	if e.Type != "error" || e.Text != "failed with <redacted>" || e.Hint != "rotate <redacted>" {
+		t.Fatalf("bad: %#v", e)
+	}
+}
@@ -0,0 +1,121 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrNonInteractive is the error returned when input is requested from
+// a NonInteractive Ui.
+type ErrNonInteractive struct {
+	// Id and Query are from the InputOpts of the input request.
+	// EnvVars are the environment variables that could've been set
+	// to answer the request instead.
+	Id      string
+	Query   string
+	EnvVars []string
+}
+
+func (e *ErrNonInteractive) Error() string {
+	msg := fmt.Sprintf(
+		"input '%s' (%s) requested while running non-interactively",
+		e.Id, e.Query)
+	if len(e.EnvVars) > 0 {
+		msg += fmt.Sprintf(
+			". Set one of these environment variables to provide it: %s",
+			strings.Join(e.EnvVars, ", "))
+	}
+
+	return msg
+}
+
+// NonInteractive is a wrapper around an existing Ui that fails all
+// input requests with an *ErrNonInteractive, unless the input can be
+// read from the environment variables configured for the request.
+// This is useful in environments such as CI where an input request
+// would otherwise hang forever.
+type NonInteractive struct {
+	Ui Ui
+}
+
+func (u *NonInteractive) Header(msg string)  { u.Ui.Header(msg) }
+func (u *NonInteractive) Message(msg string) { u.Ui.Message(msg) }
+func (u *NonInteractive) Raw(msg string)     { u.Ui.Raw(msg) }
+
+func (u *NonInteractive) Input(opts *InputOpts) (string, error) {
+	if value := opts.EnvVarValue(); value != "" {
+		if err := opts.ValidateValue(value); err != nil {
+			return "", fmt.Errorf(
+				"Invalid value for '%s' from environment: %s", opts.Id, err)
+		}
+
+		return value, nil
+	}
+
+	return "", &ErrNonInteractive{
+		Id:      opts.Id,
+		Query:   opts.Query,
+		EnvVars: opts.EnvVars,
+	}
+}
+
+// Log implements LevelUi.
+func (u *NonInteractive) Log(level Level, msg string) { Log(u.Ui, level, msg) }
+
+// ErrorHint implements HintUi.
+func (u *NonInteractive) ErrorHint(msg, hint string) { ErrorHint(u.Ui, msg, hint) }
+
+// Progress implements ProgressUi.
+func (u *NonInteractive) Progress(name string) ProgressHandle {
+	return Progress(u.Ui, name)
+}
+
+// InputTimeout is a wrapper around an existing Ui that fails an input
+// request if it isn't answered within Timeout. This is meant for
+// interactive but unattended environments so that a forgotten prompt
+// eventually fails rather than hanging forever.
+//
+// The wrapped Ui's Input call can't be canceled, so it is left running
+// in the background when the timeout is reached.
+type InputTimeout struct {
+	Ui      Ui
+	Timeout time.Duration
+}
+
+func (u *InputTimeout) Header(msg string)  { u.Ui.Header(msg) }
+func (u *InputTimeout) Message(msg string) { u.Ui.Message(msg) }
+func (u *InputTimeout) Raw(msg string)     { u.Ui.Raw(msg) }
+
+func (u *InputTimeout) Input(opts *InputOpts) (string, error) {
+	type result struct {
+		value string
+		err   error
+	}
+
+	resultCh := make(chan result, 1)
+	go func() {
+		value, err := u.Ui.Input(opts)
+		resultCh <- result{value, err}
+	}()
+
+	select {
+	case r := <-resultCh:
+		return r.value, r.err
+	case <-time.After(u.Timeout):
+		return "", fmt.Errorf(
+			"timed out after %s waiting for input '%s' (%s)",
+			u.Timeout, opts.Id, opts.Query)
+	}
+}
+
+// Log implements LevelUi.
+func (u *InputTimeout) Log(level Level, msg string) { Log(u.Ui, level, msg) }
+
+// ErrorHint implements HintUi.
+func (u *InputTimeout) ErrorHint(msg, hint string) { ErrorHint(u.Ui, msg, hint) }
+
+// Progress implements ProgressUi.
+func (u *InputTimeout) Progress(name string) ProgressHandle {
+	return Progress(u.Ui, name)
+}
@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNonInteractive_impl(t *testing.T) {
+	var _ Ui = new(NonInteractive)
+	var _ Ui = new(InputTimeout)
+}
+
+func TestNonInteractive(t *testing.T) {
+	u := &Mock{InputResult: "foo"}
+	ni := &NonInteractive{Ui: u}
+
+	_, err := ni.Input(&InputOpts{
+		Id:      "foo",
+		Query:   "Foo?",
+		EnvVars: []string{"OTTO_TEST_NONINTERACTIVE"},
+	})
+	if err == nil {
+		t.Fatal("should error")
+	}
+	nerr, ok := err.(*ErrNonInteractive)
+	if !ok {
+		t.Fatalf("bad: %#v", err)
+	}
+	if nerr.Id != "foo" {
+		t.Fatalf("bad: %#v", nerr)
+	}
+	if !strings.Contains(err.Error(), "OTTO_TEST_NONINTERACTIVE") {
+		t.Fatalf("bad: %s", err)
+	}
+	if u.InputCalled {
+		t.Fatal("input should not be called")
+	}
+}
+
+func TestNonInteractive_env(t *testing.T) {
+	defer os.Setenv("OTTO_TEST_NONINTERACTIVE", "")
+	os.Setenv("OTTO_TEST_NONINTERACTIVE", "bar")
+
+	ni := &NonInteractive{Ui: new(Mock)}
+	v, err := ni.Input(&InputOpts{
+		Id:      "foo",
+		EnvVars: []string{"OTTO_TEST_NONINTERACTIVE"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v != "bar" {
+		t.Fatalf("bad: %#v", v)
+	}
+}
+
+func TestInputTimeout(t *testing.T) {
+	u := &InputTimeout{Ui: &blockingInputUi{}, Timeout: 10 * time.Millisecond}
+	_, err := u.Input(&InputOpts{Id: "foo"})
+	if err == nil {
+		t.Fatal("should error")
+	}
+
+	u = &InputTimeout{Ui: &Mock{InputResult: "foo"}, Timeout: time.Second}
+	v, err := u.Input(&InputOpts{Id: "foo"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v != "foo" {
+		t.Fatalf("bad: %#v", v)
+	}
+}
+
+type blockingInputUi struct {
+	Mock
+}
+
+func (u *blockingInputUi) Input(*InputOpts) (string, error) {
+	select {}
+}
@@ -0,0 +1,250 @@
+package ui
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JSON is an implementation of Ui that outputs machine-readable data:
+// every call results in exactly one JSON object written on its own line
+// to Writer. This is meant for tools that wrap Otto (editors, web UIs)
+// and would otherwise have to scrape colored text.
+//
+// Color markup in messages is stripped from the text. The leading
+// color tags of a message (if any) are reported in the "style" field
+// so consumers can still render it appropriately.
+//
+// Input requests are written as an "input" event with a unique "id".
+// The answer must be written to Reader as a single line JSON object
+// of the form {"id": "<id>", "value": "<answer>"}. Answers for other
+// ids are ignored.
+type JSON struct {
+	// Writer is where the JSON events are written. This is required.
+	Writer io.Writer
+
+	// Reader is where answers to input requests are read from. If this
+	// is nil, all input requests will fail.
+	Reader io.Reader
+
+	lock      sync.Mutex
+	inputLock sync.Mutex
+	inputId   uint64
+	scanner   *bufio.Scanner
+}
+
+// JSONEvent is the structure of a single event written by the JSON Ui.
+type JSONEvent struct {
+	// Type is the type of the event: "header", "message", "raw",
+	// "input", or the name of a Level ("debug", "info", "warn", "error",
+	// "result").
+	Type string `json:"type"`
+
+	// Timestamp is the time the event was emitted, in UTC.
+	Timestamp time.Time `json:"timestamp"`
+
+	// Text is the text of the event with all color markup removed.
+	//
+	// Style is the list of leading styles that were applied to the text,
+	// such as "green" or "bold", comma-separated.
+	Text  string `json:"text,omitempty"`
+	Style string `json:"style,omitempty"`
+
+	// Hint is only set for "error" events of errors that have a
+	// remediation hint. See ErrorHint.
+	Hint string `json:"hint,omitempty"`
+
+	// The fields below are only set for "input" events. Id is the id
+	// that must be used to answer the input request and Name is the
+	// Id from the InputOpts.
+	Id          string `json:"id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Query       string `json:"query,omitempty"`
+	Description string `json:"description,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Secret      bool   `json:"secret,omitempty"`
+	Pattern     string `json:"pattern,omitempty"`
+}
+
+// JSONAnswer is the structure that must be written to the JSON Ui's
+// Reader to answer an input request.
+type JSONAnswer struct {
+	Id    string `json:"id"`
+	Value string `json:"value"`
+}
+
+func (u *JSON) Header(msg string) {
+	u.emit(u.textEvent("header", msg))
+}
+
+func (u *JSON) Message(msg string) {
+	u.emit(u.textEvent("message", msg))
+}
+
+func (u *JSON) Raw(msg string) {
+	u.emit(u.textEvent("raw", msg))
+}
+
+// Error outputs an error event. This isn't part of the Ui interface but
+// it lets callers that know they're working with the JSON Ui report
+// failures in a way that tools can distinguish from regular messages.
+func (u *JSON) Error(msg string) {
+	u.emit(u.textEvent("error", msg))
+}
+
+// ErrorHint implements HintUi. The hint is reported in the "hint" field
+// of the error event rather than as a separate event.
+func (u *JSON) ErrorHint(msg, hint string) {
+	e := u.textEvent("error", msg)
+	e.Hint = StripColors(hint)
+	u.emit(e)
+}
+
+// Log implements LevelUi. The type of the event is the name of the level.
+func (u *JSON) Log(level Level, msg string) {
+	u.emit(u.textEvent(level.String(), msg))
+}
+
+func (u *JSON) Input(opts *InputOpts) (string, error) {
+	// If any of the configured EnvVars are set, we don't ask for input.
+	if value := opts.EnvVarValue(); value != "" {
+		return value, nil
+	}
+
+	// Only one input request can be outstanding at a time since
+	// we're reading answers from a single stream.
+	u.inputLock.Lock()
+	defer u.inputLock.Unlock()
+
+	if u.Reader == nil {
+		return "", fmt.Errorf(
+			"input requested for '%s' but no input reader is configured",
+			opts.Id)
+	}
+
+	id, err := u.requestInput(opts)
+	if err != nil {
+		return "", err
+	}
+
+	if u.scanner == nil {
+		u.scanner = bufio.NewScanner(u.Reader)
+	}
+	for u.scanner.Scan() {
+		line := strings.TrimSpace(u.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var answer JSONAnswer
+		if err := json.Unmarshal([]byte(line), &answer); err != nil {
+			return "", fmt.Errorf(
+				"error decoding answer for input '%s': %s", opts.Id, err)
+		}
+		if answer.Id != id {
+			continue
+		}
+
+		if answer.Value == "" {
+			answer.Value = opts.Default
+		}
+
+		// If the value is invalid, report the error and ask again
+		// with a new id so stale answers aren't accepted.
+		if err := opts.ValidateValue(answer.Value); err != nil {
+			u.emit(u.textEvent("error", fmt.Sprintf(
+				"Invalid value for '%s': %s", opts.Id, err)))
+
+			id, err = u.requestInput(opts)
+			if err != nil {
+				return "", err
+			}
+
+			continue
+		}
+
+		return answer.Value, nil
+	}
+	if err := u.scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf(
+		"input stream closed before answering input '%s'", opts.Id)
+}
+
+// requestInput writes an input event and returns its id. The input
+// lock must be held.
+func (u *JSON) requestInput(opts *InputOpts) (string, error) {
+	u.inputId++
+	id := strconv.FormatUint(u.inputId, 10)
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	return id, u.write(&JSONEvent{
+		Type:        "input",
+		Timestamp:   time.Now().UTC(),
+		Id:          id,
+		Name:        opts.Id,
+		Query:       opts.Query,
+		Description: opts.Description,
+		Default:     opts.Default,
+		Secret:      opts.Hide,
+		Pattern:     opts.Pattern,
+	})
+}
+
+func (u *JSON) textEvent(t, msg string) *JSONEvent {
+	return &JSONEvent{
+		Type:      t,
+		Timestamp: time.Now().UTC(),
+		Text:      StripColors(msg),
+		Style:     strings.Join(leadingStyles(msg), ","),
+	}
+}
+
+func (u *JSON) emit(e *JSONEvent) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	// There isn't anything reasonable we can do with a write error
+	// since the Ui interface has no way to report it.
+	u.write(e)
+}
+
+// write writes a single event. The write lock must be held.
+func (u *JSON) write(e *JSONEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = u.Writer.Write(append(data, '\n'))
+	return err
+}
+
+var leadingStyleRe = regexp.MustCompile(`^\[([a-z_]+)\]`)
+
+// leadingStyles returns the list of colorstring style names at the
+// beginning of msg, ignoring "reset".
+func leadingStyles(msg string) []string {
+	var result []string
+	for {
+		match := leadingStyleRe.FindStringSubmatch(msg)
+		if match == nil {
+			return result
+		}
+
+		if match[1] != "reset" {
+			result = append(result, match[1])
+		}
+
+		msg = msg[len(match[0]):]
+	}
+}
@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSON_impl(t *testing.T) {
+	var _ Ui = new(JSON)
+}
+
+func TestJSON_message(t *testing.T) {
+	var buf bytes.Buffer
+	u := &JSON{Writer: &buf}
+	u.Header("[bold]hello")
+	u.Message("[reset][green]CREATED")
+
+	events := testJSONEvents(t, &buf)
+	if len(events) != 2 {
+		t.Fatalf("bad: %#v", events)
+	}
+
+	if events[0].Type != "header" || events[0].Text != "hello" || events[0].Style != "bold" {
+		t.Fatalf("bad: %#v", events[0])
+	}
+	if events[1].Type != "message" || events[1].Text != "CREATED" || events[1].Style != "green" {
+		t.Fatalf("bad: %#v", events[1])
+	}
+	if events[1].Timestamp.IsZero() {
+		t.Fatal("timestamp should be set")
+	}
+}
+
+func TestJSON_input(t *testing.T) {
+	var buf bytes.Buffer
+	u := &JSON{
+		Writer: &buf,
+		Reader: strings.NewReader(
+			`{"id": "2", "value": "wrong"}` + "\n" +
+				`{"id": "1", "value": "bar"}` + "\n"),
+	}
+
+	v, err := u.Input(&InputOpts{Id: "foo", Query: "Foo?", Hide: true})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v != "bar" {
+		t.Fatalf("bad: %s", v)
+	}
+
+	events := testJSONEvents(t, &buf)
+	if len(events) != 1 {
+		t.Fatalf("bad: %#v", events)
+	}
+	e := events[0]
+	if e.Type != "input" || e.Id != "1" || e.Name != "foo" || e.Query != "Foo?" || !e.Secret {
+		t.Fatalf("bad: %#v", e)
+	}
+}
+
+func TestJSON_inputInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	u := &JSON{
+		Writer: &buf,
+		Reader: strings.NewReader(
+			`{"id": "1", "value": "foo"}` + "\n" +
+				`{"id": "2", "value": "42"}` + "\n"),
+	}
+
+	v, err := u.Input(&InputOpts{Id: "foo", Pattern: `^[0-9]+$`})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v != "42" {
+		t.Fatalf("bad: %s", v)
+	}
+
+	events := testJSONEvents(t, &buf)
+	if len(events) != 3 {
+		t.Fatalf("bad: %#v", events)
+	}
+	if events[1].Type != "error" {
+		t.Fatalf("bad: %#v", events[1])
+	}
+	if events[2].Type != "input" || events[2].Id != "2" || events[2].Pattern == "" {
+		t.Fatalf("bad: %#v", events[2])
+	}
+}
+
+func TestJSON_inputClosed(t *testing.T) {
+	var buf bytes.Buffer
+	u := &JSON{Writer: &buf, Reader: strings.NewReader("")}
+	if _, err := u.Input(&InputOpts{Id: "foo"}); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestJSON_inputNoReader(t *testing.T) {
+	var buf bytes.Buffer
+	u := &JSON{Writer: &buf}
+	if _, err := u.Input(&InputOpts{Id: "foo"}); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func testJSONEvents(t *testing.T, buf *bytes.Buffer) []*JSONEvent {
+	var result []*JSONEvent
+	scan := bufio.NewScanner(buf)
+	for scan.Scan() {
+		var e JSONEvent
+		if err := json.Unmarshal(scan.Bytes(), &e); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		result = append(result, &e)
+	}
+
+	return result
+}
@@ -0,0 +1,176 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level is the level of importance of a message output to the Ui.
+//
+// The zero value is LevelInfo, which is the level of all output through
+// the standard Ui methods (Header, Message, Raw).
+//
+// LevelResult is used for the final outcome of an operation, such as a
+// one-line summary. It is the most important level so that it is always
+// shown, even when only errors are otherwise output.
+type Level int
+
+const (
+	LevelDebug Level = iota - 1
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelResult
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	case LevelResult:
+		return "result"
+	default:
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+}
+
+// ParseLevel parses the string form of a level as returned by
+// Level.String.
+func ParseLevel(v string) (Level, error) {
+	switch strings.ToLower(v) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	case "result":
+		return LevelResult, nil
+	default:
+		return LevelInfo, fmt.Errorf("unknown level: %s", v)
+	}
+}
+
+// LevelUi is an optional interface that Ui implementations can implement
+// to handle messages of a specific level themselves, for example to
+// filter them or to render them differently.
+//
+// Callers shouldn't call Log directly and should instead use the Log
+// function (or the Debug, Info, Warn, Error helpers) in this package,
+// which work with any Ui.
+type LevelUi interface {
+	Log(level Level, msg string)
+}
+
+// Log outputs msg at the given level to the Ui u. If u doesn't implement
+// LevelUi, the message is output with Message, colored by level.
+func Log(u Ui, level Level, msg string) {
+	if l, ok := u.(LevelUi); ok {
+		l.Log(level, msg)
+		return
+	}
+
+	u.Message(levelStyle(level) + levelPrefix(level) + msg)
+}
+
+// levelPrefix returns the text that prefixes messages of the given level
+// when the Ui doesn't handle levels itself, so that warnings stand out
+// even without color.
+func levelPrefix(level Level) string {
+	if level == LevelWarn {
+		return "WARNING: "
+	}
+
+	return ""
+}
+
+// levelStyle returns the color markup used for messages of the given
+// level when the Ui doesn't handle levels itself.
+func levelStyle(level Level) string {
+	switch level {
+	case LevelWarn:
+		return "[yellow]"
+	case LevelError:
+		return "[red]"
+	case LevelResult:
+		return "[green]"
+	default:
+		return ""
+	}
+}
+
+// Debug outputs a message at LevelDebug. See Log.
+func Debug(u Ui, msg string) { Log(u, LevelDebug, msg) }
+
+// Info outputs a message at LevelInfo. See Log.
+func Info(u Ui, msg string) { Log(u, LevelInfo, msg) }
+
+// Warn outputs a message at LevelWarn. See Log.
+func Warn(u Ui, msg string) { Log(u, LevelWarn, msg) }
+
+// Error outputs a message at LevelError. See Log.
+func Error(u Ui, msg string) { Log(u, LevelError, msg) }
+
+// Result outputs a message at LevelResult. See Log.
+func Result(u Ui, msg string) { Log(u, LevelResult, msg) }
+
+// Filtered is a wrapper around an existing Ui that only outputs messages
+// that are at or above a minimum level. Header, Message, and Raw are
+// treated as LevelInfo. Input is never filtered.
+type Filtered struct {
+	Ui    Ui
+	Level Level
+}
+
+func (u *Filtered) Header(msg string) {
+	if u.Level <= LevelInfo {
+		u.Ui.Header(msg)
+	}
+}
+
+func (u *Filtered) Message(msg string) {
+	if u.Level <= LevelInfo {
+		u.Ui.Message(msg)
+	}
+}
+
+func (u *Filtered) Raw(msg string) {
+	if u.Level <= LevelInfo {
+		u.Ui.Raw(msg)
+	}
+}
+
+func (u *Filtered) Input(opts *InputOpts) (string, error) {
+	return u.Ui.Input(opts)
+}
+
+// Log implements LevelUi.
+func (u *Filtered) Log(level Level, msg string) {
+	if level >= u.Level {
+		Log(u.Ui, level, msg)
+	}
+}
+
+// ErrorHint implements HintUi. The hint is filtered with its error.
+func (u *Filtered) ErrorHint(msg, hint string) {
+	if LevelError >= u.Level {
+		ErrorHint(u.Ui, msg, hint)
+	}
+}
+
+// Progress implements ProgressUi. Progress is treated as LevelInfo.
+func (u *Filtered) Progress(name string) ProgressHandle {
+	if u.Level > LevelInfo {
+		return new(nullProgress)
+	}
+
+	return Progress(u.Ui, name)
+}
@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFiltered_impl(t *testing.T) {
+	var _ Ui = new(Filtered)
+	var _ LevelUi = new(Filtered)
+}
+
+func TestLog_adapter(t *testing.T) {
+	u := new(Mock)
+	Debug(u, "debug")
+	Info(u, "info")
+	Warn(u, "warn")
+	Error(u, "error")
+
+	expected := []string{"debug", "info", "[yellow]WARNING: warn", "[red]error"}
+	if !reflect.DeepEqual(u.MessageBuf, expected) {
+		t.Fatalf("bad: %#v", u.MessageBuf)
+	}
+}
+
+func TestFiltered(t *testing.T) {
+	u := new(Mock)
+	f := &Filtered{Ui: u, Level: LevelWarn}
+	f.Header("header")
+	f.Message("message")
+	Info(f, "info")
+	Warn(f, "warn")
+	Error(f, "error")
+
+	if len(u.HeaderBuf) != 0 {
+		t.Fatalf("bad: %#v", u.HeaderBuf)
+	}
+
+	expected := []string{"[yellow]WARNING: warn", "[red]error"}
+	if !reflect.DeepEqual(u.MessageBuf, expected) {
+		t.Fatalf("bad: %#v", u.MessageBuf)
+	}
+}
+
+func TestFiltered_default(t *testing.T) {
+	u := new(Mock)
+	f := &Filtered{Ui: u}
+	f.Message("message")
+	Debug(f, "debug")
+	Info(f, "info")
+
+	expected := []string{"message", "info"}
+	if !reflect.DeepEqual(u.MessageBuf, expected) {
+		t.Fatalf("bad: %#v", u.MessageBuf)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, l := range []Level{LevelDebug, LevelInfo, LevelWarn, LevelError, LevelResult} {
+		actual, err := ParseLevel(l.String())
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if actual != l {
+			t.Fatalf("bad: %s", actual)
+		}
+	}
+
+	if _, err := ParseLevel("nope"); err == nil {
+		t.Fatal("should error")
+	}
+}
@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"sync"
+)
+
+// Locked is a wrapper around an existing Ui that serializes all calls
+// to it with a mutex, making any Ui safe for concurrent use. Each call
+// is passed through atomically, so a single multi-line Message from one
+// goroutine is never interleaved with output from another.
+//
+// Input holds the lock while waiting for the answer so that other
+// output doesn't overwrite the prompt.
+type Locked struct {
+	Ui Ui
+
+	lock sync.Mutex
+}
+
+// NewLocked returns a Ui that serializes all calls to inner.
+func NewLocked(inner Ui) *Locked {
+	return &Locked{Ui: inner}
+}
+
+func (u *Locked) Header(msg string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.Ui.Header(msg)
+}
+
+func (u *Locked) Message(msg string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.Ui.Message(msg)
+}
+
+func (u *Locked) Raw(msg string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.Ui.Raw(msg)
+}
+
+func (u *Locked) Input(opts *InputOpts) (string, error) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	return u.Ui.Input(opts)
+}
+
+// Log implements LevelUi.
+func (u *Locked) Log(level Level, msg string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	Log(u.Ui, level, msg)
+}
+
+// ErrorHint implements HintUi. The message and hint are output under a
+// single lock so they're never separated by other output.
+func (u *Locked) ErrorHint(msg, hint string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	ErrorHint(u.Ui, msg, hint)
+}
+
+// Progress implements ProgressUi. Updates to the returned handle are
+// serialized with all other calls.
+func (u *Locked) Progress(name string) ProgressHandle {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	return &lockedProgress{
+		ProgressHandle: Progress(u.Ui, name),
+		lock:           &u.lock,
+	}
+}
+
+// SetColor implements ColorUi.
+func (u *Locked) SetColor(enabled bool) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if c, ok := u.Ui.(ColorUi); ok {
+		c.SetColor(enabled)
+	}
+}
+
+type lockedProgress struct {
+	ProgressHandle
+
+	lock *sync.Mutex
+}
+
+func (p *lockedProgress) Update(current, total int64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.ProgressHandle.Update(current, total)
+}
+
+func (p *lockedProgress) Done() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.ProgressHandle.Done()
+}
@@ -0,0 +1,55 @@
+package ui
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestLocked_impl(t *testing.T) {
+	var _ Ui = new(Locked)
+	var _ LevelUi = new(Locked)
+	var _ ProgressUi = new(Locked)
+}
+
+func TestLocked_concurrent(t *testing.T) {
+	u := new(Mock)
+	l := NewLocked(u)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			p := Progress(l, fmt.Sprintf("p%d", i))
+			for j := 0; j < 20; j++ {
+				l.Header(fmt.Sprintf("%d-%d", i, j))
+				l.Message(fmt.Sprintf("%d-%d\n%d-%d", i, j, i, j))
+				l.Raw(fmt.Sprintf("%d-%d\n", i, j))
+				Warn(l, fmt.Sprintf("%d-%d", i, j))
+				p.Update(int64(j), 20)
+			}
+			p.Done()
+		}(i)
+	}
+	wg.Wait()
+
+	if len(u.HeaderBuf) != 50*20 {
+		t.Fatalf("bad: %d", len(u.HeaderBuf))
+	}
+	if len(u.RawBuf) != 50*20 {
+		t.Fatalf("bad: %d", len(u.RawBuf))
+	}
+
+	// Every multi-line message must be intact
+	for _, msg := range u.MessageBuf {
+		var a, b, c, d int
+		if _, err := fmt.Sscanf(msg, "%d-%d\n%d-%d", &a, &b, &c, &d); err != nil {
+			continue
+		}
+		if a != c || b != d {
+			t.Fatalf("bad: %#v", msg)
+		}
+	}
+}
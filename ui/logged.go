@@ -29,3 +29,22 @@ func (l *Logged) Input(opts *InputOpts) (string, error) {
 	// Not sure what to log here.
 	return l.Ui.Input(opts)
 }
+
+func (l *Logged) SetColor(enabled bool) {
+	if c, ok := l.Ui.(ColorUi); ok {
+		c.SetColor(enabled)
+	}
+}
+
+func (l *Logged) Log(level Level, msg string) {
+	log.Printf("[INFO] ui %s: %s", level, msg)
+	Log(l.Ui, level, msg)
+}
+
+func (l *Logged) Progress(name string) ProgressHandle {
+	if p, ok := l.Ui.(ProgressUi); ok {
+		return p.Progress(name)
+	}
+
+	return NewLineProgress(l, name)
+}
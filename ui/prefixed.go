@@ -0,0 +1,140 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+)
+
+// prefixColors are the colors that are assigned to prefixes. Red is
+// deliberately excluded since it usually denotes an error.
+var prefixColors = []string{
+	"cyan", "magenta", "yellow", "blue", "green",
+	"light_cyan", "light_magenta", "light_yellow", "light_blue", "light_green",
+}
+
+// Prefixed is a wrapper around an existing Ui that prefixes every line
+// of output with a fixed prefix, such as the name of an application.
+// This keeps output legible when multiple things write to the same
+// underlying Ui concurrently.
+//
+// Prefixed is safe for concurrent use. Raw output that doesn't end in
+// a newline is buffered until the line is complete (or Flush is called)
+// so that concurrent writers can't interleave in the middle of a line.
+type Prefixed struct {
+	Ui     Ui
+	Prefix string
+
+	lock   sync.Mutex
+	rawBuf bytes.Buffer
+}
+
+// NewPrefixed returns a Ui that prefixes all output to inner with
+// the given prefix. The prefix is colored with a color that is chosen
+// deterministically from the prefix text so a given prefix always
+// has the same color.
+func NewPrefixed(inner Ui, prefix string) *Prefixed {
+	return &Prefixed{Ui: inner, Prefix: prefix}
+}
+
+func (u *Prefixed) Header(msg string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.Ui.Header(u.prefix(msg))
+}
+
+func (u *Prefixed) Message(msg string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.Ui.Message(u.prefix(msg))
+}
+
+func (u *Prefixed) Raw(msg string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.rawBuf.WriteString(msg)
+	data := u.rawBuf.String()
+	idx := strings.LastIndex(data, "\n")
+	if idx < 0 {
+		return
+	}
+
+	u.rawBuf.Reset()
+	u.rawBuf.WriteString(data[idx+1:])
+	u.Ui.Raw(u.prefix(data[:idx]) + "\n")
+}
+
+func (u *Prefixed) Input(opts *InputOpts) (string, error) {
+	// Copy the options so that we don't modify the caller's copy, and
+	// make it clear who is asking for the input.
+	newOpts := *opts
+	newOpts.Query = fmt.Sprintf("[%s] %s", u.Prefix, opts.Query)
+	return u.Ui.Input(&newOpts)
+}
+
+// Log implements LevelUi. The level color is applied before prefixing
+// so that it isn't reset by the colored prefix.
+func (u *Prefixed) Log(level Level, msg string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	Log(u.Ui, level, u.prefix(levelStyle(level)+msg))
+}
+
+// Progress implements ProgressUi. The name of the progress is prefixed
+// in the same way as any other output.
+func (u *Prefixed) Progress(name string) ProgressHandle {
+	name = fmt.Sprintf("%s: %s", u.Prefix, name)
+	if p, ok := u.Ui.(ProgressUi); ok {
+		return p.Progress(name)
+	}
+
+	return NewLineProgress(u.Ui, name)
+}
+
+// Flush outputs any buffered partial line from Raw.
+func (u *Prefixed) Flush() {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	if u.rawBuf.Len() == 0 {
+		return
+	}
+
+	data := u.rawBuf.String()
+	u.rawBuf.Reset()
+	u.Ui.Raw(u.prefix(data) + "\n")
+}
+
+// prefix prefixes every line of msg. Color markup that starts the
+// message is moved after the prefix so it still applies to every line.
+func (u *Prefixed) prefix(msg string) string {
+	prefix := fmt.Sprintf("[%s]%s:[reset] ", prefixColor(u.Prefix), u.Prefix)
+
+	// Find the leading color markup so we can reapply it for each line
+	// after our prefix resets the color.
+	var style string
+	for _, s := range leadingStyles(msg) {
+		style += "[" + s + "]"
+	}
+
+	lines := strings.Split(msg, "\n")
+	for i, line := range lines {
+		if i > 0 {
+			line = style + line
+		}
+
+		lines[i] = prefix + line
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// prefixColor returns the color for the given prefix.
+func prefixColor(prefix string) string {
+	h := fnv.New32a()
+	h.Write([]byte(prefix))
+	return prefixColors[h.Sum32()%uint32(len(prefixColors))]
+}
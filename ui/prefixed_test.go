@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPrefixed_impl(t *testing.T) {
+	var _ Ui = new(Prefixed)
+}
+
+func TestPrefixed(t *testing.T) {
+	mock := new(Mock)
+	u := NewPrefixed(mock, "foo")
+	prefix := "[" + prefixColor("foo") + "]foo:[reset] "
+
+	u.Header("hello")
+	u.Message("[green]one\ntwo")
+
+	expected := []string{prefix + "hello"}
+	if !reflect.DeepEqual(mock.HeaderBuf, expected) {
+		t.Fatalf("bad: %#v", mock.HeaderBuf)
+	}
+
+	expected = []string{prefix + "[green]one\n" + prefix + "[green]two"}
+	if !reflect.DeepEqual(mock.MessageBuf, expected) {
+		t.Fatalf("bad: %#v", mock.MessageBuf)
+	}
+}
+
+func TestPrefixed_raw(t *testing.T) {
+	mock := new(Mock)
+	u := NewPrefixed(mock, "foo")
+	prefix := "[" + prefixColor("foo") + "]foo:[reset] "
+
+	u.Raw("partial")
+	if len(mock.RawBuf) != 0 {
+		t.Fatalf("should buffer: %#v", mock.RawBuf)
+	}
+
+	u.Raw(" line\nnext")
+	u.Flush()
+
+	expected := []string{
+		prefix + "partial line\n",
+		prefix + "next\n",
+	}
+	if !reflect.DeepEqual(mock.RawBuf, expected) {
+		t.Fatalf("bad: %#v", mock.RawBuf)
+	}
+}
+
+func TestPrefixed_input(t *testing.T) {
+	mock := new(Mock)
+	u := NewPrefixed(mock, "foo")
+
+	opts := &InputOpts{Id: "bar", Query: "Bar?"}
+	if _, err := u.Input(opts); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if mock.InputOpts.Query != "[foo] Bar?" {
+		t.Fatalf("bad: %#v", mock.InputOpts)
+	}
+	if opts.Query != "Bar?" {
+		t.Fatal("should not modify original")
+	}
+}
+
+func TestPrefixColor_deterministic(t *testing.T) {
+	if prefixColor("foo") != prefixColor("foo") {
+		t.Fatal("should be deterministic")
+	}
+}
@@ -0,0 +1,254 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProgressHandle is used to report the progress of a single long-running
+// operation. Update can be called any number of times and Done must be
+// called once the operation completes (successfully or not).
+//
+// If the total isn't known, total can be zero or negative and only the
+// current value will be shown.
+type ProgressHandle interface {
+	Update(current, total int64)
+	Done()
+}
+
+// ProgressUi is an optional interface that Ui implementations can
+// implement to render progress natively, such as with a progress bar.
+type ProgressUi interface {
+	Progress(name string) ProgressHandle
+}
+
+// Progress returns a ProgressHandle for reporting the progress of the
+// named operation to the Ui u. If u implements ProgressUi then that is
+// used, otherwise progress is reported as periodic messages.
+func Progress(u Ui, name string) ProgressHandle {
+	if p, ok := u.(ProgressUi); ok {
+		return p.Progress(name)
+	}
+
+	return NewLineProgress(u, name)
+}
+
+// progressLineInterval is the minimum time between progress lines output
+// by the line progress implementation.
+var progressLineInterval = 5 * time.Second
+
+// NewLineProgress returns a ProgressHandle that reports progress to u
+// by outputting a message with the percentage complete periodically.
+// This is meant for output that isn't a terminal where progress can't
+// be rendered in-place. Fast operations won't output anything.
+func NewLineProgress(u Ui, name string) ProgressHandle {
+	return &lineProgress{Ui: u, Name: name, last: time.Now()}
+}
+
+type lineProgress struct {
+	Ui   Ui
+	Name string
+
+	lock sync.Mutex
+	last time.Time
+}
+
+func (p *lineProgress) Update(current, total int64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(p.last) < progressLineInterval {
+		return
+	}
+	p.last = now
+
+	p.Ui.Message(fmt.Sprintf("%s: %s", p.Name, progressText(current, total)))
+}
+
+func (p *lineProgress) Done() {}
+
+// nullProgress is a ProgressHandle that does nothing.
+type nullProgress struct{}
+
+func (nullProgress) Update(int64, int64) {}
+func (nullProgress) Done()               {}
+
+// ProgressBar renders the progress of one or more handles on a single
+// line of a terminal, updating it in-place using Raw. Nested progress
+// (an overall operation and its current item, for example) is rendered
+// side-by-side on the same line.
+//
+// Output written to the same terminal while a progress bar is active
+// will conflict with it. Ui implementations using ProgressBar should
+// call Clear before any other output and Redraw afterwards.
+type ProgressBar struct {
+	Ui Ui
+
+	lock     sync.Mutex
+	handles  []*barHandle
+	lastLen  int
+	lastDraw time.Time
+}
+
+// progressBarWidth is the width of the bar of a single progress handle.
+const progressBarWidth = 20
+
+// progressBarInterval is the minimum time between redraws caused by
+// updates, so fast updates (byte-level progress) don't flood the terminal.
+var progressBarInterval = 100 * time.Millisecond
+
+// Progress implements ProgressUi.
+func (b *ProgressBar) Progress(name string) ProgressHandle {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	h := &barHandle{bar: b, name: name}
+	b.handles = append(b.handles, h)
+	b.draw(true)
+	return h
+}
+
+// Clear clears the progress bar line if a bar is currently shown.
+// Clear and Redraw are safe to call on a nil ProgressBar.
+func (b *ProgressBar) Clear() {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.clear()
+}
+
+// Redraw redraws the progress bar line if there is any active progress.
+func (b *ProgressBar) Redraw() {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.draw(true)
+}
+
+func (b *ProgressBar) clear() {
+	if b.lastLen == 0 {
+		return
+	}
+
+	b.Ui.Raw("\r" + strings.Repeat(" ", b.lastLen) + "\r")
+	b.lastLen = 0
+}
+
+// draw draws the line. The lock must be held.
+func (b *ProgressBar) draw(force bool) {
+	if len(b.handles) == 0 {
+		return
+	}
+
+	now := time.Now()
+	if !force && now.Sub(b.lastDraw) < progressBarInterval {
+		return
+	}
+	b.lastDraw = now
+
+	parts := make([]string, len(b.handles))
+	for i, h := range b.handles {
+		parts[i] = h.String()
+	}
+	line := strings.Join(parts, "  ")
+
+	// Pad with spaces to overwrite any longer prior line
+	padding := ""
+	if len(line) < b.lastLen {
+		padding = strings.Repeat(" ", b.lastLen-len(line))
+	}
+
+	b.Ui.Raw("\r" + line + padding)
+	b.lastLen = len(line)
+}
+
+func (b *ProgressBar) done(h *barHandle) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for i, v := range b.handles {
+		if v == h {
+			b.handles = append(b.handles[:i], b.handles[i+1:]...)
+			break
+		}
+	}
+
+	// If this was the last handle, then finish the line so any further
+	// output starts on a fresh line. Otherwise just redraw without it.
+	if len(b.handles) == 0 {
+		b.clear()
+		return
+	}
+
+	b.draw(true)
+}
+
+type barHandle struct {
+	bar  *ProgressBar
+	name string
+
+	// Protected by the bar lock
+	current int64
+	total   int64
+	done    bool
+}
+
+func (h *barHandle) Update(current, total int64) {
+	h.bar.lock.Lock()
+	defer h.bar.lock.Unlock()
+
+	h.current = current
+	h.total = total
+	h.bar.draw(false)
+}
+
+func (h *barHandle) Done() {
+	h.bar.lock.Lock()
+	if h.done {
+		h.bar.lock.Unlock()
+		return
+	}
+	h.done = true
+	h.bar.lock.Unlock()
+
+	h.bar.done(h)
+}
+
+// String renders the handle. The bar lock must be held.
+func (h *barHandle) String() string {
+	if h.total <= 0 {
+		return fmt.Sprintf("%s %s", h.name, progressText(h.current, h.total))
+	}
+
+	filled := int(h.current * progressBarWidth / h.total)
+	if filled > progressBarWidth {
+		filled = progressBarWidth
+	}
+	if filled < 0 {
+		filled = 0
+	}
+
+	return fmt.Sprintf("%s [%s%s] %s",
+		h.name,
+		strings.Repeat("=", filled),
+		strings.Repeat(" ", progressBarWidth-filled),
+		progressText(h.current, h.total))
+}
+
+// progressText returns the textual form of a progress value.
+func progressText(current, total int64) string {
+	if total <= 0 {
+		return fmt.Sprintf("%d", current)
+	}
+
+	return fmt.Sprintf("%d%% (%d/%d)", current*100/total, current, total)
+}
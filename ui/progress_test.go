@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgress_fallback(t *testing.T) {
+	old := progressLineInterval
+	progressLineInterval = 0
+	defer func() { progressLineInterval = old }()
+
+	u := new(Mock)
+	p := Progress(u, "foo")
+	p.Update(1, 4)
+	p.Done()
+
+	if len(u.MessageBuf) != 1 {
+		t.Fatalf("bad: %#v", u.MessageBuf)
+	}
+	if u.MessageBuf[0] != "foo: 25% (1/4)" {
+		t.Fatalf("bad: %#v", u.MessageBuf[0])
+	}
+}
+
+func TestProgress_lineInterval(t *testing.T) {
+	old := progressLineInterval
+	progressLineInterval = time.Hour
+	defer func() { progressLineInterval = old }()
+
+	u := new(Mock)
+	p := NewLineProgress(u, "foo")
+	p.Update(1, 4)
+	p.Update(2, 4)
+	p.Done()
+
+	if len(u.MessageBuf) != 0 {
+		t.Fatalf("bad: %#v", u.MessageBuf)
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	u := new(Mock)
+	b := &ProgressBar{Ui: u}
+
+	p := b.Progress("foo")
+	p.Update(2, 4)
+	p.Done()
+	b.Redraw()
+
+	if len(u.RawBuf) < 2 {
+		t.Fatalf("bad: %#v", u.RawBuf)
+	}
+
+	// The last output should clear the line
+	last := u.RawBuf[len(u.RawBuf)-1]
+	if strings.TrimSpace(last) != "" {
+		t.Fatalf("bad: %#v", last)
+	}
+}
+
+func TestProgressBar_nested(t *testing.T) {
+	old := progressBarInterval
+	progressBarInterval = 0
+	defer func() { progressBarInterval = old }()
+
+	u := new(Mock)
+	b := &ProgressBar{Ui: u}
+
+	outer := b.Progress("outer")
+	inner := b.Progress("inner")
+	outer.Update(1, 2)
+	inner.Update(5, 10)
+
+	last := u.RawBuf[len(u.RawBuf)-1]
+	if !strings.Contains(last, "outer [==========          ] 50% (1/2)") {
+		t.Fatalf("bad: %#v", last)
+	}
+	if !strings.Contains(last, "inner [==========          ] 50% (5/10)") {
+		t.Fatalf("bad: %#v", last)
+	}
+
+	inner.Done()
+	last = u.RawBuf[len(u.RawBuf)-1]
+	if strings.Contains(last, "inner") {
+		t.Fatalf("bad: %#v", last)
+	}
+
+	outer.Done()
+}
+
+func TestProgressBar_nil(t *testing.T) {
+	var b *ProgressBar
+	b.Clear()
+	b.Redraw()
+}
@@ -0,0 +1,48 @@
+package ui
+
+import (
+	"fmt"
+)
+
+// Quiet is a wrapper around an existing Ui that suppresses all output
+// except errors and results (see LevelResult). This is useful in
+// environments such as CI where only the outcome is interesting.
+//
+// Input requests are still passed through to the wrapped Ui since
+// they can't be answered otherwise. If the wrapped Ui can't ask for
+// input, the returned error notes that the input was required so it
+// isn't mistaken for an ordinary failure.
+type Quiet struct {
+	Ui Ui
+}
+
+func (u *Quiet) Header(string)  {}
+func (u *Quiet) Message(string) {}
+func (u *Quiet) Raw(string)     {}
+
+func (u *Quiet) Input(opts *InputOpts) (string, error) {
+	result, err := u.Ui.Input(opts)
+	if err != nil {
+		return "", fmt.Errorf(
+			"Input required for '%s' (%s): %s", opts.Id, opts.Query, err)
+	}
+
+	return result, nil
+}
+
+// Log implements LevelUi.
+func (u *Quiet) Log(level Level, msg string) {
+	if level >= LevelError {
+		Log(u.Ui, level, msg)
+	}
+}
+
+// ErrorHint implements HintUi. Errors are always shown.
+func (u *Quiet) ErrorHint(msg, hint string) {
+	ErrorHint(u.Ui, msg, hint)
+}
+
+// Progress implements ProgressUi. Progress isn't shown in quiet mode.
+func (u *Quiet) Progress(string) ProgressHandle {
+	return new(nullProgress)
+}
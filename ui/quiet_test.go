@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQuiet_impl(t *testing.T) {
+	var _ Ui = new(Quiet)
+	var _ LevelUi = new(Quiet)
+}
+
+func TestQuiet(t *testing.T) {
+	u := new(Mock)
+	q := &Quiet{Ui: u}
+	q.Header("header")
+	q.Message("message")
+	q.Raw("raw")
+	Warn(q, "warn")
+	Error(q, "error")
+	Result(q, "result")
+
+	if len(u.HeaderBuf) != 0 {
+		t.Fatalf("bad: %#v", u.HeaderBuf)
+	}
+	if len(u.RawBuf) != 0 {
+		t.Fatalf("bad: %#v", u.RawBuf)
+	}
+
+	expected := []string{"[red]error", "[green]result"}
+	if !reflect.DeepEqual(u.MessageBuf, expected) {
+		t.Fatalf("bad: %#v", u.MessageBuf)
+	}
+}
+
+func TestQuiet_input(t *testing.T) {
+	u := &Mock{InputResult: "bar"}
+	q := &Quiet{Ui: u}
+	result, err := q.Input(&InputOpts{Id: "foo"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if result != "bar" {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	u.InputError = errors.New("no tty")
+	_, err = q.Input(&InputOpts{Id: "foo", Query: "Foo?"})
+	if err == nil {
+		t.Fatal("should error")
+	}
+	if !strings.Contains(err.Error(), "'foo'") {
+		t.Fatalf("bad: %s", err)
+	}
+}
@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RedactedText is the text that secrets are replaced with.
+const RedactedText = "<redacted>"
+
+// Redacted is a wrapper around an existing Ui that replaces any known
+// secret values in output with RedactedText.
+//
+// The answers to inputs with Hide set are automatically added as
+// secrets, and more can be added with AddSecret. The secrets are also
+// available to anything else that must be redacted, such as debug
+// bundles, with Redact.
+type Redacted struct {
+	Ui Ui
+
+	lock    sync.RWMutex
+	secrets map[string]struct{}
+}
+
+// AddSecret adds a value that is redacted from all future output.
+func (u *Redacted) AddSecret(v string) {
+	if v == "" {
+		return
+	}
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if u.secrets == nil {
+		u.secrets = make(map[string]struct{})
+	}
+	u.secrets[v] = struct{}{}
+}
+
+// Redact returns v with every known secret replaced with RedactedText.
+func (u *Redacted) Redact(v string) string {
+	u.lock.RLock()
+	defer u.lock.RUnlock()
+	if len(u.secrets) == 0 {
+		return v
+	}
+
+	// Replace the longest secrets first so that a secret that contains
+	// another secret is fully redacted.
+	secrets := make([]string, 0, len(u.secrets))
+	for s := range u.secrets {
+		secrets = append(secrets, s)
+	}
+	sort.Sort(byLengthDesc(secrets))
+
+	for _, s := range secrets {
+		v = strings.Replace(v, s, RedactedText, -1)
+	}
+
+	return v
+}
+
+func (u *Redacted) Header(msg string) {
+	u.Ui.Header(u.Redact(msg))
+}
+
+func (u *Redacted) Message(msg string) {
+	u.Ui.Message(u.Redact(msg))
+}
+
+func (u *Redacted) Raw(msg string) {
+	u.Ui.Raw(u.Redact(msg))
+}
+
+func (u *Redacted) Input(opts *InputOpts) (string, error) {
+	result, err := u.Ui.Input(opts)
+	if err == nil && opts.Hide {
+		u.AddSecret(result)
+	}
+
+	return result, err
+}
+
+// Log implements LevelUi.
+func (u *Redacted) Log(level Level, msg string) {
+	Log(u.Ui, level, u.Redact(msg))
+}
+
+// ErrorHint implements HintUi.
+func (u *Redacted) ErrorHint(msg, hint string) {
+	ErrorHint(u.Ui, u.Redact(msg), u.Redact(hint))
+}
+
+// Progress implements ProgressUi.
+func (u *Redacted) Progress(name string) ProgressHandle {
+	return Progress(u.Ui, u.Redact(name))
+}
+
+// SetColor implements ColorUi.
+func (u *Redacted) SetColor(enabled bool) {
+	if c, ok := u.Ui.(ColorUi); ok {
+		c.SetColor(enabled)
+	}
+}
+
+// byLengthDesc sorts strings from longest to shortest.
+type byLengthDesc []string
+
+func (s byLengthDesc) Len() int           { return len(s) }
+func (s byLengthDesc) Less(i, j int) bool { return len(s[i]) > len(s[j]) }
+func (s byLengthDesc) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"testing"
+)
+
+func TestRedacted_impl(t *testing.T) {
+	var _ Ui = new(Redacted)
+	var _ LevelUi = new(Redacted)
+	var _ ProgressUi = new(Redacted)
+}
+
+func TestRedacted(t *testing.T) {
+	mock := new(Mock)
+	u := &Redacted{Ui: mock}
+	u.AddSecret("foo")
+	u.AddSecret("foobar")
+
+	u.Message("a foobar b foo")
+	u.Log(LevelWarn, "foo")
+
+	expected := []string{"a <redacted> b <redacted>", "[yellow]WARNING: <redacted>"}
+	if len(mock.MessageBuf) != 2 ||
+		mock.MessageBuf[0] != expected[0] ||
+		mock.MessageBuf[1] != expected[1] {
+		t.Fatalf("bad: %#v", mock.MessageBuf)
+	}
+}
+
+func TestRedacted_input(t *testing.T) {
+	mock := &Mock{InputResult: "secret"}
+	u := &Redacted{Ui: mock}
+
+	if _, err := u.Input(&InputOpts{Id: "foo"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v := u.Redact("secret"); v != "secret" {
+		t.Fatalf("bad: %s", v)
+	}
+
+	if _, err := u.Input(&InputOpts{Id: "bar", Hide: true}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v := u.Redact("the secret"); v != "the "+RedactedText {
+		t.Fatalf("bad: %s", v)
+	}
+}
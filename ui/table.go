@@ -0,0 +1,141 @@
+package ui
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Table is a helper for rendering aligned columns of text, such as the
+// summary of an operation.
+type Table struct {
+	// Headers are the column headers. This is optional, but if set the
+	// number of headers determines the number of columns.
+	Headers []string
+
+	// Rows are the rows of the table. Rows with fewer columns than the
+	// table are padded with blank cells.
+	Rows [][]string
+
+	// MaxWidth, if positive, is the maximum width of a rendered line.
+	// If the table is wider than this, the widest columns are truncated
+	// until it fits.
+	MaxWidth int
+}
+
+// tableColumnSep is the separator between columns.
+const tableColumnSep = "   "
+
+// tableMinColumnWidth is the minimum width that a column is truncated to
+// when the table doesn't fit in MaxWidth.
+const tableMinColumnWidth = 5
+
+// AddRow adds a row to the table.
+func (t *Table) AddRow(cols ...string) {
+	t.Rows = append(t.Rows, cols)
+}
+
+// String renders the table. Color markup in cells is allowed and isn't
+// counted towards the width of a cell.
+func (t *Table) String() string {
+	// Determine the number of columns
+	n := len(t.Headers)
+	for _, row := range t.Rows {
+		if len(row) > n {
+			n = len(row)
+		}
+	}
+	if n == 0 {
+		return ""
+	}
+
+	// Determine the width of every column
+	widths := make([]int, n)
+	measure := func(row []string) {
+		for i, col := range row {
+			if l := len(StripColors(col)); l > widths[i] {
+				widths[i] = l
+			}
+		}
+	}
+	measure(t.Headers)
+	for _, row := range t.Rows {
+		measure(row)
+	}
+
+	// If we have a maximum width, shrink the widest column until we fit
+	if t.MaxWidth > 0 {
+		for {
+			total := len(tableColumnSep) * (n - 1)
+			widest := 0
+			for i, w := range widths {
+				total += w
+				if w > widths[widest] {
+					widest = i
+				}
+			}
+
+			if total <= t.MaxWidth || widths[widest] <= tableMinColumnWidth {
+				break
+			}
+
+			widths[widest]--
+		}
+	}
+
+	var buf bytes.Buffer
+	writeRow := func(row []string) {
+		cols := make([]string, n)
+		for i := range cols {
+			var col string
+			if i < len(row) {
+				col = row[i]
+			}
+
+			// The last column isn't padded so we don't have trailing spaces
+			cols[i] = tableCell(col, widths[i], i < n-1)
+		}
+
+		buf.WriteString(strings.TrimRight(
+			strings.Join(cols, tableColumnSep), " "))
+		buf.WriteString("\n")
+	}
+
+	if len(t.Headers) > 0 {
+		writeRow(t.Headers)
+	}
+	for _, row := range t.Rows {
+		writeRow(row)
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// tableCell renders a single cell to the given width, truncating it if
+// it is too long. If the cell is truncated its color markup is removed
+// since we can't safely cut through it.
+func tableCell(col string, width int, pad bool) string {
+	plain := StripColors(col)
+	if len(plain) > width {
+		col = plain[:width-3] + "..."
+		plain = col
+	}
+
+	if pad && len(plain) < width {
+		col += strings.Repeat(" ", width-len(plain))
+	}
+
+	return col
+}
+
+// TerminalWidth returns the width of the terminal as reported by the
+// COLUMNS environment variable, or zero if it isn't known.
+func TerminalWidth() int {
+	v, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || v < 0 {
+		return 0
+	}
+
+	return v
+}
@@ -0,0 +1,36 @@
+package ui
+
+import (
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	table := &Table{Headers: []string{"NAME", "TIME"}}
+	table.AddRow("foo", "1s")
+	table.AddRow("[green]foobar[reset]", "10s")
+	table.AddRow("baz")
+
+	expected := "NAME     TIME\n" +
+		"foo      1s\n" +
+		"[green]foobar[reset]   10s\n" +
+		"baz"
+	if actual := table.String(); actual != expected {
+		t.Fatalf("bad:\n%s", actual)
+	}
+}
+
+func TestTable_maxWidth(t *testing.T) {
+	table := &Table{MaxWidth: 20}
+	table.AddRow("a-very-long-name-here", "ok")
+
+	expected := "a-very-long-...   ok"
+	if actual := table.String(); actual != expected {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestTable_empty(t *testing.T) {
+	if actual := new(Table).String(); actual != "" {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Tee is a wrapper around an existing Ui that also writes a plain text
+// copy of all output to Writer, such as a log file. Color markup is
+// removed from the copy. Errors writing to Writer are ignored.
+type Tee struct {
+	Ui     Ui
+	Writer io.Writer
+
+	lock sync.Mutex
+}
+
+func (u *Tee) Header(msg string) {
+	u.write("==> " + msg)
+	u.Ui.Header(msg)
+}
+
+func (u *Tee) Message(msg string) {
+	u.write(msg)
+	u.Ui.Message(msg)
+}
+
+func (u *Tee) Raw(msg string) {
+	u.lock.Lock()
+	fmt.Fprint(u.Writer, StripColors(msg))
+	u.lock.Unlock()
+
+	u.Ui.Raw(msg)
+}
+
+func (u *Tee) Input(opts *InputOpts) (string, error) {
+	u.write(fmt.Sprintf("input requested: %s (%s)", opts.Query, opts.Id))
+	return u.Ui.Input(opts)
+}
+
+// Log implements LevelUi.
+func (u *Tee) Log(level Level, msg string) {
+	u.write(levelPrefix(level) + msg)
+	Log(u.Ui, level, msg)
+}
+
+// Progress implements ProgressUi.
+func (u *Tee) Progress(name string) ProgressHandle {
+	return Progress(u.Ui, name)
+}
+
+// SetColor implements ColorUi.
+func (u *Tee) SetColor(enabled bool) {
+	if c, ok := u.Ui.(ColorUi); ok {
+		c.SetColor(enabled)
+	}
+}
+
+func (u *Tee) write(msg string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	fmt.Fprintln(u.Writer, strings.TrimRight(StripColors(msg), "\n"))
+}
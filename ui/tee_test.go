@@ -0,0 +1,33 @@
+package ui
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTee_impl(t *testing.T) {
+	var _ Ui = new(Tee)
+	var _ LevelUi = new(Tee)
+	var _ ProgressUi = new(Tee)
+}
+
+func TestTee(t *testing.T) {
+	var buf bytes.Buffer
+	mock := new(Mock)
+	u := &Tee{Ui: mock, Writer: &buf}
+
+	u.Header("[bold]foo")
+	u.Message("bar\n")
+	u.Log(LevelWarn, "baz")
+
+	expected := "==> foo\nbar\nWARNING: baz\n"
+	if buf.String() != expected {
+		t.Fatalf("bad: %#v", buf.String())
+	}
+	if mock.HeaderBuf[0] != "[bold]foo" {
+		t.Fatalf("bad: %#v", mock.HeaderBuf)
+	}
+	if mock.MessageBuf[1] != "[yellow]WARNING: baz" {
+		t.Fatalf("bad: %#v", mock.MessageBuf)
+	}
+}
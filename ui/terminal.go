@@ -0,0 +1,21 @@
+package ui
+
+import (
+	"os"
+)
+
+// IsTerminal returns true if the given file is a terminal (a character
+// device). This is used to decide whether output can be colored or
+// updated in-place.
+func IsTerminal(f *os.File) bool {
+	if f == nil {
+		return false
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeCharDevice != 0
+}
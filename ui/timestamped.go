@@ -0,0 +1,195 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timestamped is a wrapper around an existing Ui that prefixes every line
+// of output with the current time, formatted with Layout. If Elapsed is
+// true, the time elapsed since the Ui was created or restarted is also
+// shown.
+//
+// Each Header additionally outputs how long it has been since the previous
+// Header so the duration of each phase of an operation is visible.
+//
+// When stacked with the other wrappers in this package, Timestamped should
+// be below any filtering (Filtered, Quiet) so that suppressed output
+// doesn't affect phase durations, and below Prefixed so that the time is
+// the first thing on each line:
+//
+//	Prefixed -> Filtered/Quiet -> Timestamped -> Ui
+type Timestamped struct {
+	Ui      Ui
+	Layout  string
+	Elapsed bool
+
+	lock       sync.Mutex
+	start      time.Time
+	lastHeader time.Time
+	rawMidLine bool
+	now        func() time.Time
+}
+
+// NewTimestamped returns a Ui that prefixes all output to inner with the
+// time formatted with layout. If layout is empty, "15:04:05" is used.
+func NewTimestamped(inner Ui, layout string) *Timestamped {
+	if layout == "" {
+		layout = "15:04:05"
+	}
+
+	return &Timestamped{Ui: inner, Layout: layout}
+}
+
+func (u *Timestamped) Header(msg string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	now := u.time()
+	if !u.lastHeader.IsZero() {
+		u.Ui.Message(u.stamp(now, fmt.Sprintf(
+			"Done in %s", truncateDuration(now.Sub(u.lastHeader)))))
+	}
+	u.lastHeader = now
+
+	u.Ui.Header(u.stamp(now, msg))
+}
+
+func (u *Timestamped) Message(msg string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.Ui.Message(u.stamp(u.time(), msg))
+}
+
+func (u *Timestamped) Raw(msg string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	// Raw output can end mid-line, so we only stamp the start of lines.
+	now := u.time()
+	var buf bytes.Buffer
+	for msg != "" {
+		if !u.rawMidLine {
+			buf.WriteString(u.timestamp(now) + " ")
+			u.rawMidLine = true
+		}
+
+		idx := strings.Index(msg, "\n")
+		if idx < 0 {
+			buf.WriteString(msg)
+			break
+		}
+
+		buf.WriteString(msg[:idx+1])
+		msg = msg[idx+1:]
+		u.rawMidLine = false
+	}
+
+	u.Ui.Raw(buf.String())
+}
+
+func (u *Timestamped) Input(opts *InputOpts) (string, error) {
+	return u.Ui.Input(opts)
+}
+
+// Log implements LevelUi.
+func (u *Timestamped) Log(level Level, msg string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	Log(u.Ui, level, u.stamp(u.time(), msg))
+}
+
+// ErrorHint implements HintUi. Only the message is timestamped when the
+// wrapped Ui renders the hint itself.
+func (u *Timestamped) ErrorHint(msg, hint string) {
+	h, ok := u.Ui.(HintUi)
+	if !ok {
+		errorHintLines(u, msg, hint)
+		return
+	}
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	h.ErrorHint(u.stamp(u.time(), msg), hint)
+}
+
+// Restart starts timing again from the next output, as if the Ui was
+// just created. Call this when a new operation starts so that the elapsed
+// time is since it started, and the duration of the last phase of the
+// previous operation isn't output.
+func (u *Timestamped) Restart() {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.start = time.Time{}
+	u.lastHeader = time.Time{}
+}
+
+// Progress implements ProgressUi. Progress is rendered by the wrapped Ui
+// and isn't timestamped.
+func (u *Timestamped) Progress(name string) ProgressHandle {
+	return Progress(u.Ui, name)
+}
+
+// SetColor implements ColorUi.
+func (u *Timestamped) SetColor(enabled bool) {
+	if c, ok := u.Ui.(ColorUi); ok {
+		c.SetColor(enabled)
+	}
+}
+
+// time returns the current time, initializing the start time if this
+// is the first output. The lock must be held.
+func (u *Timestamped) time() time.Time {
+	now := time.Now()
+	if u.now != nil {
+		now = u.now()
+	}
+	if u.start.IsZero() {
+		u.start = now
+	}
+
+	return now
+}
+
+// timestamp returns the timestamp prefix for the given time.
+func (u *Timestamped) timestamp(now time.Time) string {
+	result := now.Format(u.Layout)
+	if u.Elapsed {
+		result += " +" + truncateDuration(now.Sub(u.start))
+	}
+
+	return result
+}
+
+// stamp prefixes every line of msg with the timestamp. Color markup that
+// starts the message is kept at the start so the entire line, including
+// the timestamp, is styled the same way.
+func (u *Timestamped) stamp(now time.Time, msg string) string {
+	ts := u.timestamp(now) + " "
+
+	var style string
+	for {
+		match := leadingStyleRe.FindString(msg)
+		if match == "" {
+			break
+		}
+
+		style += match
+		msg = msg[len(match):]
+	}
+
+	lines := strings.Split(msg, "\n")
+	for i, line := range lines {
+		lines[i] = ts + line
+	}
+
+	return style + strings.Join(lines, "\n")
+}
+
+// truncateDuration formats d with a precision of a second.
+func truncateDuration(d time.Duration) string {
+	return (d - d%time.Second).String()
+}
@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimestamped_impl(t *testing.T) {
+	var _ Ui = new(Timestamped)
+	var _ LevelUi = new(Timestamped)
+}
+
+func TestTimestamped(t *testing.T) {
+	u := new(Mock)
+	ts, now := testTimestamped(u)
+	ts.Elapsed = true
+
+	ts.Header("one")
+	*now = now.Add(90 * time.Second)
+	ts.Message("[green]foo\nbar")
+	ts.Header("two")
+
+	expected := []string{"12:00:00 +0s one", "12:01:30 +1m30s two"}
+	if !reflect.DeepEqual(u.HeaderBuf, expected) {
+		t.Fatalf("bad: %#v", u.HeaderBuf)
+	}
+
+	expected = []string{
+		"[green]12:01:30 +1m30s foo\n12:01:30 +1m30s bar",
+		"12:01:30 +1m30s Done in 1m30s",
+	}
+	if !reflect.DeepEqual(u.MessageBuf, expected) {
+		t.Fatalf("bad: %#v", u.MessageBuf)
+	}
+}
+
+func TestTimestamped_restart(t *testing.T) {
+	u := new(Mock)
+	ts, now := testTimestamped(u)
+	ts.Elapsed = true
+
+	ts.Header("one")
+	*now = now.Add(90 * time.Second)
+	ts.Restart()
+	ts.Header("two")
+	*now = now.Add(time.Second)
+	ts.Message("foo")
+
+	expected := []string{"12:00:00 +0s one", "12:01:30 +0s two"}
+	if !reflect.DeepEqual(u.HeaderBuf, expected) {
+		t.Fatalf("bad: %#v", u.HeaderBuf)
+	}
+
+	expected = []string{"12:01:31 +1s foo"}
+	if !reflect.DeepEqual(u.MessageBuf, expected) {
+		t.Fatalf("bad: %#v", u.MessageBuf)
+	}
+}
+
+func TestTimestamped_raw(t *testing.T) {
+	u := new(Mock)
+	ts, _ := testTimestamped(u)
+
+	ts.Raw("foo")
+	ts.Raw("bar\nbaz\n")
+	ts.Raw("qux")
+
+	actual := strings.Join(u.RawBuf, "")
+	expected := "12:00:00 foobar\n12:00:00 baz\n12:00:00 qux"
+	if actual != expected {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestTimestamped_stacking(t *testing.T) {
+	u := new(Mock)
+	ts, _ := testTimestamped(u)
+	p := NewPrefixed(&Quiet{Ui: ts}, "app")
+
+	p.Message("hidden")
+	Error(p, "failed")
+
+	if len(u.MessageBuf) != 1 {
+		t.Fatalf("bad: %#v", u.MessageBuf)
+	}
+
+	actual := StripColors(u.MessageBuf[0])
+	if actual != "12:00:00 app: failed" {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func testTimestamped(u Ui) (*Timestamped, *time.Time) {
+	now := time.Date(2015, 1, 1, 12, 0, 0, 0, time.UTC)
+	ts := NewTimestamped(u, "")
+	ts.now = func() time.Time { return now }
+	return ts, &now
+}
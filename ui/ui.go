@@ -1,6 +1,10 @@
 package ui
 
-import "os"
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
 
 // Ui is the component of Otto responsible for reading/writing to the
 // console.
@@ -52,9 +56,21 @@ type InputOpts struct {
 	// Default will be the value returned if no data is entered.
 	Default string
 
-	// Hide will hide the text while it is being typed.
+	// Hide will hide the text while it is being typed. This should be
+	// set for any secret values such as passwords or keys.
 	Hide bool
 
+	// Pattern, if set, is a regular expression that the value must match.
+	//
+	// Validate, if set, is called to validate the value and returns an
+	// error describing the problem if it is invalid. Validate isn't sent
+	// over RPC so plugins should prefer Pattern where possible.
+	//
+	// Ui implementations that are interactive ask again if the value
+	// is invalid, otherwise an error is returned.
+	Pattern  string
+	Validate func(string) error
+
 	// EnvVars is a list of environment variables where the value can be looked
 	// up, in priority order. If any of these environment Variables are
 	// non-empty, they will be returned as the value for this input and the user
@@ -72,3 +88,23 @@ func (o *InputOpts) EnvVarValue() string {
 	}
 	return ""
 }
+
+// ValidateValue validates the given value against Pattern and Validate,
+// returning an error if the value is invalid.
+func (o *InputOpts) ValidateValue(v string) error {
+	if o.Pattern != "" {
+		re, err := regexp.Compile(o.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern for '%s': %s", o.Id, err)
+		}
+		if !re.MatchString(v) {
+			return fmt.Errorf("value must match the pattern: %s", o.Pattern)
+		}
+	}
+
+	if o.Validate != nil {
+		return o.Validate(v)
+	}
+
+	return nil
+}
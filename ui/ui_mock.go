@@ -1,32 +1,178 @@
 package ui
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
 // Mock is an implementation of Ui that stores its data in-memory
 // primarily for testing purposes.
+//
+// Mock is the supported way to test code that uses a Ui, including app,
+// foundation, and infrastructure implementations: set it as the Ui of
+// the context (or CoreConfig), script the answers to any inputs with
+// AddAnswer, and check the output with the Assert helpers or by looking
+// at the recorded calls directly. Mock is safe for concurrent use, but
+// the exported fields shouldn't be read while calls are still being made.
 type Mock struct {
 	HeaderBuf  []string
 	MessageBuf []string
 	RawBuf     []string
 
+	// Calls are all the calls made to the Ui in the order they were made.
+	Calls []*MockCall
+
+	// InputOpts is the options to the last call to Input. InputResult
+	// and InputError are returned from Input if no answer added with
+	// AddAnswer matches.
 	InputCalled bool
 	InputOpts   *InputOpts
 	InputResult string
 	InputError  error
+
+	lock    sync.Mutex
+	answers []*mockAnswer
+}
+
+// MockCall is a single call made to a Mock.
+type MockCall struct {
+	// Method is the name of the method that was called: "Header",
+	// "Message", "Raw", or "Input".
+	Method string
+
+	// Value is the message, or the Id of the input for Input.
+	Value string
+
+	// InputOpts are the options for Input.
+	InputOpts *InputOpts
+}
+
+// MockT is the subset of testing.T that is used by the Mock assertions.
+type MockT interface {
+	Fatalf(format string, args ...interface{})
+}
+
+type mockAnswer struct {
+	Pattern *regexp.Regexp
+	Result  string
+	Err     error
+}
+
+// AddAnswer scripts the answer to any input whose Id or Query matches
+// the regular expression pattern. Answers are checked in the order they
+// were added.
+func (u *Mock) AddAnswer(pattern, result string) {
+	u.addAnswer(pattern, result, nil)
+}
+
+// AddInputError scripts an error for any input whose Id or Query matches
+// the regular expression pattern.
+func (u *Mock) AddInputError(pattern string, err error) {
+	u.addAnswer(pattern, "", err)
+}
+
+func (u *Mock) addAnswer(pattern, result string, err error) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.answers = append(u.answers, &mockAnswer{
+		Pattern: regexp.MustCompile(pattern),
+		Result:  result,
+		Err:     err,
+	})
 }
 
 func (u *Mock) Header(msg string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
 	u.HeaderBuf = append(u.HeaderBuf, msg)
+	u.Calls = append(u.Calls, &MockCall{Method: "Header", Value: msg})
 }
 
 func (u *Mock) Message(msg string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
 	u.MessageBuf = append(u.MessageBuf, msg)
+	u.Calls = append(u.Calls, &MockCall{Method: "Message", Value: msg})
 }
 
 func (u *Mock) Raw(msg string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
 	u.RawBuf = append(u.RawBuf, msg)
+	u.Calls = append(u.Calls, &MockCall{Method: "Raw", Value: msg})
 }
 
 func (u *Mock) Input(opts *InputOpts) (string, error) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
 	u.InputCalled = true
 	u.InputOpts = opts
+	u.Calls = append(u.Calls, &MockCall{
+		Method: "Input", Value: opts.Id, InputOpts: opts})
+
+	for _, a := range u.answers {
+		if a.Pattern.MatchString(opts.Id) || a.Pattern.MatchString(opts.Query) {
+			return a.Result, a.Err
+		}
+	}
+
 	return u.InputResult, u.InputError
 }
+
+// InputRequested reports whether an input with the given Id was requested.
+func (u *Mock) InputRequested(id string) bool {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	for _, c := range u.Calls {
+		if c.Method == "Input" && c.Value == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AssertHeaderContains fails the test if no header contains substr.
+func (u *Mock) AssertHeaderContains(t MockT, substr string) {
+	u.assertContains(t, "Header", substr)
+}
+
+// AssertMessageContains fails the test if no message contains substr.
+func (u *Mock) AssertMessageContains(t MockT, substr string) {
+	u.assertContains(t, "Message", substr)
+}
+
+// AssertMessageNotContains fails the test if any message contains substr.
+func (u *Mock) AssertMessageNotContains(t MockT, substr string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	for _, c := range u.Calls {
+		if c.Method == "Message" && strings.Contains(c.Value, substr) {
+			t.Fatalf("message should not contain %q: %q", substr, c.Value)
+		}
+	}
+}
+
+func (u *Mock) assertContains(t MockT, method, substr string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	for _, c := range u.Calls {
+		if c.Method == method && strings.Contains(c.Value, substr) {
+			return
+		}
+	}
+
+	t.Fatalf("no %s contains %q:\n\n%s", strings.ToLower(method), substr, u.dump())
+}
+
+// dump returns all the calls for a failure message. The lock must be held.
+func (u *Mock) dump() string {
+	lines := make([]string, len(u.Calls))
+	for i, c := range u.Calls {
+		lines[i] = fmt.Sprintf("%s: %s", c.Method, c.Value)
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -1,9 +1,104 @@
 package ui
 
 import (
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 )
 
 func TestMock_impl(t *testing.T) {
 	var _ Ui = new(Mock)
 }
+
+func TestMock_calls(t *testing.T) {
+	u := new(Mock)
+	u.Header("foo")
+	u.Message("bar")
+	u.Input(&InputOpts{Id: "baz"})
+
+	if len(u.Calls) != 3 {
+		t.Fatalf("bad: %#v", u.Calls)
+	}
+	for i, expected := range []string{"Header", "Message", "Input"} {
+		if u.Calls[i].Method != expected {
+			t.Fatalf("bad: %d %#v", i, u.Calls[i])
+		}
+	}
+
+	u.AssertHeaderContains(t, "fo")
+	u.AssertMessageContains(t, "ar")
+	u.AssertMessageNotContains(t, "foo")
+	if !u.InputRequested("baz") {
+		t.Fatal("should be requested")
+	}
+	if u.InputRequested("foo") {
+		t.Fatal("should not be requested")
+	}
+}
+
+func TestMock_answers(t *testing.T) {
+	u := &Mock{InputResult: "default"}
+	u.AddAnswer("^foo$", "a")
+	u.AddAnswer("Bar", "b")
+	u.AddInputError("baz", errors.New("error"))
+
+	cases := []struct {
+		Opts   *InputOpts
+		Result string
+		Err    bool
+	}{
+		{&InputOpts{Id: "foo"}, "a", false},
+		{&InputOpts{Id: "other", Query: "Bar?"}, "b", false},
+		{&InputOpts{Id: "baz"}, "", true},
+		{&InputOpts{Id: "foo2"}, "default", false},
+	}
+
+	for _, tc := range cases {
+		v, err := u.Input(tc.Opts)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s: err: %s", tc.Opts.Id, err)
+		}
+		if v != tc.Result {
+			t.Fatalf("%s: bad: %s", tc.Opts.Id, v)
+		}
+	}
+}
+
+func TestMock_assertFail(t *testing.T) {
+	u := new(Mock)
+	u.Message("foo")
+
+	mt := new(mockT)
+	u.AssertMessageContains(mt, "bar")
+	if !mt.failed {
+		t.Fatal("should fail")
+	}
+}
+
+func TestMock_concurrent(t *testing.T) {
+	u := new(Mock)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			u.Message(fmt.Sprintf("%d", i))
+			u.Input(&InputOpts{Id: "foo"})
+		}(i)
+	}
+	wg.Wait()
+
+	if len(u.MessageBuf) != 50 || len(u.Calls) != 100 {
+		t.Fatalf("bad: %d %d", len(u.MessageBuf), len(u.Calls))
+	}
+}
+
+type mockT struct {
+	failed bool
+}
+
+func (t *mockT) Fatalf(string, ...interface{}) {
+	t.failed = true
+}
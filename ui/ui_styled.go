@@ -22,6 +22,39 @@ func (u *Styled) Message(msg string) {
 	u.Ui.Message(u.prefix("    ", msg))
 }
 
+// SetColor implements ColorUi.
+func (u *Styled) SetColor(enabled bool) {
+	if c, ok := u.Ui.(ColorUi); ok {
+		c.SetColor(enabled)
+	}
+}
+
+// Log implements LevelUi. Leveled messages are styled like Message.
+func (u *Styled) Log(level Level, msg string) {
+	Log(u.Ui, level, u.prefix("    ", msg))
+}
+
+// ErrorHint implements HintUi. Errors that the wrapped Ui renders itself
+// aren't styled.
+func (u *Styled) ErrorHint(msg, hint string) {
+	if h, ok := u.Ui.(HintUi); ok {
+		h.ErrorHint(msg, hint)
+		return
+	}
+
+	errorHintLines(u, msg, hint)
+}
+
+// Progress implements ProgressUi, using the wrapped Ui's progress
+// rendering if it has any.
+func (u *Styled) Progress(name string) ProgressHandle {
+	if p, ok := u.Ui.(ProgressUi); ok {
+		return p.Progress(name)
+	}
+
+	return NewLineProgress(u, name)
+}
+
 func (u *Styled) prefix(prefix, msg string) string {
 	var buf bytes.Buffer
 
@@ -0,0 +1,31 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestInputOptsValidateValue(t *testing.T) {
+	cases := []struct {
+		Opts  *InputOpts
+		Value string
+		Err   bool
+	}{
+		{&InputOpts{}, "", false},
+		{&InputOpts{Pattern: `^[0-9]+$`}, "42", false},
+		{&InputOpts{Pattern: `^[0-9]+$`}, "foo", true},
+		{&InputOpts{Pattern: `[`}, "foo", true},
+		{
+			&InputOpts{Validate: func(string) error { return errors.New("bad") }},
+			"foo",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		err := tc.Opts.ValidateValue(tc.Value)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%d: err: %s", i, err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"sync"
+)
+
+// WarningRecorder is a wrapper around an existing Ui that records every
+// warning that passes through it, so that they can be repeated at the
+// end of an operation or counted.
+type WarningRecorder struct {
+	Ui Ui
+
+	lock     sync.Mutex
+	warnings []string
+}
+
+// Warnings returns the warnings recorded since the last Reset.
+func (u *WarningRecorder) Warnings() []string {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	result := make([]string, len(u.warnings))
+	copy(result, u.warnings)
+	return result
+}
+
+// Reset clears the recorded warnings.
+func (u *WarningRecorder) Reset() {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.warnings = nil
+}
+
+func (u *WarningRecorder) Header(msg string)  { u.Ui.Header(msg) }
+func (u *WarningRecorder) Message(msg string) { u.Ui.Message(msg) }
+func (u *WarningRecorder) Raw(msg string)     { u.Ui.Raw(msg) }
+
+func (u *WarningRecorder) Input(opts *InputOpts) (string, error) {
+	return u.Ui.Input(opts)
+}
+
+// Log implements LevelUi.
+func (u *WarningRecorder) Log(level Level, msg string) {
+	if level == LevelWarn {
+		u.lock.Lock()
+		u.warnings = append(u.warnings, msg)
+		u.lock.Unlock()
+	}
+
+	Log(u.Ui, level, msg)
+}
+
+// ErrorHint implements HintUi.
+func (u *WarningRecorder) ErrorHint(msg, hint string) {
+	ErrorHint(u.Ui, msg, hint)
+}
+
+// Progress implements ProgressUi.
+func (u *WarningRecorder) Progress(name string) ProgressHandle {
+	return Progress(u.Ui, name)
+}
+
+// SetColor implements ColorUi.
+func (u *WarningRecorder) SetColor(enabled bool) {
+	if c, ok := u.Ui.(ColorUi); ok {
+		c.SetColor(enabled)
+	}
+}
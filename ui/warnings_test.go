@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWarningRecorder_impl(t *testing.T) {
+	var _ Ui = new(WarningRecorder)
+	var _ LevelUi = new(WarningRecorder)
+	var _ ProgressUi = new(WarningRecorder)
+}
+
+func TestWarningRecorder(t *testing.T) {
+	mock := new(Mock)
+	u := &WarningRecorder{Ui: mock}
+
+	Warn(u, "foo")
+	Info(u, "bar")
+	Warn(u, "baz")
+
+	expected := []string{"foo", "baz"}
+	if actual := u.Warnings(); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+	if mock.MessageBuf[0] != "[yellow]WARNING: foo" {
+		t.Fatalf("bad: %#v", mock.MessageBuf)
+	}
+
+	u.Reset()
+	if actual := u.Warnings(); len(actual) != 0 {
+		t.Fatalf("bad: %#v", actual)
+	}
+}